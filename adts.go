@@ -0,0 +1,160 @@
+package mp4
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/Spidey120703/go-mp4/internal/bitio"
+)
+
+const adtsHeaderSize = 7
+
+var aacSamplingFrequencies = []uint32{
+	96000, 88200, 64000, 48000, 44100, 32000, 24000, 22050, 16000, 12000, 11025, 8000, 7350,
+}
+
+// adtsConfig holds the fields of AudioSpecificConfig which are needed to build ADTS headers.
+type adtsConfig struct {
+	audioObjectType        uint8
+	samplingFrequencyIndex uint8
+	channelConfiguration   uint8
+}
+
+// ExtractADTS reads samples of the AAC track and writes them to w as an ADTS stream.
+// Each ADTS header is built from AudioSpecificConfig in the esds box.
+// The first AAC track is used when trackID is zero.
+func ExtractADTS(r io.ReadSeeker, w io.Writer, trackID uint32) error {
+	bips, err := ExtractBoxesWithPayload(r, nil, []BoxPath{
+		{BoxTypeMoov(), BoxTypeTrak(), BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeMp4a(), BoxTypeEsds()},
+	})
+	if err != nil {
+		return err
+	}
+	var esds *Esds
+	for _, bip := range bips {
+		if trackID == 0 || bip.Info.TrackID == trackID {
+			trackID = bip.Info.TrackID
+			esds = bip.Payload.(*Esds)
+			break
+		}
+	}
+	if esds == nil {
+		if trackID != 0 {
+			return fmt.Errorf("AAC track not found: trackID=%d", trackID)
+		}
+		return errors.New("AAC track not found")
+	}
+	config, err := parseADTSConfig(esds)
+	if err != nil {
+		return err
+	}
+
+	sr, err := NewSampleReader(r, trackID)
+	if err != nil {
+		return err
+	}
+	for {
+		s, err := sr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		header, err := config.header(len(s.Data))
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(header); err != nil {
+			return err
+		}
+		if _, err := w.Write(s.Data); err != nil {
+			return err
+		}
+	}
+}
+
+func parseADTSConfig(esds *Esds) (*adtsConfig, error) {
+	configDscr := findDescriptorByTag(esds.Descriptors, DecoderConfigDescrTag)
+	if configDscr == nil || configDscr.DecoderConfigDescriptor == nil ||
+		configDscr.DecoderConfigDescriptor.ObjectTypeIndication != 0x40 {
+		return nil, errors.New("not MPEG-4 audio")
+	}
+	specificDscr := findDescriptorByTag(esds.Descriptors, DecSpecificInfoTag)
+	if specificDscr == nil {
+		return nil, errors.New("DecoderSpecificationInfoDescriptor not found")
+	}
+
+	r := bitio.NewReader(bytes.NewReader(specificDscr.Data))
+	readSamplingFrequencyIndex := func() (uint8, error) {
+		sfi, err := r.ReadBits(4)
+		if err != nil {
+			return 0, err
+		}
+		if sfi[0] != 0x0f {
+			return sfi[0], nil
+		}
+		freq, err := r.ReadBits(24)
+		if err != nil {
+			return 0, err
+		}
+		frequency := uint32(freq[0])<<16 | uint32(freq[1])<<8 | uint32(freq[2])
+		for i, f := range aacSamplingFrequencies {
+			if f == frequency {
+				return uint8(i), nil
+			}
+		}
+		return 0, fmt.Errorf("sampling frequency not supported by ADTS: %d", frequency)
+	}
+
+	config := &adtsConfig{}
+	audioObjectType, _, err := getAudioObjectType(r)
+	if err != nil {
+		return nil, err
+	}
+	if config.samplingFrequencyIndex, err = readSamplingFrequencyIndex(); err != nil {
+		return nil, err
+	}
+	channelConfiguration, err := r.ReadBits(4)
+	if err != nil {
+		return nil, err
+	}
+	config.channelConfiguration = channelConfiguration[0]
+	if audioObjectType == 5 || audioObjectType == 29 {
+		// explicit SBR or PS signaling: ADTS carries the core AAC configuration
+		if _, err := readSamplingFrequencyIndex(); err != nil {
+			return nil, err
+		}
+		if audioObjectType, _, err = getAudioObjectType(r); err != nil {
+			return nil, err
+		}
+	}
+	config.audioObjectType = audioObjectType
+
+	if config.audioObjectType == 0 || config.audioObjectType > 4 {
+		return nil, fmt.Errorf("audio object type not supported by ADTS: %d", config.audioObjectType)
+	}
+	if config.channelConfiguration == 0 || config.channelConfiguration > 7 {
+		return nil, fmt.Errorf("channel configuration not supported by ADTS: %d", config.channelConfiguration)
+	}
+	return config, nil
+}
+
+// header builds the ADTS header without CRC for the raw data block of the specified size.
+func (c *adtsConfig) header(dataSize int) ([]byte, error) {
+	frameLength := dataSize + adtsHeaderSize
+	if frameLength > 0x1fff {
+		return nil, fmt.Errorf("too large AAC frame: %d", dataSize)
+	}
+	profile := c.audioObjectType - 1
+	return []byte{
+		0xff, // syncword
+		0xf1, // syncword, ID=0 (MPEG-4), layer=0, protection_absent=1
+		profile<<6 | c.samplingFrequencyIndex<<2 | c.channelConfiguration>>2,
+		(c.channelConfiguration&0x3)<<6 | uint8(frameLength>>11),
+		uint8(frameLength >> 3),
+		uint8(frameLength&0x7)<<5 | 0x1f, // adts_buffer_fullness=0x7ff (VBR)
+		0xfc,                             // adts_buffer_fullness, number_of_raw_data_blocks_in_frame=0
+	}, nil
+}
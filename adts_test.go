@@ -0,0 +1,70 @@
+package mp4
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractADTS(t *testing.T) {
+	f, err := os.Open("./testdata/sample.mp4")
+	require.NoError(t, err)
+	defer f.Close()
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, ExtractADTS(f, buf, 0))
+
+	sr, err := NewSampleReader(f, 2)
+	require.NoError(t, err)
+	data := buf.Bytes()
+	for {
+		s, err := sr.Next()
+		if err != nil {
+			break
+		}
+		require.Greater(t, len(data), adtsHeaderSize)
+		header := data[:adtsHeaderSize]
+		assert.Equal(t, []byte{0xff, 0xf1}, header[:2])
+		assert.Equal(t, uint8(1), header[2]>>6)        // AAC LC
+		assert.Equal(t, uint8(4), (header[2]>>2)&0x0f) // 44100 Hz
+		frameLength := int(header[3]&0x3)<<11 | int(header[4])<<3 | int(header[5]>>5)
+		assert.Equal(t, len(s.Data)+adtsHeaderSize, frameLength)
+		assert.Equal(t, s.Data, data[adtsHeaderSize:frameLength])
+		data = data[frameLength:]
+	}
+	assert.Empty(t, data)
+
+	assert.Error(t, ExtractADTS(f, &bytes.Buffer{}, 1))
+}
+
+func TestParseADTSConfig(t *testing.T) {
+	newEsds := func(asc []byte) *Esds {
+		return &Esds{Descriptors: []Descriptor{
+			{Tag: DecoderConfigDescrTag, DecoderConfigDescriptor: &DecoderConfigDescriptor{ObjectTypeIndication: 0x40}},
+			{Tag: DecSpecificInfoTag, Data: asc},
+		}}
+	}
+
+	// AAC LC, 48000 Hz, stereo
+	config, err := parseADTSConfig(newEsds([]byte{0x11, 0x90}))
+	require.NoError(t, err)
+	assert.Equal(t, &adtsConfig{audioObjectType: 2, samplingFrequencyIndex: 3, channelConfiguration: 2}, config)
+
+	// HE-AAC with explicit SBR signaling, 24000 Hz core, stereo
+	config, err = parseADTSConfig(newEsds([]byte{0x2b, 0x11, 0x88, 0x00}))
+	require.NoError(t, err)
+	assert.Equal(t, &adtsConfig{audioObjectType: 2, samplingFrequencyIndex: 6, channelConfiguration: 2}, config)
+
+	header, err := config.header(100)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0xff, 0xf1, 0x58, 0x80, 0x0d, 0x7f, 0xfc}, header)
+	_, err = config.header(0x2000)
+	assert.Error(t, err)
+
+	// channel configuration 0 (program config element)
+	_, err = parseADTSConfig(newEsds([]byte{0x11, 0x80}))
+	assert.Error(t, err)
+}
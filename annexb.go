@@ -0,0 +1,216 @@
+package mp4
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+var annexBStartCode = []byte{0x00, 0x00, 0x00, 0x01}
+
+// annexBConfig holds the decoder configuration of a sample entry.
+type annexBConfig struct {
+	hevc          bool
+	lengthSize    int
+	parameterSets [][]byte
+}
+
+type annexBTrack struct {
+	trackID uint32
+	// configs holds decoder configurations in order of sample entries.
+	configs []*annexBConfig
+}
+
+// ExtractAnnexB reads samples of the H.264 or H.265 track and writes them to w as an Annex B byte stream.
+// Length-prefixed NAL units are converted to start code prefixed ones, and parameter sets
+// (VPS, SPS and PPS) in avcC or hvcC box are inserted into each access unit of IDR pictures.
+// The first AVC or HEVC track is used when trackID is zero.
+func ExtractAnnexB(r io.ReadSeeker, w io.Writer, trackID uint32) error {
+	track, err := readAnnexBTrack(r, trackID)
+	if err != nil {
+		return err
+	}
+
+	sr, err := NewSampleReader(r, track.trackID)
+	if err != nil {
+		return err
+	}
+	for {
+		s, err := sr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		index := int(s.SampleDescriptionIndex) - 1
+		if index < 0 || index >= len(track.configs) || track.configs[index] == nil {
+			return fmt.Errorf("sample description not found: index=%d", s.SampleDescriptionIndex)
+		}
+		if err := track.configs[index].writeSample(w, s.Data); err != nil {
+			return err
+		}
+	}
+}
+
+func readAnnexBTrack(r io.ReadSeeker, trackID uint32) (*annexBTrack, error) {
+	var tracks []*annexBTrack
+	var track *annexBTrack
+	_, err := ReadBoxStructure(r, func(h *ReadHandle) (interface{}, error) {
+		if len(h.Path) >= 2 && h.Path[len(h.Path)-2] == BoxTypeStsd() {
+			// each sample entry has a slot even if it is not AVC nor HEVC
+			track.configs = append(track.configs, nil)
+			if !h.BoxInfo.IsSupportedType() {
+				return nil, nil
+			}
+			box, _, err := h.ReadPayload()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := box.(*VisualSampleEntry); ok {
+				return h.Expand()
+			}
+			return nil, nil
+		}
+
+		switch h.BoxInfo.Type {
+		case BoxTypeMoov(), BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd():
+			return h.Expand()
+		case BoxTypeTrak():
+			track = &annexBTrack{}
+			tracks = append(tracks, track)
+			return h.Expand()
+		case BoxTypeTkhd():
+			box, _, err := h.ReadPayload()
+			if err != nil {
+				return nil, err
+			}
+			track.trackID = box.(*Tkhd).TrackID
+		case BoxTypeAvcC(), BoxTypeHvcC():
+			box, _, err := h.ReadPayload()
+			if err != nil {
+				return nil, err
+			}
+			config := &annexBConfig{}
+			switch box := box.(type) {
+			case *AVCDecoderConfiguration:
+				config.lengthSize = int(box.LengthSizeMinusOne) + 1
+				for _, ps := range box.SequenceParameterSets {
+					config.parameterSets = append(config.parameterSets, ps.NALUnit)
+				}
+				for _, ps := range box.SequenceParameterSetsExt {
+					config.parameterSets = append(config.parameterSets, ps.NALUnit)
+				}
+				for _, ps := range box.PictureParameterSets {
+					config.parameterSets = append(config.parameterSets, ps.NALUnit)
+				}
+			case *HvcC:
+				config.hevc = true
+				config.lengthSize = int(box.LengthSizeMinusOne) + 1
+				for _, array := range box.NaluArrays {
+					for _, nalu := range array.Nalus {
+						config.parameterSets = append(config.parameterSets, nalu.NALUnit)
+					}
+				}
+			}
+			track.configs[len(track.configs)-1] = config
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range tracks {
+		if trackID != 0 && t.trackID != trackID {
+			continue
+		}
+		for _, config := range t.configs {
+			if config != nil {
+				return t, nil
+			}
+		}
+		if trackID != 0 {
+			return nil, fmt.Errorf("track is neither AVC nor HEVC: trackID=%d", trackID)
+		}
+	}
+	if trackID != 0 {
+		return nil, fmt.Errorf("track not found: trackID=%d", trackID)
+	}
+	return nil, errors.New("AVC or HEVC track not found")
+}
+
+// isIDR reports whether the NAL unit is an IDR picture (H.264) or an IRAP picture (H.265).
+func (c *annexBConfig) isIDR(nalu []byte) bool {
+	if c.hevc {
+		nalType := (nalu[0] >> 1) & 0x3f
+		return nalType >= 16 && nalType <= 23
+	}
+	return nalu[0]&0x1f == 5
+}
+
+// isParameterSet reports whether the NAL unit is a VPS, SPS or PPS.
+func (c *annexBConfig) isParameterSet(nalu []byte) bool {
+	if c.hevc {
+		nalType := (nalu[0] >> 1) & 0x3f
+		return nalType >= 32 && nalType <= 34
+	}
+	nalType := nalu[0] & 0x1f
+	return nalType == 7 || nalType == 8 || nalType == 13
+}
+
+// isAUD reports whether the NAL unit is an access unit delimiter.
+func (c *annexBConfig) isAUD(nalu []byte) bool {
+	if c.hevc {
+		return (nalu[0]>>1)&0x3f == 35
+	}
+	return nalu[0]&0x1f == 9
+}
+
+func (c *annexBConfig) writeSample(w io.Writer, data []byte) error {
+	var nalus [][]byte
+	var hasIDR, hasParameterSets bool
+	for offset := 0; offset < len(data); {
+		if offset+c.lengthSize > len(data) {
+			return errors.New("truncated NAL unit length")
+		}
+		var length int
+		for i := 0; i < c.lengthSize; i++ {
+			length = length<<8 | int(data[offset+i])
+		}
+		offset += c.lengthSize
+		if length == 0 || offset+length > len(data) {
+			return fmt.Errorf("invalid NAL unit length: %d", length)
+		}
+		nalu := data[offset : offset+length]
+		hasIDR = hasIDR || c.isIDR(nalu)
+		hasParameterSets = hasParameterSets || c.isParameterSet(nalu)
+		nalus = append(nalus, nalu)
+		offset += length
+	}
+
+	// parameter sets are placed at the head of the access unit next to the access unit delimiter,
+	// and in-band parameter sets (avc3 or hev1) take priority over ones in the configuration
+	inserted := !hasIDR || hasParameterSets
+	for _, nalu := range nalus {
+		if !inserted && !c.isAUD(nalu) {
+			for _, ps := range c.parameterSets {
+				if err := writeAnnexBNALUnit(w, ps); err != nil {
+					return err
+				}
+			}
+			inserted = true
+		}
+		if err := writeAnnexBNALUnit(w, nalu); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeAnnexBNALUnit(w io.Writer, nalu []byte) error {
+	if _, err := w.Write(annexBStartCode); err != nil {
+		return err
+	}
+	_, err := w.Write(nalu)
+	return err
+}
@@ -0,0 +1,96 @@
+package mp4
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractAnnexB(t *testing.T) {
+	for _, file := range []string{"./testdata/sample.mp4", "./testdata/sample_fragmented.mp4"} {
+		t.Run(file, func(t *testing.T) {
+			f, err := os.Open(file)
+			require.NoError(t, err)
+			defer f.Close()
+
+			buf := &bytes.Buffer{}
+			require.NoError(t, ExtractAnnexB(f, buf, 0))
+			nalus := bytes.Split(buf.Bytes(), annexBStartCode)
+			require.Greater(t, len(nalus), 1)
+			assert.Empty(t, nalus[0])
+			nalus = nalus[1:]
+
+			sr, err := NewSampleReader(f, 1)
+			require.NoError(t, err)
+			var idrs, spss int
+			for _, nalu := range nalus {
+				switch nalu[0] & 0x1f {
+				case 5:
+					idrs++
+				case 7:
+					spss++
+				}
+			}
+			if nalus[0][0]&0x1f == 9 {
+				// access unit delimiter
+				nalus = nalus[1:]
+			}
+			assert.Equal(t, 0x67, int(nalus[0][0]))
+			assert.Equal(t, 0x68, int(nalus[1][0]))
+			assert.NotZero(t, idrs)
+			assert.Equal(t, idrs, spss)
+			var syncs int
+			for _, s := range sr.Samples() {
+				if s.IsSync {
+					syncs++
+				}
+			}
+			assert.Equal(t, syncs, idrs)
+		})
+	}
+
+	t.Run("not video", func(t *testing.T) {
+		f, err := os.Open("./testdata/sample.mp4")
+		require.NoError(t, err)
+		defer f.Close()
+		assert.Error(t, ExtractAnnexB(f, &bytes.Buffer{}, 2))
+		assert.Error(t, ExtractAnnexB(f, &bytes.Buffer{}, 3))
+	})
+}
+
+func TestAnnexBConfigWriteSample(t *testing.T) {
+	config := &annexBConfig{
+		hevc:          true,
+		lengthSize:    2,
+		parameterSets: [][]byte{{0x40, 0x01, 0xaa}, {0x42, 0x01, 0xbb}, {0x44, 0x01, 0xcc}},
+	}
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, config.writeSample(buf, []byte{
+		0x00, 0x03, 0x46, 0x01, 0x50, // AUD
+		0x00, 0x03, 0x26, 0x01, 0xdd, // IDR_W_RADL
+	}))
+	assert.Equal(t, []byte{
+		0x00, 0x00, 0x00, 0x01, 0x46, 0x01, 0x50,
+		0x00, 0x00, 0x00, 0x01, 0x40, 0x01, 0xaa,
+		0x00, 0x00, 0x00, 0x01, 0x42, 0x01, 0xbb,
+		0x00, 0x00, 0x00, 0x01, 0x44, 0x01, 0xcc,
+		0x00, 0x00, 0x00, 0x01, 0x26, 0x01, 0xdd,
+	}, buf.Bytes())
+
+	// in-band parameter sets
+	buf.Reset()
+	require.NoError(t, config.writeSample(buf, []byte{
+		0x00, 0x03, 0x42, 0x01, 0xee, // SPS
+		0x00, 0x03, 0x26, 0x01, 0xdd, // IDR_W_RADL
+	}))
+	assert.Equal(t, []byte{
+		0x00, 0x00, 0x00, 0x01, 0x42, 0x01, 0xee,
+		0x00, 0x00, 0x00, 0x01, 0x26, 0x01, 0xdd,
+	}, buf.Bytes())
+
+	assert.Error(t, config.writeSample(buf, []byte{0x00, 0x05, 0x26, 0x01}))
+}
@@ -0,0 +1,434 @@
+package mp4
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/Spidey120703/go-mp4/internal/bitio"
+)
+
+// AV1 OBU types
+const (
+	AV1OBUSequenceHeader       = 1
+	AV1OBUTemporalDelimiter    = 2
+	AV1OBUFrameHeader          = 3
+	AV1OBUTileGroup            = 4
+	AV1OBUMetadata             = 5
+	AV1OBUFrame                = 6
+	AV1OBURedundantFrameHeader = 7
+	AV1OBUTileList             = 8
+	AV1OBUPadding              = 15
+)
+
+// AV1OBU is an open bitstream unit of AV1.
+type AV1OBU struct {
+	Type       uint8
+	TemporalID uint8
+	SpatialID  uint8
+	Payload    []byte
+}
+
+// ReadAV1OBUs splits the data in the low overhead bitstream format into OBUs.
+// The last OBU may omit obu_size, and then it extends to the end of the data.
+func ReadAV1OBUs(data []byte) ([]AV1OBU, error) {
+	var obus []AV1OBU
+	for len(data) != 0 {
+		header := data[0]
+		if header&0x80 != 0 {
+			return nil, errors.New("obu_forbidden_bit is set")
+		}
+		obu := AV1OBU{Type: (header >> 3) & 0x0f}
+		offset := 1
+		if header&0x04 != 0 { // obu_extension_flag
+			if len(data) < 2 {
+				return nil, io.ErrUnexpectedEOF
+			}
+			obu.TemporalID = data[1] >> 5
+			obu.SpatialID = (data[1] >> 3) & 0x03
+			offset++
+		}
+		size := uint64(len(data) - offset)
+		if header&0x02 != 0 { // obu_has_size_field
+			var n int
+			var err error
+			size, n, err = readLEB128(data[offset:])
+			if err != nil {
+				return nil, err
+			}
+			offset += n
+			if size > uint64(len(data)-offset) {
+				return nil, io.ErrUnexpectedEOF
+			}
+		}
+		obu.Payload = data[offset : offset+int(size)]
+		obus = append(obus, obu)
+		data = data[offset+int(size):]
+	}
+	return obus, nil
+}
+
+// readLEB128 reads leb128() of AV1, and returns the value and the number of read bytes.
+func readLEB128(data []byte) (uint64, int, error) {
+	var v uint64
+	for i := 0; i < 8; i++ {
+		if i >= len(data) {
+			return 0, 0, io.ErrUnexpectedEOF
+		}
+		v |= uint64(data[i]&0x7f) << (uint(i) * 7)
+		if data[i]&0x80 == 0 {
+			return v, i + 1, nil
+		}
+	}
+	return 0, 0, errors.New("invalid leb128")
+}
+
+// AV1OperatingPoint is the parameters of an operating point in the sequence header.
+type AV1OperatingPoint struct {
+	Idc         uint16
+	SeqLevelIdx uint8
+	SeqTier     bool
+}
+
+// AV1SequenceHeader is the sequence header OBU of AV1.
+type AV1SequenceHeader struct {
+	SeqProfile                    uint8
+	StillPicture                  bool
+	ReducedStillPictureHeader     bool
+	TimingInfoPresent             bool
+	NumUnitsInDisplayTick         uint32
+	TimeScale                     uint32
+	EqualPictureInterval          bool
+	NumTicksPerPictureMinus1      uint32
+	DecoderModelInfoPresent       bool
+	InitialDisplayDelayPresent    bool
+	OperatingPoints               []AV1OperatingPoint
+	FrameWidthBitsMinus1          uint8
+	FrameHeightBitsMinus1         uint8
+	MaxFrameWidthMinus1           uint32
+	MaxFrameHeightMinus1          uint32
+	FrameIDNumbersPresent         bool
+	DeltaFrameIDLengthMinus2      uint8
+	AdditionalFrameIDLengthMinus1 uint8
+	Use128x128Superblock          bool
+	EnableFilterIntra             bool
+	EnableIntraEdgeFilter         bool
+	EnableInterintraCompound      bool
+	EnableMaskedCompound          bool
+	EnableWarpedMotion            bool
+	EnableDualFilter              bool
+	EnableOrderHint               bool
+	EnableJntComp                 bool
+	EnableRefFrameMvs             bool
+	OrderHintBits                 uint8
+	EnableSuperres                bool
+	EnableCdef                    bool
+	EnableRestoration             bool
+	ColorConfig                   AV1ColorConfig
+	FilmGrainParamsPresent        bool
+}
+
+// AV1ColorConfig is color_config() of the sequence header.
+type AV1ColorConfig struct {
+	BitDepth                uint8
+	MonoChrome              bool
+	ColorDescriptionPresent bool
+	ColorPrimaries          uint8
+	TransferCharacteristics uint8
+	MatrixCoefficients      uint8
+	ColorRange              bool
+	SubsamplingX            bool
+	SubsamplingY            bool
+	ChromaSamplePosition    uint8
+	SeparateUVDeltaQ        bool
+}
+
+// ParseAV1SequenceHeader parses the payload of the sequence header OBU.
+func ParseAV1SequenceHeader(payload []byte) (*AV1SequenceHeader, error) {
+	// AV1 has no emulation prevention, so the payload is read as it is
+	r := &rbspReader{Reader: bitio.NewReader(bytes.NewReader(payload))}
+
+	seq := &AV1SequenceHeader{}
+	seq.SeqProfile = uint8(r.u(3))
+	if seq.SeqProfile > 2 {
+		return nil, fmt.Errorf("unsupported seq_profile: %d", seq.SeqProfile)
+	}
+	seq.StillPicture = r.flag()
+	seq.ReducedStillPictureHeader = r.flag()
+	if seq.ReducedStillPictureHeader {
+		seq.OperatingPoints = []AV1OperatingPoint{{SeqLevelIdx: uint8(r.u(5))}}
+	} else {
+		var bufferDelayLength uint
+		seq.TimingInfoPresent = r.flag()
+		if seq.TimingInfoPresent {
+			seq.NumUnitsInDisplayTick = uint32(r.u(32))
+			seq.TimeScale = uint32(r.u(32))
+			seq.EqualPictureInterval = r.flag()
+			if seq.EqualPictureInterval {
+				seq.NumTicksPerPictureMinus1 = uint32(r.ue()) // uvlc
+			}
+			seq.DecoderModelInfoPresent = r.flag()
+			if seq.DecoderModelInfoPresent {
+				bufferDelayLength = uint(r.u(5)) + 1
+				r.u(32) // num_units_in_decoding_tick
+				r.u(5)  // buffer_removal_time_length_minus_1
+				r.u(5)  // frame_presentation_time_length_minus_1
+			}
+		}
+		seq.InitialDisplayDelayPresent = r.flag()
+		seq.OperatingPoints = make([]AV1OperatingPoint, r.u(5)+1)
+		for i := range seq.OperatingPoints {
+			op := &seq.OperatingPoints[i]
+			op.Idc = uint16(r.u(12))
+			op.SeqLevelIdx = uint8(r.u(5))
+			if op.SeqLevelIdx > 7 {
+				op.SeqTier = r.flag()
+			}
+			if seq.DecoderModelInfoPresent {
+				if r.flag() { // decoder_model_present_for_this_op
+					r.u(bufferDelayLength) // decoder_buffer_delay
+					r.u(bufferDelayLength) // encoder_buffer_delay
+					r.flag()               // low_delay_mode_flag
+				}
+			}
+			if seq.InitialDisplayDelayPresent {
+				if r.flag() { // initial_display_delay_present_for_this_op
+					r.u(4) // initial_display_delay_minus_1
+				}
+			}
+		}
+	}
+	seq.FrameWidthBitsMinus1 = uint8(r.u(4))
+	seq.FrameHeightBitsMinus1 = uint8(r.u(4))
+	seq.MaxFrameWidthMinus1 = uint32(r.u(uint(seq.FrameWidthBitsMinus1) + 1))
+	seq.MaxFrameHeightMinus1 = uint32(r.u(uint(seq.FrameHeightBitsMinus1) + 1))
+	if !seq.ReducedStillPictureHeader {
+		seq.FrameIDNumbersPresent = r.flag()
+	}
+	if seq.FrameIDNumbersPresent {
+		seq.DeltaFrameIDLengthMinus2 = uint8(r.u(4))
+		seq.AdditionalFrameIDLengthMinus1 = uint8(r.u(3))
+	}
+	seq.Use128x128Superblock = r.flag()
+	seq.EnableFilterIntra = r.flag()
+	seq.EnableIntraEdgeFilter = r.flag()
+	if !seq.ReducedStillPictureHeader {
+		seq.EnableInterintraCompound = r.flag()
+		seq.EnableMaskedCompound = r.flag()
+		seq.EnableWarpedMotion = r.flag()
+		seq.EnableDualFilter = r.flag()
+		seq.EnableOrderHint = r.flag()
+		if seq.EnableOrderHint {
+			seq.EnableJntComp = r.flag()
+			seq.EnableRefFrameMvs = r.flag()
+		}
+		forceScreenContentTools := true
+		if !r.flag() { // seq_choose_screen_content_tools
+			forceScreenContentTools = r.flag()
+		}
+		if forceScreenContentTools {
+			if !r.flag() { // seq_choose_integer_mv
+				r.flag() // seq_force_integer_mv
+			}
+		}
+		if seq.EnableOrderHint {
+			seq.OrderHintBits = uint8(r.u(3)) + 1
+		}
+	}
+	seq.EnableSuperres = r.flag()
+	seq.EnableCdef = r.flag()
+	seq.EnableRestoration = r.flag()
+	seq.ColorConfig = r.readAV1ColorConfig(seq.SeqProfile)
+	seq.FilmGrainParamsPresent = r.flag()
+	if r.err != nil {
+		return nil, r.err
+	}
+	return seq, nil
+}
+
+// readAV1ColorConfig reads color_config() of AV1.
+func (r *rbspReader) readAV1ColorConfig(seqProfile uint8) AV1ColorConfig {
+	// CP_UNSPECIFIED, TC_UNSPECIFIED and MC_UNSPECIFIED
+	cc := AV1ColorConfig{ColorPrimaries: 2, TransferCharacteristics: 2, MatrixCoefficients: 2}
+	cc.BitDepth = 8
+	if r.flag() { // high_bitdepth
+		cc.BitDepth = 10
+		if seqProfile == 2 && r.flag() { // twelve_bit
+			cc.BitDepth = 12
+		}
+	}
+	if seqProfile != 1 {
+		cc.MonoChrome = r.flag()
+	}
+	cc.ColorDescriptionPresent = r.flag()
+	if cc.ColorDescriptionPresent {
+		cc.ColorPrimaries = uint8(r.u(8))
+		cc.TransferCharacteristics = uint8(r.u(8))
+		cc.MatrixCoefficients = uint8(r.u(8))
+	}
+	switch {
+	case cc.MonoChrome:
+		cc.ColorRange = r.flag()
+		cc.SubsamplingX, cc.SubsamplingY = true, true
+		return cc
+	case cc.ColorPrimaries == 1 && cc.TransferCharacteristics == 13 && cc.MatrixCoefficients == 0:
+		// sRGB
+		cc.ColorRange = true
+	default:
+		cc.ColorRange = r.flag()
+		switch seqProfile {
+		case 0:
+			cc.SubsamplingX, cc.SubsamplingY = true, true
+		case 1:
+		default:
+			if cc.BitDepth == 12 {
+				cc.SubsamplingX = r.flag()
+				if cc.SubsamplingX {
+					cc.SubsamplingY = r.flag()
+				}
+			} else {
+				cc.SubsamplingX = true
+			}
+		}
+		if cc.SubsamplingX && cc.SubsamplingY {
+			cc.ChromaSamplePosition = uint8(r.u(2))
+		}
+	}
+	cc.SeparateUVDeltaQ = r.flag()
+	return cc
+}
+
+// MaxFrameWidth returns the maximum width of the frames.
+func (seq *AV1SequenceHeader) MaxFrameWidth() uint32 {
+	return seq.MaxFrameWidthMinus1 + 1
+}
+
+// MaxFrameHeight returns the maximum height of the frames.
+func (seq *AV1SequenceHeader) MaxFrameHeight() uint32 {
+	return seq.MaxFrameHeightMinus1 + 1
+}
+
+// FrameRate returns the frame rate derived from the timing information.
+// It returns 0 if the pictures are not equally spaced.
+func (seq *AV1SequenceHeader) FrameRate() float64 {
+	if !seq.EqualPictureInterval || seq.NumUnitsInDisplayTick == 0 {
+		return 0
+	}
+	return float64(seq.TimeScale) / (float64(seq.NumUnitsInDisplayTick) * (float64(seq.NumTicksPerPictureMinus1) + 1))
+}
+
+// DecodeSequenceHeader decodes the sequence header OBU in ConfigOBUs.
+// It returns nil without error if ConfigOBUs has no sequence header.
+func (av1C *Av1C) DecodeSequenceHeader() (*AV1SequenceHeader, error) {
+	obus, err := ReadAV1OBUs(av1C.ConfigOBUs)
+	if err != nil {
+		return nil, err
+	}
+	for _, obu := range obus {
+		if obu.Type == AV1OBUSequenceHeader {
+			return ParseAV1SequenceHeader(obu.Payload)
+		}
+	}
+	return nil, nil
+}
+
+// AV1FrameType is frame_type of AV1.
+type AV1FrameType uint8
+
+const (
+	AV1KeyFrame       AV1FrameType = 0
+	AV1InterFrame     AV1FrameType = 1
+	AV1IntraOnlyFrame AV1FrameType = 2
+	AV1SwitchFrame    AV1FrameType = 3
+)
+
+func (t AV1FrameType) String() string {
+	switch t {
+	case AV1KeyFrame:
+		return "KEY_FRAME"
+	case AV1InterFrame:
+		return "INTER_FRAME"
+	case AV1IntraOnlyFrame:
+		return "INTRA_ONLY_FRAME"
+	case AV1SwitchFrame:
+		return "SWITCH_FRAME"
+	}
+	return fmt.Sprintf("AV1FrameType(%d)", uint8(t))
+}
+
+// AV1FrameHeader is the leading fields of the uncompressed header of a frame.
+type AV1FrameHeader struct {
+	ShowExistingFrame bool
+	// FrameToShowMapIdx is valid only if ShowExistingFrame is true.
+	FrameToShowMapIdx uint8
+	// FrameType and ShowFrame are valid only if ShowExistingFrame is false.
+	FrameType AV1FrameType
+	ShowFrame bool
+}
+
+// AV1TemporalUnit is the OBUs of a temporal unit which are needed to classify the frames.
+type AV1TemporalUnit struct {
+	// SequenceHeader is the sequence header OBU in the temporal unit, or nil if it is absent.
+	SequenceHeader *AV1SequenceHeader
+	// FrameHeaders doesn't include redundant frame headers.
+	FrameHeaders []AV1FrameHeader
+}
+
+// ParseAV1TemporalUnit parses the temporal unit, which is a sample of av01 track.
+// seq is the active sequence header, which is used unless the temporal unit has its own sequence header.
+func ParseAV1TemporalUnit(data []byte, seq *AV1SequenceHeader) (*AV1TemporalUnit, error) {
+	obus, err := ReadAV1OBUs(data)
+	if err != nil {
+		return nil, err
+	}
+	tu := &AV1TemporalUnit{}
+	for _, obu := range obus {
+		switch obu.Type {
+		case AV1OBUSequenceHeader:
+			if tu.SequenceHeader, err = ParseAV1SequenceHeader(obu.Payload); err != nil {
+				return nil, err
+			}
+			seq = tu.SequenceHeader
+		case AV1OBUFrameHeader, AV1OBUFrame:
+			if seq == nil {
+				return nil, errors.New("sequence header not found")
+			}
+			header, err := parseAV1FrameHeader(obu.Payload, seq)
+			if err != nil {
+				return nil, err
+			}
+			tu.FrameHeaders = append(tu.FrameHeaders, *header)
+		}
+	}
+	return tu, nil
+}
+
+// IsKeyFrame reports whether the first frame of the temporal unit is a key frame with show_frame set,
+// which makes the temporal unit a sync sample.
+func (tu *AV1TemporalUnit) IsKeyFrame() bool {
+	return len(tu.FrameHeaders) != 0 &&
+		!tu.FrameHeaders[0].ShowExistingFrame &&
+		tu.FrameHeaders[0].FrameType == AV1KeyFrame &&
+		tu.FrameHeaders[0].ShowFrame
+}
+
+// parseAV1FrameHeader reads the leading fields of uncompressed_header().
+func parseAV1FrameHeader(payload []byte, seq *AV1SequenceHeader) (*AV1FrameHeader, error) {
+	if seq.ReducedStillPictureHeader {
+		return &AV1FrameHeader{FrameType: AV1KeyFrame, ShowFrame: true}, nil
+	}
+	r := &rbspReader{Reader: bitio.NewReader(bytes.NewReader(payload))}
+	header := &AV1FrameHeader{}
+	header.ShowExistingFrame = r.flag()
+	if header.ShowExistingFrame {
+		header.FrameToShowMapIdx = uint8(r.u(3))
+	} else {
+		header.FrameType = AV1FrameType(r.u(2))
+		header.ShowFrame = r.flag()
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return header, nil
+}
@@ -0,0 +1,357 @@
+package mp4
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/orcaman/writerseeker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testAV1SequenceHeader is the payload of a sequence header OBU of 1920x1080 10-bit 29.97 fps video.
+var testAV1SequenceHeader = buildTestBits(
+	[2]uint64{0, 3},      // seq_profile
+	[2]uint64{0, 1},      // still_picture
+	[2]uint64{0, 1},      // reduced_still_picture_header
+	[2]uint64{1, 1},      // timing_info_present_flag
+	[2]uint64{1001, 32},  // num_units_in_display_tick
+	[2]uint64{30000, 32}, // time_scale
+	[2]uint64{1, 1},      // equal_picture_interval
+	testUE(0),            // num_ticks_per_picture_minus_1
+	[2]uint64{0, 1},      // decoder_model_info_present_flag
+	[2]uint64{0, 1},      // initial_display_delay_present_flag
+	[2]uint64{0, 5},      // operating_points_cnt_minus_1
+	[2]uint64{0, 12},     // operating_point_idc[0]
+	[2]uint64{8, 5},      // seq_level_idx[0]
+	[2]uint64{1, 1},      // seq_tier[0]
+	[2]uint64{10, 4},     // frame_width_bits_minus_1
+	[2]uint64{10, 4},     // frame_height_bits_minus_1
+	[2]uint64{1919, 11},  // max_frame_width_minus_1
+	[2]uint64{1079, 11},  // max_frame_height_minus_1
+	[2]uint64{0, 1},      // frame_id_numbers_present_flag
+	[2]uint64{0, 1},      // use_128x128_superblock
+	[2]uint64{1, 1},      // enable_filter_intra
+	[2]uint64{1, 1},      // enable_intra_edge_filter
+	[2]uint64{1, 1},      // enable_interintra_compound
+	[2]uint64{1, 1},      // enable_masked_compound
+	[2]uint64{1, 1},      // enable_warped_motion
+	[2]uint64{0, 1},      // enable_dual_filter
+	[2]uint64{1, 1},      // enable_order_hint
+	[2]uint64{1, 1},      // enable_jnt_comp
+	[2]uint64{1, 1},      // enable_ref_frame_mvs
+	[2]uint64{1, 1},      // seq_choose_screen_content_tools
+	[2]uint64{1, 1},      // seq_choose_integer_mv
+	[2]uint64{6, 3},      // order_hint_bits_minus_1
+	[2]uint64{0, 1},      // enable_superres
+	[2]uint64{1, 1},      // enable_cdef
+	[2]uint64{1, 1},      // enable_restoration
+	[2]uint64{1, 1},      // high_bitdepth
+	[2]uint64{0, 1},      // mono_chrome
+	[2]uint64{1, 1},      // color_description_present_flag
+	[2]uint64{9, 8},      // color_primaries
+	[2]uint64{16, 8},     // transfer_characteristics
+	[2]uint64{9, 8},      // matrix_coefficients
+	[2]uint64{0, 1},      // color_range
+	[2]uint64{1, 2},      // chroma_sample_position
+	[2]uint64{0, 1},      // separate_uv_delta_q
+	[2]uint64{0, 1},      // film_grain_params_present
+	[2]uint64{1, 1},      // trailing_one_bit
+)
+
+// testAV1OBU returns the OBU with obu_has_size_field.
+func testAV1OBU(obuType uint8, payload []byte) []byte {
+	obu := []byte{obuType<<3 | 0x02}
+	size := len(payload)
+	for size >= 0x80 {
+		obu = append(obu, byte(size&0x7f)|0x80)
+		size >>= 7
+	}
+	obu = append(obu, byte(size))
+	return append(obu, payload...)
+}
+
+func TestReadAV1OBUs(t *testing.T) {
+	data := []byte{
+		0x12, 0x00, // temporal delimiter
+		0x2e, 0x48, 0x02, 0xaa, 0xbb, // metadata with extension: temporal_id=2 spatial_id=1
+		0x78, 0x00, // padding without obu_size
+	}
+	obus, err := ReadAV1OBUs(data)
+	require.NoError(t, err)
+	assert.Equal(t, []AV1OBU{
+		{Type: AV1OBUTemporalDelimiter, Payload: []byte{}},
+		{Type: AV1OBUMetadata, TemporalID: 2, SpatialID: 1, Payload: []byte{0xaa, 0xbb}},
+		{Type: AV1OBUPadding, Payload: []byte{0x00}},
+	}, obus)
+
+	obus, err = ReadAV1OBUs(testAV1OBU(AV1OBUPadding, make([]byte, 300)))
+	require.NoError(t, err)
+	require.Len(t, obus, 1)
+	assert.Len(t, obus[0].Payload, 300)
+
+	_, err = ReadAV1OBUs([]byte{0x32, 0x05, 0x00})
+	assert.Error(t, err)
+	_, err = ReadAV1OBUs([]byte{0x92, 0x00})
+	assert.Error(t, err)
+	_, err = ReadAV1OBUs([]byte{0x32, 0x80})
+	assert.Error(t, err)
+}
+
+func TestParseAV1SequenceHeader(t *testing.T) {
+	seq, err := ParseAV1SequenceHeader(testAV1SequenceHeader)
+	require.NoError(t, err)
+	assert.Equal(t, &AV1SequenceHeader{
+		TimingInfoPresent:        true,
+		NumUnitsInDisplayTick:    1001,
+		TimeScale:                30000,
+		EqualPictureInterval:     true,
+		OperatingPoints:          []AV1OperatingPoint{{SeqLevelIdx: 8, SeqTier: true}},
+		FrameWidthBitsMinus1:     10,
+		FrameHeightBitsMinus1:    10,
+		MaxFrameWidthMinus1:      1919,
+		MaxFrameHeightMinus1:     1079,
+		EnableFilterIntra:        true,
+		EnableIntraEdgeFilter:    true,
+		EnableInterintraCompound: true,
+		EnableMaskedCompound:     true,
+		EnableWarpedMotion:       true,
+		EnableOrderHint:          true,
+		EnableJntComp:            true,
+		EnableRefFrameMvs:        true,
+		OrderHintBits:            7,
+		EnableCdef:               true,
+		EnableRestoration:        true,
+		ColorConfig: AV1ColorConfig{
+			BitDepth:                10,
+			ColorDescriptionPresent: true,
+			ColorPrimaries:          9,
+			TransferCharacteristics: 16,
+			MatrixCoefficients:      9,
+			SubsamplingX:            true,
+			SubsamplingY:            true,
+			ChromaSamplePosition:    1,
+		},
+	}, seq)
+	assert.Equal(t, uint32(1920), seq.MaxFrameWidth())
+	assert.Equal(t, uint32(1080), seq.MaxFrameHeight())
+	assert.InDelta(t, 29.97, seq.FrameRate(), 0.001)
+
+	// reduced still picture header
+	seq, err = ParseAV1SequenceHeader(buildTestBits(
+		[2]uint64{0, 3},   // seq_profile
+		[2]uint64{1, 1},   // still_picture
+		[2]uint64{1, 1},   // reduced_still_picture_header
+		[2]uint64{5, 5},   // seq_level_idx[0]
+		[2]uint64{7, 4},   // frame_width_bits_minus_1
+		[2]uint64{7, 4},   // frame_height_bits_minus_1
+		[2]uint64{255, 8}, // max_frame_width_minus_1
+		[2]uint64{127, 8}, // max_frame_height_minus_1
+		[2]uint64{0, 1},   // use_128x128_superblock
+		[2]uint64{0, 1},   // enable_filter_intra
+		[2]uint64{0, 1},   // enable_intra_edge_filter
+		[2]uint64{0, 1},   // enable_superres
+		[2]uint64{0, 1},   // enable_cdef
+		[2]uint64{0, 1},   // enable_restoration
+		[2]uint64{0, 1},   // high_bitdepth
+		[2]uint64{1, 1},   // mono_chrome
+		[2]uint64{0, 1},   // color_description_present_flag
+		[2]uint64{1, 1},   // color_range
+		[2]uint64{0, 1},   // film_grain_params_present
+		[2]uint64{1, 1},   // trailing_one_bit
+	))
+	require.NoError(t, err)
+	assert.Equal(t, &AV1SequenceHeader{
+		StillPicture:              true,
+		ReducedStillPictureHeader: true,
+		OperatingPoints:           []AV1OperatingPoint{{SeqLevelIdx: 5}},
+		FrameWidthBitsMinus1:      7,
+		FrameHeightBitsMinus1:     7,
+		MaxFrameWidthMinus1:       255,
+		MaxFrameHeightMinus1:      127,
+		ColorConfig: AV1ColorConfig{
+			BitDepth:                8,
+			MonoChrome:              true,
+			ColorPrimaries:          2,
+			TransferCharacteristics: 2,
+			MatrixCoefficients:      2,
+			ColorRange:              true,
+			SubsamplingX:            true,
+			SubsamplingY:            true,
+		},
+	}, seq)
+	assert.Equal(t, float64(0), seq.FrameRate())
+
+	_, err = ParseAV1SequenceHeader([]byte{0xe0})
+	assert.Error(t, err)
+	_, err = ParseAV1SequenceHeader(testAV1SequenceHeader[:10])
+	assert.Error(t, err)
+}
+
+func TestAv1CDecodeSequenceHeader(t *testing.T) {
+	av1C := &Av1C{ConfigOBUs: testAV1OBU(AV1OBUSequenceHeader, testAV1SequenceHeader)}
+	seq, err := av1C.DecodeSequenceHeader()
+	require.NoError(t, err)
+	require.NotNil(t, seq)
+	assert.Equal(t, uint32(1920), seq.MaxFrameWidth())
+
+	av1C = &Av1C{}
+	seq, err = av1C.DecodeSequenceHeader()
+	require.NoError(t, err)
+	assert.Nil(t, seq)
+}
+
+func TestParseAV1TemporalUnit(t *testing.T) {
+	seq, err := ParseAV1SequenceHeader(testAV1SequenceHeader)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name         string
+		data         []byte
+		frameHeaders []AV1FrameHeader
+		isKeyFrame   bool
+	}{
+		{
+			name: "key frame",
+			data: bytes.Join([][]byte{
+				testAV1OBU(AV1OBUTemporalDelimiter, nil),
+				testAV1OBU(AV1OBUFrame, []byte{0x10, 0xff}),
+			}, nil),
+			frameHeaders: []AV1FrameHeader{{FrameType: AV1KeyFrame, ShowFrame: true}},
+			isKeyFrame:   true,
+		},
+		{
+			name: "inter frame",
+			data: bytes.Join([][]byte{
+				testAV1OBU(AV1OBUTemporalDelimiter, nil),
+				testAV1OBU(AV1OBUFrame, []byte{0x30, 0xff}),
+			}, nil),
+			frameHeaders: []AV1FrameHeader{{FrameType: AV1InterFrame, ShowFrame: true}},
+		},
+		{
+			name: "hidden key frame and show existing frame",
+			data: bytes.Join([][]byte{
+				testAV1OBU(AV1OBUTemporalDelimiter, nil),
+				testAV1OBU(AV1OBUFrameHeader, []byte{0x00}),
+				testAV1OBU(AV1OBUTileGroup, []byte{0xff}),
+				testAV1OBU(AV1OBUFrameHeader, []byte{0xb0}),
+			}, nil),
+			frameHeaders: []AV1FrameHeader{
+				{FrameType: AV1KeyFrame},
+				{ShowExistingFrame: true, FrameToShowMapIdx: 3},
+			},
+		},
+		{
+			name: "intra only frame",
+			data: testAV1OBU(AV1OBUFrame, []byte{0x50}),
+			frameHeaders: []AV1FrameHeader{
+				{FrameType: AV1IntraOnlyFrame, ShowFrame: true},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tu, err := ParseAV1TemporalUnit(tc.data, seq)
+			require.NoError(t, err)
+			assert.Nil(t, tu.SequenceHeader)
+			assert.Equal(t, tc.frameHeaders, tu.FrameHeaders)
+			assert.Equal(t, tc.isKeyFrame, tu.IsKeyFrame())
+		})
+	}
+
+	// the sequence header in the temporal unit is used
+	tu, err := ParseAV1TemporalUnit(bytes.Join([][]byte{
+		testAV1OBU(AV1OBUTemporalDelimiter, nil),
+		testAV1OBU(AV1OBUSequenceHeader, testAV1SequenceHeader),
+		testAV1OBU(AV1OBUFrame, []byte{0x10, 0xff}),
+	}, nil), nil)
+	require.NoError(t, err)
+	assert.Equal(t, seq, tu.SequenceHeader)
+	assert.True(t, tu.IsKeyFrame())
+
+	_, err = ParseAV1TemporalUnit(testAV1OBU(AV1OBUFrame, []byte{0x10}), nil)
+	assert.Error(t, err)
+
+	assert.Equal(t, "INTRA_ONLY_FRAME", AV1IntraOnlyFrame.String())
+	assert.Equal(t, "AV1FrameType(4)", AV1FrameType(4).String())
+}
+
+// buildTestAV1File builds the progressive file which has an av01 track of the samples.
+func buildTestAV1File(t *testing.T, samples [][]byte, configOBUs []byte) io.ReadSeeker {
+	sizes := make([]uint32, len(samples))
+	for i, sample := range samples {
+		sizes[i] = uint32(len(sample))
+	}
+	const mdatOffset = 16 // after the ftyp box
+
+	buf := &writerseeker.WriterSeeker{}
+	w := NewWriter(buf)
+	ctx := Context{}
+	writeTestBox(t, w, &Ftyp{MajorBrand: [4]byte{'i', 's', 'o', 'm'}}, ctx, nil)
+	writeTestBox(t, w, &Mdat{Data: bytes.Join(samples, nil)}, ctx, nil)
+	writeTestBox(t, w, &Moov{}, ctx, func() {
+		writeTestBox(t, w, &Mvhd{Timescale: 1000, NextTrackID: 2}, ctx, nil)
+		writeTestBox(t, w, &Trak{}, ctx, func() {
+			writeTestBox(t, w, &Tkhd{TrackID: 1}, ctx, nil)
+			writeTestBox(t, w, &Mdia{}, ctx, func() {
+				writeTestBox(t, w, &Mdhd{Timescale: 1000}, ctx, nil)
+				writeTestBox(t, w, &Minf{}, ctx, func() {
+					writeTestBox(t, w, &Stbl{}, ctx, func() {
+						writeTestBox(t, w, &Stsd{EntryCount: 1}, ctx, func() {
+							writeTestBox(t, w, &VisualSampleEntry{
+								SampleEntry: SampleEntry{AnyTypeBox: AnyTypeBox{Type: BoxTypeAv01()}, DataReferenceIndex: 1},
+								Width:       320,
+								Height:      240,
+							}, ctx, func() {
+								writeTestBox(t, w, &Av1C{Marker: 1, Version: 1, ConfigOBUs: configOBUs}, ctx, nil)
+							})
+						})
+						writeTestBox(t, w, &Stts{EntryCount: 1, Entries: []SttsEntry{{SampleCount: uint32(len(samples)), SampleDelta: 100}}}, ctx, nil)
+						writeTestBox(t, w, &Stsc{EntryCount: 1, Entries: []StscEntry{{FirstChunk: 1, SamplesPerChunk: uint32(len(samples)), SampleDescriptionIndex: 1}}}, ctx, nil)
+						writeTestBox(t, w, &Stsz{SampleCount: uint32(len(samples)), EntrySize: sizes}, ctx, nil)
+						writeTestBox(t, w, &Stco{EntryCount: 1, ChunkOffset: []uint32{mdatOffset + 8}}, ctx, nil)
+					})
+				})
+			})
+		})
+	})
+	data, err := io.ReadAll(buf.BytesReader())
+	require.NoError(t, err)
+	return bytes.NewReader(data)
+}
+
+func TestFindIDRFramesAV1(t *testing.T) {
+	samples := [][]byte{
+		bytes.Join([][]byte{
+			testAV1OBU(AV1OBUTemporalDelimiter, nil),
+			testAV1OBU(AV1OBUSequenceHeader, testAV1SequenceHeader),
+			testAV1OBU(AV1OBUFrame, []byte{0x10, 0xff}),
+		}, nil),
+		testAV1OBU(AV1OBUFrame, []byte{0x30, 0xff}),
+		{0xff, 0xff, 0xff}, // broken sample
+		testAV1OBU(AV1OBUFrame, []byte{0x10, 0xff}),
+		testAV1OBU(AV1OBUFrame, []byte{0x30, 0xff}),
+	}
+
+	// the sequence header in the first sample is used without av1C
+	r := buildTestAV1File(t, samples, nil)
+	info, err := Probe(r)
+	require.NoError(t, err)
+	require.Len(t, info.Tracks, 1)
+	require.NotNil(t, info.Tracks[0].AV1)
+	assert.Nil(t, info.Tracks[0].AV1.SequenceHeader)
+	idxs, err := FindIDRFrames(r, info.Tracks[0])
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 3}, idxs)
+
+	// the sequence header in av1C is used for the first sample
+	r = buildTestAV1File(t, samples[1:], testAV1OBU(AV1OBUSequenceHeader, testAV1SequenceHeader))
+	info, err = Probe(r)
+	require.NoError(t, err)
+	require.Len(t, info.Tracks, 1)
+	require.NotNil(t, info.Tracks[0].AV1.SequenceHeader)
+	idxs, err = FindIDRFrames(r, info.Tracks[0])
+	require.NoError(t, err)
+	assert.Equal(t, []int{2}, idxs)
+}
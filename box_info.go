@@ -30,6 +30,9 @@ type Context struct {
 	// UnderUdta represents whether current box is under the udta box.
 	UnderUdta bool
 
+	// UnderTref represents whether current box is under the tref box.
+	UnderTref bool
+
 	// UnderStsd represents whether current box is under the stsd box.
 	UnderStsd bool
 
@@ -0,0 +1,49 @@
+package mp4
+
+// https://professional.dolby.com/siteassets/content-creation/dolby-vision-for-content-creators/dolby_vision_bitstreams_within_the_iso_base_media_file_format_dec2017.pdf
+
+/*************************** dvh1, dvhe, dva1, dvav ****************************/
+
+func BoxTypeDvh1() BoxType { return StrToBoxType("dvh1") }
+func BoxTypeDvhe() BoxType { return StrToBoxType("dvhe") }
+func BoxTypeDva1() BoxType { return StrToBoxType("dva1") }
+func BoxTypeDvav() BoxType { return StrToBoxType("dvav") }
+
+func init() {
+	AddAnyTypeBoxDef(&VisualSampleEntry{}, BoxTypeDvh1())
+	AddAnyTypeBoxDef(&VisualSampleEntry{}, BoxTypeDvhe())
+	AddAnyTypeBoxDef(&VisualSampleEntry{}, BoxTypeDva1())
+	AddAnyTypeBoxDef(&VisualSampleEntry{}, BoxTypeDvav())
+}
+
+/*************************** dvcC, dvvC, dvwC ****************************/
+
+// BoxTypeDvcC is for Dolby Vision profiles up to 7.
+func BoxTypeDvcC() BoxType { return StrToBoxType("dvcC") }
+
+// BoxTypeDvvC is for Dolby Vision profiles 8 to 10.
+func BoxTypeDvvC() BoxType { return StrToBoxType("dvvC") }
+
+// BoxTypeDvwC is for Dolby Vision profiles greater than 10.
+func BoxTypeDvwC() BoxType { return StrToBoxType("dvwC") }
+
+func init() {
+	AddAnyTypeBoxDef(&DOVIDecoderConfiguration{}, BoxTypeDvcC())
+	AddAnyTypeBoxDef(&DOVIDecoderConfiguration{}, BoxTypeDvvC())
+	AddAnyTypeBoxDef(&DOVIDecoderConfiguration{}, BoxTypeDvwC())
+}
+
+// DOVIDecoderConfiguration is DOVIDecoderConfigurationRecord
+type DOVIDecoderConfiguration struct {
+	AnyTypeBox
+	DVVersionMajor            uint8     `mp4:"0,size=8"`
+	DVVersionMinor            uint8     `mp4:"1,size=8"`
+	DVProfile                 uint8     `mp4:"2,size=7"`
+	DVLevel                   uint8     `mp4:"3,size=6"`
+	RPUPresentFlag            bool      `mp4:"4,size=1"`
+	ELPresentFlag             bool      `mp4:"5,size=1"`
+	BLPresentFlag             bool      `mp4:"6,size=1"`
+	DVBLSignalCompatibilityID uint8     `mp4:"7,size=4"`
+	Reserved                  uint32    `mp4:"8,size=28,const=0"`
+	Reserved2                 [4]uint32 `mp4:"9,size=32,const=0"`
+}
@@ -0,0 +1,119 @@
+package mp4
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBoxTypesDolbyVision(t *testing.T) {
+	testCases := []struct {
+		name string
+		src  IImmutableBox
+		dst  IBox
+		bin  []byte
+		str  string
+		ctx  Context
+	}{
+		{
+			name: "dvcC",
+			src: &DOVIDecoderConfiguration{
+				AnyTypeBox:                AnyTypeBox{Type: BoxTypeDvcC()},
+				DVVersionMajor:            1,
+				DVVersionMinor:            0,
+				DVProfile:                 5,
+				DVLevel:                   6,
+				RPUPresentFlag:            true,
+				ELPresentFlag:             false,
+				BLPresentFlag:             true,
+				DVBLSignalCompatibilityID: 0,
+			},
+			dst: &DOVIDecoderConfiguration{AnyTypeBox: AnyTypeBox{Type: BoxTypeDvcC()}},
+			bin: []byte{
+				0x01, 0x00, 0x0a, 0x35, 0x00, 0x00, 0x00, 0x00,
+				0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			},
+			str: `DVVersionMajor=0x1 DVVersionMinor=0x0 DVProfile=0x5 DVLevel=0x6 RPUPresentFlag=true ELPresentFlag=false BLPresentFlag=true DVBLSignalCompatibilityID=0x0`,
+		},
+		{
+			name: "dvvC",
+			src: &DOVIDecoderConfiguration{
+				AnyTypeBox:                AnyTypeBox{Type: BoxTypeDvvC()},
+				DVVersionMajor:            1,
+				DVVersionMinor:            0,
+				DVProfile:                 8,
+				DVLevel:                   9,
+				RPUPresentFlag:            true,
+				ELPresentFlag:             false,
+				BLPresentFlag:             true,
+				DVBLSignalCompatibilityID: 1,
+			},
+			dst: &DOVIDecoderConfiguration{AnyTypeBox: AnyTypeBox{Type: BoxTypeDvvC()}},
+			bin: []byte{
+				0x01, 0x00, 0x10, 0x4d, 0x10, 0x00, 0x00, 0x00,
+				0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			},
+			str: `DVVersionMajor=0x1 DVVersionMinor=0x0 DVProfile=0x8 DVLevel=0x9 RPUPresentFlag=true ELPresentFlag=false BLPresentFlag=true DVBLSignalCompatibilityID=0x1`,
+		},
+		{
+			name: "dvwC",
+			src: &DOVIDecoderConfiguration{
+				AnyTypeBox:                AnyTypeBox{Type: BoxTypeDvwC()},
+				DVVersionMajor:            2,
+				DVVersionMinor:            1,
+				DVProfile:                 20,
+				DVLevel:                   13,
+				RPUPresentFlag:            true,
+				ELPresentFlag:             true,
+				BLPresentFlag:             true,
+				DVBLSignalCompatibilityID: 15,
+			},
+			dst: &DOVIDecoderConfiguration{AnyTypeBox: AnyTypeBox{Type: BoxTypeDvwC()}},
+			bin: []byte{
+				0x02, 0x01, 0x28, 0x6f, 0xf0, 0x00, 0x00, 0x00,
+				0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			},
+			str: `DVVersionMajor=0x2 DVVersionMinor=0x1 DVProfile=0x14 DVLevel=0xd RPUPresentFlag=true ELPresentFlag=true BLPresentFlag=true DVBLSignalCompatibilityID=0xf`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Marshal
+			buf := bytes.NewBuffer(nil)
+			n, err := Marshal(buf, tc.src, tc.ctx)
+			require.NoError(t, err)
+			assert.Equal(t, uint64(len(tc.bin)), n)
+			assert.Equal(t, tc.bin, buf.Bytes())
+
+			// Unmarshal
+			r := bytes.NewReader(tc.bin)
+			n, err = Unmarshal(r, uint64(len(tc.bin)), tc.dst, tc.ctx)
+			require.NoError(t, err)
+			assert.Equal(t, uint64(buf.Len()), n)
+			assert.Equal(t, tc.src, tc.dst)
+			s, err := r.Seek(0, io.SeekCurrent)
+			require.NoError(t, err)
+			assert.Equal(t, int64(buf.Len()), s)
+
+			// UnmarshalAny
+			dst, n, err := UnmarshalAny(bytes.NewReader(tc.bin), tc.src.GetType(), uint64(len(tc.bin)), tc.ctx)
+			require.NoError(t, err)
+			assert.Equal(t, uint64(buf.Len()), n)
+			assert.Equal(t, tc.src, dst)
+			s, err = r.Seek(0, io.SeekCurrent)
+			require.NoError(t, err)
+			assert.Equal(t, int64(buf.Len()), s)
+
+			// Stringify
+			str, err := Stringify(tc.src, tc.ctx)
+			require.NoError(t, err)
+			assert.Equal(t, tc.str, str)
+		})
+	}
+}
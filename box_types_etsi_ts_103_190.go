@@ -0,0 +1,355 @@
+package mp4
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/Spidey120703/go-mp4/internal/bitio"
+)
+
+/*************************** ac-4 ****************************/
+
+// https://www.etsi.org/deliver/etsi_ts/103100_103199/10319002/01.02.01_60/ts_10319002v010201p.pdf
+
+func BoxTypeAC4() BoxType { return StrToBoxType("ac-4") }
+
+func init() {
+	AddAnyTypeBoxDef(&AudioSampleEntry{}, BoxTypeAC4())
+}
+
+/*************************** dac4 ****************************/
+
+// https://www.etsi.org/deliver/etsi_ts/103100_103199/10319002/01.02.01_60/ts_10319002v010201p.pdf
+
+func BoxTypeDAC4() BoxType { return StrToBoxType("dac4") }
+
+func init() {
+	AddBoxDef(&Dac4{})
+}
+
+// Dac4 is the AC-4 specific box which has ac4_dsi_v1.
+type Dac4 struct {
+	Box
+	AC4DSIVersion    uint8             `mp4:"0,size=3"`
+	BitstreamVersion uint8             `mp4:"1,size=7"`
+	FsIndex          uint8             `mp4:"2,size=1"`
+	FrameRateIndex   uint8             `mp4:"3,size=4"`
+	NPresentations   uint16            `mp4:"4,size=9"`
+	BProgramID       bool              `mp4:"5,size=1,opt=dynamic"`
+	ShortProgramID   uint16            `mp4:"6,size=16,opt=dynamic"`
+	BUUID            bool              `mp4:"7,size=1,opt=dynamic"`
+	ProgramUUID      [16]byte          `mp4:"8,size=8,opt=dynamic,uuid"`
+	BitRateMode      uint8             `mp4:"9,size=2"`
+	BitRate          uint32            `mp4:"10,size=32"`
+	BitRatePrecision uint32            `mp4:"11,size=32"`
+	ByteAlign        uint8             `mp4:"12,size=dynamic,opt=dynamic,const=0"`
+	Presentations    []AC4Presentation `mp4:"13,len=dynamic"`
+}
+
+// AC4Presentation is a presentation of ac4_dsi_v1.
+// PresentationBytes holds ac4_presentation_v0_dsi or ac4_presentation_v1_dsi, which can be decoded by Decode.
+type AC4Presentation struct {
+	BaseCustomFieldObject
+	PresentationVersion uint8  `mp4:"0,size=8"`
+	PresBytes           uint8  `mp4:"1,size=8"`
+	AddPresBytes        uint16 `mp4:"2,size=16,opt=dynamic"`
+	PresentationBytes   []byte `mp4:"3,size=8,len=dynamic"`
+}
+
+func (Dac4) GetType() BoxType {
+	return BoxTypeDAC4()
+}
+
+// IsOptFieldEnabled check whether if the optional field is enabled
+func (dac4 *Dac4) IsOptFieldEnabled(name string, ctx Context) bool {
+	switch name {
+	case "BProgramID":
+		return dac4.BitstreamVersion > 1
+	case "ShortProgramID", "BUUID":
+		return dac4.BitstreamVersion > 1 && dac4.BProgramID
+	case "ProgramUUID":
+		return dac4.BitstreamVersion > 1 && dac4.BProgramID && dac4.BUUID
+	case "ByteAlign":
+		return dac4.alignmentBits() != 0
+	}
+	return false
+}
+
+// GetFieldSize returns size of dynamic field
+func (dac4 *Dac4) GetFieldSize(name string, ctx Context) uint {
+	switch name {
+	case "ByteAlign":
+		return dac4.alignmentBits()
+	}
+	panic(fmt.Errorf("invalid name of dynamic-size field: boxType=dac4 fieldName=%s", name))
+}
+
+// GetFieldLength returns length of dynamic field
+func (dac4 *Dac4) GetFieldLength(name string, ctx Context) uint {
+	switch name {
+	case "Presentations":
+		return uint(dac4.NPresentations)
+	}
+	panic(fmt.Errorf("invalid name of dynamic-length field: boxType=dac4 fieldName=%s", name))
+}
+
+// alignmentBits returns the number of bits to align the presentations to a byte boundary.
+func (dac4 *Dac4) alignmentBits() uint {
+	bits := uint(3 + 7 + 1 + 4 + 9)
+	if dac4.BitstreamVersion > 1 {
+		bits++
+		if dac4.BProgramID {
+			bits += 16 + 1
+			if dac4.BUUID {
+				bits += 16 * 8
+			}
+		}
+	}
+	bits += 2 + 32 + 32
+	return (8 - bits%8) % 8
+}
+
+// IsOptFieldEnabled check whether if the optional field is enabled
+func (p *AC4Presentation) IsOptFieldEnabled(name string, ctx Context) bool {
+	switch name {
+	case "AddPresBytes":
+		return p.PresBytes == 255
+	}
+	return false
+}
+
+// GetFieldLength returns length of dynamic field
+func (p *AC4Presentation) GetFieldLength(name string, ctx Context) uint {
+	switch name {
+	case "PresentationBytes":
+		if p.PresBytes == 255 {
+			return uint(p.PresBytes) + uint(p.AddPresBytes)
+		}
+		return uint(p.PresBytes)
+	}
+	return 0
+}
+
+// AC4PresentationInfo is decoded from ac4_presentation_v1_dsi.
+type AC4PresentationInfo struct {
+	PresentationVersion uint8
+	PresentationConfig  uint8
+	// MDCompat is mdcompat, which is zero if it is not present.
+	MDCompat uint8
+	// PresentationID is -1 if it is not present.
+	PresentationID int
+	// ChannelCoded reports whether the presentation is channel based.
+	// ChannelMode and ChannelMask are valid only if it is true.
+	ChannelCoded bool
+	ChannelMode  uint8
+	ChannelMask  uint32
+	// SubstreamGroups are empty for presentations which consist of EMDF substreams only.
+	SubstreamGroups []*AC4SubstreamGroupInfo
+	// DolbyAtmos is dolby_atmos_indicator, which is false if it is not present.
+	DolbyAtmos bool
+}
+
+// AC4SubstreamGroupInfo is decoded from ac4_substream_group_dsi.
+type AC4SubstreamGroupInfo struct {
+	SubstreamsPresent bool
+	HSFExt            bool
+	ChannelCoded      bool
+	Substreams        []*AC4SubstreamInfo
+	// ContentClassifier is -1 if it is not present.
+	ContentClassifier int
+	Language          string
+}
+
+// AC4SubstreamInfo is a substream of ac4_substream_group_dsi.
+type AC4SubstreamInfo struct {
+	SFMultiplier uint8
+	// ChannelMask is valid only if the substream group is channel coded.
+	ChannelMask uint32
+	// AJOC reports whether the substream uses advanced joint object coding.
+	AJOC                  bool
+	ContainsBedObjects    bool
+	ContainsDynamicObject bool
+	ContainsISFObjects    bool
+}
+
+// ac4ChannelModeChannels is the number of channels of each presentation channel mode.
+var ac4ChannelModeChannels = []int{1, 2, 3, 5, 6, 7, 8, 7, 8, 7, 8, 11, 12, 13, 14, 24}
+
+// ChannelCount returns the number of channels of the channel mode, or zero if it is not channel coded.
+func (info *AC4PresentationInfo) ChannelCount() int {
+	if !info.ChannelCoded || int(info.ChannelMode) >= len(ac4ChannelModeChannels) {
+		return 0
+	}
+	return ac4ChannelModeChannels[info.ChannelMode]
+}
+
+// Decode decodes the presentation.
+// Only presentation version 1 and 2 (ac4_presentation_v1_dsi) are supported.
+func (p *AC4Presentation) Decode() (*AC4PresentationInfo, error) {
+	if p.PresentationVersion != 1 && p.PresentationVersion != 2 {
+		return nil, fmt.Errorf("unsupported AC-4 presentation version: %d", p.PresentationVersion)
+	}
+	d := &ac4Decoder{r: bitio.NewReader(bytes.NewReader(p.PresentationBytes))}
+	info := &AC4PresentationInfo{PresentationVersion: p.PresentationVersion, PresentationID: -1}
+	info.PresentationConfig = uint8(d.uint(5))
+	addEMDFSubstreams := true
+	if info.PresentationConfig != 0x06 {
+		info.MDCompat = uint8(d.uint(3))
+		if d.flag() {
+			info.PresentationID = int(d.uint(5))
+		}
+		d.uint(2 + 2 + 5 + 10) // frame rate info, emdf version and key id
+		if info.ChannelCoded = d.flag(); info.ChannelCoded {
+			info.ChannelMode = uint8(d.uint(5))
+			if info.ChannelMode >= 11 && info.ChannelMode <= 14 {
+				d.uint(1 + 2) // pres_b_4_back_channels_present, pres_top_channel_pairs
+			}
+			info.ChannelMask = uint32(d.uint(24))
+		}
+		if d.flag() { // b_presentation_core_differs
+			if d.flag() {
+				d.uint(2)
+			}
+		}
+		if d.flag() { // b_presentation_filter
+			d.uint(1)
+			d.skipBytes(int(d.uint(8)))
+		}
+		if info.PresentationConfig == 0x1f {
+			info.SubstreamGroups = append(info.SubstreamGroups, d.substreamGroup())
+		} else {
+			d.uint(1) // b_multi_pid
+			var n int
+			switch info.PresentationConfig {
+			case 0, 1, 2:
+				n = 2
+			case 3, 4:
+				n = 3
+			case 5:
+				n = int(d.uint(3)) + 2
+			default:
+				d.skipBytes(int(d.uint(7)))
+			}
+			for i := 0; i < n; i++ {
+				info.SubstreamGroups = append(info.SubstreamGroups, d.substreamGroup())
+			}
+		}
+		d.uint(1) // b_pre_virtualized
+		addEMDFSubstreams = d.flag()
+	}
+	if addEMDFSubstreams {
+		d.uint(int(d.uint(7)) * (5 + 10))
+	}
+	if d.flag() { // b_presentation_bitrate_info
+		d.uint(2 + 32 + 32)
+	}
+	if d.flag() { // b_alternative
+		d.align()
+		d.skipBytes(int(d.uint(16)))     // name_len and presentation_name
+		d.uint(int(d.uint(5)) * (3 + 8)) // target_md_compat and target_device_category
+	}
+	d.align()
+	if d.err != nil {
+		return nil, d.err
+	}
+	if d.bits < len(p.PresentationBytes)*8 {
+		d.uint(1) // de_indicator
+		info.DolbyAtmos = d.flag()
+		if d.err != nil {
+			return nil, d.err
+		}
+	}
+	return info, nil
+}
+
+// ac4Decoder reads the bit fields of AC-4 decoder specific information.
+// Once an error occurs, the following reads return zero values.
+type ac4Decoder struct {
+	r    bitio.Reader
+	bits int
+	err  error
+}
+
+// uint reads the bits of the width, which can be longer than 64 bits to skip them.
+// Only the last 64 bits are returned for such a long width.
+func (d *ac4Decoder) uint(width int) uint64 {
+	if d.err != nil {
+		return 0
+	}
+	var val uint64
+	for width > 0 {
+		w := width
+		if w > 64 {
+			w = 64
+		}
+		v, err := bitio.ReadUint(d.r, uint(w))
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			d.err = err
+			return 0
+		}
+		val = v
+		width -= w
+		d.bits += w
+	}
+	return val
+}
+
+func (d *ac4Decoder) flag() bool {
+	return d.uint(1) != 0
+}
+
+func (d *ac4Decoder) align() {
+	d.uint((8 - d.bits%8) % 8)
+}
+
+func (d *ac4Decoder) skipBytes(n int) {
+	for i := 0; i < n; i++ {
+		d.uint(8)
+	}
+}
+
+func (d *ac4Decoder) substreamGroup() *AC4SubstreamGroupInfo {
+	g := &AC4SubstreamGroupInfo{ContentClassifier: -1}
+	g.SubstreamsPresent = d.flag()
+	g.HSFExt = d.flag()
+	g.ChannelCoded = d.flag()
+	n := int(d.uint(8))
+	for i := 0; i < n && d.err == nil; i++ {
+		s := &AC4SubstreamInfo{}
+		s.SFMultiplier = uint8(d.uint(2))
+		if d.flag() { // b_substream_bitrate_indicator
+			d.uint(5)
+		}
+		if g.ChannelCoded {
+			s.ChannelMask = uint32(d.uint(24))
+		} else {
+			if s.AJOC = d.flag(); s.AJOC {
+				if !d.flag() { // b_static_dmx
+					d.uint(4)
+				}
+				d.uint(6)
+			}
+			s.ContainsBedObjects = d.flag()
+			s.ContainsDynamicObject = d.flag()
+			s.ContainsISFObjects = d.flag()
+			d.uint(1)
+		}
+		g.Substreams = append(g.Substreams, s)
+	}
+	if d.flag() { // b_content_type
+		g.ContentClassifier = int(d.uint(3))
+		if d.flag() { // b_language_indicator
+			n := int(d.uint(6))
+			lang := make([]byte, 0, n)
+			for i := 0; i < n; i++ {
+				lang = append(lang, byte(d.uint(8)))
+			}
+			g.Language = string(lang)
+		}
+	}
+	return g
+}
@@ -0,0 +1,217 @@
+package mp4
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// buildTestBits packs the pairs of value and width into bytes, which are padded with zeros.
+func buildTestBits(fields ...[2]uint64) []byte {
+	var data []byte
+	var bits uint64
+	for _, f := range fields {
+		for i := int(f[1]) - 1; i >= 0; i-- {
+			if bits%8 == 0 {
+				data = append(data, 0)
+			}
+			if f[0]>>uint(i)&1 != 0 {
+				data[len(data)-1] |= 0x80 >> (bits % 8)
+			}
+			bits++
+		}
+	}
+	return data
+}
+
+// testAC4PresentationV1 is ac4_presentation_v1_dsi of a 5.1 channel presentation.
+var testAC4PresentationV1 = buildTestBits(
+	[2]uint64{0x1f, 5},      // presentation_config_v1
+	[2]uint64{3, 3},         // mdcompat
+	[2]uint64{1, 1},         // b_presentation_id
+	[2]uint64{1, 5},         // presentation_id
+	[2]uint64{0, 4},         // dsi_frame_rate_multiply_info, dsi_frame_rate_fraction_info
+	[2]uint64{0, 5},         // presentation_emdf_version
+	[2]uint64{0, 10},        // presentation_key_id
+	[2]uint64{1, 1},         // b_presentation_channel_coded
+	[2]uint64{4, 5},         // dsi_presentation_ch_mode
+	[2]uint64{0x47, 24},     // presentation_channel_mask_v1
+	[2]uint64{0, 1},         // b_presentation_core_differs
+	[2]uint64{0, 1},         // b_presentation_filter
+	[2]uint64{1, 1},         // b_substreams_present
+	[2]uint64{0, 1},         // b_hsf_ext
+	[2]uint64{1, 1},         // b_channel_coded
+	[2]uint64{1, 8},         // n_substreams
+	[2]uint64{0, 2},         // dsi_sf_multiplier
+	[2]uint64{0, 1},         // b_substream_bitrate_indicator
+	[2]uint64{0x47, 24},     // dsi_substream_channel_mask
+	[2]uint64{1, 1},         // b_content_type
+	[2]uint64{0, 3},         // content_classifier
+	[2]uint64{1, 1},         // b_language_indicator
+	[2]uint64{3, 6},         // n_language_tag_bytes
+	[2]uint64{0x656e67, 24}, // language_tag_bytes
+	[2]uint64{0, 1},         // b_pre_virtualized
+	[2]uint64{0, 1},         // b_add_emdf_substreams
+	[2]uint64{0, 1},         // b_presentation_bitrate_info
+	[2]uint64{0, 1},         // b_alternative
+	[2]uint64{0, 2},         // byte_align
+	[2]uint64{0, 1},         // de_indicator
+	[2]uint64{1, 1},         // dolby_atmos_indicator
+	[2]uint64{0, 6},         // reserved, b_extended_presentation_id, reserved
+)
+
+func TestBoxTypesETSITS103190(t *testing.T) {
+	testCases := []struct {
+		name string
+		src  IImmutableBox
+		dst  IBox
+		bin  []byte
+		str  string
+		ctx  Context
+	}{
+		{
+			name: "dac4",
+			src: &Dac4{
+				AC4DSIVersion:    1,
+				BitstreamVersion: 2,
+				FsIndex:          1,
+				FrameRateIndex:   1,
+				NPresentations:   1,
+				BProgramID:       true,
+				ShortProgramID:   0x1234,
+				BUUID:            false,
+				BitRateMode:      0,
+				BitRate:          64000,
+				BitRatePrecision: 0xffffffff,
+				Presentations: []AC4Presentation{{
+					PresentationVersion: 1,
+					PresBytes:           uint8(len(testAC4PresentationV1)),
+					PresentationBytes:   testAC4PresentationV1,
+				}},
+			},
+			dst: &Dac4{},
+			bin: append(buildTestBits(
+				[2]uint64{1, 3},           // ac4_dsi_version
+				[2]uint64{2, 7},           // bitstream_version
+				[2]uint64{1, 1},           // fs_index
+				[2]uint64{1, 4},           // frame_rate_index
+				[2]uint64{1, 9},           // n_presentations
+				[2]uint64{1, 1},           // b_program_id
+				[2]uint64{0x1234, 16},     // short_program_id
+				[2]uint64{0, 1},           // b_uuid
+				[2]uint64{0, 2},           // bit_rate_mode
+				[2]uint64{64000, 32},      // bit_rate
+				[2]uint64{0xffffffff, 32}, // bit_rate_precision
+				[2]uint64{0, 4},           // byte_align
+				[2]uint64{1, 8},           // presentation_version
+				[2]uint64{uint64(len(testAC4PresentationV1)), 8}, // pres_bytes
+			), testAC4PresentationV1...),
+			str: `AC4DSIVersion=0x1 BitstreamVersion=0x2 FsIndex=0x1 FrameRateIndex=0x1 NPresentations=1 ` +
+				`BProgramID=true ShortProgramID=4660 BUUID=false ` +
+				`BitRateMode=0x0 BitRate=64000 BitRatePrecision=4294967295 ` +
+				`Presentations=[{PresentationVersion=0x1 PresBytes=0x13 PresentationBytes=[` +
+				`0xfb, 0x84, 0x0, 0x0, 0x48, 0x0, 0x0, 0x8e, 0x50, 0x10, 0x0, 0x0, 0x8f, 0x10, 0xd9, 0x5b, 0x99, 0xc0, 0x40]}]`,
+		},
+		{
+			name: "dac4: bitstream version 1",
+			src: &Dac4{
+				AC4DSIVersion:    1,
+				BitstreamVersion: 1,
+				FsIndex:          1,
+				FrameRateIndex:   2,
+				NPresentations:   0,
+				BitRateMode:      1,
+				BitRate:          0,
+				BitRatePrecision: 0,
+				Presentations:    []AC4Presentation{},
+			},
+			dst: &Dac4{},
+			bin: buildTestBits(
+				[2]uint64{1, 3},  // ac4_dsi_version
+				[2]uint64{1, 7},  // bitstream_version
+				[2]uint64{1, 1},  // fs_index
+				[2]uint64{2, 4},  // frame_rate_index
+				[2]uint64{0, 9},  // n_presentations
+				[2]uint64{1, 2},  // bit_rate_mode
+				[2]uint64{0, 64}, // bit_rate, bit_rate_precision
+			),
+			str: `AC4DSIVersion=0x1 BitstreamVersion=0x1 FsIndex=0x1 FrameRateIndex=0x2 NPresentations=0 ` +
+				`BitRateMode=0x1 BitRate=0 BitRatePrecision=0 Presentations=[]`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Marshal
+			buf := bytes.NewBuffer(nil)
+			n, err := Marshal(buf, tc.src, tc.ctx)
+			require.NoError(t, err)
+			assert.Equal(t, uint64(len(tc.bin)), n)
+			assert.Equal(t, tc.bin, buf.Bytes())
+
+			// Unmarshal
+			r := bytes.NewReader(tc.bin)
+			n, err = Unmarshal(r, uint64(len(tc.bin)), tc.dst, tc.ctx)
+			require.NoError(t, err)
+			assert.Equal(t, uint64(buf.Len()), n)
+			assert.Equal(t, tc.src, tc.dst)
+			s, err := r.Seek(0, io.SeekCurrent)
+			require.NoError(t, err)
+			assert.Equal(t, int64(buf.Len()), s)
+
+			// UnmarshalAny
+			dst, n, err := UnmarshalAny(bytes.NewReader(tc.bin), tc.src.GetType(), uint64(len(tc.bin)), tc.ctx)
+			require.NoError(t, err)
+			assert.Equal(t, uint64(buf.Len()), n)
+			assert.Equal(t, tc.src, dst)
+			s, err = r.Seek(0, io.SeekCurrent)
+			require.NoError(t, err)
+			assert.Equal(t, int64(buf.Len()), s)
+
+			// Stringify
+			str, err := Stringify(tc.src, tc.ctx)
+			require.NoError(t, err)
+			assert.Equal(t, tc.str, str)
+		})
+	}
+}
+
+func TestAC4PresentationDecode(t *testing.T) {
+	p := &AC4Presentation{
+		PresentationVersion: 1,
+		PresBytes:           uint8(len(testAC4PresentationV1)),
+		PresentationBytes:   testAC4PresentationV1,
+	}
+	info, err := p.Decode()
+	require.NoError(t, err)
+	assert.Equal(t, &AC4PresentationInfo{
+		PresentationVersion: 1,
+		PresentationConfig:  0x1f,
+		MDCompat:            3,
+		PresentationID:      1,
+		ChannelCoded:        true,
+		ChannelMode:         4,
+		ChannelMask:         0x47,
+		SubstreamGroups: []*AC4SubstreamGroupInfo{{
+			SubstreamsPresent: true,
+			ChannelCoded:      true,
+			Substreams:        []*AC4SubstreamInfo{{ChannelMask: 0x47}},
+			ContentClassifier: 0,
+			Language:          "eng",
+		}},
+		DolbyAtmos: true,
+	}, info)
+	assert.Equal(t, 6, info.ChannelCount())
+
+	// truncated
+	p.PresentationBytes = testAC4PresentationV1[:8]
+	_, err = p.Decode()
+	assert.Error(t, err)
+
+	// version 0 is not supported
+	p.PresentationVersion = 0
+	_, err = p.Decode()
+	assert.Error(t, err)
+}
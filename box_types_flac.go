@@ -0,0 +1,262 @@
+package mp4
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/Spidey120703/go-mp4/internal/bitio"
+)
+
+/*************************** fLaC ****************************/
+
+// https://github.com/xiph/flac/blob/master/doc/isoflac.txt
+
+func BoxTypeFLaC() BoxType { return StrToBoxType("fLaC") }
+
+func init() {
+	AddAnyTypeBoxDef(&AudioSampleEntry{}, BoxTypeFLaC())
+}
+
+/*************************** dfLa ****************************/
+
+// https://github.com/xiph/flac/blob/master/doc/isoflac.txt
+
+func BoxTypeDfLa() BoxType { return StrToBoxType("dfLa") }
+
+func init() {
+	AddBoxDef(&DfLa{}, 0)
+}
+
+const (
+	FLACMetadataBlockTypeStreamInfo    = 0
+	FLACMetadataBlockTypePadding       = 1
+	FLACMetadataBlockTypeApplication   = 2
+	FLACMetadataBlockTypeSeekTable     = 3
+	FLACMetadataBlockTypeVorbisComment = 4
+	FLACMetadataBlockTypeCueSheet      = 5
+	FLACMetadataBlockTypePicture       = 6
+)
+
+type DfLa struct {
+	FullBox        `mp4:"0,extend"`
+	MetadataBlocks []FLACMetadataBlock `mp4:"1"`
+}
+
+// GetType returns the BoxType
+func (*DfLa) GetType() BoxType {
+	return BoxTypeDfLa()
+}
+
+// GetStreamInfo returns the STREAMINFO block, or nil if there is none.
+func (dfla *DfLa) GetStreamInfo() *FLACMetadataBlock {
+	for i := range dfla.MetadataBlocks {
+		if dfla.MetadataBlocks[i].BlockType == FLACMetadataBlockTypeStreamInfo {
+			return &dfla.MetadataBlocks[i]
+		}
+	}
+	return nil
+}
+
+// FLACMetadataBlock is METADATA_BLOCK of FLAC.
+// The fields following Length are decoded depending on BlockType,
+// and BlockData holds the data of PADDING, CUESHEET and unknown blocks.
+// Note that the lengths of VORBIS_COMMENT are little-endian as in FLAC files.
+type FLACMetadataBlock struct {
+	BaseCustomFieldObject
+	LastMetadataBlockFlag bool   `mp4:"0,size=1"`
+	BlockType             uint8  `mp4:"1,size=7"`
+	Length                uint32 `mp4:"2,size=24"`
+
+	// STREAMINFO
+	MinimumBlockSize    uint16   `mp4:"3,size=16,opt=dynamic"`
+	MaximumBlockSize    uint16   `mp4:"4,size=16,opt=dynamic"`
+	MinimumFrameSize    uint32   `mp4:"5,size=24,opt=dynamic"`
+	MaximumFrameSize    uint32   `mp4:"6,size=24,opt=dynamic"`
+	SampleRate          uint32   `mp4:"7,size=20,opt=dynamic"`
+	ChannelsMinusOne    uint8    `mp4:"8,size=3,opt=dynamic"`
+	BitsPerSampleMinus1 uint8    `mp4:"9,size=5,opt=dynamic"`
+	TotalSamples        uint64   `mp4:"10,size=36,opt=dynamic"`
+	MD5Signature        [16]byte `mp4:"11,size=8,opt=dynamic"`
+
+	// APPLICATION
+	ApplicationID   [4]byte `mp4:"12,size=8,opt=dynamic,string"`
+	ApplicationData []byte  `mp4:"13,size=8,opt=dynamic,len=dynamic"`
+
+	// SEEKTABLE
+	SeekPoints []FLACSeekPoint `mp4:"14,opt=dynamic,len=dynamic"`
+
+	// VORBIS_COMMENT
+	VendorString string   `mp4:"15,opt=dynamic"`
+	UserComments []string `mp4:"16,opt=dynamic"`
+
+	// PICTURE
+	PictureType       uint32 `mp4:"17,size=32,opt=dynamic"`
+	MIMETypeLength    uint32 `mp4:"18,size=32,opt=dynamic"`
+	MIMEType          []byte `mp4:"19,size=8,opt=dynamic,len=dynamic,string"`
+	DescriptionLength uint32 `mp4:"20,size=32,opt=dynamic"`
+	Description       []byte `mp4:"21,size=8,opt=dynamic,len=dynamic,string"`
+	Width             uint32 `mp4:"22,size=32,opt=dynamic"`
+	Height            uint32 `mp4:"23,size=32,opt=dynamic"`
+	ColorDepth        uint32 `mp4:"24,size=32,opt=dynamic"`
+	NumberOfColors    uint32 `mp4:"25,size=32,opt=dynamic"`
+	PictureDataLength uint32 `mp4:"26,size=32,opt=dynamic"`
+	PictureData       []byte `mp4:"27,size=8,opt=dynamic,len=dynamic"`
+
+	BlockData []byte `mp4:"28,size=8,opt=dynamic,len=dynamic"`
+}
+
+type FLACSeekPoint struct {
+	SampleNumber uint64 `mp4:"0,size=64"`
+	Offset       uint64 `mp4:"1,size=64"`
+	NumSamples   uint16 `mp4:"2,size=16"`
+}
+
+const flacSeekPointSize = 18
+
+// IsOptFieldEnabled check whether if the optional field is enabled
+func (b *FLACMetadataBlock) IsOptFieldEnabled(name string, ctx Context) bool {
+	switch name {
+	case "MinimumBlockSize", "MaximumBlockSize", "MinimumFrameSize", "MaximumFrameSize",
+		"SampleRate", "ChannelsMinusOne", "BitsPerSampleMinus1", "TotalSamples", "MD5Signature":
+		return b.BlockType == FLACMetadataBlockTypeStreamInfo
+	case "ApplicationID", "ApplicationData":
+		return b.BlockType == FLACMetadataBlockTypeApplication
+	case "SeekPoints":
+		return b.BlockType == FLACMetadataBlockTypeSeekTable
+	case "VendorString", "UserComments":
+		return b.BlockType == FLACMetadataBlockTypeVorbisComment
+	case "PictureType", "MIMETypeLength", "MIMEType", "DescriptionLength", "Description",
+		"Width", "Height", "ColorDepth", "NumberOfColors", "PictureDataLength", "PictureData":
+		return b.BlockType == FLACMetadataBlockTypePicture
+	case "BlockData":
+		switch b.BlockType {
+		case FLACMetadataBlockTypeStreamInfo,
+			FLACMetadataBlockTypeApplication,
+			FLACMetadataBlockTypeSeekTable,
+			FLACMetadataBlockTypeVorbisComment,
+			FLACMetadataBlockTypePicture:
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+// GetFieldLength returns length of dynamic field
+func (b *FLACMetadataBlock) GetFieldLength(name string, ctx Context) uint {
+	switch name {
+	case "ApplicationData":
+		if b.Length < 4 {
+			return 0
+		}
+		return uint(b.Length - 4)
+	case "SeekPoints":
+		return uint(b.Length / flacSeekPointSize)
+	case "MIMEType":
+		return uint(b.MIMETypeLength)
+	case "Description":
+		return uint(b.DescriptionLength)
+	case "PictureData":
+		return uint(b.PictureDataLength)
+	case "BlockData":
+		return uint(b.Length)
+	}
+	panic(fmt.Errorf("invalid name of dynamic-length field: boxType=dfLa fieldName=%s", name))
+}
+
+// OnReadField reads VORBIS_COMMENT, whose lengths are little-endian.
+// It also rejects SEEKTABLE whose length is not a multiple of the seek point size.
+func (b *FLACMetadataBlock) OnReadField(name string, r bitio.ReadSeeker, leftBits uint64, ctx Context) (rbits uint64, override bool, err error) {
+	switch name {
+	case "SeekPoints":
+		if b.Length%flacSeekPointSize != 0 {
+			return 0, false, fmt.Errorf("dfLa: invalid length of SEEKTABLE: %d", b.Length)
+		}
+	case "VendorString":
+		if uint64(b.Length)*8 > leftBits {
+			return 0, false, errors.New("dfLa: metadata block exceeds the box size")
+		}
+		data := make([]byte, b.Length)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return 0, false, err
+		}
+		if b.VendorString, b.UserComments, err = parseVorbisComment(data); err != nil {
+			return 0, false, err
+		}
+		return uint64(b.Length) * 8, true, nil
+	case "UserComments":
+		return 0, true, nil
+	}
+	return 0, false, nil
+}
+
+// OnWriteField writes VORBIS_COMMENT, whose lengths are little-endian.
+func (b *FLACMetadataBlock) OnWriteField(name string, w bitio.Writer, ctx Context) (wbits uint64, override bool, err error) {
+	switch name {
+	case "VendorString":
+		data := buildVorbisComment(b.VendorString, b.UserComments)
+		if len(data) != int(b.Length) {
+			return 0, false, errors.New("dfLa: Length and the size of VORBIS_COMMENT are inconsistent")
+		}
+		if _, err := w.Write(data); err != nil {
+			return 0, false, err
+		}
+		return uint64(len(data)) * 8, true, nil
+	case "UserComments":
+		return 0, true, nil
+	}
+	return 0, false, nil
+}
+
+func parseVorbisComment(data []byte) (string, []string, error) {
+	readString := func() (string, error) {
+		if len(data) < 4 {
+			return "", errors.New("dfLa: invalid VORBIS_COMMENT")
+		}
+		n := binary.LittleEndian.Uint32(data)
+		data = data[4:]
+		if uint64(n) > uint64(len(data)) {
+			return "", errors.New("dfLa: invalid VORBIS_COMMENT")
+		}
+		s := string(data[:n])
+		data = data[n:]
+		return s, nil
+	}
+	vendor, err := readString()
+	if err != nil {
+		return "", nil, err
+	}
+	if len(data) < 4 {
+		return "", nil, errors.New("dfLa: invalid VORBIS_COMMENT")
+	}
+	count := binary.LittleEndian.Uint32(data)
+	data = data[4:]
+	comments := make([]string, 0)
+	for i := uint32(0); i < count; i++ {
+		comment, err := readString()
+		if err != nil {
+			return "", nil, err
+		}
+		comments = append(comments, comment)
+	}
+	return vendor, comments, nil
+}
+
+func buildVorbisComment(vendor string, comments []string) []byte {
+	data := make([]byte, 0, 8+len(vendor))
+	data = appendVorbisString(data, vendor)
+	data = append(data, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(data[len(data)-4:], uint32(len(comments)))
+	for _, comment := range comments {
+		data = appendVorbisString(data, comment)
+	}
+	return data
+}
+
+func appendVorbisString(data []byte, s string) []byte {
+	data = append(data, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(data[len(data)-4:], uint32(len(s)))
+	return append(data, s...)
+}
@@ -0,0 +1,181 @@
+package mp4
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBoxTypesFLAC(t *testing.T) {
+	testCases := []struct {
+		name string
+		src  IImmutableBox
+		dst  IBox
+		bin  []byte
+		str  string
+		ctx  Context
+	}{
+		{
+			name: "dfLa: STREAMINFO, VORBIS_COMMENT, PADDING",
+			src: &DfLa{
+				MetadataBlocks: []FLACMetadataBlock{
+					{
+						BlockType:           FLACMetadataBlockTypeStreamInfo,
+						Length:              34,
+						MinimumBlockSize:    4096,
+						MaximumBlockSize:    4096,
+						MinimumFrameSize:    14,
+						MaximumFrameSize:    4096,
+						SampleRate:          44100,
+						ChannelsMinusOne:    1,
+						BitsPerSampleMinus1: 15,
+						TotalSamples:        441000,
+						MD5Signature:        [16]byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10},
+					},
+					{
+						BlockType:    FLACMetadataBlockTypeVorbisComment,
+						Length:       25,
+						VendorString: "go-mp4",
+						UserComments: []string{"TITLE=x"},
+					},
+					{
+						LastMetadataBlockFlag: true,
+						BlockType:             FLACMetadataBlockTypePadding,
+						Length:                4,
+						BlockData:             []byte{0x00, 0x00, 0x00, 0x00},
+					},
+				},
+			},
+			dst: &DfLa{},
+			bin: []byte{
+				0, 0x00, 0x00, 0x00, // version & flags
+				// STREAMINFO
+				0x00, 0x00, 0x00, 0x22,
+				0x10, 0x00, 0x10, 0x00,
+				0x00, 0x00, 0x0e, 0x00, 0x10, 0x00,
+				0x0a, 0xc4, 0x42, 0xf0, 0x00, 0x06, 0xba, 0xa8,
+				0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08,
+				0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10,
+				// VORBIS_COMMENT
+				0x04, 0x00, 0x00, 0x19,
+				0x06, 0x00, 0x00, 0x00, 'g', 'o', '-', 'm', 'p', '4',
+				0x01, 0x00, 0x00, 0x00,
+				0x07, 0x00, 0x00, 0x00, 'T', 'I', 'T', 'L', 'E', '=', 'x',
+				// PADDING
+				0x81, 0x00, 0x00, 0x04,
+				0x00, 0x00, 0x00, 0x00,
+			},
+			str: `Version=0 Flags=0x000000 MetadataBlocks=[{LastMetadataBlockFlag=false BlockType=0x0 Length=34 MinimumBlockSize=4096 MaximumBlockSize=4096 MinimumFrameSize=14 MaximumFrameSize=4096 SampleRate=44100 ChannelsMinusOne=0x1 BitsPerSampleMinus1=0xf TotalSamples=441000 MD5Signature=[0x1, 0x2, 0x3, 0x4, 0x5, 0x6, 0x7, 0x8, 0x9, 0xa, 0xb, 0xc, 0xd, 0xe, 0xf, 0x10]}, {LastMetadataBlockFlag=false BlockType=0x4 Length=25 VendorString="go-mp4" UserComments=["TITLE=x"]}, {LastMetadataBlockFlag=true BlockType=0x1 Length=4 BlockData=[0x0, 0x0, 0x0, 0x0]}]`,
+		},
+		{
+			name: "dfLa: APPLICATION, SEEKTABLE, PICTURE",
+			src: &DfLa{
+				MetadataBlocks: []FLACMetadataBlock{
+					{
+						BlockType:       FLACMetadataBlockTypeApplication,
+						Length:          6,
+						ApplicationID:   [4]byte{'t', 'e', 's', 't'},
+						ApplicationData: []byte{0x12, 0x34},
+					},
+					{
+						BlockType: FLACMetadataBlockTypeSeekTable,
+						Length:    18,
+						SeekPoints: []FLACSeekPoint{
+							{SampleNumber: 44100, Offset: 0x1234, NumSamples: 4096},
+						},
+					},
+					{
+						LastMetadataBlockFlag: true,
+						BlockType:             FLACMetadataBlockTypePicture,
+						Length:                45,
+						PictureType:           3,
+						MIMETypeLength:        9,
+						MIMEType:              []byte("image/png"),
+						DescriptionLength:     0,
+						Description:           []byte{},
+						Width:                 1,
+						Height:                1,
+						ColorDepth:            24,
+						NumberOfColors:        0,
+						PictureDataLength:     4,
+						PictureData:           []byte{0x89, 'P', 'N', 'G'},
+					},
+				},
+			},
+			dst: &DfLa{},
+			bin: []byte{
+				0, 0x00, 0x00, 0x00, // version & flags
+				// APPLICATION
+				0x02, 0x00, 0x00, 0x06,
+				't', 'e', 's', 't', 0x12, 0x34,
+				// SEEKTABLE
+				0x03, 0x00, 0x00, 0x12,
+				0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xac, 0x44,
+				0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x12, 0x34,
+				0x10, 0x00,
+				// PICTURE
+				0x86, 0x00, 0x00, 0x2d,
+				0x00, 0x00, 0x00, 0x03,
+				0x00, 0x00, 0x00, 0x09, 'i', 'm', 'a', 'g', 'e', '/', 'p', 'n', 'g',
+				0x00, 0x00, 0x00, 0x00,
+				0x00, 0x00, 0x00, 0x01,
+				0x00, 0x00, 0x00, 0x01,
+				0x00, 0x00, 0x00, 0x18,
+				0x00, 0x00, 0x00, 0x00,
+				0x00, 0x00, 0x00, 0x04, 0x89, 'P', 'N', 'G',
+			},
+			str: `Version=0 Flags=0x000000 MetadataBlocks=[{LastMetadataBlockFlag=false BlockType=0x2 Length=6 ApplicationID="test" ApplicationData=[0x12, 0x34]}, {LastMetadataBlockFlag=false BlockType=0x3 Length=18 SeekPoints=[{SampleNumber=44100 Offset=4660 NumSamples=4096}]}, {LastMetadataBlockFlag=true BlockType=0x6 Length=45 PictureType=3 MIMETypeLength=9 MIMEType="image/png" DescriptionLength=0 Description="" Width=1 Height=1 ColorDepth=24 NumberOfColors=0 PictureDataLength=4 PictureData=[0x89, 0x50, 0x4e, 0x47]}]`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Marshal
+			buf := bytes.NewBuffer(nil)
+			n, err := Marshal(buf, tc.src, tc.ctx)
+			require.NoError(t, err)
+			assert.Equal(t, uint64(len(tc.bin)), n)
+			assert.Equal(t, tc.bin, buf.Bytes())
+
+			// Unmarshal
+			r := bytes.NewReader(tc.bin)
+			n, err = Unmarshal(r, uint64(len(tc.bin)), tc.dst, tc.ctx)
+			require.NoError(t, err)
+			assert.Equal(t, uint64(buf.Len()), n)
+			assert.Equal(t, tc.src, tc.dst)
+			s, err := r.Seek(0, io.SeekCurrent)
+			require.NoError(t, err)
+			assert.Equal(t, int64(buf.Len()), s)
+
+			// UnmarshalAny
+			dst, n, err := UnmarshalAny(bytes.NewReader(tc.bin), tc.src.GetType(), uint64(len(tc.bin)), tc.ctx)
+			require.NoError(t, err)
+			assert.Equal(t, uint64(buf.Len()), n)
+			assert.Equal(t, tc.src, dst)
+			s, err = r.Seek(0, io.SeekCurrent)
+			require.NoError(t, err)
+			assert.Equal(t, int64(buf.Len()), s)
+
+			// Stringify
+			str, err := Stringify(tc.src, tc.ctx)
+			require.NoError(t, err)
+			assert.Equal(t, tc.str, str)
+		})
+	}
+}
+
+func TestFLACSeekTableInvalidLength(t *testing.T) {
+	bin := []byte{
+		0,                // version
+		0x00, 0x00, 0x00, // flags
+		0x83, 0x00, 0x00, 0x13, // last, SEEKTABLE, length=19
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xac, 0x44, // sample number
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x12, 0x34, // offset
+		0x10, 0x00, // number of samples
+		0x00, // garbage
+	}
+	_, err := Unmarshal(bytes.NewReader(bin), uint64(len(bin)), &DfLa{}, Context{})
+	assert.EqualError(t, err, "dfLa: invalid length of SEEKTABLE: 19")
+}
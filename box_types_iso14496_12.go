@@ -1,15 +1,59 @@
 package mp4
 
 import (
+	"bytes"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
+	"reflect"
 	"strings"
 
 	"github.com/Spidey120703/go-mp4/internal/bitio"
 	"github.com/Spidey120703/go-mp4/internal/util"
 )
 
+/*************************** amve ****************************/
+
+func BoxTypeAmve() BoxType { return StrToBoxType("amve") }
+
+func init() {
+	AddBoxDef(&Amve{})
+}
+
+// Amve is ISOBMFF amve box type (ambient viewing environment)
+type Amve struct {
+	Box
+	AmbientIlluminance uint32 `mp4:"0,size=32"`
+	AmbientLightX      uint16 `mp4:"1,size=16"`
+	AmbientLightY      uint16 `mp4:"2,size=16"`
+}
+
+// GetType returns the BoxType
+func (*Amve) GetType() BoxType {
+	return BoxTypeAmve()
+}
+
+// StringifyField returns field value as string
+func (amve *Amve) StringifyField(name string, indent string, depth int, ctx Context) (string, bool) {
+	switch name {
+	case "AmbientIlluminance":
+		// in units of 0.0001 lux
+		return util.FormatFixedDecimal(int64(amve.AmbientIlluminance), 10000), true
+	case "AmbientLightX":
+		return formatChromaticity(int64(amve.AmbientLightX)), true
+	case "AmbientLightY":
+		return formatChromaticity(int64(amve.AmbientLightY)), true
+	default:
+		return "", false
+	}
+}
+
+// formatChromaticity formats the chromaticity coordinate in units of 0.00002.
+func formatChromaticity(val int64) string {
+	return util.FormatFixedDecimal(val, 50000)
+}
+
 /*************************** btrt ****************************/
 
 func BoxTypeBtrt() BoxType { return StrToBoxType("btrt") }
@@ -30,6 +74,103 @@ func (*Btrt) GetType() BoxType {
 	return BoxTypeBtrt()
 }
 
+/*************************** cclv ****************************/
+
+func BoxTypeCclv() BoxType { return StrToBoxType("cclv") }
+
+func init() {
+	AddBoxDef(&Cclv{})
+}
+
+// Cclv is ISOBMFF cclv box type (content colour volume)
+type Cclv struct {
+	Box
+	Reserved1                       uint8         `mp4:"0,size=1,const=0"`
+	Reserved2                       uint8         `mp4:"1,size=1,const=0"`
+	CCVPrimariesPresentFlag         bool          `mp4:"2,size=1"`
+	CCVMinLuminanceValuePresentFlag bool          `mp4:"3,size=1"`
+	CCVMaxLuminanceValuePresentFlag bool          `mp4:"4,size=1"`
+	CCVAvgLuminanceValuePresentFlag bool          `mp4:"5,size=1"`
+	CCVReservedZero2Bits            uint8         `mp4:"6,size=2,const=0"`
+	CCVPrimaries                    [3]CCVPrimary `mp4:"7,opt=dynamic"`
+	CCVMinLuminanceValue            uint32        `mp4:"8,size=32,opt=dynamic"`
+	CCVMaxLuminanceValue            uint32        `mp4:"9,size=32,opt=dynamic"`
+	CCVAvgLuminanceValue            uint32        `mp4:"10,size=32,opt=dynamic"`
+}
+
+type CCVPrimary struct {
+	BaseCustomFieldObject
+	X int32 `mp4:"0,size=32"`
+	Y int32 `mp4:"1,size=32"`
+}
+
+// GetType returns the BoxType
+func (*Cclv) GetType() BoxType {
+	return BoxTypeCclv()
+}
+
+// IsOptFieldEnabled check whether if the optional field is enabled
+func (cclv *Cclv) IsOptFieldEnabled(name string, ctx Context) bool {
+	switch name {
+	case "CCVPrimaries":
+		return cclv.CCVPrimariesPresentFlag
+	case "CCVMinLuminanceValue":
+		return cclv.CCVMinLuminanceValuePresentFlag
+	case "CCVMaxLuminanceValue":
+		return cclv.CCVMaxLuminanceValuePresentFlag
+	case "CCVAvgLuminanceValue":
+		return cclv.CCVAvgLuminanceValuePresentFlag
+	}
+	return false
+}
+
+// StringifyField returns field value as string
+func (cclv *Cclv) StringifyField(name string, indent string, depth int, ctx Context) (string, bool) {
+	// luminance values are in units of 0.0000001 cd/m2
+	switch name {
+	case "CCVMinLuminanceValue":
+		return util.FormatFixedDecimal(int64(cclv.CCVMinLuminanceValue), 10000000), true
+	case "CCVMaxLuminanceValue":
+		return util.FormatFixedDecimal(int64(cclv.CCVMaxLuminanceValue), 10000000), true
+	case "CCVAvgLuminanceValue":
+		return util.FormatFixedDecimal(int64(cclv.CCVAvgLuminanceValue), 10000000), true
+	default:
+		return "", false
+	}
+}
+
+// StringifyField returns field value as string
+func (p *CCVPrimary) StringifyField(name string, indent string, depth int, ctx Context) (string, bool) {
+	switch name {
+	case "X":
+		return formatChromaticity(int64(p.X)), true
+	case "Y":
+		return formatChromaticity(int64(p.Y)), true
+	default:
+		return "", false
+	}
+}
+
+/*************************** clli ****************************/
+
+func BoxTypeClli() BoxType { return StrToBoxType("clli") }
+
+func init() {
+	AddBoxDef(&Clli{})
+}
+
+// Clli is ISOBMFF clli box type (content light level)
+type Clli struct {
+	Box
+	MaxContentLightLevel    uint16 `mp4:"0,size=16"`
+	MaxPicAverageLightLevel uint16 `mp4:"1,size=16"`
+}
+
+// GetType returns the BoxType
+func (*Clli) GetType() BoxType {
+	return BoxTypeClli()
+}
+
 /*************************** co64 ****************************/
 
 func BoxTypeCo64() BoxType { return StrToBoxType("co64") }
@@ -80,6 +221,16 @@ type Colr struct {
 
 func (colr *Colr) IsOptFieldEnabled(name string, ctx Context) bool {
 	switch colr.ColourType {
+	case [4]byte{'n', 'c', 'l', 'c'}:
+		// QuickTime nclc has no full range flag
+		switch name {
+		case "ColourPrimaries",
+			"TransferCharacteristics",
+			"MatrixCoefficients":
+			return true
+		default:
+			return false
+		}
 	case [4]byte{'n', 'c', 'l', 'x'}:
 		switch name {
 		case "ColourType",
@@ -395,6 +546,26 @@ func (elst *Elst) GetMediaTime(index int) int64 {
 	}
 }
 
+/*************************** elng ****************************/
+
+func BoxTypeElng() BoxType { return StrToBoxType("elng") }
+
+func init() {
+	AddBoxDef(&Elng{}, 0)
+}
+
+// Elng is ISOBMFF elng box type
+type Elng struct {
+	FullBox `mp4:"0,extend"`
+	// ExtendedLanguage is a BCP 47 language tag (e.g. "en-US")
+	ExtendedLanguage string `mp4:"1,string"`
+}
+
+// GetType returns the BoxType
+func (*Elng) GetType() BoxType {
+	return BoxTypeElng()
+}
+
 /*************************** emsg ****************************/
 
 func BoxTypeEmsg() BoxType { return StrToBoxType("emsg") }
@@ -714,6 +885,15 @@ func (s HEVCNalu) GetFieldLength(name string, ctx Context) uint {
 	return 0
 }
 
+// StringifyField returns field value as string
+func (s HEVCNalu) StringifyField(name string, indent string, depth int, ctx Context) (string, bool) {
+	switch name {
+	case "NALUnit":
+		return stringifyParameterSet(s.NALUnit, true)
+	}
+	return "", false
+}
+
 type HEVCNaluArray struct {
 	BaseCustomFieldObject
 	Completeness bool       `mp4:"0,size=1"`
@@ -771,6 +951,621 @@ func (hvcc HvcC) GetFieldLength(name string, ctx Context) uint {
 	return 0
 }
 
+/*************************** idat ****************************/
+
+func BoxTypeIdat() BoxType { return StrToBoxType("idat") }
+
+func init() {
+	AddBoxDef(&Idat{})
+}
+
+// Idat is ISOBMFF idat box type
+type Idat struct {
+	Box
+	Data []byte `mp4:"0,size=8"`
+}
+
+// GetType returns the BoxType
+func (*Idat) GetType() BoxType {
+	return BoxTypeIdat()
+}
+
+/*************************** iinf ****************************/
+
+func BoxTypeIinf() BoxType { return StrToBoxType("iinf") }
+
+func init() {
+	AddBoxDef(&Iinf{}, 0, 1)
+}
+
+// Iinf is ISOBMFF iinf box type
+type Iinf struct {
+	FullBox    `mp4:"0,extend"`
+	EntryCount uint32 `mp4:"1,size=dynamic"`
+}
+
+// GetType returns the BoxType
+func (*Iinf) GetType() BoxType {
+	return BoxTypeIinf()
+}
+
+// GetFieldSize returns size of dynamic field
+func (iinf *Iinf) GetFieldSize(name string, ctx Context) uint {
+	switch name {
+	case "EntryCount":
+		if iinf.GetVersion() == 0 {
+			return 16
+		}
+		return 32
+	}
+	panic(fmt.Errorf("invalid name of dynamic-size field: boxType=iinf fieldName=%s", name))
+}
+
+/*************************** iloc ****************************/
+
+func BoxTypeIloc() BoxType { return StrToBoxType("iloc") }
+
+func init() {
+	AddBoxDef(&Iloc{}, 0, 1, 2)
+}
+
+const (
+	IlocConstructionMethodFileOffset = 0
+	IlocConstructionMethodIdatOffset = 1
+	IlocConstructionMethodItemOffset = 2
+)
+
+// Iloc is ISOBMFF iloc box type
+type Iloc struct {
+	FullBox        `mp4:"0,extend"`
+	OffsetSize     uint8      `mp4:"1,size=4"`
+	LengthSize     uint8      `mp4:"2,size=4"`
+	BaseOffsetSize uint8      `mp4:"3,size=4"`
+	IndexSize      uint8      `mp4:"4,size=4,nver=0"`
+	Reserved       uint8      `mp4:"5,size=4,ver=0,const=0"`
+	ItemCount      uint32     `mp4:"6,size=dynamic"`
+	Items          []IlocItem `mp4:"7,len=dynamic"`
+}
+
+// IlocItem is an item location of the iloc box.
+// Its fields are variable-length, so they are read and written by Iloc.
+type IlocItem struct {
+	ItemID             uint32       `mp4:"0,size=32"`
+	ConstructionMethod uint8        `mp4:"1,size=4,nver=0"`
+	DataReferenceIndex uint16       `mp4:"2,size=16"`
+	BaseOffset         uint64       `mp4:"3,size=64"`
+	ExtentCount        uint16       `mp4:"4,size=16"`
+	Extents            []IlocExtent `mp4:"5,size=192"`
+}
+
+type IlocExtent struct {
+	ExtentIndex  uint64 `mp4:"0,size=64,opt=dynamic"`
+	ExtentOffset uint64 `mp4:"1,size=64"`
+	ExtentLength uint64 `mp4:"2,size=64"`
+}
+
+// GetType returns the BoxType
+func (*Iloc) GetType() BoxType {
+	return BoxTypeIloc()
+}
+
+// GetFieldSize returns size of dynamic field
+func (iloc *Iloc) GetFieldSize(name string, ctx Context) uint {
+	switch name {
+	case "ItemCount":
+		if iloc.GetVersion() < 2 {
+			return 16
+		}
+		return 32
+	}
+	panic(fmt.Errorf("invalid name of dynamic-size field: boxType=iloc fieldName=%s", name))
+}
+
+// GetFieldLength returns length of dynamic field
+func (iloc *Iloc) GetFieldLength(name string, ctx Context) uint {
+	switch name {
+	case "Items":
+		return uint(iloc.ItemCount)
+	}
+	panic(fmt.Errorf("invalid name of dynamic-length field: boxType=iloc fieldName=%s", name))
+}
+
+func (iloc *Iloc) IsOptFieldEnabled(name string, ctx Context) bool {
+	switch name {
+	case "ExtentIndex":
+		return iloc.indexSize() != 0
+	}
+	return false
+}
+
+func (iloc *Iloc) itemIDSize() uint8 {
+	if iloc.GetVersion() < 2 {
+		return 2
+	}
+	return 4
+}
+
+func (iloc *Iloc) indexSize() uint8 {
+	if iloc.GetVersion() == 0 {
+		return 0
+	}
+	return iloc.IndexSize
+}
+
+func (iloc *Iloc) OnReadField(name string, r bitio.ReadSeeker, leftBits uint64, ctx Context) (rbits uint64, override bool, err error) {
+	if name != "Items" {
+		return 0, false, nil
+	}
+	read := func(size uint8) (uint64, error) {
+		if uint64(size)*8 > leftBits-rbits {
+			return 0, errors.New("iloc: item locations exceed the box size")
+		}
+		rbits += uint64(size) * 8
+		return bitio.ReadUint(r, uint(size)*8)
+	}
+	iloc.Items = make([]IlocItem, 0, iloc.ItemCount)
+	for i := uint32(0); i < iloc.ItemCount; i++ {
+		var item IlocItem
+		var val uint64
+		if val, err = read(iloc.itemIDSize()); err != nil {
+			return
+		}
+		item.ItemID = uint32(val)
+		if iloc.GetVersion() != 0 {
+			if val, err = read(2); err != nil {
+				return
+			}
+			item.ConstructionMethod = uint8(val & 0x0f)
+		}
+		if val, err = read(2); err != nil {
+			return
+		}
+		item.DataReferenceIndex = uint16(val)
+		if item.BaseOffset, err = read(iloc.BaseOffsetSize); err != nil {
+			return
+		}
+		if val, err = read(2); err != nil {
+			return
+		}
+		item.ExtentCount = uint16(val)
+		item.Extents = make([]IlocExtent, item.ExtentCount)
+		for j := range item.Extents {
+			extent := &item.Extents[j]
+			if extent.ExtentIndex, err = read(iloc.indexSize()); err != nil {
+				return
+			}
+			if extent.ExtentOffset, err = read(iloc.OffsetSize); err != nil {
+				return
+			}
+			if extent.ExtentLength, err = read(iloc.LengthSize); err != nil {
+				return
+			}
+		}
+		iloc.Items = append(iloc.Items, item)
+	}
+	return rbits, true, nil
+}
+
+func (iloc *Iloc) OnWriteField(name string, w bitio.Writer, ctx Context) (wbits uint64, override bool, err error) {
+	if name != "Items" {
+		return 0, false, nil
+	}
+	if len(iloc.Items) != int(iloc.ItemCount) {
+		return 0, false, errors.New("iloc: ItemCount and the number of Items are inconsistent")
+	}
+	write := func(val uint64, size uint8) error {
+		buf := make([]byte, 8)
+		binary.BigEndian.PutUint64(buf, val)
+		if size < 8 && val>>(uint(size)*8) != 0 {
+			return fmt.Errorf("iloc: value overflows %d bytes: %d", size, val)
+		}
+		wbits += uint64(size) * 8
+		_, err := w.Write(buf[8-size:])
+		return err
+	}
+	for _, item := range iloc.Items {
+		if len(item.Extents) != int(item.ExtentCount) {
+			return 0, false, errors.New("iloc: ExtentCount and the number of Extents are inconsistent")
+		}
+		if err = write(uint64(item.ItemID), iloc.itemIDSize()); err != nil {
+			return
+		}
+		if iloc.GetVersion() != 0 {
+			if err = write(uint64(item.ConstructionMethod&0x0f), 2); err != nil {
+				return
+			}
+		}
+		if err = write(uint64(item.DataReferenceIndex), 2); err != nil {
+			return
+		}
+		if err = write(item.BaseOffset, iloc.BaseOffsetSize); err != nil {
+			return
+		}
+		if err = write(uint64(item.ExtentCount), 2); err != nil {
+			return
+		}
+		for _, extent := range item.Extents {
+			if err = write(extent.ExtentIndex, iloc.indexSize()); err != nil {
+				return
+			}
+			if err = write(extent.ExtentOffset, iloc.OffsetSize); err != nil {
+				return
+			}
+			if err = write(extent.ExtentLength, iloc.LengthSize); err != nil {
+				return
+			}
+		}
+	}
+	return wbits, true, nil
+}
+
+/*************************** infe ****************************/
+
+func BoxTypeInfe() BoxType { return StrToBoxType("infe") }
+
+func init() {
+	AddBoxDef(&Infe{}, 0, 1, 2, 3)
+}
+
+// Infe is ISOBMFF infe box type
+type Infe struct {
+	FullBox             `mp4:"0,extend"`
+	ItemID              uint32  `mp4:"1,size=dynamic"`
+	ItemProtectionIndex uint16  `mp4:"2,size=16"`
+	ItemType            [4]byte `mp4:"3,size=8,string,opt=dynamic"`
+	ItemName            string  `mp4:"4,string"`
+	ContentType         string  `mp4:"5,string,opt=dynamic"`
+	ContentEncoding     string  `mp4:"6,string,opt=dynamic"` // optional
+	ItemURIType         string  `mp4:"7,string,opt=dynamic"`
+	// Extension is the item information extension of version 1.
+	Extension []byte `mp4:"8,size=8,ver=1"`
+}
+
+// GetType returns the BoxType
+func (*Infe) GetType() BoxType {
+	return BoxTypeInfe()
+}
+
+// GetFieldSize returns size of dynamic field
+func (infe *Infe) GetFieldSize(name string, ctx Context) uint {
+	switch name {
+	case "ItemID":
+		if infe.GetVersion() == 3 {
+			return 32
+		}
+		return 16
+	}
+	panic(fmt.Errorf("invalid name of dynamic-size field: boxType=infe fieldName=%s", name))
+}
+
+func (infe *Infe) IsOptFieldEnabled(name string, ctx Context) bool {
+	switch name {
+	case "ItemType":
+		return infe.GetVersion() >= 2
+	case "ContentType", "ContentEncoding":
+		return infe.GetVersion() < 2 || infe.ItemType == [4]byte{'m', 'i', 'm', 'e'}
+	case "ItemURIType":
+		return infe.GetVersion() >= 2 && infe.ItemType == [4]byte{'u', 'r', 'i', ' '}
+	}
+	return false
+}
+
+// IsHidden reports whether the item is hidden, which is not intended to be displayed.
+func (infe *Infe) IsHidden() bool {
+	return infe.GetFlags()&0x000001 != 0
+}
+
+/*************************** ipco ****************************/
+
+func BoxTypeIpco() BoxType { return StrToBoxType("ipco") }
+
+func init() {
+	AddBoxDef(&Ipco{})
+}
+
+// Ipco is ISOBMFF ipco box type
+type Ipco struct {
+	Box
+}
+
+// GetType returns the BoxType
+func (*Ipco) GetType() BoxType {
+	return BoxTypeIpco()
+}
+
+/*************************** ipma ****************************/
+
+func BoxTypeIpma() BoxType { return StrToBoxType("ipma") }
+
+func init() {
+	AddBoxDef(&Ipma{}, 0, 1)
+}
+
+// Ipma is ISOBMFF ipma box type
+type Ipma struct {
+	FullBox    `mp4:"0,extend"`
+	EntryCount uint32      `mp4:"1,size=32"`
+	Entries    []IpmaEntry `mp4:"2,len=dynamic"`
+}
+
+type IpmaEntry struct {
+	BaseCustomFieldObject
+	ItemIDV0         uint16            `mp4:"0,size=16,ver=0"`
+	ItemIDV1         uint32            `mp4:"1,size=32,nver=0"`
+	AssociationCount uint8             `mp4:"2,size=8"`
+	Associations     []IpmaAssociation `mp4:"3,len=dynamic"`
+}
+
+type IpmaAssociation struct {
+	Essential     bool   `mp4:"0,size=1"`
+	PropertyIndex uint16 `mp4:"1,size=dynamic"`
+}
+
+// GetType returns the BoxType
+func (*Ipma) GetType() BoxType {
+	return BoxTypeIpma()
+}
+
+// GetFieldSize returns size of dynamic field
+func (ipma *Ipma) GetFieldSize(name string, ctx Context) uint {
+	switch name {
+	case "PropertyIndex":
+		if ipma.GetFlags()&0x000001 != 0 {
+			return 15
+		}
+		return 7
+	}
+	panic(fmt.Errorf("invalid name of dynamic-size field: boxType=ipma fieldName=%s", name))
+}
+
+// GetFieldLength returns length of dynamic field
+func (ipma *Ipma) GetFieldLength(name string, ctx Context) uint {
+	switch name {
+	case "Entries":
+		return uint(ipma.EntryCount)
+	}
+	panic(fmt.Errorf("invalid name of dynamic-length field: boxType=ipma fieldName=%s", name))
+}
+
+// GetFieldLength returns length of dynamic field
+func (entry *IpmaEntry) GetFieldLength(name string, ctx Context) uint {
+	switch name {
+	case "Associations":
+		return uint(entry.AssociationCount)
+	}
+	panic(fmt.Errorf("invalid name of dynamic-length field: boxType=ipma fieldName=%s", name))
+}
+
+func (ipma *Ipma) GetItemID(index int) uint32 {
+	switch ipma.GetVersion() {
+	case 0:
+		return uint32(ipma.Entries[index].ItemIDV0)
+	case 1:
+		return ipma.Entries[index].ItemIDV1
+	default:
+		return 0
+	}
+}
+
+/*************************** iprp ****************************/
+
+func BoxTypeIprp() BoxType { return StrToBoxType("iprp") }
+
+func init() {
+	AddBoxDef(&Iprp{})
+}
+
+// Iprp is ISOBMFF iprp box type
+type Iprp struct {
+	Box
+}
+
+// GetType returns the BoxType
+func (*Iprp) GetType() BoxType {
+	return BoxTypeIprp()
+}
+
+/*************************** iref ****************************/
+
+func BoxTypeIref() BoxType { return StrToBoxType("iref") }
+
+func init() {
+	AddBoxDef(&Iref{}, 0, 1)
+}
+
+// Iref is ISOBMFF iref box type
+type Iref struct {
+	FullBox `mp4:"0,extend"`
+	// References are the single item type reference boxes in the iref box.
+	// They are read and written by Iref because the size of item IDs depends on the version of the iref box.
+	References []ItemReference `mp4:"1,size=8"`
+}
+
+type ItemReference struct {
+	ReferenceType  [4]byte  `mp4:"0,size=8,string"`
+	FromItemID     uint32   `mp4:"1,size=32"`
+	ReferenceCount uint16   `mp4:"2,size=16"`
+	ToItemIDs      []uint32 `mp4:"3,size=32"`
+}
+
+// GetType returns the BoxType
+func (*Iref) GetType() BoxType {
+	return BoxTypeIref()
+}
+
+func (iref *Iref) itemIDSize() uint64 {
+	if iref.GetVersion() == 0 {
+		return 2
+	}
+	return 4
+}
+
+func (iref *Iref) OnReadField(name string, r bitio.ReadSeeker, leftBits uint64, ctx Context) (rbits uint64, override bool, err error) {
+	if name != "References" {
+		return 0, false, nil
+	}
+	idSize := iref.itemIDSize()
+	readID := func(data []byte) uint32 {
+		if idSize == 2 {
+			return uint32(binary.BigEndian.Uint16(data))
+		}
+		return binary.BigEndian.Uint32(data)
+	}
+	for leftBits-rbits >= SmallHeaderSize*8 {
+		header := make([]byte, SmallHeaderSize)
+		if _, err = io.ReadFull(r, header); err != nil {
+			return
+		}
+		size := uint64(binary.BigEndian.Uint32(header))
+		if size < SmallHeaderSize+idSize+2 || size*8 > leftBits-rbits {
+			return 0, false, fmt.Errorf("iref: invalid reference box size: %d", size)
+		}
+		data := make([]byte, size-SmallHeaderSize)
+		if _, err = io.ReadFull(r, data); err != nil {
+			return
+		}
+		rbits += size * 8
+
+		ref := ItemReference{
+			FromItemID:     readID(data),
+			ReferenceCount: binary.BigEndian.Uint16(data[idSize:]),
+		}
+		copy(ref.ReferenceType[:], header[4:])
+		data = data[idSize+2:]
+		if uint64(len(data)) < idSize*uint64(ref.ReferenceCount) {
+			return 0, false, fmt.Errorf("iref: invalid reference box size: %d", size)
+		}
+		ref.ToItemIDs = make([]uint32, ref.ReferenceCount)
+		for i := range ref.ToItemIDs {
+			ref.ToItemIDs[i] = readID(data[uint64(i)*idSize:])
+		}
+		iref.References = append(iref.References, ref)
+	}
+	return rbits, true, nil
+}
+
+func (iref *Iref) OnWriteField(name string, w bitio.Writer, ctx Context) (wbits uint64, override bool, err error) {
+	if name != "References" {
+		return 0, false, nil
+	}
+	idSize := iref.itemIDSize()
+	for _, ref := range iref.References {
+		if len(ref.ToItemIDs) != int(ref.ReferenceCount) {
+			return 0, false, errors.New("iref: ReferenceCount and the number of ToItemIDs are inconsistent")
+		}
+		size := SmallHeaderSize + idSize*uint64(ref.ReferenceCount+1) + 2
+		buf := make([]byte, 4, size)
+		binary.BigEndian.PutUint32(buf, uint32(size))
+		buf = append(buf, ref.ReferenceType[:]...)
+		appendID := func(id uint32) error {
+			if idSize == 2 {
+				if id > 0xffff {
+					return fmt.Errorf("iref: item ID overflows 16 bits: %d", id)
+				}
+				buf = append(buf, byte(id>>8), byte(id))
+			} else {
+				buf = append(buf, byte(id>>24), byte(id>>16), byte(id>>8), byte(id))
+			}
+			return nil
+		}
+		if err = appendID(ref.FromItemID); err != nil {
+			return
+		}
+		buf = append(buf, byte(ref.ReferenceCount>>8), byte(ref.ReferenceCount))
+		for _, id := range ref.ToItemIDs {
+			if err = appendID(id); err != nil {
+				return
+			}
+		}
+		if _, err = w.Write(buf); err != nil {
+			return
+		}
+		wbits += size * 8
+	}
+	return wbits, true, nil
+}
+
+/*************************** kind ****************************/
+
+func BoxTypeKind() BoxType { return StrToBoxType("kind") }
+
+func init() {
+	AddBoxDef(&Kind{}, 0)
+}
+
+// Kind is ISOBMFF kind box type
+type Kind struct {
+	FullBox   `mp4:"0,extend"`
+	SchemeURI string `mp4:"1,string"`
+	Value     string `mp4:"2,string"`
+}
+
+// GetType returns the BoxType
+func (*Kind) GetType() BoxType {
+	return BoxTypeKind()
+}
+
+/*************************** leva ****************************/
+
+func BoxTypeLeva() BoxType { return StrToBoxType("leva") }
+
+func init() {
+	AddBoxDef(&Leva{}, 0)
+}
+
+const (
+	LevaAssignmentTypeSampleGroup          = 0
+	LevaAssignmentTypeSampleGroupParameter = 1
+	LevaAssignmentTypeTrack                = 2
+	LevaAssignmentTypeTrackSubsequent      = 3
+	LevaAssignmentTypeSubTrack             = 4
+)
+
+// Leva is ISOBMFF leva box type
+type Leva struct {
+	FullBox    `mp4:"0,extend"`
+	LevelCount uint8       `mp4:"1,size=8"`
+	Levels     []LevaLevel `mp4:"2,len=dynamic"`
+}
+
+type LevaLevel struct {
+	BaseCustomFieldObject
+	TrackID               uint32  `mp4:"0,size=32"`
+	PaddingFlag           bool    `mp4:"1,size=1"`
+	AssignmentType        uint8   `mp4:"2,size=7"`
+	GroupingType          [4]byte `mp4:"3,size=8,opt=dynamic,string"`
+	GroupingTypeParameter uint32  `mp4:"4,size=32,opt=dynamic"`
+	SubTrackID            uint32  `mp4:"5,size=32,opt=dynamic"`
+}
+
+// GetType returns the BoxType
+func (*Leva) GetType() BoxType {
+	return BoxTypeLeva()
+}
+
+// GetFieldLength returns length of dynamic field
+func (leva *Leva) GetFieldLength(name string, ctx Context) uint {
+	switch name {
+	case "Levels":
+		return uint(leva.LevelCount)
+	}
+	panic(fmt.Errorf("invalid name of dynamic-length field: boxType=leva fieldName=%s", name))
+}
+
+// IsOptFieldEnabled check whether if the optional field is enabled
+func (level *LevaLevel) IsOptFieldEnabled(name string, ctx Context) bool {
+	switch name {
+	case "GroupingType":
+		return level.AssignmentType == LevaAssignmentTypeSampleGroup ||
+			level.AssignmentType == LevaAssignmentTypeSampleGroupParameter
+	case "GroupingTypeParameter":
+		return level.AssignmentType == LevaAssignmentTypeSampleGroupParameter
+	case "SubTrackID":
+		return level.AssignmentType == LevaAssignmentTypeSubTrack
+	}
+	return false
+}
+
 /*************************** ludt ****************************/
 
 func BoxTypeLudt() BoxType {
@@ -885,6 +1680,64 @@ func (*Mdat) GetType() BoxType {
 	return BoxTypeMdat()
 }
 
+/*************************** mdcv ****************************/
+
+func BoxTypeMdcv() BoxType { return StrToBoxType("mdcv") }
+
+func init() {
+	AddBoxDef(&Mdcv{})
+}
+
+// Mdcv is ISOBMFF mdcv box type (mastering display colour volume)
+type Mdcv struct {
+	Box
+	DisplayPrimaries             [3]MdcvPrimary `mp4:"0"`
+	WhitePointX                  uint16         `mp4:"1,size=16"`
+	WhitePointY                  uint16         `mp4:"2,size=16"`
+	MaxDisplayMasteringLuminance uint32         `mp4:"3,size=32"`
+	MinDisplayMasteringLuminance uint32         `mp4:"4,size=32"`
+}
+
+type MdcvPrimary struct {
+	BaseCustomFieldObject
+	X uint16 `mp4:"0,size=16"`
+	Y uint16 `mp4:"1,size=16"`
+}
+
+// GetType returns the BoxType
+func (*Mdcv) GetType() BoxType {
+	return BoxTypeMdcv()
+}
+
+// StringifyField returns field value as string
+func (mdcv *Mdcv) StringifyField(name string, indent string, depth int, ctx Context) (string, bool) {
+	switch name {
+	case "WhitePointX":
+		return formatChromaticity(int64(mdcv.WhitePointX)), true
+	case "WhitePointY":
+		return formatChromaticity(int64(mdcv.WhitePointY)), true
+	case "MaxDisplayMasteringLuminance":
+		// in units of 0.0001 cd/m2
+		return util.FormatFixedDecimal(int64(mdcv.MaxDisplayMasteringLuminance), 10000), true
+	case "MinDisplayMasteringLuminance":
+		return util.FormatFixedDecimal(int64(mdcv.MinDisplayMasteringLuminance), 10000), true
+	default:
+		return "", false
+	}
+}
+
+// StringifyField returns field value as string
+func (p *MdcvPrimary) StringifyField(name string, indent string, depth int, ctx Context) (string, bool) {
+	switch name {
+	case "X":
+		return formatChromaticity(int64(p.X)), true
+	case "Y":
+		return formatChromaticity(int64(p.Y)), true
+	default:
+		return "", false
+	}
+}
+
 /*************************** mdhd ****************************/
 
 func BoxTypeMdhd() BoxType { return StrToBoxType("mdhd") }
@@ -965,6 +1818,24 @@ func (*Mdia) GetType() BoxType {
 	return BoxTypeMdia()
 }
 
+/*************************** meco ****************************/
+
+func BoxTypeMeco() BoxType { return StrToBoxType("meco") }
+
+func init() {
+	AddBoxDef(&Meco{})
+}
+
+// Meco is ISOBMFF meco box type
+type Meco struct {
+	Box
+}
+
+// GetType returns the BoxType
+func (*Meco) GetType() BoxType {
+	return BoxTypeMeco()
+}
+
 /*************************** mehd ****************************/
 
 func BoxTypeMehd() BoxType { return StrToBoxType("mehd") }
@@ -996,6 +1867,27 @@ func (mdhd *Mehd) GetFragmentDuration() uint64 {
 	}
 }
 
+/*************************** mere ****************************/
+
+func BoxTypeMere() BoxType { return StrToBoxType("mere") }
+
+func init() {
+	AddBoxDef(&Mere{}, 0)
+}
+
+// Mere is ISOBMFF mere box type
+type Mere struct {
+	FullBox                  `mp4:"0,extend"`
+	FirstMetaboxHandlerType  [4]byte `mp4:"1,size=8,string"`
+	SecondMetaboxHandlerType [4]byte `mp4:"2,size=8,string"`
+	MetaboxRelation          uint8   `mp4:"3,size=8"`
+}
+
+// GetType returns the BoxType
+func (*Mere) GetType() BoxType {
+	return BoxTypeMere()
+}
+
 /*************************** meta ****************************/
 
 func BoxTypeMeta() BoxType { return StrToBoxType("meta") }
@@ -1223,43 +2115,145 @@ func (mvhd *Mvhd) GetModificationTime() uint64 {
 	}
 }
 
-func (mvhd *Mvhd) GetDuration() uint64 {
-	switch mvhd.GetVersion() {
-	case 0:
-		return uint64(mvhd.DurationV0)
-	case 1:
-		return mvhd.DurationV1
-	default:
-		return 0
-	}
+func (mvhd *Mvhd) GetDuration() uint64 {
+	switch mvhd.GetVersion() {
+	case 0:
+		return uint64(mvhd.DurationV0)
+	case 1:
+		return mvhd.DurationV1
+	default:
+		return 0
+	}
+}
+
+// GetRate returns value of rate as float64
+func (mvhd *Mvhd) GetRate() float64 {
+	return float64(mvhd.Rate) / (1 << 16)
+}
+
+// GetRateInt returns value of rate as int16
+func (mvhd *Mvhd) GetRateInt() int16 {
+	return int16(mvhd.Rate >> 16)
+}
+
+/*************************** nmhd ****************************/
+
+func BoxTypeNmhd() BoxType { return StrToBoxType("nmhd") }
+
+func init() {
+	AddBoxDef(&Nmhd{}, 0)
+}
+
+// Nmhd is ISOBMFF nmhd box type
+type Nmhd struct {
+	FullBox `mp4:"0,extend"`
+}
+
+// GetType returns the BoxType
+func (*Nmhd) GetType() BoxType {
+	return BoxTypeNmhd()
+}
+
+/*************************** padb ****************************/
+
+func BoxTypePadb() BoxType { return StrToBoxType("padb") }
+
+func init() {
+	AddBoxDef(&Padb{}, 0)
+}
+
+// Padb is ISOBMFF padb box type
+type Padb struct {
+	FullBox     `mp4:"0,extend"`
+	SampleCount uint32      `mp4:"1,size=32"`
+	Entries     []PadbEntry `mp4:"2,size=8,len=dynamic"`
+}
+
+// PadbEntry holds the padding bits of two samples.
+type PadbEntry struct {
+	Reserved1 uint8 `mp4:"0,size=1,const=0"`
+	Pad1      uint8 `mp4:"1,size=3"`
+	Reserved2 uint8 `mp4:"2,size=1,const=0"`
+	Pad2      uint8 `mp4:"3,size=3"`
+}
+
+// GetType returns the BoxType
+func (*Padb) GetType() BoxType {
+	return BoxTypePadb()
+}
+
+// GetFieldLength returns length of dynamic field
+func (padb *Padb) GetFieldLength(name string, ctx Context) uint {
+	switch name {
+	case "Entries":
+		return uint((uint64(padb.SampleCount) + 1) / 2)
+	}
+	panic(fmt.Errorf("invalid name of dynamic-length field: boxType=padb fieldName=%s", name))
+}
+
+/*************************** pitm ****************************/
+
+func BoxTypePitm() BoxType { return StrToBoxType("pitm") }
+
+func init() {
+	AddBoxDef(&Pitm{}, 0, 1)
+}
+
+// Pitm is ISOBMFF pitm box type
+type Pitm struct {
+	FullBox `mp4:"0,extend"`
+	ItemID  uint32 `mp4:"1,size=dynamic"`
 }
 
-// GetRate returns value of rate as float64
-func (mvhd *Mvhd) GetRate() float64 {
-	return float64(mvhd.Rate) / (1 << 16)
+// GetType returns the BoxType
+func (*Pitm) GetType() BoxType {
+	return BoxTypePitm()
 }
 
-// GetRateInt returns value of rate as int16
-func (mvhd *Mvhd) GetRateInt() int16 {
-	return int16(mvhd.Rate >> 16)
+// GetFieldSize returns size of dynamic field
+func (pitm *Pitm) GetFieldSize(name string, ctx Context) uint {
+	switch name {
+	case "ItemID":
+		if pitm.GetVersion() == 0 {
+			return 16
+		}
+		return 32
+	}
+	panic(fmt.Errorf("invalid name of dynamic-size field: boxType=pitm fieldName=%s", name))
 }
 
-/*************************** nmhd ****************************/
+/*************************** prft ****************************/
 
-func BoxTypeNmhd() BoxType { return StrToBoxType("nmhd") }
+func BoxTypePrft() BoxType { return StrToBoxType("prft") }
 
 func init() {
-	AddBoxDef(&Nmhd{}, 0)
+	AddBoxDef(&Prft{}, 0, 1)
 }
 
-// Nmhd is ISOBMFF nmhd box type
-type Nmhd struct {
-	FullBox `mp4:"0,extend"`
+// Prft is ISOBMFF prft box type
+type Prft struct {
+	FullBox          `mp4:"0,extend"`
+	ReferenceTrackID uint32 `mp4:"1,size=32"`
+	// NTPTimestamp is in NTP format, which has 32-bit seconds and 32-bit fraction
+	NTPTimestamp uint64 `mp4:"2,size=64"`
+	MediaTimeV0  uint32 `mp4:"3,size=32,ver=0"`
+	MediaTimeV1  uint64 `mp4:"4,size=64,nver=0"`
 }
 
 // GetType returns the BoxType
-func (*Nmhd) GetType() BoxType {
-	return BoxTypeNmhd()
+func (*Prft) GetType() BoxType {
+	return BoxTypePrft()
+}
+
+func (prft *Prft) GetMediaTime() uint64 {
+	switch prft.GetVersion() {
+	case 0:
+		return uint64(prft.MediaTimeV0)
+	case 1:
+		return prft.MediaTimeV1
+	default:
+		return 0
+	}
 }
 
 /*************************** saio ****************************/
@@ -1555,6 +2549,15 @@ func (s *AVCParameterSet) GetFieldLength(name string, ctx Context) uint {
 	return 0
 }
 
+// StringifyField returns field value as string
+func (s *AVCParameterSet) StringifyField(name string, indent string, depth int, ctx Context) (string, bool) {
+	switch name {
+	case "NALUnit":
+		return stringifyParameterSet(s.NALUnit, false)
+	}
+	return "", false
+}
+
 type PixelAspectRatioBox struct {
 	AnyTypeBox
 	HSpacing uint32 `mp4:"0,size=32"`
@@ -1710,6 +2713,8 @@ type Sgpd struct {
 	TemporalLevelEntries          []TemporalLevelEntry       `mp4:"11,len=dynamic,opt=dynamic"`
 	TemporalLevelEntriesL         []TemporalLevelEntryL      `mp4:"12,len=dynamic,opt=dynamic"`
 	Unsupported                   []byte                     `mp4:"13,size=8,opt=dynamic"`
+	// Entries are the entries of the grouping types registered by AddSampleGroupEntryDef.
+	Entries []ISampleGroupEntry `mp4:"14,opt=dynamic"`
 }
 
 type RollDistanceWithLength struct {
@@ -1781,6 +2786,11 @@ func (sgpd *Sgpd) IsOptFieldEnabled(name string, ctx Context) bool {
 	alternativeStartupEntries := sgpd.GroupingType == [4]byte{'a', 'l', 's', 't'}
 	visualRandomAccessEntries := sgpd.GroupingType == [4]byte{'r', 'a', 'p', ' '}
 	temporalLevelEntries := sgpd.GroupingType == [4]byte{'t', 'e', 'l', 'e'}
+	builtin := rollDistances ||
+		alternativeStartupEntries ||
+		visualRandomAccessEntries ||
+		temporalLevelEntries
+	_, registered := sampleGroupEntryDefs[sgpd.GroupingType]
 	switch name {
 	case "RollDistances":
 		return rollDistances && !noDefaultLength
@@ -1798,11 +2808,10 @@ func (sgpd *Sgpd) IsOptFieldEnabled(name string, ctx Context) bool {
 		return temporalLevelEntries && !noDefaultLength
 	case "TemporalLevelEntriesL":
 		return temporalLevelEntries && noDefaultLength
+	case "Entries":
+		return !builtin && registered
 	case "Unsupported":
-		return !rollDistances &&
-			!alternativeStartupEntries &&
-			!visualRandomAccessEntries &&
-			!temporalLevelEntries
+		return !builtin && !registered
 	default:
 		return false
 	}
@@ -1812,6 +2821,141 @@ func (*Sgpd) GetType() BoxType {
 	return BoxTypeSgpd()
 }
 
+func (sgpd *Sgpd) OnReadField(name string, r bitio.ReadSeeker, leftBits uint64, ctx Context) (rbits uint64, override bool, err error) {
+	if name != "Entries" {
+		return 0, false, nil
+	}
+	def := sampleGroupEntryDefs[sgpd.GroupingType]
+	noDefaultLength := sgpd.Version == 1 && sgpd.DefaultLength == 0
+	sgpd.Entries = make([]ISampleGroupEntry, 0, sgpd.EntryCount)
+	for i := uint32(0); i < sgpd.EntryCount; i++ {
+		// size is unknown on version 2, so the entry is limited to the rest of the box
+		size := leftBits - rbits
+		if noDefaultLength {
+			if size < 32 {
+				return 0, false, errors.New("sgpd: description length exceeds the box size")
+			}
+			data, err := r.ReadBits(32)
+			if err != nil {
+				return 0, false, err
+			}
+			rbits += 32
+			size = uint64(binary.BigEndian.Uint32(data)) * 8
+		} else if sgpd.Version == 1 {
+			size = uint64(sgpd.DefaultLength) * 8
+		}
+		if size > leftBits-rbits {
+			return 0, false, errors.New("sgpd: sample group entry exceeds the box size")
+		}
+
+		entry := reflect.New(def.dataType)
+		u := &unmarshaller{reader: r, dst: sgpd, size: size / 8, ctx: ctx}
+		if err := u.unmarshalStruct(entry.Elem(), def.fields); err != nil {
+			return 0, false, err
+		}
+		if u.rbits%8 != 0 {
+			return 0, false, fmt.Errorf("sgpd: sample group entry is not multiple of 8 bits: groupingType=%s", string(sgpd.GroupingType[:]))
+		}
+		if noDefaultLength || sgpd.Version == 1 {
+			// skip the trailing data which is not defined by the entry type
+			if _, err := r.Seek(int64((size-u.rbits)/8), io.SeekCurrent); err != nil {
+				return 0, false, err
+			}
+			rbits += size
+		} else {
+			rbits += u.rbits
+		}
+		sgpd.Entries = append(sgpd.Entries, entry.Interface().(ISampleGroupEntry))
+	}
+	return rbits, true, nil
+}
+
+func (sgpd *Sgpd) OnWriteField(name string, w bitio.Writer, ctx Context) (wbits uint64, override bool, err error) {
+	if name != "Entries" {
+		return 0, false, nil
+	}
+	if len(sgpd.Entries) != int(sgpd.EntryCount) {
+		return 0, false, errors.New("sgpd: EntryCount and the number of Entries are inconsistent")
+	}
+	def := sampleGroupEntryDefs[sgpd.GroupingType]
+	noDefaultLength := sgpd.Version == 1 && sgpd.DefaultLength == 0
+	for _, entry := range sgpd.Entries {
+		v := reflect.ValueOf(entry)
+		if v.Type() != reflect.PtrTo(def.dataType) {
+			return 0, false, fmt.Errorf("sgpd: unexpected sample group entry type: groupingType=%s type=%s", string(sgpd.GroupingType[:]), v.Type())
+		}
+		buf := bytes.NewBuffer(nil)
+		m := &marshaller{writer: bitio.NewWriter(buf), src: sgpd, ctx: ctx}
+		if err := m.marshalStruct(v.Elem(), def.fields); err != nil {
+			return 0, false, err
+		}
+		if m.wbits%8 != 0 {
+			return 0, false, fmt.Errorf("sgpd: sample group entry is not multiple of 8 bits: groupingType=%s", string(sgpd.GroupingType[:]))
+		}
+		if noDefaultLength {
+			length := make([]byte, 4)
+			binary.BigEndian.PutUint32(length, uint32(buf.Len()))
+			if _, err := w.Write(length); err != nil {
+				return 0, false, err
+			}
+			wbits += 32
+		} else if sgpd.Version == 1 && uint32(buf.Len()) != sgpd.DefaultLength {
+			return 0, false, errors.New("sgpd: DefaultLength and the size of the sample group entry are inconsistent")
+		}
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return 0, false, err
+		}
+		wbits += uint64(buf.Len()) * 8
+	}
+	return wbits, true, nil
+}
+
+// StringifyField returns field value as string
+func (sgpd *Sgpd) StringifyField(name string, indent string, depth int, ctx Context) (string, bool) {
+	if name != "Entries" {
+		return "", false
+	}
+	def := sampleGroupEntryDefs[sgpd.GroupingType]
+	m := &stringifier{buf: bytes.NewBuffer(nil), src: sgpd, indent: indent, ctx: ctx}
+	m.buf.WriteString("[")
+	for i, entry := range sgpd.Entries {
+		if i != 0 {
+			m.buf.WriteString(", ")
+		}
+		if err := m.stringifyStruct(reflect.ValueOf(entry).Elem(), def.fields, depth, false); err != nil {
+			return "", false
+		}
+	}
+	m.buf.WriteString("]")
+	return m.buf.String(), true
+}
+
+// ISampleGroupEntry is an entry of the sgpd box.
+// The entry is marshalled and unmarshalled in the same manner as boxes,
+// so its fields are declared by mp4 struct tags.
+type ISampleGroupEntry interface {
+	ICustomFieldObject
+}
+
+type sampleGroupEntryDef struct {
+	dataType reflect.Type
+	fields   []*field
+}
+
+var sampleGroupEntryDefs = map[[4]byte]sampleGroupEntryDef{}
+
+// AddSampleGroupEntryDef registers the entry type of the grouping type,
+// and the entries of the sgpd box of the grouping type are stored in Sgpd.Entries.
+// entry must be a pointer to a struct which embeds BaseCustomFieldObject.
+// The grouping types which have their own fields in Sgpd (roll, prol, alst, rap and tele) cannot be overridden.
+func AddSampleGroupEntryDef(groupingType [4]byte, entry ISampleGroupEntry) {
+	t := reflect.TypeOf(entry).Elem()
+	sampleGroupEntryDefs[groupingType] = sampleGroupEntryDef{
+		dataType: t,
+		fields:   buildFieldsStruct(t),
+	}
+}
+
 func (entry *AlternativeStartupEntry) GetFieldLength(name string, ctx Context) uint {
 	switch name {
 	case "SampleOffset":
@@ -1828,6 +2972,272 @@ func (entry *AlternativeStartupEntryL) GetFieldSize(name string, ctx Context) ui
 	return 0
 }
 
+func init() {
+	AddSampleGroupEntryDef([4]byte{'s', 'y', 'n', 'c'}, &SyncSampleEntry{})
+	AddSampleGroupEntryDef([4]byte{'r', 'a', 's', 'h'}, &RateShareEntry{})
+	AddSampleGroupEntryDef([4]byte{'t', 's', 'c', 'l'}, &TemporalLayerEntry{})
+	AddSampleGroupEntryDef([4]byte{'s', 't', 'm', 'i'}, &SampleToMetadataItemEntry{})
+	AddSampleGroupEntryDef([4]byte{'o', 'i', 'n', 'f'}, &OperatingPointsInformation{})
+	AddSampleGroupEntryDef([4]byte{'l', 'i', 'n', 'f'}, &LayerInfoGroupEntry{})
+}
+
+// SyncSampleEntry is the sample group entry of the sync grouping type defined in ISO/IEC 14496-15
+type SyncSampleEntry struct {
+	BaseCustomFieldObject
+	Reserved    uint8 `mp4:"0,size=2,const=0"`
+	NALUnitType uint8 `mp4:"1,size=6"`
+}
+
+// RateShareEntry is the sample group entry of the rash grouping type
+type RateShareEntry struct {
+	BaseCustomFieldObject
+	OperationPointCount uint16                    `mp4:"0,size=16"`
+	TargetRateShare     uint16                    `mp4:"1,size=16,opt=dynamic"`
+	OperationPoints     []RateShareOperationPoint `mp4:"2,size=48,len=dynamic,opt=dynamic"`
+	MaximumBitrate      uint32                    `mp4:"3,size=32"`
+	MinimumBitrate      uint32                    `mp4:"4,size=32"`
+	DiscardPriority     uint8                     `mp4:"5,size=8"`
+}
+
+type RateShareOperationPoint struct {
+	AvailableBitrate uint32 `mp4:"0,size=32"`
+	TargetRateShare  uint16 `mp4:"1,size=16"`
+}
+
+// IsOptFieldEnabled check whether if the optional field is enabled
+func (entry *RateShareEntry) IsOptFieldEnabled(name string, ctx Context) bool {
+	switch name {
+	case "TargetRateShare":
+		return entry.OperationPointCount == 1
+	case "OperationPoints":
+		return entry.OperationPointCount != 1
+	}
+	return false
+}
+
+// GetFieldLength returns length of dynamic field
+func (entry *RateShareEntry) GetFieldLength(name string, ctx Context) uint {
+	switch name {
+	case "OperationPoints":
+		return uint(entry.OperationPointCount)
+	}
+	panic(fmt.Errorf("invalid name of dynamic-length field: groupingType=rash fieldName=%s", name))
+}
+
+// TemporalLayerEntry is the sample group entry of the tscl grouping type defined in ISO/IEC 14496-15
+type TemporalLayerEntry struct {
+	BaseCustomFieldObject
+	TemporalLayerID             uint8  `mp4:"0,size=8"`
+	TLProfileSpace              uint8  `mp4:"1,size=2"`
+	TLTierFlag                  bool   `mp4:"2,size=1"`
+	TLProfileIdc                uint8  `mp4:"3,size=5"`
+	TLProfileCompatibilityFlags uint32 `mp4:"4,size=32,hex"`
+	TLConstraintIndicatorFlags  uint64 `mp4:"5,size=48,hex"`
+	TLLevelIdc                  uint8  `mp4:"6,size=8"`
+	TLMaxBitRate                uint16 `mp4:"7,size=16"`
+	TLAvgBitRate                uint16 `mp4:"8,size=16"`
+	TLConstantFrameRate         uint8  `mp4:"9,size=8"`
+	TLAvgFrameRate              uint16 `mp4:"10,size=16"`
+}
+
+// SampleToMetadataItemEntry is the sample group entry of the stmi grouping type
+type SampleToMetadataItemEntry struct {
+	BaseCustomFieldObject
+	MetaBoxHandlerType [4]byte  `mp4:"0,size=8,string"`
+	NumItems           uint32   `mp4:"1,size=32"`
+	ItemIDs            []uint32 `mp4:"2,size=32,len=dynamic"`
+}
+
+// GetFieldLength returns length of dynamic field
+func (entry *SampleToMetadataItemEntry) GetFieldLength(name string, ctx Context) uint {
+	switch name {
+	case "ItemIDs":
+		return uint(entry.NumItems)
+	}
+	panic(fmt.Errorf("invalid name of dynamic-length field: groupingType=stmi fieldName=%s", name))
+}
+
+// OperatingPointsInformation is the sample group entry of the oinf grouping type defined in ISO/IEC 14496-15
+type OperatingPointsInformation struct {
+	BaseCustomFieldObject
+	ScalabilityMask     uint16                 `mp4:"0,size=16,hex"`
+	Reserved            uint8                  `mp4:"1,size=2,const=0"`
+	NumProfileTierLevel uint8                  `mp4:"2,size=6"`
+	ProfileTierLevels   []OinfProfileTierLevel `mp4:"3,size=96,len=dynamic"`
+	NumOperatingPoints  uint16                 `mp4:"4,size=16"`
+	OperatingPoints     []OinfOperatingPoint   `mp4:"5,len=dynamic"`
+	MaxLayerCount       uint8                  `mp4:"6,size=8"`
+	// Layers are read and written by OperatingPointsInformation,
+	// because the number of dimension identifiers depends on ScalabilityMask.
+	Layers []OinfLayer `mp4:"7"`
+}
+
+type OinfProfileTierLevel struct {
+	GeneralProfileSpace              uint8  `mp4:"0,size=2"`
+	GeneralTierFlag                  bool   `mp4:"1,size=1"`
+	GeneralProfileIdc                uint8  `mp4:"2,size=5"`
+	GeneralProfileCompatibilityFlags uint32 `mp4:"3,size=32,hex"`
+	GeneralConstraintIndicatorFlags  uint64 `mp4:"4,size=48,hex"`
+	GeneralLevelIdc                  uint8  `mp4:"5,size=8"`
+}
+
+type OinfOperatingPoint struct {
+	BaseCustomFieldObject
+	OutputLayerSetIdx uint16                    `mp4:"0,size=16"`
+	MaxTemporalID     uint8                     `mp4:"1,size=8"`
+	LayerCount        uint8                     `mp4:"2,size=8"`
+	Layers            []OinfOperatingPointLayer `mp4:"3,size=16,len=dynamic"`
+	MinPicWidth       uint16                    `mp4:"4,size=16"`
+	MinPicHeight      uint16                    `mp4:"5,size=16"`
+	MaxPicWidth       uint16                    `mp4:"6,size=16"`
+	MaxPicHeight      uint16                    `mp4:"7,size=16"`
+	MaxChromaFormat   uint8                     `mp4:"8,size=2"`
+	MaxBitDepthMinus8 uint8                     `mp4:"9,size=3"`
+	Reserved          uint8                     `mp4:"10,size=1,const=0"`
+	FrameRateInfoFlag bool                      `mp4:"11,size=1"`
+	BitRateInfoFlag   bool                      `mp4:"12,size=1"`
+	AvgFrameRate      uint16                    `mp4:"13,size=16,opt=dynamic"`
+	Reserved2         uint8                     `mp4:"14,size=6,opt=dynamic,const=0"`
+	ConstantFrameRate uint8                     `mp4:"15,size=2,opt=dynamic"`
+	MaxBitRate        uint32                    `mp4:"16,size=32,opt=dynamic"`
+	AvgBitRate        uint32                    `mp4:"17,size=32,opt=dynamic"`
+}
+
+type OinfOperatingPointLayer struct {
+	PTLIdx                 uint8 `mp4:"0,size=8"`
+	LayerID                uint8 `mp4:"1,size=6"`
+	IsOutputLayer          bool  `mp4:"2,size=1"`
+	IsAlternateOutputLayer bool  `mp4:"3,size=1"`
+}
+
+type OinfLayer struct {
+	LayerID              uint8   `mp4:"0,size=8"`
+	NumDirectRefLayers   uint8   `mp4:"1,size=8"`
+	DirectRefLayerIDs    []uint8 `mp4:"2,size=8"`
+	DimensionIdentifiers []uint8 `mp4:"3,size=8"`
+}
+
+// GetFieldLength returns length of dynamic field
+func (entry *OperatingPointsInformation) GetFieldLength(name string, ctx Context) uint {
+	switch name {
+	case "ProfileTierLevels":
+		return uint(entry.NumProfileTierLevel)
+	case "OperatingPoints":
+		return uint(entry.NumOperatingPoints)
+	}
+	panic(fmt.Errorf("invalid name of dynamic-length field: groupingType=oinf fieldName=%s", name))
+}
+
+// dimensionCount returns the number of the dimension identifiers of each layer.
+func (entry *OperatingPointsInformation) dimensionCount() int {
+	var n int
+	for mask := entry.ScalabilityMask; mask != 0; mask >>= 1 {
+		n += int(mask & 1)
+	}
+	return n
+}
+
+func (entry *OperatingPointsInformation) OnReadField(name string, r bitio.ReadSeeker, leftBits uint64, ctx Context) (rbits uint64, override bool, err error) {
+	if name != "Layers" {
+		return 0, false, nil
+	}
+	entry.Layers = make([]OinfLayer, 0, entry.MaxLayerCount)
+	readBytes := func(n int) ([]byte, error) {
+		if uint64(n)*8 > leftBits-rbits {
+			return nil, errors.New("oinf: layer exceeds the entry size")
+		}
+		data := make([]byte, n)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		rbits += uint64(n) * 8
+		return data, nil
+	}
+	for i := 0; i < int(entry.MaxLayerCount); i++ {
+		header, err := readBytes(2)
+		if err != nil {
+			return 0, false, err
+		}
+		layer := OinfLayer{LayerID: header[0], NumDirectRefLayers: header[1]}
+		if layer.DirectRefLayerIDs, err = readBytes(int(layer.NumDirectRefLayers)); err != nil {
+			return 0, false, err
+		}
+		if layer.DimensionIdentifiers, err = readBytes(entry.dimensionCount()); err != nil {
+			return 0, false, err
+		}
+		entry.Layers = append(entry.Layers, layer)
+	}
+	return rbits, true, nil
+}
+
+func (entry *OperatingPointsInformation) OnWriteField(name string, w bitio.Writer, ctx Context) (wbits uint64, override bool, err error) {
+	if name != "Layers" {
+		return 0, false, nil
+	}
+	if len(entry.Layers) != int(entry.MaxLayerCount) {
+		return 0, false, errors.New("oinf: MaxLayerCount and the number of Layers are inconsistent")
+	}
+	for _, layer := range entry.Layers {
+		if len(layer.DirectRefLayerIDs) != int(layer.NumDirectRefLayers) {
+			return 0, false, errors.New("oinf: NumDirectRefLayers and the number of DirectRefLayerIDs are inconsistent")
+		}
+		if len(layer.DimensionIdentifiers) != entry.dimensionCount() {
+			return 0, false, errors.New("oinf: ScalabilityMask and the number of DimensionIdentifiers are inconsistent")
+		}
+		data := append([]byte{layer.LayerID, layer.NumDirectRefLayers}, layer.DirectRefLayerIDs...)
+		data = append(data, layer.DimensionIdentifiers...)
+		if _, err := w.Write(data); err != nil {
+			return 0, false, err
+		}
+		wbits += uint64(len(data)) * 8
+	}
+	return wbits, true, nil
+}
+
+// IsOptFieldEnabled check whether if the optional field is enabled
+func (op *OinfOperatingPoint) IsOptFieldEnabled(name string, ctx Context) bool {
+	switch name {
+	case "AvgFrameRate", "Reserved2", "ConstantFrameRate":
+		return op.FrameRateInfoFlag
+	case "MaxBitRate", "AvgBitRate":
+		return op.BitRateInfoFlag
+	}
+	return false
+}
+
+// GetFieldLength returns length of dynamic field
+func (op *OinfOperatingPoint) GetFieldLength(name string, ctx Context) uint {
+	switch name {
+	case "Layers":
+		return uint(op.LayerCount)
+	}
+	panic(fmt.Errorf("invalid name of dynamic-length field: groupingType=oinf fieldName=%s", name))
+}
+
+// LayerInfoGroupEntry is the sample group entry of the linf grouping type defined in ISO/IEC 14496-15
+type LayerInfoGroupEntry struct {
+	BaseCustomFieldObject
+	Reserved         uint8       `mp4:"0,size=2,const=0"`
+	NumLayersInTrack uint8       `mp4:"1,size=6"`
+	Layers           []LinfLayer `mp4:"2,size=16,len=dynamic"`
+}
+
+type LinfLayer struct {
+	Reserved      uint8 `mp4:"0,size=4,const=0"`
+	LayerID       uint8 `mp4:"1,size=6"`
+	MinSubLayerID uint8 `mp4:"2,size=3"`
+	MaxSubLayerID uint8 `mp4:"3,size=3"`
+}
+
+// GetFieldLength returns length of dynamic field
+func (entry *LayerInfoGroupEntry) GetFieldLength(name string, ctx Context) uint {
+	switch name {
+	case "Layers":
+		return uint(entry.NumLayersInTrack)
+	}
+	panic(fmt.Errorf("invalid name of dynamic-length field: groupingType=linf fieldName=%s", name))
+}
+
 /*************************** sidx ****************************/
 
 func BoxTypeSidx() BoxType { return StrToBoxType("sidx") }
@@ -1946,6 +3356,55 @@ func (smhd *Smhd) GetBalanceInt() int8 {
 	return int8(smhd.Balance >> 8)
 }
 
+/*************************** ssix ****************************/
+
+func BoxTypeSsix() BoxType { return StrToBoxType("ssix") }
+
+func init() {
+	AddBoxDef(&Ssix{}, 0)
+}
+
+// Ssix is ISOBMFF ssix box type
+type Ssix struct {
+	FullBox         `mp4:"0,extend"`
+	SubsegmentCount uint32           `mp4:"1,size=32"`
+	Subsegments     []SsixSubsegment `mp4:"2,len=dynamic"`
+}
+
+type SsixSubsegment struct {
+	BaseCustomFieldObject
+	RangeCount uint32      `mp4:"0,size=32"`
+	Ranges     []SsixRange `mp4:"1,size=32,len=dynamic"`
+}
+
+type SsixRange struct {
+	Level     uint8  `mp4:"0,size=8"`
+	RangeSize uint32 `mp4:"1,size=24"`
+}
+
+// GetType returns the BoxType
+func (*Ssix) GetType() BoxType {
+	return BoxTypeSsix()
+}
+
+// GetFieldLength returns length of dynamic field
+func (ssix *Ssix) GetFieldLength(name string, ctx Context) uint {
+	switch name {
+	case "Subsegments":
+		return uint(ssix.SubsegmentCount)
+	}
+	panic(fmt.Errorf("invalid name of dynamic-length field: boxType=ssix fieldName=%s", name))
+}
+
+// GetFieldLength returns length of dynamic field
+func (subsegment *SsixSubsegment) GetFieldLength(name string, ctx Context) uint {
+	switch name {
+	case "Ranges":
+		return uint(subsegment.RangeCount)
+	}
+	panic(fmt.Errorf("invalid name of dynamic-length field: boxType=ssix fieldName=%s", name))
+}
+
 /*************************** stbl ****************************/
 
 func BoxTypeStbl() BoxType { return StrToBoxType("stbl") }
@@ -1993,6 +3452,26 @@ func (stco *Stco) GetFieldLength(name string, ctx Context) uint {
 	panic(fmt.Errorf("invalid name of dynamic-length field: boxType=stco fieldName=%s", name))
 }
 
+/*************************** stdp ****************************/
+
+func BoxTypeStdp() BoxType { return StrToBoxType("stdp") }
+
+func init() {
+	AddBoxDef(&Stdp{}, 0)
+}
+
+// Stdp is ISOBMFF stdp box type
+type Stdp struct {
+	FullBox `mp4:"0,extend"`
+	// Priorities has an entry for each sample, and the number of samples is given by the stsz box.
+	Priorities []uint16 `mp4:"1,size=16"`
+}
+
+// GetType returns the BoxType
+func (*Stdp) GetType() BoxType {
+	return BoxTypeStdp()
+}
+
 /*************************** sthd ****************************/
 
 func BoxTypeSthd() BoxType { return StrToBoxType("sthd") }
@@ -2065,6 +3544,40 @@ func (*Stsd) GetType() BoxType {
 	return BoxTypeStsd()
 }
 
+/*************************** stsh ****************************/
+
+func BoxTypeStsh() BoxType { return StrToBoxType("stsh") }
+
+func init() {
+	AddBoxDef(&Stsh{}, 0)
+}
+
+// Stsh is ISOBMFF stsh box type
+type Stsh struct {
+	FullBox    `mp4:"0,extend"`
+	EntryCount uint32      `mp4:"1,size=32"`
+	Entries    []StshEntry `mp4:"2,size=64,len=dynamic"`
+}
+
+type StshEntry struct {
+	ShadowedSampleNumber uint32 `mp4:"0,size=32"`
+	SyncSampleNumber     uint32 `mp4:"1,size=32"`
+}
+
+// GetType returns the BoxType
+func (*Stsh) GetType() BoxType {
+	return BoxTypeStsh()
+}
+
+// GetFieldLength returns length of dynamic field
+func (stsh *Stsh) GetFieldLength(name string, ctx Context) uint {
+	switch name {
+	case "Entries":
+		return uint(stsh.EntryCount)
+	}
+	panic(fmt.Errorf("invalid name of dynamic-length field: boxType=stsh fieldName=%s", name))
+}
+
 /*************************** stss ****************************/
 
 func BoxTypeStss() BoxType { return StrToBoxType("stss") }
@@ -2224,7 +3737,7 @@ func (stz2 *Stz2) GetFieldSize(name string, ctx Context) uint {
 func BoxTypeSubs() BoxType { return StrToBoxType("subs") }
 
 func init() {
-	AddBoxDef(&Subs{}, 0)
+	AddBoxDef(&Subs{}, 0, 1)
 }
 
 // Subs is ISOBMFF subs box type
@@ -2253,7 +3766,6 @@ type SubSampleInformationEntry struct {
 	SampleDelta          uint32                 `mp4:"0,size=32"`
 	SubsampleCount       uint16                 `mp4:"1,size=16"`
 	SubsampleInformation []SubSampleInformation `mp4:"2,len=dynamic"`
-	// TODO: Cannot calculate the size of SubsampleInformation because the version of the parent 'subs' box is unavailable.
 }
 
 // GetFieldLength returns length of dynamic field
@@ -2572,6 +4084,65 @@ func (*Trak) GetType() BoxType {
 	return BoxTypeTrak()
 }
 
+/*************************** tref ****************************/
+
+func BoxTypeTref() BoxType { return StrToBoxType("tref") }
+func BoxTypeHint() BoxType { return StrToBoxType("hint") }
+func BoxTypeCdsc() BoxType { return StrToBoxType("cdsc") }
+func BoxTypeChap() BoxType { return StrToBoxType("chap") }
+func BoxTypeSubt() BoxType { return StrToBoxType("subt") }
+
+var trackReferenceTypes = []BoxType{
+	BoxTypeHint(),
+	BoxTypeCdsc(),
+	BoxTypeChap(),
+	BoxTypeSubt(),
+	StrToBoxType("font"),
+	StrToBoxType("hind"),
+	StrToBoxType("vdep"),
+	StrToBoxType("vplx"),
+	StrToBoxType("thmb"),
+	StrToBoxType("auxl"),
+	StrToBoxType("cdtg"),
+	StrToBoxType("shsc"),
+	StrToBoxType("aest"),
+	StrToBoxType("sync"),
+	StrToBoxType("mpod"),
+	StrToBoxType("dpnd"),
+	StrToBoxType("ipir"),
+	StrToBoxType("sbas"),
+	StrToBoxType("scal"),
+	StrToBoxType("tbas"),
+	StrToBoxType("oref"),
+}
+
+func init() {
+	AddBoxDef(&Tref{})
+	for _, bt := range trackReferenceTypes {
+		AddAnyTypeBoxDefEx(&TrackReferenceType{}, bt, isUnderTref)
+	}
+}
+
+// Tref is ISOBMFF tref box type
+type Tref struct {
+	Box
+}
+
+// GetType returns the BoxType
+func (*Tref) GetType() BoxType {
+	return BoxTypeTref()
+}
+
+func isUnderTref(ctx Context) bool {
+	return ctx.UnderTref
+}
+
+// TrackReferenceType is TrackReferenceTypeBox, whose box type is the reference type.
+type TrackReferenceType struct {
+	AnyTypeBox
+	TrackIDs []uint32 `mp4:"0,size=32"`
+}
+
 /*************************** trep ****************************/
 
 func BoxTypeTrep() BoxType { return StrToBoxType("trep") }
@@ -2641,6 +4212,23 @@ type TrunEntry struct {
 	SampleCompositionTimeOffsetV1 int32  `mp4:"4,size=32,opt=0x000800,nver=0"`
 }
 
+const (
+	TrunDataOffsetPresent                  = 0x000001
+	TrunFirstSampleFlagsPresent            = 0x000004
+	TrunSampleDurationPresent              = 0x000100
+	TrunSampleSizePresent                  = 0x000200
+	TrunSampleFlagsPresent                 = 0x000400
+	TrunSampleCompositionTimeOffsetPresent = 0x000800
+)
+
+// sample flags used by tfhd, trex and trun
+const (
+	SampleFlagIsNonSyncSample = 0x00010000
+	SampleFlagDependsOnMask   = 0x03000000
+	SampleFlagDependsOnOthers = 0x01000000
+	SampleFlagDependsOnNone   = 0x02000000
+)
+
 // GetType returns the BoxType
 func (*Trun) GetType() BoxType {
 	return BoxTypeTrun()
@@ -19,6 +19,21 @@ func TestBoxTypesISO14496_12(t *testing.T) {
 		str  string
 		ctx  Context
 	}{
+		{
+			name: "amve",
+			src: &Amve{
+				AmbientIlluminance: 3140000,
+				AmbientLightX:      15635,
+				AmbientLightY:      16450,
+			},
+			dst: &Amve{},
+			bin: []byte{
+				0x00, 0x2f, 0xe9, 0xa0, // AmbientIlluminance
+				0x3d, 0x13, // AmbientLightX
+				0x40, 0x42, // AmbientLightY
+			},
+			str: `AmbientIlluminance=314 AmbientLightX=0.3127 AmbientLightY=0.329`,
+		},
 		{
 			name: "btrt",
 			src: &Btrt{
@@ -34,6 +49,53 @@ func TestBoxTypesISO14496_12(t *testing.T) {
 			},
 			str: `BufferSizeDB=305419896 MaxBitrate=878082202 AvgBitrate=1450744508`,
 		},
+		{
+			name: "cclv",
+			src: &Cclv{
+				CCVPrimariesPresentFlag:         true,
+				CCVMaxLuminanceValuePresentFlag: true,
+				CCVAvgLuminanceValuePresentFlag: true,
+				CCVPrimaries: [3]CCVPrimary{
+					{X: 34000, Y: 16000},
+					{X: 13250, Y: 34500},
+					{X: 7500, Y: -3000},
+				},
+				CCVMaxLuminanceValue: 4000000000,
+				CCVAvgLuminanceValue: 1005000000,
+			},
+			dst: &Cclv{},
+			bin: []byte{
+				0x2c,                   // flags
+				0x00, 0x00, 0x84, 0xd0, // CCVPrimaries[0].X
+				0x00, 0x00, 0x3e, 0x80, // CCVPrimaries[0].Y
+				0x00, 0x00, 0x33, 0xc2, // CCVPrimaries[1].X
+				0x00, 0x00, 0x86, 0xc4, // CCVPrimaries[1].Y
+				0x00, 0x00, 0x1d, 0x4c, // CCVPrimaries[2].X
+				0xff, 0xff, 0xf4, 0x48, // CCVPrimaries[2].Y
+				0xee, 0x6b, 0x28, 0x00, // CCVMaxLuminanceValue
+				0x3b, 0xe7, 0x15, 0x40, // CCVAvgLuminanceValue
+			},
+			str: `CCVPrimariesPresentFlag=true ` +
+				`CCVMinLuminanceValuePresentFlag=false ` +
+				`CCVMaxLuminanceValuePresentFlag=true ` +
+				`CCVAvgLuminanceValuePresentFlag=true ` +
+				`CCVPrimaries=[{X=0.68 Y=0.32}, {X=0.265 Y=0.69}, {X=0.15 Y=-0.06}] ` +
+				`CCVMaxLuminanceValue=400 ` +
+				`CCVAvgLuminanceValue=100.5`,
+		},
+		{
+			name: "clli",
+			src: &Clli{
+				MaxContentLightLevel:    1000,
+				MaxPicAverageLightLevel: 400,
+			},
+			dst: &Clli{},
+			bin: []byte{
+				0x03, 0xe8, // MaxContentLightLevel
+				0x01, 0x90, // MaxPicAverageLightLevel
+			},
+			str: `MaxContentLightLevel=1000 MaxPicAverageLightLevel=400`,
+		},
 		{
 			name: "co64",
 			src: &Co64{
@@ -95,15 +157,35 @@ func TestBoxTypesISO14496_12(t *testing.T) {
 		{
 			name: "colr: nclc",
 			src: &Colr{
-				ColourType: [4]byte{'n', 'c', 'l', 'c'},
-				Unknown:    []byte{0x01, 0x23, 0x45},
+				ColourType:              [4]byte{'n', 'c', 'l', 'c'},
+				ColourPrimaries:         1,
+				TransferCharacteristics: 1,
+				MatrixCoefficients:      1,
 			},
 			dst: &Colr{},
 			bin: []byte{
 				'n', 'c', 'l', 'c',
+				0x00, 0x01, // ColourPrimaries
+				0x00, 0x01, // TransferCharacteristics
+				0x00, 0x01, // MatrixCoefficients
+			},
+			str: `ColourType="nclc" ` +
+				`ColourPrimaries=1 ` +
+				`TransferCharacteristics=1 ` +
+				`MatrixCoefficients=1`,
+		},
+		{
+			name: "colr: unknown",
+			src: &Colr{
+				ColourType: [4]byte{'x', 'x', 'x', 'x'},
+				Unknown:    []byte{0x01, 0x23, 0x45},
+			},
+			dst: &Colr{},
+			bin: []byte{
+				'x', 'x', 'x', 'x',
 				0x01, 0x23, 0x45,
 			},
-			str: `ColourType="nclc" Unknown=[0x1, 0x23, 0x45]`,
+			str: `ColourType="xxxx" Unknown=[0x1, 0x23, 0x45]`,
 		},
 		{
 			name: "cslg: version 0",
@@ -220,6 +302,19 @@ func TestBoxTypesISO14496_12(t *testing.T) {
 			bin:  nil,
 			str:  ``,
 		},
+		{
+			name: "elng",
+			src: &Elng{
+				ExtendedLanguage: "en-US",
+			},
+			dst: &Elng{},
+			bin: []byte{
+				0,                // version
+				0x00, 0x00, 0x00, // flags
+				'e', 'n', '-', 'U', 'S', 0x00, // extended language
+			},
+			str: `Version=0 Flags=0x000000 ExtendedLanguage="en-US"`,
+		},
 		{
 			name: "elst: version 0",
 			src: &Elst{
@@ -556,14 +651,443 @@ func TestBoxTypesISO14496_12(t *testing.T) {
 				`MinSpatialSegmentationIdc=0 ParallelismType=0x0 ChromaFormatIdc=0x1 BitDepthLumaMinus8=0x0 BitDepthChromaMinus8=0x0 ` +
 				`AvgFrameRate=0 ConstantFrameRate=0x0 NumTemporalLayers=0x0 TemporalIdNested=0x3 LengthSizeMinusOne=0x3 NumOfNaluArrays=0x4 ` +
 				`NaluArrays=[{Completeness=false Reserved=false NaluType=0x20 NumNalus=1 Nalus=[{Length=24 NALUnit=[0x40, 0x1, 0xc, 0x1, ` +
-				`0xff, 0xff, 0x1, 0x60, 0x0, 0x0, 0x3, 0x0, 0x90, 0x0, 0x0, 0x3, 0x0, 0x0, 0x3, 0x0, 0x78, 0x99, 0x98, 0x9]}]}, ` +
+				`0xff, 0xff, 0x1, 0x60, 0x0, 0x0, 0x3, 0x0, 0x90, 0x0, 0x0, 0x3, 0x0, 0x0, 0x3, 0x0, 0x78, 0x99, 0x98, 0x9] ` +
+				`VPS={VideoParameterSetID=0 MaxLayersMinus1=0 MaxSubLayersMinus1=0 GeneralProfileIdc=1 GeneralLevelIdc=120}}]}, ` +
 				`{Completeness=false Reserved=false NaluType=0x21 NumNalus=1 Nalus=[{Length=42 NALUnit=[0x6, 0x1, 0x1, 0x1, 0x60, 0x0, ` +
 				`0x0, 0x3, 0x0, 0x90, 0x0, 0x0, 0x3, 0x0, 0x0, 0x3, 0x0, 0x78, 0xa0, 0x3, 0xc0, 0x80, 0x10, 0xe5, 0x96, 0x66, 0x69, 0x24, ` +
 				`0xca, 0xe0, 0x10, 0x0, 0x0, 0x3, 0x0, 0x10, 0x0, 0x0, 0x3, 0x1, 0xe0, 0x80]}]}, {Completeness=false Reserved=false ` +
-				`NaluType=0x22 NumNalus=1 Nalus=[{Length=7 NALUnit=[0x44, 0x1, 0xc1, 0x72, 0xb4, 0x62, 0x40]}]}, ` +
+				`NaluType=0x22 NumNalus=1 Nalus=[{Length=7 NALUnit=[0x44, 0x1, 0xc1, 0x72, 0xb4, 0x62, 0x40] ` +
+				`PPS={PicParameterSetID=0 SeqParameterSetID=0 TilesEnabled=false EntropyCodingSyncEnabled=true}}]}, ` +
 				`{Completeness=false Reserved=false NaluType=0x27 NumNalus=1 Nalus=[{Length=11 NALUnit=[0x4e, 0x1, 0x5, 0xff, 0xff, 0xff, ` +
 				`0xa6, 0x2c, 0xa2, 0xde, 0x9]}]}]`,
 		},
+		{
+			name: "idat",
+			src: &Idat{
+				Data: []byte{0x01, 0x02, 0x03},
+			},
+			dst: &Idat{},
+			bin: []byte{
+				0x01, 0x02, 0x03,
+			},
+			str: `Data=[0x1, 0x2, 0x3]`,
+		},
+		{
+			name: "iinf: version 0",
+			src: &Iinf{
+				FullBox: FullBox{
+					Version: 0,
+					Flags:   [3]byte{0x00, 0x00, 0x00},
+				},
+				EntryCount: 0x1234,
+			},
+			dst: &Iinf{},
+			bin: []byte{
+				0,                // version
+				0x00, 0x00, 0x00, // flags
+				0x12, 0x34, // entry count
+			},
+			str: `Version=0 Flags=0x000000 EntryCount=4660`,
+		},
+		{
+			name: "iinf: version 1",
+			src: &Iinf{
+				FullBox: FullBox{
+					Version: 1,
+					Flags:   [3]byte{0x00, 0x00, 0x00},
+				},
+				EntryCount: 0x12345678,
+			},
+			dst: &Iinf{},
+			bin: []byte{
+				1,                // version
+				0x00, 0x00, 0x00, // flags
+				0x12, 0x34, 0x56, 0x78, // entry count
+			},
+			str: `Version=1 Flags=0x000000 EntryCount=305419896`,
+		},
+		{
+			name: "iloc: version 0",
+			src: &Iloc{
+				FullBox: FullBox{
+					Version: 0,
+					Flags:   [3]byte{0x00, 0x00, 0x00},
+				},
+				OffsetSize:     4,
+				LengthSize:     4,
+				BaseOffsetSize: 0,
+				ItemCount:      1,
+				Items: []IlocItem{
+					{
+						ItemID:      1,
+						ExtentCount: 1,
+						Extents: []IlocExtent{
+							{ExtentOffset: 0x100, ExtentLength: 0x200},
+						},
+					},
+				},
+			},
+			dst: &Iloc{},
+			bin: []byte{
+				0,                // version
+				0x00, 0x00, 0x00, // flags
+				0x44,       // offset size, length size
+				0x00,       // base offset size, reserved
+				0x00, 0x01, // item count
+				0x00, 0x01, // item ID
+				0x00, 0x00, // data reference index
+				0x00, 0x01, // extent count
+				0x00, 0x00, 0x01, 0x00, // extent offset
+				0x00, 0x00, 0x02, 0x00, // extent length
+			},
+			str: `Version=0 Flags=0x000000 OffsetSize=0x4 LengthSize=0x4 BaseOffsetSize=0x0 ItemCount=1 ` +
+				`Items=[{ItemID=1 DataReferenceIndex=0 BaseOffset=0 ExtentCount=1 Extents=[{ExtentOffset=256 ExtentLength=512}]}]`,
+		},
+		{
+			name: "iloc: version 1",
+			src: &Iloc{
+				FullBox: FullBox{
+					Version: 1,
+					Flags:   [3]byte{0x00, 0x00, 0x00},
+				},
+				OffsetSize:     4,
+				LengthSize:     4,
+				BaseOffsetSize: 8,
+				IndexSize:      4,
+				ItemCount:      1,
+				Items: []IlocItem{
+					{
+						ItemID:             2,
+						ConstructionMethod: IlocConstructionMethodIdatOffset,
+						BaseOffset:         0x10,
+						ExtentCount:        2,
+						Extents: []IlocExtent{
+							{ExtentIndex: 1, ExtentOffset: 0, ExtentLength: 3},
+							{ExtentIndex: 2, ExtentOffset: 3, ExtentLength: 4},
+						},
+					},
+				},
+			},
+			dst: &Iloc{},
+			bin: []byte{
+				1,                // version
+				0x00, 0x00, 0x00, // flags
+				0x44,       // offset size, length size
+				0x84,       // base offset size, index size
+				0x00, 0x01, // item count
+				0x00, 0x02, // item ID
+				0x00, 0x01, // reserved, construction method
+				0x00, 0x00, // data reference index
+				0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x10, // base offset
+				0x00, 0x02, // extent count
+				0x00, 0x00, 0x00, 0x01, // extent index
+				0x00, 0x00, 0x00, 0x00, // extent offset
+				0x00, 0x00, 0x00, 0x03, // extent length
+				0x00, 0x00, 0x00, 0x02, // extent index
+				0x00, 0x00, 0x00, 0x03, // extent offset
+				0x00, 0x00, 0x00, 0x04, // extent length
+			},
+			str: `Version=1 Flags=0x000000 OffsetSize=0x4 LengthSize=0x4 BaseOffsetSize=0x8 IndexSize=0x4 ItemCount=1 ` +
+				`Items=[{ItemID=2 ConstructionMethod=0x1 DataReferenceIndex=0 BaseOffset=16 ExtentCount=2 Extents=[` +
+				`{ExtentIndex=1 ExtentOffset=0 ExtentLength=3}, {ExtentIndex=2 ExtentOffset=3 ExtentLength=4}]}]`,
+		},
+		{
+			name: "iloc: version 2",
+			src: &Iloc{
+				FullBox: FullBox{
+					Version: 2,
+					Flags:   [3]byte{0x00, 0x00, 0x00},
+				},
+				BaseOffsetSize: 4,
+				ItemCount:      1,
+				Items: []IlocItem{
+					{
+						ItemID:      0x10000,
+						BaseOffset:  0x1234,
+						ExtentCount: 1,
+						Extents:     []IlocExtent{{}},
+					},
+				},
+			},
+			dst: &Iloc{},
+			bin: []byte{
+				2,                // version
+				0x00, 0x00, 0x00, // flags
+				0x00,                   // offset size, length size
+				0x40,                   // base offset size, index size
+				0x00, 0x00, 0x00, 0x01, // item count
+				0x00, 0x01, 0x00, 0x00, // item ID
+				0x00, 0x00, // reserved, construction method
+				0x00, 0x00, // data reference index
+				0x00, 0x00, 0x12, 0x34, // base offset
+				0x00, 0x01, // extent count
+			},
+			str: `Version=2 Flags=0x000000 OffsetSize=0x0 LengthSize=0x0 BaseOffsetSize=0x4 IndexSize=0x0 ItemCount=1 ` +
+				`Items=[{ItemID=65536 ConstructionMethod=0x0 DataReferenceIndex=0 BaseOffset=4660 ExtentCount=1 Extents=[{ExtentOffset=0 ExtentLength=0}]}]`,
+		},
+		{
+			name: "infe: version 0",
+			src: &Infe{
+				FullBox: FullBox{
+					Version: 0,
+					Flags:   [3]byte{0x00, 0x00, 0x00},
+				},
+				ItemID:      1,
+				ItemName:    "a",
+				ContentType: "image/jpeg",
+			},
+			dst: &Infe{},
+			bin: []byte{
+				0,                // version
+				0x00, 0x00, 0x00, // flags
+				0x00, 0x01, // item ID
+				0x00, 0x00, // item protection index
+				'a', 0x00, // item name
+				'i', 'm', 'a', 'g', 'e', '/', 'j', 'p', 'e', 'g', 0x00, // content type
+				0x00, // content encoding
+			},
+			str: `Version=0 Flags=0x000000 ItemID=1 ItemProtectionIndex=0 ItemName="a" ContentType="image/jpeg" ContentEncoding=""`,
+		},
+		{
+			name: "infe: version 2",
+			src: &Infe{
+				FullBox: FullBox{
+					Version: 2,
+					Flags:   [3]byte{0x00, 0x00, 0x01},
+				},
+				ItemID:   1,
+				ItemType: [4]byte{'h', 'v', 'c', '1'},
+			},
+			dst: &Infe{},
+			bin: []byte{
+				2,                // version
+				0x00, 0x00, 0x01, // flags
+				0x00, 0x01, // item ID
+				0x00, 0x00, // item protection index
+				'h', 'v', 'c', '1', // item type
+				0x00, // item name
+			},
+			str: `Version=2 Flags=0x000001 ItemID=1 ItemProtectionIndex=0 ItemType="hvc1" ItemName=""`,
+		},
+		{
+			name: "infe: version 2 uri",
+			src: &Infe{
+				FullBox: FullBox{
+					Version: 2,
+					Flags:   [3]byte{0x00, 0x00, 0x00},
+				},
+				ItemID:      3,
+				ItemType:    [4]byte{'u', 'r', 'i', ' '},
+				ItemURIType: "urn:x",
+			},
+			dst: &Infe{},
+			bin: []byte{
+				2,                // version
+				0x00, 0x00, 0x00, // flags
+				0x00, 0x03, // item ID
+				0x00, 0x00, // item protection index
+				'u', 'r', 'i', ' ', // item type
+				0x00,                          // item name
+				'u', 'r', 'n', ':', 'x', 0x00, // item uri type
+			},
+			str: `Version=2 Flags=0x000000 ItemID=3 ItemProtectionIndex=0 ItemType="uri " ItemName="" ItemURIType="urn:x"`,
+		},
+		{
+			name: "infe: version 3 mime",
+			src: &Infe{
+				FullBox: FullBox{
+					Version: 3,
+					Flags:   [3]byte{0x00, 0x00, 0x00},
+				},
+				ItemID:      0x10000,
+				ItemType:    [4]byte{'m', 'i', 'm', 'e'},
+				ItemName:    "XMP",
+				ContentType: "application/rdf+xml",
+			},
+			dst: &Infe{},
+			bin: []byte{
+				3,                // version
+				0x00, 0x00, 0x00, // flags
+				0x00, 0x01, 0x00, 0x00, // item ID
+				0x00, 0x00, // item protection index
+				'm', 'i', 'm', 'e', // item type
+				'X', 'M', 'P', 0x00, // item name
+				'a', 'p', 'p', 'l', 'i', 'c', 'a', 't', 'i', 'o', 'n', '/', 'r', 'd', 'f', '+', 'x', 'm', 'l', 0x00, // content type
+				0x00, // content encoding
+			},
+			str: `Version=3 Flags=0x000000 ItemID=65536 ItemProtectionIndex=0 ItemType="mime" ItemName="XMP" ` +
+				`ContentType="application/rdf+xml" ContentEncoding=""`,
+		},
+		{
+			name: "ipco",
+			src:  &Ipco{},
+			dst:  &Ipco{},
+			bin:  nil,
+			str:  ``,
+		},
+		{
+			name: "ipma: version 0",
+			src: &Ipma{
+				FullBox: FullBox{
+					Version: 0,
+					Flags:   [3]byte{0x00, 0x00, 0x00},
+				},
+				EntryCount: 1,
+				Entries: []IpmaEntry{
+					{
+						ItemIDV0:         1,
+						AssociationCount: 2,
+						Associations: []IpmaAssociation{
+							{Essential: true, PropertyIndex: 1},
+							{Essential: false, PropertyIndex: 2},
+						},
+					},
+				},
+			},
+			dst: &Ipma{},
+			bin: []byte{
+				0,                // version
+				0x00, 0x00, 0x00, // flags
+				0x00, 0x00, 0x00, 0x01, // entry count
+				0x00, 0x01, // item ID
+				0x02,       // association count
+				0x81, 0x02, // associations
+			},
+			str: `Version=0 Flags=0x000000 EntryCount=1 Entries=[{ItemIDV0=1 AssociationCount=0x2 ` +
+				`Associations=[{Essential=true PropertyIndex=1}, {Essential=false PropertyIndex=2}]}]`,
+		},
+		{
+			name: "ipma: version 1",
+			src: &Ipma{
+				FullBox: FullBox{
+					Version: 1,
+					Flags:   [3]byte{0x00, 0x00, 0x01},
+				},
+				EntryCount: 1,
+				Entries: []IpmaEntry{
+					{
+						ItemIDV1:         0x10000,
+						AssociationCount: 1,
+						Associations: []IpmaAssociation{
+							{Essential: true, PropertyIndex: 0x100},
+						},
+					},
+				},
+			},
+			dst: &Ipma{},
+			bin: []byte{
+				1,                // version
+				0x00, 0x00, 0x01, // flags
+				0x00, 0x00, 0x00, 0x01, // entry count
+				0x00, 0x01, 0x00, 0x00, // item ID
+				0x01,       // association count
+				0x81, 0x00, // associations
+			},
+			str: `Version=1 Flags=0x000001 EntryCount=1 Entries=[{ItemIDV1=65536 AssociationCount=0x1 ` +
+				`Associations=[{Essential=true PropertyIndex=256}]}]`,
+		},
+		{
+			name: "iprp",
+			src:  &Iprp{},
+			dst:  &Iprp{},
+			bin:  nil,
+			str:  ``,
+		},
+		{
+			name: "iref: version 0",
+			src: &Iref{
+				FullBox: FullBox{
+					Version: 0,
+					Flags:   [3]byte{0x00, 0x00, 0x00},
+				},
+				References: []ItemReference{
+					{ReferenceType: [4]byte{'t', 'h', 'm', 'b'}, FromItemID: 2, ReferenceCount: 1, ToItemIDs: []uint32{1}},
+					{ReferenceType: [4]byte{'c', 'd', 's', 'c'}, FromItemID: 3, ReferenceCount: 2, ToItemIDs: []uint32{1, 2}},
+				},
+			},
+			dst: &Iref{},
+			bin: []byte{
+				0,                // version
+				0x00, 0x00, 0x00, // flags
+				0x00, 0x00, 0x00, 0x0e, 't', 'h', 'm', 'b', // box header
+				0x00, 0x02, // from item ID
+				0x00, 0x01, // reference count
+				0x00, 0x01, // to item ID
+				0x00, 0x00, 0x00, 0x10, 'c', 'd', 's', 'c', // box header
+				0x00, 0x03, // from item ID
+				0x00, 0x02, // reference count
+				0x00, 0x01, 0x00, 0x02, // to item IDs
+			},
+			str: `Version=0 Flags=0x000000 References=[` +
+				`{ReferenceType="thmb" FromItemID=2 ReferenceCount=1 ToItemIDs=[1]}, ` +
+				`{ReferenceType="cdsc" FromItemID=3 ReferenceCount=2 ToItemIDs=[1, 2]}]`,
+		},
+		{
+			name: "iref: version 1",
+			src: &Iref{
+				FullBox: FullBox{
+					Version: 1,
+					Flags:   [3]byte{0x00, 0x00, 0x00},
+				},
+				References: []ItemReference{
+					{ReferenceType: [4]byte{'d', 'i', 'm', 'g'}, FromItemID: 0x10000, ReferenceCount: 1, ToItemIDs: []uint32{0x10001}},
+				},
+			},
+			dst: &Iref{},
+			bin: []byte{
+				1,                // version
+				0x00, 0x00, 0x00, // flags
+				0x00, 0x00, 0x00, 0x12, 'd', 'i', 'm', 'g', // box header
+				0x00, 0x01, 0x00, 0x00, // from item ID
+				0x00, 0x01, // reference count
+				0x00, 0x01, 0x00, 0x01, // to item ID
+			},
+			str: `Version=1 Flags=0x000000 References=[{ReferenceType="dimg" FromItemID=65536 ReferenceCount=1 ToItemIDs=[65537]}]`,
+		},
+		{
+			name: "kind",
+			src: &Kind{
+				SchemeURI: "urn:mpeg:dash:role:2011",
+				Value:     "main",
+			},
+			dst: &Kind{},
+			bin: []byte{
+				0,                // version
+				0x00, 0x00, 0x00, // flags
+				'u', 'r', 'n', ':', 'm', 'p', 'e', 'g', ':', 'd', 'a', 's', 'h', ':',
+				'r', 'o', 'l', 'e', ':', '2', '0', '1', '1', 0x00, // scheme URI
+				'm', 'a', 'i', 'n', 0x00, // value
+			},
+			str: `Version=0 Flags=0x000000 SchemeURI="urn:mpeg:dash:role:2011" Value="main"`,
+		},
+		{
+			name: "leva",
+			src: &Leva{
+				LevelCount: 3,
+				Levels: []LevaLevel{
+					{TrackID: 1, PaddingFlag: true, AssignmentType: LevaAssignmentTypeSampleGroup, GroupingType: [4]byte{'t', 'e', 'l', 'e'}},
+					{TrackID: 1, AssignmentType: LevaAssignmentTypeSampleGroupParameter, GroupingType: [4]byte{'s', 'y', 'n', 'c'}, GroupingTypeParameter: 0x01234567},
+					{TrackID: 2, AssignmentType: LevaAssignmentTypeSubTrack, SubTrackID: 0x89abcdef},
+				},
+			},
+			dst: &Leva{},
+			bin: []byte{
+				0,                // version
+				0x00, 0x00, 0x00, // flags
+				0x03,                   // level count
+				0x00, 0x00, 0x00, 0x01, // track ID
+				0x80,               // padding flag & assignment type
+				't', 'e', 'l', 'e', // grouping type
+				0x00, 0x00, 0x00, 0x01, // track ID
+				0x01,               // padding flag & assignment type
+				's', 'y', 'n', 'c', // grouping type
+				0x01, 0x23, 0x45, 0x67, // grouping type parameter
+				0x00, 0x00, 0x00, 0x02, // track ID
+				0x04,                   // padding flag & assignment type
+				0x89, 0xab, 0xcd, 0xef, // sub track ID
+			},
+			str: `Version=0 Flags=0x000000 LevelCount=0x3 Levels=[{TrackID=1 PaddingFlag=true AssignmentType=0x0 GroupingType="tele"}, {TrackID=1 PaddingFlag=false AssignmentType=0x1 GroupingType="sync" GroupingTypeParameter=19088743}, {TrackID=2 PaddingFlag=false AssignmentType=0x4 SubTrackID=2309737967}]`,
+		},
 		{
 			name: "mdat",
 			src: &Mdat{
@@ -575,6 +1099,35 @@ func TestBoxTypesISO14496_12(t *testing.T) {
 			},
 			str: `Data=[0x11, 0x22, 0x33]`,
 		},
+		{
+			name: "mdcv",
+			src: &Mdcv{
+				DisplayPrimaries: [3]MdcvPrimary{
+					{X: 13250, Y: 34500},
+					{X: 7500, Y: 3000},
+					{X: 34000, Y: 16000},
+				},
+				WhitePointX:                  15635,
+				WhitePointY:                  16450,
+				MaxDisplayMasteringLuminance: 10000000,
+				MinDisplayMasteringLuminance: 50,
+			},
+			dst: &Mdcv{},
+			bin: []byte{
+				0x33, 0xc2, 0x86, 0xc4, // DisplayPrimaries[0]
+				0x1d, 0x4c, 0x0b, 0xb8, // DisplayPrimaries[1]
+				0x84, 0xd0, 0x3e, 0x80, // DisplayPrimaries[2]
+				0x3d, 0x13, // WhitePointX
+				0x40, 0x42, // WhitePointY
+				0x00, 0x98, 0x96, 0x80, // MaxDisplayMasteringLuminance
+				0x00, 0x00, 0x00, 0x32, // MinDisplayMasteringLuminance
+			},
+			str: `DisplayPrimaries=[{X=0.265 Y=0.69}, {X=0.15 Y=0.06}, {X=0.68 Y=0.32}] ` +
+				`WhitePointX=0.3127 ` +
+				`WhitePointY=0.329 ` +
+				`MaxDisplayMasteringLuminance=1000 ` +
+				`MinDisplayMasteringLuminance=0.005`,
+		},
 		{
 			name: "mdhd: version 0",
 			src: &Mdhd{
@@ -650,6 +1203,13 @@ func TestBoxTypesISO14496_12(t *testing.T) {
 			bin:  nil,
 			str:  ``,
 		},
+		{
+			name: "meco",
+			src:  &Meco{},
+			dst:  &Meco{},
+			bin:  nil,
+			str:  ``,
+		},
 		{
 			name: "mehd: version 0",
 			src: &Mehd{
@@ -684,6 +1244,23 @@ func TestBoxTypesISO14496_12(t *testing.T) {
 			},
 			str: `Version=1 Flags=0x000000 FragmentDurationV1=81985529216486895`,
 		},
+		{
+			name: "mere",
+			src: &Mere{
+				FirstMetaboxHandlerType:  [4]byte{'p', 'i', 'c', 't'},
+				SecondMetaboxHandlerType: [4]byte{'m', 'd', 'i', 'r'},
+				MetaboxRelation:          2,
+			},
+			dst: &Mere{},
+			bin: []byte{
+				0,                // version
+				0x00, 0x00, 0x00, // flags
+				'p', 'i', 'c', 't', // first metabox handler type
+				'm', 'd', 'i', 'r', // second metabox handler type
+				0x02, // metabox relation
+			},
+			str: `Version=0 Flags=0x000000 FirstMetaboxHandlerType="pict" SecondMetaboxHandlerType="mdir" MetaboxRelation=0x2`,
+		},
 		{
 			name: "meta",
 			src: &Meta{
@@ -862,6 +1439,100 @@ func TestBoxTypesISO14496_12(t *testing.T) {
 				`PreDefined=[0, 0, 0, 0, 0, 0] ` +
 				`NextTrackID=2882400001`,
 		},
+		{
+			name: "padb",
+			src: &Padb{
+				SampleCount: 3,
+				Entries: []PadbEntry{
+					{Pad1: 1, Pad2: 7},
+					{Pad1: 3},
+				},
+			},
+			dst: &Padb{},
+			bin: []byte{
+				0,                // version
+				0x00, 0x00, 0x00, // flags
+				0x00, 0x00, 0x00, 0x03, // sample count
+				0x17, 0x30, // entries
+			},
+			str: `Version=0 Flags=0x000000 SampleCount=3 Entries=[{Pad1=0x1 Pad2=0x7}, {Pad1=0x3 Pad2=0x0}]`,
+		},
+		{
+			name: "pitm: version 0",
+			src: &Pitm{
+				FullBox: FullBox{
+					Version: 0,
+					Flags:   [3]byte{0x00, 0x00, 0x00},
+				},
+				ItemID: 0x1234,
+			},
+			dst: &Pitm{},
+			bin: []byte{
+				0,                // version
+				0x00, 0x00, 0x00, // flags
+				0x12, 0x34, // item ID
+			},
+			str: `Version=0 Flags=0x000000 ItemID=4660`,
+		},
+		{
+			name: "pitm: version 1",
+			src: &Pitm{
+				FullBox: FullBox{
+					Version: 1,
+					Flags:   [3]byte{0x00, 0x00, 0x00},
+				},
+				ItemID: 0x12345678,
+			},
+			dst: &Pitm{},
+			bin: []byte{
+				1,                // version
+				0x00, 0x00, 0x00, // flags
+				0x12, 0x34, 0x56, 0x78, // item ID
+			},
+			str: `Version=1 Flags=0x000000 ItemID=305419896`,
+		},
+		{
+			name: "prft: version 0",
+			src: &Prft{
+				FullBox: FullBox{
+					Version: 0,
+					Flags:   [3]byte{0x00, 0x00, 0x18},
+				},
+				ReferenceTrackID: 1,
+				NTPTimestamp:     0xe2b1c2d380000000,
+				MediaTimeV0:      0x01234567,
+			},
+			dst: &Prft{},
+			bin: []byte{
+				0,                // version
+				0x00, 0x00, 0x18, // flags
+				0x00, 0x00, 0x00, 0x01, // reference track ID
+				0xe2, 0xb1, 0xc2, 0xd3, 0x80, 0x00, 0x00, 0x00, // NTP timestamp
+				0x01, 0x23, 0x45, 0x67, // media time
+			},
+			str: `Version=0 Flags=0x000018 ReferenceTrackID=1 NTPTimestamp=16335051537090871296 MediaTimeV0=19088743`,
+		},
+		{
+			name: "prft: version 1",
+			src: &Prft{
+				FullBox: FullBox{
+					Version: 1,
+					Flags:   [3]byte{0x00, 0x00, 0x00},
+				},
+				ReferenceTrackID: 1,
+				NTPTimestamp:     0xe2b1c2d380000000,
+				MediaTimeV1:      0x0123456789abcdef,
+			},
+			dst: &Prft{},
+			bin: []byte{
+				1,                // version
+				0x00, 0x00, 0x00, // flags
+				0x00, 0x00, 0x00, 0x01, // reference track ID
+				0xe2, 0xb1, 0xc2, 0xd3, 0x80, 0x00, 0x00, 0x00, // NTP timestamp
+				0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef, // media time
+			},
+			str: `Version=1 Flags=0x000000 ReferenceTrackID=1 NTPTimestamp=16335051537090871296 MediaTimeV1=81985529216486895`,
+		},
 		{
 			name: "saio: version 0: no aux info type",
 			src: &Saio{
@@ -1947,6 +2618,315 @@ func TestBoxTypesISO14496_12(t *testing.T) {
 				`EntryCount=2 ` +
 				`RollDistances=[4369, 8738]`,
 		},
+		{
+			name: "sgpd: version 1 seig",
+			src: &Sgpd{
+				FullBox: FullBox{
+					Version: 1,
+					Flags:   [3]byte{0x00, 0x00, 0x00},
+				},
+				GroupingType:  [4]byte{'s', 'e', 'i', 'g'},
+				DefaultLength: 0,
+				EntryCount:    2,
+				Entries: []ISampleGroupEntry{
+					&CencSampleEncryptionInformationGroupEntry{
+						CryptByteBlock:  1,
+						SkipByteBlock:   9,
+						IsProtected:     1,
+						PerSampleIVSize: 8,
+						KID:             [16]byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10},
+					},
+					&CencSampleEncryptionInformationGroupEntry{
+						IsProtected:     1,
+						PerSampleIVSize: 0,
+						KID:             [16]byte{0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f, 0x20},
+						ConstantIVSize:  8,
+						ConstantIV:      []byte{0x21, 0x22, 0x23, 0x24, 0x25, 0x26, 0x27, 0x28},
+					},
+				},
+			},
+			dst: &Sgpd{},
+			bin: []byte{
+				1,                // version
+				0x00, 0x00, 0x00, // flags
+				's', 'e', 'i', 'g', // grouping type
+				0x00, 0x00, 0x00, 0x00, // default length
+				0x00, 0x00, 0x00, 0x02, // entry count
+				0x00, 0x00, 0x00, 0x14, // description length
+				0x00, 0x19, 0x01, 0x08, // reserved & crypt byte block & skip byte block & is protected & per sample IV size
+				0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10, // KID
+				0x00, 0x00, 0x00, 0x1d, // description length
+				0x00, 0x00, 0x01, 0x00, // reserved & crypt byte block & skip byte block & is protected & per sample IV size
+				0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f, 0x20, // KID
+				0x08,                                           // constant IV size
+				0x21, 0x22, 0x23, 0x24, 0x25, 0x26, 0x27, 0x28, // constant IV
+			},
+			str: `Version=1 Flags=0x000000 GroupingType="seig" DefaultLength=0 EntryCount=2 Entries=[{Reserved=0 CryptByteBlock=1 SkipByteBlock=9 IsProtected=1 PerSampleIVSize=8 KID=01020304-0506-0708-090a-0b0c0d0e0f10}, {Reserved=0 CryptByteBlock=0 SkipByteBlock=0 IsProtected=1 PerSampleIVSize=0 KID=11121314-1516-1718-191a-1b1c1d1e1f20 ConstantIVSize=8 ConstantIV=[0x21, 0x22, 0x23, 0x24, 0x25, 0x26, 0x27, 0x28]}]`,
+		},
+		{
+			name: "sgpd: version 1 sync",
+			src: &Sgpd{
+				FullBox: FullBox{
+					Version: 1,
+					Flags:   [3]byte{0x00, 0x00, 0x00},
+				},
+				GroupingType:  [4]byte{'s', 'y', 'n', 'c'},
+				DefaultLength: 1,
+				EntryCount:    2,
+				Entries: []ISampleGroupEntry{
+					&SyncSampleEntry{NALUnitType: 20},
+					&SyncSampleEntry{NALUnitType: 19},
+				},
+			},
+			dst: &Sgpd{},
+			bin: []byte{
+				1,                // version
+				0x00, 0x00, 0x00, // flags
+				's', 'y', 'n', 'c', // grouping type
+				0x00, 0x00, 0x00, 0x01, // default length
+				0x00, 0x00, 0x00, 0x02, // entry count
+				0x14, 0x13, // sync sample entries
+			},
+			str: `Version=1 Flags=0x000000 GroupingType="sync" DefaultLength=1 EntryCount=2 Entries=[{NALUnitType=0x14}, {NALUnitType=0x13}]`,
+		},
+		{
+			name: "sgpd: version 2 rash",
+			src: &Sgpd{
+				FullBox: FullBox{
+					Version: 2,
+					Flags:   [3]byte{0x00, 0x00, 0x00},
+				},
+				GroupingType:                  [4]byte{'r', 'a', 's', 'h'},
+				DefaultSampleDescriptionIndex: 1,
+				EntryCount:                    2,
+				Entries: []ISampleGroupEntry{
+					&RateShareEntry{
+						OperationPointCount: 1,
+						TargetRateShare:     0x80,
+						MaximumBitrate:      3000000,
+						MinimumBitrate:      500000,
+						DiscardPriority:     1,
+					},
+					&RateShareEntry{
+						OperationPointCount: 2,
+						OperationPoints: []RateShareOperationPoint{
+							{AvailableBitrate: 1000000, TargetRateShare: 0x40},
+							{AvailableBitrate: 2000000, TargetRateShare: 0xc0},
+						},
+						MaximumBitrate:  3000000,
+						MinimumBitrate:  500000,
+						DiscardPriority: 5,
+					},
+				},
+			},
+			dst: &Sgpd{},
+			bin: []byte{
+				2,                // version
+				0x00, 0x00, 0x00, // flags
+				'r', 'a', 's', 'h', // grouping type
+				0x00, 0x00, 0x00, 0x01, // default sample description index
+				0x00, 0x00, 0x00, 0x02, // entry count
+				0x00, 0x01, // operation point count
+				0x00, 0x80, // target rate share
+				0x00, 0x2d, 0xc6, 0xc0, // maximum bitrate
+				0x00, 0x07, 0xa1, 0x20, // minimum bitrate
+				0x01,       // discard priority
+				0x00, 0x02, // operation point count
+				0x00, 0x0f, 0x42, 0x40, 0x00, 0x40, // available bitrate & target rate share
+				0x00, 0x1e, 0x84, 0x80, 0x00, 0xc0, // available bitrate & target rate share
+				0x00, 0x2d, 0xc6, 0xc0, // maximum bitrate
+				0x00, 0x07, 0xa1, 0x20, // minimum bitrate
+				0x05, // discard priority
+			},
+			str: `Version=2 Flags=0x000000 GroupingType="rash" DefaultSampleDescriptionIndex=1 EntryCount=2 Entries=[{OperationPointCount=1 TargetRateShare=128 MaximumBitrate=3000000 MinimumBitrate=500000 DiscardPriority=0x1}, {OperationPointCount=2 OperationPoints=[{AvailableBitrate=1000000 TargetRateShare=64}, {AvailableBitrate=2000000 TargetRateShare=192}] MaximumBitrate=3000000 MinimumBitrate=500000 DiscardPriority=0x5}]`,
+		},
+		{
+			name: "sgpd: version 1 tscl",
+			src: &Sgpd{
+				FullBox: FullBox{
+					Version: 1,
+					Flags:   [3]byte{0x00, 0x00, 0x00},
+				},
+				GroupingType:  [4]byte{'t', 's', 'c', 'l'},
+				DefaultLength: 20,
+				EntryCount:    1,
+				Entries: []ISampleGroupEntry{
+					&TemporalLayerEntry{
+						TemporalLayerID:             0,
+						TLProfileIdc:                1,
+						TLProfileCompatibilityFlags: 0x60000000,
+						TLConstraintIndicatorFlags:  0x900000000000,
+						TLLevelIdc:                  93,
+						TLMaxBitRate:                1000,
+						TLAvgBitRate:                500,
+						TLConstantFrameRate:         0,
+						TLAvgFrameRate:              3000,
+					},
+				},
+			},
+			dst: &Sgpd{},
+			bin: []byte{
+				1,                // version
+				0x00, 0x00, 0x00, // flags
+				't', 's', 'c', 'l', // grouping type
+				0x00, 0x00, 0x00, 0x14, // default length
+				0x00, 0x00, 0x00, 0x01, // entry count
+				0x00,                   // temporal layer ID
+				0x01,                   // profile space & tier flag & profile idc
+				0x60, 0x00, 0x00, 0x00, // profile compatibility flags
+				0x90, 0x00, 0x00, 0x00, 0x00, 0x00, // constraint indicator flags
+				0x5d,       // level idc
+				0x03, 0xe8, // max bit rate
+				0x01, 0xf4, // avg bit rate
+				0x00,       // constant frame rate
+				0x0b, 0xb8, // avg frame rate
+			},
+			str: `Version=1 Flags=0x000000 GroupingType="tscl" DefaultLength=20 EntryCount=1 Entries=[{TemporalLayerID=0x0 TLProfileSpace=0x0 TLTierFlag=false TLProfileIdc=0x1 TLProfileCompatibilityFlags=0x60000000 TLConstraintIndicatorFlags=0x900000000000 TLLevelIdc=0x5d TLMaxBitRate=1000 TLAvgBitRate=500 TLConstantFrameRate=0x0 TLAvgFrameRate=3000}]`,
+		},
+		{
+			name: "sgpd: version 1 stmi",
+			src: &Sgpd{
+				FullBox: FullBox{
+					Version: 1,
+					Flags:   [3]byte{0x00, 0x00, 0x00},
+				},
+				GroupingType:  [4]byte{'s', 't', 'm', 'i'},
+				DefaultLength: 0,
+				EntryCount:    1,
+				Entries: []ISampleGroupEntry{
+					&SampleToMetadataItemEntry{
+						MetaBoxHandlerType: [4]byte{'p', 'i', 'c', 't'},
+						NumItems:           2,
+						ItemIDs:            []uint32{1, 2},
+					},
+				},
+			},
+			dst: &Sgpd{},
+			bin: []byte{
+				1,                // version
+				0x00, 0x00, 0x00, // flags
+				's', 't', 'm', 'i', // grouping type
+				0x00, 0x00, 0x00, 0x00, // default length
+				0x00, 0x00, 0x00, 0x01, // entry count
+				0x00, 0x00, 0x00, 0x10, // description length
+				'p', 'i', 'c', 't', // meta box handler type
+				0x00, 0x00, 0x00, 0x02, // num items
+				0x00, 0x00, 0x00, 0x01, // item ID
+				0x00, 0x00, 0x00, 0x02, // item ID
+			},
+			str: `Version=1 Flags=0x000000 GroupingType="stmi" DefaultLength=0 EntryCount=1 Entries=[{MetaBoxHandlerType="pict" NumItems=2 ItemIDs=[1, 2]}]`,
+		},
+		{
+			name: "sgpd: version 1 oinf",
+			src: &Sgpd{
+				FullBox: FullBox{
+					Version: 1,
+					Flags:   [3]byte{0x00, 0x00, 0x00},
+				},
+				GroupingType:  [4]byte{'o', 'i', 'n', 'f'},
+				DefaultLength: 0,
+				EntryCount:    1,
+				Entries: []ISampleGroupEntry{
+					&OperatingPointsInformation{
+						ScalabilityMask:     0x0002,
+						NumProfileTierLevel: 1,
+						ProfileTierLevels: []OinfProfileTierLevel{
+							{
+								GeneralProfileIdc:                1,
+								GeneralProfileCompatibilityFlags: 0x60000000,
+								GeneralConstraintIndicatorFlags:  0x900000000000,
+								GeneralLevelIdc:                  93,
+							},
+						},
+						NumOperatingPoints: 1,
+						OperatingPoints: []OinfOperatingPoint{
+							{
+								LayerCount: 1,
+								Layers: []OinfOperatingPointLayer{
+									{IsOutputLayer: true},
+								},
+								MinPicWidth:       1920,
+								MinPicHeight:      1080,
+								MaxPicWidth:       1920,
+								MaxPicHeight:      1080,
+								MaxChromaFormat:   1,
+								FrameRateInfoFlag: true,
+								AvgFrameRate:      6000,
+								ConstantFrameRate: 1,
+							},
+						},
+						MaxLayerCount: 2,
+						Layers: []OinfLayer{
+							{LayerID: 0, DirectRefLayerIDs: []uint8{}, DimensionIdentifiers: []uint8{0}},
+							{LayerID: 1, NumDirectRefLayers: 1, DirectRefLayerIDs: []uint8{0}, DimensionIdentifiers: []uint8{1}},
+						},
+					},
+				},
+			},
+			dst: &Sgpd{},
+			bin: []byte{
+				1,                // version
+				0x00, 0x00, 0x00, // flags
+				'o', 'i', 'n', 'f', // grouping type
+				0x00, 0x00, 0x00, 0x00, // default length
+				0x00, 0x00, 0x00, 0x01, // entry count
+				0x00, 0x00, 0x00, 0x2b, // description length
+				0x00, 0x02, // scalability mask
+				0x01,                   // reserved & num profile tier level
+				0x01,                   // profile space & tier flag & profile idc
+				0x60, 0x00, 0x00, 0x00, // profile compatibility flags
+				0x90, 0x00, 0x00, 0x00, 0x00, 0x00, // constraint indicator flags
+				0x5d,       // level idc
+				0x00, 0x01, // num operating points
+				0x00, 0x00, // output layer set idx
+				0x00,       // max temporal ID
+				0x01,       // layer count
+				0x00, 0x02, // ptl idx & layer ID & is output layer & is alternate output layer
+				0x07, 0x80, 0x04, 0x38, // min pic width & height
+				0x07, 0x80, 0x04, 0x38, // max pic width & height
+				0x42,       // max chroma format & max bit depth & reserved & flags
+				0x17, 0x70, // avg frame rate
+				0x01,             // reserved & constant frame rate
+				0x02,             // max layer count
+				0x00, 0x00, 0x00, // layer ID & num direct ref layers & dimension identifier
+				0x01, 0x01, 0x00, 0x01, // layer ID & num direct ref layers & direct ref layer ID & dimension identifier
+			},
+			str: `Version=1 Flags=0x000000 GroupingType="oinf" DefaultLength=0 EntryCount=1 Entries=[{ScalabilityMask=0x2 NumProfileTierLevel=0x1 ProfileTierLevels=[{GeneralProfileSpace=0x0 GeneralTierFlag=false GeneralProfileIdc=0x1 GeneralProfileCompatibilityFlags=0x60000000 GeneralConstraintIndicatorFlags=0x900000000000 GeneralLevelIdc=0x5d}] NumOperatingPoints=1 OperatingPoints=[{OutputLayerSetIdx=0 MaxTemporalID=0x0 LayerCount=0x1 Layers=[{PTLIdx=0x0 LayerID=0x0 IsOutputLayer=true IsAlternateOutputLayer=false}] MinPicWidth=1920 MinPicHeight=1080 MaxPicWidth=1920 MaxPicHeight=1080 MaxChromaFormat=0x1 MaxBitDepthMinus8=0x0 FrameRateInfoFlag=true BitRateInfoFlag=false AvgFrameRate=6000 ConstantFrameRate=0x1}] MaxLayerCount=0x2 Layers=[{LayerID=0x0 NumDirectRefLayers=0x0 DirectRefLayerIDs=[] DimensionIdentifiers=[0x0]}, {LayerID=0x1 NumDirectRefLayers=0x1 DirectRefLayerIDs=[0x0] DimensionIdentifiers=[0x1]}]}]`,
+		},
+		{
+			name: "sgpd: version 1 linf",
+			src: &Sgpd{
+				FullBox: FullBox{
+					Version: 1,
+					Flags:   [3]byte{0x00, 0x00, 0x00},
+				},
+				GroupingType:  [4]byte{'l', 'i', 'n', 'f'},
+				DefaultLength: 0,
+				EntryCount:    1,
+				Entries: []ISampleGroupEntry{
+					&LayerInfoGroupEntry{
+						NumLayersInTrack: 2,
+						Layers: []LinfLayer{
+							{LayerID: 0, MinSubLayerID: 0, MaxSubLayerID: 2},
+							{LayerID: 1, MinSubLayerID: 0, MaxSubLayerID: 1},
+						},
+					},
+				},
+			},
+			dst: &Sgpd{},
+			bin: []byte{
+				1,                // version
+				0x00, 0x00, 0x00, // flags
+				'l', 'i', 'n', 'f', // grouping type
+				0x00, 0x00, 0x00, 0x00, // default length
+				0x00, 0x00, 0x00, 0x01, // entry count
+				0x00, 0x00, 0x00, 0x05, // description length
+				0x02,       // reserved & num layers in track
+				0x00, 0x02, // reserved & layer ID & min sub layer ID & max sub layer ID
+				0x00, 0x41, // reserved & layer ID & min sub layer ID & max sub layer ID
+			},
+			str: `Version=1 Flags=0x000000 GroupingType="linf" DefaultLength=0 EntryCount=1 Entries=[{NumLayersInTrack=0x2 Layers=[{LayerID=0x0 MinSubLayerID=0x0 MaxSubLayerID=0x2}, {LayerID=0x1 MinSubLayerID=0x0 MaxSubLayerID=0x1}]}]`,
+		},
 		{
 			name: "sidx: version=0",
 			src: &Sidx{
@@ -2080,6 +3060,28 @@ func TestBoxTypesISO14496_12(t *testing.T) {
 			},
 			str: `Version=0 Flags=0x000000 Balance=1.137`,
 		},
+		{
+			name: "ssix",
+			src: &Ssix{
+				SubsegmentCount: 2,
+				Subsegments: []SsixSubsegment{
+					{RangeCount: 2, Ranges: []SsixRange{{Level: 0, RangeSize: 0x012345}, {Level: 1, RangeSize: 0x6789ab}}},
+					{RangeCount: 1, Ranges: []SsixRange{{Level: 0, RangeSize: 0xcdef01}}},
+				},
+			},
+			dst: &Ssix{},
+			bin: []byte{
+				0,                // version
+				0x00, 0x00, 0x00, // flags
+				0x00, 0x00, 0x00, 0x02, // subsegment count
+				0x00, 0x00, 0x00, 0x02, // range count
+				0x00, 0x01, 0x23, 0x45, // level & range size
+				0x01, 0x67, 0x89, 0xab, // level & range size
+				0x00, 0x00, 0x00, 0x01, // range count
+				0x00, 0xcd, 0xef, 0x01, // level & range size
+			},
+			str: `Version=0 Flags=0x000000 SubsegmentCount=2 Subsegments=[{RangeCount=2 Ranges=[{Level=0x0 RangeSize=74565}, {Level=0x1 RangeSize=6785451}]}, {RangeCount=1 Ranges=[{Level=0x0 RangeSize=13496065}]}]`,
+		},
 		{
 			name: "stbl",
 			src:  &Stbl{},
@@ -2107,6 +3109,19 @@ func TestBoxTypesISO14496_12(t *testing.T) {
 			},
 			str: `Version=0 Flags=0x000000 EntryCount=2 ChunkOffset=[19088743, 2309737967]`,
 		},
+		{
+			name: "stdp",
+			src: &Stdp{
+				Priorities: []uint16{0x0123, 0x4567, 0x89ab},
+			},
+			dst: &Stdp{},
+			bin: []byte{
+				0,                // version
+				0x00, 0x00, 0x00, // flags
+				0x01, 0x23, 0x45, 0x67, 0x89, 0xab, // priorities
+			},
+			str: `Version=0 Flags=0x000000 Priorities=[291, 17767, 35243]`,
+		},
 		{
 			name: "stsc",
 			src: &Stsc{
@@ -2153,6 +3168,25 @@ func TestBoxTypesISO14496_12(t *testing.T) {
 			},
 			str: `Version=0 Flags=0x000000 EntryCount=19088743`,
 		},
+		{
+			name: "stsh",
+			src: &Stsh{
+				EntryCount: 2,
+				Entries: []StshEntry{
+					{ShadowedSampleNumber: 10, SyncSampleNumber: 11},
+					{ShadowedSampleNumber: 30, SyncSampleNumber: 28},
+				},
+			},
+			dst: &Stsh{},
+			bin: []byte{
+				0,                // version
+				0x00, 0x00, 0x00, // flags
+				0x00, 0x00, 0x00, 0x02, // entry count
+				0x00, 0x00, 0x00, 0x0a, 0x00, 0x00, 0x00, 0x0b, // entry
+				0x00, 0x00, 0x00, 0x1e, 0x00, 0x00, 0x00, 0x1c, // entry
+			},
+			str: `Version=0 Flags=0x000000 EntryCount=2 Entries=[{ShadowedSampleNumber=10 SyncSampleNumber=11}, {ShadowedSampleNumber=30 SyncSampleNumber=28}]`,
+		},
 		{
 			name: "stss",
 			src: &Stss{
@@ -2237,6 +3271,45 @@ func TestBoxTypesISO14496_12(t *testing.T) {
 			},
 			str: `Version=0 Flags=0x000000 FieldSize=0x20 SampleCount=2 EntrySize=[19088743, 591751049]`,
 		},
+		{
+			name: "subs: version 1",
+			src: &Subs{
+				FullBox: FullBox{
+					Version: 1,
+					Flags:   [3]byte{0x00, 0x00, 0x00},
+				},
+				EntryCount: 1,
+				Entries: []SubSampleInformationEntry{
+					{
+						SampleDelta:    2,
+						SubsampleCount: 2,
+						SubsampleInformation: []SubSampleInformation{
+							{SubsampleSizeV1: 0x01234567, SubsamplePriority: 1},
+							{SubsampleSizeV1: 0x10, Discardable: 1, CodecSpecificParameters: 0x89abcdef},
+						},
+					},
+				},
+			},
+			dst: &Subs{},
+			bin: []byte{
+				1,                // version
+				0x00, 0x00, 0x00, // flags
+				0x00, 0x00, 0x00, 0x01, // entry count
+				0x00, 0x00, 0x00, 0x02, // sample delta
+				0x00, 0x02, // subsample count
+				0x01, 0x23, 0x45, 0x67, // subsample size
+				0x01,                   // subsample priority
+				0x00,                   // discardable
+				0x00, 0x00, 0x00, 0x00, // codec specific parameters
+				0x00, 0x00, 0x00, 0x10, // subsample size
+				0x00,                   // subsample priority
+				0x01,                   // discardable
+				0x89, 0xab, 0xcd, 0xef, // codec specific parameters
+			},
+			str: `Version=1 Flags=0x000000 EntryCount=1 Entries=[{SampleDelta=2 SubsampleCount=2 SubsampleInformation=[` +
+				`{SubsampleSizeV1=19088743 SubsamplePriority=0x1 Discardable=0x0 CodecSpecificParameters=0}, ` +
+				`{SubsampleSizeV1=16 SubsamplePriority=0x0 Discardable=0x1 CodecSpecificParameters=2309737967}]}]`,
+		},
 		{
 			name: "stts",
 			src: &Stts{
@@ -2595,6 +3668,29 @@ func TestBoxTypesISO14496_12(t *testing.T) {
 			bin:  nil,
 			str:  ``,
 		},
+		{
+			name: "tref",
+			src:  &Tref{},
+			dst:  &Tref{},
+			bin:  nil,
+			str:  ``,
+		},
+		{
+			name: "tref: cdsc",
+			src: &TrackReferenceType{
+				AnyTypeBox: AnyTypeBox{Type: BoxTypeCdsc()},
+				TrackIDs:   []uint32{1, 2},
+			},
+			dst: &TrackReferenceType{
+				AnyTypeBox: AnyTypeBox{Type: BoxTypeCdsc()},
+			},
+			bin: []byte{
+				0x00, 0x00, 0x00, 0x01, // track ID
+				0x00, 0x00, 0x00, 0x02, // track ID
+			},
+			str: `TrackIDs=[1, 2]`,
+			ctx: Context{UnderTref: true},
+		},
 		{
 			name: "trep",
 			src: &Trep{
@@ -3216,6 +4312,74 @@ func TestAvcCInconsistentError(t *testing.T) {
 	assert.Equal(t, "each values of Profile and HighProfileFieldsEnabled are inconsistent", err.Error())
 }
 
+type testSampleGroupEntry struct {
+	BaseCustomFieldObject
+	Value uint16 `mp4:"0,size=16"`
+	Flag  uint8  `mp4:"1,size=8"`
+}
+
+func TestSgpdCustomSampleGroupEntry(t *testing.T) {
+	AddSampleGroupEntryDef([4]byte{'t', 'e', 's', 't'}, &testSampleGroupEntry{})
+
+	src := &Sgpd{
+		FullBox:       FullBox{Version: 1},
+		GroupingType:  [4]byte{'t', 'e', 's', 't'},
+		DefaultLength: 3,
+		EntryCount:    2,
+		Entries: []ISampleGroupEntry{
+			&testSampleGroupEntry{Value: 0x1234, Flag: 1},
+			&testSampleGroupEntry{Value: 0x5678},
+		},
+	}
+	bin := []byte{
+		1,                // version
+		0x00, 0x00, 0x00, // flags
+		't', 'e', 's', 't', // grouping type
+		0x00, 0x00, 0x00, 0x03, // default length
+		0x00, 0x00, 0x00, 0x02, // entry count
+		0x12, 0x34, 0x01, // entry
+		0x56, 0x78, 0x00, // entry
+	}
+
+	buf := bytes.NewBuffer(nil)
+	n, err := Marshal(buf, src, Context{})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(len(bin)), n)
+	assert.Equal(t, bin, buf.Bytes())
+
+	dst := &Sgpd{}
+	r := bytes.NewReader(bin)
+	n, err = Unmarshal(r, uint64(len(bin)), dst, Context{})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(len(bin)), n)
+	assert.Equal(t, src, dst)
+
+	str, err := Stringify(dst, Context{})
+	require.NoError(t, err)
+	assert.Equal(t, `Version=1 Flags=0x000000 GroupingType="test" DefaultLength=3 EntryCount=2 Entries=[{Value=4660 Flag=0x1}, {Value=22136 Flag=0x0}]`, str)
+
+	// an entry of another type cannot be written
+	src.Entries[1] = &SyncSampleEntry{NALUnitType: 20}
+	_, err = Marshal(bytes.NewBuffer(nil), src, Context{})
+	require.Error(t, err)
+}
+
+func TestSgpdUnregisteredGroupingType(t *testing.T) {
+	bin := []byte{
+		1,                // version
+		0x00, 0x00, 0x00, // flags
+		'x', 'x', 'x', 'x', // grouping type
+		0x00, 0x00, 0x00, 0x02, // default length
+		0x00, 0x00, 0x00, 0x01, // entry count
+		0x12, 0x34, // entry
+	}
+	dst := &Sgpd{}
+	_, err := Unmarshal(bytes.NewReader(bin), uint64(len(bin)), dst, Context{})
+	require.NoError(t, err)
+	assert.Nil(t, dst.Entries)
+	assert.Equal(t, []byte{0x12, 0x34}, dst.Unsupported)
+}
+
 func TestFixedPoint(t *testing.T) {
 	mvhd := Mvhd{Rate: 0x4d2b000}
 	assert.Equal(t, float64(1234.6875), mvhd.GetRate())
@@ -17,6 +17,8 @@ const (
 	DecoderConfigDescrTag = 0x04
 	DecSpecificInfoTag    = 0x05
 	SLConfigDescrTag      = 0x06
+	ESIDIncDescrTag       = 0x0e
+	MP4IODescrTag         = 0x10
 )
 
 // Esds is ES descripter box
@@ -72,6 +74,8 @@ func (ds *Descriptor) StringifyField(name string, indent string, depth int, ctx
 			return "DecSpecificInfo", true
 		case SLConfigDescrTag:
 			return "SLConfigDescr", true
+		case ESIDIncDescrTag:
+			return "ESIDIncDescr", true
 		default:
 			return "", false
 		}
@@ -124,3 +128,60 @@ type DecoderConfigDescriptor struct {
 	MaxBitrate           uint32 `mp4:"5,size=32"`
 	AvgBitrate           uint32 `mp4:"6,size=32"`
 }
+
+/*************************** iods ****************************/
+
+func BoxTypeIods() BoxType { return StrToBoxType("iods") }
+
+func init() {
+	AddBoxDef(&Iods{}, 0)
+}
+
+// Iods is object descriptor box which has an initial object descriptor
+type Iods struct {
+	FullBox                        `mp4:"0,extend"`
+	Tag                            int8   `mp4:"1,size=8"` // must be 0x10
+	Size                           uint32 `mp4:"2,varint"`
+	ObjectDescriptorID             uint16 `mp4:"3,size=10"`
+	URLFlag                        bool   `mp4:"4,size=1"`
+	IncludeInlineProfileLevelFlag  bool   `mp4:"5,size=1"`
+	Reserved                       uint8  `mp4:"6,size=4,const=15"`
+	URLLength                      uint8  `mp4:"7,size=8,opt=dynamic"`
+	URLString                      []byte `mp4:"8,size=8,len=dynamic,opt=dynamic,string"`
+	ODProfileLevelIndication       uint8  `mp4:"9,size=8,opt=dynamic"`
+	SceneProfileLevelIndication    uint8  `mp4:"10,size=8,opt=dynamic"`
+	AudioProfileLevelIndication    uint8  `mp4:"11,size=8,opt=dynamic"`
+	VisualProfileLevelIndication   uint8  `mp4:"12,size=8,opt=dynamic"`
+	GraphicsProfileLevelIndication uint8  `mp4:"13,size=8,opt=dynamic"`
+	// Descriptors are ES_ID_Inc descriptors or the other descriptors following the profile levels.
+	Descriptors []Descriptor `mp4:"14,array"`
+}
+
+// GetType returns the BoxType
+func (*Iods) GetType() BoxType {
+	return BoxTypeIods()
+}
+
+// GetFieldLength returns length of dynamic field
+func (iods *Iods) GetFieldLength(name string, ctx Context) uint {
+	switch name {
+	case "URLString":
+		return uint(iods.URLLength)
+	}
+	panic(fmt.Errorf("invalid name of dynamic-length field: boxType=iods fieldName=%s", name))
+}
+
+// IsOptFieldEnabled check whether if the optional field is enabled
+func (iods *Iods) IsOptFieldEnabled(name string, ctx Context) bool {
+	switch name {
+	case "URLLength", "URLString":
+		return iods.URLFlag
+	case "ODProfileLevelIndication",
+		"SceneProfileLevelIndication",
+		"AudioProfileLevelIndication",
+		"VisualProfileLevelIndication",
+		"GraphicsProfileLevelIndication":
+		return !iods.URLFlag
+	}
+	return false
+}
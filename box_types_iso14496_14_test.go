@@ -119,6 +119,41 @@ func TestBoxTypesISO14496_14(t *testing.T) {
 				"{Tag=DecSpecificInfo Size=3 Data=[0x11, 0x22, 0x33]}, " +
 				"{Tag=SLConfigDescr Size=5 Data=[0x11, 0x22, 0x33, 0x44, 0x55]}]",
 		},
+		{
+			name: "iods",
+			src: &Iods{
+				Tag:                            MP4IODescrTag,
+				Size:                           13,
+				ObjectDescriptorID:             1,
+				Reserved:                       0x0f,
+				ODProfileLevelIndication:       0xff,
+				SceneProfileLevelIndication:    0xff,
+				AudioProfileLevelIndication:    0x29,
+				VisualProfileLevelIndication:   0xff,
+				GraphicsProfileLevelIndication: 0xff,
+				Descriptors: []Descriptor{
+					{
+						Tag:  ESIDIncDescrTag,
+						Size: 0x04,
+						Data: []byte{0x00, 0x00, 0x00, 0x01},
+					},
+				},
+			},
+			dst: &Iods{},
+			bin: []byte{
+				0,                // version
+				0x00, 0x00, 0x00, // flags
+				0x10,       // tag
+				0x0d,       // size (varint)
+				0x00, 0x4f, // objectDescriptorID & flags & reserved
+				0xff, 0xff, 0x29, 0xff, 0xff, // profile level indications
+				//
+				0x0e,                   // tag
+				0x04,                   // size (varint)
+				0x00, 0x00, 0x00, 0x01, // data
+			},
+			str: `Version=0 Flags=0x000000 Tag=16 Size=13 ObjectDescriptorID=1 URLFlag=false IncludeInlineProfileLevelFlag=false ODProfileLevelIndication=0xff SceneProfileLevelIndication=0xff AudioProfileLevelIndication=0x29 VisualProfileLevelIndication=0xff GraphicsProfileLevelIndication=0xff Descriptors=[{Tag=ESIDIncDescr Size=4 Data=[0x0, 0x0, 0x0, 0x1]}]`,
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
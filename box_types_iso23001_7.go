@@ -166,3 +166,41 @@ func (tenc *Tenc) GetFieldLength(name string, ctx Context) uint {
 func (*Tenc) GetType() BoxType {
 	return BoxTypeTenc()
 }
+
+/*************************** seig ****************************/
+
+func init() {
+	AddSampleGroupEntryDef([4]byte{'s', 'e', 'i', 'g'}, &CencSampleEncryptionInformationGroupEntry{})
+}
+
+// CencSampleEncryptionInformationGroupEntry is the sample group entry of the seig grouping type,
+// which overrides the default encryption parameters in the tenc box (e.g. on key rotation).
+type CencSampleEncryptionInformationGroupEntry struct {
+	BaseCustomFieldObject
+	Reserved        uint8    `mp4:"0,size=8,dec"`
+	CryptByteBlock  uint8    `mp4:"1,size=4,dec"`
+	SkipByteBlock   uint8    `mp4:"2,size=4,dec"`
+	IsProtected     uint8    `mp4:"3,size=8,dec"`
+	PerSampleIVSize uint8    `mp4:"4,size=8,dec"`
+	KID             [16]byte `mp4:"5,size=8,uuid"`
+	ConstantIVSize  uint8    `mp4:"6,size=8,opt=dynamic,dec"`
+	ConstantIV      []byte   `mp4:"7,size=8,opt=dynamic,len=dynamic"`
+}
+
+// IsOptFieldEnabled check whether if the optional field is enabled
+func (entry *CencSampleEncryptionInformationGroupEntry) IsOptFieldEnabled(name string, ctx Context) bool {
+	switch name {
+	case "ConstantIVSize", "ConstantIV":
+		return entry.IsProtected == 1 && entry.PerSampleIVSize == 0
+	}
+	return false
+}
+
+// GetFieldLength returns length of dynamic field
+func (entry *CencSampleEncryptionInformationGroupEntry) GetFieldLength(name string, ctx Context) uint {
+	switch name {
+	case "ConstantIV":
+		return uint(entry.ConstantIVSize)
+	}
+	panic(fmt.Errorf("invalid name of dynamic-length field: groupingType=seig fieldName=%s", name))
+}
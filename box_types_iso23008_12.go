@@ -0,0 +1,94 @@
+package mp4
+
+import "fmt"
+
+/*************************** imir ****************************/
+
+func BoxTypeImir() BoxType { return StrToBoxType("imir") }
+
+func init() {
+	AddBoxDef(&Imir{})
+}
+
+// Imir is HEIF image mirroring property
+type Imir struct {
+	Box
+	Reserved uint8 `mp4:"0,size=7,const=0"`
+	// Axis is 0 for the vertical axis (left-right flip) and 1 for the horizontal axis (top-bottom flip).
+	Axis uint8 `mp4:"1,size=1"`
+}
+
+// GetType returns the BoxType
+func (*Imir) GetType() BoxType {
+	return BoxTypeImir()
+}
+
+/*************************** irot ****************************/
+
+func BoxTypeIrot() BoxType { return StrToBoxType("irot") }
+
+func init() {
+	AddBoxDef(&Irot{})
+}
+
+// Irot is HEIF image rotation property
+type Irot struct {
+	Box
+	Reserved uint8 `mp4:"0,size=6,const=0"`
+	// Angle is the anti-clockwise rotation angle in units of 90 degrees.
+	Angle uint8 `mp4:"1,size=2"`
+}
+
+// GetType returns the BoxType
+func (*Irot) GetType() BoxType {
+	return BoxTypeIrot()
+}
+
+/*************************** ispe ****************************/
+
+func BoxTypeIspe() BoxType { return StrToBoxType("ispe") }
+
+func init() {
+	AddBoxDef(&Ispe{}, 0)
+}
+
+// Ispe is HEIF image spatial extents property
+type Ispe struct {
+	FullBox     `mp4:"0,extend"`
+	ImageWidth  uint32 `mp4:"1,size=32"`
+	ImageHeight uint32 `mp4:"2,size=32"`
+}
+
+// GetType returns the BoxType
+func (*Ispe) GetType() BoxType {
+	return BoxTypeIspe()
+}
+
+/*************************** pixi ****************************/
+
+func BoxTypePixi() BoxType { return StrToBoxType("pixi") }
+
+func init() {
+	AddBoxDef(&Pixi{}, 0)
+}
+
+// Pixi is HEIF pixel information property
+type Pixi struct {
+	FullBox        `mp4:"0,extend"`
+	NumChannels    uint8   `mp4:"1,size=8"`
+	BitsPerChannel []uint8 `mp4:"2,size=8,len=dynamic,dec"`
+}
+
+// GetType returns the BoxType
+func (*Pixi) GetType() BoxType {
+	return BoxTypePixi()
+}
+
+// GetFieldLength returns length of dynamic field
+func (pixi *Pixi) GetFieldLength(name string, ctx Context) uint {
+	switch name {
+	case "BitsPerChannel":
+		return uint(pixi.NumChannels)
+	}
+	panic(fmt.Errorf("invalid name of dynamic-length field: boxType=pixi fieldName=%s", name))
+}
@@ -0,0 +1,108 @@
+package mp4
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBoxTypesISO23008_12(t *testing.T) {
+	testCases := []struct {
+		name string
+		src  IImmutableBox
+		dst  IBox
+		bin  []byte
+		str  string
+		ctx  Context
+	}{
+		{
+			name: "imir",
+			src:  &Imir{Axis: 1},
+			dst:  &Imir{},
+			bin:  []byte{0x01},
+			str:  `Axis=0x1`,
+		},
+		{
+			name: "irot",
+			src:  &Irot{Angle: 3},
+			dst:  &Irot{},
+			bin:  []byte{0x03},
+			str:  `Angle=0x3`,
+		},
+		{
+			name: "ispe",
+			src: &Ispe{
+				FullBox: FullBox{
+					Version: 0,
+					Flags:   [3]byte{0x00, 0x00, 0x00},
+				},
+				ImageWidth:  1920,
+				ImageHeight: 1080,
+			},
+			dst: &Ispe{},
+			bin: []byte{
+				0,                // version
+				0x00, 0x00, 0x00, // flags
+				0x00, 0x00, 0x07, 0x80, // image width
+				0x00, 0x00, 0x04, 0x38, // image height
+			},
+			str: `Version=0 Flags=0x000000 ImageWidth=1920 ImageHeight=1080`,
+		},
+		{
+			name: "pixi",
+			src: &Pixi{
+				FullBox: FullBox{
+					Version: 0,
+					Flags:   [3]byte{0x00, 0x00, 0x00},
+				},
+				NumChannels:    3,
+				BitsPerChannel: []uint8{10, 10, 10},
+			},
+			dst: &Pixi{},
+			bin: []byte{
+				0,                // version
+				0x00, 0x00, 0x00, // flags
+				0x03,             // num channels
+				0x0a, 0x0a, 0x0a, // bits per channel
+			},
+			str: `Version=0 Flags=0x000000 NumChannels=0x3 BitsPerChannel=[10, 10, 10]`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Marshal
+			buf := bytes.NewBuffer(nil)
+			n, err := Marshal(buf, tc.src, tc.ctx)
+			require.NoError(t, err)
+			assert.Equal(t, uint64(len(tc.bin)), n)
+			assert.Equal(t, tc.bin, buf.Bytes())
+
+			// Unmarshal
+			r := bytes.NewReader(tc.bin)
+			n, err = Unmarshal(r, uint64(len(tc.bin)), tc.dst, tc.ctx)
+			require.NoError(t, err)
+			assert.Equal(t, uint64(buf.Len()), n)
+			assert.Equal(t, tc.src, tc.dst)
+			s, err := r.Seek(0, io.SeekCurrent)
+			require.NoError(t, err)
+			assert.Equal(t, int64(buf.Len()), s)
+
+			// UnmarshalAny
+			dst, n, err := UnmarshalAny(bytes.NewReader(tc.bin), tc.src.GetType(), uint64(len(tc.bin)), tc.ctx)
+			require.NoError(t, err)
+			assert.Equal(t, uint64(buf.Len()), n)
+			assert.Equal(t, tc.src, dst)
+			s, err = r.Seek(0, io.SeekCurrent)
+			require.NoError(t, err)
+			assert.Equal(t, int64(buf.Len()), s)
+
+			// Stringify
+			str, err := Stringify(tc.src, tc.ctx)
+			require.NoError(t, err)
+			assert.Equal(t, tc.str, str)
+		})
+	}
+}
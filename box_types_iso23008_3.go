@@ -0,0 +1,61 @@
+package mp4
+
+// ISO/IEC 23008-3 (MPEG-H 3D Audio)
+
+/*************************** mha1, mha2, mhm1, mhm2 ****************************/
+
+func BoxTypeMha1() BoxType { return StrToBoxType("mha1") }
+func BoxTypeMha2() BoxType { return StrToBoxType("mha2") }
+func BoxTypeMhm1() BoxType { return StrToBoxType("mhm1") }
+func BoxTypeMhm2() BoxType { return StrToBoxType("mhm2") }
+
+func init() {
+	AddAnyTypeBoxDef(&AudioSampleEntry{}, BoxTypeMha1())
+	AddAnyTypeBoxDef(&AudioSampleEntry{}, BoxTypeMha2())
+	AddAnyTypeBoxDef(&AudioSampleEntry{}, BoxTypeMhm1())
+	AddAnyTypeBoxDef(&AudioSampleEntry{}, BoxTypeMhm2())
+}
+
+/*************************** mhaC ****************************/
+
+func BoxTypeMhaC() BoxType { return StrToBoxType("mhaC") }
+
+func init() {
+	AddBoxDef(&MhaC{})
+}
+
+// MhaC is MHADecoderConfigurationRecord
+type MhaC struct {
+	Box
+	ConfigurationVersion           uint8  `mp4:"0,size=8"`
+	MPEGH3DAProfileLevelIndication uint8  `mp4:"1,size=8"`
+	ReferenceChannelLayout         uint8  `mp4:"2,size=8"`
+	MPEGH3DAConfigLength           uint16 `mp4:"3,size=16"`
+	MPEGH3DAConfig                 []byte `mp4:"4,size=8,len=dynamic"`
+}
+
+// GetType returns the BoxType
+func (*MhaC) GetType() BoxType {
+	return BoxTypeMhaC()
+}
+
+// GetFieldLength returns length of dynamic field
+func (mhac *MhaC) GetFieldLength(name string, ctx Context) uint {
+	switch name {
+	case "MPEGH3DAConfig":
+		return uint(mhac.MPEGH3DAConfigLength)
+	}
+	return 0
+}
+
+// mpeghChannelLayoutChannels is the number of channels of each ChannelConfiguration of ISO/IEC 23091-3.
+var mpeghChannelLayoutChannels = []uint16{0, 1, 2, 3, 4, 5, 6, 8, 2, 3, 4, 7, 8, 24, 8, 12, 10, 12, 14, 12, 14}
+
+// GetChannelCount returns the number of channels of the reference channel layout,
+// or zero if the layout is not defined by ChannelConfiguration.
+func (mhac *MhaC) GetChannelCount() uint16 {
+	if int(mhac.ReferenceChannelLayout) >= len(mpeghChannelLayoutChannels) {
+		return 0
+	}
+	return mpeghChannelLayoutChannels[mhac.ReferenceChannelLayout]
+}
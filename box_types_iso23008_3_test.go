@@ -0,0 +1,75 @@
+package mp4
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBoxTypesISO23008_3(t *testing.T) {
+	testCases := []struct {
+		name string
+		src  IImmutableBox
+		dst  IBox
+		bin  []byte
+		str  string
+		ctx  Context
+	}{
+		{
+			name: "mhaC",
+			src: &MhaC{
+				ConfigurationVersion:           1,
+				MPEGH3DAProfileLevelIndication: 0x0d,
+				ReferenceChannelLayout:         6,
+				MPEGH3DAConfigLength:           3,
+				MPEGH3DAConfig:                 []byte{0x12, 0x34, 0x56},
+			},
+			dst: &MhaC{},
+			bin: []byte{
+				0x01,       // ConfigurationVersion
+				0x0d,       // MPEGH3DAProfileLevelIndication
+				0x06,       // ReferenceChannelLayout
+				0x00, 0x03, // MPEGH3DAConfigLength
+				0x12, 0x34, 0x56, // MPEGH3DAConfig
+			},
+			str: `ConfigurationVersion=0x1 MPEGH3DAProfileLevelIndication=0xd ReferenceChannelLayout=0x6 MPEGH3DAConfigLength=3 MPEGH3DAConfig=[0x12, 0x34, 0x56]`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Marshal
+			buf := bytes.NewBuffer(nil)
+			n, err := Marshal(buf, tc.src, tc.ctx)
+			require.NoError(t, err)
+			assert.Equal(t, uint64(len(tc.bin)), n)
+			assert.Equal(t, tc.bin, buf.Bytes())
+
+			// Unmarshal
+			r := bytes.NewReader(tc.bin)
+			n, err = Unmarshal(r, uint64(len(tc.bin)), tc.dst, tc.ctx)
+			require.NoError(t, err)
+			assert.Equal(t, uint64(buf.Len()), n)
+			assert.Equal(t, tc.src, tc.dst)
+			s, err := r.Seek(0, io.SeekCurrent)
+			require.NoError(t, err)
+			assert.Equal(t, int64(buf.Len()), s)
+
+			// UnmarshalAny
+			dst, n, err := UnmarshalAny(bytes.NewReader(tc.bin), tc.src.GetType(), uint64(len(tc.bin)), tc.ctx)
+			require.NoError(t, err)
+			assert.Equal(t, uint64(buf.Len()), n)
+			assert.Equal(t, tc.src, dst)
+			s, err = r.Seek(0, io.SeekCurrent)
+			require.NoError(t, err)
+			assert.Equal(t, int64(buf.Len()), s)
+
+			// Stringify
+			str, err := Stringify(tc.src, tc.ctx)
+			require.NoError(t, err)
+			assert.Equal(t, tc.str, str)
+		})
+	}
+}
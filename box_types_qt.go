@@ -77,6 +77,63 @@ type ClosedCaptionSubtitleSampleEntry struct {
 	SampleEntry `mp4:"0,extend"`
 }
 
+/*************************** ccdp ****************************/
+
+func BoxTypeCcdp() BoxType {
+	return StrToBoxType("ccdp")
+}
+
+func init() {
+	AddBoxDef((*Ccdp)(nil))
+}
+
+// Ccdp is QuickTime ccdp atom type which contains a caption distribution packet (SMPTE 334-2) in c708 samples
+type Ccdp struct {
+	Box
+	Data []byte `mp4:"0,size=8"`
+}
+
+// GetType returns the BoxType
+func (*Ccdp) GetType() BoxType {
+	return BoxTypeCcdp()
+}
+
+/*************************** cdat/cdt2 ****************************/
+
+func BoxTypeCdat() BoxType {
+	return StrToBoxType("cdat")
+}
+func BoxTypeCdt2() BoxType {
+	return StrToBoxType("cdt2")
+}
+
+func init() {
+	AddBoxDef((*Cdat)(nil))
+	AddBoxDef((*Cdt2)(nil))
+}
+
+// Cdat is QuickTime cdat atom type which contains CEA-608 byte pairs of field 1 in c608 samples
+type Cdat struct {
+	Box
+	Data []byte `mp4:"0,size=8"`
+}
+
+// GetType returns the BoxType
+func (*Cdat) GetType() BoxType {
+	return BoxTypeCdat()
+}
+
+// Cdt2 is QuickTime cdt2 atom type which contains CEA-608 byte pairs of field 2 in c608 samples
+type Cdt2 struct {
+	Box
+	Data []byte `mp4:"0,size=8"`
+}
+
+// GetType returns the BoxType
+func (*Cdt2) GetType() BoxType {
+	return BoxTypeCdt2()
+}
+
 /*************************** chrm ****************************/
 
 func BoxTypeChrm() BoxType {
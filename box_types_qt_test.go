@@ -64,6 +64,30 @@ func TestBoxTypesQuickTime(t *testing.T) {
 			str: "Version=0 Flags=0x000000 FrameLength=4096 CompatibleVersion=0x0 BitDepth=0x10 Pb=0x28 Mb=0xa Kb=0xe NumChannels=0x2 MaxRun=255 MaxFrameBytes=16388 AvgBitRate=1411200 SampleRate=44100",
 			ctx: Context{},
 		},
+		{
+			name: "ccdp",
+			src:  &Ccdp{Data: []byte{0x96, 0x69, 0x09}},
+			dst:  &Ccdp{},
+			bin:  []byte{0x96, 0x69, 0x09},
+			str:  "Data=[0x96, 0x69, 0x9]",
+			ctx:  Context{},
+		},
+		{
+			name: "cdat",
+			src:  &Cdat{Data: []byte{0x94, 0x20, 0x94, 0x20}},
+			dst:  &Cdat{},
+			bin:  []byte{0x94, 0x20, 0x94, 0x20},
+			str:  "Data=[0x94, 0x20, 0x94, 0x20]",
+			ctx:  Context{},
+		},
+		{
+			name: "cdt2",
+			src:  &Cdt2{Data: []byte{0x15, 0x2c}},
+			dst:  &Cdt2{},
+			bin:  []byte{0x15, 0x2c},
+			str:  "Data=[0x15, 0x2c]",
+			ctx:  Context{},
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
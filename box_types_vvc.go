@@ -0,0 +1,213 @@
+package mp4
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Spidey120703/go-mp4/internal/bitio"
+)
+
+// ISO/IEC 14496-15 Chapter 11 (VVC)
+
+/*************************** vvc1, vvi1 ****************************/
+
+func BoxTypeVvc1() BoxType { return StrToBoxType("vvc1") }
+func BoxTypeVvi1() BoxType { return StrToBoxType("vvi1") }
+
+func init() {
+	AddAnyTypeBoxDef(&VisualSampleEntry{}, BoxTypeVvc1())
+	AddAnyTypeBoxDef(&VisualSampleEntry{}, BoxTypeVvi1())
+}
+
+/*************************** vvcC ****************************/
+
+func BoxTypeVvcC() BoxType { return StrToBoxType("vvcC") }
+
+func init() {
+	AddBoxDef(&VvcC{}, 0)
+}
+
+const (
+	VVCNaluTypeOPI = 12
+	VVCNaluTypeDCI = 13
+	VVCNaluTypeVPS = 14
+	VVCNaluTypeSPS = 15
+	VVCNaluTypePPS = 16
+)
+
+// VVCPTLRecord is VvcPTLRecord.
+// The fields depending on the number of sublayers are resolved by VvcC.
+type VVCPTLRecord struct {
+	Reserved                   uint8 `mp4:"0,size=2,const=0"`
+	NumBytesConstraintInfo     uint8 `mp4:"1,size=6"`
+	GeneralProfileIdc          uint8 `mp4:"2,size=7"`
+	GeneralTierFlag            bool  `mp4:"3,size=1"`
+	GeneralLevelIdc            uint8 `mp4:"4,size=8"`
+	PtlFrameOnlyConstraintFlag bool  `mp4:"5,size=1"`
+	PtlMultiLayerEnabledFlag   bool  `mp4:"6,size=1"`
+	// GeneralConstraintInfo holds the (8*NumBytesConstraintInfo-2) bits right-aligned.
+	GeneralConstraintInfo []byte `mp4:"7,size=8"`
+	// SublayerLevelPresentFlags are in order of the bitstream, from the highest sublayer to the lowest.
+	SublayerLevelPresentFlags []bool   `mp4:"8,size=1,len=dynamic"`
+	PtlReservedZeroBits       uint8    `mp4:"9,size=dynamic,opt=dynamic,const=0"`
+	SublayerLevelIdcs         []uint8  `mp4:"10,size=8,len=dynamic"`
+	PtlNumSubProfiles         uint8    `mp4:"11,size=8"`
+	GeneralSubProfileIdcs     []uint32 `mp4:"12,size=32,len=dynamic"`
+}
+
+type VVCNalu struct {
+	BaseCustomFieldObject
+	Length  uint16 `mp4:"0,size=16"`
+	NALUnit []byte `mp4:"1,size=8,len=dynamic"`
+}
+
+func (s *VVCNalu) GetFieldLength(name string, ctx Context) uint {
+	switch name {
+	case "NALUnit":
+		return uint(s.Length)
+	}
+	return 0
+}
+
+type VVCNaluArray struct {
+	BaseCustomFieldObject
+	Completeness bool      `mp4:"0,size=1"`
+	Reserved     uint8     `mp4:"1,size=2,const=0"`
+	NaluType     uint8     `mp4:"2,size=5"`
+	NumNalus     uint16    `mp4:"3,size=16,opt=dynamic"`
+	Nalus        []VVCNalu `mp4:"4,len=dynamic"`
+}
+
+// IsOptFieldEnabled check whether if the optional field is enabled
+func (a *VVCNaluArray) IsOptFieldEnabled(name string, ctx Context) bool {
+	switch name {
+	case "NumNalus":
+		// DCI and OPI arrays have only one NAL unit
+		return a.NaluType != VVCNaluTypeDCI && a.NaluType != VVCNaluTypeOPI
+	}
+	return false
+}
+
+func (a *VVCNaluArray) GetFieldLength(name string, ctx Context) uint {
+	switch name {
+	case "Nalus":
+		if a.NaluType == VVCNaluTypeDCI || a.NaluType == VVCNaluTypeOPI {
+			return 1
+		}
+		return uint(a.NumNalus)
+	}
+	return 0
+}
+
+type VvcC struct {
+	FullBox            `mp4:"0,extend"`
+	Reserved1          uint8          `mp4:"1,size=5,const=31"`
+	LengthSizeMinusOne uint8          `mp4:"2,size=2"`
+	PtlPresentFlag     bool           `mp4:"3,size=1"`
+	OlsIdx             uint16         `mp4:"4,size=9,opt=dynamic"`
+	NumSublayers       uint8          `mp4:"5,size=3,opt=dynamic"`
+	ConstantFrameRate  uint8          `mp4:"6,size=2,opt=dynamic"`
+	ChromaFormatIdc    uint8          `mp4:"7,size=2,opt=dynamic"`
+	BitDepthMinus8     uint8          `mp4:"8,size=3,opt=dynamic"`
+	Reserved2          uint8          `mp4:"9,size=5,opt=dynamic,const=31"`
+	NativePTL          VVCPTLRecord   `mp4:"10,opt=dynamic"`
+	MaxPictureWidth    uint16         `mp4:"11,size=16,opt=dynamic"`
+	MaxPictureHeight   uint16         `mp4:"12,size=16,opt=dynamic"`
+	AvgFrameRate       uint16         `mp4:"13,size=16,opt=dynamic"`
+	NumOfArrays        uint8          `mp4:"14,size=8"`
+	NaluArrays         []VVCNaluArray `mp4:"15,len=dynamic"`
+}
+
+// GetType returns the BoxType
+func (*VvcC) GetType() BoxType {
+	return BoxTypeVvcC()
+}
+
+// IsOptFieldEnabled check whether if the optional field is enabled
+func (vvcc *VvcC) IsOptFieldEnabled(name string, ctx Context) bool {
+	switch name {
+	case "OlsIdx",
+		"NumSublayers",
+		"ConstantFrameRate",
+		"ChromaFormatIdc",
+		"BitDepthMinus8",
+		"Reserved2",
+		"NativePTL",
+		"MaxPictureWidth",
+		"MaxPictureHeight",
+		"AvgFrameRate":
+		return vvcc.PtlPresentFlag
+	case "PtlReservedZeroBits":
+		return vvcc.NumSublayers > 1
+	}
+	return false
+}
+
+// GetFieldSize returns size of dynamic field
+func (vvcc *VvcC) GetFieldSize(name string, ctx Context) uint {
+	switch name {
+	case "PtlReservedZeroBits":
+		return 9 - uint(vvcc.NumSublayers)
+	}
+	panic(fmt.Errorf("invalid name of dynamic-size field: boxType=vvcC fieldName=%s", name))
+}
+
+// GetFieldLength returns length of dynamic field
+func (vvcc *VvcC) GetFieldLength(name string, ctx Context) uint {
+	switch name {
+	case "SublayerLevelPresentFlags":
+		if vvcc.NumSublayers == 0 {
+			return 0
+		}
+		return uint(vvcc.NumSublayers) - 1
+	case "SublayerLevelIdcs":
+		var n uint
+		for _, present := range vvcc.NativePTL.SublayerLevelPresentFlags {
+			if present {
+				n++
+			}
+		}
+		return n
+	case "GeneralSubProfileIdcs":
+		return uint(vvcc.NativePTL.PtlNumSubProfiles)
+	case "NaluArrays":
+		return uint(vvcc.NumOfArrays)
+	}
+	panic(fmt.Errorf("invalid name of dynamic-length field: boxType=vvcC fieldName=%s", name))
+}
+
+func (vvcc *VvcC) OnReadField(name string, r bitio.ReadSeeker, leftBits uint64, ctx Context) (rbits uint64, override bool, err error) {
+	if name != "GeneralConstraintInfo" {
+		return 0, false, nil
+	}
+	size := vvcc.constraintInfoBits()
+	if size > leftBits {
+		return 0, false, errors.New("vvcC: general constraint info exceeds the box size")
+	}
+	if vvcc.NativePTL.GeneralConstraintInfo, err = r.ReadBits(uint(size)); err != nil {
+		return 0, false, err
+	}
+	return size, true, nil
+}
+
+func (vvcc *VvcC) OnWriteField(name string, w bitio.Writer, ctx Context) (wbits uint64, override bool, err error) {
+	if name != "GeneralConstraintInfo" {
+		return 0, false, nil
+	}
+	size := vvcc.constraintInfoBits()
+	if uint64(len(vvcc.NativePTL.GeneralConstraintInfo))*8 < size {
+		return 0, false, errors.New("vvcC: GeneralConstraintInfo is shorter than NumBytesConstraintInfo")
+	}
+	if err := w.WriteBits(vvcc.NativePTL.GeneralConstraintInfo, uint(size)); err != nil {
+		return 0, false, err
+	}
+	return size, true, nil
+}
+
+// constraintInfoBits returns the number of bits of general_constraint_info.
+func (vvcc *VvcC) constraintInfoBits() uint64 {
+	if vvcc.NativePTL.NumBytesConstraintInfo == 0 {
+		return 0
+	}
+	return uint64(vvcc.NativePTL.NumBytesConstraintInfo)*8 - 2
+}
@@ -0,0 +1,152 @@
+package mp4
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBoxTypesVVC(t *testing.T) {
+	testCases := []struct {
+		name string
+		src  IImmutableBox
+		dst  IBox
+		bin  []byte
+		str  string
+		ctx  Context
+	}{
+		{
+			name: "vvcC",
+			src: &VvcC{
+				FullBox:            FullBox{Version: 0, Flags: [3]byte{0x00, 0x00, 0x00}},
+				Reserved1:          31,
+				LengthSizeMinusOne: 3,
+				PtlPresentFlag:     true,
+				OlsIdx:             0,
+				NumSublayers:       3,
+				ConstantFrameRate:  1,
+				ChromaFormatIdc:    1,
+				BitDepthMinus8:     2,
+				Reserved2:          31,
+				NativePTL: VVCPTLRecord{
+					NumBytesConstraintInfo:     1,
+					GeneralProfileIdc:          1,
+					GeneralTierFlag:            false,
+					GeneralLevelIdc:            83,
+					PtlFrameOnlyConstraintFlag: true,
+					PtlMultiLayerEnabledFlag:   false,
+					GeneralConstraintInfo:      []byte{0x00},
+					SublayerLevelPresentFlags:  []bool{true, false},
+					SublayerLevelIdcs:          []uint8{80},
+					PtlNumSubProfiles:          1,
+					GeneralSubProfileIdcs:      []uint32{0x12345678},
+				},
+				MaxPictureWidth:  1920,
+				MaxPictureHeight: 1080,
+				AvgFrameRate:     0,
+				NumOfArrays:      2,
+				NaluArrays: []VVCNaluArray{
+					{
+						Completeness: true,
+						NaluType:     VVCNaluTypeDCI,
+						Nalus: []VVCNalu{{
+							Length:  2,
+							NALUnit: []byte{0x00, 0x68},
+						}},
+					},
+					{
+						Completeness: true,
+						NaluType:     VVCNaluTypeSPS,
+						NumNalus:     1,
+						Nalus: []VVCNalu{{
+							Length:  3,
+							NALUnit: []byte{0x00, 0x79, 0x00},
+						}},
+					},
+				},
+			},
+			dst: &VvcC{},
+			bin: []byte{
+				0, 0x00, 0x00, 0x00, // version, flags
+				0xff,       // reserved, LengthSizeMinusOne, PtlPresentFlag
+				0x00, 0x35, // OlsIdx, NumSublayers, ConstantFrameRate, ChromaFormatIdc
+				0x5f,                   // BitDepthMinus8, reserved
+				0x01,                   // NumBytesConstraintInfo
+				0x02,                   // GeneralProfileIdc, GeneralTierFlag
+				0x53,                   // GeneralLevelIdc
+				0x80,                   // PtlFrameOnlyConstraintFlag, PtlMultiLayerEnabledFlag, GeneralConstraintInfo
+				0x80,                   // SublayerLevelPresentFlags, PtlReservedZeroBits
+				0x50,                   // SublayerLevelIdcs
+				0x01,                   // PtlNumSubProfiles
+				0x12, 0x34, 0x56, 0x78, // GeneralSubProfileIdcs
+				0x07, 0x80, // MaxPictureWidth
+				0x04, 0x38, // MaxPictureHeight
+				0x00, 0x00, // AvgFrameRate
+				0x02,                   // NumOfArrays
+				0x8d,                   // Completeness, reserved, NaluType (DCI)
+				0x00, 0x02, 0x00, 0x68, // Nalus
+				0x8f,       // Completeness, reserved, NaluType (SPS)
+				0x00, 0x01, // NumNalus
+				0x00, 0x03, 0x00, 0x79, 0x00, // Nalus
+			},
+			str: `Version=0 Flags=0x000000 LengthSizeMinusOne=0x3 PtlPresentFlag=true OlsIdx=0 NumSublayers=0x3 ConstantFrameRate=0x1 ChromaFormatIdc=0x1 BitDepthMinus8=0x2 ` +
+				`NativePTL={NumBytesConstraintInfo=0x1 GeneralProfileIdc=0x1 GeneralTierFlag=false GeneralLevelIdc=0x53 PtlFrameOnlyConstraintFlag=true PtlMultiLayerEnabledFlag=false GeneralConstraintInfo=[0x0] SublayerLevelPresentFlags=[true, false] SublayerLevelIdcs=[0x50] PtlNumSubProfiles=0x1 GeneralSubProfileIdcs=[305419896]} ` +
+				`MaxPictureWidth=1920 MaxPictureHeight=1080 AvgFrameRate=0 NumOfArrays=0x2 ` +
+				`NaluArrays=[{Completeness=true NaluType=0xd Nalus=[{Length=2 NALUnit=[0x0, 0x68]}]}, {Completeness=true NaluType=0xf NumNalus=1 Nalus=[{Length=3 NALUnit=[0x0, 0x79, 0x0]}]}]`,
+		},
+		{
+			name: "vvcC: without PTL",
+			src: &VvcC{
+				FullBox:            FullBox{Version: 0, Flags: [3]byte{0x00, 0x00, 0x00}},
+				Reserved1:          31,
+				LengthSizeMinusOne: 1,
+				NumOfArrays:        0,
+				NaluArrays:         []VVCNaluArray{},
+			},
+			dst: &VvcC{},
+			bin: []byte{
+				0, 0x00, 0x00, 0x00, // version, flags
+				0xfa, // reserved, LengthSizeMinusOne, PtlPresentFlag
+				0x00, // NumOfArrays
+			},
+			str: `Version=0 Flags=0x000000 LengthSizeMinusOne=0x1 PtlPresentFlag=false NumOfArrays=0x0 NaluArrays=[]`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Marshal
+			buf := bytes.NewBuffer(nil)
+			n, err := Marshal(buf, tc.src, tc.ctx)
+			require.NoError(t, err)
+			assert.Equal(t, uint64(len(tc.bin)), n)
+			assert.Equal(t, tc.bin, buf.Bytes())
+
+			// Unmarshal
+			r := bytes.NewReader(tc.bin)
+			n, err = Unmarshal(r, uint64(len(tc.bin)), tc.dst, tc.ctx)
+			require.NoError(t, err)
+			assert.Equal(t, uint64(buf.Len()), n)
+			assert.Equal(t, tc.src, tc.dst)
+			s, err := r.Seek(0, io.SeekCurrent)
+			require.NoError(t, err)
+			assert.Equal(t, int64(buf.Len()), s)
+
+			// UnmarshalAny
+			dst, n, err := UnmarshalAny(bytes.NewReader(tc.bin), tc.src.GetType(), uint64(len(tc.bin)), tc.ctx)
+			require.NoError(t, err)
+			assert.Equal(t, uint64(buf.Len()), n)
+			assert.Equal(t, tc.src, dst)
+			s, err = r.Seek(0, io.SeekCurrent)
+			require.NoError(t, err)
+			assert.Equal(t, int64(buf.Len()), s)
+
+			// Stringify
+			str, err := Stringify(tc.src, tc.ctx)
+			require.NoError(t, err)
+			assert.Equal(t, tc.str, str)
+		})
+	}
+}
@@ -0,0 +1,378 @@
+package mp4
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CaptionChannel is a caption channel of CEA-608 (CC1 to CC4) or a caption service of CEA-708.
+type CaptionChannel int
+
+const (
+	CaptionCC1 CaptionChannel = iota + 1
+	CaptionCC2
+	CaptionCC3
+	CaptionCC4
+)
+
+const captionServiceBase = 0x100
+
+// CaptionService returns the channel of the CEA-708 caption service whose number is from 1 to 63.
+func CaptionService(number int) CaptionChannel {
+	return CaptionChannel(captionServiceBase + number)
+}
+
+// IsCEA708 reports whether the channel is a CEA-708 caption service.
+func (c CaptionChannel) IsCEA708() bool {
+	return c > captionServiceBase && c < captionServiceBase+64
+}
+
+func (c CaptionChannel) String() string {
+	if c.IsCEA708() {
+		return fmt.Sprintf("SERVICE%d", c-captionServiceBase)
+	}
+	return fmt.Sprintf("CC%d", int(c))
+}
+
+// ParseCaptionChannel parses the channel name like "CC1" or "SERVICE1".
+func ParseCaptionChannel(s string) (CaptionChannel, error) {
+	upper := strings.ToUpper(s)
+	if strings.HasPrefix(upper, "CC") {
+		n, err := strconv.Atoi(upper[2:])
+		if err == nil && n >= 1 && n <= 4 {
+			return CaptionChannel(n), nil
+		}
+	} else if strings.HasPrefix(upper, "SERVICE") {
+		n, err := strconv.Atoi(upper[7:])
+		if err == nil && n >= 1 && n <= 63 {
+			return CaptionService(n), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid caption channel: %s", s)
+}
+
+const (
+	ccTypeNTSCField1 = 0
+	ccTypeNTSCField2 = 1
+	ccTypeDTVCCData  = 2
+	ccTypeDTVCCStart = 3
+)
+
+// ccPacket is a cc_data triple of CEA-708 (or a byte pair of CEA-608 with the corresponding cc_type).
+type ccPacket struct {
+	time   time.Duration
+	ccType uint8
+	data   [2]byte
+}
+
+// captionSource holds the caption data of a track in order of presentation time.
+type captionSource struct {
+	packets []ccPacket
+	// end is the end time of the track.
+	end time.Duration
+}
+
+// ExtractCaptions decodes the CEA-608 or CEA-708 captions of the channel and returns them as cues.
+// The captions are read from the c608 or c708 track (cdat, cdt2 or ccdp atoms),
+// or from SEI NAL units (ATSC A/53 user_data_registered_itu_t_t35) of the H.264 or H.265 track.
+// The first closed caption track, or the first video track if there is none, is used when trackID is zero.
+func ExtractCaptions(r io.ReadSeeker, trackID uint32, channel CaptionChannel) ([]*WebVTTCue, error) {
+	src, err := readCaptionSource(r, trackID)
+	if err != nil {
+		return nil, err
+	}
+	return src.decode(channel), nil
+}
+
+// CaptionChannels returns the caption channels and services which have any caption text.
+// The track is selected in the same way as ExtractCaptions.
+func CaptionChannels(r io.ReadSeeker, trackID uint32) ([]CaptionChannel, error) {
+	src, err := readCaptionSource(r, trackID)
+	if err != nil {
+		return nil, err
+	}
+	channels := []CaptionChannel{CaptionCC1, CaptionCC2, CaptionCC3, CaptionCC4}
+	for _, service := range src.services() {
+		channels = append(channels, CaptionService(service))
+	}
+	var found []CaptionChannel
+	for _, channel := range channels {
+		if len(src.decode(channel)) != 0 {
+			found = append(found, channel)
+		}
+	}
+	return found, nil
+}
+
+// WriteSRT writes the cues to w as a SubRip file.
+func WriteSRT(w io.Writer, cues []*WebVTTCue) error {
+	buf := &bytes.Buffer{}
+	for i, cue := range cues {
+		fmt.Fprintf(buf, "%d\n%s --> %s\n%s\n\n", i+1,
+			strings.Replace(formatWebVTTTimestamp(cue.Start), ".", ",", 1),
+			strings.Replace(formatWebVTTTimestamp(cue.End), ".", ",", 1),
+			cue.Payload)
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+func readCaptionSource(r io.ReadSeeker, trackID uint32) (*captionSource, error) {
+	bis, err := ExtractBoxes(r, nil, []BoxPath{
+		{BoxTypeMoov(), BoxTypeTrak(), BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeC608()},
+		{BoxTypeMoov(), BoxTypeTrak(), BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeC708()},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var ccTrackID uint32
+	for _, bi := range bis {
+		if trackID == 0 || bi.TrackID == trackID {
+			ccTrackID = bi.TrackID
+			break
+		}
+	}
+
+	var track *annexBTrack
+	if ccTrackID == 0 {
+		if track, err = readAnnexBTrack(r, trackID); err != nil {
+			return nil, fmt.Errorf("caption track not found: %w", err)
+		}
+		ccTrackID = track.trackID
+	}
+
+	sr, err := NewSampleReader(r, ccTrackID)
+	if err != nil {
+		return nil, err
+	}
+	if sr.Timescale == 0 {
+		return nil, errors.New("invalid timescale")
+	}
+	timescale := int64(sr.Timescale)
+	toDuration := func(t int64) time.Duration {
+		return time.Duration(t/timescale)*time.Second + time.Duration(t%timescale)*time.Second/time.Duration(timescale)
+	}
+
+	src := &captionSource{}
+	for {
+		s, err := sr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		t := toDuration(s.PTS)
+		if end := toDuration(s.PTS + int64(s.Duration)); end > src.end {
+			src.end = end
+		}
+		if track == nil {
+			err = src.appendCaptionSample(t, s.Data)
+		} else {
+			index := int(s.SampleDescriptionIndex) - 1
+			if index >= 0 && index < len(track.configs) && track.configs[index] != nil {
+				err = src.appendVideoSample(t, track.configs[index], s.Data)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// captions in SEI are stored in order of decoding
+	sort.SliceStable(src.packets, func(i, j int) bool {
+		return src.packets[i].time < src.packets[j].time
+	})
+	return src, nil
+}
+
+// appendCaptionSample appends byte pairs in cdat and cdt2 atoms, and cc_data in ccdp atoms.
+func (src *captionSource) appendCaptionSample(t time.Duration, data []byte) error {
+	bips, err := ExtractBoxesWithPayload(bytes.NewReader(data), nil, []BoxPath{
+		{BoxTypeCdat()}, {BoxTypeCdt2()}, {BoxTypeCcdp()},
+	})
+	if err != nil {
+		return err
+	}
+	for _, bip := range bips {
+		switch box := bip.Payload.(type) {
+		case *Cdat:
+			src.appendPairs(t, ccTypeNTSCField1, box.Data)
+		case *Cdt2:
+			src.appendPairs(t, ccTypeNTSCField2, box.Data)
+		case *Ccdp:
+			src.appendCDP(t, box.Data)
+		}
+	}
+	return nil
+}
+
+func (src *captionSource) appendPairs(t time.Duration, ccType uint8, data []byte) {
+	for i := 0; i+1 < len(data); i += 2 {
+		src.packets = append(src.packets, ccPacket{time: t, ccType: ccType, data: [2]byte{data[i], data[i+1]}})
+	}
+}
+
+// appendCDP appends cc_data in the caption distribution packet (SMPTE 334-2).
+func (src *captionSource) appendCDP(t time.Duration, cdp []byte) {
+	if len(cdp) < 7 || cdp[0] != 0x96 || cdp[1] != 0x69 {
+		return
+	}
+	flags := cdp[4]
+	offset := 7
+	if flags&0x80 != 0 { // time_code_present
+		offset += 5
+	}
+	if flags&0x40 == 0 || offset+2 > len(cdp) || cdp[offset] != 0x72 { // ccdata_present, ccdata_id
+		return
+	}
+	src.appendCCData(t, int(cdp[offset+1]&0x1f), cdp[offset+2:])
+}
+
+// appendCCData appends the cc_data triples.
+func (src *captionSource) appendCCData(t time.Duration, count int, data []byte) {
+	for i := 0; i < count && 3*i+2 < len(data); i++ {
+		triple := data[3*i : 3*i+3]
+		if triple[0]&0x04 == 0 { // cc_valid
+			continue
+		}
+		src.packets = append(src.packets, ccPacket{time: t, ccType: triple[0] & 0x03, data: [2]byte{triple[1], triple[2]}})
+	}
+}
+
+// appendVideoSample appends cc_data in SEI NAL units of the video sample.
+func (src *captionSource) appendVideoSample(t time.Duration, config *annexBConfig, data []byte) error {
+	for offset := 0; offset < len(data); {
+		if offset+config.lengthSize > len(data) {
+			return errors.New("truncated NAL unit length")
+		}
+		var length int
+		for i := 0; i < config.lengthSize; i++ {
+			length = length<<8 | int(data[offset+i])
+		}
+		offset += config.lengthSize
+		if offset+length > len(data) {
+			return fmt.Errorf("invalid NAL unit length: %d", length)
+		}
+		nalu := data[offset : offset+length]
+		offset += length
+
+		if config.hevc {
+			if len(nalu) > 2 && (nalu[0]>>1)&0x3f == 39 { // PREFIX_SEI_NUT
+				src.appendSEI(t, removeEmulationPrevention(nalu[2:]))
+			}
+		} else if len(nalu) > 1 && nalu[0]&0x1f == 6 {
+			src.appendSEI(t, removeEmulationPrevention(nalu[1:]))
+		}
+	}
+	return nil
+}
+
+// appendSEI appends cc_data in user_data_registered_itu_t_t35 SEI messages of ATSC A/53.
+func (src *captionSource) appendSEI(t time.Duration, rbsp []byte) {
+	for len(rbsp) > 1 {
+		var payloadType, payloadSize int
+		for len(rbsp) != 0 && rbsp[0] == 0xff {
+			payloadType += 0xff
+			rbsp = rbsp[1:]
+		}
+		if len(rbsp) == 0 {
+			return
+		}
+		payloadType += int(rbsp[0])
+		rbsp = rbsp[1:]
+		for len(rbsp) != 0 && rbsp[0] == 0xff {
+			payloadSize += 0xff
+			rbsp = rbsp[1:]
+		}
+		if len(rbsp) == 0 {
+			return
+		}
+		payloadSize += int(rbsp[0])
+		rbsp = rbsp[1:]
+		if payloadSize > len(rbsp) {
+			return
+		}
+		payload := rbsp[:payloadSize]
+		rbsp = rbsp[payloadSize:]
+
+		// itu_t_t35_country_code (United States), itu_t_t35_provider_code (ATSC),
+		// user_identifier ("GA94") and user_data_type_code (cc_data)
+		if payloadType != 4 || len(payload) < 10 || payload[0] != 0xb5 ||
+			payload[1] != 0x00 || payload[2] != 0x31 || string(payload[3:7]) != "GA94" || payload[7] != 0x03 {
+			continue
+		}
+		if payload[8]&0x40 == 0 { // process_cc_data_flag
+			continue
+		}
+		src.appendCCData(t, int(payload[8]&0x1f), payload[10:])
+	}
+}
+
+// services returns the numbers of CEA-708 services which appear in the caption data.
+func (src *captionSource) services() []int {
+	found := make(map[int]bool)
+	d := &cea708Decoder{}
+	d.onServiceBlock = func(service int, block []byte) {
+		found[service] = true
+	}
+	for _, p := range src.packets {
+		d.decode(p)
+	}
+	d.flushPacket()
+	var services []int
+	for service := range found {
+		services = append(services, service)
+	}
+	sort.Ints(services)
+	return services
+}
+
+func (src *captionSource) decode(channel CaptionChannel) []*WebVTTCue {
+	var cues *captionCueBuilder
+	if channel.IsCEA708() {
+		d := newCEA708Decoder(int(channel - captionServiceBase))
+		for _, p := range src.packets {
+			d.decode(p)
+		}
+		d.flushPacket()
+		cues = &d.cues
+	} else {
+		d := newCEA608Decoder(channel)
+		for _, p := range src.packets {
+			d.decode(p)
+		}
+		cues = &d.cues
+	}
+	cues.flush(src.end)
+	return cues.cues
+}
+
+// captionCueBuilder makes cues from the changes of the displayed text.
+type captionCueBuilder struct {
+	cues  []*WebVTTCue
+	shown string
+	since time.Duration
+}
+
+func (b *captionCueBuilder) update(t time.Duration, text string) {
+	if text == b.shown {
+		return
+	}
+	b.flush(t)
+	b.shown = text
+	b.since = t
+}
+
+func (b *captionCueBuilder) flush(t time.Duration) {
+	if b.shown != "" && t > b.since {
+		b.cues = append(b.cues, &WebVTTCue{Start: b.since, End: t, Payload: b.shown})
+	}
+	b.shown = ""
+	b.since = t
+}
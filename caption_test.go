@@ -0,0 +1,327 @@
+package mp4
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/orcaman/writerseeker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cea608Packets returns the packets of the byte pairs at the time.
+func cea608Packets(t time.Duration, ccType uint8, pairs ...[2]byte) []ccPacket {
+	packets := make([]ccPacket, 0, len(pairs))
+	for _, pair := range pairs {
+		packets = append(packets, ccPacket{time: t, ccType: ccType, data: pair})
+	}
+	return packets
+}
+
+// cea608Text returns the byte pairs of the text.
+func cea608Text(text string) [][2]byte {
+	var pairs [][2]byte
+	for i := 0; i < len(text); i += 2 {
+		pair := [2]byte{text[i], 0}
+		if i+1 < len(text) {
+			pair[1] = text[i+1]
+		}
+		pairs = append(pairs, pair)
+	}
+	return pairs
+}
+
+// dtvccPackets returns the packets of a DTVCC packet which has the service block.
+func dtvccPackets(t time.Duration, service int, block []byte) []ccPacket {
+	packet := append([]byte{0, byte(service<<5 | len(block))}, block...)
+	if len(packet)%2 != 0 {
+		packet = append(packet, 0)
+	}
+	packet[0] = byte(len(packet) / 2)
+	var packets []ccPacket
+	for i := 0; i < len(packet); i += 2 {
+		ccType := uint8(ccTypeDTVCCData)
+		if i == 0 {
+			ccType = ccTypeDTVCCStart
+		}
+		packets = append(packets, ccPacket{time: t, ccType: ccType, data: [2]byte{packet[i], packet[i+1]}})
+	}
+	return packets
+}
+
+func decodeCEA608(channel CaptionChannel, end time.Duration, packets ...[]ccPacket) []*WebVTTCue {
+	src := &captionSource{end: end}
+	for _, p := range packets {
+		src.packets = append(src.packets, p...)
+	}
+	return src.decode(channel)
+}
+
+func TestCEA608PopOn(t *testing.T) {
+	load := append([][2]byte{{0x14, 0x20}, {0x14, 0x20}, {0x14, 0x60}, {0x14, 0x60}}, cea608Text("HELLO")...)
+	load = append(load, [2]byte{0x14, 0x2e}, [2]byte{0x14, 0x2e}) // ENM is applied only once
+	load = append(load, [2]byte{0x13, 0x60}, [2]byte{0x13, 0x60})
+	load = append(load, cea608Text("WORLD")...)
+	cues := decodeCEA608(CaptionCC1, 10*time.Second,
+		cea608Packets(0, ccTypeNTSCField1, load...),
+		cea608Packets(time.Second, ccTypeNTSCField1, [2]byte{0x14, 0x2f}, [2]byte{0x14, 0x2f}),
+		cea608Packets(3*time.Second, ccTypeNTSCField1, [2]byte{0x14, 0x2c}, [2]byte{0x14, 0x2c}),
+	)
+	require.Len(t, cues, 1)
+	assert.Equal(t, time.Second, cues[0].Start)
+	assert.Equal(t, 3*time.Second, cues[0].End)
+	assert.Equal(t, "WORLD", cues[0].Payload)
+
+	// special and extended characters
+	load = append([][2]byte{{0x14, 0x20}, {0x14, 0x60}}, cea608Text("A")...)
+	load = append(load, [2]byte{0x11, 0x37}) // ♪
+	load = append(load, cea608Text("E")...)
+	load = append(load, [2]byte{0x12, 0x21}) // É replaces E
+	load = append(load, cea608Text("*")...)
+	load = append(load, [2]byte{0x14, 0x2f})
+	cues = decodeCEA608(CaptionCC1, 2*time.Second, cea608Packets(0, ccTypeNTSCField1, load...))
+	require.Len(t, cues, 1)
+	assert.Equal(t, "A♪Éá", cues[0].Payload)
+}
+
+func TestCEA608RollUp(t *testing.T) {
+	ru3 := [2]byte{0x14, 0x26}
+	cr := [2]byte{0x14, 0x2d}
+	pac := [2]byte{0x14, 0x60}
+	cues := decodeCEA608(CaptionCC1, 4*time.Second,
+		cea608Packets(0, ccTypeNTSCField1, append([][2]byte{ru3, pac}, cea608Text("ONE")...)...),
+		cea608Packets(time.Second, ccTypeNTSCField1, append([][2]byte{cr}, cea608Text("TWO")...)...),
+		cea608Packets(2*time.Second, ccTypeNTSCField1, append([][2]byte{cr}, cea608Text("THREE")...)...),
+		cea608Packets(3*time.Second, ccTypeNTSCField1, cr),
+	)
+	require.Len(t, cues, 3)
+	assert.Equal(t, &WebVTTCue{Start: time.Second, End: 2 * time.Second, Payload: "ONE"}, cues[0])
+	assert.Equal(t, &WebVTTCue{Start: 2 * time.Second, End: 3 * time.Second, Payload: "ONE\nTWO"}, cues[1])
+	assert.Equal(t, &WebVTTCue{Start: 3 * time.Second, End: 4 * time.Second, Payload: "TWO\nTHREE"}, cues[2])
+}
+
+func TestCEA608PaintOn(t *testing.T) {
+	load := append([][2]byte{{0x14, 0x29}, {0x14, 0x60}}, cea608Text("PAINT")...)
+	cues := decodeCEA608(CaptionCC1, 3*time.Second,
+		cea608Packets(0, ccTypeNTSCField1, load...),
+		cea608Packets(time.Second, ccTypeNTSCField1, [2]byte{0x14, 0x2d}),
+		cea608Packets(2*time.Second, ccTypeNTSCField1, [2]byte{0x14, 0x2c}),
+	)
+	require.Len(t, cues, 1)
+	assert.Equal(t, &WebVTTCue{Start: time.Second, End: 2 * time.Second, Payload: "PAINT"}, cues[0])
+}
+
+func TestCEA608Channels(t *testing.T) {
+	var packets []ccPacket
+	// CC1 and CC2 are interleaved in field 1
+	packets = append(packets, cea608Packets(0, ccTypeNTSCField1, append([][2]byte{{0x14, 0x20}, {0x14, 0x60}}, cea608Text("FIRST")...)...)...)
+	packets = append(packets, cea608Packets(0, ccTypeNTSCField1, append([][2]byte{{0x1c, 0x20}, {0x1c, 0x60}}, cea608Text("SECOND")...)...)...)
+	packets = append(packets, cea608Packets(0, ccTypeNTSCField1, [2]byte{0x14, 0x2f}, [2]byte{0x1c, 0x2f})...)
+	// CC3 in field 2 with odd parity bits
+	packets = append(packets, cea608Packets(0, ccTypeNTSCField2, append([][2]byte{{0x94, 0x20}, {0x94, 0xe0}}, cea608Text("THIRD")...)...)...)
+	packets = append(packets, cea608Packets(0, ccTypeNTSCField2, [2]byte{0x94, 0x2f})...)
+
+	for channel, expected := range map[CaptionChannel]string{
+		CaptionCC1: "FIRST",
+		CaptionCC2: "SECOND",
+		CaptionCC3: "THIRD",
+	} {
+		cues := decodeCEA608(channel, time.Second, packets)
+		require.Len(t, cues, 1, channel.String())
+		assert.Equal(t, expected, cues[0].Payload)
+	}
+	assert.Empty(t, decodeCEA608(CaptionCC4, time.Second, packets))
+}
+
+func TestCEA708(t *testing.T) {
+	define := []byte{0x98, 0x38, 0x00, 0x00, 0x01, 0x1f, 0x00} // DF0: visible, 2 rows, 32 columns
+	show := append(append(define, "Hello"...), 0x0d)
+	show = append(show, "caf"...)
+	show = append(show, 0xe9, 0x03) // G1 é, ETX
+	src := &captionSource{end: 5 * time.Second}
+	src.packets = append(src.packets, dtvccPackets(time.Second, 1, show)...)
+	src.packets = append(src.packets, dtvccPackets(time.Second, 2, append(define, "Other"...))...)
+	src.packets = append(src.packets, dtvccPackets(3*time.Second, 1, []byte{0x8a, 0x01})...) // HDW
+	src.packets = append(src.packets, dtvccPackets(4*time.Second, 1, []byte{0x89, 0x01})...) // DSW
+
+	assert.Equal(t, []int{1, 2}, src.services())
+	cues := src.decode(CaptionService(1))
+	require.Len(t, cues, 2)
+	assert.Equal(t, &WebVTTCue{Start: time.Second, End: 3 * time.Second, Payload: "Hello\ncafé"}, cues[0])
+	assert.Equal(t, &WebVTTCue{Start: 4 * time.Second, End: 5 * time.Second, Payload: "Hello\ncafé"}, cues[1])
+	cues = src.decode(CaptionService(2))
+	require.Len(t, cues, 1)
+	assert.Equal(t, "Other", cues[0].Payload)
+	assert.Empty(t, src.decode(CaptionService(3)))
+}
+
+func TestCaptionSEI(t *testing.T) {
+	ccData := []byte{
+		0xfc, 0x14, 0x20, // field 1
+		0xfc, 0x14, 0x60,
+		0xf8, 0x00, 0x00, // invalid
+		0xfc, 0x48, 0x49,
+		0xfc, 0x14, 0x2f,
+	}
+	payload := append([]byte{0xb5, 0x00, 0x31, 'G', 'A', '9', '4', 0x03, 0x40 | 5, 0xff}, ccData...)
+	payload = append(payload, 0xff)
+
+	nalu := []byte{0x06, 0x05, 0x02, 0x00, 0x00, 0x03} // user_data_unregistered with emulation prevention
+	nalu = append(nalu, 0x04, byte(len(payload)))
+	nalu = append(nalu, payload...)
+	nalu = append(nalu, 0x80)
+	sample := append([]byte{0x00, 0x00, 0x00, byte(len(nalu))}, nalu...)
+	slice := []byte{0x65, 0x88, 0x84}
+	sample = append(sample, 0x00, 0x00, 0x00, byte(len(slice)))
+	sample = append(sample, slice...)
+
+	src := &captionSource{end: 2 * time.Second}
+	require.NoError(t, src.appendVideoSample(time.Second, &annexBConfig{lengthSize: 4}, sample))
+	require.Len(t, src.packets, 4)
+	cues := src.decode(CaptionCC1)
+	require.Len(t, cues, 1)
+	assert.Equal(t, &WebVTTCue{Start: time.Second, End: 2 * time.Second, Payload: "HI"}, cues[0])
+
+	assert.Error(t, src.appendVideoSample(0, &annexBConfig{lengthSize: 4}, []byte{0x00, 0x00, 0x00, 0x10, 0x06}))
+}
+
+// buildTestCDP returns a caption distribution packet which has the cc_data triples.
+func buildTestCDP(triples ...[3]byte) []byte {
+	cdp := []byte{0x96, 0x69, 0x00, 0x4f, 0x43, 0x00, 0x01, 0x72, 0xe0 | byte(len(triples))}
+	for _, triple := range triples {
+		cdp = append(cdp, triple[:]...)
+	}
+	cdp = append(cdp, 0x74, 0x00, 0x01, 0x00)
+	cdp[2] = byte(len(cdp))
+	return cdp
+}
+
+// buildTestCaptionFile returns a movie file which has a closed caption track of the samples.
+// Each sample has the duration of one second.
+func buildTestCaptionFile(t *testing.T, samples [][]byte) io.ReadSeeker {
+	buf := &writerseeker.WriterSeeker{}
+	w := NewWriter(buf)
+	ctx := Context{}
+	writeTestBox(t, w, &Ftyp{MajorBrand: [4]byte{'q', 't', ' ', ' '}}, ctx, nil)
+	offset, err := w.Seek(0, io.SeekCurrent)
+	require.NoError(t, err)
+	var data []byte
+	sizes := make([]uint32, 0, len(samples))
+	for _, sample := range samples {
+		data = append(data, sample...)
+		sizes = append(sizes, uint32(len(sample)))
+	}
+	writeTestBox(t, w, &Mdat{Data: data}, ctx, nil)
+
+	count := uint32(len(samples))
+	writeTestBox(t, w, &Moov{}, ctx, func() {
+		writeTestBox(t, w, &Mvhd{Timescale: 1000, DurationV0: 1000 * count, NextTrackID: 2}, ctx, nil)
+		writeTestBox(t, w, &Trak{}, ctx, func() {
+			writeTestBox(t, w, &Tkhd{TrackID: 1, DurationV0: 1000 * count}, ctx, nil)
+			writeTestBox(t, w, &Mdia{}, ctx, func() {
+				writeTestBox(t, w, &Mdhd{Timescale: 1000, DurationV0: 1000 * count}, ctx, nil)
+				writeTestBox(t, w, &Hdlr{HandlerType: [4]byte{'c', 'l', 'c', 'p'}}, ctx, nil)
+				writeTestBox(t, w, &Minf{}, ctx, func() {
+					writeTestBox(t, w, &Stbl{}, ctx, func() {
+						writeTestBox(t, w, &Stsd{EntryCount: 1}, ctx, func() {
+							writeTestBox(t, w, &ClosedCaptionSubtitleSampleEntry{
+								SampleEntry: SampleEntry{AnyTypeBox: AnyTypeBox{Type: BoxTypeC608()}, DataReferenceIndex: 1},
+							}, ctx, nil)
+						})
+						writeTestBox(t, w, &Stts{EntryCount: 1, Entries: []SttsEntry{{SampleCount: count, SampleDelta: 1000}}}, ctx, nil)
+						writeTestBox(t, w, &Stsc{EntryCount: 1, Entries: []StscEntry{{FirstChunk: 1, SamplesPerChunk: count, SampleDescriptionIndex: 1}}}, ctx, nil)
+						writeTestBox(t, w, &Stsz{SampleCount: count, EntrySize: sizes}, ctx, nil)
+						writeTestBox(t, w, &Stco{EntryCount: 1, ChunkOffset: []uint32{uint32(offset) + 8}}, ctx, nil)
+					})
+				})
+			})
+		})
+	})
+	data, err = io.ReadAll(buf.BytesReader())
+	require.NoError(t, err)
+	return bytes.NewReader(data)
+}
+
+// buildTestCaptionSample returns a sample which has the boxes.
+func buildTestCaptionSample(t *testing.T, boxes ...IBox) []byte {
+	buf := &writerseeker.WriterSeeker{}
+	w := NewWriter(buf)
+	for _, box := range boxes {
+		writeTestBox(t, w, box, Context{}, nil)
+	}
+	data, err := io.ReadAll(buf.BytesReader())
+	require.NoError(t, err)
+	return data
+}
+
+func TestExtractCaptions(t *testing.T) {
+	var cc1 []byte
+	for _, pair := range append([][2]byte{{0x14, 0x20}, {0x14, 0x20}, {0x14, 0x60}}, cea608Text("CAPTION")...) {
+		cc1 = append(cc1, pair[:]...)
+	}
+	cc3 := []byte{0x15, 0x20, 0x15, 0x60, 'F', 'I', 'E', 'L', 'D', '2', 0x15, 0x2f}
+	dtvcc := dtvccPackets(0, 1, []byte{0x98, 0x38, 0x00, 0x00, 0x00, 0x1f, 0x00, 'D', 'T', 'V', 0x03})
+	var triples [][3]byte
+	for _, p := range dtvcc {
+		triples = append(triples, [3]byte{0xfc | p.ccType, p.data[0], p.data[1]})
+	}
+
+	r := buildTestCaptionFile(t, [][]byte{
+		buildTestCaptionSample(t, &Cdat{Data: cc1}),
+		buildTestCaptionSample(t, &Cdat{Data: []byte{0x14, 0x2f, 0x14, 0x2f}}, &Cdt2{Data: cc3}),
+		buildTestCaptionSample(t, &Ccdp{Data: buildTestCDP(triples...)}),
+		buildTestCaptionSample(t, &Cdat{Data: []byte{0x14, 0x2c}}),
+	})
+
+	channels, err := CaptionChannels(r, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []CaptionChannel{CaptionCC1, CaptionCC3, CaptionService(1)}, channels)
+
+	cues, err := ExtractCaptions(r, 1, CaptionCC1)
+	require.NoError(t, err)
+	require.Len(t, cues, 1)
+	assert.Equal(t, &WebVTTCue{Start: time.Second, End: 3 * time.Second, Payload: "CAPTION"}, cues[0])
+
+	cues, err = ExtractCaptions(r, 0, CaptionCC3)
+	require.NoError(t, err)
+	require.Len(t, cues, 1)
+	assert.Equal(t, &WebVTTCue{Start: time.Second, End: 4 * time.Second, Payload: "FIELD2"}, cues[0])
+
+	cues, err = ExtractCaptions(r, 0, CaptionService(1))
+	require.NoError(t, err)
+	require.Len(t, cues, 1)
+	assert.Equal(t, &WebVTTCue{Start: 2 * time.Second, End: 4 * time.Second, Payload: "DTV"}, cues[0])
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, WriteSRT(buf, cues))
+	assert.Equal(t, "1\n00:00:02,000 --> 00:00:04,000\nDTV\n\n", buf.String())
+
+	_, err = ExtractCaptions(r, 2, CaptionCC1)
+	assert.Error(t, err)
+}
+
+func TestParseCaptionChannel(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		channel CaptionChannel
+	}{
+		{name: "CC1", channel: CaptionCC1},
+		{name: "cc4", channel: CaptionCC4},
+		{name: "SERVICE1", channel: CaptionService(1)},
+		{name: "service63", channel: CaptionService(63)},
+	} {
+		channel, err := ParseCaptionChannel(tc.name)
+		require.NoError(t, err)
+		assert.Equal(t, tc.channel, channel)
+	}
+	assert.Equal(t, "CC2", CaptionCC2.String())
+	assert.Equal(t, "SERVICE2", CaptionService(2).String())
+	assert.True(t, CaptionService(2).IsCEA708())
+	assert.False(t, CaptionCC2.IsCEA708())
+	for _, name := range []string{"CC0", "CC5", "SERVICE0", "SERVICE64", "CH1", ""} {
+		_, err := ParseCaptionChannel(name)
+		assert.Error(t, err, name)
+	}
+}
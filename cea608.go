@@ -0,0 +1,269 @@
+package mp4
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	cea608Rows    = 15
+	cea608Columns = 32
+)
+
+type cea608Mode int
+
+const (
+	cea608ModePopOn cea608Mode = iota
+	cea608ModeRollUp
+	cea608ModePaintOn
+	cea608ModeText
+)
+
+// cea608BasicChars maps the basic characters which differ from ASCII.
+var cea608BasicChars = map[byte]rune{
+	0x2a: 'á', 0x5c: 'é', 0x5e: 'í', 0x5f: 'ó', 0x60: 'ú',
+	0x7b: 'ç', 0x7c: '÷', 0x7d: 'Ñ', 0x7e: 'ñ', 0x7f: '█',
+}
+
+var (
+	// cea608SpecialChars are the characters of 0x30 to 0x3f following 0x11 (0x19).
+	cea608SpecialChars = []rune("®°½¿™¢£♪à èâêîôû")
+	// cea608ExtendedChars are the characters of 0x20 to 0x3f following 0x12 (0x1a) and 0x13 (0x1b).
+	cea608ExtendedChars = [2][]rune{
+		[]rune("ÁÉÓÚÜü‘¡*'—©℠•“”ÀÂÇÈÊËëÎÏïÔÙùÛ«»"),
+		[]rune("ÃãÍÌìÒòÕõ{}\\^_|~ÄäÖöß¥¤│ÅåØø┌┐└┘"),
+	}
+	// cea608PACRows maps the first byte of preamble address codes to 0-origin rows.
+	cea608PACRows = map[byte]int{0x11: 0, 0x12: 2, 0x15: 4, 0x16: 6, 0x17: 8, 0x10: 10, 0x13: 11, 0x14: 13}
+)
+
+type cea608Memory [cea608Rows][cea608Columns]rune
+
+func (m *cea608Memory) text() string {
+	var lines []string
+	for _, row := range m {
+		line := strings.TrimSpace(strings.Map(func(r rune) rune {
+			if r == 0 {
+				return ' '
+			}
+			return r
+		}, string(row[:])))
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// cea608Decoder decodes a data channel (CC1 to CC4) of CEA-608 into cues.
+type cea608Decoder struct {
+	field   uint8
+	channel int
+	// current is the data channel (1 or 2) of the field which is selected by the last control code.
+	current int
+
+	mode        cea608Mode
+	rollUpRows  int
+	displayed   cea608Memory
+	undisplayed cea608Memory
+	row, col    int
+
+	// lastControl is the last control code, which is ignored when it is transmitted twice.
+	lastControl [2]byte
+
+	cues captionCueBuilder
+}
+
+func newCEA608Decoder(channel CaptionChannel) *cea608Decoder {
+	d := &cea608Decoder{
+		field:   ccTypeNTSCField1,
+		channel: int(channel-CaptionCC1)%2 + 1,
+		row:     cea608Rows - 1,
+	}
+	if channel == CaptionCC3 || channel == CaptionCC4 {
+		d.field = ccTypeNTSCField2
+	}
+	return d
+}
+
+func (d *cea608Decoder) decode(p ccPacket) {
+	if p.ccType != d.field {
+		return
+	}
+	b1, b2 := p.data[0]&0x7f, p.data[1]&0x7f // odd parity bits
+	if b1 == 0 && b2 == 0 {
+		return
+	}
+	if b1 < 0x10 {
+		// extended data services
+		d.lastControl = [2]byte{}
+		return
+	}
+	if b1 >= 0x20 {
+		d.lastControl = [2]byte{}
+		if d.current != d.channel {
+			return
+		}
+		d.writeChar(basicCEA608Char(b1))
+		if b2 >= 0x20 {
+			d.writeChar(basicCEA608Char(b2))
+		}
+		return
+	}
+
+	// control codes are transmitted twice for redundancy
+	if [2]byte{b1, b2} == d.lastControl {
+		d.lastControl = [2]byte{}
+		return
+	}
+	d.lastControl = [2]byte{b1, b2}
+	d.current = 1
+	if b1&0x08 != 0 {
+		d.current = 2
+	}
+	if d.current != d.channel {
+		return
+	}
+	d.control(p.time, b1&^0x08, b2)
+}
+
+func basicCEA608Char(b byte) rune {
+	if r, ok := cea608BasicChars[b]; ok {
+		return r
+	}
+	return rune(b)
+}
+
+func (d *cea608Decoder) control(t time.Duration, b1, b2 byte) {
+	switch {
+	case b1 == 0x11 && b2 >= 0x20 && b2 <= 0x2f:
+		// mid-row code is displayed as a space
+		d.writeChar(' ')
+	case b1 == 0x11 && b2 >= 0x30 && b2 <= 0x3f:
+		d.writeChar(cea608SpecialChars[b2-0x30])
+	case (b1 == 0x12 || b1 == 0x13) && b2 >= 0x20 && b2 <= 0x3f:
+		// extended character replaces the preceding standard character
+		d.backspace()
+		d.writeChar(cea608ExtendedChars[b1-0x12][b2-0x20])
+	case (b1 == 0x14 || b1 == 0x15) && b2 >= 0x20 && b2 <= 0x2f:
+		d.miscControl(t, b2)
+	case b1 == 0x17 && b2 >= 0x21 && b2 <= 0x23:
+		// tab offset
+		d.col += int(b2 - 0x20)
+		if d.col >= cea608Columns {
+			d.col = cea608Columns - 1
+		}
+	case b2 >= 0x40 && b2 <= 0x7f:
+		d.preambleAddress(b1, b2)
+	}
+	if d.mode == cea608ModePaintOn {
+		d.cues.update(t, d.displayed.text())
+	}
+}
+
+func (d *cea608Decoder) miscControl(t time.Duration, b2 byte) {
+	switch b2 {
+	case 0x20: // RCL: resume caption loading
+		d.mode = cea608ModePopOn
+	case 0x21: // BS: backspace
+		d.backspace()
+	case 0x24: // DER: delete to end of row
+		memory := d.memory()
+		for col := d.col; col < cea608Columns; col++ {
+			memory[d.row][col] = 0
+		}
+	case 0x25, 0x26, 0x27: // RU2, RU3, RU4: roll-up captions
+		if d.mode != cea608ModeRollUp {
+			d.displayed = cea608Memory{}
+			d.undisplayed = cea608Memory{}
+			d.row, d.col = cea608Rows-1, 0
+			d.cues.update(t, "")
+		}
+		d.mode = cea608ModeRollUp
+		d.rollUpRows = int(b2-0x25) + 2
+	case 0x29: // RDC: resume direct captioning
+		d.mode = cea608ModePaintOn
+	case 0x2a, 0x2b: // TR, RTD: text restart, resume text display
+		d.mode = cea608ModeText
+	case 0x2c: // EDM: erase displayed memory
+		d.displayed = cea608Memory{}
+		d.cues.update(t, "")
+	case 0x2d: // CR: carriage return
+		if d.mode == cea608ModeRollUp {
+			d.rollUp()
+			d.cues.update(t, d.displayed.text())
+		}
+	case 0x2e: // ENM: erase non-displayed memory
+		d.undisplayed = cea608Memory{}
+	case 0x2f: // EOC: end of caption
+		d.displayed, d.undisplayed = d.undisplayed, d.displayed
+		d.mode = cea608ModePopOn
+		d.cues.update(t, d.displayed.text())
+	}
+}
+
+func (d *cea608Decoder) preambleAddress(b1, b2 byte) {
+	row, ok := cea608PACRows[b1]
+	if !ok || (b1 == 0x10 && b2 >= 0x60) {
+		return
+	}
+	if b1 != 0x10 && b2&0x20 != 0 {
+		row++
+	}
+	if d.mode == cea608ModeRollUp && row != d.row {
+		// the roll-up window moves to the new base row
+		var moved cea608Memory
+		for r := range moved {
+			if src := r - row + d.row; src >= 0 && src < cea608Rows {
+				moved[r] = d.displayed[src]
+			}
+		}
+		d.displayed = moved
+	}
+	d.row = row
+	d.col = 0
+	if b2&0x10 != 0 {
+		d.col = (int(b2&0x0e) >> 1) * 4
+	}
+}
+
+// memory returns the memory which characters are written to.
+func (d *cea608Decoder) memory() *cea608Memory {
+	if d.mode == cea608ModePopOn {
+		return &d.undisplayed
+	}
+	return &d.displayed
+}
+
+func (d *cea608Decoder) writeChar(r rune) {
+	if d.mode == cea608ModeText {
+		return
+	}
+	d.memory()[d.row][d.col] = r
+	if d.col < cea608Columns-1 {
+		d.col++
+	}
+}
+
+func (d *cea608Decoder) backspace() {
+	if d.col > 0 {
+		d.col--
+		d.memory()[d.row][d.col] = 0
+	}
+}
+
+func (d *cea608Decoder) rollUp() {
+	top := d.row - d.rollUpRows + 1
+	if top < 0 {
+		top = 0
+	}
+	for row := 0; row < d.row; row++ {
+		if row < top {
+			d.displayed[row] = [cea608Columns]rune{}
+		} else {
+			d.displayed[row] = d.displayed[row+1]
+		}
+	}
+	d.displayed[d.row] = [cea608Columns]rune{}
+	d.col = 0
+}
@@ -0,0 +1,371 @@
+package mp4
+
+import (
+	"strings"
+	"time"
+)
+
+const cea708Windows = 8
+
+// cea708G2Chars maps the characters of the G2 code set which are supported.
+var cea708G2Chars = map[byte]rune{
+	0x20: ' ', 0x21: ' ', 0x25: '…', 0x2a: 'Š', 0x2c: 'Œ', 0x30: '█',
+	0x31: '‘', 0x32: '’', 0x33: '“', 0x34: '”', 0x35: '•', 0x39: '™',
+	0x3a: 'š', 0x3c: 'œ', 0x3d: '℠', 0x3f: 'Ÿ',
+	0x76: '⅛', 0x77: '⅜', 0x78: '⅝', 0x79: '⅞', 0x7a: '│',
+	0x7b: '┐', 0x7c: '└', 0x7d: '─', 0x7e: '┘', 0x7f: '┌',
+}
+
+// cea708Window is a caption window of CEA-708.
+type cea708Window struct {
+	visible  bool
+	rowCount int
+	colCount int
+	rows     [][]rune
+	row, col int
+}
+
+func (w *cea708Window) text() string {
+	var lines []string
+	for _, row := range w.rows {
+		if line := strings.TrimSpace(string(row)); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (w *cea708Window) clear() {
+	w.rows = make([][]rune, w.rowCount)
+	w.row, w.col = 0, 0
+}
+
+func (w *cea708Window) writeChar(r rune) {
+	if w.col >= w.colCount {
+		return
+	}
+	row := w.rows[w.row]
+	for len(row) <= w.col {
+		row = append(row, ' ')
+	}
+	row[w.col] = r
+	w.rows[w.row] = row
+	w.col++
+}
+
+func (w *cea708Window) carriageReturn() {
+	w.col = 0
+	if w.row+1 < w.rowCount {
+		w.row++
+		return
+	}
+	// the window scrolls up
+	copy(w.rows, w.rows[1:])
+	w.rows[w.rowCount-1] = nil
+}
+
+// cea708Decoder decodes a caption service of CEA-708 into cues.
+type cea708Decoder struct {
+	service int
+	packet  []byte
+	windows [cea708Windows]*cea708Window
+	current int
+
+	// onServiceBlock is called for each service block instead of decoding it when it is not nil.
+	onServiceBlock func(service int, block []byte)
+
+	cues captionCueBuilder
+	// time is the time of the DTVCC packet being decoded.
+	time time.Duration
+}
+
+func newCEA708Decoder(service int) *cea708Decoder {
+	return &cea708Decoder{service: service}
+}
+
+func (d *cea708Decoder) decode(p ccPacket) {
+	switch p.ccType {
+	case ccTypeDTVCCStart:
+		d.flushPacket()
+		d.time = p.time
+		d.packet = append(d.packet[:0], p.data[0], p.data[1])
+	case ccTypeDTVCCData:
+		if len(d.packet) != 0 {
+			d.packet = append(d.packet, p.data[0], p.data[1])
+		}
+	default:
+		return
+	}
+	if size := d.packetSize(); size != 0 && len(d.packet) >= size {
+		d.flushPacket()
+	}
+}
+
+// packetSize returns the size of the DTVCC packet including its header.
+func (d *cea708Decoder) packetSize() int {
+	if len(d.packet) == 0 {
+		return 0
+	}
+	if code := int(d.packet[0] & 0x3f); code != 0 {
+		return code * 2
+	}
+	return 128
+}
+
+// flushPacket decodes the service blocks in the DTVCC packet.
+func (d *cea708Decoder) flushPacket() {
+	if len(d.packet) == 0 {
+		return
+	}
+	packet := d.packet
+	if size := d.packetSize(); len(packet) > size {
+		packet = packet[:size]
+	}
+	d.packet = d.packet[:0]
+
+	for i := 1; i < len(packet); {
+		service := int(packet[i] >> 5)
+		size := int(packet[i] & 0x1f)
+		i++
+		if service == 7 { // extended service number
+			if i >= len(packet) {
+				return
+			}
+			service = int(packet[i] & 0x3f)
+			i++
+		}
+		if service == 0 || i+size > len(packet) {
+			return
+		}
+		block := packet[i : i+size]
+		i += size
+		if d.onServiceBlock != nil {
+			d.onServiceBlock(service, block)
+		} else if service == d.service {
+			d.decodeBlock(block)
+		}
+	}
+}
+
+func (d *cea708Decoder) decodeBlock(block []byte) {
+	for i := 0; i < len(block); {
+		c := block[i]
+		i++
+		switch {
+		case c == 0x10: // EXT1
+			if i >= len(block) {
+				return
+			}
+			i = d.extended(block, i)
+		case c < 0x20:
+			i = d.c0(block, i, c)
+		case c < 0x80:
+			if c == 0x7f {
+				d.writeChar('♪')
+			} else {
+				d.writeChar(rune(c))
+			}
+		case c < 0xa0:
+			i = d.c1(block, i, c)
+		default:
+			// G1: ISO 8859-1
+			d.writeChar(rune(c))
+		}
+	}
+	// the text written to the visible windows is displayed immediately
+	d.updateDisplay()
+}
+
+// c0 executes the C0 control code, and returns the position of the next code.
+func (d *cea708Decoder) c0(block []byte, i int, c byte) int {
+	w := d.window()
+	switch {
+	case c == 0x03: // ETX
+		d.updateDisplay()
+	case c == 0x08: // BS
+		if w != nil && w.col > 0 {
+			w.col--
+			if row := w.rows[w.row]; w.col < len(row) {
+				w.rows[w.row] = row[:w.col]
+			}
+		}
+	case c == 0x0c: // FF
+		if w != nil {
+			w.clear()
+			d.updateVisibleWindow(w)
+		}
+	case c == 0x0d: // CR
+		if w != nil {
+			w.carriageReturn()
+			d.updateVisibleWindow(w)
+		}
+	case c == 0x0e: // HCR
+		if w != nil {
+			w.rows[w.row] = nil
+			w.col = 0
+		}
+	case c >= 0x11 && c <= 0x17:
+		return i + 1
+	case c >= 0x18:
+		return i + 2
+	}
+	return i
+}
+
+// c1 executes the C1 caption command, and returns the position of the next code.
+func (d *cea708Decoder) c1(block []byte, i int, c byte) int {
+	param := func(n int) []byte {
+		if i+n > len(block) {
+			return nil
+		}
+		return block[i : i+n]
+	}
+	eachWindow := func(bitmap byte, f func(id int)) {
+		for id := 0; id < cea708Windows; id++ {
+			if bitmap&(1<<id) != 0 {
+				f(id)
+			}
+		}
+	}
+
+	switch {
+	case c <= 0x87: // CWx: set current window
+		d.current = int(c - 0x80)
+		return i
+	case c <= 0x8c: // CLW, DSW, HDW, TGW, DLW
+		p := param(1)
+		if p == nil {
+			return len(block)
+		}
+		eachWindow(p[0], func(id int) {
+			w := d.windows[id]
+			if w == nil {
+				return
+			}
+			switch c {
+			case 0x88:
+				w.clear()
+			case 0x89:
+				w.visible = true
+			case 0x8a:
+				w.visible = false
+			case 0x8b:
+				w.visible = !w.visible
+			case 0x8c:
+				d.windows[id] = nil
+			}
+		})
+		d.updateDisplay()
+		return i + 1
+	case c == 0x8d || c == 0x8e: // DLY, DLC
+		if c == 0x8d {
+			return i + 1
+		}
+		return i
+	case c == 0x8f: // RST
+		d.windows = [cea708Windows]*cea708Window{}
+		d.updateDisplay()
+		return i
+	case c == 0x90: // SPA
+		return i + 2
+	case c == 0x91: // SPC
+		return i + 3
+	case c == 0x92: // SPL: set pen location
+		p := param(2)
+		if p == nil {
+			return len(block)
+		}
+		if w := d.window(); w != nil {
+			w.row = int(p[0] & 0x0f)
+			if w.row >= w.rowCount {
+				w.row = w.rowCount - 1
+			}
+			w.col = int(p[1] & 0x3f)
+		}
+		return i + 2
+	case c == 0x97: // SWA
+		return i + 4
+	case c >= 0x98: // DFx: define window
+		p := param(6)
+		if p == nil {
+			return len(block)
+		}
+		id := int(c - 0x98)
+		w := d.windows[id]
+		rowCount := int(p[3]&0x0f) + 1
+		colCount := int(p[4]&0x3f) + 1
+		if w == nil || w.rowCount != rowCount {
+			w = &cea708Window{rowCount: rowCount}
+			w.clear()
+			d.windows[id] = w
+		}
+		w.colCount = colCount
+		w.visible = p[0]&0x20 != 0
+		d.current = id
+		d.updateDisplay()
+		return i + 6
+	}
+	return i
+}
+
+// extended executes the code following EXT1, and returns the position of the next code.
+func (d *cea708Decoder) extended(block []byte, i int) int {
+	c := block[i]
+	i++
+	switch {
+	case c < 0x08: // C2
+		return i
+	case c < 0x10:
+		return i + 1
+	case c < 0x18:
+		return i + 2
+	case c < 0x20:
+		return i + 3
+	case c < 0x80: // G2
+		if r, ok := cea708G2Chars[c]; ok {
+			d.writeChar(r)
+		}
+		return i
+	case c < 0x88: // C3
+		return i + 4
+	case c < 0x90:
+		return i + 5
+	case c < 0xa0: // variable length
+		if i >= len(block) {
+			return len(block)
+		}
+		return i + 1 + int(block[i]&0x3f)
+	default: // G3
+		d.writeChar('_')
+		return i
+	}
+}
+
+func (d *cea708Decoder) window() *cea708Window {
+	return d.windows[d.current]
+}
+
+func (d *cea708Decoder) writeChar(r rune) {
+	if w := d.window(); w != nil {
+		w.writeChar(r)
+	}
+}
+
+func (d *cea708Decoder) updateVisibleWindow(w *cea708Window) {
+	if w.visible {
+		d.updateDisplay()
+	}
+}
+
+func (d *cea708Decoder) updateDisplay() {
+	var texts []string
+	for _, w := range d.windows {
+		if w == nil || !w.visible {
+			continue
+		}
+		if text := w.text(); text != "" {
+			texts = append(texts, text)
+		}
+	}
+	d.cues.update(d.time, strings.Join(texts, "\n"))
+}
@@ -0,0 +1,126 @@
+package mp4
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"fmt"
+)
+
+// protection scheme types defined at ISO/IEC 23001-7
+func SchemeTypeCENC() [4]byte { return [4]byte{'c', 'e', 'n', 'c'} }
+func SchemeTypeCENS() [4]byte { return [4]byte{'c', 'e', 'n', 's'} }
+func SchemeTypeCBC1() [4]byte { return [4]byte{'c', 'b', 'c', '1'} }
+func SchemeTypeCBCS() [4]byte { return [4]byte{'c', 'b', 'c', 's'} }
+
+const cencBlockSize = aes.BlockSize
+
+// cencCipher encrypts or decrypts samples in accordance with a protection scheme.
+type cencCipher struct {
+	scheme         [4]byte
+	block          cipher.Block
+	cryptByteBlock int
+	skipByteBlock  int
+	newCBC         func(b cipher.Block, iv []byte) cipher.BlockMode
+}
+
+func newCencCipher(scheme [4]byte, key []byte, cryptByteBlock, skipByteBlock uint8, decrypt bool) (*cencCipher, error) {
+	switch scheme {
+	case SchemeTypeCENC(), SchemeTypeCENS(), SchemeTypeCBC1(), SchemeTypeCBCS():
+	default:
+		return nil, fmt.Errorf("unsupported protection scheme: %s", string(scheme[:]))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	c := &cencCipher{
+		scheme:         scheme,
+		block:          block,
+		cryptByteBlock: int(cryptByteBlock),
+		skipByteBlock:  int(skipByteBlock),
+		newCBC:         cipher.NewCBCEncrypter,
+	}
+	if decrypt {
+		c.newCBC = cipher.NewCBCDecrypter
+	}
+	return c, nil
+}
+
+// cryptSample encrypts or decrypts the sample data in place.
+// When subsamples is empty, whole of the sample is protected.
+func (c *cencCipher) cryptSample(data []byte, iv []byte, subsamples []SubsampleEntry) error {
+	if len(iv) != 8 && len(iv) != 16 {
+		return fmt.Errorf("invalid IV size: %d", len(iv))
+	}
+	iv16 := make([]byte, cencBlockSize)
+	copy(iv16, iv)
+
+	ranges := make([][]byte, 0, len(subsamples)+1)
+	if len(subsamples) == 0 {
+		ranges = append(ranges, data)
+	} else {
+		var offset int
+		for _, subsample := range subsamples {
+			offset += int(subsample.BytesOfClearData)
+			end := offset + int(subsample.BytesOfProtectedData)
+			if end > len(data) {
+				return errors.New("subsamples exceed sample size")
+			}
+			ranges = append(ranges, data[offset:end])
+			offset = end
+		}
+	}
+
+	switch c.scheme {
+	case SchemeTypeCENC():
+		stream := cipher.NewCTR(c.block, iv16)
+		for _, r := range ranges {
+			stream.XORKeyStream(r, r)
+		}
+	case SchemeTypeCENS():
+		stream := cipher.NewCTR(c.block, iv16)
+		for _, r := range ranges {
+			c.forEachCryptBlocks(r, func(b []byte) {
+				stream.XORKeyStream(b, b)
+			})
+		}
+	case SchemeTypeCBC1():
+		// the cipher block chaining continues across subsamples
+		mode := c.newCBC(c.block, iv16)
+		for _, r := range ranges {
+			n := len(r) / cencBlockSize * cencBlockSize
+			mode.CryptBlocks(r[:n], r[:n])
+		}
+	case SchemeTypeCBCS():
+		// the cipher block chaining is reset with the IV on each subsample
+		for _, r := range ranges {
+			mode := c.newCBC(c.block, iv16)
+			c.forEachCryptBlocks(r, func(b []byte) {
+				mode.CryptBlocks(b, b)
+			})
+		}
+	}
+	return nil
+}
+
+// forEachCryptBlocks calls f for each run of encrypted blocks according to the crypt/skip pattern.
+// A trailing partial block is always left in the clear.
+func (c *cencCipher) forEachCryptBlocks(data []byte, f func(b []byte)) {
+	n := len(data) / cencBlockSize * cencBlockSize
+	if c.cryptByteBlock == 0 && c.skipByteBlock == 0 {
+		if n != 0 {
+			f(data[:n])
+		}
+		return
+	}
+	crypt := c.cryptByteBlock * cencBlockSize
+	skip := c.skipByteBlock * cencBlockSize
+	for offset := 0; offset < n; offset += crypt + skip {
+		end := offset + crypt
+		if end > n {
+			end = n
+		}
+		f(data[offset:end])
+	}
+}
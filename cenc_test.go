@@ -0,0 +1,89 @@
+package mp4
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCencCipher(t *testing.T) {
+	key := []byte{
+		0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77,
+		0x88, 0x99, 0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff,
+	}
+	iv := []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}
+	plain := make([]byte, 500)
+	for i := range plain {
+		plain[i] = byte(i * 7)
+	}
+	subsamples := []SubsampleEntry{
+		{BytesOfClearData: 10, BytesOfProtectedData: 200},
+		{BytesOfClearData: 5, BytesOfProtectedData: 285},
+	}
+
+	testCases := []struct {
+		name       string
+		scheme     [4]byte
+		crypt      uint8
+		skip       uint8
+		subsamples []SubsampleEntry
+	}{
+		{name: "cenc full sample", scheme: SchemeTypeCENC()},
+		{name: "cenc subsamples", scheme: SchemeTypeCENC(), subsamples: subsamples},
+		{name: "cens", scheme: SchemeTypeCENS(), crypt: 1, skip: 9, subsamples: subsamples},
+		{name: "cbc1", scheme: SchemeTypeCBC1(), subsamples: subsamples},
+		{name: "cbcs", scheme: SchemeTypeCBCS(), crypt: 1, skip: 9, subsamples: subsamples},
+		{name: "cbcs without pattern", scheme: SchemeTypeCBCS()},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			enc, err := newCencCipher(tc.scheme, key, tc.crypt, tc.skip, false)
+			require.NoError(t, err)
+			dec, err := newCencCipher(tc.scheme, key, tc.crypt, tc.skip, true)
+			require.NoError(t, err)
+
+			data := append([]byte(nil), plain...)
+			require.NoError(t, enc.cryptSample(data, iv, tc.subsamples))
+			assert.NotEqual(t, plain, data)
+			if len(tc.subsamples) != 0 {
+				assert.Equal(t, plain[:10], data[:10])
+				assert.Equal(t, plain[210:215], data[210:215])
+			}
+			require.NoError(t, dec.cryptSample(data, iv, tc.subsamples))
+			assert.Equal(t, plain, data)
+		})
+	}
+
+	t.Run("cenc keystream continues across subsamples", func(t *testing.T) {
+		c, err := newCencCipher(SchemeTypeCENC(), key, 0, 0, true)
+		require.NoError(t, err)
+		data := append([]byte(nil), plain...)
+		require.NoError(t, c.cryptSample(data, iv, subsamples))
+
+		block, err := aes.NewCipher(key)
+		require.NoError(t, err)
+		protected := append(append([]byte(nil), plain[10:210]...), plain[215:500]...)
+		iv16 := make([]byte, 16)
+		copy(iv16, iv)
+		cipher.NewCTR(block, iv16).XORKeyStream(protected, protected)
+		assert.Equal(t, protected[:200], data[10:210])
+		assert.Equal(t, protected[200:], data[215:500])
+	})
+
+	t.Run("cbcs leaves skipped and partial blocks in the clear", func(t *testing.T) {
+		c, err := newCencCipher(SchemeTypeCBCS(), key, 1, 9, false)
+		require.NoError(t, err)
+		data := append([]byte(nil), plain[:180]...)
+		require.NoError(t, c.cryptSample(data, iv, nil))
+		assert.NotEqual(t, plain[:16], data[:16])
+		assert.Equal(t, plain[16:160], data[16:160])
+		assert.NotEqual(t, plain[160:176], data[160:176])
+		assert.Equal(t, plain[176:180], data[176:180])
+	})
+
+	_, err := newCencCipher([4]byte{'x', 'x', 'x', 'x'}, key, 0, 0, true)
+	assert.Error(t, err)
+}
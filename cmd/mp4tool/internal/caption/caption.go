@@ -0,0 +1,121 @@
+package caption
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Spidey120703/go-mp4"
+	"github.com/sunfish-shogi/bufseekio"
+)
+
+const (
+	blockSize        = 128 * 1024
+	blockHistorySize = 4
+)
+
+func Main(args []string) int {
+	flagSet := flag.NewFlagSet("caption", flag.ExitOnError)
+	trackID := flagSet.Uint("track", 0, "track ID (the first closed caption or video track by default)")
+	channel := flagSet.String("channel", "CC1", "caption channel (CC1-CC4) or CEA-708 service (SERVICE1-SERVICE63)")
+	list := flagSet.Bool("list", false, "list channels which have captions, and fail if there is none")
+	flagSet.Usage = func() {
+		println("USAGE: mp4tool caption [OPTIONS] INPUT.mp4 [OUTPUT]")
+		println("       mp4tool caption -list [OPTIONS] INPUT.mp4")
+		println()
+		println("OUTPUT FORMAT:")
+		println("  *.vtt      : WebVTT")
+		println("  otherwise  : SubRip (written to stdout if OUTPUT is omitted)")
+		println()
+		flagSet.PrintDefaults()
+	}
+	flagSet.Parse(args)
+
+	if len(flagSet.Args()) < 1 {
+		flagSet.Usage()
+		return 1
+	}
+
+	inputPath := flagSet.Args()[0]
+	var err error
+	if *list {
+		err = listChannels(inputPath, uint32(*trackID))
+	} else {
+		var outputPath string
+		if len(flagSet.Args()) >= 2 {
+			outputPath = flagSet.Args()[1]
+		}
+		err = extractCaptions(inputPath, outputPath, uint32(*trackID), *channel)
+	}
+	if err != nil {
+		fmt.Println("Error:", err)
+		return 1
+	}
+	return 0
+}
+
+func listChannels(inputPath string, trackID uint32) error {
+	input, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	r := bufseekio.NewReadSeeker(input, blockSize, blockHistorySize)
+	channels, err := mp4.CaptionChannels(r, trackID)
+	if err != nil {
+		return err
+	}
+	if len(channels) == 0 {
+		return errors.New("no captions found")
+	}
+	for _, channel := range channels {
+		fmt.Println(channel)
+	}
+	return nil
+}
+
+func extractCaptions(inputPath, outputPath string, trackID uint32, channelName string) error {
+	channel, err := mp4.ParseCaptionChannel(channelName)
+	if err != nil {
+		return err
+	}
+
+	input, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	r := bufseekio.NewReadSeeker(input, blockSize, blockHistorySize)
+	cues, err := mp4.ExtractCaptions(r, trackID, channel)
+	if err != nil {
+		return err
+	}
+
+	var output io.Writer = os.Stdout
+	if outputPath != "" {
+		f, err := os.Create(outputPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		output = f
+	}
+
+	w := bufio.NewWriter(output)
+	if strings.ToLower(filepath.Ext(outputPath)) == ".vtt" {
+		_, err = (&mp4.WebVTT{Cues: cues}).WriteTo(w)
+	} else {
+		err = mp4.WriteSRT(w, cues)
+	}
+	if err != nil {
+		return err
+	}
+	return w.Flush()
+}
@@ -0,0 +1,30 @@
+package caption
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCaption(t *testing.T) {
+	// sample.mp4 has no captions
+	assert.NotZero(t, Main([]string{"-list", "../../../../testdata/sample.mp4"}))
+
+	output := filepath.Join(t.TempDir(), "output.vtt")
+	require.Zero(t, Main([]string{"-channel", "CC3", "../../../../testdata/sample.mp4", output}))
+	data, err := os.ReadFile(output)
+	require.NoError(t, err)
+	assert.Equal(t, "WEBVTT\n\n", string(data))
+
+	output = filepath.Join(t.TempDir(), "output.srt")
+	require.Zero(t, Main([]string{"-channel", "SERVICE1", "../../../../testdata/sample.mp4", output}))
+	data, err = os.ReadFile(output)
+	require.NoError(t, err)
+	assert.Empty(t, data)
+
+	assert.NotZero(t, Main([]string{"-channel", "CC5", "../../../../testdata/sample.mp4", output}))
+	assert.NotZero(t, Main([]string{"-track", "2", "../../../../testdata/sample.mp4", output}))
+}
@@ -0,0 +1,88 @@
+package decrypt
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Spidey120703/go-mp4"
+	"github.com/sunfish-shogi/bufseekio"
+)
+
+const (
+	blockSize        = 128 * 1024
+	blockHistorySize = 4
+)
+
+type keyFlags map[[16]byte][]byte
+
+func (keys keyFlags) String() string {
+	pairs := make([]string, 0, len(keys))
+	for kid, key := range keys {
+		pairs = append(pairs, hex.EncodeToString(kid[:])+":"+hex.EncodeToString(key))
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (keys keyFlags) Set(value string) error {
+	pair := strings.SplitN(value, ":", 2)
+	if len(pair) != 2 {
+		return fmt.Errorf("invalid key format: %s", value)
+	}
+	kid, err := hex.DecodeString(strings.ReplaceAll(pair[0], "-", ""))
+	if err != nil || len(kid) != 16 {
+		return fmt.Errorf("invalid KID: %s", pair[0])
+	}
+	key, err := hex.DecodeString(pair[1])
+	if err != nil || len(key) != 16 {
+		return fmt.Errorf("invalid key: %s", pair[1])
+	}
+	var k [16]byte
+	copy(k[:], kid)
+	keys[k] = key
+	return nil
+}
+
+func Main(args []string) int {
+	keys := make(keyFlags)
+	flagSet := flag.NewFlagSet("decrypt", flag.ExitOnError)
+	flagSet.Var(keys, "key", "KID:KEY pair in hexadecimal (can be specified multiple times)")
+	flagSet.Usage = func() {
+		println("USAGE: mp4tool decrypt [OPTIONS] INPUT.mp4 OUTPUT.mp4")
+		flagSet.PrintDefaults()
+	}
+	flagSet.Parse(args)
+
+	if len(flagSet.Args()) < 2 {
+		flagSet.Usage()
+		return 1
+	}
+
+	inputPath := flagSet.Args()[0]
+	outputPath := flagSet.Args()[1]
+
+	if err := decrypt(inputPath, outputPath, keys); err != nil {
+		fmt.Println("Error:", err)
+		return 1
+	}
+	return 0
+}
+
+func decrypt(inputPath, outputPath string, keys keyFlags) error {
+	input, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	output, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+
+	r := bufseekio.NewReadSeeker(input, blockSize, blockHistorySize)
+	return mp4.Decrypt(r, output, keys)
+}
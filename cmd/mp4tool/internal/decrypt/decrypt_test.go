@@ -0,0 +1,49 @@
+package decrypt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Spidey120703/go-mp4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecrypt(t *testing.T) {
+	testCases := []struct {
+		name  string
+		file  string
+		wants mp4.BoxType
+	}{
+		{name: "sample_init.encv.mp4", file: "../../../../testdata/sample_init.encv.mp4", wants: mp4.BoxTypeAvc1()},
+		{name: "sample_init.enca.mp4", file: "../../../../testdata/sample_init.enca.mp4", wants: mp4.BoxTypeMp4a()},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			output := filepath.Join(t.TempDir(), "output.mp4")
+			require.Zero(t, Main([]string{
+				"-key", "0123456789abcdef0123456789abcdef:00112233445566778899aabbccddeeff",
+				tc.file, output,
+			}))
+
+			f, err := os.Open(output)
+			require.NoError(t, err)
+			defer f.Close()
+			bis, err := mp4.ExtractBoxes(f, nil, []mp4.BoxPath{
+				{mp4.BoxTypeMoov(), mp4.BoxTypeTrak(), mp4.BoxTypeMdia(), mp4.BoxTypeMinf(), mp4.BoxTypeStbl(), mp4.BoxTypeStsd(), tc.wants},
+				{mp4.BoxTypeMoov(), mp4.BoxTypePssh()},
+			})
+			require.NoError(t, err)
+			assert.Len(t, bis, 1)
+		})
+	}
+}
+
+func TestKeyFlags(t *testing.T) {
+	keys := make(keyFlags)
+	require.NoError(t, keys.Set("01234567-89ab-cdef-0123-456789abcdef:00112233445566778899aabbccddeeff"))
+	assert.Equal(t, "0123456789abcdef0123456789abcdef:00112233445566778899aabbccddeeff", keys.String())
+	assert.Error(t, keys.Set("0123456789abcdef0123456789abcdef"))
+	assert.Error(t, keys.Set("0123:00112233445566778899aabbccddeeff"))
+}
@@ -0,0 +1,121 @@
+package defrag
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/Spidey120703/go-mp4"
+	"github.com/sunfish-shogi/bufseekio"
+)
+
+const (
+	blockSize        = 128 * 1024
+	blockHistorySize = 4
+)
+
+func Main(args []string) int {
+	flagSet := flag.NewFlagSet("defrag", flag.ExitOnError)
+	fastStart := flagSet.Bool("faststart", false, "place moov box before mdat box")
+	chunkDuration := flagSet.Duration("chunk", time.Second, "maximum duration of interleaved chunks")
+	flagSet.Usage = func() {
+		println("USAGE: mp4tool defrag [OPTIONS] INPUT.mp4 [SEGMENT.m4s ...] OUTPUT.mp4")
+		flagSet.PrintDefaults()
+	}
+	flagSet.Parse(args)
+
+	if len(flagSet.Args()) < 2 {
+		flagSet.Usage()
+		return 1
+	}
+
+	inputPaths := flagSet.Args()[:len(flagSet.Args())-1]
+	outputPath := flagSet.Args()[len(flagSet.Args())-1]
+
+	config := &mp4.DefragmentConfig{
+		FastStart:     *fastStart,
+		ChunkDuration: *chunkDuration,
+	}
+	if err := defrag(inputPaths, outputPath, config); err != nil {
+		fmt.Println("Error:", err)
+		return 1
+	}
+	return 0
+}
+
+func defrag(inputPaths []string, outputPath string, config *mp4.DefragmentConfig) error {
+	input := &concatReadSeeker{}
+	for _, path := range inputPaths {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		stat, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		input.files = append(input.files, f)
+		input.sizes = append(input.sizes, stat.Size())
+	}
+
+	output, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+
+	r := bufseekio.NewReadSeeker(input, blockSize, blockHistorySize)
+	return mp4.Defragment(r, output, config)
+}
+
+// concatReadSeeker reads the init segment and the media segments as a single stream.
+type concatReadSeeker struct {
+	files  []io.ReadSeeker
+	sizes  []int64
+	offset int64
+}
+
+func (c *concatReadSeeker) Read(p []byte) (int, error) {
+	base := int64(0)
+	for i, f := range c.files {
+		if c.offset < base+c.sizes[i] {
+			if _, err := f.Seek(c.offset-base, io.SeekStart); err != nil {
+				return 0, err
+			}
+			if rest := base + c.sizes[i] - c.offset; int64(len(p)) > rest {
+				p = p[:rest]
+			}
+			n, err := f.Read(p)
+			c.offset += int64(n)
+			if err == io.EOF && n != 0 {
+				err = nil
+			}
+			return n, err
+		}
+		base += c.sizes[i]
+	}
+	return 0, io.EOF
+}
+
+func (c *concatReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += c.offset
+	case io.SeekEnd:
+		for _, size := range c.sizes {
+			offset += size
+		}
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("negative offset")
+	}
+	c.offset = offset
+	return offset, nil
+}
@@ -0,0 +1,59 @@
+package defrag
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Spidey120703/go-mp4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefrag(t *testing.T) {
+	dir := t.TempDir()
+	input := "../../../../testdata/sample_fragmented.mp4"
+
+	// split the input into an init segment and a media segment
+	data, err := os.ReadFile(input)
+	require.NoError(t, err)
+	f, err := os.Open(input)
+	require.NoError(t, err)
+	defer f.Close()
+	bis, err := mp4.ExtractBox(f, nil, mp4.BoxPath{mp4.BoxTypeMoov()})
+	require.NoError(t, err)
+	require.Len(t, bis, 1)
+	initEnd := bis[0].Offset + bis[0].Size
+	initPath := filepath.Join(dir, "init.mp4")
+	segmentPath := filepath.Join(dir, "segment.m4s")
+	require.NoError(t, os.WriteFile(initPath, data[:initEnd], 0644))
+	require.NoError(t, os.WriteFile(segmentPath, data[initEnd:], 0644))
+
+	testCases := []struct {
+		name   string
+		args   []string
+		inputs []string
+	}{
+		{name: "single file", inputs: []string{input}},
+		{name: "segments with faststart", args: []string{"-faststart"}, inputs: []string{initPath, segmentPath}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			output := filepath.Join(dir, "output.mp4")
+			args := append(append(tc.args, tc.inputs...), output)
+			require.Zero(t, Main(args))
+
+			f, err := os.Open(output)
+			require.NoError(t, err)
+			defer f.Close()
+			bis, err := mp4.ExtractBoxes(f, nil, []mp4.BoxPath{{mp4.BoxTypeMdat()}, {mp4.BoxTypeMoof()}})
+			require.NoError(t, err)
+			assert.Len(t, bis, 1)
+			for _, trackID := range []uint32{1, 2} {
+				sr, err := mp4.NewSampleReader(f, trackID)
+				require.NoError(t, err)
+				assert.NotEmpty(t, sr.Samples())
+			}
+		})
+	}
+}
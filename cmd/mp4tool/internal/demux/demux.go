@@ -0,0 +1,83 @@
+package demux
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Spidey120703/go-mp4"
+	"github.com/sunfish-shogi/bufseekio"
+)
+
+const (
+	blockSize        = 128 * 1024
+	blockHistorySize = 4
+)
+
+func Main(args []string) int {
+	flagSet := flag.NewFlagSet("demux", flag.ExitOnError)
+	trackID := flagSet.Uint("track", 0, "track ID (the first track of the codec by default)")
+	flagSet.Usage = func() {
+		println("USAGE: mp4tool demux [OPTIONS] INPUT.mp4 OUTPUT")
+		println()
+		println("OUTPUT FORMAT:")
+		println("  *.aac, *.adts  : AAC in ADTS")
+		println("  *.opus, *.ogg  : Opus in Ogg")
+		println("  *.flac         : FLAC")
+		println("  *.vtt          : WebVTT")
+		println("  otherwise      : H.264/H.265 in Annex B byte stream")
+		println()
+		flagSet.PrintDefaults()
+	}
+	flagSet.Parse(args)
+
+	if len(flagSet.Args()) < 2 {
+		flagSet.Usage()
+		return 1
+	}
+
+	inputPath := flagSet.Args()[0]
+	outputPath := flagSet.Args()[1]
+
+	if err := demux(inputPath, outputPath, uint32(*trackID)); err != nil {
+		fmt.Println("Error:", err)
+		return 1
+	}
+	return 0
+}
+
+func demux(inputPath, outputPath string, trackID uint32) error {
+	input, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	output, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+
+	r := bufseekio.NewReadSeeker(input, blockSize, blockHistorySize)
+	w := bufio.NewWriter(output)
+	switch strings.ToLower(filepath.Ext(outputPath)) {
+	case ".aac", ".adts":
+		err = mp4.ExtractADTS(r, w, trackID)
+	case ".opus", ".ogg":
+		err = mp4.ExtractOggOpus(r, w, trackID)
+	case ".flac":
+		err = mp4.ExtractFLAC(r, w, trackID)
+	case ".vtt":
+		err = mp4.ExtractWebVTT(r, w, trackID)
+	default:
+		err = mp4.ExtractAnnexB(r, w, trackID)
+	}
+	if err != nil {
+		return err
+	}
+	return w.Flush()
+}
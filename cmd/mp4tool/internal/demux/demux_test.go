@@ -0,0 +1,52 @@
+package demux
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Spidey120703/go-mp4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDemux(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "output.h264")
+	require.Zero(t, Main([]string{"../../../../testdata/sample.mp4", output}))
+
+	data, err := os.ReadFile(output)
+	require.NoError(t, err)
+	require.Greater(t, len(data), 5)
+	assert.Equal(t, []byte{0x00, 0x00, 0x00, 0x01, 0x67}, data[:5])
+
+	assert.NotZero(t, Main([]string{"-track", "2", "../../../../testdata/sample.mp4", output}))
+
+	output = filepath.Join(t.TempDir(), "output.aac")
+	require.Zero(t, Main([]string{"../../../../testdata/sample.mp4", output}))
+	data, err = os.ReadFile(output)
+	require.NoError(t, err)
+	require.Greater(t, len(data), 2)
+	assert.Equal(t, []byte{0xff, 0xf1}, data[:2])
+
+	output = filepath.Join(t.TempDir(), "output.opus")
+	assert.NotZero(t, Main([]string{"../../../../testdata/sample.mp4", output}))
+
+	output = filepath.Join(t.TempDir(), "output.flac")
+	assert.NotZero(t, Main([]string{"../../../../testdata/sample.mp4", output}))
+
+	input := filepath.Join(t.TempDir(), "subtitle.mp4")
+	f, err := os.Create(input)
+	require.NoError(t, err)
+	m := mp4.NewMuxer(f, nil)
+	track, err := m.AddWebVTTTrack(1000, "")
+	require.NoError(t, err)
+	require.NoError(t, track.WriteWebVTT([]*mp4.WebVTTCue{{Start: time.Second, End: 2 * time.Second, Payload: "Hello"}}))
+	require.NoError(t, m.Close())
+	require.NoError(t, f.Close())
+	output = filepath.Join(t.TempDir(), "output.vtt")
+	require.Zero(t, Main([]string{input, output}))
+	data, err = os.ReadFile(output)
+	require.NoError(t, err)
+	assert.Equal(t, "WEBVTT\n\n00:00:01.000 --> 00:00:02.000\nHello\n\n", string(data))
+}
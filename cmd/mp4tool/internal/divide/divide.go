@@ -55,6 +55,7 @@ type track struct {
 	id          uint32
 	trackType   trackType
 	timescale   uint32
+	codec       string
 	bandwidth   uint64
 	height      uint16
 	width       uint16
@@ -71,6 +72,16 @@ func divide(inputFilePath, outputDir string) error {
 	}
 	defer inputFile.Close()
 
+	// get codecs parameters of tracks
+	probeInfo, err := mp4.Probe(inputFile)
+	if err != nil {
+		return err
+	}
+	codecs := make(map[uint32]string, len(probeInfo.Tracks))
+	for _, tr := range probeInfo.Tracks {
+		codecs[tr.TrackID] = tr.CodecString()
+	}
+
 	// generate track map
 	tracks := make(map[uint32]*track, 4)
 	bis, err := mp4.ExtractBox(inputFile, nil, mp4.BoxPath{mp4.BoxTypeMoov(), mp4.BoxTypeTrak()})
@@ -89,6 +100,7 @@ func divide(inputFilePath, outputDir string) error {
 		}
 		tkhd := bs[0].Payload.(*mp4.Tkhd)
 		t.id = tkhd.TrackID
+		t.codec = codecs[t.id]
 
 		// get timescale from Mdhd box
 		bs, err = mp4.ExtractBoxWithPayload(inputFile, bi, mp4.BoxPath{mp4.BoxTypeMdia(), mp4.BoxTypeMdhd()})
@@ -366,11 +378,14 @@ func outputMasterPlaylist(filePath string, trackTypeMap map[trackType]*track) er
 	var adir string
 	var vdir string
 	var vt *track
+	var at *track
 
-	if _, exists := trackTypeMap[trackAudio]; exists {
+	if t, exists := trackTypeMap[trackAudio]; exists {
 		adir = audioDirName
-	} else if _, exists := trackTypeMap[trackEncAudio]; exists {
+		at = t
+	} else if t, exists := trackTypeMap[trackEncAudio]; exists {
 		adir = encAudioDirName
+		at = t
 	}
 
 	if t, exists := trackTypeMap[trackVideo]; exists {
@@ -386,8 +401,12 @@ func outputMasterPlaylist(filePath string, trackTypeMap map[trackType]*track) er
 		file.WriteString("#EXT-X-MEDIA:TYPE=AUDIO,URI=\"" + adir + "/" + playlistFileName + "\",GROUP-ID=\"audio\",NAME=\"audio\",AUTOSELECT=YES,CHANNELS=\"2\"\n")
 	}
 	if vdir != "" {
-		_, err = fmt.Fprintf(file, "#EXT-X-STREAM-INF:BANDWIDTH=%d,CODECS=\"avc1.64001f,mp4a.40.2\",RESOLUTION=%dx%d", // FIXME: hard coding
-			vt.bandwidth, vt.width, vt.height)
+		codecs := vt.codec
+		if at != nil && at.codec != "" {
+			codecs += "," + at.codec
+		}
+		_, err = fmt.Fprintf(file, "#EXT-X-STREAM-INF:BANDWIDTH=%d,CODECS=\"%s\",RESOLUTION=%dx%d",
+			vt.bandwidth, codecs, vt.width, vt.height)
 		if err != nil {
 			return err
 		}
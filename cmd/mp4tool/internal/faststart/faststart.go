@@ -0,0 +1,55 @@
+package faststart
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Spidey120703/go-mp4"
+	"github.com/sunfish-shogi/bufseekio"
+)
+
+const (
+	blockSize        = 128 * 1024
+	blockHistorySize = 4
+)
+
+func Main(args []string) int {
+	flagSet := flag.NewFlagSet("faststart", flag.ExitOnError)
+	flagSet.Usage = func() {
+		println("USAGE: mp4tool faststart INPUT.mp4 OUTPUT.mp4")
+		flagSet.PrintDefaults()
+	}
+	flagSet.Parse(args)
+
+	if len(flagSet.Args()) < 2 {
+		flagSet.Usage()
+		return 1
+	}
+
+	inputPath := flagSet.Args()[0]
+	outputPath := flagSet.Args()[1]
+
+	if err := faststart(inputPath, outputPath); err != nil {
+		fmt.Println("Error:", err)
+		return 1
+	}
+	return 0
+}
+
+func faststart(inputPath, outputPath string) error {
+	input, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	output, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+
+	r := bufseekio.NewReadSeeker(input, blockSize, blockHistorySize)
+	return mp4.MakeFastStart(r, output)
+}
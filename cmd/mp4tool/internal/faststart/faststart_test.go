@@ -0,0 +1,23 @@
+package faststart
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Spidey120703/go-mp4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFastStart(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "output.mp4")
+	require.Zero(t, Main([]string{"../../../../testdata/sample.mp4", output}))
+
+	f, err := os.Open(output)
+	require.NoError(t, err)
+	defer f.Close()
+	info, err := mp4.Probe(f)
+	require.NoError(t, err)
+	assert.True(t, info.FastStart)
+}
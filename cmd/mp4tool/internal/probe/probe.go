@@ -75,9 +75,11 @@ type track struct {
 	Encrypted       bool    `yaml:"encrypted"`
 	Width           uint16  `json:",omitempty" yaml:"width,omitempty"`
 	Height          uint16  `json:",omitempty" yaml:"height,omitempty"`
+	FrameRate       float64 `json:",omitempty" yaml:"frame_rate,omitempty"`
+	SAR             string  `json:",omitempty" yaml:"sar,omitempty"`
 	SampleNum       int     `json:",omitempty" yaml:"sample_num,omitempty"`
 	ChunkNum        int     `json:",omitempty" yaml:"chunk_num,omitempty"`
-	IDRFrameNum     int     `json:",omitempty" yaml:"idr_frame_num,omitempty"`
+	IDRFrameNum     int     `json:",omitempty" yaml:"idr_frame_num,omitempty"` // random access points including CRA and BLA pictures of HEVC
 	Bitrate         uint64  `json:",omitempty" yaml:"bitrate,omitempty"`
 	MaxBitrate      uint64  `json:",omitempty" yaml:"max_bitrate,omitempty"`
 }
@@ -119,34 +121,46 @@ func buildReport(r io.ReadSeeker) (*report, error) {
 			SampleNum:       len(tr.Samples),
 			ChunkNum:        len(tr.Chunks),
 		}
-		switch tr.Codec {
-		case mp4.CodecAVC1:
-			if tr.AVC != nil {
-				t.Codec = fmt.Sprintf("avc1.%02X%02X%02X",
-					tr.AVC.Profile,
-					tr.AVC.ProfileCompatibility,
-					tr.AVC.Level,
-				)
-				t.Width = tr.AVC.Width
-				t.Height = tr.AVC.Height
-			} else {
-				t.Codec = "avc1"
+		t.Codec = tr.CodecString()
+		if t.Codec == "" {
+			t.Codec = "unknown"
+		}
+		var vui *mp4.VUIParameters
+		switch {
+		case tr.AVC != nil:
+			t.Width, t.Height = tr.AVC.Width, tr.AVC.Height
+			if tr.AVC.SPS != nil {
+				t.FrameRate = tr.AVC.SPS.FrameRate()
+				vui = tr.AVC.SPS.VUI
+			}
+		case tr.HEVC != nil:
+			t.Width, t.Height = tr.HEVC.Width, tr.HEVC.Height
+			if tr.HEVC.SPS != nil {
+				t.FrameRate = tr.HEVC.SPS.FrameRate()
+				vui = tr.HEVC.SPS.VUI
+			}
+		case tr.DOVI != nil:
+			t.Width, t.Height = tr.DOVI.Width, tr.DOVI.Height
+		case tr.VVC != nil:
+			t.Width, t.Height = tr.VVC.Width, tr.VVC.Height
+		case tr.AV1 != nil:
+			t.Width, t.Height = tr.AV1.Width, tr.AV1.Height
+			if tr.AV1.SequenceHeader != nil {
+				t.FrameRate = tr.AV1.SequenceHeader.FrameRate()
 			}
+		case tr.VP != nil:
+			t.Width, t.Height = tr.VP.Width, tr.VP.Height
+		}
+		if vui != nil && vui.SARWidth != 0 && vui.SARHeight != 0 {
+			t.SAR = fmt.Sprintf("%d:%d", vui.SARWidth, vui.SARHeight)
+		}
+		switch tr.Codec {
+		case mp4.CodecAVC1, mp4.CodecHEVC, mp4.CodecAV1, mp4.CodecVP8, mp4.CodecVP9:
 			idxs, err := mp4.FindIDRFrames(r, tr)
 			if err != nil {
 				return nil, err
 			}
 			t.IDRFrameNum = len(idxs)
-		case mp4.CodecMP4A:
-			if tr.MP4A == nil || tr.MP4A.OTI == 0 {
-				t.Codec = "mp4a"
-			} else if tr.MP4A.AudOTI == 0 {
-				t.Codec = fmt.Sprintf("mp4a.%X", tr.MP4A.OTI)
-			} else {
-				t.Codec = fmt.Sprintf("mp4a.%X.%d", tr.MP4A.OTI, tr.MP4A.AudOTI)
-			}
-		default:
-			t.Codec = "unknown"
 		}
 		rep.Tracks = append(rep.Tracks, t)
 	}
@@ -76,6 +76,8 @@ var sampleMP4JSONOutput = "" +
 	`      "Encrypted": false,` + "\n" +
 	`      "Width": 320,` + "\n" +
 	`      "Height": 180,` + "\n" +
+	`      "FrameRate": 10,` + "\n" +
+	`      "SAR": "1:1",` + "\n" +
 	`      "SampleNum": 10,` + "\n" +
 	`      "ChunkNum": 9,` + "\n" +
 	`      "IDRFrameNum": 1,` + "\n" +
@@ -118,6 +120,8 @@ var sampleMP4YamlOutput = "" +
 	`  encrypted: false` + "\n" +
 	`  width: 320` + "\n" +
 	`  height: 180` + "\n" +
+	`  frame_rate: 10` + "\n" +
+	`  sar: "1:1"` + "\n" +
 	`  sample_num: 10` + "\n" +
 	`  chunk_num: 9` + "\n" +
 	`  idr_frame_num: 1` + "\n" +
@@ -0,0 +1,93 @@
+package rapcheck
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Spidey120703/go-mp4"
+	"github.com/sunfish-shogi/bufseekio"
+)
+
+const (
+	blockSize        = 128 * 1024
+	blockHistorySize = 4
+)
+
+var errMismatch = errors.New("random access information is inconsistent")
+
+func Main(args []string) int {
+	flagSet := flag.NewFlagSet("rapcheck", flag.ExitOnError)
+	trackID := flagSet.Uint("track", 0, "track ID (all tracks by default)")
+	verbose := flagSet.Bool("verbose", false, "print random access information of all samples")
+	flagSet.Usage = func() {
+		println("USAGE: mp4tool rapcheck [OPTIONS] INPUT.mp4")
+		println()
+		println("Cross-checks stss, sdtp, sync and rap sample groups, sample flags of movie fragments")
+		println("and the bitstream, and fails if they are inconsistent.")
+		println()
+		flagSet.PrintDefaults()
+	}
+	flagSet.Parse(args)
+
+	if len(flagSet.Args()) < 1 {
+		flagSet.Usage()
+		return 1
+	}
+
+	input, err := os.Open(flagSet.Args()[0])
+	if err != nil {
+		fmt.Println("Error:", err)
+		return 1
+	}
+	defer input.Close()
+
+	r := bufseekio.NewReadSeeker(input, blockSize, blockHistorySize)
+	if err := check(r, os.Stdout, uint32(*trackID), *verbose); err != nil {
+		fmt.Println("Error:", err)
+		return 1
+	}
+	return 0
+}
+
+func check(r io.ReadSeeker, w io.Writer, trackID uint32, verbose bool) error {
+	info, err := mp4.Probe(r)
+	if err != nil {
+		return err
+	}
+
+	var found, mismatch bool
+	for _, track := range info.Tracks {
+		if trackID != 0 && track.TrackID != trackID {
+			continue
+		}
+		found = true
+		rep, err := mp4.AnalyzeRandomAccess(r, track)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "track %d: samples=%d sync=%d random_access_points=%d mismatches=%d\n",
+			track.TrackID, len(rep.Samples), len(rep.SyncSamples()), len(rep.RandomAccessPoints()), len(rep.Mismatches))
+		if verbose {
+			for _, s := range rep.Samples {
+				fmt.Fprintf(w, "  sample %d: sync=%t depends_on=%d sync_group=%t rap_group=%t bitstream=%s\n",
+					s.Index, s.Sync, s.DependsOn, s.SyncGroup, s.RAPGroup, s.Bitstream)
+			}
+		}
+		for _, m := range rep.Mismatches {
+			fmt.Fprintf(w, "  %s\n", m)
+		}
+		if len(rep.Mismatches) != 0 {
+			mismatch = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("track not found: trackID=%d", trackID)
+	}
+	if mismatch {
+		return errMismatch
+	}
+	return nil
+}
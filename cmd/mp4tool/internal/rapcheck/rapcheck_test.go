@@ -0,0 +1,48 @@
+package rapcheck
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+	f, err := os.Open("../../../../testdata/sample_fragmented.mp4")
+	require.NoError(t, err)
+	defer f.Close()
+
+	buf := bytes.NewBuffer(nil)
+	require.NoError(t, check(f, buf, 0, false))
+	assert.Equal(t, ""+
+		"track 1: samples=10 sync=4 random_access_points=4 mismatches=0\n"+
+		"track 2: samples=44 sync=44 random_access_points=0 mismatches=0\n",
+		buf.String())
+
+	buf.Reset()
+	require.NoError(t, check(f, buf, 1, true))
+	assert.Equal(t, ""+
+		"track 1: samples=10 sync=4 random_access_points=4 mismatches=0\n"+
+		"  sample 0: sync=true depends_on=2 sync_group=false rap_group=false bitstream=IDR\n"+
+		"  sample 1: sync=false depends_on=1 sync_group=false rap_group=false bitstream=none\n"+
+		"  sample 2: sync=false depends_on=1 sync_group=false rap_group=false bitstream=none\n"+
+		"  sample 3: sync=true depends_on=2 sync_group=false rap_group=false bitstream=IDR\n"+
+		"  sample 4: sync=false depends_on=1 sync_group=false rap_group=false bitstream=none\n"+
+		"  sample 5: sync=true depends_on=2 sync_group=false rap_group=false bitstream=IDR\n"+
+		"  sample 6: sync=false depends_on=1 sync_group=false rap_group=false bitstream=none\n"+
+		"  sample 7: sync=false depends_on=1 sync_group=false rap_group=false bitstream=none\n"+
+		"  sample 8: sync=true depends_on=2 sync_group=false rap_group=false bitstream=IDR\n"+
+		"  sample 9: sync=false depends_on=1 sync_group=false rap_group=false bitstream=none\n",
+		buf.String())
+
+	assert.Error(t, check(f, buf, 3, false))
+}
+
+func TestRapcheck(t *testing.T) {
+	assert.Zero(t, Main([]string{"../../../../testdata/sample.mp4"}))
+	assert.NotZero(t, Main([]string{"-track", "3", "../../../../testdata/sample.mp4"}))
+	assert.NotZero(t, Main([]string{"../../../../testdata/not_found.mp4"}))
+	assert.NotZero(t, Main([]string{}))
+}
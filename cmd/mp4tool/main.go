@@ -4,12 +4,18 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/Spidey120703/go-mp4/cmd/mp4tool/internal/caption"
+	"github.com/Spidey120703/go-mp4/cmd/mp4tool/internal/decrypt"
+	"github.com/Spidey120703/go-mp4/cmd/mp4tool/internal/defrag"
+	"github.com/Spidey120703/go-mp4/cmd/mp4tool/internal/demux"
 	"github.com/Spidey120703/go-mp4/cmd/mp4tool/internal/divide"
 	"github.com/Spidey120703/go-mp4/cmd/mp4tool/internal/dump"
 	"github.com/Spidey120703/go-mp4/cmd/mp4tool/internal/edit"
 	"github.com/Spidey120703/go-mp4/cmd/mp4tool/internal/extract"
+	"github.com/Spidey120703/go-mp4/cmd/mp4tool/internal/faststart"
 	"github.com/Spidey120703/go-mp4/cmd/mp4tool/internal/probe"
 	"github.com/Spidey120703/go-mp4/cmd/mp4tool/internal/psshdump"
+	"github.com/Spidey120703/go-mp4/cmd/mp4tool/internal/rapcheck"
 )
 
 func main() {
@@ -31,6 +37,18 @@ func main() {
 		os.Exit(probe.Main(args[1:]))
 	case "extract":
 		os.Exit(extract.Main(args[1:]))
+	case "decrypt":
+		os.Exit(decrypt.Main(args[1:]))
+	case "defrag":
+		os.Exit(defrag.Main(args[1:]))
+	case "faststart":
+		os.Exit(faststart.Main(args[1:]))
+	case "demux":
+		os.Exit(demux.Main(args[1:]))
+	case "caption":
+		os.Exit(caption.Main(args[1:]))
+	case "rapcheck":
+		os.Exit(rapcheck.Main(args[1:]))
 	case "alpha":
 		os.Exit(alpha(args[1:]))
 	default:
@@ -64,6 +82,12 @@ func printUsage() {
 	fmt.Fprintln(os.Stderr, "  psshdump     : display pssh box attributes")
 	fmt.Fprintln(os.Stderr, "  probe        : probe and summarize mp4 file status")
 	fmt.Fprintln(os.Stderr, "  extract      : extract specific box")
+	fmt.Fprintln(os.Stderr, "  decrypt      : decrypt common encryption protected tracks")
+	fmt.Fprintln(os.Stderr, "  defrag       : convert fragmented mp4 into progressive mp4")
+	fmt.Fprintln(os.Stderr, "  faststart    : move moov box to the front of the file")
+	fmt.Fprintln(os.Stderr, "  demux        : export H.264/H.265, AAC, Opus, FLAC or WebVTT track as elementary stream")
+	fmt.Fprintln(os.Stderr, "  caption      : extract CEA-608/708 closed captions as SRT or WebVTT")
+	fmt.Fprintln(os.Stderr, "  rapcheck     : check consistency of sync samples and random access points")
 	fmt.Fprintln(os.Stderr, "  alpha edit")
 	fmt.Fprintln(os.Stderr, "  alpha divide")
 }
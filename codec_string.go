@@ -0,0 +1,117 @@
+package mp4
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+// CodecString returns the codecs parameter defined in RFC 6381,
+// which is used by CODECS attribute of HLS and codecs attribute of DASH.
+// The type of the sample entry is returned without parameters
+// if the configuration of the codec is not available,
+// and an empty string is returned if the codec is unknown.
+func (t *Track) CodecString() string {
+	switch t.Codec {
+	case CodecAVC1:
+		if t.AVC == nil {
+			return "avc1"
+		}
+		return fmt.Sprintf("avc1.%02X%02X%02X", t.AVC.Profile, t.AVC.ProfileCompatibility, t.AVC.Level)
+	case CodecHEVC:
+		return t.hevcCodecString()
+	case CodecDolbyVision:
+		if t.DOVI == nil {
+			return t.Format.String()
+		}
+		return fmt.Sprintf("%s.%02d.%02d", t.Format.String(), t.DOVI.Profile, t.DOVI.Level)
+	case CodecVVC:
+		if t.VVC == nil || t.VVC.Profile == 0 {
+			return t.Format.String()
+		}
+		return fmt.Sprintf("%s.%d.%s%d", t.Format.String(), t.VVC.Profile, tierString(t.VVC.Tier), t.VVC.Level)
+	case CodecAV1:
+		if t.AV1 == nil {
+			return "av01"
+		}
+		tier := "M"
+		if t.AV1.Tier {
+			tier = "H"
+		}
+		return fmt.Sprintf("av01.%d.%02d%s.%02d", t.AV1.Profile, t.AV1.Level, tier, t.AV1.BitDepth)
+	case CodecVP8, CodecVP9:
+		if t.VP == nil {
+			return t.Format.String()
+		}
+		return fmt.Sprintf("%s.%02d.%02d.%02d", t.Format.String(), t.VP.Profile, t.VP.Level, t.VP.BitDepth)
+	case CodecMP4A:
+		if t.MP4A == nil || t.MP4A.OTI == 0 {
+			return "mp4a"
+		} else if t.MP4A.AudOTI == 0 {
+			return fmt.Sprintf("mp4a.%X", t.MP4A.OTI)
+		}
+		return fmt.Sprintf("mp4a.%X.%d", t.MP4A.OTI, t.MP4A.AudOTI)
+	case CodecOpus:
+		return "opus"
+	case CodecAC3:
+		return "ac-3"
+	case CodecEAC3:
+		return "ec-3"
+	case CodecAC4:
+		if t.AC4 == nil || len(t.AC4.Presentations) == 0 {
+			return "ac-4"
+		}
+		p := t.AC4.Presentations[0]
+		return fmt.Sprintf("ac-4.%02d.%02d.%02d", t.AC4.BitstreamVersion, p.PresentationVersion, p.MDCompat)
+	case CodecMPEGH:
+		if t.MPEGH == nil || t.MPEGH.ProfileLevelIndication == 0 {
+			return t.Format.String()
+		}
+		return fmt.Sprintf("%s.0x%02X", t.Format.String(), t.MPEGH.ProfileLevelIndication)
+	case CodecALAC, CodecPCM, CodecFLAC, CodecWebVTT, CodecTTML:
+		return t.Format.String()
+	}
+	if t.Format == (BoxType{}) {
+		return ""
+	}
+	return t.Format.String()
+}
+
+// hevcCodecString builds the codecs parameter of HEVC defined in ISO/IEC 14496-15 Annex E.
+func (t *Track) hevcCodecString() string {
+	format := "hvc1"
+	if t.Format == BoxTypeHev1() {
+		format = "hev1"
+	}
+	if t.HEVC == nil {
+		return format
+	}
+	var b strings.Builder
+	b.WriteString(format)
+	b.WriteString(".")
+	if t.HEVC.ProfileSpace != 0 {
+		b.WriteByte('A' + t.HEVC.ProfileSpace - 1)
+	}
+	fmt.Fprintf(&b, "%d.%X.%s%d",
+		t.HEVC.Profile,
+		bits.Reverse32(t.HEVC.ProfileCompatibility),
+		tierString(t.HEVC.Tier),
+		t.HEVC.Level,
+	)
+	// trailing zero bytes of the constraint indicator flags are omitted
+	n := len(t.HEVC.ConstraintIndicator)
+	for n > 0 && t.HEVC.ConstraintIndicator[n-1] == 0 {
+		n--
+	}
+	for _, c := range t.HEVC.ConstraintIndicator[:n] {
+		fmt.Fprintf(&b, ".%X", c)
+	}
+	return b.String()
+}
+
+func tierString(tier bool) string {
+	if tier {
+		return "H"
+	}
+	return "L"
+}
@@ -0,0 +1,129 @@
+package mp4
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrackCodecString(t *testing.T) {
+	testCases := []struct {
+		name  string
+		track *Track
+		want  string
+	}{
+		{
+			name:  "avc1",
+			track: &Track{Codec: CodecAVC1, Format: BoxTypeAvc1(), AVC: &AVCDecConfigInfo{Profile: 0x64, Level: 0x1f}},
+			want:  "avc1.64001F",
+		},
+		{
+			name:  "avc1 without avcC",
+			track: &Track{Codec: CodecAVC1, Format: BoxTypeEncv()},
+			want:  "avc1",
+		},
+		{
+			name: "hvc1 main",
+			track: &Track{Codec: CodecHEVC, Format: BoxTypeHvc1(), HEVC: &HEVCDecConfigInfo{
+				Profile:              1,
+				ProfileCompatibility: 0x60000000,
+				ConstraintIndicator:  [6]uint8{0xb0},
+				Level:                93,
+			}},
+			want: "hvc1.1.6.L93.B0",
+		},
+		{
+			name: "hev1 main10 high tier",
+			track: &Track{Codec: CodecHEVC, Format: BoxTypeHev1(), HEVC: &HEVCDecConfigInfo{
+				Tier:                 true,
+				Profile:              2,
+				ProfileCompatibility: 0x20000000,
+				ConstraintIndicator:  [6]uint8{0x90, 0x00, 0x01},
+				Level:                153,
+			}},
+			want: "hev1.2.4.H153.90.0.1",
+		},
+		{
+			name: "hvc1 with profile space",
+			track: &Track{Codec: CodecHEVC, Format: BoxTypeHvc1(), HEVC: &HEVCDecConfigInfo{
+				ProfileSpace: 1,
+				Profile:      1,
+				Level:        120,
+			}},
+			want: "hvc1.A1.0.L120",
+		},
+		{
+			name:  "dvh1",
+			track: &Track{Codec: CodecDolbyVision, Format: BoxTypeDvh1(), DOVI: &DOVIDecConfigInfo{Profile: 5, Level: 6}},
+			want:  "dvh1.05.06",
+		},
+		{
+			name:  "vvc1",
+			track: &Track{Codec: CodecVVC, Format: BoxTypeVvc1(), VVC: &VVCDecConfigInfo{Profile: 1, Level: 83}},
+			want:  "vvc1.1.L83",
+		},
+		{
+			name:  "av01",
+			track: &Track{Codec: CodecAV1, Format: BoxTypeAv01(), AV1: &AV1DecConfigInfo{Profile: 0, Level: 8, BitDepth: 10}},
+			want:  "av01.0.08M.10",
+		},
+		{
+			name:  "vp09",
+			track: &Track{Codec: CodecVP9, Format: BoxTypeVp09(), VP: &VPDecConfigInfo{Profile: 2, Level: 51, BitDepth: 10}},
+			want:  "vp09.02.51.10",
+		},
+		{
+			name:  "mp4a",
+			track: &Track{Codec: CodecMP4A, Format: BoxTypeMp4a(), MP4A: &MP4AInfo{OTI: 0x40, AudOTI: 2}},
+			want:  "mp4a.40.2",
+		},
+		{
+			name:  "mp4a mp3",
+			track: &Track{Codec: CodecMP4A, Format: BoxTypeMp4a(), MP4A: &MP4AInfo{OTI: 0x6b}},
+			want:  "mp4a.6B",
+		},
+		{
+			name:  "Opus",
+			track: &Track{Codec: CodecOpus, Format: BoxTypeOpus()},
+			want:  "opus",
+		},
+		{
+			name:  "ec-3",
+			track: &Track{Codec: CodecEAC3, Format: BoxTypeEC3()},
+			want:  "ec-3",
+		},
+		{
+			name: "ac-4",
+			track: &Track{Codec: CodecAC4, Format: BoxTypeAC4(), AC4: &AC4Info{
+				BitstreamVersion: 2,
+				Presentations:    []*AC4PresentationInfo{{PresentationVersion: 1, MDCompat: 3}},
+			}},
+			want: "ac-4.02.01.03",
+		},
+		{
+			name:  "mhm1",
+			track: &Track{Codec: CodecMPEGH, Format: BoxTypeMhm1(), MPEGH: &MPEGHInfo{ProfileLevelIndication: 0x0d}},
+			want:  "mhm1.0x0D",
+		},
+		{
+			name:  "fLaC",
+			track: &Track{Codec: CodecFLAC, Format: BoxTypeFLaC()},
+			want:  "fLaC",
+		},
+		{
+			name:  "unknown",
+			track: &Track{Codec: CodecUnknown, Format: StrToBoxType("mp4v")},
+			want:  "mp4v",
+		},
+		{
+			name:  "no sample entry",
+			track: &Track{},
+			want:  "",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, tc.track.CodecString())
+		})
+	}
+}
@@ -0,0 +1,501 @@
+package mp4
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/google/uuid"
+)
+
+// protectedSampleEntry is a sample entry which has a protection scheme information box (sinf).
+type protectedSampleEntry struct {
+	info   BoxInfo
+	format [4]byte
+	scheme [4]byte
+	tenc   *Tenc
+}
+
+// sampleAuxInfo holds boxes which carry the sample auxiliary information of a stbl or traf box.
+// The senc box is parsed later, because the IV size of each sample depends on its seig sample group.
+type sampleAuxInfo struct {
+	senc *BoxInfo
+	saiz *Saiz
+	saio *Saio
+}
+
+// sampleEncryption is the encryption parameters of a sample, which are given by the tenc box or a seig sample group entry.
+type sampleEncryption struct {
+	isProtected     bool
+	kid             [16]byte
+	perSampleIVSize uint8
+	constantIV      []byte
+	cryptByteBlock  uint8
+	skipByteBlock   uint8
+}
+
+var sampleGroupTypeSeig = [4]byte{'s', 'e', 'i', 'g'}
+
+type protectionInfo struct {
+	// entries maps track IDs and sample description indices to protected sample entries.
+	entries map[uint32]map[uint32]*protectedSampleEntry
+
+	// auxInfos maps offsets of stbl and traf boxes to their sample auxiliary information.
+	auxInfos map[uint64]*sampleAuxInfo
+
+	// stblOffsets maps track IDs to offsets of stbl boxes.
+	stblOffsets map[uint32]uint64
+
+	// groups maps offsets of stbl and traf boxes to their sample groups.
+	groups map[uint64]*sampleGroups
+
+	// freeBoxes are boxes which are only used for protection.
+	freeBoxes []BoxInfo
+}
+
+// Decrypt decrypts all samples of protected tracks in r and writes the clear file to w.
+// keys maps KIDs to 16-byte AES keys. cenc, cens, cbc1 and cbcs schemes are supported.
+// seig sample groups override the KID, the IV and the pattern of tenc boxes for each sample,
+// and the samples which are not protected in the groups (e.g. clear lead) are left as they are.
+//
+// Decrypt preserves the layout of the file in order not to shift any offsets.
+// Protected sample entries such as encv and enca are renamed to the original formats given by frma boxes,
+// and boxes used only for protection (sinf, senc, saiz, saio, pssh and seig sample groups) are replaced with free boxes.
+func Decrypt(r io.ReadSeeker, w io.WriteSeeker, keys map[[16]byte][]byte) error {
+	pi, err := readProtectionInfo(r)
+	if err != nil {
+		return err
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := w.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		return err
+	}
+
+	for trackID, entries := range pi.entries {
+		if err := decryptTrack(r, w, pi, trackID, entries, keys); err != nil {
+			return err
+		}
+	}
+
+	for _, entries := range pi.entries {
+		for _, entry := range entries {
+			if err := writeBoxType(w, &entry.info, BoxType(entry.format)); err != nil {
+				return err
+			}
+		}
+	}
+	for i := range pi.freeBoxes {
+		if err := writeBoxType(w, &pi.freeBoxes[i], BoxTypeFree()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readProtectionInfo(r io.ReadSeeker) (*protectionInfo, error) {
+	pi := &protectionInfo{
+		entries:     make(map[uint32]map[uint32]*protectedSampleEntry),
+		auxInfos:    make(map[uint64]*sampleAuxInfo),
+		stblOffsets: make(map[uint32]uint64),
+		groups:      make(map[uint64]*sampleGroups),
+	}
+	descCounts := make(map[uint32]uint32)
+	var entry *protectedSampleEntry
+	var container uint64
+
+	auxInfo := func() *sampleAuxInfo {
+		if pi.auxInfos[container] == nil {
+			pi.auxInfos[container] = &sampleAuxInfo{}
+		}
+		return pi.auxInfos[container]
+	}
+	groups := func() *sampleGroups {
+		if pi.groups[container] == nil {
+			pi.groups[container] = &sampleGroups{}
+		}
+		return pi.groups[container]
+	}
+
+	_, err := ReadBoxStructureWithContext(r, Context{Crypto: NewCryptoContext()}, func(h *ReadHandle) (interface{}, error) {
+		trackID := h.BoxInfo.TrackID
+		if len(h.Path) >= 2 && h.Path[len(h.Path)-2] == BoxTypeStsd() {
+			descCounts[trackID]++
+			switch h.BoxInfo.Type {
+			case BoxTypeEncv(), BoxTypeEnca():
+				entry = &protectedSampleEntry{info: h.BoxInfo}
+				if pi.entries[trackID] == nil {
+					pi.entries[trackID] = make(map[uint32]*protectedSampleEntry)
+				}
+				pi.entries[trackID][descCounts[trackID]] = entry
+				return h.Expand()
+			}
+			return nil, nil
+		}
+
+		switch h.BoxInfo.Type {
+		case BoxTypeMoov(), BoxTypeTrak(), BoxTypeMdia(), BoxTypeMinf(), BoxTypeStsd(),
+			BoxTypeSchi(), BoxTypeMoof():
+			return h.Expand()
+		case BoxTypeStbl():
+			container = h.BoxInfo.Offset
+			pi.stblOffsets[trackID] = h.BoxInfo.Offset
+			return h.Expand()
+		case BoxTypeTraf():
+			container = h.BoxInfo.Offset
+			return h.Expand()
+		case BoxTypeSinf():
+			pi.freeBoxes = append(pi.freeBoxes, h.BoxInfo)
+			return h.Expand()
+		case BoxTypePssh():
+			pi.freeBoxes = append(pi.freeBoxes, h.BoxInfo)
+			return nil, nil
+		case BoxTypeSenc():
+			bi := h.BoxInfo
+			auxInfo().senc = &bi
+			pi.freeBoxes = append(pi.freeBoxes, h.BoxInfo)
+			return nil, nil
+		}
+
+		switch h.BoxInfo.Type {
+		case BoxTypeFrma(), BoxTypeSchm(), BoxTypeTenc(), BoxTypeSaiz(), BoxTypeSaio(),
+			BoxTypeSbgp(), BoxTypeSgpd():
+		default:
+			return nil, nil
+		}
+		box, _, err := h.ReadPayload()
+		if err != nil {
+			return nil, err
+		}
+		switch box := box.(type) {
+		case *Frma:
+			if entry != nil {
+				entry.format = box.DataFormat
+			}
+		case *Schm:
+			if entry != nil {
+				entry.scheme = box.SchemeType
+			}
+		case *Tenc:
+			if entry != nil {
+				entry.tenc = box
+			}
+		case *Saiz:
+			if isCencAuxInfo(box.GetFlags(), box.AuxInfoType) {
+				auxInfo().saiz = box
+				pi.freeBoxes = append(pi.freeBoxes, h.BoxInfo)
+			}
+		case *Saio:
+			if isCencAuxInfo(box.GetFlags(), box.AuxInfoType) {
+				auxInfo().saio = box
+				pi.freeBoxes = append(pi.freeBoxes, h.BoxInfo)
+			}
+		case *Sbgp:
+			if box.GroupingType == binary.BigEndian.Uint32(sampleGroupTypeSeig[:]) {
+				groups().sbgps = append(groups().sbgps, box)
+				pi.freeBoxes = append(pi.freeBoxes, h.BoxInfo)
+			}
+		case *Sgpd:
+			if box.GroupingType == sampleGroupTypeSeig {
+				groups().sgpds = append(groups().sgpds, box)
+				pi.freeBoxes = append(pi.freeBoxes, h.BoxInfo)
+			}
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for trackID, entries := range pi.entries {
+		for _, entry := range entries {
+			if entry.tenc == nil {
+				return nil, fmt.Errorf("tenc box not found: trackID=%d", trackID)
+			}
+			if entry.format == [4]byte{} {
+				return nil, fmt.Errorf("frma box not found: trackID=%d", trackID)
+			}
+			if entry.scheme == [4]byte{} {
+				// the scheme type box is mandatory for common encryption, but assume cenc without it
+				entry.scheme = SchemeTypeCENC()
+			}
+		}
+	}
+	return pi, nil
+}
+
+func isCencAuxInfo(flags uint32, auxInfoType [4]byte) bool {
+	if flags&0x000001 == 0 {
+		return true
+	}
+	switch auxInfoType {
+	case SchemeTypeCENC(), SchemeTypeCENS(), SchemeTypeCBC1(), SchemeTypeCBCS():
+		return true
+	}
+	return false
+}
+
+func decryptTrack(r io.ReadSeeker, w io.WriteSeeker, pi *protectionInfo, trackID uint32, entries map[uint32]*protectedSampleEntry, keys map[[16]byte][]byte) error {
+	sr, err := NewSampleReader(r, trackID)
+	if err != nil {
+		return err
+	}
+
+	// the encryption parameters of the samples are resolved for each container before parsing its sample auxiliary information
+	stblOffset := pi.stblOffsets[trackID]
+	encs := make(map[uint64][]*sampleEncryption)
+	seigIndices := make(map[uint64][]uint32)
+	for _, sample := range sr.Samples() {
+		entry := entries[sample.SampleDescriptionIndex]
+		container := stblOffset
+		index := sample.Index
+		if sample.fragment != nil {
+			container = sample.fragment.traf.Offset
+			index = sample.fragmentSampleIndex
+		}
+		for len(encs[container]) <= index {
+			encs[container] = append(encs[container], nil)
+		}
+		if entry == nil {
+			continue
+		}
+		enc := &sampleEncryption{
+			isProtected:     entry.tenc.DefaultIsProtected != 0,
+			kid:             entry.tenc.DefaultKID,
+			perSampleIVSize: entry.tenc.DefaultPerSampleIVSize,
+			constantIV:      entry.tenc.DefaultConstantIV,
+			cryptByteBlock:  entry.tenc.DefaultCryptByteBlock,
+			skipByteBlock:   entry.tenc.DefaultSkipByteBlock,
+		}
+		if sg := pi.groups[container]; sg != nil {
+			if _, ok := seigIndices[container]; !ok {
+				seigIndices[container] = sg.groupDescriptionIndices(sampleGroupTypeSeig, len(sr.Samples()))
+			}
+			if gdi := sampleGroupIndexAt(seigIndices[container], index); gdi != 0 {
+				seig, ok := sg.entry(pi.groups[stblOffset], sampleGroupTypeSeig, gdi, sample.fragment != nil).(*CencSampleEncryptionInformationGroupEntry)
+				if !ok {
+					return fmt.Errorf("seig sample group entry not found: trackID=%d, sample=%d, index=%d", trackID, sample.Index, gdi)
+				}
+				enc = &sampleEncryption{
+					isProtected:     seig.IsProtected != 0,
+					kid:             seig.KID,
+					perSampleIVSize: seig.PerSampleIVSize,
+					constantIV:      seig.ConstantIV,
+					cryptByteBlock:  seig.CryptByteBlock,
+					skipByteBlock:   seig.SkipByteBlock,
+				}
+			}
+		}
+		encs[container][index] = enc
+	}
+
+	type cipherKey struct {
+		scheme         [4]byte
+		kid            [16]byte
+		cryptByteBlock uint8
+		skipByteBlock  uint8
+	}
+	ciphers := make(map[cipherKey]*cencCipher)
+	auxEntries := make(map[uint64][]SencSampleEntry)
+	for _, sample := range sr.Samples() {
+		entry := entries[sample.SampleDescriptionIndex]
+		if entry == nil {
+			continue
+		}
+		container := stblOffset
+		index := sample.Index
+		if sample.fragment != nil {
+			container = sample.fragment.traf.Offset
+			index = sample.fragmentSampleIndex
+		}
+		enc := encs[container][index]
+		if !enc.isProtected {
+			continue
+		}
+
+		ck := cipherKey{scheme: entry.scheme, kid: enc.kid, cryptByteBlock: enc.cryptByteBlock, skipByteBlock: enc.skipByteBlock}
+		c := ciphers[ck]
+		if c == nil {
+			key, ok := keys[enc.kid]
+			if !ok {
+				return fmt.Errorf("key not found: trackID=%d, KID=%s", trackID, uuid.UUID(enc.kid).String())
+			}
+			c, err = newCencCipher(entry.scheme, key, enc.cryptByteBlock, enc.skipByteBlock, true)
+			if err != nil {
+				return err
+			}
+			ciphers[ck] = c
+		}
+
+		if _, ok := auxEntries[container]; !ok {
+			containerEncs := encs[container]
+			ivSize := func(i int) int {
+				if i < len(containerEncs) && containerEncs[i] != nil {
+					return int(containerEncs[i].perSampleIVSize)
+				}
+				return 0
+			}
+			aux, err := loadSampleAuxInfo(r, pi.auxInfos[container], sample, ivSize)
+			if err != nil {
+				return err
+			}
+			auxEntries[container] = aux
+		}
+		if index >= len(auxEntries[container]) {
+			return fmt.Errorf("sample auxiliary information not found: trackID=%d, sample=%d", trackID, sample.Index)
+		}
+		aux := &auxEntries[container][index]
+
+		iv := aux.InitializationVector
+		if enc.perSampleIVSize == 0 {
+			iv = enc.constantIV
+		}
+
+		data, err := sr.ReadSampleData(sample)
+		if err != nil {
+			return err
+		}
+		if err := c.cryptSample(data, iv, aux.SubsampleEntries); err != nil {
+			return fmt.Errorf("failed to decrypt sample: trackID=%d, sample=%d: %w", trackID, sample.Index, err)
+		}
+		if _, err := w.Seek(int64(sample.Offset), io.SeekStart); err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadSampleAuxInfo returns the sample auxiliary information from the senc box, or from the data referred by saiz and saio boxes.
+// ivSize returns the per-sample IV size of the sample of the index in the container.
+func loadSampleAuxInfo(r io.ReadSeeker, auxInfo *sampleAuxInfo, sample *TrackSample, ivSize func(index int) int) ([]SencSampleEntry, error) {
+	if auxInfo == nil {
+		return nil, errors.New("sample auxiliary information not found")
+	}
+	if auxInfo.senc != nil {
+		return readSencEntries(r, auxInfo.senc, ivSize)
+	}
+	if auxInfo.saiz == nil || auxInfo.saio == nil {
+		return nil, errors.New("senc box or saiz/saio boxes not found")
+	}
+	if auxInfo.saio.EntryCount != 1 {
+		return nil, fmt.Errorf("unsupported saio entry count: %d", auxInfo.saio.EntryCount)
+	}
+
+	offset := auxInfo.saio.GetOffset(0)
+	if sample.fragment != nil {
+		tfhd := sample.fragment.tfhd
+		if tfhd.CheckFlag(TfhdBaseDataOffsetPresent) {
+			offset += tfhd.BaseDataOffset
+		} else {
+			offset += sample.fragment.moof.Offset
+		}
+	}
+
+	saiz := auxInfo.saiz
+	var total uint64
+	for i := uint32(0); i < saiz.SampleCount; i++ {
+		total += uint64(saizSampleInfoSize(saiz, int(i)))
+	}
+	if _, err := r.Seek(int64(offset), io.SeekStart); err != nil {
+		return nil, err
+	}
+	data := make([]byte, total)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+
+	entries := make([]SencSampleEntry, saiz.SampleCount)
+	buf := bytes.NewReader(data)
+	for i := range entries {
+		size := int(saizSampleInfoSize(saiz, i))
+		if size < ivSize(i) {
+			return nil, fmt.Errorf("invalid sample auxiliary information size: %d", size)
+		}
+		subsamples := size > ivSize(i)
+		if err := readSencEntry(buf, &entries[i], ivSize(i), subsamples); err != nil {
+			return nil, err
+		}
+		if !subsamples {
+			continue
+		}
+		if skip := size - ivSize(i) - 2 - 6*int(entries[i].SubsampleCount); skip > 0 {
+			if _, err := buf.Seek(int64(skip), io.SeekCurrent); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return entries, nil
+}
+
+// readSencEntries reads the entries of the senc box with the per-sample IV sizes.
+func readSencEntries(r io.ReadSeeker, bi *BoxInfo, ivSize func(index int) int) ([]SencSampleEntry, error) {
+	if _, err := bi.SeekToPayload(r); err != nil {
+		return nil, err
+	}
+	data := make([]byte, bi.Size-bi.HeaderSize)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	if len(data) < 8 {
+		return nil, errors.New("invalid senc box size")
+	}
+	subsamples := data[3]&0x02 != 0
+	entries := make([]SencSampleEntry, binary.BigEndian.Uint32(data[4:8]))
+	buf := bytes.NewReader(data[8:])
+	for i := range entries {
+		if err := readSencEntry(buf, &entries[i], ivSize(i), subsamples); err != nil {
+			return nil, fmt.Errorf("failed to read senc box: %w", err)
+		}
+	}
+	return entries, nil
+}
+
+// readSencEntry reads the IV and the subsample entries of a sample.
+func readSencEntry(buf io.Reader, entry *SencSampleEntry, ivSize int, subsamples bool) error {
+	entry.InitializationVector = make([]byte, ivSize)
+	if _, err := io.ReadFull(buf, entry.InitializationVector); err != nil {
+		return err
+	}
+	if !subsamples {
+		return nil
+	}
+	if err := binary.Read(buf, binary.BigEndian, &entry.SubsampleCount); err != nil {
+		return err
+	}
+	entry.SubsampleEntries = make([]SubsampleEntry, entry.SubsampleCount)
+	for j := range entry.SubsampleEntries {
+		if err := binary.Read(buf, binary.BigEndian, &entry.SubsampleEntries[j].BytesOfClearData); err != nil {
+			return err
+		}
+		if err := binary.Read(buf, binary.BigEndian, &entry.SubsampleEntries[j].BytesOfProtectedData); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func saizSampleInfoSize(saiz *Saiz, index int) uint8 {
+	if saiz.DefaultSampleInfoSize != 0 {
+		return saiz.DefaultSampleInfoSize
+	}
+	return saiz.SampleInfoSize[index]
+}
+
+// writeBoxType overwrites the type field of the box.
+func writeBoxType(w io.WriteSeeker, bi *BoxInfo, boxType BoxType) error {
+	if _, err := w.Seek(int64(bi.Offset)+4, io.SeekStart); err != nil {
+		return err
+	}
+	_, err := w.Write(boxType[:])
+	return err
+}
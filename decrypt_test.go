@@ -0,0 +1,250 @@
+package mp4
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/orcaman/writerseeker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecrypt(t *testing.T) {
+	kid := [16]byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef, 0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef}
+	key := []byte{0x10, 0x32, 0x54, 0x76, 0x98, 0xba, 0xdc, 0xfe, 0x10, 0x32, 0x54, 0x76, 0x98, 0xba, 0xdc, 0xfe}
+
+	init, err := os.ReadFile("./testdata/sample_init.encv.mp4")
+	require.NoError(t, err)
+
+	plain := [][]byte{
+		bytes.Repeat([]byte{0x00, 0x00, 0x00, 0x1c, 0x65, 0x88, 0x84, 0x00}, 4),
+		bytes.Repeat([]byte{0x00, 0x00, 0x00, 0x14, 0x41, 0x9a, 0x02, 0x03, 0x04, 0x05}, 3),
+	}
+	senc := &Senc{
+		FullBox:     FullBox{Flags: [3]byte{0x00, 0x00, 0x02}},
+		SampleCount: 2,
+		SampleEntries: []SencSampleEntry{
+			{
+				InitializationVector: bytes.Repeat([]byte{0x11}, 16),
+				SubsampleCount:       1,
+				SubsampleEntries:     []SubsampleEntry{{BytesOfClearData: 5, BytesOfProtectedData: 27}},
+			},
+			{
+				InitializationVector: bytes.Repeat([]byte{0x22}, 16),
+				SubsampleCount:       1,
+				SubsampleEntries:     []SubsampleEntry{{BytesOfClearData: 5, BytesOfProtectedData: 25}},
+			},
+		},
+	}
+	c, err := newCencCipher(SchemeTypeCENC(), key, 0, 0, false)
+	require.NoError(t, err)
+	encrypted := make([]byte, 0)
+	for i := range plain {
+		data := append([]byte(nil), plain[i]...)
+		require.NoError(t, c.cryptSample(data, senc.SampleEntries[i].InitializationVector, senc.SampleEntries[i].SubsampleEntries))
+		encrypted = append(encrypted, data...)
+	}
+
+	crypto := NewCryptoContext()
+	crypto.TencRegistry[1] = &Tenc{DefaultIsProtected: 1, DefaultPerSampleIVSize: 16, DefaultKID: kid}
+	ctx := Context{TrackID: 1, Crypto: crypto}
+	buildMoof := func(dataOffset int32) []byte {
+		buf := &writerseeker.WriterSeeker{}
+		w := NewWriter(buf)
+		writeTestBox(t, w, &Moof{}, ctx, func() {
+			writeTestBox(t, w, &Mfhd{SequenceNumber: 1}, ctx, nil)
+			writeTestBox(t, w, &Traf{}, ctx, func() {
+				writeTestBox(t, w, &Tfhd{
+					FullBox: FullBox{Flags: [3]byte{0x02, 0x00, 0x00}},
+					TrackID: 1,
+				}, ctx, nil)
+				writeTestBox(t, w, &Tfdt{}, ctx, nil)
+				writeTestBox(t, w, &Trun{
+					FullBox:     FullBox{Flags: [3]byte{0x00, 0x03, 0x01}},
+					SampleCount: 2,
+					DataOffset:  dataOffset,
+					Entries: []TrunEntry{
+						{SampleDuration: 3000, SampleSize: uint32(len(plain[0]))},
+						{SampleDuration: 3000, SampleSize: uint32(len(plain[1]))},
+					},
+				}, ctx, nil)
+				writeTestBox(t, w, senc, ctx, nil)
+			})
+		})
+		data, err := io.ReadAll(buf.BytesReader())
+		require.NoError(t, err)
+		return data
+	}
+	moof := buildMoof(0)
+	moof = buildMoof(int32(len(moof) + 8))
+
+	buf := &writerseeker.WriterSeeker{}
+	_, err = buf.Write(init)
+	require.NoError(t, err)
+	_, err = buf.Write(moof)
+	require.NoError(t, err)
+	w := NewWriter(buf)
+	writeTestBox(t, w, &Mdat{Data: encrypted}, Context{}, nil)
+	input := buf.BytesReader()
+
+	output := &writerseeker.WriterSeeker{}
+	require.Error(t, Decrypt(input, output, map[[16]byte][]byte{}))
+
+	output = &writerseeker.WriterSeeker{}
+	require.NoError(t, Decrypt(input, output, map[[16]byte][]byte{kid: key}))
+	result := output.BytesReader()
+
+	sr, err := NewSampleReader(result, 1)
+	require.NoError(t, err)
+	for i := range plain {
+		sample, err := sr.Next()
+		require.NoError(t, err)
+		assert.Equal(t, plain[i], sample.Data)
+	}
+
+	bis, err := ExtractBoxes(result, nil, []BoxPath{
+		{BoxTypeMoov(), BoxTypeTrak(), BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeAny()},
+		{BoxTypeMoov(), BoxTypePssh()},
+		{BoxTypeMoof(), BoxTypeTraf(), BoxTypeSenc()},
+	})
+	require.NoError(t, err)
+	require.Len(t, bis, 2)
+	assert.Equal(t, BoxTypeAvc1(), bis[0].Type)
+	assert.Equal(t, BoxTypeMp4a(), bis[1].Type)
+}
+
+func writeTestBox(t *testing.T, w *Writer, box IBox, ctx Context, children func()) {
+	_, err := w.StartBox(&BoxInfo{Type: box.GetType()})
+	require.NoError(t, err)
+	_, err = Marshal(w, box, ctx)
+	require.NoError(t, err)
+	if children != nil {
+		children()
+	}
+	_, err = w.EndBox()
+	require.NoError(t, err)
+}
+
+func TestDecryptSampleGroups(t *testing.T) {
+	kid := [16]byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef, 0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef}
+	key := []byte{0x10, 0x32, 0x54, 0x76, 0x98, 0xba, 0xdc, 0xfe, 0x10, 0x32, 0x54, 0x76, 0x98, 0xba, 0xdc, 0xfe}
+	rotatedKID := [16]byte{0xfe, 0xdc, 0xba, 0x98, 0x76, 0x54, 0x32, 0x10, 0xfe, 0xdc, 0xba, 0x98, 0x76, 0x54, 0x32, 0x10}
+	rotatedKey := []byte{0xef, 0xcd, 0xab, 0x89, 0x67, 0x45, 0x23, 0x01, 0xef, 0xcd, 0xab, 0x89, 0x67, 0x45, 0x23, 0x01}
+
+	init, err := os.ReadFile("./testdata/sample_init.encv.mp4")
+	require.NoError(t, err)
+
+	// the clear lead sample, the sample encrypted with the default key of tenc, and the sample encrypted with the rotated key
+	plain := [][]byte{
+		bytes.Repeat([]byte{0x00, 0x00, 0x00, 0x1c, 0x65, 0x88, 0x84, 0x00}, 4),
+		bytes.Repeat([]byte{0x00, 0x00, 0x00, 0x14, 0x41, 0x9a, 0x02, 0x03, 0x04, 0x05}, 3),
+		bytes.Repeat([]byte{0x00, 0x00, 0x00, 0x14, 0x41, 0x9a, 0x06, 0x07, 0x08, 0x09}, 3),
+	}
+	ivs := [][]byte{nil, bytes.Repeat([]byte{0x11}, 16), bytes.Repeat([]byte{0x22}, 8)}
+	subsamples := [][]SubsampleEntry{
+		nil,
+		{{BytesOfClearData: 5, BytesOfProtectedData: 25}},
+		{{BytesOfClearData: 5, BytesOfProtectedData: 25}},
+	}
+	encrypted := append([]byte(nil), plain[0]...)
+	for i, k := range [][]byte{key, rotatedKey} {
+		c, err := newCencCipher(SchemeTypeCENC(), k, 0, 0, false)
+		require.NoError(t, err)
+		data := append([]byte(nil), plain[i+1]...)
+		require.NoError(t, c.cryptSample(data, ivs[i+1], subsamples[i+1]))
+		encrypted = append(encrypted, data...)
+	}
+
+	// the IV sizes of the senc box differ for each sample
+	senc := []byte{0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x03}
+	for i := range plain {
+		senc = append(senc, ivs[i]...)
+		senc = append(senc, byte(len(subsamples[i])>>8), byte(len(subsamples[i])))
+		for _, subsample := range subsamples[i] {
+			senc = append(senc, byte(subsample.BytesOfClearData>>8), byte(subsample.BytesOfClearData))
+			senc = append(senc,
+				byte(subsample.BytesOfProtectedData>>24), byte(subsample.BytesOfProtectedData>>16),
+				byte(subsample.BytesOfProtectedData>>8), byte(subsample.BytesOfProtectedData))
+		}
+	}
+
+	ctx := Context{TrackID: 1}
+	buildMoof := func(dataOffset int32) []byte {
+		buf := &writerseeker.WriterSeeker{}
+		w := NewWriter(buf)
+		writeTestBox(t, w, &Moof{}, ctx, func() {
+			writeTestBox(t, w, &Mfhd{SequenceNumber: 1}, ctx, nil)
+			writeTestBox(t, w, &Traf{}, ctx, func() {
+				writeTestBox(t, w, &Tfhd{
+					FullBox: FullBox{Flags: [3]byte{0x02, 0x00, 0x00}},
+					TrackID: 1,
+				}, ctx, nil)
+				writeTestBox(t, w, &Tfdt{}, ctx, nil)
+				trun := &Trun{
+					FullBox:     FullBox{Flags: [3]byte{0x00, 0x03, 0x01}},
+					SampleCount: uint32(len(plain)),
+					DataOffset:  dataOffset,
+				}
+				for i := range plain {
+					trun.Entries = append(trun.Entries, TrunEntry{SampleDuration: 3000, SampleSize: uint32(len(plain[i]))})
+				}
+				writeTestBox(t, w, trun, ctx, nil)
+				_, err := w.StartBox(&BoxInfo{Type: BoxTypeSenc()})
+				require.NoError(t, err)
+				_, err = w.Write(senc)
+				require.NoError(t, err)
+				_, err = w.EndBox()
+				require.NoError(t, err)
+				writeTestBox(t, w, &Sbgp{
+					GroupingType: binary.BigEndian.Uint32([]byte("seig")),
+					EntryCount:   3,
+					Entries: []SbgpEntry{
+						{SampleCount: 1, GroupDescriptionIndex: 0x10001},
+						{SampleCount: 1, GroupDescriptionIndex: 0},
+						{SampleCount: 1, GroupDescriptionIndex: 0x10002},
+					},
+				}, ctx, nil)
+				writeTestBox(t, w, &Sgpd{
+					FullBox:      FullBox{Version: 1},
+					GroupingType: [4]byte{'s', 'e', 'i', 'g'},
+					EntryCount:   2,
+					Entries: []ISampleGroupEntry{
+						&CencSampleEncryptionInformationGroupEntry{},
+						&CencSampleEncryptionInformationGroupEntry{IsProtected: 1, PerSampleIVSize: 8, KID: rotatedKID},
+					},
+				}, ctx, nil)
+			})
+		})
+		data, err := io.ReadAll(buf.BytesReader())
+		require.NoError(t, err)
+		return data
+	}
+	moof := buildMoof(0)
+	moof = buildMoof(int32(len(moof) + 8))
+
+	buf := &writerseeker.WriterSeeker{}
+	_, err = buf.Write(init)
+	require.NoError(t, err)
+	_, err = buf.Write(moof)
+	require.NoError(t, err)
+	writeTestBox(t, NewWriter(buf), &Mdat{Data: encrypted}, Context{}, nil)
+	input := buf.BytesReader()
+
+	// the key of the rotated KID is required
+	err = Decrypt(input, &writerseeker.WriterSeeker{}, map[[16]byte][]byte{kid: key})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "fedcba98-7654-3210-fedc-ba9876543210")
+
+	output := &writerseeker.WriterSeeker{}
+	require.NoError(t, Decrypt(input, output, map[[16]byte][]byte{kid: key, rotatedKID: rotatedKey}))
+	sr, err := NewSampleReader(output.BytesReader(), 1)
+	require.NoError(t, err)
+	for i := range plain {
+		sample, err := sr.Next()
+		require.NoError(t, err)
+		assert.Equal(t, plain[i], sample.Data, "sample %d", i)
+	}
+}
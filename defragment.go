@@ -0,0 +1,435 @@
+package mp4
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/orcaman/writerseeker"
+)
+
+// DefragmentConfig is the configuration of Defragment.
+type DefragmentConfig struct {
+	// FastStart represents whether to place the moov box before the mdat box.
+	FastStart bool
+
+	// ChunkDuration is the maximum duration of chunks which are interleaved in the mdat box.
+	// One second is used when it is zero.
+	ChunkDuration time.Duration
+}
+
+type defragmentTrack struct {
+	trackID   uint32
+	timescale uint32
+	samples   []*TrackSample
+	chunks    []*sampleChunk
+	duration  uint64
+}
+
+type defragmentChunk struct {
+	*sampleChunk
+	track *defragmentTrack
+}
+
+// sampleChunk is a run of contiguous samples of a track in a mdat box.
+type sampleChunk struct {
+	samples []*TrackSample
+	offset  uint64
+}
+
+type defragmenter struct {
+	r         io.ReadSeeker
+	w         *Writer
+	config    *DefragmentConfig
+	tracks    []*defragmentTrack
+	chunks    []*defragmentChunk
+	timescale uint32
+}
+
+// Defragment converts the fragmented file which consists of an init segment and movie fragments
+// into the progressive file which has a single moov box and a single interleaved mdat box.
+// Sample tables and durations in mvhd, tkhd and mdhd boxes are rebuilt from moof boxes.
+// Protected tracks are not supported, because their sample auxiliary information and sample groups are not carried over.
+func Defragment(r io.ReadSeeker, w io.WriteSeeker, config *DefragmentConfig) error {
+	d := &defragmenter{
+		r:      r,
+		w:      NewWriter(w),
+		config: config,
+	}
+	if err := d.readTracks(); err != nil {
+		return err
+	}
+	d.buildChunks()
+
+	var dataSize uint64
+	for _, chunk := range d.chunks {
+		for _, s := range chunk.samples {
+			dataSize += uint64(s.Size)
+		}
+	}
+	mdatHeaderSize := uint64(SmallHeaderSize)
+	if dataSize+SmallHeaderSize > math.MaxUint32 {
+		mdatHeaderSize = LargeHeaderSize
+	}
+
+	ftyp, err := d.readFtyp()
+	if err != nil {
+		return err
+	}
+	if err := d.w.CopyBox(r, ftyp); err != nil {
+		return err
+	}
+
+	var moov []byte
+	if d.config.FastStart {
+		// chunk offsets depend on the size of the moov box, and the size depends on whether co64 is used
+		for {
+			d.setChunkOffsets(ftyp.Size + uint64(len(moov)) + mdatHeaderSize)
+			newMoov, err := d.buildMoov()
+			if err != nil {
+				return err
+			}
+			converged := len(newMoov) == len(moov)
+			moov = newMoov
+			if converged {
+				break
+			}
+		}
+		if _, err := d.w.Write(moov); err != nil {
+			return err
+		}
+	} else {
+		d.setChunkOffsets(ftyp.Size + mdatHeaderSize)
+	}
+
+	if _, err := d.w.StartBox(&BoxInfo{Type: BoxTypeMdat(), HeaderSize: mdatHeaderSize}); err != nil {
+		return err
+	}
+	for _, chunk := range d.chunks {
+		for _, s := range chunk.samples {
+			if _, err := r.Seek(int64(s.Offset), io.SeekStart); err != nil {
+				return err
+			}
+			if _, err := io.CopyN(d.w, r, int64(s.Size)); err != nil {
+				return err
+			}
+		}
+	}
+	if _, err := d.w.EndBox(); err != nil {
+		return err
+	}
+
+	if !d.config.FastStart {
+		if moov, err = d.buildMoov(); err != nil {
+			return err
+		}
+		if _, err := d.w.Write(moov); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *defragmenter) readTracks() error {
+	bips, err := ExtractBoxesWithPayload(d.r, nil, []BoxPath{
+		{BoxTypeMoov(), BoxTypeMvhd()},
+		{BoxTypeMoov(), BoxTypeTrak(), BoxTypeTkhd()},
+		{BoxTypeMoov(), BoxTypeTrak(), BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeEncv()},
+		{BoxTypeMoov(), BoxTypeTrak(), BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeEnca()},
+	})
+	if err != nil {
+		return err
+	}
+	for _, bip := range bips {
+		switch box := bip.Payload.(type) {
+		case *Mvhd:
+			d.timescale = box.Timescale
+		case *Tkhd:
+			d.tracks = append(d.tracks, &defragmentTrack{trackID: box.TrackID})
+		default:
+			if n := len(d.tracks); n != 0 {
+				return fmt.Errorf("protected track is not supported: trackID=%d", d.tracks[n-1].trackID)
+			}
+			return errors.New("protected track is not supported")
+		}
+	}
+	if d.timescale == 0 {
+		return errors.New("mvhd box not found")
+	}
+
+	for _, track := range d.tracks {
+		sr, err := NewSampleReader(d.r, track.trackID)
+		if err != nil {
+			return err
+		}
+		track.timescale = sr.Timescale
+		track.samples = sr.Samples()
+		for _, s := range track.samples {
+			track.duration += uint64(s.Duration)
+		}
+	}
+	return nil
+}
+
+func (d *defragmenter) readFtyp() (*BoxInfo, error) {
+	bis, err := ExtractBox(d.r, nil, BoxPath{BoxTypeFtyp()})
+	if err != nil {
+		return nil, err
+	}
+	if len(bis) == 0 {
+		return nil, errors.New("ftyp box not found")
+	}
+	return bis[0], nil
+}
+
+// buildChunks groups samples into chunks, and sorts them by their start times.
+func (d *defragmenter) buildChunks() {
+	chunkDuration := d.config.ChunkDuration
+	if chunkDuration <= 0 {
+		chunkDuration = time.Second
+	}
+	for _, track := range d.tracks {
+		max := uint64(chunkDuration) * uint64(track.timescale) / uint64(time.Second)
+		var chunk *sampleChunk
+		for _, s := range track.samples {
+			if chunk == nil ||
+				s.DTS-chunk.samples[0].DTS >= max ||
+				s.SampleDescriptionIndex != chunk.samples[0].SampleDescriptionIndex {
+				chunk = &sampleChunk{}
+				track.chunks = append(track.chunks, chunk)
+				d.chunks = append(d.chunks, &defragmentChunk{sampleChunk: chunk, track: track})
+			}
+			chunk.samples = append(chunk.samples, s)
+		}
+	}
+	sort.SliceStable(d.chunks, func(i, j int) bool {
+		ci, cj := d.chunks[i], d.chunks[j]
+		return ci.samples[0].DTS*uint64(cj.track.timescale) < cj.samples[0].DTS*uint64(ci.track.timescale)
+	})
+}
+
+func (d *defragmenter) setChunkOffsets(offset uint64) {
+	for _, chunk := range d.chunks {
+		chunk.offset = offset
+		for _, s := range chunk.samples {
+			offset += uint64(s.Size)
+		}
+	}
+}
+
+func (d *defragmenter) movieDuration(track *defragmentTrack) uint64 {
+	if track.timescale == 0 {
+		return 0
+	}
+	return track.duration * uint64(d.timescale) / uint64(track.timescale)
+}
+
+func (d *defragmenter) buildMoov() ([]byte, error) {
+	bis, err := ExtractBox(d.r, nil, BoxPath{BoxTypeMoov()})
+	if err != nil {
+		return nil, err
+	}
+	if len(bis) != 1 {
+		return nil, errors.New("moov box not found")
+	}
+
+	buf := &writerseeker.WriterSeeker{}
+	w := NewWriter(buf)
+	var track *defragmentTrack
+	_, err = ReadBoxStructureFromInternal(d.r, bis[0], func(h *ReadHandle) (interface{}, error) {
+		switch h.BoxInfo.Type {
+		case BoxTypeMoov(), BoxTypeTrak(), BoxTypeMdia(), BoxTypeMinf():
+			return nil, writeContainerBox(d.r, w, h, nil)
+		case BoxTypeStbl():
+			if track == nil {
+				return nil, errors.New("trak box has no tkhd box")
+			}
+			return nil, writeContainerBox(d.r, w, h, func() error {
+				return writeSampleTable(w, track.samples, track.chunks)
+			})
+		case BoxTypeMvhd():
+			box, _, err := h.ReadPayload()
+			if err != nil {
+				return nil, err
+			}
+			var duration uint64
+			for _, t := range d.tracks {
+				if dur := d.movieDuration(t); dur > duration {
+					duration = dur
+				}
+			}
+			mvhd := box.(*Mvhd)
+			if duration > math.MaxUint32 && mvhd.GetVersion() == 0 {
+				mvhd.SetVersion(1)
+				mvhd.CreationTimeV1 = uint64(mvhd.CreationTimeV0)
+				mvhd.ModificationTimeV1 = uint64(mvhd.ModificationTimeV0)
+			}
+			mvhd.DurationV0 = uint32(duration)
+			mvhd.DurationV1 = duration
+			return nil, writeBox(w, mvhd, h.BoxInfo.Context)
+		case BoxTypeTkhd():
+			box, _, err := h.ReadPayload()
+			if err != nil {
+				return nil, err
+			}
+			tkhd := box.(*Tkhd)
+			for _, t := range d.tracks {
+				if t.trackID == tkhd.TrackID {
+					track = t
+				}
+			}
+			duration := d.movieDuration(track)
+			if duration > math.MaxUint32 && tkhd.GetVersion() == 0 {
+				tkhd.SetVersion(1)
+				tkhd.CreationTimeV1 = uint64(tkhd.CreationTimeV0)
+				tkhd.ModificationTimeV1 = uint64(tkhd.ModificationTimeV0)
+			}
+			tkhd.DurationV0 = uint32(duration)
+			tkhd.DurationV1 = duration
+			return nil, writeBox(w, tkhd, h.BoxInfo.Context)
+		case BoxTypeMdhd():
+			box, _, err := h.ReadPayload()
+			if err != nil {
+				return nil, err
+			}
+			mdhd := box.(*Mdhd)
+			if track.duration > math.MaxUint32 && mdhd.GetVersion() == 0 {
+				mdhd.SetVersion(1)
+				mdhd.CreationTimeV1 = uint64(mdhd.CreationTimeV0)
+				mdhd.ModificationTimeV1 = uint64(mdhd.ModificationTimeV0)
+			}
+			mdhd.DurationV0 = uint32(track.duration)
+			mdhd.DurationV1 = track.duration
+			return nil, writeBox(w, mdhd, h.BoxInfo.Context)
+		case BoxTypeMvex(),
+			BoxTypeStts(), BoxTypeCtts(), BoxTypeStss(), BoxTypeStsc(), BoxTypeStsz(), BoxTypeStz2(),
+			BoxTypeStco(), BoxTypeCo64(), BoxTypeSdtp(), BoxTypeSbgp(), BoxTypeSubs(), BoxTypeSaiz(), BoxTypeSaio():
+			// these boxes are rebuilt or no longer needed
+			return nil, nil
+		}
+		return nil, w.CopyBox(d.r, &h.BoxInfo)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(buf.BytesReader())
+}
+
+// writeSampleTable writes stts, ctts, stss, stsc, stsz and stco (or co64) boxes of the samples.
+func writeSampleTable(w *Writer, samples []*TrackSample, chunks []*sampleChunk) error {
+	stts := &Stts{}
+	for _, s := range samples {
+		if n := len(stts.Entries); n != 0 && stts.Entries[n-1].SampleDelta == s.Duration {
+			stts.Entries[n-1].SampleCount++
+		} else {
+			stts.Entries = append(stts.Entries, SttsEntry{SampleCount: 1, SampleDelta: s.Duration})
+		}
+	}
+	stts.EntryCount = uint32(len(stts.Entries))
+	if err := writeBox(w, stts, Context{}); err != nil {
+		return err
+	}
+
+	var hasCtts, negativeCtts bool
+	for _, s := range samples {
+		if offset := s.PTS - int64(s.DTS); offset != 0 {
+			hasCtts = true
+			negativeCtts = negativeCtts || offset < 0
+		}
+	}
+	if hasCtts {
+		ctts := &Ctts{}
+		if negativeCtts {
+			ctts.SetVersion(1)
+		}
+		var prev int64
+		for _, s := range samples {
+			offset := s.PTS - int64(s.DTS)
+			if n := len(ctts.Entries); n != 0 && prev == offset {
+				ctts.Entries[n-1].SampleCount++
+			} else {
+				ctts.Entries = append(ctts.Entries, CttsEntry{
+					SampleCount:    1,
+					SampleOffsetV0: uint32(offset),
+					SampleOffsetV1: int32(offset),
+				})
+			}
+			prev = offset
+		}
+		ctts.EntryCount = uint32(len(ctts.Entries))
+		if err := writeBox(w, ctts, Context{}); err != nil {
+			return err
+		}
+	}
+
+	stss := &Stss{}
+	for i, s := range samples {
+		if s.IsSync {
+			stss.SampleNumber = append(stss.SampleNumber, uint32(i+1))
+		}
+	}
+	if len(stss.SampleNumber) != len(samples) {
+		stss.EntryCount = uint32(len(stss.SampleNumber))
+		if err := writeBox(w, stss, Context{}); err != nil {
+			return err
+		}
+	}
+
+	stsc := &Stsc{}
+	for i, chunk := range chunks {
+		n := len(stsc.Entries)
+		descIndex := chunk.samples[0].SampleDescriptionIndex
+		if n != 0 && stsc.Entries[n-1].SamplesPerChunk == uint32(len(chunk.samples)) &&
+			stsc.Entries[n-1].SampleDescriptionIndex == descIndex {
+			continue
+		}
+		stsc.Entries = append(stsc.Entries, StscEntry{
+			FirstChunk:             uint32(i + 1),
+			SamplesPerChunk:        uint32(len(chunk.samples)),
+			SampleDescriptionIndex: descIndex,
+		})
+	}
+	stsc.EntryCount = uint32(len(stsc.Entries))
+	if err := writeBox(w, stsc, Context{}); err != nil {
+		return err
+	}
+
+	stsz := &Stsz{SampleCount: uint32(len(samples))}
+	for i, s := range samples {
+		if i == 0 || s.Size == stsz.SampleSize {
+			stsz.SampleSize = s.Size
+			continue
+		}
+		stsz.SampleSize = 0
+		break
+	}
+	if stsz.SampleSize == 0 {
+		stsz.EntrySize = make([]uint32, len(samples))
+		for i, s := range samples {
+			stsz.EntrySize[i] = s.Size
+		}
+	}
+	if err := writeBox(w, stsz, Context{}); err != nil {
+		return err
+	}
+
+	var useCo64 bool
+	for _, chunk := range chunks {
+		useCo64 = useCo64 || chunk.offset > math.MaxUint32
+	}
+	if useCo64 {
+		co64 := &Co64{EntryCount: uint32(len(chunks))}
+		for _, chunk := range chunks {
+			co64.ChunkOffset = append(co64.ChunkOffset, chunk.offset)
+		}
+		return writeBox(w, co64, Context{})
+	}
+	stco := &Stco{EntryCount: uint32(len(chunks))}
+	for _, chunk := range chunks {
+		stco.ChunkOffset = append(stco.ChunkOffset, uint32(chunk.offset))
+	}
+	return writeBox(w, stco, Context{})
+}
@@ -0,0 +1,89 @@
+package mp4
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/orcaman/writerseeker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefragment(t *testing.T) {
+	f, err := os.Open("./testdata/sample_fragmented.mp4")
+	require.NoError(t, err)
+	defer f.Close()
+
+	for _, fastStart := range []bool{false, true} {
+		output := &writerseeker.WriterSeeker{}
+		require.NoError(t, Defragment(f, output, &DefragmentConfig{FastStart: fastStart}))
+		r := output.BytesReader()
+
+		bis, err := ExtractBoxes(r, nil, []BoxPath{
+			{BoxTypeMoov()},
+			{BoxTypeMdat()},
+			{BoxTypeMoof()},
+			{BoxTypeMoov(), BoxTypeMvex()},
+		})
+		require.NoError(t, err)
+		require.Len(t, bis, 2)
+		if fastStart {
+			assert.Equal(t, BoxTypeMoov(), bis[0].Type)
+			assert.Equal(t, BoxTypeMdat(), bis[1].Type)
+		} else {
+			assert.Equal(t, BoxTypeMdat(), bis[0].Type)
+			assert.Equal(t, BoxTypeMoov(), bis[1].Type)
+		}
+
+		bips, err := ExtractBoxesWithPayload(r, nil, []BoxPath{
+			{BoxTypeMoov(), BoxTypeMvhd()},
+			{BoxTypeMoov(), BoxTypeTrak(), BoxTypeMdia(), BoxTypeMdhd()},
+		})
+		require.NoError(t, err)
+		require.Len(t, bips, 3)
+		assert.NotZero(t, bips[0].Payload.(*Mvhd).GetDuration())
+
+		for _, trackID := range []uint32{1, 2} {
+			frag, err := NewSampleReader(f, trackID)
+			require.NoError(t, err)
+			defrag, err := NewSampleReader(r, trackID)
+			require.NoError(t, err)
+			assert.Equal(t, frag.Timescale, defrag.Timescale)
+			require.Len(t, defrag.Samples(), len(frag.Samples()))
+
+			var duration uint64
+			for {
+				s1, err := frag.Next()
+				if err != nil {
+					break
+				}
+				s2, err := defrag.Next()
+				require.NoError(t, err)
+				assert.Equal(t, s1.Data, s2.Data)
+				assert.Equal(t, s1.DTS, s2.DTS)
+				assert.Equal(t, s1.PTS, s2.PTS)
+				assert.Equal(t, s1.Duration, s2.Duration)
+				assert.Equal(t, s1.IsSync, s2.IsSync)
+				duration += uint64(s1.Duration)
+			}
+			assert.Equal(t, duration, bips[trackID].Payload.(*Mdhd).GetDuration())
+		}
+	}
+}
+
+func TestDefragmentProtectedTrack(t *testing.T) {
+	f, err := os.Open("./testdata/sample_fragmented.mp4")
+	require.NoError(t, err)
+	defer f.Close()
+
+	encrypted := &writerseeker.WriterSeeker{}
+	require.NoError(t, Encrypt(f, encrypted, &EncryptConfig{
+		KID: [16]byte{0x01},
+		Key: bytes.Repeat([]byte{0x02}, 16),
+		IV:  []byte{1, 2, 3, 4, 5, 6, 7, 8},
+	}))
+	err = Defragment(encrypted.BytesReader(), &writerseeker.WriterSeeker{}, &DefragmentConfig{})
+	require.Error(t, err)
+	assert.Equal(t, "protected track is not supported: trackID=1", err.Error())
+}
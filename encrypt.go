@@ -0,0 +1,813 @@
+package mp4
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/orcaman/writerseeker"
+)
+
+// EncryptConfig is the configuration of Encrypt.
+type EncryptConfig struct {
+	// Scheme is the protection scheme type. SchemeTypeCENC() is used when it is zero.
+	Scheme [4]byte
+
+	// KID is the default key ID which is stored in tenc boxes.
+	KID [16]byte
+
+	// Key is the 16-byte AES key.
+	Key []byte
+
+	// IV is the initial IV, which must be 8 bytes for cenc and cens schemes and 16 bytes for cbc1 and cbcs schemes.
+	// IVs of samples are derived by incrementing it for each sample through all tracks, so that no IV is reused.
+	// With the cbcs scheme, it is used as the constant IV of the first track, and incremented for each following track.
+	// A random IV is generated when it is nil.
+	IV []byte
+
+	// CryptByteBlock and SkipByteBlock specify the pattern for video tracks of cens and cbcs schemes.
+	// 1:9 is used when both of them are zero.
+	CryptByteBlock uint8
+	SkipByteBlock  uint8
+
+	// TrackIDs specifies tracks to be encrypted.
+	// All of video and audio tracks are encrypted when it is empty.
+	TrackIDs []uint32
+
+	// Pssh boxes are inserted into the moov box.
+	Pssh []*Pssh
+}
+
+type encryptTrack struct {
+	trackID    uint32
+	video      bool
+	lengthSize int
+	hevc       bool
+	tenc       *Tenc
+	cipher     *cencCipher
+	iv         []byte
+
+	// parameter sets by their IDs, which are needed to find the ends of slice headers
+	avcSPS  map[uint32]*AVCSPS
+	avcPPS  map[uint32]*AVCPPS
+	hevcSPS map[uint32]*HEVCSPS
+	hevcPPS map[uint32]*HEVCPPS
+}
+
+type encryptSample struct {
+	sample *TrackSample
+	track  *encryptTrack
+	traf   uint64
+	index  int
+}
+
+type encrypter struct {
+	r      io.ReadSeeker
+	w      *Writer
+	config *EncryptConfig
+	scheme [4]byte
+	tracks map[uint32]*encryptTrack
+
+	// nextIV is the IV which is used for the next track.
+	nextIV []byte
+
+	// auxInfos maps offsets of traf boxes to the sample auxiliary information.
+	auxInfos map[uint64][]SencSampleEntry
+	samples  []*encryptSample
+
+	// shifts holds differences between offsets of the output and the input at the end of each top-level box.
+	shifts []offsetShift
+	sidxes []*sidxPatch
+}
+
+type offsetShift struct {
+	offset uint64
+	shift  int64
+}
+
+type sidxPatch struct {
+	in   BoxInfo
+	out  uint64
+	sidx *Sidx
+}
+
+// Encrypt encrypts the clear fragmented file in accordance with ISO/IEC 23001-7 and writes it to w.
+// Sample entries of the encrypted tracks are renamed to encv or enca and given sinf boxes,
+// and each traf box of them is given senc, saiz and saio boxes.
+// For AVC and HEVC tracks, slice headers of VCL NAL units and the other NAL units are left in the clear.
+// Slice headers are parsed with the parameter sets in the sample entry and in the samples.
+func Encrypt(r io.ReadSeeker, w io.WriteSeeker, config *EncryptConfig) error {
+	e := &encrypter{
+		r:        r,
+		w:        NewWriter(w),
+		config:   config,
+		scheme:   config.Scheme,
+		tracks:   make(map[uint32]*encryptTrack),
+		auxInfos: make(map[uint64][]SencSampleEntry),
+	}
+	if e.scheme == [4]byte{} {
+		e.scheme = SchemeTypeCENC()
+	}
+	if _, err := newCencCipher(e.scheme, config.Key, 0, 0, false); err != nil {
+		return err
+	}
+
+	if err := e.readTracks(); err != nil {
+		return err
+	}
+	// IVs are assigned in order of track IDs
+	trackIDs := make([]uint32, 0, len(e.tracks))
+	for trackID := range e.tracks {
+		trackIDs = append(trackIDs, trackID)
+	}
+	sort.Slice(trackIDs, func(i, j int) bool { return trackIDs[i] < trackIDs[j] })
+	for _, trackID := range trackIDs {
+		if err := e.prepareTrack(e.tracks[trackID]); err != nil {
+			return err
+		}
+	}
+
+	if _, err := ReadBoxStructure(r, func(h *ReadHandle) (interface{}, error) {
+		if err := e.writeTopLevelBox(h); err != nil {
+			return nil, err
+		}
+		end, err := e.w.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, err
+		}
+		inEnd := h.BoxInfo.Offset + h.BoxInfo.Size
+		e.shifts = append(e.shifts, offsetShift{offset: inEnd, shift: end - int64(inEnd)})
+		return nil, nil
+	}); err != nil {
+		return err
+	}
+
+	if err := e.encryptSamples(); err != nil {
+		return err
+	}
+	return e.patchSidxes()
+}
+
+func (e *encrypter) writeTopLevelBox(h *ReadHandle) error {
+	switch h.BoxInfo.Type {
+	case BoxTypeMoov():
+		return e.writeMoov(h)
+	case BoxTypeMoof():
+		return e.writeMoof(&h.BoxInfo)
+	case BoxTypeSidx():
+		box, _, err := h.ReadPayload()
+		if err != nil {
+			return err
+		}
+		out, err := e.w.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+		e.sidxes = append(e.sidxes, &sidxPatch{in: h.BoxInfo, out: uint64(out), sidx: box.(*Sidx)})
+	}
+	return e.w.CopyBox(e.r, &h.BoxInfo)
+}
+
+// readTracks collects tracks to be encrypted from sample entries in the moov box.
+func (e *encrypter) readTracks() error {
+	targets := make(map[uint32]bool, len(e.config.TrackIDs))
+	for _, trackID := range e.config.TrackIDs {
+		targets[trackID] = true
+	}
+
+	_, err := ReadBoxStructure(e.r, func(h *ReadHandle) (interface{}, error) {
+		trackID := h.BoxInfo.TrackID
+		if len(h.Path) >= 2 && h.Path[len(h.Path)-2] == BoxTypeStsd() {
+			if len(targets) != 0 && !targets[trackID] || !h.BoxInfo.IsSupportedType() {
+				return nil, nil
+			}
+			box, _, err := h.ReadPayload()
+			if err != nil {
+				return nil, err
+			}
+			switch box.(type) {
+			case *VisualSampleEntry:
+				e.tracks[trackID] = &encryptTrack{
+					trackID: trackID,
+					video:   true,
+					avcSPS:  make(map[uint32]*AVCSPS),
+					avcPPS:  make(map[uint32]*AVCPPS),
+					hevcSPS: make(map[uint32]*HEVCSPS),
+					hevcPPS: make(map[uint32]*HEVCPPS),
+				}
+				return h.Expand()
+			case *AudioSampleEntry:
+				e.tracks[trackID] = &encryptTrack{trackID: trackID}
+			}
+			return nil, nil
+		}
+
+		switch h.BoxInfo.Type {
+		case BoxTypeMoov(), BoxTypeTrak(), BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd():
+			return h.Expand()
+		case BoxTypeAvcC(), BoxTypeHvcC():
+			track := e.tracks[trackID]
+			if track == nil {
+				return nil, nil
+			}
+			box, _, err := h.ReadPayload()
+			if err != nil {
+				return nil, err
+			}
+			var parameterSets [][]byte
+			switch box := box.(type) {
+			case *AVCDecoderConfiguration:
+				track.lengthSize = int(box.LengthSizeMinusOne) + 1
+				for _, ps := range box.SequenceParameterSets {
+					parameterSets = append(parameterSets, ps.NALUnit)
+				}
+				for _, ps := range box.PictureParameterSets {
+					parameterSets = append(parameterSets, ps.NALUnit)
+				}
+			case *HvcC:
+				track.lengthSize = int(box.LengthSizeMinusOne) + 1
+				track.hevc = true
+				for _, array := range box.NaluArrays {
+					for _, nalu := range array.Nalus {
+						parameterSets = append(parameterSets, nalu.NALUnit)
+					}
+				}
+			}
+			for _, ps := range parameterSets {
+				if err := track.addParameterSet(ps); err != nil {
+					return nil, fmt.Errorf("failed to parse parameter set: trackID=%d: %w", trackID, err)
+				}
+			}
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for trackID := range targets {
+		if e.tracks[trackID] == nil {
+			return fmt.Errorf("track not found or not supported: trackID=%d", trackID)
+		}
+	}
+	for _, track := range e.tracks {
+		if track.video && track.lengthSize == 0 {
+			return fmt.Errorf("unsupported video codec for encryption: trackID=%d", track.trackID)
+		}
+	}
+	return nil
+}
+
+func (e *encrypter) prepareTrack(track *encryptTrack) error {
+	tenc := &Tenc{
+		DefaultIsProtected: 1,
+		DefaultKID:         e.config.KID,
+	}
+	var crypt, skip uint8
+	switch e.scheme {
+	case SchemeTypeCENS(), SchemeTypeCBCS():
+		tenc.SetVersion(1)
+		if track.video {
+			crypt, skip = e.config.CryptByteBlock, e.config.SkipByteBlock
+			if crypt == 0 && skip == 0 {
+				crypt, skip = 1, 9
+			}
+		}
+		tenc.DefaultCryptByteBlock = crypt
+		tenc.DefaultSkipByteBlock = skip
+	}
+
+	ivSize := 8
+	if e.scheme == SchemeTypeCBC1() || e.scheme == SchemeTypeCBCS() {
+		ivSize = 16
+	}
+	if e.nextIV == nil {
+		iv := e.config.IV
+		if iv == nil {
+			iv = make([]byte, ivSize)
+			if _, err := rand.Read(iv); err != nil {
+				return err
+			}
+		} else if len(iv) != ivSize {
+			// a 16-byte IV of cenc and cens schemes would overlap the block counter of AES-CTR
+			return fmt.Errorf("invalid IV size: %d, expected=%d", len(iv), ivSize)
+		}
+		e.nextIV = append([]byte(nil), iv...)
+	}
+	track.iv = append([]byte(nil), e.nextIV...)
+
+	if e.scheme == SchemeTypeCBCS() {
+		tenc.DefaultConstantIVSize = uint8(len(track.iv))
+		tenc.DefaultConstantIV = track.iv
+	} else {
+		tenc.DefaultPerSampleIVSize = uint8(len(track.iv))
+	}
+	track.tenc = tenc
+
+	c, err := newCencCipher(e.scheme, e.config.Key, crypt, skip, false)
+	if err != nil {
+		return err
+	}
+	track.cipher = c
+
+	sr, err := NewSampleReader(e.r, track.trackID)
+	if err != nil {
+		return err
+	}
+	iv := append([]byte(nil), track.iv...)
+	for _, sample := range sr.Samples() {
+		if sample.fragment == nil {
+			return fmt.Errorf("sample tables in moov box are not supported: trackID=%d", track.trackID)
+		}
+		aux := SencSampleEntry{}
+		if e.scheme != SchemeTypeCBCS() {
+			aux.InitializationVector = append([]byte(nil), iv...)
+			incrementIV(iv)
+		}
+		if track.video {
+			data, err := sr.ReadSampleData(sample)
+			if err != nil {
+				return err
+			}
+			aux.SubsampleEntries, err = track.subsamples(data)
+			if err != nil {
+				return fmt.Errorf("failed to parse NAL units: trackID=%d, sample=%d: %w", track.trackID, sample.Index, err)
+			}
+			aux.SubsampleCount = uint16(len(aux.SubsampleEntries))
+		}
+		traf := sample.fragment.traf.Offset
+		e.auxInfos[traf] = append(e.auxInfos[traf], aux)
+		e.samples = append(e.samples, &encryptSample{
+			sample: sample,
+			track:  track,
+			traf:   traf,
+			index:  len(e.auxInfos[traf]) - 1,
+		})
+	}
+
+	// the next track continues the sequence of IVs
+	if e.scheme == SchemeTypeCBCS() {
+		incrementIV(e.nextIV)
+	} else {
+		e.nextIV = iv
+	}
+	return nil
+}
+
+// incrementIV increments the IV as a big-endian integer.
+func incrementIV(iv []byte) {
+	for i := len(iv) - 1; i >= 0; i-- {
+		iv[i]++
+		if iv[i] != 0 {
+			return
+		}
+	}
+}
+
+// addParameterSet parses the SPS or PPS NAL unit and keeps it by its ID.
+// The other NAL units are ignored.
+func (track *encryptTrack) addParameterSet(nalu []byte) error {
+	if len(nalu) == 0 {
+		return nil
+	}
+	if track.hevc {
+		switch (nalu[0] >> 1) & 0x3f {
+		case 33:
+			sps, err := ParseHEVCSPS(nalu)
+			if err != nil {
+				return err
+			}
+			track.hevcSPS[sps.SeqParameterSetID] = sps
+		case 34:
+			pps, err := ParseHEVCPPS(nalu)
+			if err != nil {
+				return err
+			}
+			track.hevcPPS[pps.PicParameterSetID] = pps
+		}
+		return nil
+	}
+	switch nalu[0] & 0x1f {
+	case 7:
+		sps, err := ParseAVCSPS(nalu)
+		if err != nil {
+			return err
+		}
+		track.avcSPS[sps.SeqParameterSetID] = sps
+	case 8:
+		// the scaling matrix of the PPS depends on the SPS
+		pps, err := ParseAVCPPS(nalu, nil)
+		if err != nil {
+			return err
+		}
+		if sps := track.avcSPS[pps.SeqParameterSetID]; sps != nil {
+			if pps, err = ParseAVCPPS(nalu, sps); err != nil {
+				return err
+			}
+		}
+		track.avcPPS[pps.PicParameterSetID] = pps
+	}
+	return nil
+}
+
+// subsamples returns subsample entries which leave NAL unit lengths, non-VCL NAL units and slice headers in the clear.
+// Protected ranges start at the first byte of slice data with the cbcs scheme,
+// whose pattern leaves a trailing partial block in the clear.
+// With the other schemes, the sizes of protected ranges are rounded down to multiples of the AES block size.
+func (track *encryptTrack) subsamples(data []byte) ([]SubsampleEntry, error) {
+	entries := make([]SubsampleEntry, 0, 2)
+	var clear int
+	appendEntry := func(protected int) {
+		for clear > 0xffff {
+			entries = append(entries, SubsampleEntry{BytesOfClearData: 0xffff})
+			clear -= 0xffff
+		}
+		entries = append(entries, SubsampleEntry{
+			BytesOfClearData:     uint16(clear),
+			BytesOfProtectedData: uint32(protected),
+		})
+		clear = 0
+	}
+
+	for offset := 0; offset < len(data); {
+		if offset+track.lengthSize > len(data) {
+			return nil, errors.New("truncated NAL unit length")
+		}
+		var length int
+		for i := 0; i < track.lengthSize; i++ {
+			length = length<<8 | int(data[offset+i])
+		}
+		offset += track.lengthSize
+		if length == 0 || offset+length > len(data) {
+			return nil, fmt.Errorf("invalid NAL unit length: %d", length)
+		}
+
+		nalu := data[offset : offset+length]
+		var vcl bool
+		if track.hevc {
+			vcl = (nalu[0]>>1)&0x3f < 32
+		} else {
+			nalType := nalu[0] & 0x1f
+			vcl = nalType >= 1 && nalType <= 5
+		}
+
+		protected := 0
+		if vcl {
+			var header int
+			var err error
+			if track.hevc {
+				header, err = hevcSliceHeaderSize(nalu, track.hevcSPS, track.hevcPPS)
+			} else {
+				header, err = avcSliceHeaderSize(nalu, track.avcSPS, track.avcPPS)
+			}
+			if err != nil {
+				return nil, err
+			}
+			protected = length - header
+			if track.cipher.scheme != SchemeTypeCBCS() {
+				protected = protected / cencBlockSize * cencBlockSize
+			}
+		} else if err := track.addParameterSet(nalu); err != nil {
+			return nil, err
+		}
+		clear += track.lengthSize + length - protected
+		if protected != 0 {
+			appendEntry(protected)
+		}
+		offset += length
+	}
+	if clear != 0 || len(entries) == 0 {
+		appendEntry(0)
+	}
+	return entries, nil
+}
+
+func (e *encrypter) writeMoov(h *ReadHandle) error {
+	_, err := ReadBoxStructureFromInternal(e.r, &h.BoxInfo, func(h *ReadHandle) (interface{}, error) {
+		var entry *encryptTrack
+		if len(h.Path) >= 2 && h.Path[len(h.Path)-2] == BoxTypeStsd() {
+			entry = e.tracks[h.BoxInfo.TrackID]
+		}
+		switch {
+		case entry != nil:
+			return nil, e.writeProtectedSampleEntry(h, entry)
+		case len(h.Path) == 1 && h.BoxInfo.Type == BoxTypeMoov():
+			return nil, writeContainerBox(e.r, e.w, h, func() error {
+				for _, pssh := range e.config.Pssh {
+					if err := writeBox(e.w, pssh, Context{}); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		}
+		switch h.BoxInfo.Type {
+		case BoxTypeTrak(), BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd():
+			return nil, writeContainerBox(e.r, e.w, h, nil)
+		}
+		return nil, e.w.CopyBox(e.r, &h.BoxInfo)
+	})
+	return err
+}
+
+func (e *encrypter) writeProtectedSampleEntry(h *ReadHandle, track *encryptTrack) error {
+	boxType := BoxTypeEnca()
+	if track.video {
+		boxType = BoxTypeEncv()
+	}
+	if _, err := e.w.StartBox(&BoxInfo{Type: boxType}); err != nil {
+		return err
+	}
+	if err := copyBoxFields(e.r, e.w, h); err != nil {
+		return err
+	}
+	if _, err := h.Expand(); err != nil {
+		return err
+	}
+
+	ctx := h.BoxInfo.Context
+	if _, err := e.w.StartBox(&BoxInfo{Type: BoxTypeSinf()}); err != nil {
+		return err
+	}
+	if err := writeBox(e.w, &Frma{DataFormat: h.BoxInfo.Type}, ctx); err != nil {
+		return err
+	}
+	if err := writeBox(e.w, &Schm{SchemeType: e.scheme, SchemeVersion: 0x00010000}, ctx); err != nil {
+		return err
+	}
+	if _, err := e.w.StartBox(&BoxInfo{Type: BoxTypeSchi()}); err != nil {
+		return err
+	}
+	if err := writeBox(e.w, track.tenc, ctx); err != nil {
+		return err
+	}
+	if _, err := e.w.EndBox(); err != nil { // schi
+		return err
+	}
+	if _, err := e.w.EndBox(); err != nil { // sinf
+		return err
+	}
+	_, err := e.w.EndBox()
+	return err
+}
+
+// writeMoof writes the moof box which contains senc, saiz and saio boxes.
+// The box is built twice in memory, because data offsets depend on the size of the new moof box.
+func (e *encrypter) writeMoof(moof *BoxInfo) error {
+	out, err := e.w.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	var shift int64
+	if len(e.shifts) != 0 {
+		shift = e.shifts[len(e.shifts)-1].shift
+	}
+
+	b := &moofBuilder{e: e, moof: moof, out: uint64(out), shift: shift}
+	if _, err := b.build(); err != nil {
+		return err
+	}
+	b.growth = int64(b.size) - int64(moof.Size)
+	data, err := b.build()
+	if err != nil {
+		return err
+	}
+	_, err = e.w.Write(data)
+	return err
+}
+
+type moofBuilder struct {
+	e     *encrypter
+	moof  *BoxInfo
+	out   uint64
+	shift int64
+
+	// growth is the difference between sizes of the new moof box and the original one.
+	growth int64
+	size   uint64
+
+	// sencDataOffsets holds offsets of the first sample entries of senc boxes from the new moof box.
+	sencDataOffsets []uint64
+	firstTraf       bool
+}
+
+func (b *moofBuilder) build() ([]byte, error) {
+	buf := &writerseeker.WriterSeeker{}
+	w := NewWriter(buf)
+	var tfhd *Tfhd
+	var trafIndex int
+	b.firstTraf = true
+
+	_, err := ReadBoxStructureFromInternal(b.e.r, b.moof, func(h *ReadHandle) (interface{}, error) {
+		switch h.BoxInfo.Type {
+		case BoxTypeMoof():
+			if _, err := w.StartBox(&BoxInfo{Type: BoxTypeMoof()}); err != nil {
+				return nil, err
+			}
+			if _, err := h.Expand(); err != nil {
+				return nil, err
+			}
+			_, err := w.EndBox()
+			return nil, err
+
+		case BoxTypeTraf():
+			tfhd = nil
+			if _, err := w.StartBox(&BoxInfo{Type: BoxTypeTraf()}); err != nil {
+				return nil, err
+			}
+			if _, err := h.Expand(); err != nil {
+				return nil, err
+			}
+			if tfhd == nil {
+				return nil, errors.New("tfhd box not found")
+			}
+			if track := b.e.tracks[tfhd.TrackID]; track != nil {
+				if err := b.writeAuxInfo(w, &h.BoxInfo, tfhd, track, trafIndex); err != nil {
+					return nil, err
+				}
+				trafIndex++
+			}
+			b.firstTraf = false
+			_, err := w.EndBox()
+			return nil, err
+
+		case BoxTypeTfhd():
+			box, _, err := h.ReadPayload()
+			if err != nil {
+				return nil, err
+			}
+			tfhd = box.(*Tfhd)
+			if tfhd.CheckFlag(TfhdBaseDataOffsetPresent) {
+				tfhd.BaseDataOffset = uint64(int64(tfhd.BaseDataOffset) + b.shift + b.growth)
+			} else if !tfhd.CheckFlag(TfhdDefaultBaseIsMoof) && !b.firstTraf {
+				if _, ok := b.e.tracks[tfhd.TrackID]; ok {
+					return nil, errors.New("implicit base data offset of non-first traf box is not supported")
+				}
+			}
+			return nil, writeBox(w, tfhd, h.BoxInfo.Context)
+
+		case BoxTypeTrun():
+			box, _, err := h.ReadPayload()
+			if err != nil {
+				return nil, err
+			}
+			trun := box.(*Trun)
+			if trun.CheckFlag(TrunDataOffsetPresent) && !tfhd.CheckFlag(TfhdBaseDataOffsetPresent) &&
+				(tfhd.CheckFlag(TfhdDefaultBaseIsMoof) || b.firstTraf) {
+				trun.DataOffset += int32(b.growth)
+			}
+			return nil, writeBox(w, trun, h.BoxInfo.Context)
+		}
+		return nil, w.CopyBox(b.e.r, &h.BoxInfo)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(buf.BytesReader())
+	if err != nil {
+		return nil, err
+	}
+	b.size = uint64(len(data))
+	return data, nil
+}
+
+func (b *moofBuilder) writeAuxInfo(w *Writer, traf *BoxInfo, tfhd *Tfhd, track *encryptTrack, trafIndex int) error {
+	entries := b.e.auxInfos[traf.Offset]
+	ctx := traf.Context
+	ctx.TrackID = tfhd.TrackID
+	ctx.Crypto = NewCryptoContext()
+	ctx.Crypto.TencRegistry[tfhd.TrackID] = track.tenc
+
+	saiz := &Saiz{SampleCount: uint32(len(entries))}
+	sizes := make([]uint8, len(entries))
+	for i := range entries {
+		size, err := auxInfoSize(&entries[i], track.video)
+		if err != nil {
+			return fmt.Errorf("%w: trackID=%d", err, tfhd.TrackID)
+		}
+		sizes[i] = size
+		if i == 0 || sizes[i] == saiz.DefaultSampleInfoSize {
+			saiz.DefaultSampleInfoSize = sizes[i]
+		} else {
+			saiz.DefaultSampleInfoSize = 0
+		}
+	}
+	if saiz.DefaultSampleInfoSize == 0 {
+		saiz.SampleInfoSize = sizes
+	}
+	if err := writeBox(w, saiz, ctx); err != nil {
+		return err
+	}
+
+	// the offset is relative to the base offset established by tfhd
+	var offset uint64
+	if trafIndex < len(b.sencDataOffsets) {
+		offset = b.out + b.sencDataOffsets[trafIndex]
+		base := b.out
+		if tfhd.CheckFlag(TfhdBaseDataOffsetPresent) {
+			base = tfhd.BaseDataOffset
+		}
+		if offset < base || offset-base > math.MaxUint32 {
+			return fmt.Errorf("senc box is out of range of saio offset from base data offset %d: trackID=%d", base, tfhd.TrackID)
+		}
+		offset -= base
+	}
+	if err := writeBox(w, &Saio{EntryCount: 1, OffsetV0: []uint32{uint32(offset)}}, ctx); err != nil {
+		return err
+	}
+
+	senc := &Senc{SampleCount: uint32(len(entries)), SampleEntries: entries}
+	if track.video {
+		senc.SetFlags(0x000002)
+	}
+	start, err := w.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if trafIndex >= len(b.sencDataOffsets) {
+		// header (8 bytes), version and flags (4 bytes) and sample_count (4 bytes)
+		b.sencDataOffsets = append(b.sencDataOffsets, uint64(start)+16)
+	}
+	return writeBox(w, senc, ctx)
+}
+
+// auxInfoSize returns the size of the sample auxiliary information, which must fit in sample_info_size of saiz.
+func auxInfoSize(entry *SencSampleEntry, subsamples bool) (uint8, error) {
+	size := len(entry.InitializationVector)
+	if subsamples {
+		size += 2 + 6*len(entry.SubsampleEntries)
+	}
+	if size > 0xff {
+		return 0, fmt.Errorf("too many subsamples: %d", len(entry.SubsampleEntries))
+	}
+	return uint8(size), nil
+}
+
+func (e *encrypter) mapOffset(offset uint64) uint64 {
+	var shift int64
+	for _, s := range e.shifts {
+		if s.offset > offset {
+			break
+		}
+		shift = s.shift
+	}
+	return uint64(int64(offset) + shift)
+}
+
+func (e *encrypter) encryptSamples() error {
+	for _, s := range e.samples {
+		if _, err := e.r.Seek(int64(s.sample.Offset), io.SeekStart); err != nil {
+			return err
+		}
+		data := make([]byte, s.sample.Size)
+		if _, err := io.ReadFull(e.r, data); err != nil {
+			return err
+		}
+		aux := &e.auxInfos[s.traf][s.index]
+		iv := aux.InitializationVector
+		if e.scheme == SchemeTypeCBCS() {
+			iv = s.track.iv
+		}
+		if err := s.track.cipher.cryptSample(data, iv, aux.SubsampleEntries); err != nil {
+			return err
+		}
+		if _, err := e.w.Seek(int64(e.mapOffset(s.sample.Offset)), io.SeekStart); err != nil {
+			return err
+		}
+		if _, err := e.w.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// patchSidxes rewrites sidx boxes in accordance with the new sizes of moof boxes.
+func (e *encrypter) patchSidxes() error {
+	for _, p := range e.sidxes {
+		sidx := p.sidx
+		inStart := p.in.Offset + p.in.Size
+		if sidx.GetVersion() == 0 {
+			inStart += uint64(sidx.FirstOffsetV0)
+			sidx.FirstOffsetV0 = uint32(e.mapOffset(inStart) - (p.out + p.in.Size))
+		} else {
+			inStart += sidx.FirstOffsetV1
+			sidx.FirstOffsetV1 = e.mapOffset(inStart) - (p.out + p.in.Size)
+		}
+		for i := range sidx.References {
+			inEnd := inStart + uint64(sidx.References[i].ReferencedSize)
+			sidx.References[i].ReferencedSize = uint32(e.mapOffset(inEnd) - e.mapOffset(inStart))
+			inStart = inEnd
+		}
+		if _, err := e.w.Seek(int64(p.out+p.in.HeaderSize), io.SeekStart); err != nil {
+			return err
+		}
+		if _, err := Marshal(e.w, sidx, p.in.Context); err != nil {
+			return err
+		}
+	}
+	return nil
+}
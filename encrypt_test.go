@@ -0,0 +1,474 @@
+package mp4
+
+import (
+	"bytes"
+	"io"
+	"math/big"
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/orcaman/writerseeker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncrypt(t *testing.T) {
+	kid := [16]byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef, 0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef}
+	key := []byte{0x10, 0x32, 0x54, 0x76, 0x98, 0xba, 0xdc, 0xfe, 0x10, 0x32, 0x54, 0x76, 0x98, 0xba, 0xdc, 0xfe}
+
+	f, err := os.Open("./testdata/sample_fragmented.mp4")
+	require.NoError(t, err)
+	defer f.Close()
+
+	clear := make(map[uint32][][]byte)
+	for _, trackID := range []uint32{1, 2} {
+		sr, err := NewSampleReader(f, trackID)
+		require.NoError(t, err)
+		for {
+			sample, err := sr.Next()
+			if err != nil {
+				break
+			}
+			clear[trackID] = append(clear[trackID], sample.Data)
+		}
+	}
+
+	testCases := []struct {
+		name   string
+		scheme [4]byte
+		iv     []byte
+	}{
+		{name: "cenc", scheme: SchemeTypeCENC(), iv: []byte{1, 2, 3, 4, 5, 6, 7, 8}},
+		{name: "cens", scheme: SchemeTypeCENS()},
+		{name: "cbc1", scheme: SchemeTypeCBC1()},
+		{name: "cbcs", scheme: SchemeTypeCBCS(), iv: bytes.Repeat([]byte{0x5a}, 16)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			encrypted := &writerseeker.WriterSeeker{}
+			require.NoError(t, Encrypt(f, encrypted, &EncryptConfig{
+				Scheme: tc.scheme,
+				KID:    kid,
+				Key:    key,
+				IV:     tc.iv,
+				Pssh: []*Pssh{{
+					FullBox:  FullBox{Version: 1},
+					SystemID: [16]byte{0x10, 0x77, 0xef, 0xec, 0xc0, 0xb2, 0x4d, 0x02, 0xac, 0xe3, 0x3c, 0x1e, 0x52, 0xe2, 0xfb, 0x4b},
+					KIDCount: 1,
+					KIDs:     []PsshKID{{KID: kid}},
+				}},
+			}))
+			er := encrypted.BytesReader()
+
+			bis, err := ExtractBoxes(er, nil, []BoxPath{
+				{BoxTypeMoov(), BoxTypeTrak(), BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeAny()},
+				{BoxTypeMoov(), BoxTypePssh()},
+			})
+			require.NoError(t, err)
+			require.Len(t, bis, 3)
+			assert.Equal(t, BoxTypeEncv(), bis[0].Type)
+			assert.Equal(t, BoxTypeEnca(), bis[1].Type)
+			assert.Equal(t, BoxTypePssh(), bis[2].Type)
+
+			bips, err := ExtractBoxWithPayload(er, nil, BoxPath{
+				BoxTypeMoov(), BoxTypeTrak(), BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeEncv(), BoxTypeSinf(), BoxTypeSchm(),
+			})
+			require.NoError(t, err)
+			require.Len(t, bips, 1)
+			assert.Equal(t, tc.scheme, bips[0].Payload.(*Schm).SchemeType)
+
+			bis, err = ExtractBoxes(er, nil, []BoxPath{
+				{BoxTypeMoof(), BoxTypeTraf(), BoxTypeSenc()},
+				{BoxTypeMoof(), BoxTypeTraf(), BoxTypeSaiz()},
+				{BoxTypeMoof(), BoxTypeTraf(), BoxTypeSaio()},
+			})
+			require.NoError(t, err)
+			assert.Len(t, bis, 3*8)
+
+			sr, err := NewSampleReader(er, 1)
+			require.NoError(t, err)
+			sample, err := sr.Next()
+			require.NoError(t, err)
+			assert.NotEqual(t, clear[1][0], sample.Data)
+			assert.Equal(t, clear[1][0][:36], sample.Data[:36])
+
+			decrypted := &writerseeker.WriterSeeker{}
+			require.NoError(t, Decrypt(er, decrypted, map[[16]byte][]byte{kid: key}))
+			dr := decrypted.BytesReader()
+			for _, trackID := range []uint32{1, 2} {
+				sr, err := NewSampleReader(dr, trackID)
+				require.NoError(t, err)
+				for i := range clear[trackID] {
+					sample, err := sr.Next()
+					require.NoError(t, err)
+					assert.Equal(t, clear[trackID][i], sample.Data, "trackID=%d, sample=%d", trackID, i)
+				}
+			}
+		})
+	}
+}
+
+func TestEncryptSubsamples(t *testing.T) {
+	key := []byte{0x10, 0x32, 0x54, 0x76, 0x98, 0xba, 0xdc, 0xfe, 0x10, 0x32, 0x54, 0x76, 0x98, 0xba, 0xdc, 0xfe}
+
+	sps := buildTestNALUnit([]byte{0x67, 77, 0x40, 31},
+		testUE(0),       // seq_parameter_set_id
+		testUE(0),       // log2_max_frame_num_minus4
+		testUE(2),       // pic_order_cnt_type
+		testUE(16),      // max_num_ref_frames
+		[2]uint64{0, 1}, // gaps_in_frame_num_value_allowed_flag
+		testUE(79),      // pic_width_in_mbs_minus1
+		testUE(44),      // pic_height_in_map_units_minus1
+		[2]uint64{1, 1}, // frame_mbs_only_flag
+		[2]uint64{1, 1}, // direct_8x8_inference_flag
+		[2]uint64{0, 1}, // frame_cropping_flag
+		[2]uint64{0, 1}, // vui_parameters_present_flag
+		[2]uint64{1, 1}, // rbsp_stop_one_bit
+	)
+	pps := buildTestNALUnit([]byte{0x68},
+		testUE(0),       // pic_parameter_set_id
+		testUE(0),       // seq_parameter_set_id
+		[2]uint64{1, 1}, // entropy_coding_mode_flag
+		[2]uint64{0, 1}, // bottom_field_pic_order_in_frame_present_flag
+		testUE(0),       // num_slice_groups_minus1
+		testUE(0),       // num_ref_idx_l0_default_active_minus1
+		testUE(0),       // num_ref_idx_l1_default_active_minus1
+		[2]uint64{1, 1}, // weighted_pred_flag
+		[2]uint64{0, 2}, // weighted_bipred_idc
+		testSE(0),       // pic_init_qp_minus26
+		testSE(0),       // pic_init_qs_minus26
+		testSE(0),       // chroma_qp_index_offset
+		[2]uint64{0, 1}, // deblocking_filter_control_present_flag
+		[2]uint64{0, 1}, // constrained_intra_pred_flag
+		[2]uint64{0, 1}, // redundant_pic_cnt_present_flag
+		[2]uint64{1, 1}, // rbsp_stop_one_bit
+	)
+
+	// the slice header is longer than 32 bytes because of pred_weight_table:
+	// 1 + 5 + 1 + 4 + 1 + 9 + 1 + 1 + 1 + 16*(1 + 9 + 9 + 1) + 1 + 1 + 3 = 349 bits,
+	// which are followed by 3 bits of cabac_alignment_one_bit
+	weights := make([][2]uint64, 0, 16*4)
+	for i := 0; i < 16; i++ {
+		weights = append(weights,
+			[2]uint64{1, 1}, // luma_weight_l0_flag
+			testSE(-10),     // luma_weight_l0
+			testSE(-10),     // luma_offset_l0
+			[2]uint64{0, 1}, // chroma_weight_l0_flag
+		)
+	}
+	slice := buildTestNALUnit([]byte{0x41}, append(append([][2]uint64{
+		testUE(0),       // first_mb_in_slice
+		testUE(5),       // slice_type
+		testUE(0),       // pic_parameter_set_id
+		[2]uint64{1, 4}, // frame_num
+		[2]uint64{1, 1}, // num_ref_idx_active_override_flag
+		testUE(15),      // num_ref_idx_l0_active_minus1
+		[2]uint64{0, 1}, // ref_pic_list_modification_flag_l0
+		testUE(0),       // luma_log2_weight_denom
+		testUE(0),       // chroma_log2_weight_denom
+	}, weights...),
+		[2]uint64{0, 1},   // adaptive_ref_pic_marking_mode_flag
+		testUE(0),         // cabac_init_idc
+		testSE(-1),        // slice_qp_delta
+		[2]uint64{0x7, 3}, // cabac_alignment_one_bit
+	)...)
+	require.Len(t, slice, 1+44)
+	slice = append(slice, bytes.Repeat([]byte{0xa5}, 100)...) // slice_data
+
+	data := make([]byte, 0)
+	data = append(data, 0x00, 0x00, 0x00, 0x02, 0x09, 0xf0) // AUD
+	data = append(data, 0x00, 0x00, 0x00, byte(len(sps)))
+	data = append(data, sps...)
+	data = append(data, 0x00, 0x00, 0x00, byte(len(pps)))
+	data = append(data, pps...)
+	data = append(data, 0x00, 0x00, 0x00, byte(len(slice)))
+	data = append(data, slice...)
+	parameterSets := 4 + len(sps) + 4 + len(pps)
+
+	testCases := []struct {
+		name       string
+		scheme     [4]byte
+		subsamples []SubsampleEntry
+	}{
+		{
+			// the protected range is rounded down to 16-byte blocks
+			name:   "cenc",
+			scheme: SchemeTypeCENC(),
+			subsamples: []SubsampleEntry{
+				{BytesOfClearData: uint16(6 + parameterSets + 4 + 45 + 4), BytesOfProtectedData: 96},
+			},
+		},
+		{
+			// the protected range starts at the first byte of slice data
+			name:   "cbcs",
+			scheme: SchemeTypeCBCS(),
+			subsamples: []SubsampleEntry{
+				{BytesOfClearData: uint16(6 + parameterSets + 4 + 45), BytesOfProtectedData: 100},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := newCencCipher(tc.scheme, key, 1, 9, false)
+			require.NoError(t, err)
+			newTrack := func() *encryptTrack {
+				return &encryptTrack{
+					lengthSize: 4,
+					cipher:     c,
+					avcSPS:     make(map[uint32]*AVCSPS),
+					avcPPS:     make(map[uint32]*AVCPPS),
+				}
+			}
+			subsamples, err := newTrack().subsamples(data)
+			require.NoError(t, err)
+			assert.Equal(t, tc.subsamples, subsamples)
+
+			// the slice without the parameter sets
+			_, err = newTrack().subsamples(append(append([]byte(nil), data[:6]...), data[6+parameterSets:]...))
+			assert.Error(t, err)
+
+			_, err = newTrack().subsamples(data[:len(data)-1])
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("sample_fragmented.mp4", func(t *testing.T) {
+		f, err := os.Open("./testdata/sample_fragmented.mp4")
+		require.NoError(t, err)
+		defer f.Close()
+
+		e := &encrypter{r: f, config: &EncryptConfig{}, tracks: make(map[uint32]*encryptTrack)}
+		require.NoError(t, e.readTracks())
+		track := e.tracks[1]
+		require.NotNil(t, track)
+		track.cipher, err = newCencCipher(SchemeTypeCBCS(), key, 1, 9, false)
+		require.NoError(t, err)
+		sr, err := NewSampleReader(f, 1)
+		require.NoError(t, err)
+
+		// AUD, SPS, PPS, SEI and the IDR slice whose header has 4 bytes after the NAL unit header
+		sample, err := sr.Next()
+		require.NoError(t, err)
+		subsamples, err := track.subsamples(sample.Data)
+		require.NoError(t, err)
+		assert.Equal(t, []SubsampleEntry{
+			{BytesOfClearData: 4 + 2 + 4 + 25 + 4 + 6 + 4 + 728 + 4 + 1 + 4, BytesOfProtectedData: 193 - 1 - 4},
+		}, subsamples)
+
+		// AUD and the P slice whose header has 4 bytes after the NAL unit header
+		sample, err = sr.Next()
+		require.NoError(t, err)
+		subsamples, err = track.subsamples(sample.Data)
+		require.NoError(t, err)
+		assert.Equal(t, []SubsampleEntry{
+			{BytesOfClearData: 4 + 2 + 4 + 1 + 4, BytesOfProtectedData: 30 - 1 - 4},
+		}, subsamples)
+	})
+}
+
+func TestEncryptIVs(t *testing.T) {
+	kid := [16]byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef, 0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef}
+	key := []byte{0x10, 0x32, 0x54, 0x76, 0x98, 0xba, 0xdc, 0xfe, 0x10, 0x32, 0x54, 0x76, 0x98, 0xba, 0xdc, 0xfe}
+
+	f, err := os.Open("./testdata/sample_fragmented.mp4")
+	require.NoError(t, err)
+	defer f.Close()
+
+	// the 16-byte IV would be used as the block counter of AES-CTR
+	for _, scheme := range [][4]byte{SchemeTypeCENC(), SchemeTypeCENS()} {
+		err := Encrypt(f, &writerseeker.WriterSeeker{}, &EncryptConfig{
+			Scheme: scheme, KID: kid, Key: key, IV: bytes.Repeat([]byte{0xff}, 16),
+		})
+		assert.Error(t, err)
+	}
+
+	t.Run("keystreams of cenc", func(t *testing.T) {
+		encrypted := &writerseeker.WriterSeeker{}
+		require.NoError(t, Encrypt(f, encrypted, &EncryptConfig{
+			Scheme: SchemeTypeCENC(),
+			KID:    kid,
+			Key:    key,
+			// the IV which carries over its lower bytes
+			IV: []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xf0},
+		}))
+		er := encrypted.BytesReader()
+
+		bis, err := ExtractBoxes(er, nil, []BoxPath{{BoxTypeMoof(), BoxTypeTraf(), BoxTypeSenc()}})
+		require.NoError(t, err)
+		entries := make(map[uint32][]SencSampleEntry)
+		for _, bi := range bis {
+			ctx := bi.Context
+			ctx.Crypto = NewCryptoContext()
+			ctx.Crypto.TencRegistry[ctx.TrackID] = &Tenc{DefaultPerSampleIVSize: 8}
+			_, err := bi.SeekToPayload(er)
+			require.NoError(t, err)
+			var senc Senc
+			_, err = Unmarshal(er, bi.Size-bi.HeaderSize, &senc, ctx)
+			require.NoError(t, err)
+			entries[ctx.TrackID] = append(entries[ctx.TrackID], senc.SampleEntries...)
+		}
+
+		// the counter blocks of AES-CTR used by each sample
+		type counterRange struct {
+			start, end *big.Int
+		}
+		var ranges []counterRange
+		for _, trackID := range []uint32{1, 2} {
+			sr, err := NewSampleReader(er, trackID)
+			require.NoError(t, err)
+			require.Len(t, entries[trackID], len(sr.Samples()))
+			for i, sample := range sr.Samples() {
+				entry := entries[trackID][i]
+				require.Len(t, entry.InitializationVector, 8)
+				protected := uint64(sample.Size)
+				if len(entry.SubsampleEntries) != 0 {
+					protected = 0
+					for _, subsample := range entry.SubsampleEntries {
+						protected += uint64(subsample.BytesOfProtectedData)
+					}
+				}
+				start := new(big.Int).Lsh(new(big.Int).SetBytes(entry.InitializationVector), 64)
+				blocks := new(big.Int).SetUint64((protected + 15) / 16)
+				ranges = append(ranges, counterRange{start: start, end: new(big.Int).Add(start, blocks)})
+			}
+		}
+		sort.Slice(ranges, func(i, j int) bool { return ranges[i].start.Cmp(ranges[j].start) < 0 })
+		for i := 1; i < len(ranges); i++ {
+			assert.True(t, ranges[i-1].end.Cmp(ranges[i].start) <= 0, "counter ranges overlap: %d", i)
+		}
+		assert.Len(t, ranges, 10+44)
+	})
+
+	t.Run("constant IVs of cbcs", func(t *testing.T) {
+		encrypted := &writerseeker.WriterSeeker{}
+		require.NoError(t, Encrypt(f, encrypted, &EncryptConfig{
+			Scheme: SchemeTypeCBCS(),
+			KID:    kid,
+			Key:    key,
+			IV:     bytes.Repeat([]byte{0x5a}, 16),
+		}))
+		bips, err := ExtractBoxesWithPayload(encrypted.BytesReader(), nil, []BoxPath{
+			{BoxTypeMoov(), BoxTypeTrak(), BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeAny(), BoxTypeSinf(), BoxTypeSchi(), BoxTypeTenc()},
+		})
+		require.NoError(t, err)
+		require.Len(t, bips, 2)
+		assert.Equal(t, bytes.Repeat([]byte{0x5a}, 16), bips[0].Payload.(*Tenc).DefaultConstantIV)
+		assert.Equal(t, append(bytes.Repeat([]byte{0x5a}, 15), 0x5b), bips[1].Payload.(*Tenc).DefaultConstantIV)
+	})
+}
+
+func TestAuxInfoSize(t *testing.T) {
+	entry := &SencSampleEntry{
+		InitializationVector: make([]byte, 8),
+		SubsampleEntries:     make([]SubsampleEntry, 40),
+	}
+	size, err := auxInfoSize(entry, false)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(8), size)
+	size, err = auxInfoSize(entry, true)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(8+2+6*40), size)
+
+	entry.SubsampleEntries = make([]SubsampleEntry, 41)
+	_, err = auxInfoSize(entry, true)
+	assert.Error(t, err)
+}
+
+// buildTestExplicitBaseFile builds a fragmented file of an audio track whose tfhd box has base-data-offset.
+// base returns base-data-offset from the offsets of the moof box and the sample data.
+func buildTestExplicitBaseFile(t *testing.T, base func(moof, data uint64) uint64) io.ReadSeeker {
+	samples := bytes.Repeat([]byte{0x01, 0x23, 0x45, 0x67}, 8)
+	build := func(moof, data uint64) []byte {
+		buf := &writerseeker.WriterSeeker{}
+		w := NewWriter(buf)
+		ctx := Context{}
+		writeTestBox(t, w, &Ftyp{MajorBrand: [4]byte{'i', 's', 'o', '6'}}, ctx, nil)
+		writeTestBox(t, w, &Moov{}, ctx, func() {
+			writeTestBox(t, w, &Mvhd{Timescale: 1000, NextTrackID: 2}, ctx, nil)
+			writeTestBox(t, w, &Trak{}, ctx, func() {
+				writeTestBox(t, w, &Tkhd{TrackID: 1}, ctx, nil)
+				writeTestBox(t, w, &Mdia{}, ctx, func() {
+					writeTestBox(t, w, &Mdhd{Timescale: 48000}, ctx, nil)
+					writeTestBox(t, w, &Minf{}, ctx, func() {
+						writeTestBox(t, w, &Stbl{}, ctx, func() {
+							writeTestBox(t, w, &Stsd{EntryCount: 1}, ctx, func() {
+								writeTestBox(t, w, &AudioSampleEntry{
+									SampleEntry:  SampleEntry{AnyTypeBox: AnyTypeBox{Type: BoxTypeMp4a()}, DataReferenceIndex: 1},
+									ChannelCount: 2,
+									SampleSize:   16,
+									SampleRate:   48000 << 16,
+								}, ctx, nil)
+							})
+							writeTestBox(t, w, &Stts{}, ctx, nil)
+							writeTestBox(t, w, &Stsc{}, ctx, nil)
+							writeTestBox(t, w, &Stsz{}, ctx, nil)
+							writeTestBox(t, w, &Stco{}, ctx, nil)
+						})
+					})
+				})
+			})
+			writeTestBox(t, w, &Mvex{}, ctx, func() {
+				writeTestBox(t, w, &Trex{TrackID: 1, DefaultSampleDescriptionIndex: 1}, ctx, nil)
+			})
+		})
+		baseDataOffset := base(moof, data)
+		writeTestBox(t, w, &Moof{}, ctx, func() {
+			writeTestBox(t, w, &Mfhd{SequenceNumber: 1}, ctx, nil)
+			writeTestBox(t, w, &Traf{}, ctx, func() {
+				writeTestBox(t, w, &Tfhd{
+					FullBox:               FullBox{Flags: [3]byte{0x00, 0x00, TfhdBaseDataOffsetPresent | TfhdDefaultSampleDurationPresent | TfhdDefaultSampleSizePresent}},
+					TrackID:               1,
+					BaseDataOffset:        baseDataOffset,
+					DefaultSampleDuration: 1024,
+					DefaultSampleSize:     uint32(len(samples) / 2),
+				}, ctx, nil)
+				writeTestBox(t, w, &Trun{
+					FullBox:     FullBox{Flags: [3]byte{0x00, 0x00, TrunDataOffsetPresent}},
+					SampleCount: 2,
+					DataOffset:  int32(int64(data) - int64(baseDataOffset)),
+					Entries:     []TrunEntry{{}, {}},
+				}, ctx, nil)
+			})
+		})
+		writeTestBox(t, w, &Mdat{Data: samples}, ctx, nil)
+		file, err := io.ReadAll(buf.BytesReader())
+		require.NoError(t, err)
+		return file
+	}
+
+	// the offsets do not depend on base-data-offset
+	bis, err := ExtractBoxes(bytes.NewReader(build(0, 0)), nil, []BoxPath{{BoxTypeMoof()}, {BoxTypeMdat()}})
+	require.NoError(t, err)
+	require.Len(t, bis, 2)
+	return bytes.NewReader(build(bis[0].Offset, bis[1].Offset+bis[1].HeaderSize))
+}
+
+func TestEncryptExplicitBaseDataOffset(t *testing.T) {
+	kid := [16]byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef, 0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef}
+	key := []byte{0x10, 0x32, 0x54, 0x76, 0x98, 0xba, 0xdc, 0xfe, 0x10, 0x32, 0x54, 0x76, 0x98, 0xba, 0xdc, 0xfe}
+	config := &EncryptConfig{KID: kid, Key: key}
+
+	t.Run("base at moof", func(t *testing.T) {
+		r := buildTestExplicitBaseFile(t, func(moof, data uint64) uint64 { return moof })
+		encrypted := &writerseeker.WriterSeeker{}
+		require.NoError(t, Encrypt(r, encrypted, config))
+
+		decrypted := &writerseeker.WriterSeeker{}
+		require.NoError(t, Decrypt(encrypted.BytesReader(), decrypted, map[[16]byte][]byte{kid: key}))
+		sr, err := NewSampleReader(decrypted.BytesReader(), 1)
+		require.NoError(t, err)
+		for i := 0; i < 2; i++ {
+			sample, err := sr.Next()
+			require.NoError(t, err)
+			assert.Equal(t, bytes.Repeat([]byte{0x01, 0x23, 0x45, 0x67}, 4), sample.Data)
+		}
+	})
+
+	t.Run("base after senc", func(t *testing.T) {
+		// saio cannot point backward to senc from the sample data
+		r := buildTestExplicitBaseFile(t, func(moof, data uint64) uint64 { return data })
+		err := Encrypt(r, &writerseeker.WriterSeeker{}, config)
+		assert.Error(t, err)
+	})
+}
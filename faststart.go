@@ -0,0 +1,179 @@
+package mp4
+
+import (
+	"errors"
+	"io"
+	"math"
+
+	"github.com/orcaman/writerseeker"
+)
+
+type fastStarter struct {
+	r     io.ReadSeeker
+	boxes []*BoxInfo
+	moov  *BoxInfo
+
+	// outOffsets holds offsets of the top-level boxes in the output.
+	outOffsets map[*BoxInfo]uint64
+}
+
+// MakeFastStart moves the moov box in front of the first mdat box so that players can start
+// playback before downloading the whole file.
+// Chunk offsets in stco and co64 boxes and sample auxiliary information offsets in saio boxes
+// in the moov box are shifted, and stco boxes are upgraded to co64 boxes when offsets overflow 32 bits.
+// The input is copied as is when the moov box already precedes the mdat boxes.
+func MakeFastStart(r io.ReadSeeker, w io.WriteSeeker) error {
+	boxes, err := ExtractBox(r, nil, BoxPath{BoxTypeAny()})
+	if err != nil {
+		return err
+	}
+	f := &fastStarter{r: r, outOffsets: make(map[*BoxInfo]uint64)}
+	var mdatAppeared bool
+	for _, bi := range boxes {
+		switch bi.Type {
+		case BoxTypeMoov():
+			if !mdatAppeared {
+				// already fast start
+				if _, err := r.Seek(0, io.SeekStart); err != nil {
+					return err
+				}
+				_, err := io.Copy(w, r)
+				return err
+			}
+			f.moov = bi
+			continue
+		case BoxTypeMdat():
+			if !mdatAppeared && f.moov == nil {
+				mdatAppeared = true
+				f.boxes = append(f.boxes, nil) // placeholder of moov
+			}
+		}
+		f.boxes = append(f.boxes, bi)
+	}
+	if f.moov == nil {
+		return errors.New("moov box not found")
+	}
+
+	// the size of the moov box changes when stco boxes are upgraded to co64 boxes
+	var moov []byte
+	moovSize := f.moov.Size
+	for {
+		f.layout(moovSize)
+		if moov, err = f.buildMoov(); err != nil {
+			return err
+		}
+		if uint64(len(moov)) == moovSize {
+			break
+		}
+		moovSize = uint64(len(moov))
+	}
+
+	mw := NewWriter(w)
+	for _, bi := range f.boxes {
+		if bi == nil {
+			if _, err := mw.Write(moov); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := mw.CopyBox(r, bi); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fastStarter) layout(moovSize uint64) {
+	var offset uint64
+	for _, bi := range f.boxes {
+		if bi == nil {
+			offset += moovSize
+			continue
+		}
+		f.outOffsets[bi] = offset
+		offset += bi.Size
+	}
+}
+
+// mapOffset converts the absolute offset in the input into the offset in the output.
+func (f *fastStarter) mapOffset(offset uint64) uint64 {
+	for _, bi := range f.boxes {
+		if bi != nil && offset >= bi.Offset && offset < bi.Offset+bi.Size {
+			return f.outOffsets[bi] + offset - bi.Offset
+		}
+	}
+	return offset
+}
+
+func (f *fastStarter) buildMoov() ([]byte, error) {
+	buf := &writerseeker.WriterSeeker{}
+	w := NewWriter(buf)
+	_, err := ReadBoxStructureFromInternal(f.r, f.moov, func(h *ReadHandle) (interface{}, error) {
+		switch h.BoxInfo.Type {
+		case BoxTypeMoov(), BoxTypeTrak(), BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl():
+			return nil, writeContainerBox(f.r, w, h, nil)
+		case BoxTypeStco():
+			box, _, err := h.ReadPayload()
+			if err != nil {
+				return nil, err
+			}
+			stco := box.(*Stco)
+			co64 := &Co64{EntryCount: stco.EntryCount, ChunkOffset: make([]uint64, len(stco.ChunkOffset))}
+			var overflow bool
+			for i, offset := range stco.ChunkOffset {
+				co64.ChunkOffset[i] = f.mapOffset(uint64(offset))
+				overflow = overflow || co64.ChunkOffset[i] > math.MaxUint32
+			}
+			if overflow {
+				return nil, writeBox(w, co64, h.BoxInfo.Context)
+			}
+			for i, offset := range co64.ChunkOffset {
+				stco.ChunkOffset[i] = uint32(offset)
+			}
+			return nil, writeBox(w, stco, h.BoxInfo.Context)
+		case BoxTypeCo64():
+			box, _, err := h.ReadPayload()
+			if err != nil {
+				return nil, err
+			}
+			co64 := box.(*Co64)
+			for i, offset := range co64.ChunkOffset {
+				co64.ChunkOffset[i] = f.mapOffset(offset)
+			}
+			return nil, writeBox(w, co64, h.BoxInfo.Context)
+		case BoxTypeSaio():
+			box, _, err := h.ReadPayload()
+			if err != nil {
+				return nil, err
+			}
+			saio := box.(*Saio)
+			offsets := saio.OffsetV1
+			if saio.GetVersion() == 0 {
+				offsets = make([]uint64, len(saio.OffsetV0))
+				for i, offset := range saio.OffsetV0 {
+					offsets[i] = uint64(offset)
+				}
+			}
+			var overflow bool
+			for i := range offsets {
+				offsets[i] = f.mapOffset(offsets[i])
+				overflow = overflow || offsets[i] > math.MaxUint32
+			}
+			if overflow || saio.GetVersion() != 0 {
+				saio.SetVersion(1)
+				saio.OffsetV0 = nil
+				saio.OffsetV1 = offsets
+			} else {
+				for i, offset := range offsets {
+					saio.OffsetV0[i] = uint32(offset)
+				}
+			}
+			return nil, writeBox(w, saio, h.BoxInfo.Context)
+		}
+		return nil, w.CopyBox(f.r, &h.BoxInfo)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(buf.BytesReader())
+}
@@ -0,0 +1,61 @@
+package mp4
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/orcaman/writerseeker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMakeFastStart(t *testing.T) {
+	f, err := os.Open("./testdata/sample.mp4")
+	require.NoError(t, err)
+	defer f.Close()
+
+	info, err := Probe(f)
+	require.NoError(t, err)
+	require.False(t, info.FastStart)
+
+	output := &writerseeker.WriterSeeker{}
+	require.NoError(t, MakeFastStart(f, output))
+	r := output.BytesReader()
+
+	info, err = Probe(r)
+	require.NoError(t, err)
+	assert.True(t, info.FastStart)
+	size, err := r.Seek(0, io.SeekEnd)
+	require.NoError(t, err)
+	stat, err := f.Stat()
+	require.NoError(t, err)
+	assert.Equal(t, stat.Size(), size)
+
+	for _, trackID := range []uint32{1, 2} {
+		orig, err := NewSampleReader(f, trackID)
+		require.NoError(t, err)
+		fast, err := NewSampleReader(r, trackID)
+		require.NoError(t, err)
+		require.Len(t, fast.Samples(), len(orig.Samples()))
+		for {
+			s1, err := orig.Next()
+			if err != nil {
+				break
+			}
+			s2, err := fast.Next()
+			require.NoError(t, err)
+			assert.Equal(t, s1.Data, s2.Data)
+		}
+	}
+
+	// the file which is already fast start is copied as is
+	data, err := io.ReadAll(output.BytesReader())
+	require.NoError(t, err)
+	output2 := &writerseeker.WriterSeeker{}
+	require.NoError(t, MakeFastStart(bytes.NewReader(data), output2))
+	data2, err := io.ReadAll(output2.BytesReader())
+	require.NoError(t, err)
+	assert.Equal(t, data, data2)
+}
@@ -0,0 +1,79 @@
+package mp4
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var flacStreamMarker = []byte("fLaC")
+
+// ExtractFLAC reads samples of the FLAC track and writes them to w as a native FLAC stream.
+// The metadata blocks in the dfLa box are written following the stream marker,
+// and the samples, each of which is a FLAC frame, are written as they are.
+// The first FLAC track is used when trackID is zero.
+func ExtractFLAC(r io.ReadSeeker, w io.Writer, trackID uint32) error {
+	bips, err := ExtractBoxesWithPayload(r, nil, []BoxPath{
+		{BoxTypeMoov(), BoxTypeTrak(), BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeFLaC(), BoxTypeDfLa()},
+	})
+	if err != nil {
+		return err
+	}
+	var dfla *DfLa
+	for _, bip := range bips {
+		if trackID == 0 || bip.Info.TrackID == trackID {
+			trackID = bip.Info.TrackID
+			dfla = bip.Payload.(*DfLa)
+			break
+		}
+	}
+	if dfla == nil {
+		if trackID != 0 {
+			return fmt.Errorf("FLAC track not found: trackID=%d", trackID)
+		}
+		return errors.New("FLAC track not found")
+	}
+	header, err := buildFLACHeader(dfla)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	sr, err := NewSampleReader(r, trackID)
+	if err != nil {
+		return err
+	}
+	for {
+		s, err := sr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if _, err := w.Write(s.Data); err != nil {
+			return err
+		}
+	}
+}
+
+// buildFLACHeader builds the stream marker and the metadata blocks of a FLAC stream.
+// The last-metadata-block flag is set to the last block only.
+func buildFLACHeader(dfla *DfLa) ([]byte, error) {
+	if dfla.GetStreamInfo() == nil {
+		return nil, errors.New("dfLa: STREAMINFO not found")
+	}
+	blocks := make([]FLACMetadataBlock, len(dfla.MetadataBlocks))
+	copy(blocks, dfla.MetadataBlocks)
+	for i := range blocks {
+		blocks[i].LastMetadataBlockFlag = i == len(blocks)-1
+	}
+	buf := bytes.NewBuffer(nil)
+	if _, err := Marshal(buf, &DfLa{MetadataBlocks: blocks}, Context{}); err != nil {
+		return nil, err
+	}
+	// skip version and flags of dfLa
+	return append(append([]byte{}, flacStreamMarker...), buf.Bytes()[4:]...), nil
+}
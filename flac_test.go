@@ -0,0 +1,91 @@
+package mp4
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/orcaman/writerseeker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractFLAC(t *testing.T) {
+	frames := [][]byte{
+		{0xff, 0xf8, 0x69, 0x08, 0x00, 0x01},
+		{0xff, 0xf8, 0x69, 0x08, 0x01, 0x02, 0x03},
+		{0xff, 0xf8, 0x69, 0x08, 0x02, 0x04},
+	}
+	streamInfo := FLACMetadataBlock{
+		BlockType:           FLACMetadataBlockTypeStreamInfo,
+		Length:              34,
+		MinimumBlockSize:    4096,
+		MaximumBlockSize:    4096,
+		SampleRate:          44100,
+		ChannelsMinusOne:    1,
+		BitsPerSampleMinus1: 15,
+		TotalSamples:        12288,
+	}
+
+	buf := &writerseeker.WriterSeeker{}
+	w := NewWriter(buf)
+	writeTestBox(t, w, &Ftyp{MajorBrand: [4]byte{'i', 's', 'o', 'm'}}, Context{}, nil)
+	var data []byte
+	stsz := &Stsz{SampleCount: uint32(len(frames))}
+	for _, frame := range frames {
+		data = append(data, frame...)
+		stsz.EntrySize = append(stsz.EntrySize, uint32(len(frame)))
+	}
+	mdat, err := w.StartBox(&BoxInfo{Type: BoxTypeMdat()})
+	require.NoError(t, err)
+	_, err = w.Write(data)
+	require.NoError(t, err)
+	_, err = w.EndBox()
+	require.NoError(t, err)
+	writeTestBox(t, w, &Moov{}, Context{}, func() {
+		writeTestBox(t, w, &Mvhd{Timescale: 1000, NextTrackID: 2}, Context{}, nil)
+		writeTestBox(t, w, &Trak{}, Context{}, func() {
+			writeTestBox(t, w, &Tkhd{TrackID: 1}, Context{}, nil)
+			writeTestBox(t, w, &Mdia{}, Context{}, func() {
+				writeTestBox(t, w, &Mdhd{Timescale: 44100}, Context{}, nil)
+				writeTestBox(t, w, &Hdlr{HandlerType: [4]byte{'s', 'o', 'u', 'n'}}, Context{}, nil)
+				writeTestBox(t, w, &Minf{}, Context{}, func() {
+					writeTestBox(t, w, &Stbl{}, Context{}, func() {
+						writeTestBox(t, w, &Stsd{EntryCount: 1}, Context{}, func() {
+							writeTestBox(t, w, &AudioSampleEntry{
+								SampleEntry:  SampleEntry{AnyTypeBox: AnyTypeBox{Type: BoxTypeFLaC()}, DataReferenceIndex: 1},
+								ChannelCount: 2,
+								SampleSize:   16,
+								SampleRate:   44100 << 16,
+							}, Context{}, func() {
+								// the last-metadata-block flag is not set intentionally
+								writeTestBox(t, w, &DfLa{MetadataBlocks: []FLACMetadataBlock{streamInfo}}, Context{}, nil)
+							})
+						})
+						writeTestBox(t, w, &Stts{EntryCount: 1, Entries: []SttsEntry{{SampleCount: 3, SampleDelta: 4096}}}, Context{}, nil)
+						writeTestBox(t, w, &Stsc{EntryCount: 1, Entries: []StscEntry{{FirstChunk: 1, SamplesPerChunk: 3, SampleDescriptionIndex: 1}}}, Context{}, nil)
+						writeTestBox(t, w, stsz, Context{}, nil)
+						writeTestBox(t, w, &Stco{EntryCount: 1, ChunkOffset: []uint32{uint32(mdat.Offset + mdat.HeaderSize)}}, Context{}, nil)
+					})
+				})
+			})
+		})
+	})
+
+	output := &bytes.Buffer{}
+	require.NoError(t, ExtractFLAC(buf.BytesReader(), output, 0))
+	assert.Error(t, ExtractFLAC(buf.BytesReader(), &bytes.Buffer{}, 2))
+
+	expected := []byte{
+		'f', 'L', 'a', 'C',
+		0x80, 0x00, 0x00, 0x22,
+		0x10, 0x00, 0x10, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x0a, 0xc4, 0x42, 0xf0, 0x00, 0x00, 0x30, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+	for _, frame := range frames {
+		expected = append(expected, frame...)
+	}
+	assert.Equal(t, expected, output.Bytes())
+}
@@ -0,0 +1,522 @@
+package mp4
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/orcaman/writerseeker"
+)
+
+// FragmentMode specifies how Fragment decides fragment boundaries.
+type FragmentMode int
+
+const (
+	// FragmentByDuration starts a new fragment at the first sample after each fixed duration.
+	FragmentByDuration FragmentMode = iota
+
+	// FragmentBySyncSample starts a new fragment at the first sync sample after each fixed duration.
+	FragmentBySyncSample
+
+	// FragmentByGOP starts a new fragment at every sync sample.
+	FragmentByGOP
+)
+
+// FragmentConfig is the configuration of Fragment.
+type FragmentConfig struct {
+	Mode FragmentMode
+
+	// Duration is the target duration of fragments which is used by FragmentByDuration and FragmentBySyncSample.
+	Duration time.Duration
+
+	// Sidx represents whether to write a sidx box which indexes all fragments.
+	Sidx bool
+
+	// Styp represents whether to write a styp box at the beginning of each fragment.
+	Styp bool
+}
+
+type fragmentTrack struct {
+	trackID   uint32
+	timescale uint32
+	video     bool
+	samples   []*TrackSample
+
+	// next is the index of the first sample of the next fragment.
+	next int
+}
+
+type fragmenter struct {
+	r      io.ReadSeeker
+	w      *Writer
+	config *FragmentConfig
+	tracks []*fragmentTrack
+	ref    *fragmentTrack
+
+	// editList represents whether any track has an edit list.
+	editList bool
+
+	timescale uint32
+	duration  uint64
+	sequence  uint32
+}
+
+// Fragment converts the progressive file which has moov and mdat boxes into the fragmented file.
+// It writes an init segment (ftyp and moov with mvex) followed by moof and mdat boxes.
+// Fragment boundaries are decided on the first video track which has samples,
+// or on the first track which has samples if there is no such video track.
+// Each fragment has one pair of moof and mdat boxes for each track.
+// The ftyp box has the cmfc brand when the file has a single track.
+func Fragment(r io.ReadSeeker, w io.WriteSeeker, config *FragmentConfig) error {
+	f := &fragmenter{
+		r:      r,
+		w:      NewWriter(w),
+		config: config,
+	}
+	if config.Mode != FragmentByGOP && config.Duration <= 0 {
+		return errors.New("fragment duration must be positive")
+	}
+	if err := f.readTracks(); err != nil {
+		return err
+	}
+
+	if err := f.writeInitSegment(); err != nil {
+		return err
+	}
+
+	boundaries, err := f.boundaries()
+	if err != nil {
+		return err
+	}
+
+	var sidx *Sidx
+	var sidxInfo *BoxInfo
+	if config.Sidx {
+		if len(boundaries) > 0xffff {
+			return fmt.Errorf("too many fragments for sidx box: %d", len(boundaries))
+		}
+		sidx = &Sidx{
+			ReferenceID:    f.ref.trackID,
+			Timescale:      f.ref.timescale,
+			ReferenceCount: uint16(len(boundaries)),
+			References:     make([]SidxReference, len(boundaries)),
+		}
+		sidx.SetVersion(1)
+		if len(f.ref.samples) != 0 {
+			sidx.EarliestPresentationTimeV1 = uint64(minPTS(f.ref.samples))
+		}
+		var err error
+		if sidxInfo, err = f.w.StartBox(&BoxInfo{Type: BoxTypeSidx()}); err != nil {
+			return err
+		}
+		if _, err := Marshal(f.w, sidx, Context{}); err != nil {
+			return err
+		}
+		if _, err := f.w.EndBox(); err != nil {
+			return err
+		}
+	}
+
+	for i, end := range boundaries {
+		start, err := f.w.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+		first := f.ref.samples[f.ref.next]
+		var duration uint64
+		for _, s := range f.ref.samples[f.ref.next:end] {
+			duration += uint64(s.Duration)
+		}
+
+		if err := f.writeFragment(end, first.DTS+duration); err != nil {
+			return err
+		}
+
+		if sidx != nil {
+			end, err := f.w.Seek(0, io.SeekCurrent)
+			if err != nil {
+				return err
+			}
+			sidx.References[i] = SidxReference{
+				ReferencedSize:     uint32(end - start),
+				SubsegmentDuration: uint32(duration),
+				StartsWithSAP:      first.IsSync,
+			}
+			if first.IsSync {
+				sidx.References[i].SAPType = 1
+			}
+		}
+	}
+
+	if sidx != nil {
+		if _, err := f.w.Seek(int64(sidxInfo.Offset+sidxInfo.HeaderSize), io.SeekStart); err != nil {
+			return err
+		}
+		if _, err := Marshal(f.w, sidx, Context{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fragmenter) readTracks() error {
+	bips, err := ExtractBoxesWithPayload(f.r, nil, []BoxPath{
+		{BoxTypeMoov(), BoxTypeMvhd()},
+		{BoxTypeMoov(), BoxTypeMvex()},
+		{BoxTypeMoov(), BoxTypeTrak(), BoxTypeTkhd()},
+		{BoxTypeMoov(), BoxTypeTrak(), BoxTypeMdia(), BoxTypeHdlr()},
+		{BoxTypeMoov(), BoxTypeTrak(), BoxTypeEdts(), BoxTypeElst()},
+	})
+	if err != nil {
+		return err
+	}
+	for _, bip := range bips {
+		switch box := bip.Payload.(type) {
+		case *Mvhd:
+			f.timescale = box.Timescale
+			if box.GetVersion() == 0 {
+				f.duration = uint64(box.DurationV0)
+			} else {
+				f.duration = box.DurationV1
+			}
+		case *Mvex:
+			return errors.New("the file is already fragmented")
+		case *Tkhd:
+			f.tracks = append(f.tracks, &fragmentTrack{trackID: box.TrackID})
+		case *Hdlr:
+			for _, track := range f.tracks {
+				if track.trackID == bip.Info.TrackID {
+					track.video = box.HandlerType == [4]byte{'v', 'i', 'd', 'e'}
+				}
+			}
+		case *Elst:
+			f.editList = true
+		}
+	}
+	if len(f.tracks) == 0 {
+		return errors.New("no track found")
+	}
+
+	for _, track := range f.tracks {
+		sr, err := NewSampleReader(f.r, track.trackID)
+		if err != nil {
+			return err
+		}
+		track.timescale = sr.Timescale
+		track.samples = sr.Samples()
+		if track.timescale == 0 {
+			return fmt.Errorf("invalid timescale: trackID=%d", track.trackID)
+		}
+		if f.ref == nil && track.video && len(track.samples) != 0 {
+			f.ref = track
+		}
+	}
+	if f.ref == nil {
+		for _, track := range f.tracks {
+			if len(track.samples) != 0 {
+				f.ref = track
+				break
+			}
+		}
+	}
+	if f.ref == nil {
+		return errors.New("no sample found")
+	}
+	return nil
+}
+
+// boundaries returns indices of the end samples of fragments on the reference track.
+func (f *fragmenter) boundaries() ([]int, error) {
+	samples := f.ref.samples
+	var target uint64
+	if f.config.Mode != FragmentByGOP {
+		target = uint64(f.config.Duration) * uint64(f.ref.timescale) / uint64(time.Second)
+		if target == 0 {
+			return nil, fmt.Errorf("fragment duration is shorter than a tick of the timescale: duration=%s, timescale=%d", f.config.Duration, f.ref.timescale)
+		}
+	}
+	boundaries := make([]int, 0)
+	var next uint64
+	if len(samples) != 0 {
+		next = samples[0].DTS + target
+	}
+	for i := 1; i < len(samples); i++ {
+		var split bool
+		switch f.config.Mode {
+		case FragmentByDuration:
+			split = samples[i].DTS >= next
+		case FragmentBySyncSample:
+			split = samples[i].DTS >= next && samples[i].IsSync
+		case FragmentByGOP:
+			split = samples[i].IsSync
+		}
+		if split {
+			boundaries = append(boundaries, i)
+			if target != 0 {
+				for next <= samples[i].DTS {
+					next += target
+				}
+			}
+		}
+	}
+	if len(samples) != 0 {
+		boundaries = append(boundaries, len(samples))
+	}
+	return boundaries, nil
+}
+
+func (f *fragmenter) writeInitSegment() error {
+	ftyp := &Ftyp{
+		MajorBrand: BrandISO6(),
+		CompatibleBrands: []CompatibleBrandElem{
+			{CompatibleBrand: BrandISO6()},
+			{CompatibleBrand: BrandMP41()},
+		},
+	}
+	// a CMAF track file has a single track
+	if len(f.tracks) == 1 {
+		ftyp.AddCompatibleBrand([4]byte{'c', 'm', 'f', 'c'})
+		if f.cmf2() {
+			ftyp.AddCompatibleBrand([4]byte{'c', 'm', 'f', '2'})
+		}
+	}
+	if err := writeBox(f.w, ftyp, Context{}); err != nil {
+		return err
+	}
+
+	bis, err := ExtractBox(f.r, nil, BoxPath{BoxTypeMoov()})
+	if err != nil {
+		return err
+	}
+	if len(bis) != 1 {
+		return errors.New("moov box not found")
+	}
+	_, err = ReadBoxStructureFromInternal(f.r, bis[0], func(h *ReadHandle) (interface{}, error) {
+		switch h.BoxInfo.Type {
+		case BoxTypeMoov():
+			return nil, writeContainerBox(f.r, f.w, h, f.writeMvex)
+		case BoxTypeTrak(), BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl():
+			return nil, writeContainerBox(f.r, f.w, h, nil)
+		case BoxTypeStts():
+			return nil, writeBox(f.w, &Stts{}, h.BoxInfo.Context)
+		case BoxTypeStsc():
+			return nil, writeBox(f.w, &Stsc{}, h.BoxInfo.Context)
+		case BoxTypeStsz(), BoxTypeStz2():
+			return nil, writeBox(f.w, &Stsz{}, h.BoxInfo.Context)
+		case BoxTypeStco(), BoxTypeCo64():
+			return nil, writeBox(f.w, &Stco{}, h.BoxInfo.Context)
+		case BoxTypeStss(), BoxTypeCtts(), BoxTypeSdtp(), BoxTypeSbgp(), BoxTypeSubs(), BoxTypeSaiz(), BoxTypeSaio():
+			// these boxes describe samples which are moved to movie fragments
+			return nil, nil
+		}
+		return nil, f.w.CopyBox(f.r, &h.BoxInfo)
+	})
+	return err
+}
+
+// cmf2 reports whether the track conforms to the cmf2 brand,
+// which requires the presentation to start at the decode time without an edit list or composition time offsets.
+func (f *fragmenter) cmf2() bool {
+	if f.editList {
+		return false
+	}
+	for _, s := range f.ref.samples {
+		if s.PTS != int64(s.DTS) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *fragmenter) writeMvex() error {
+	if _, err := f.w.StartBox(&BoxInfo{Type: BoxTypeMvex()}); err != nil {
+		return err
+	}
+	mehd := &Mehd{}
+	if f.duration > 0xffffffff {
+		mehd.SetVersion(1)
+		mehd.FragmentDurationV1 = f.duration
+	} else {
+		mehd.FragmentDurationV0 = uint32(f.duration)
+	}
+	if err := writeBox(f.w, mehd, Context{}); err != nil {
+		return err
+	}
+	for _, track := range f.tracks {
+		if err := writeBox(f.w, &Trex{
+			TrackID:                       track.trackID,
+			DefaultSampleDescriptionIndex: 1,
+		}, Context{}); err != nil {
+			return err
+		}
+	}
+	_, err := f.w.EndBox()
+	return err
+}
+
+// writeFragment writes samples of the reference track up to the end index,
+// and samples of the other tracks up to the same time.
+func (f *fragmenter) writeFragment(end int, endTime uint64) error {
+	if f.config.Styp {
+		styp := &Styp{
+			MajorBrand: [4]byte{'m', 's', 'd', 'h'},
+			CompatibleBrands: []CompatibleBrandElem{
+				{CompatibleBrand: [4]byte{'m', 's', 'd', 'h'}},
+			},
+		}
+		// msix requires the segment index
+		if f.config.Sidx {
+			styp.CompatibleBrands = append(styp.CompatibleBrands, CompatibleBrandElem{CompatibleBrand: [4]byte{'m', 's', 'i', 'x'}})
+		}
+		if err := writeBox(f.w, styp, Context{}); err != nil {
+			return err
+		}
+	}
+
+	last := end == len(f.ref.samples)
+	for _, track := range f.tracks {
+		trackEnd := track.next
+		if track == f.ref {
+			trackEnd = end
+		} else if last {
+			trackEnd = len(track.samples)
+		} else {
+			for trackEnd < len(track.samples) &&
+				track.samples[trackEnd].DTS*uint64(f.ref.timescale) < endTime*uint64(track.timescale) {
+				trackEnd++
+			}
+		}
+		if trackEnd == track.next {
+			continue
+		}
+		samples := track.samples[track.next:trackEnd]
+		track.next = trackEnd
+		if err := f.writeTrackFragment(track, samples); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fragmenter) writeTrackFragment(track *fragmentTrack, samples []*TrackSample) error {
+	f.sequence++
+	moof, err := buildTrackFragmentMoof(f.sequence, track.trackID, samples)
+	if err != nil {
+		return err
+	}
+	if _, err := f.w.Write(moof); err != nil {
+		return err
+	}
+
+	if _, err := f.w.StartBox(&BoxInfo{Type: BoxTypeMdat()}); err != nil {
+		return err
+	}
+	for _, s := range samples {
+		if _, err := f.r.Seek(int64(s.Offset), io.SeekStart); err != nil {
+			return err
+		}
+		if _, err := io.CopyN(f.w, f.r, int64(s.Size)); err != nil {
+			return err
+		}
+	}
+	_, err = f.w.EndBox()
+	return err
+}
+
+// buildTrackFragmentMoof builds a moof box which has a single traf box for the samples.
+// The data offset of the trun box points to the payload of the mdat box which follows the moof box.
+// The extra boxes (e.g. subs) are appended to the traf box.
+func buildTrackFragmentMoof(sequence, trackID uint32, samples []*TrackSample, extra ...IBox) ([]byte, error) {
+	trun := &Trun{
+		SampleCount: uint32(len(samples)),
+		Entries:     make([]TrunEntry, len(samples)),
+	}
+	flags := uint32(TrunDataOffsetPresent | TrunSampleDurationPresent | TrunSampleSizePresent | TrunSampleFlagsPresent)
+	for i, s := range samples {
+		cto := s.PTS - int64(s.DTS)
+		if cto != 0 {
+			flags |= TrunSampleCompositionTimeOffsetPresent
+		}
+		if cto < 0 {
+			trun.SetVersion(1)
+		}
+		trun.Entries[i] = TrunEntry{
+			SampleDuration:                s.Duration,
+			SampleSize:                    s.Size,
+			SampleFlags:                   fragmentSampleFlags(s),
+			SampleCompositionTimeOffsetV0: uint32(cto),
+			SampleCompositionTimeOffsetV1: int32(cto),
+		}
+	}
+	trun.SetFlags(flags)
+
+	tfhd := &Tfhd{
+		FullBox: FullBox{Flags: [3]byte{0x02, 0x00, 0x00}}, // default-base-is-moof
+		TrackID: trackID,
+	}
+	if index := samples[0].SampleDescriptionIndex; index != 1 {
+		tfhd.AddFlag(TfhdSampleDescriptionIndexPresent)
+		tfhd.SampleDescriptionIndex = index
+	}
+	tfdt := &Tfdt{}
+	tfdt.SetVersion(1)
+	tfdt.BaseMediaDecodeTimeV1 = samples[0].DTS
+
+	// the moof box is built twice, because the data offset depends on its size
+	var moof []byte
+	for i := 0; i < 2; i++ {
+		buf := &writerseeker.WriterSeeker{}
+		w := NewWriter(buf)
+		if _, err := w.StartBox(&BoxInfo{Type: BoxTypeMoof()}); err != nil {
+			return nil, err
+		}
+		if err := writeBox(w, &Mfhd{SequenceNumber: sequence}, Context{}); err != nil {
+			return nil, err
+		}
+		if _, err := w.StartBox(&BoxInfo{Type: BoxTypeTraf()}); err != nil {
+			return nil, err
+		}
+		for _, box := range append([]IBox{tfhd, tfdt, trun}, extra...) {
+			if err := writeBox(w, box, Context{}); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := w.EndBox(); err != nil { // traf
+			return nil, err
+		}
+		if _, err := w.EndBox(); err != nil { // moof
+			return nil, err
+		}
+		var err error
+		if moof, err = io.ReadAll(buf.BytesReader()); err != nil {
+			return nil, err
+		}
+		trun.DataOffset = int32(len(moof)) + int32(SmallHeaderSize)
+	}
+	return moof, nil
+}
+
+// fragmentSampleFlags returns the sample flags of the sample.
+// When the dependency is unknown, it is derived from whether the sample is a sync sample.
+func fragmentSampleFlags(s *TrackSample) uint32 {
+	flags := s.Flags
+	if flags&SampleFlagDependsOnMask == 0 {
+		if s.IsSync {
+			flags |= SampleFlagDependsOnNone
+		} else {
+			flags |= SampleFlagDependsOnOthers
+		}
+	}
+	return flags
+}
+
+func minPTS(samples []*TrackSample) int64 {
+	min := samples[0].PTS
+	for _, s := range samples {
+		if s.PTS < min {
+			min = s.PTS
+		}
+	}
+	return min
+}
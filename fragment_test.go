@@ -0,0 +1,293 @@
+package mp4
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/orcaman/writerseeker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFragment(t *testing.T) {
+	f, err := os.Open("./testdata/sample.mp4")
+	require.NoError(t, err)
+	defer f.Close()
+
+	testCases := []struct {
+		name      string
+		config    FragmentConfig
+		fragments int
+	}{
+		{name: "duration", config: FragmentConfig{Mode: FragmentByDuration, Duration: 200 * time.Millisecond}, fragments: 5},
+		{name: "sync sample", config: FragmentConfig{Mode: FragmentBySyncSample, Duration: 200 * time.Millisecond}, fragments: 1},
+		{name: "gop with sidx and styp", config: FragmentConfig{Mode: FragmentByGOP, Sidx: true, Styp: true}, fragments: 1},
+		{name: "duration with sidx and styp", config: FragmentConfig{Mode: FragmentByDuration, Duration: 300 * time.Millisecond, Sidx: true, Styp: true}, fragments: 4},
+		{name: "duration with styp", config: FragmentConfig{Mode: FragmentByDuration, Duration: 300 * time.Millisecond, Styp: true}, fragments: 4},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			output := &writerseeker.WriterSeeker{}
+			require.NoError(t, Fragment(f, output, &tc.config))
+			r := output.BytesReader()
+
+			bis, err := ExtractBoxes(r, nil, []BoxPath{
+				{BoxTypeMoov(), BoxTypeMvex(), BoxTypeTrex()},
+				{BoxTypeMoov(), BoxTypeTrak(), BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStss()},
+				{BoxTypeMoof()},
+			})
+			require.NoError(t, err)
+			assert.Len(t, bis, 2+2*tc.fragments)
+
+			bips, err := ExtractBoxesWithPayload(r, nil, []BoxPath{{BoxTypeStyp()}})
+			require.NoError(t, err)
+			if tc.config.Styp {
+				require.Len(t, bips, tc.fragments)
+				var msix bool
+				for _, elem := range bips[0].Payload.(*Styp).CompatibleBrands {
+					msix = msix || elem.CompatibleBrand == [4]byte{'m', 's', 'i', 'x'}
+				}
+				assert.Equal(t, tc.config.Sidx, msix)
+			} else {
+				assert.Empty(t, bips)
+			}
+
+			bips, err = ExtractBoxesWithPayload(r, nil, []BoxPath{{BoxTypeSidx()}, {BoxTypeStyp()}})
+			require.NoError(t, err)
+			if tc.config.Sidx {
+				require.Len(t, bips, 1+tc.fragments)
+				sidx := bips[0].Payload.(*Sidx)
+				assert.Equal(t, uint32(1), sidx.ReferenceID)
+				assert.Equal(t, uint32(10240), sidx.Timescale)
+				require.Len(t, sidx.References, tc.fragments)
+				offset := bips[0].Info.Offset + bips[0].Info.Size
+				for i, ref := range sidx.References {
+					assert.Equal(t, bips[1+i].Info.Offset, offset)
+					offset += uint64(ref.ReferencedSize)
+				}
+				assert.True(t, sidx.References[0].StartsWithSAP)
+			}
+
+			// the file has two tracks, which is not a CMAF track file
+			bips, err = ExtractBoxWithPayload(r, nil, BoxPath{BoxTypeFtyp()})
+			require.NoError(t, err)
+			require.Len(t, bips, 1)
+			assert.False(t, bips[0].Payload.(*Ftyp).HasCompatibleBrand([4]byte{'c', 'm', 'f', 'c'}))
+
+			for _, trackID := range []uint32{1, 2} {
+				orig, err := NewSampleReader(f, trackID)
+				require.NoError(t, err)
+				frag, err := NewSampleReader(r, trackID)
+				require.NoError(t, err)
+				assert.Equal(t, orig.Timescale, frag.Timescale)
+				require.Len(t, frag.Samples(), len(orig.Samples()))
+				for {
+					s1, err := orig.Next()
+					if err != nil {
+						break
+					}
+					s2, err := frag.Next()
+					require.NoError(t, err)
+					assert.Equal(t, s1.Data, s2.Data)
+					assert.Equal(t, s1.DTS, s2.DTS)
+					assert.Equal(t, s1.PTS, s2.PTS)
+					assert.Equal(t, s1.Duration, s2.Duration)
+					assert.Equal(t, s1.IsSync, s2.IsSync)
+				}
+			}
+		})
+	}
+
+	fragmented, err := os.Open("./testdata/sample_fragmented.mp4")
+	require.NoError(t, err)
+	defer fragmented.Close()
+	assert.Error(t, Fragment(fragmented, &writerseeker.WriterSeeker{}, &FragmentConfig{Mode: FragmentByGOP}))
+}
+
+func TestFragmentMultipleGOPs(t *testing.T) {
+	fragmented, err := os.Open("./testdata/sample_fragmented.mp4")
+	require.NoError(t, err)
+	defer fragmented.Close()
+
+	// the video track has 4 sync samples
+	progressive := &writerseeker.WriterSeeker{}
+	require.NoError(t, Defragment(fragmented, progressive, &DefragmentConfig{FastStart: true}))
+	r := progressive.BytesReader()
+
+	testCases := []struct {
+		name      string
+		config    FragmentConfig
+		fragments int
+	}{
+		{name: "gop", config: FragmentConfig{Mode: FragmentByGOP}, fragments: 4},
+		{name: "sync sample", config: FragmentConfig{Mode: FragmentBySyncSample, Duration: 200 * time.Millisecond}, fragments: 4},
+		{name: "sync sample with long duration", config: FragmentConfig{Mode: FragmentBySyncSample, Duration: 500 * time.Millisecond}, fragments: 2},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			output := &writerseeker.WriterSeeker{}
+			require.NoError(t, Fragment(r, output, &tc.config))
+
+			bis, err := ExtractBoxes(output.BytesReader(), nil, []BoxPath{{BoxTypeMoof()}})
+			require.NoError(t, err)
+			assert.Len(t, bis, 2*tc.fragments)
+
+			sr, err := NewSampleReader(output.BytesReader(), 1)
+			require.NoError(t, err)
+			var syncSamples []int
+			for _, s := range sr.Samples() {
+				if s.IsSync {
+					syncSamples = append(syncSamples, s.Index)
+				}
+			}
+			assert.Equal(t, []int{0, 3, 5, 8}, syncSamples)
+		})
+	}
+
+	// the duration is shorter than a tick of the timescale (10240)
+	for _, mode := range []FragmentMode{FragmentByDuration, FragmentBySyncSample} {
+		err := Fragment(r, &writerseeker.WriterSeeker{}, &FragmentConfig{Mode: mode, Duration: time.Microsecond})
+		assert.Error(t, err)
+	}
+}
+
+// buildTestProgressiveFile builds a progressive file which has a video track and an audio track
+// with the specified numbers of samples of a single byte and a duration of 100.
+func buildTestProgressiveFile(t *testing.T, videoSamples, audioSamples int, editList bool) io.ReadSeeker {
+	buf := &writerseeker.WriterSeeker{}
+	w := NewWriter(buf)
+	ctx := Context{}
+	writeTestBox(t, w, &Ftyp{MajorBrand: BrandISOM()}, ctx, nil)
+	mdat, err := w.StartBox(&BoxInfo{Type: BoxTypeMdat()})
+	require.NoError(t, err)
+	_, err = w.Write(make([]byte, videoSamples+audioSamples))
+	require.NoError(t, err)
+	_, err = w.EndBox()
+	require.NoError(t, err)
+
+	writeTrack := func(trackID uint32, samples int, offset uint64, handler [4]byte, sampleEntry IBox) {
+		writeTestBox(t, w, &Trak{}, ctx, func() {
+			writeTestBox(t, w, &Tkhd{TrackID: trackID}, ctx, nil)
+			if editList {
+				writeTestBox(t, w, &Edts{}, ctx, func() {
+					writeTestBox(t, w, &Elst{
+						EntryCount: 1,
+						Entries:    []ElstEntry{{SegmentDurationV0: uint32(samples * 100), MediaRateInteger: 1}},
+					}, ctx, nil)
+				})
+			}
+			writeTestBox(t, w, &Mdia{}, ctx, func() {
+				writeTestBox(t, w, &Mdhd{Timescale: 1000}, ctx, nil)
+				writeTestBox(t, w, &Hdlr{HandlerType: handler}, ctx, nil)
+				writeTestBox(t, w, &Minf{}, ctx, func() {
+					writeTestBox(t, w, &Stbl{}, ctx, func() {
+						writeTestBox(t, w, &Stsd{EntryCount: 1}, ctx, func() {
+							writeTestBox(t, w, sampleEntry, ctx, nil)
+						})
+						if samples == 0 {
+							writeTestBox(t, w, &Stts{}, ctx, nil)
+							writeTestBox(t, w, &Stsc{}, ctx, nil)
+							writeTestBox(t, w, &Stsz{}, ctx, nil)
+							writeTestBox(t, w, &Stco{}, ctx, nil)
+							return
+						}
+						writeTestBox(t, w, &Stts{EntryCount: 1, Entries: []SttsEntry{{SampleCount: uint32(samples), SampleDelta: 100}}}, ctx, nil)
+						writeTestBox(t, w, &Stsc{EntryCount: 1, Entries: []StscEntry{{FirstChunk: 1, SamplesPerChunk: uint32(samples), SampleDescriptionIndex: 1}}}, ctx, nil)
+						writeTestBox(t, w, &Stsz{SampleSize: 1, SampleCount: uint32(samples)}, ctx, nil)
+						writeTestBox(t, w, &Stco{EntryCount: 1, ChunkOffset: []uint32{uint32(offset)}}, ctx, nil)
+					})
+				})
+			})
+		})
+	}
+	writeTestBox(t, w, &Moov{}, ctx, func() {
+		writeTestBox(t, w, &Mvhd{Timescale: 1000, NextTrackID: 3}, ctx, nil)
+		offset := mdat.Offset + mdat.HeaderSize
+		if videoSamples >= 0 {
+			writeTrack(1, videoSamples, offset, [4]byte{'v', 'i', 'd', 'e'}, &VisualSampleEntry{
+				SampleEntry: SampleEntry{AnyTypeBox: AnyTypeBox{Type: BoxTypeAvc1()}, DataReferenceIndex: 1},
+				Width:       320,
+				Height:      180,
+			})
+			offset += uint64(videoSamples)
+		}
+		writeTrack(2, audioSamples, offset, [4]byte{'s', 'o', 'u', 'n'}, &AudioSampleEntry{
+			SampleEntry:  SampleEntry{AnyTypeBox: AnyTypeBox{Type: BoxTypeMp4a()}, DataReferenceIndex: 1},
+			ChannelCount: 2,
+			SampleSize:   16,
+			SampleRate:   48000 << 16,
+		})
+	})
+	data, err := io.ReadAll(buf.BytesReader())
+	require.NoError(t, err)
+	return bytes.NewReader(data)
+}
+
+func TestFragmentReferenceTrack(t *testing.T) {
+	// the video track has no sample
+	r := buildTestProgressiveFile(t, 0, 10, false)
+	output := &writerseeker.WriterSeeker{}
+	require.NoError(t, Fragment(r, output, &FragmentConfig{Mode: FragmentByDuration, Duration: 500 * time.Millisecond, Sidx: true}))
+	bips, err := ExtractBoxesWithPayload(output.BytesReader(), nil, []BoxPath{{BoxTypeSidx()}, {BoxTypeMoof()}})
+	require.NoError(t, err)
+	require.Len(t, bips, 1+2)
+	assert.Equal(t, uint32(2), bips[0].Payload.(*Sidx).ReferenceID)
+	sr, err := NewSampleReader(output.BytesReader(), 2)
+	require.NoError(t, err)
+	assert.Len(t, sr.Samples(), 10)
+
+	// no track has samples
+	r = buildTestProgressiveFile(t, 0, 0, false)
+	assert.Error(t, Fragment(r, &writerseeker.WriterSeeker{}, &FragmentConfig{Mode: FragmentByGOP}))
+}
+
+func TestFragmentSidxReferenceCount(t *testing.T) {
+	r := buildTestProgressiveFile(t, 0x10000, 0, false)
+	config := &FragmentConfig{Mode: FragmentByDuration, Duration: 100 * time.Millisecond, Sidx: true}
+	assert.Error(t, Fragment(r, &writerseeker.WriterSeeker{}, config))
+
+	r = buildTestProgressiveFile(t, 0xffff, 0, false)
+	config.Duration = 200 * time.Millisecond
+	assert.NoError(t, Fragment(r, &writerseeker.WriterSeeker{}, config))
+}
+
+func TestFragmentCMAFBrands(t *testing.T) {
+	testCases := []struct {
+		name   string
+		r      io.ReadSeeker
+		brands [][4]byte
+	}{
+		{
+			name:   "single track",
+			r:      buildTestProgressiveFile(t, -1, 10, false),
+			brands: [][4]byte{BrandISO6(), BrandMP41(), {'c', 'm', 'f', 'c'}, {'c', 'm', 'f', '2'}},
+		},
+		{
+			name:   "single track with edit list",
+			r:      buildTestProgressiveFile(t, -1, 10, true),
+			brands: [][4]byte{BrandISO6(), BrandMP41(), {'c', 'm', 'f', 'c'}},
+		},
+		{
+			name:   "multiple tracks",
+			r:      buildTestProgressiveFile(t, 10, 10, false),
+			brands: [][4]byte{BrandISO6(), BrandMP41()},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			output := &writerseeker.WriterSeeker{}
+			require.NoError(t, Fragment(tc.r, output, &FragmentConfig{Mode: FragmentByGOP}))
+			bips, err := ExtractBoxWithPayload(output.BytesReader(), nil, BoxPath{BoxTypeFtyp()})
+			require.NoError(t, err)
+			require.Len(t, bips, 1)
+			var brands [][4]byte
+			for _, elem := range bips[0].Payload.(*Ftyp).CompatibleBrands {
+				brands = append(brands, elem.CompatibleBrand)
+			}
+			assert.Equal(t, tc.brands, brands)
+		})
+	}
+}
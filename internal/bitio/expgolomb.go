@@ -0,0 +1,56 @@
+package bitio
+
+import "errors"
+
+var ErrExpGolombOverflow = errors.New("exp-golomb code overflow")
+
+// ReadUint reads an unsigned integer of the specified width (up to 64 bits).
+func ReadUint(r Reader, width uint) (uint64, error) {
+	var v uint64
+	for i := uint(0); i < width; i++ {
+		bit, err := r.ReadBit()
+		if err != nil {
+			return 0, err
+		}
+		v <<= 1
+		if bit {
+			v |= 1
+		}
+	}
+	return v, nil
+}
+
+// ReadUE reads an unsigned integer Exp-Golomb-coded (ue(v) of ITU-T H.264 and H.265).
+func ReadUE(r Reader) (uint64, error) {
+	var leadingZeros uint
+	for {
+		bit, err := r.ReadBit()
+		if err != nil {
+			return 0, err
+		}
+		if bit {
+			break
+		}
+		leadingZeros++
+		if leadingZeros > 63 {
+			return 0, ErrExpGolombOverflow
+		}
+	}
+	v, err := ReadUint(r, leadingZeros)
+	if err != nil {
+		return 0, err
+	}
+	return (1<<leadingZeros - 1) + v, nil
+}
+
+// ReadSE reads a signed integer Exp-Golomb-coded (se(v) of ITU-T H.264 and H.265).
+func ReadSE(r Reader) (int64, error) {
+	v, err := ReadUE(r)
+	if err != nil {
+		return 0, err
+	}
+	if v&1 != 0 {
+		return int64(v/2) + 1, nil
+	}
+	return -int64(v / 2), nil
+}
@@ -0,0 +1,32 @@
+package bitio
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadExpGolomb(t *testing.T) {
+	// ue: 1 010 011 00100 00111 0001000
+	// se: 010 011 00100 00101
+	// u(3): 100
+	r := NewReader(bytes.NewReader([]byte{0xa6, 0x43, 0x88, 0x4c, 0x85, 0x80}))
+	for _, expected := range []uint64{0, 1, 2, 3, 6, 7} {
+		v, err := ReadUE(r)
+		require.NoError(t, err)
+		assert.Equal(t, expected, v)
+	}
+	for _, expected := range []int64{1, -1, 2, -2} {
+		v, err := ReadSE(r)
+		require.NoError(t, err)
+		assert.Equal(t, expected, v)
+	}
+	v, err := ReadUint(r, 3)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0x4), v)
+	_, err = ReadUE(r)
+	assert.Equal(t, io.EOF, err)
+}
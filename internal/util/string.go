@@ -30,6 +30,15 @@ func FormatSignedFixedFloat88(val int16) string {
 	}
 }
 
+// FormatFixedDecimal formats the value in units of 1/divisor (e.g. 0.00002 when divisor is 50000).
+func FormatFixedDecimal(val int64, divisor int64) string {
+	if val%divisor == 0 {
+		return strconv.FormatInt(val/divisor, 10)
+	} else {
+		return strconv.FormatFloat(float64(val)/float64(divisor), 'f', -1, 64)
+	}
+}
+
 func EscapeUnprintable(r rune) rune {
 	if unicode.IsGraphic(r) {
 		return r
@@ -32,6 +32,15 @@ func TestFormatSignedFixedFloat88(t *testing.T) {
 	assert.Equal(t, "-123.457", FormatSignedFixedFloat88(-0x7b75))
 }
 
+func TestFormatFixedDecimal(t *testing.T) {
+	assert.Equal(t, "1000", FormatFixedDecimal(10000000, 10000))
+	assert.Equal(t, "-2", FormatFixedDecimal(-100000, 50000))
+	assert.Equal(t, "0.68", FormatFixedDecimal(34000, 50000))
+	assert.Equal(t, "0.3127", FormatFixedDecimal(15635, 50000))
+	assert.Equal(t, "0.005", FormatFixedDecimal(50, 10000))
+	assert.Equal(t, "-0.0001", FormatFixedDecimal(-1, 10000))
+}
+
 func TestEscapeUnprintables(t *testing.T) {
 	assert.Equal(t, ".ABC.あいう.", EscapeUnprintables(string([]byte{
 		0x00,             // NULL
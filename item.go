@@ -0,0 +1,304 @@
+package mp4
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// MetaItem is an item of the file-level meta box, such as an image of HEIF or AVIF files.
+type MetaItem struct {
+	ID uint32
+	// Type is the item type (e.g. "hvc1", "av01", "grid", "Exif" and "mime").
+	// It is zero if the infe box is version 0 or 1, which has no item type.
+	Type            [4]byte
+	Name            string
+	ContentType     string
+	ContentEncoding string
+	// Hidden reports whether the item is not intended to be displayed (e.g. a tile of a grid image).
+	Hidden bool
+
+	// Properties are the properties associated with the item in order of the ipma box.
+	Properties []*ItemProperty
+	// References are the references from the item to other items.
+	References []ItemReference
+}
+
+// ItemProperty is an item property in the ipco box.
+type ItemProperty struct {
+	Type      BoxType
+	Essential bool
+	// Box is nil if the property type is not supported.
+	Box IBox
+}
+
+// Property returns the first property of the box type, or nil if there is none.
+func (item *MetaItem) Property(boxType BoxType) IBox {
+	for _, prop := range item.Properties {
+		if prop.Type == boxType && prop.Box != nil {
+			return prop.Box
+		}
+	}
+	return nil
+}
+
+// ReferencedItemIDs returns the IDs of items referenced by the item with the reference type (e.g. "thmb", "cdsc" and "dimg").
+func (item *MetaItem) ReferencedItemIDs(referenceType [4]byte) []uint32 {
+	var ids []uint32
+	for _, ref := range item.References {
+		if ref.ReferenceType == referenceType {
+			ids = append(ids, ref.ToItemIDs...)
+		}
+	}
+	return ids
+}
+
+var (
+	itemReferenceThumbnail = [4]byte{'t', 'h', 'm', 'b'}
+	itemReferenceLocation  = [4]byte{'i', 'l', 'o', 'c'}
+)
+
+// ItemReader reads items of the file-level meta box of HEIF or AVIF files.
+type ItemReader struct {
+	// PrimaryItemID is the ID of the primary item, which is zero if there is no pitm box.
+	PrimaryItemID uint32
+	// Items are the items in order of the iinf box.
+	Items []*MetaItem
+
+	r         io.ReadSeeker
+	locations map[uint32]*IlocItem
+	idat      *BoxInfo
+}
+
+// NewItemReader returns a new ItemReader.
+func NewItemReader(r io.ReadSeeker) (*ItemReader, error) {
+	bis, err := ExtractBox(r, nil, BoxPath{BoxTypeMeta()})
+	if err != nil {
+		return nil, err
+	}
+	if len(bis) == 0 {
+		return nil, errors.New("meta box not found")
+	}
+	meta := bis[0]
+
+	ir := &ItemReader{
+		r:         r,
+		locations: make(map[uint32]*IlocItem),
+	}
+	bips, err := ExtractBoxesWithPayload(r, meta, []BoxPath{
+		{BoxTypePitm()},
+		{BoxTypeIinf(), BoxTypeInfe()},
+		{BoxTypeIloc()},
+		{BoxTypeIref()},
+		{BoxTypeIprp(), BoxTypeIpma()},
+	})
+	if err != nil {
+		return nil, err
+	}
+	items := make(map[uint32]*MetaItem)
+	var ipmas []*Ipma
+	for _, bip := range bips {
+		switch box := bip.Payload.(type) {
+		case *Pitm:
+			ir.PrimaryItemID = box.ItemID
+		case *Infe:
+			item := &MetaItem{
+				ID:              box.ItemID,
+				Type:            box.ItemType,
+				Name:            box.ItemName,
+				ContentType:     box.ContentType,
+				ContentEncoding: box.ContentEncoding,
+				Hidden:          box.IsHidden(),
+			}
+			items[item.ID] = item
+			ir.Items = append(ir.Items, item)
+		case *Iloc:
+			for i := range box.Items {
+				ir.locations[box.Items[i].ItemID] = &box.Items[i]
+			}
+		case *Iref:
+			for _, ref := range box.References {
+				if item := items[ref.FromItemID]; item != nil {
+					item.References = append(item.References, ref)
+				}
+			}
+		case *Ipma:
+			ipmas = append(ipmas, box)
+		}
+	}
+
+	properties, err := readItemProperties(r, meta)
+	if err != nil {
+		return nil, err
+	}
+	for _, ipma := range ipmas {
+		for i, entry := range ipma.Entries {
+			item := items[ipma.GetItemID(i)]
+			if item == nil {
+				continue
+			}
+			for _, assoc := range entry.Associations {
+				if assoc.PropertyIndex == 0 {
+					continue
+				}
+				if int(assoc.PropertyIndex) > len(properties) {
+					return nil, fmt.Errorf("invalid property index: itemID=%d, index=%d", item.ID, assoc.PropertyIndex)
+				}
+				prop := *properties[assoc.PropertyIndex-1]
+				prop.Essential = assoc.Essential
+				item.Properties = append(item.Properties, &prop)
+			}
+		}
+	}
+
+	idats, err := ExtractBox(r, meta, BoxPath{BoxTypeIdat()})
+	if err != nil {
+		return nil, err
+	}
+	if len(idats) != 0 {
+		ir.idat = idats[0]
+	}
+	return ir, nil
+}
+
+// readItemProperties reads the properties in the ipco box in order.
+func readItemProperties(r io.ReadSeeker, meta *BoxInfo) ([]*ItemProperty, error) {
+	bis, err := ExtractBox(r, meta, BoxPath{BoxTypeIprp(), BoxTypeIpco(), BoxTypeAny()})
+	if err != nil {
+		return nil, err
+	}
+	properties := make([]*ItemProperty, 0, len(bis))
+	for _, bi := range bis {
+		prop := &ItemProperty{Type: bi.Type}
+		if bi.IsSupportedType() {
+			if _, err := bi.SeekToPayload(r); err != nil {
+				return nil, err
+			}
+			prop.Box, _, err = UnmarshalAny(r, bi.Type, bi.Size-bi.HeaderSize, bi.Context)
+			if err == ErrUnsupportedBoxVersion {
+				prop.Box = nil
+			} else if err != nil {
+				return nil, err
+			}
+		}
+		properties = append(properties, prop)
+	}
+	return properties, nil
+}
+
+// Item returns the item of the ID, or nil if there is none.
+func (ir *ItemReader) Item(id uint32) *MetaItem {
+	for _, item := range ir.Items {
+		if item.ID == id {
+			return item
+		}
+	}
+	return nil
+}
+
+// PrimaryItem returns the primary item, or nil if there is none.
+func (ir *ItemReader) PrimaryItem() *MetaItem {
+	if ir.PrimaryItemID == 0 {
+		return nil
+	}
+	return ir.Item(ir.PrimaryItemID)
+}
+
+// Thumbnails returns the thumbnail items of the item.
+func (ir *ItemReader) Thumbnails(id uint32) []*MetaItem {
+	var thumbnails []*MetaItem
+	for _, item := range ir.Items {
+		for _, to := range item.ReferencedItemIDs(itemReferenceThumbnail) {
+			if to == id {
+				thumbnails = append(thumbnails, item)
+				break
+			}
+		}
+	}
+	return thumbnails
+}
+
+// ReadItemData reads the data of the item, which is concatenated from the extents in the iloc box.
+// The extents are resolved in the file (mdat), the idat box, or other items.
+func (ir *ItemReader) ReadItemData(id uint32) ([]byte, error) {
+	return ir.readItemData(id, make(map[uint32]bool))
+}
+
+func (ir *ItemReader) readItemData(id uint32, visited map[uint32]bool) ([]byte, error) {
+	if visited[id] {
+		return nil, fmt.Errorf("circular item reference: itemID=%d", id)
+	}
+	visited[id] = true
+	defer delete(visited, id)
+
+	loc := ir.locations[id]
+	if loc == nil {
+		return nil, fmt.Errorf("item location not found: itemID=%d", id)
+	}
+	if loc.DataReferenceIndex != 0 {
+		return nil, fmt.Errorf("external data reference is not supported: itemID=%d", id)
+	}
+
+	var data []byte
+	for _, extent := range loc.Extents {
+		offset := loc.BaseOffset + extent.ExtentOffset
+		var source []byte
+		var sourceOffset, sourceSize uint64
+		switch loc.ConstructionMethod {
+		case IlocConstructionMethodFileOffset:
+			end, err := ir.r.Seek(0, io.SeekEnd)
+			if err != nil {
+				return nil, err
+			}
+			sourceSize = uint64(end)
+		case IlocConstructionMethodIdatOffset:
+			if ir.idat == nil {
+				return nil, fmt.Errorf("idat box not found: itemID=%d", id)
+			}
+			sourceOffset = ir.idat.Offset + ir.idat.HeaderSize
+			sourceSize = ir.idat.Size - ir.idat.HeaderSize
+		case IlocConstructionMethodItemOffset:
+			item := ir.Item(id)
+			if item == nil {
+				return nil, fmt.Errorf("item not found: itemID=%d", id)
+			}
+			ids := item.ReferencedItemIDs(itemReferenceLocation)
+			index := extent.ExtentIndex
+			if index == 0 {
+				index = 1
+			}
+			if index > uint64(len(ids)) {
+				return nil, fmt.Errorf("invalid extent index: itemID=%d, index=%d", id, extent.ExtentIndex)
+			}
+			var err error
+			if source, err = ir.readItemData(ids[index-1], visited); err != nil {
+				return nil, err
+			}
+			sourceSize = uint64(len(source))
+		default:
+			return nil, fmt.Errorf("unsupported construction method: itemID=%d, method=%d", id, loc.ConstructionMethod)
+		}
+
+		length := extent.ExtentLength
+		if length == 0 && offset <= sourceSize {
+			// the extent reaches the end of the source
+			length = sourceSize - offset
+		}
+		if offset > sourceSize || length > sourceSize-offset {
+			return nil, fmt.Errorf("extent is out of range: itemID=%d, offset=%d, length=%d", id, offset, length)
+		}
+		if loc.ConstructionMethod == IlocConstructionMethodItemOffset {
+			data = append(data, source[offset:offset+length]...)
+			continue
+		}
+		if _, err := ir.r.Seek(int64(sourceOffset+offset), io.SeekStart); err != nil {
+			return nil, err
+		}
+		buf := make([]byte, length)
+		if _, err := io.ReadFull(ir.r, buf); err != nil {
+			return nil, err
+		}
+		data = append(data, buf...)
+	}
+	return data, nil
+}
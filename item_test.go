@@ -0,0 +1,177 @@
+package mp4
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/orcaman/writerseeker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func buildTestHEIF(t *testing.T) io.ReadSeeker {
+	buf := &writerseeker.WriterSeeker{}
+	w := NewWriter(buf)
+	ctx := Context{}
+	writeTestBox(t, w, &Ftyp{
+		MajorBrand:       [4]byte{'h', 'e', 'i', 'c'},
+		CompatibleBrands: []CompatibleBrandElem{{CompatibleBrand: [4]byte{'m', 'i', 'f', '1'}}},
+	}, ctx, nil)
+	offset, err := w.Seek(0, io.SeekCurrent)
+	require.NoError(t, err)
+	mdatOffset := uint64(offset) + 8
+	writeTestBox(t, w, &Mdat{Data: []byte("PRIMARY-IMGTHMB")}, ctx, nil)
+
+	writeTestBox(t, w, &Meta{}, ctx, func() {
+		writeTestBox(t, w, &Hdlr{HandlerType: [4]byte{'p', 'i', 'c', 't'}}, ctx, nil)
+		writeTestBox(t, w, &Pitm{ItemID: 1}, ctx, nil)
+		writeTestBox(t, w, &Iinf{EntryCount: 4}, ctx, func() {
+			writeTestBox(t, w, &Infe{FullBox: FullBox{Version: 2}, ItemID: 1, ItemType: [4]byte{'h', 'v', 'c', '1'}, ItemName: "primary"}, ctx, nil)
+			writeTestBox(t, w, &Infe{FullBox: FullBox{Version: 2}, ItemID: 2, ItemType: [4]byte{'h', 'v', 'c', '1'}}, ctx, nil)
+			writeTestBox(t, w, &Infe{FullBox: FullBox{Version: 2}, ItemID: 3, ItemType: [4]byte{'E', 'x', 'i', 'f'}}, ctx, nil)
+			writeTestBox(t, w, &Infe{FullBox: FullBox{Version: 2, Flags: [3]byte{0, 0, 1}}, ItemID: 4, ItemType: [4]byte{'h', 'v', 'c', '1'}}, ctx, nil)
+		})
+		writeTestBox(t, w, &Iref{References: []ItemReference{
+			{ReferenceType: [4]byte{'t', 'h', 'm', 'b'}, FromItemID: 2, ReferenceCount: 1, ToItemIDs: []uint32{1}},
+			{ReferenceType: [4]byte{'c', 'd', 's', 'c'}, FromItemID: 3, ReferenceCount: 1, ToItemIDs: []uint32{1}},
+			{ReferenceType: [4]byte{'i', 'l', 'o', 'c'}, FromItemID: 4, ReferenceCount: 1, ToItemIDs: []uint32{1}},
+		}}, ctx, nil)
+		writeTestBox(t, w, &Iprp{}, ctx, func() {
+			writeTestBox(t, w, &Ipco{}, ctx, func() {
+				writeTestBox(t, w, &Av1C{Marker: 1, Version: 1}, ctx, nil)
+				writeTestBox(t, w, &Ispe{ImageWidth: 1920, ImageHeight: 1080}, ctx, nil)
+				writeTestBox(t, w, &Colr{ColourType: [4]byte{'n', 'c', 'l', 'x'}, ColourPrimaries: 1, TransferCharacteristics: 1, MatrixCoefficients: 1}, ctx, nil)
+				writeTestBox(t, w, &Pixi{NumChannels: 3, BitsPerChannel: []uint8{8, 8, 8}}, ctx, nil)
+				writeTestBox(t, w, &Irot{Angle: 1}, ctx, nil)
+				writeTestBox(t, w, &Imir{Axis: 1}, ctx, nil)
+				writeTestBox(t, w, &Ispe{ImageWidth: 320, ImageHeight: 180}, ctx, nil)
+				// unsupported property
+				_, err := w.StartBox(&BoxInfo{Type: StrToBoxType("xxxx")})
+				require.NoError(t, err)
+				_, err = w.Write([]byte{0x01, 0x02})
+				require.NoError(t, err)
+				_, err = w.EndBox()
+				require.NoError(t, err)
+			})
+			writeTestBox(t, w, &Ipma{EntryCount: 3, Entries: []IpmaEntry{
+				{ItemIDV0: 1, AssociationCount: 6, Associations: []IpmaAssociation{
+					{Essential: true, PropertyIndex: 1},
+					{PropertyIndex: 2},
+					{PropertyIndex: 3},
+					{PropertyIndex: 4},
+					{Essential: true, PropertyIndex: 5},
+					{Essential: true, PropertyIndex: 6},
+				}},
+				{ItemIDV0: 2, AssociationCount: 2, Associations: []IpmaAssociation{
+					{Essential: true, PropertyIndex: 1},
+					{PropertyIndex: 7},
+				}},
+				{ItemIDV0: 4, AssociationCount: 1, Associations: []IpmaAssociation{
+					{PropertyIndex: 8},
+				}},
+			}}, ctx, nil)
+		})
+		writeTestBox(t, w, &Iloc{
+			FullBox:        FullBox{Version: 1},
+			OffsetSize:     4,
+			LengthSize:     4,
+			BaseOffsetSize: 8,
+			ItemCount:      4,
+			Items: []IlocItem{
+				{ItemID: 1, BaseOffset: mdatOffset, ExtentCount: 2, Extents: []IlocExtent{
+					{ExtentOffset: 0, ExtentLength: 7},
+					{ExtentOffset: 8, ExtentLength: 3},
+				}},
+				{ItemID: 2, ExtentCount: 1, Extents: []IlocExtent{
+					{ExtentOffset: mdatOffset + 11, ExtentLength: 4},
+				}},
+				{ItemID: 3, ConstructionMethod: IlocConstructionMethodIdatOffset, ExtentCount: 1, Extents: []IlocExtent{{}}},
+				{ItemID: 4, ConstructionMethod: IlocConstructionMethodItemOffset, BaseOffset: 2, ExtentCount: 1, Extents: []IlocExtent{
+					{ExtentOffset: 1, ExtentLength: 4},
+				}},
+			},
+		}, ctx, nil)
+		writeTestBox(t, w, &Idat{Data: []byte("Exif\x00\x00II*")}, ctx, nil)
+	})
+	data, err := io.ReadAll(buf.BytesReader())
+	require.NoError(t, err)
+	return bytes.NewReader(data)
+}
+
+func TestItemReader(t *testing.T) {
+	ir, err := NewItemReader(buildTestHEIF(t))
+	require.NoError(t, err)
+
+	assert.Equal(t, uint32(1), ir.PrimaryItemID)
+	require.Len(t, ir.Items, 4)
+	primary := ir.PrimaryItem()
+	require.NotNil(t, primary)
+	assert.Equal(t, uint32(1), primary.ID)
+	assert.Equal(t, [4]byte{'h', 'v', 'c', '1'}, primary.Type)
+	assert.Equal(t, "primary", primary.Name)
+	assert.False(t, primary.Hidden)
+	assert.True(t, ir.Item(4).Hidden)
+	assert.Nil(t, ir.Item(5))
+
+	require.Len(t, primary.Properties, 6)
+	assert.Equal(t, BoxTypeAv1C(), primary.Properties[0].Type)
+	assert.True(t, primary.Properties[0].Essential)
+	assert.False(t, primary.Properties[1].Essential)
+	assert.Equal(t, &Ispe{ImageWidth: 1920, ImageHeight: 1080}, primary.Property(BoxTypeIspe()))
+	assert.Equal(t, uint16(1), primary.Property(BoxTypeColr()).(*Colr).ColourPrimaries)
+	assert.Equal(t, []uint8{8, 8, 8}, primary.Property(BoxTypePixi()).(*Pixi).BitsPerChannel)
+	assert.Equal(t, uint8(1), primary.Property(BoxTypeIrot()).(*Irot).Angle)
+	assert.Equal(t, uint8(1), primary.Property(BoxTypeImir()).(*Imir).Axis)
+	assert.Nil(t, primary.Property(BoxTypeHvcC()))
+
+	thumbnails := ir.Thumbnails(1)
+	require.Len(t, thumbnails, 1)
+	assert.Equal(t, uint32(2), thumbnails[0].ID)
+	assert.Equal(t, []uint32{1}, thumbnails[0].ReferencedItemIDs([4]byte{'t', 'h', 'm', 'b'}))
+	assert.Equal(t, &Ispe{ImageWidth: 320, ImageHeight: 180}, thumbnails[0].Property(BoxTypeIspe()))
+	assert.Empty(t, ir.Thumbnails(2))
+	assert.Equal(t, []uint32{1}, ir.Item(3).ReferencedItemIDs([4]byte{'c', 'd', 's', 'c'}))
+
+	unsupported := ir.Item(4).Properties
+	require.Len(t, unsupported, 1)
+	assert.Equal(t, StrToBoxType("xxxx"), unsupported[0].Type)
+	assert.Nil(t, unsupported[0].Box)
+
+	data, err := ir.ReadItemData(1)
+	require.NoError(t, err)
+	assert.Equal(t, "PRIMARYIMG", string(data))
+	data, err = ir.ReadItemData(2)
+	require.NoError(t, err)
+	assert.Equal(t, "THMB", string(data))
+	data, err = ir.ReadItemData(3)
+	require.NoError(t, err)
+	assert.Equal(t, "Exif\x00\x00II*", string(data))
+	data, err = ir.ReadItemData(4)
+	require.NoError(t, err)
+	assert.Equal(t, "MARY", string(data))
+	_, err = ir.ReadItemData(5)
+	assert.Error(t, err)
+
+	_, err = NewItemReader(bytes.NewReader(nil))
+	assert.Error(t, err)
+}
+
+func TestItemReaderInfeV1(t *testing.T) {
+	buf := &writerseeker.WriterSeeker{}
+	w := NewWriter(buf)
+	ctx := Context{}
+	writeTestBox(t, w, &Meta{}, ctx, func() {
+		writeTestBox(t, w, &Hdlr{HandlerType: [4]byte{'p', 'i', 'c', 't'}}, ctx, nil)
+		writeTestBox(t, w, &Iinf{EntryCount: 1}, ctx, func() {
+			writeTestBox(t, w, &Infe{FullBox: FullBox{Version: 1}, ItemID: 1, ItemName: "xmp", ContentType: "application/rdf+xml"}, ctx, nil)
+		})
+	})
+
+	ir, err := NewItemReader(buf.BytesReader())
+	require.NoError(t, err)
+	require.Len(t, ir.Items, 1)
+	assert.Equal(t, [4]byte{}, ir.Items[0].Type)
+	assert.Equal(t, "xmp", ir.Items[0].Name)
+	assert.Equal(t, "application/rdf+xml", ir.Items[0].ContentType)
+}
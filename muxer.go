@@ -0,0 +1,1018 @@
+package mp4
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// MuxerConfig is the configuration of Muxer.
+type MuxerConfig struct {
+	// Fragmented represents whether to write a fragmented file instead of a progressive file.
+	Fragmented bool
+
+	// FragmentDuration is the target duration of fragments of the fragmented file.
+	// Fragments start at sync samples of the first video track (or the first track if there is no video track).
+	// Two seconds is used when it is zero.
+	FragmentDuration time.Duration
+}
+
+type muxerCodec int
+
+const (
+	muxerCodecAVC muxerCodec = iota
+	muxerCodecHEVC
+	muxerCodecAAC
+	muxerCodecWebVTT
+	muxerCodecTTML
+)
+
+// Muxer builds an MP4 file from H.264/H.265 Annex B access units, ADTS AAC frames, WebVTT cues and TTML documents.
+// The progressive file has an mdat box followed by a moov box,
+// and the fragmented file has an init segment followed by pairs of moof and mdat boxes.
+type Muxer struct {
+	w      *Writer
+	config MuxerConfig
+	tracks []*MuxerTrack
+
+	// sealed is set when the first sample is written, and tracks can't be added after that.
+	sealed  bool
+	started bool
+	closed  bool
+
+	// lastTrack is the track of the sample which is written last to the mdat box of the progressive file.
+	lastTrack *MuxerTrack
+	sequence  uint32
+}
+
+// MuxerTrack is a track of Muxer.
+type MuxerTrack struct {
+	m         *Muxer
+	trackID   uint32
+	codec     muxerCodec
+	timescale uint32
+
+	// parameter sets of H.264/H.265 in order of appearance
+	vpss [][]byte
+	spss [][]byte
+	ppss [][]byte
+
+	adts        *adtsConfig
+	sampleCount uint64
+
+	webVTTHeader string
+	// webVTTEnd is the end time of the cues which have been written.
+	webVTTEnd time.Duration
+
+	stpp *XMLSubtitleSampleEntry
+	// subsamples holds subsample sizes of samples which have images.
+	subsamples map[*TrackSample][]uint32
+
+	// samples are written samples of the progressive file or pending samples of the fragmented file.
+	samples []*TrackSample
+	// last is the sample whose duration is determined when the next sample comes.
+	last *TrackSample
+	// lastDuration is the duration of the sample which is determined last, and is used for the final sample.
+	lastDuration uint32
+	chunks       []*sampleChunk
+	size         uint64
+}
+
+// NewMuxer returns a new Muxer which writes the file to w.
+func NewMuxer(w io.WriteSeeker, config *MuxerConfig) *Muxer {
+	m := &Muxer{w: NewWriter(w)}
+	if config != nil {
+		m.config = *config
+	}
+	if m.config.FragmentDuration <= 0 {
+		m.config.FragmentDuration = 2 * time.Second
+	}
+	return m
+}
+
+// AddAVCTrack adds an H.264 track whose timestamps are represented in the timescale.
+func (m *Muxer) AddAVCTrack(timescale uint32) (*MuxerTrack, error) {
+	return m.addTrack(muxerCodecAVC, timescale)
+}
+
+// AddHEVCTrack adds an H.265 track whose timestamps are represented in the timescale.
+func (m *Muxer) AddHEVCTrack(timescale uint32) (*MuxerTrack, error) {
+	return m.addTrack(muxerCodecHEVC, timescale)
+}
+
+// AddAACTrack adds an AAC track. Its timescale is the sampling frequency of ADTS frames.
+func (m *Muxer) AddAACTrack() (*MuxerTrack, error) {
+	return m.addTrack(muxerCodecAAC, 0)
+}
+
+// AddWebVTTTrack adds a WebVTT track whose timestamps are represented in the timescale.
+// The header is stored in the vttC box, and "WEBVTT" is used when it is empty.
+func (m *Muxer) AddWebVTTTrack(timescale uint32, header string) (*MuxerTrack, error) {
+	t, err := m.addTrack(muxerCodecWebVTT, timescale)
+	if err != nil {
+		return nil, err
+	}
+	t.webVTTHeader = strings.TrimRight(header, "\n")
+	if t.webVTTHeader == "" {
+		t.webVTTHeader = webVTTSignature
+	}
+	return t, nil
+}
+
+// AddTTMLTrack adds an XML subtitle (stpp) track of TTML documents whose timestamps are represented in the timescale.
+// The namespace, the schema location and the auxiliary MIME types are space-separated lists,
+// and TTMLNamespace is used when the namespace is empty.
+func (m *Muxer) AddTTMLTrack(timescale uint32, namespace, schemaLocation, auxiliaryMIMETypes string) (*MuxerTrack, error) {
+	t, err := m.addTrack(muxerCodecTTML, timescale)
+	if err != nil {
+		return nil, err
+	}
+	if namespace == "" {
+		namespace = TTMLNamespace
+	}
+	t.stpp = &XMLSubtitleSampleEntry{
+		SampleEntry:        SampleEntry{AnyTypeBox: AnyTypeBox{Type: BoxTypeStpp()}, DataReferenceIndex: 1},
+		Namespace:          namespace,
+		SchemaLocation:     schemaLocation,
+		AuxiliaryMIMETypes: auxiliaryMIMETypes,
+	}
+	t.subsamples = make(map[*TrackSample][]uint32)
+	return t, nil
+}
+
+func (m *Muxer) addTrack(codec muxerCodec, timescale uint32) (*MuxerTrack, error) {
+	if m.sealed || m.closed {
+		return nil, errors.New("tracks must be added before writing samples")
+	}
+	if codec != muxerCodecAAC && timescale == 0 {
+		return nil, errors.New("timescale must be positive")
+	}
+	t := &MuxerTrack{
+		m:         m,
+		trackID:   uint32(len(m.tracks) + 1),
+		codec:     codec,
+		timescale: timescale,
+	}
+	m.tracks = append(m.tracks, t)
+	return t, nil
+}
+
+// TrackID returns the track ID of the track.
+func (t *MuxerTrack) TrackID() uint32 {
+	return t.trackID
+}
+
+// WriteAnnexB writes an access unit which consists of start code prefixed NAL units.
+// Parameter sets in the access unit are moved to the decoder configuration record.
+// dts and pts are represented in the timescale of the track, and dts must increase monotonically.
+func (t *MuxerTrack) WriteAnnexB(accessUnit []byte, dts uint64, pts int64) error {
+	if !t.isVideo() {
+		return errors.New("not a video track")
+	}
+	if t.m.closed {
+		return errors.New("muxer is closed")
+	}
+	if t.last != nil && dts <= t.last.DTS {
+		return fmt.Errorf("non-monotonic DTS: %d", dts)
+	}
+	t.m.sealed = true
+
+	var data []byte
+	var isSync bool
+	for _, nalu := range splitAnnexB(accessUnit) {
+		var nalType uint8
+		if t.codec == muxerCodecHEVC {
+			nalType = (nalu[0] >> 1) & 0x3f
+			switch nalType {
+			case 32:
+				t.vpss = appendParameterSet(t.vpss, nalu)
+				continue
+			case 33:
+				t.spss = appendParameterSet(t.spss, nalu)
+				continue
+			case 34:
+				t.ppss = appendParameterSet(t.ppss, nalu)
+				continue
+			}
+			isSync = isSync || nalType >= 16 && nalType <= 23
+		} else {
+			nalType = nalu[0] & 0x1f
+			switch nalType {
+			case 7:
+				t.spss = appendParameterSet(t.spss, nalu)
+				continue
+			case 8:
+				t.ppss = appendParameterSet(t.ppss, nalu)
+				continue
+			}
+			isSync = isSync || nalType == 5
+		}
+		length := len(nalu)
+		data = append(data, byte(length>>24), byte(length>>16), byte(length>>8), byte(length))
+		data = append(data, nalu...)
+	}
+	if len(data) == 0 {
+		// the access unit has only parameter sets
+		return nil
+	}
+
+	sample := &TrackSample{
+		DTS:                    dts,
+		PTS:                    pts,
+		Size:                   uint32(len(data)),
+		IsSync:                 isSync,
+		Flags:                  SampleFlagDependsOnNone,
+		SampleDescriptionIndex: 1,
+		Data:                   data,
+	}
+	if !isSync {
+		sample.Flags = SampleFlagDependsOnOthers | SampleFlagIsNonSyncSample
+	}
+	if last := t.last; last != nil {
+		last.Duration = uint32(dts - last.DTS)
+		t.lastDuration = last.Duration
+		if err := t.m.addSample(t, last); err != nil {
+			return err
+		}
+	}
+	t.last = sample
+	return nil
+}
+
+// frameDuration returns the frame duration of the timing information of the SPS, or 0 if it is not available.
+func (t *MuxerTrack) frameDuration() uint32 {
+	if len(t.spss) == 0 {
+		return 0
+	}
+	var frameRate float64
+	if t.codec == muxerCodecHEVC {
+		if sps, err := ParseHEVCSPS(t.spss[len(t.spss)-1]); err == nil {
+			frameRate = sps.FrameRate()
+		}
+	} else {
+		if sps, err := ParseAVCSPS(t.spss[len(t.spss)-1]); err == nil {
+			frameRate = sps.FrameRate()
+		}
+	}
+	if frameRate <= 0 {
+		return 0
+	}
+	return uint32(math.Round(float64(t.timescale) / frameRate))
+}
+
+// WriteADTS writes one or more ADTS frames.
+// All frames must have the same audio object type, sampling frequency and channel configuration.
+func (t *MuxerTrack) WriteADTS(data []byte) error {
+	if t.codec != muxerCodecAAC {
+		return errors.New("not an AAC track")
+	}
+	if t.m.closed {
+		return errors.New("muxer is closed")
+	}
+	t.m.sealed = true
+	for len(data) != 0 {
+		config, headerSize, frameLength, err := parseADTSHeader(data)
+		if err != nil {
+			return err
+		}
+		if t.adts == nil {
+			t.adts = config
+			t.timescale = aacSamplingFrequencies[config.samplingFrequencyIndex]
+		} else if *t.adts != *config {
+			return errors.New("ADTS configuration changed")
+		}
+		frame := data[headerSize:frameLength]
+		sample := &TrackSample{
+			DTS:                    t.sampleCount * 1024,
+			PTS:                    int64(t.sampleCount * 1024),
+			Duration:               1024,
+			Size:                   uint32(len(frame)),
+			IsSync:                 true,
+			Flags:                  SampleFlagDependsOnNone,
+			SampleDescriptionIndex: 1,
+			Data:                   append([]byte(nil), frame...),
+		}
+		t.sampleCount++
+		if err := t.m.addSample(t, sample); err != nil {
+			return err
+		}
+		data = data[frameLength:]
+	}
+	return nil
+}
+
+// WriteWebVTT writes the WebVTT cues.
+// Overlapping cues are split into samples, and gaps between cues are filled with empty samples.
+// Cues must not start before the end of the cues which have been written by the previous calls.
+func (t *MuxerTrack) WriteWebVTT(cues []*WebVTTCue) error {
+	if t.codec != muxerCodecWebVTT {
+		return errors.New("not a WebVTT track")
+	}
+	if t.m.closed {
+		return errors.New("muxer is closed")
+	}
+	t.m.sealed = true
+	samples, err := buildWebVTTSamples(cues, t.webVTTEnd, t.timescale)
+	if err != nil {
+		return err
+	}
+	for _, s := range samples {
+		if err := t.m.addSample(t, s); err != nil {
+			return err
+		}
+	}
+	for _, cue := range cues {
+		if cue.End > t.webVTTEnd {
+			t.webVTTEnd = cue.End
+		}
+	}
+	return nil
+}
+
+// WriteTTML writes the TTML document. Images of the document are stored as subsamples.
+// Documents must be written in order of time and must not overlap.
+// A gap between documents is covered by extending the preceding sample,
+// because each document has its own timing.
+func (t *MuxerTrack) WriteTTML(doc *TTMLDocument) error {
+	if t.codec != muxerCodecTTML {
+		return errors.New("not a TTML track")
+	}
+	if t.m.closed {
+		return errors.New("muxer is closed")
+	}
+	t.m.sealed = true
+
+	toTime := func(d time.Duration) uint64 {
+		return uint64(d/time.Second)*uint64(t.timescale) + uint64(d%time.Second)*uint64(t.timescale)/uint64(time.Second)
+	}
+	dts := toTime(doc.Start)
+	duration := toTime(doc.Start+doc.Duration) - dts
+	if t.last == nil {
+		// the first sample starts at zero
+		dts, duration = 0, dts+duration
+	} else if dts < t.last.DTS+uint64(t.last.Duration) {
+		return fmt.Errorf("TTML document overlaps the previous one: start=%s", doc.Start)
+	}
+
+	data := append([]byte(nil), doc.Document...)
+	for _, image := range doc.Images {
+		data = append(data, image...)
+	}
+	sample := &TrackSample{
+		DTS:                    dts,
+		PTS:                    int64(dts),
+		Duration:               uint32(duration),
+		Size:                   uint32(len(data)),
+		IsSync:                 true,
+		Flags:                  SampleFlagDependsOnNone,
+		SampleDescriptionIndex: 1,
+		Data:                   data,
+	}
+	if len(doc.Images) != 0 {
+		sizes := []uint32{uint32(len(doc.Document))}
+		for _, image := range doc.Images {
+			sizes = append(sizes, uint32(len(image)))
+		}
+		t.subsamples[sample] = sizes
+	}
+	if last := t.last; last != nil {
+		last.Duration = uint32(dts - last.DTS)
+		if err := t.m.addSample(t, last); err != nil {
+			return err
+		}
+	}
+	t.last = sample
+	return nil
+}
+
+// subs builds the subs box for the samples. It returns nil when no sample has subsamples.
+func (t *MuxerTrack) subs(samples []*TrackSample) *Subs {
+	subs := &Subs{}
+	var number uint32
+	for i, s := range samples {
+		sizes, ok := t.subsamples[s]
+		if !ok {
+			continue
+		}
+		entry := SubSampleInformationEntry{
+			SampleDelta:    uint32(i+1) - number,
+			SubsampleCount: uint16(len(sizes)),
+		}
+		for _, size := range sizes {
+			if size > 0xffff {
+				subs.SetVersion(1)
+			}
+			entry.SubsampleInformation = append(entry.SubsampleInformation, SubSampleInformation{
+				SubsampleSizeV0: uint16(size),
+				SubsampleSizeV1: size,
+			})
+		}
+		subs.Entries = append(subs.Entries, entry)
+		number = uint32(i + 1)
+	}
+	if len(subs.Entries) == 0 {
+		return nil
+	}
+	subs.EntryCount = uint32(len(subs.Entries))
+	return subs
+}
+
+func (t *MuxerTrack) isVideo() bool {
+	return t.codec == muxerCodecAVC || t.codec == muxerCodecHEVC
+}
+
+// parseADTSHeader parses the ADTS header at the head of data,
+// and returns the configuration, the header size and the frame length.
+func parseADTSHeader(data []byte) (*adtsConfig, int, int, error) {
+	if len(data) < adtsHeaderSize || data[0] != 0xff || data[1]&0xf0 != 0xf0 {
+		return nil, 0, 0, errors.New("ADTS syncword not found")
+	}
+	headerSize := adtsHeaderSize
+	if data[1]&0x01 == 0 { // protection_absent
+		headerSize += 2
+	}
+	config := &adtsConfig{
+		audioObjectType:        data[2]>>6 + 1,
+		samplingFrequencyIndex: (data[2] >> 2) & 0x0f,
+		channelConfiguration:   (data[2]&0x01)<<2 | data[3]>>6,
+	}
+	if int(config.samplingFrequencyIndex) >= len(aacSamplingFrequencies) {
+		return nil, 0, 0, fmt.Errorf("invalid sampling frequency index: %d", config.samplingFrequencyIndex)
+	}
+	frameLength := int(data[3]&0x03)<<11 | int(data[4])<<3 | int(data[5]>>5)
+	if data[6]&0x03 != 0 {
+		return nil, 0, 0, errors.New("ADTS frame which has multiple raw data blocks is not supported")
+	}
+	if frameLength < headerSize || frameLength > len(data) {
+		return nil, 0, 0, fmt.Errorf("invalid ADTS frame length: %d", frameLength)
+	}
+	return config, headerSize, frameLength, nil
+}
+
+// Close writes the remaining samples and the moov box of the progressive file.
+func (m *Muxer) Close() error {
+	if m.closed {
+		return nil
+	}
+	for _, t := range m.tracks {
+		if t.last == nil {
+			continue
+		}
+		if t.isVideo() {
+			// the final sample takes over the duration of the previous sample,
+			// or the frame duration of the SPS when it is the only sample
+			t.last.Duration = t.lastDuration
+			if t.last.Duration == 0 {
+				t.last.Duration = t.frameDuration()
+			}
+		}
+		if err := m.addSample(t, t.last); err != nil {
+			return err
+		}
+		t.last = nil
+	}
+	m.closed = true
+
+	if m.config.Fragmented {
+		return m.flushFragment()
+	}
+	if err := m.start(); err != nil {
+		return err
+	}
+	if _, err := m.w.EndBox(); err != nil { // mdat
+		return err
+	}
+	return m.writeMoov(false)
+}
+
+// start writes the ftyp box and the mdat header of the progressive file, or the init segment of the fragmented file.
+func (m *Muxer) start() error {
+	if m.started {
+		return nil
+	}
+	m.started = true
+	if m.config.Fragmented {
+		ftyp := &Ftyp{
+			MajorBrand: BrandISO6(),
+			CompatibleBrands: []CompatibleBrandElem{
+				{CompatibleBrand: BrandISO6()},
+				{CompatibleBrand: BrandMP41()},
+			},
+		}
+		if err := writeBox(m.w, ftyp, Context{}); err != nil {
+			return err
+		}
+		return m.writeMoov(true)
+	}
+
+	ftyp := &Ftyp{
+		MajorBrand:   BrandISOM(),
+		MinorVersion: 0x200,
+		CompatibleBrands: []CompatibleBrandElem{
+			{CompatibleBrand: BrandISOM()},
+			{CompatibleBrand: BrandISO2()},
+			{CompatibleBrand: BrandMP41()},
+		},
+	}
+	for _, t := range m.tracks {
+		if t.codec == muxerCodecAVC {
+			ftyp.AddCompatibleBrand(BrandAVC1())
+			break
+		}
+	}
+	if err := writeBox(m.w, ftyp, Context{}); err != nil {
+		return err
+	}
+	// the large header is used because the size of the mdat box is unknown
+	_, err := m.w.StartBox(&BoxInfo{Type: BoxTypeMdat(), HeaderSize: LargeHeaderSize})
+	return err
+}
+
+func (m *Muxer) addSample(t *MuxerTrack, s *TrackSample) error {
+	t.size += uint64(s.Size)
+	if m.config.Fragmented {
+		if t == m.referenceTrack() && s.IsSync && len(t.samples) != 0 {
+			var duration uint64
+			for _, p := range t.samples {
+				duration += uint64(p.Duration)
+			}
+			if duration*uint64(time.Second) >= uint64(m.config.FragmentDuration)*uint64(t.timescale) {
+				if err := m.flushFragment(); err != nil {
+					return err
+				}
+			}
+		}
+		t.samples = append(t.samples, s)
+		return nil
+	}
+
+	if err := m.start(); err != nil {
+		return err
+	}
+	offset, err := m.w.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := m.w.Write(s.Data); err != nil {
+		return err
+	}
+	s.Offset = uint64(offset)
+	s.Data = nil
+	if len(t.chunks) == 0 || m.lastTrack != t {
+		t.chunks = append(t.chunks, &sampleChunk{offset: s.Offset})
+	}
+	chunk := t.chunks[len(t.chunks)-1]
+	chunk.samples = append(chunk.samples, s)
+	t.samples = append(t.samples, s)
+	m.lastTrack = t
+	return nil
+}
+
+// referenceTrack returns the track which decides fragment boundaries.
+func (m *Muxer) referenceTrack() *MuxerTrack {
+	for _, t := range m.tracks {
+		if t.isVideo() {
+			return t
+		}
+	}
+	return m.tracks[0]
+}
+
+func (m *Muxer) flushFragment() error {
+	if err := m.start(); err != nil {
+		return err
+	}
+	for _, t := range m.tracks {
+		if len(t.samples) == 0 {
+			continue
+		}
+		m.sequence++
+		var extra []IBox
+		if subs := t.subs(t.samples); subs != nil {
+			extra = append(extra, subs)
+		}
+		moof, err := buildTrackFragmentMoof(m.sequence, t.trackID, t.samples, extra...)
+		if err != nil {
+			return err
+		}
+		if _, err := m.w.Write(moof); err != nil {
+			return err
+		}
+		if _, err := m.w.StartBox(&BoxInfo{Type: BoxTypeMdat()}); err != nil {
+			return err
+		}
+		for _, s := range t.samples {
+			if _, err := m.w.Write(s.Data); err != nil {
+				return err
+			}
+		}
+		if _, err := m.w.EndBox(); err != nil {
+			return err
+		}
+		for _, s := range t.samples {
+			delete(t.subsamples, s)
+		}
+		t.samples = t.samples[:0]
+	}
+	return nil
+}
+
+func (t *MuxerTrack) duration() uint64 {
+	var duration uint64
+	for _, s := range t.samples {
+		duration += uint64(s.Duration)
+	}
+	return duration
+}
+
+// writeMoov writes the moov box. The fragmented file has empty sample tables and the mvex box.
+func (m *Muxer) writeMoov(fragmented bool) error {
+	const movieTimescale = 1000
+	matrix := [9]int32{0x00010000, 0, 0, 0, 0x00010000, 0, 0, 0, 0x40000000}
+
+	movieDurations := make([]uint64, len(m.tracks))
+	var movieDuration uint64
+	for i, t := range m.tracks {
+		if fragmented || t.timescale == 0 {
+			continue
+		}
+		movieDurations[i] = t.duration() * movieTimescale / uint64(t.timescale)
+		if movieDurations[i] > movieDuration {
+			movieDuration = movieDurations[i]
+		}
+	}
+
+	return writeParentBox(m.w, &Moov{}, Context{}, func() error {
+		mvhd := &Mvhd{
+			Timescale:   movieTimescale,
+			DurationV0:  uint32(movieDuration),
+			DurationV1:  movieDuration,
+			Rate:        0x00010000,
+			Volume:      0x0100,
+			Matrix:      matrix,
+			NextTrackID: uint32(len(m.tracks) + 1),
+		}
+		if movieDuration > 0xffffffff {
+			mvhd.SetVersion(1)
+		}
+		if err := writeBox(m.w, mvhd, Context{}); err != nil {
+			return err
+		}
+		for i, t := range m.tracks {
+			if err := t.writeTrak(movieDurations[i], matrix, fragmented); err != nil {
+				return err
+			}
+		}
+		if !fragmented {
+			return nil
+		}
+		return writeParentBox(m.w, &Mvex{}, Context{}, func() error {
+			for _, t := range m.tracks {
+				if err := writeBox(m.w, &Trex{TrackID: t.trackID, DefaultSampleDescriptionIndex: 1}, Context{}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+func (t *MuxerTrack) writeTrak(movieDuration uint64, matrix [9]int32, fragmented bool) error {
+	w := t.m.w
+	sampleEntry, err := t.sampleEntry()
+	if err != nil {
+		return err
+	}
+	var duration uint64
+	if !fragmented {
+		duration = t.duration()
+	}
+
+	return writeParentBox(w, &Trak{}, Context{}, func() error {
+		tkhd := &Tkhd{
+			FullBox:    FullBox{Flags: [3]byte{0x00, 0x00, 0x03}}, // enabled and in movie
+			TrackID:    t.trackID,
+			DurationV0: uint32(movieDuration),
+			DurationV1: movieDuration,
+			Matrix:     matrix,
+		}
+		if movieDuration > 0xffffffff {
+			tkhd.SetVersion(1)
+		}
+		if vse, ok := sampleEntry.box.(*VisualSampleEntry); ok {
+			tkhd.Width = uint32(vse.Width) << 16
+			tkhd.Height = uint32(vse.Height) << 16
+		} else if t.codec == muxerCodecAAC {
+			tkhd.Volume = 0x0100
+		}
+		if err := writeBox(w, tkhd, Context{}); err != nil {
+			return err
+		}
+
+		return writeParentBox(w, &Mdia{}, Context{}, func() error {
+			mdhd := &Mdhd{
+				Timescale:  t.timescale,
+				DurationV0: uint32(duration),
+				DurationV1: duration,
+				Language:   [3]byte{'u', 'n', 'd'},
+			}
+			if duration > 0xffffffff {
+				mdhd.SetVersion(1)
+			}
+			if err := writeBox(w, mdhd, Context{}); err != nil {
+				return err
+			}
+			var hdlr *Hdlr
+			var mhd IBox
+			switch t.codec {
+			case muxerCodecAAC:
+				hdlr = &Hdlr{HandlerType: [4]byte{'s', 'o', 'u', 'n'}, Name: "SoundHandler"}
+				mhd = &Smhd{}
+			case muxerCodecWebVTT:
+				hdlr = &Hdlr{HandlerType: [4]byte{'t', 'e', 'x', 't'}, Name: "TextHandler"}
+				mhd = &Nmhd{}
+			case muxerCodecTTML:
+				hdlr = &Hdlr{HandlerType: [4]byte{'s', 'u', 'b', 't'}, Name: "SubtitleHandler"}
+				mhd = &Sthd{}
+			default:
+				hdlr = &Hdlr{HandlerType: [4]byte{'v', 'i', 'd', 'e'}, Name: "VideoHandler"}
+				mhd = &Vmhd{FullBox: FullBox{Flags: [3]byte{0x00, 0x00, 0x01}}}
+			}
+			if err := writeBox(w, hdlr, Context{}); err != nil {
+				return err
+			}
+
+			return writeParentBox(w, &Minf{}, Context{}, func() error {
+				if err := writeBox(w, mhd, Context{}); err != nil {
+					return err
+				}
+				if err := writeParentBox(w, &Dinf{}, Context{}, func() error {
+					return writeParentBox(w, &Dref{EntryCount: 1}, Context{}, func() error {
+						return writeBox(w, &Url{FullBox: FullBox{Flags: [3]byte{0x00, 0x00, 0x01}}}, Context{})
+					})
+				}); err != nil {
+					return err
+				}
+
+				return writeParentBox(w, &Stbl{}, Context{}, func() error {
+					if err := writeParentBox(w, &Stsd{EntryCount: 1}, Context{}, func() error {
+						return writeParentBox(w, sampleEntry.box, Context{}, func() error {
+							if sampleEntry.config == nil {
+								return nil
+							}
+							return writeBox(w, sampleEntry.config, Context{})
+						})
+					}); err != nil {
+						return err
+					}
+					if fragmented {
+						return writeSampleTable(w, nil, nil)
+					}
+					if err := writeSampleTable(w, t.samples, t.chunks); err != nil {
+						return err
+					}
+					if subs := t.subs(t.samples); subs != nil {
+						return writeBox(w, subs, Context{})
+					}
+					return nil
+				})
+			})
+		})
+	})
+}
+
+type muxerSampleEntry struct {
+	box    IBox
+	config IBox
+}
+
+func (t *MuxerTrack) sampleEntry() (*muxerSampleEntry, error) {
+	switch t.codec {
+	case muxerCodecAVC:
+		if len(t.spss) == 0 || len(t.ppss) == 0 {
+			return nil, fmt.Errorf("SPS or PPS not found: trackID=%d", t.trackID)
+		}
+		sps, err := ParseAVCSPS(t.spss[0])
+		if err != nil {
+			return nil, err
+		}
+		avcc := &AVCDecoderConfiguration{
+			AnyTypeBox:                 AnyTypeBox{Type: BoxTypeAvcC()},
+			ConfigurationVersion:       1,
+			Profile:                    sps.ProfileIdc,
+			ProfileCompatibility:       sps.ConstraintSetFlags,
+			Level:                      sps.LevelIdc,
+			LengthSizeMinusOne:         3,
+			NumOfSequenceParameterSets: uint8(len(t.spss)),
+			NumOfPictureParameterSets:  uint8(len(t.ppss)),
+		}
+		for _, ps := range t.spss {
+			avcc.SequenceParameterSets = append(avcc.SequenceParameterSets, AVCParameterSet{Length: uint16(len(ps)), NALUnit: ps})
+		}
+		for _, ps := range t.ppss {
+			avcc.PictureParameterSets = append(avcc.PictureParameterSets, AVCParameterSet{Length: uint16(len(ps)), NALUnit: ps})
+		}
+		switch sps.ProfileIdc {
+		case AVCHighProfile, AVCHigh10Profile, AVCHigh422Profile, 144:
+			avcc.HighProfileFieldsEnabled = true
+			avcc.ChromaFormat = sps.ChromaFormatIdc
+			avcc.BitDepthLumaMinus8 = sps.BitDepthLumaMinus8
+			avcc.BitDepthChromaMinus8 = sps.BitDepthChromaMinus8
+		}
+		return &muxerSampleEntry{box: newMuxerVisualSampleEntry(BoxTypeAvc1(), sps.Width, sps.Height), config: avcc}, nil
+
+	case muxerCodecHEVC:
+		if len(t.vpss) == 0 || len(t.spss) == 0 || len(t.ppss) == 0 {
+			return nil, fmt.Errorf("VPS, SPS or PPS not found: trackID=%d", t.trackID)
+		}
+		sps, err := ParseHEVCSPS(t.spss[0])
+		if err != nil {
+			return nil, err
+		}
+		hvcc := &HvcC{
+			ConfigurationVersion:        1,
+			GeneralProfileSpace:         sps.ProfileTierLevel.GeneralProfileSpace,
+			GeneralTierFlag:             sps.ProfileTierLevel.GeneralTierFlag,
+			GeneralProfileIdc:           sps.ProfileTierLevel.GeneralProfileIdc,
+			GeneralProfileCompatibility: sps.ProfileTierLevel.GeneralProfileCompatibility,
+			GeneralConstraintIndicator:  sps.ProfileTierLevel.GeneralConstraintIndicator,
+			GeneralLevelIdc:             sps.ProfileTierLevel.GeneralLevelIdc,
+			Reserved1:                   0xf,
+			Reserved2:                   0x3f,
+			Reserved3:                   0x3f,
+			ChromaFormatIdc:             sps.ChromaFormatIdc,
+			Reserved4:                   0x1f,
+			BitDepthLumaMinus8:          sps.BitDepthLumaMinus8,
+			Reserved5:                   0x1f,
+			BitDepthChromaMinus8:        sps.BitDepthChromaMinus8,
+			NumTemporalLayers:           sps.MaxSubLayersMinus1 + 1,
+			LengthSizeMinusOne:          3,
+		}
+		if sps.TemporalIDNesting {
+			hvcc.TemporalIdNested = 1
+		}
+		for i, pss := range [][][]byte{t.vpss, t.spss, t.ppss} {
+			array := HEVCNaluArray{
+				Completeness: true,
+				NaluType:     uint8(32 + i),
+				NumNalus:     uint16(len(pss)),
+			}
+			for _, ps := range pss {
+				array.Nalus = append(array.Nalus, HEVCNalu{Length: uint16(len(ps)), NALUnit: ps})
+			}
+			hvcc.NaluArrays = append(hvcc.NaluArrays, array)
+		}
+		hvcc.NumOfNaluArrays = uint8(len(hvcc.NaluArrays))
+		return &muxerSampleEntry{box: newMuxerVisualSampleEntry(BoxTypeHvc1(), sps.Width, sps.Height), config: hvcc}, nil
+
+	case muxerCodecTTML:
+		return &muxerSampleEntry{box: t.stpp}, nil
+
+	case muxerCodecWebVTT:
+		return &muxerSampleEntry{
+			box: &WVTTSampleEntry{
+				SampleEntry: SampleEntry{AnyTypeBox: AnyTypeBox{Type: BoxTypeWvtt()}, DataReferenceIndex: 1},
+			},
+			config: &WebVTTConfigurationBox{Config: t.webVTTHeader},
+		}, nil
+
+	default:
+		if t.adts == nil {
+			return nil, fmt.Errorf("no ADTS frame: trackID=%d", t.trackID)
+		}
+		asc := []byte{
+			t.adts.audioObjectType<<3 | t.adts.samplingFrequencyIndex>>1,
+			t.adts.samplingFrequencyIndex<<7 | t.adts.channelConfiguration<<3,
+		}
+		var bitrate uint32
+		if duration := t.duration(); duration != 0 {
+			bitrate = uint32(t.size * 8 * uint64(t.timescale) / duration)
+		}
+		decConfigSize := uint32(13 + 2 + len(asc))
+		esds := &Esds{Descriptors: []Descriptor{
+			{
+				Tag:          ESDescrTag,
+				Size:         3 + 2 + decConfigSize + 2 + 1,
+				ESDescriptor: &ESDescriptor{ESID: uint16(t.trackID)},
+			},
+			{
+				Tag:  DecoderConfigDescrTag,
+				Size: decConfigSize,
+				DecoderConfigDescriptor: &DecoderConfigDescriptor{
+					ObjectTypeIndication: 0x40,
+					StreamType:           0x05, // audio stream
+					Reserved:             true,
+					MaxBitrate:           bitrate,
+					AvgBitrate:           bitrate,
+				},
+			},
+			{Tag: DecSpecificInfoTag, Size: uint32(len(asc)), Data: asc},
+			{Tag: SLConfigDescrTag, Size: 1, Data: []byte{0x02}},
+		}}
+		channelCount := uint16(t.adts.channelConfiguration)
+		if channelCount == 7 {
+			channelCount = 8
+		}
+		return &muxerSampleEntry{
+			box: &AudioSampleEntry{
+				SampleEntry:  SampleEntry{AnyTypeBox: AnyTypeBox{Type: BoxTypeMp4a()}, DataReferenceIndex: 1},
+				ChannelCount: channelCount,
+				SampleSize:   16,
+				SampleRate:   t.timescale << 16,
+			},
+			config: esds,
+		}, nil
+	}
+}
+
+func newMuxerVisualSampleEntry(boxType BoxType, width, height uint32) *VisualSampleEntry {
+	return &VisualSampleEntry{
+		SampleEntry:     SampleEntry{AnyTypeBox: AnyTypeBox{Type: boxType}, DataReferenceIndex: 1},
+		Width:           uint16(width),
+		Height:          uint16(height),
+		Horizresolution: 0x00480000, // 72 dpi
+		Vertresolution:  0x00480000,
+		FrameCount:      1,
+		Depth:           0x0018,
+		PreDefined3:     -1,
+	}
+}
+
+func appendParameterSet(pss [][]byte, ps []byte) [][]byte {
+	for _, p := range pss {
+		if bytes.Equal(p, ps) {
+			return pss
+		}
+	}
+	return append(pss, append([]byte(nil), ps...))
+}
+
+// splitAnnexB splits the Annex B byte stream into NAL units without start codes.
+func splitAnnexB(data []byte) [][]byte {
+	var nalus [][]byte
+	start := -1
+	for i := 0; i+2 < len(data); i++ {
+		if data[i] != 0x00 || data[i+1] != 0x00 || data[i+2] != 0x01 {
+			continue
+		}
+		if start >= 0 {
+			nalus = appendNALUnit(nalus, data[start:i])
+		}
+		start = i + 3
+		i += 2
+	}
+	if start >= 0 {
+		nalus = appendNALUnit(nalus, data[start:])
+	}
+	return nalus
+}
+
+func appendNALUnit(nalus [][]byte, nalu []byte) [][]byte {
+	// trailing zero bytes belong to the next start code or trailing_zero_8bits
+	for len(nalu) != 0 && nalu[len(nalu)-1] == 0x00 {
+		nalu = nalu[:len(nalu)-1]
+	}
+	if len(nalu) == 0 {
+		return nalus
+	}
+	return append(nalus, nalu)
+}
+
+// SplitAnnexBAccessUnits splits the H.264 (or H.265 if hevc is true) Annex B byte stream into access units.
+// Each access unit keeps its start codes and can be passed to MuxerTrack.WriteAnnexB.
+func SplitAnnexBAccessUnits(stream []byte, hevc bool) [][]byte {
+	var units [][]byte
+	var unit []byte
+	var hasVCL bool
+	for _, nalu := range splitAnnexB(stream) {
+		var vcl, first bool
+		if hevc {
+			nalType := (nalu[0] >> 1) & 0x3f
+			vcl = nalType < 32
+			// first_slice_segment_in_pic_flag
+			first = vcl && len(nalu) > 2 && nalu[2]&0x80 != 0 ||
+				nalType >= 32 && nalType <= 35 || nalType == 39 || nalType >= 41 && nalType <= 44 || nalType >= 48 && nalType <= 55
+		} else {
+			nalType := nalu[0] & 0x1f
+			vcl = nalType >= 1 && nalType <= 5
+			// first_mb_in_slice is zero
+			first = vcl && len(nalu) > 1 && nalu[1]&0x80 != 0 ||
+				nalType >= 6 && nalType <= 9 || nalType >= 14 && nalType <= 18
+		}
+		if first && hasVCL {
+			units = append(units, unit)
+			unit, hasVCL = nil, false
+		}
+		unit = append(unit, annexBStartCode...)
+		unit = append(unit, nalu...)
+		hasVCL = hasVCL || vcl
+	}
+	if len(unit) != 0 {
+		units = append(units, unit)
+	}
+	return units
+}
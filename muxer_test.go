@@ -0,0 +1,219 @@
+package mp4
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/orcaman/writerseeker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMuxer(t *testing.T) {
+	f, err := os.Open("./testdata/sample.mp4")
+	require.NoError(t, err)
+	defer f.Close()
+
+	annexB := &bytes.Buffer{}
+	require.NoError(t, ExtractAnnexB(f, annexB, 1))
+	adts := &bytes.Buffer{}
+	require.NoError(t, ExtractADTS(f, adts, 2))
+
+	video, err := NewSampleReader(f, 1)
+	require.NoError(t, err)
+	accessUnits := SplitAnnexBAccessUnits(annexB.Bytes(), false)
+	require.Len(t, accessUnits, len(video.Samples()))
+
+	for _, fragmented := range []bool{false, true} {
+		output := &writerseeker.WriterSeeker{}
+		m := NewMuxer(output, &MuxerConfig{Fragmented: fragmented, FragmentDuration: 500 * time.Millisecond})
+		vt, err := m.AddAVCTrack(video.Timescale)
+		require.NoError(t, err)
+		at, err := m.AddAACTrack()
+		require.NoError(t, err)
+		require.NoError(t, at.WriteADTS(adts.Bytes()))
+		for i, s := range video.Samples() {
+			require.NoError(t, vt.WriteAnnexB(accessUnits[i], s.DTS, s.PTS))
+		}
+		require.NoError(t, m.Close())
+		r := output.BytesReader()
+
+		bis, err := ExtractBoxes(r, nil, []BoxPath{{BoxTypeMoov(), BoxTypeMvex()}, {BoxTypeMoof()}})
+		require.NoError(t, err)
+		if fragmented {
+			assert.Greater(t, len(bis), 2)
+		} else {
+			assert.Empty(t, bis)
+		}
+
+		bips, err := ExtractBoxesWithPayload(r, nil, []BoxPath{
+			{BoxTypeMoov(), BoxTypeTrak(), BoxTypeTkhd()},
+			{BoxTypeMoov(), BoxTypeTrak(), BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeAvc1()},
+			{BoxTypeMoov(), BoxTypeTrak(), BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeMp4a(), BoxTypeEsds()},
+		})
+		require.NoError(t, err)
+		require.Len(t, bips, 4)
+		assert.Equal(t, uint32(320<<16), bips[0].Payload.(*Tkhd).Width)
+		assert.Equal(t, uint32(180<<16), bips[0].Payload.(*Tkhd).Height)
+		assert.Equal(t, uint16(320), bips[1].Payload.(*VisualSampleEntry).Width)
+		assert.Equal(t, uint16(180), bips[1].Payload.(*VisualSampleEntry).Height)
+		config, err := parseADTSConfig(bips[3].Payload.(*Esds))
+		require.NoError(t, err)
+		assert.Equal(t, uint8(2), config.audioObjectType)
+
+		for _, trackID := range []uint32{1, 2} {
+			src, err := NewSampleReader(f, trackID)
+			require.NoError(t, err)
+			dst, err := NewSampleReader(r, trackID)
+			require.NoError(t, err)
+			assert.Equal(t, src.Timescale, dst.Timescale)
+			require.Len(t, dst.Samples(), len(src.Samples()))
+			for {
+				s1, err := src.Next()
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err)
+				s2, err := dst.Next()
+				require.NoError(t, err)
+				if trackID == 1 {
+					assert.Equal(t, s1.DTS, s2.DTS)
+					assert.Equal(t, s1.PTS, s2.PTS)
+					assert.Equal(t, s1.Duration, s2.Duration)
+				} else {
+					// ADTS frames have no timestamps
+					assert.Equal(t, uint32(1024), s2.Duration)
+				}
+				assert.Equal(t, s1.IsSync, s2.IsSync)
+				assert.Equal(t, s1.Data, s2.Data)
+			}
+		}
+	}
+}
+
+func TestMuxerLastSampleDuration(t *testing.T) {
+	f, err := os.Open("./testdata/sample.mp4")
+	require.NoError(t, err)
+	defer f.Close()
+
+	annexB := &bytes.Buffer{}
+	require.NoError(t, ExtractAnnexB(f, annexB, 1))
+	video, err := NewSampleReader(f, 1)
+	require.NoError(t, err)
+	accessUnits := SplitAnnexBAccessUnits(annexB.Bytes(), false)
+	require.Len(t, accessUnits, len(video.Samples()))
+	// the frame rate of the SPS is 10
+	frameDuration := video.Timescale / 10
+
+	t.Run("single sample", func(t *testing.T) {
+		for _, fragmented := range []bool{false, true} {
+			output := &writerseeker.WriterSeeker{}
+			m := NewMuxer(output, &MuxerConfig{Fragmented: fragmented})
+			vt, err := m.AddAVCTrack(video.Timescale)
+			require.NoError(t, err)
+			require.NoError(t, vt.WriteAnnexB(accessUnits[0], 0, 0))
+			require.NoError(t, m.Close())
+
+			dst, err := NewSampleReader(output.BytesReader(), 1)
+			require.NoError(t, err)
+			require.Len(t, dst.Samples(), 1)
+			assert.Equal(t, frameDuration, dst.Samples()[0].Duration)
+		}
+	})
+
+	t.Run("fragmented", func(t *testing.T) {
+		// the final sample of the first track is a sync sample,
+		// and its fragment flushes the pending samples of the second track
+		f, err := os.Open("./testdata/sample_fragmented.mp4")
+		require.NoError(t, err)
+		defer f.Close()
+		annexB := &bytes.Buffer{}
+		require.NoError(t, ExtractAnnexB(f, annexB, 1))
+		video, err := NewSampleReader(f, 1)
+		require.NoError(t, err)
+		accessUnits := SplitAnnexBAccessUnits(annexB.Bytes(), false)
+		require.Len(t, accessUnits, len(video.Samples()))
+		samples := video.Samples()
+		for len(samples) > 1 && !samples[len(samples)-1].IsSync {
+			samples = samples[:len(samples)-1]
+		}
+		require.Greater(t, len(samples), 1)
+		output := &writerseeker.WriterSeeker{}
+		m := NewMuxer(output, &MuxerConfig{Fragmented: true, FragmentDuration: time.Millisecond})
+		tracks := make([]*MuxerTrack, 2)
+		for i := range tracks {
+			tracks[i], err = m.AddAVCTrack(video.Timescale)
+			require.NoError(t, err)
+		}
+		for i, s := range samples {
+			for _, track := range tracks {
+				require.NoError(t, track.WriteAnnexB(accessUnits[i], s.DTS, s.PTS))
+			}
+		}
+		require.NoError(t, m.Close())
+
+		for _, trackID := range []uint32{1, 2} {
+			dst, err := NewSampleReader(output.BytesReader(), trackID)
+			require.NoError(t, err)
+			require.Len(t, dst.Samples(), len(samples))
+			for i, s := range samples {
+				assert.Equal(t, s.Duration, dst.Samples()[i].Duration, "trackID=%d index=%d", trackID, i)
+			}
+		}
+	})
+}
+
+func TestMuxerErrors(t *testing.T) {
+	m := NewMuxer(&writerseeker.WriterSeeker{}, nil)
+	_, err := m.AddAVCTrack(0)
+	assert.Error(t, err)
+	vt, err := m.AddHEVCTrack(90000)
+	require.NoError(t, err)
+	assert.Error(t, vt.WriteADTS([]byte{0xff, 0xf1}))
+	require.NoError(t, vt.WriteAnnexB([]byte{0x00, 0x00, 0x01, 0x26, 0x01, 0xaa}, 3000, 3000))
+	assert.Error(t, vt.WriteAnnexB([]byte{0x00, 0x00, 0x01, 0x02, 0x01, 0xbb}, 3000, 3000))
+	_, err = m.AddAACTrack()
+	assert.Error(t, err)
+	// VPS, SPS and PPS are missing
+	assert.Error(t, m.Close())
+}
+
+func TestSplitAnnexBAccessUnits(t *testing.T) {
+	stream := []byte{
+		0x00, 0x00, 0x00, 0x01, 0x09, 0xf0, // AUD
+		0x00, 0x00, 0x00, 0x01, 0x67, 0x42, // SPS
+		0x00, 0x00, 0x01, 0x68, 0xce, // PPS
+		0x00, 0x00, 0x01, 0x65, 0x88, 0x80, // IDR, first_mb_in_slice=0
+		0x00, 0x00, 0x01, 0x65, 0x40, 0x80, 0x00, // IDR, first_mb_in_slice=1, trailing zero
+		0x00, 0x00, 0x00, 0x01, 0x41, 0x9a, // non-IDR, first_mb_in_slice=0
+		0x00, 0x00, 0x01, 0x06, 0x05, // SEI
+		0x00, 0x00, 0x01, 0x41, 0x9b,
+	}
+	assert.Equal(t, [][]byte{
+		{
+			0x00, 0x00, 0x00, 0x01, 0x09, 0xf0,
+			0x00, 0x00, 0x00, 0x01, 0x67, 0x42,
+			0x00, 0x00, 0x00, 0x01, 0x68, 0xce,
+			0x00, 0x00, 0x00, 0x01, 0x65, 0x88, 0x80,
+			0x00, 0x00, 0x00, 0x01, 0x65, 0x40, 0x80,
+		},
+		{0x00, 0x00, 0x00, 0x01, 0x41, 0x9a},
+		{
+			0x00, 0x00, 0x00, 0x01, 0x06, 0x05,
+			0x00, 0x00, 0x00, 0x01, 0x41, 0x9b,
+		},
+	}, SplitAnnexBAccessUnits(stream, false))
+
+	hevc := []byte{
+		0x00, 0x00, 0x01, 0x26, 0x01, 0xaf, // IDR_W_RADL, first_slice_segment_in_pic_flag=1
+		0x00, 0x00, 0x01, 0x26, 0x01, 0x2f, // first_slice_segment_in_pic_flag=0
+		0x00, 0x00, 0x01, 0x02, 0x01, 0xd0, // TRAIL_R, first_slice_segment_in_pic_flag=1
+	}
+	units := SplitAnnexBAccessUnits(hevc, true)
+	require.Len(t, units, 2)
+	assert.Len(t, units[0], 14)
+	assert.Len(t, units[1], 7)
+}
@@ -0,0 +1,205 @@
+package mp4
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	oggHeaderTypeContinued = 0x01
+	oggHeaderTypeBOS       = 0x02
+	oggHeaderTypeEOS       = 0x04
+
+	oggMaxSegments = 255
+
+	// opusGranuleRate is the sampling rate of granule positions in Ogg Opus streams.
+	opusGranuleRate = 48000
+)
+
+var oggCRCTable = func() [256]uint32 {
+	var table [256]uint32
+	for i := range table {
+		crc := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04c11db7
+			} else {
+				crc <<= 1
+			}
+		}
+		table[i] = crc
+	}
+	return table
+}()
+
+func oggCRC(data []byte) uint32 {
+	var crc uint32
+	for _, b := range data {
+		crc = crc<<8 ^ oggCRCTable[byte(crc>>24)^b]
+	}
+	return crc
+}
+
+// oggWriter writes packets of a single logical bitstream as Ogg pages.
+type oggWriter struct {
+	w        io.Writer
+	serial   uint32
+	sequence uint32
+	bos      bool
+
+	segments []byte
+	data     []byte
+	granule  int64
+}
+
+func newOggWriter(w io.Writer, serial uint32) *oggWriter {
+	return &oggWriter{w: w, serial: serial, bos: true}
+}
+
+// writePacket appends the packet to the current page.
+// The page is flushed in advance when the packet doesn't fit in the page.
+func (ow *oggWriter) writePacket(packet []byte, granule int64) error {
+	n := len(packet)/255 + 1
+	if n > oggMaxSegments {
+		return fmt.Errorf("too large Ogg packet: %d", len(packet))
+	}
+	if len(ow.segments)+n > oggMaxSegments {
+		if err := ow.flush(false); err != nil {
+			return err
+		}
+	}
+	for i := 0; i < n-1; i++ {
+		ow.segments = append(ow.segments, 255)
+	}
+	ow.segments = append(ow.segments, byte(len(packet)%255))
+	ow.data = append(ow.data, packet...)
+	ow.granule = granule
+	return nil
+}
+
+// flush writes the current page.
+func (ow *oggWriter) flush(eos bool) error {
+	if len(ow.segments) == 0 && !eos {
+		return nil
+	}
+	var headerType byte
+	if ow.bos {
+		headerType |= oggHeaderTypeBOS
+	}
+	if eos {
+		headerType |= oggHeaderTypeEOS
+	}
+	page := make([]byte, 27, 27+len(ow.segments)+len(ow.data))
+	copy(page, "OggS")
+	page[4] = 0 // stream_structure_version
+	page[5] = headerType
+	binary.LittleEndian.PutUint64(page[6:], uint64(ow.granule))
+	binary.LittleEndian.PutUint32(page[14:], ow.serial)
+	binary.LittleEndian.PutUint32(page[18:], ow.sequence)
+	page[26] = byte(len(ow.segments))
+	page = append(page, ow.segments...)
+	page = append(page, ow.data...)
+	binary.LittleEndian.PutUint32(page[22:], oggCRC(page))
+	if _, err := ow.w.Write(page); err != nil {
+		return err
+	}
+	ow.bos = false
+	ow.sequence++
+	ow.segments = ow.segments[:0]
+	ow.data = ow.data[:0]
+	return nil
+}
+
+// opusHead builds the identification header of Ogg Opus (RFC 7845) from the dOps box.
+func opusHead(dops *DOps) []byte {
+	head := make([]byte, 19, 21+len(dops.ChannelMapping))
+	copy(head, "OpusHead")
+	head[8] = 1 // version
+	head[9] = dops.OutputChannelCount
+	binary.LittleEndian.PutUint16(head[10:], dops.PreSkip)
+	binary.LittleEndian.PutUint32(head[12:], dops.InputSampleRate)
+	binary.LittleEndian.PutUint16(head[16:], uint16(dops.OutputGain))
+	head[18] = dops.ChannelMappingFamily
+	if dops.ChannelMappingFamily != 0 {
+		head = append(head, dops.StreamCount, dops.CoupledCount)
+		head = append(head, dops.ChannelMapping...)
+	}
+	return head
+}
+
+// opusTags builds the comment header of Ogg Opus which has only the vendor string.
+func opusTags() []byte {
+	const vendor = "go-mp4"
+	tags := make([]byte, 8+4+len(vendor)+4)
+	copy(tags, "OpusTags")
+	binary.LittleEndian.PutUint32(tags[8:], uint32(len(vendor)))
+	copy(tags[12:], vendor)
+	return tags
+}
+
+// ExtractOggOpus reads samples of the Opus track and writes them to w as an Ogg Opus stream.
+// The OpusHead packet is built from the dOps box, and granule positions are computed from sample durations.
+// The first Opus track is used when trackID is zero.
+func ExtractOggOpus(r io.ReadSeeker, w io.Writer, trackID uint32) error {
+	bips, err := ExtractBoxesWithPayload(r, nil, []BoxPath{
+		{BoxTypeMoov(), BoxTypeTrak(), BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeOpus(), BoxTypeDOps()},
+	})
+	if err != nil {
+		return err
+	}
+	var dops *DOps
+	for _, bip := range bips {
+		if trackID == 0 || bip.Info.TrackID == trackID {
+			trackID = bip.Info.TrackID
+			dops = bip.Payload.(*DOps)
+			break
+		}
+	}
+	if dops == nil {
+		if trackID != 0 {
+			return fmt.Errorf("Opus track not found: trackID=%d", trackID)
+		}
+		return errors.New("Opus track not found")
+	}
+
+	sr, err := NewSampleReader(r, trackID)
+	if err != nil {
+		return err
+	}
+	if sr.Timescale == 0 {
+		return errors.New("invalid timescale")
+	}
+
+	ow := newOggWriter(w, trackID)
+	// each header packet is placed on its own page
+	if err := ow.writePacket(opusHead(dops), 0); err != nil {
+		return err
+	}
+	if err := ow.flush(false); err != nil {
+		return err
+	}
+	if err := ow.writePacket(opusTags(), 0); err != nil {
+		return err
+	}
+	if err := ow.flush(false); err != nil {
+		return err
+	}
+
+	var duration uint64
+	for {
+		s, err := sr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		duration += uint64(s.Duration)
+		granule := int64(duration * opusGranuleRate / uint64(sr.Timescale))
+		if err := ow.writePacket(s.Data, granule); err != nil {
+			return err
+		}
+	}
+	return ow.flush(true)
+}
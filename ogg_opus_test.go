@@ -0,0 +1,168 @@
+package mp4
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/orcaman/writerseeker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOggCRC(t *testing.T) {
+	assert.Equal(t, uint32(0x89a1897f), oggCRC([]byte("123456789")))
+}
+
+func TestOpusHead(t *testing.T) {
+	assert.Equal(t, []byte{
+		'O', 'p', 'u', 's', 'H', 'e', 'a', 'd',
+		0x01, 0x02, 0x38, 0x01, 0x80, 0xbb, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}, opusHead(&DOps{OutputChannelCount: 2, PreSkip: 312, InputSampleRate: 48000}))
+
+	assert.Equal(t, []byte{
+		'O', 'p', 'u', 's', 'H', 'e', 'a', 'd',
+		0x01, 0x03, 0x38, 0x01, 0x80, 0xbb, 0x00, 0x00, 0x00, 0x01, 0x01,
+		0x02, 0x01, 0x00, 0x02, 0x01,
+	}, opusHead(&DOps{
+		OutputChannelCount:   3,
+		PreSkip:              312,
+		InputSampleRate:      48000,
+		OutputGain:           256,
+		ChannelMappingFamily: 1,
+		StreamCount:          2,
+		CoupledCount:         1,
+		ChannelMapping:       []uint8{0, 2, 1},
+	}))
+}
+
+func TestExtractOggOpus(t *testing.T) {
+	packets := [][]byte{
+		bytes.Repeat([]byte{0xfc, 0x01}, 10),
+		bytes.Repeat([]byte{0xfc, 0x02}, 200),
+		bytes.Repeat([]byte{0xfc, 0x03}, 30),
+	}
+
+	buf := &writerseeker.WriterSeeker{}
+	w := NewWriter(buf)
+	writeTestBox(t, w, &Ftyp{MajorBrand: [4]byte{'i', 's', 'o', 'm'}}, Context{}, nil)
+	var data []byte
+	stsz := &Stsz{SampleCount: uint32(len(packets))}
+	for _, packet := range packets {
+		data = append(data, packet...)
+		stsz.EntrySize = append(stsz.EntrySize, uint32(len(packet)))
+	}
+	mdat, err := w.StartBox(&BoxInfo{Type: BoxTypeMdat()})
+	require.NoError(t, err)
+	_, err = w.Write(data)
+	require.NoError(t, err)
+	_, err = w.EndBox()
+	require.NoError(t, err)
+	writeTestBox(t, w, &Moov{}, Context{}, func() {
+		writeTestBox(t, w, &Mvhd{Timescale: 1000, NextTrackID: 2}, Context{}, nil)
+		writeTestBox(t, w, &Trak{}, Context{}, func() {
+			writeTestBox(t, w, &Tkhd{TrackID: 1}, Context{}, nil)
+			writeTestBox(t, w, &Mdia{}, Context{}, func() {
+				writeTestBox(t, w, &Mdhd{Timescale: 48000}, Context{}, nil)
+				writeTestBox(t, w, &Hdlr{HandlerType: [4]byte{'s', 'o', 'u', 'n'}}, Context{}, nil)
+				writeTestBox(t, w, &Minf{}, Context{}, func() {
+					writeTestBox(t, w, &Stbl{}, Context{}, func() {
+						writeTestBox(t, w, &Stsd{EntryCount: 1}, Context{}, func() {
+							writeTestBox(t, w, &AudioSampleEntry{
+								SampleEntry:  SampleEntry{AnyTypeBox: AnyTypeBox{Type: BoxTypeOpus()}, DataReferenceIndex: 1},
+								ChannelCount: 2,
+								SampleSize:   16,
+								SampleRate:   48000 << 16,
+							}, Context{}, func() {
+								writeTestBox(t, w, &DOps{OutputChannelCount: 2, PreSkip: 312, InputSampleRate: 48000}, Context{}, nil)
+							})
+						})
+						writeTestBox(t, w, &Stts{EntryCount: 1, Entries: []SttsEntry{{SampleCount: 3, SampleDelta: 960}}}, Context{}, nil)
+						writeTestBox(t, w, &Stsc{EntryCount: 1, Entries: []StscEntry{{FirstChunk: 1, SamplesPerChunk: 3, SampleDescriptionIndex: 1}}}, Context{}, nil)
+						writeTestBox(t, w, stsz, Context{}, nil)
+						writeTestBox(t, w, &Stco{EntryCount: 1, ChunkOffset: []uint32{uint32(mdat.Offset + mdat.HeaderSize)}}, Context{}, nil)
+					})
+				})
+			})
+		})
+	})
+
+	output := &bytes.Buffer{}
+	require.NoError(t, ExtractOggOpus(buf.BytesReader(), output, 0))
+	assert.Error(t, ExtractOggOpus(buf.BytesReader(), &bytes.Buffer{}, 2))
+
+	type page struct {
+		headerType byte
+		granule    uint64
+		sequence   uint32
+		packets    [][]byte
+	}
+	var pages []page
+	for stream := output.Bytes(); len(stream) != 0; {
+		require.GreaterOrEqual(t, len(stream), 27)
+		require.Equal(t, []byte("OggS"), stream[:4])
+		n := int(stream[26])
+		segments := stream[27 : 27+n]
+		size := 27 + n
+		for _, s := range segments {
+			size += int(s)
+		}
+		raw := append([]byte(nil), stream[:size]...)
+		crc := binary.LittleEndian.Uint32(raw[22:])
+		binary.LittleEndian.PutUint32(raw[22:], 0)
+		assert.Equal(t, oggCRC(raw), crc)
+
+		p := page{
+			headerType: stream[5],
+			granule:    binary.LittleEndian.Uint64(stream[6:]),
+			sequence:   binary.LittleEndian.Uint32(stream[18:]),
+		}
+		body := stream[27+n : size]
+		var packet []byte
+		for _, s := range segments {
+			packet = append(packet, body[:s]...)
+			body = body[s:]
+			if s < 255 {
+				p.packets = append(p.packets, packet)
+				packet = nil
+			}
+		}
+		pages = append(pages, p)
+		stream = stream[size:]
+	}
+
+	require.Len(t, pages, 3)
+	assert.Equal(t, byte(oggHeaderTypeBOS), pages[0].headerType)
+	assert.Equal(t, [][]byte{opusHead(&DOps{OutputChannelCount: 2, PreSkip: 312, InputSampleRate: 48000})}, pages[0].packets)
+	assert.Equal(t, byte(0), pages[1].headerType)
+	assert.Equal(t, []byte("OpusTags"), pages[1].packets[0][:8])
+	assert.Equal(t, byte(oggHeaderTypeEOS), pages[2].headerType)
+	assert.Equal(t, uint64(2880), pages[2].granule)
+	assert.Equal(t, packets, pages[2].packets)
+	for i, p := range pages {
+		assert.Equal(t, uint32(i), p.sequence)
+	}
+}
+
+func TestOggWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	ow := newOggWriter(buf, 1)
+	for i := 0; i < 300; i++ {
+		require.NoError(t, ow.writePacket([]byte{byte(i)}, int64(i+1)))
+	}
+	require.NoError(t, ow.flush(true))
+
+	data := buf.Bytes()
+	// first page: 255 packets
+	assert.Equal(t, byte(oggHeaderTypeBOS), data[5])
+	assert.Equal(t, uint64(255), binary.LittleEndian.Uint64(data[6:]))
+	assert.Equal(t, byte(255), data[26])
+	data = data[27+255+255:]
+	// second page: 45 packets
+	assert.Equal(t, byte(oggHeaderTypeEOS), data[5])
+	assert.Equal(t, uint64(300), binary.LittleEndian.Uint64(data[6:]))
+	assert.Equal(t, byte(45), data[26])
+	assert.Len(t, data, 27+45+45)
+
+	assert.Error(t, ow.writePacket(make([]byte, 255*255), 0))
+}
@@ -0,0 +1,242 @@
+package mp4
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+// AVCPPS is the picture parameter set of ITU-T H.264.
+type AVCPPS struct {
+	PicParameterSetID                 uint32
+	SeqParameterSetID                 uint32
+	EntropyCodingMode                 bool
+	BottomFieldPicOrderInFramePresent bool
+	NumSliceGroupsMinus1              uint32
+	SliceGroupMapType                 uint32
+	SliceGroupChangeRateMinus1        uint32
+	NumRefIdxL0DefaultActiveMinus1    uint32
+	NumRefIdxL1DefaultActiveMinus1    uint32
+	WeightedPred                      bool
+	WeightedBipredIdc                 uint8
+	PicInitQPMinus26                  int32
+	PicInitQSMinus26                  int32
+	ChromaQPIndexOffset               int32
+	DeblockingFilterControlPresent    bool
+	ConstrainedIntraPred              bool
+	RedundantPicCntPresent            bool
+	Transform8x8Mode                  bool
+	PicScalingMatrixPresent           bool
+	SecondChromaQPIndexOffset         int32
+}
+
+// ParseAVCPPS parses the H.264 PPS NAL unit including its NAL unit header.
+// sps is the SPS referred by the PPS, which is needed to parse the scaling matrix.
+// If sps is nil, chroma_format_idc is assumed to be 1.
+func ParseAVCPPS(nalu []byte, sps *AVCSPS) (*AVCPPS, error) {
+	if len(nalu) < 2 || nalu[0]&0x1f != 8 {
+		return nil, errors.New("invalid PPS NAL unit")
+	}
+	r := newRBSPReader(nalu[1:])
+
+	pps := &AVCPPS{}
+	pps.PicParameterSetID = uint32(r.ue())
+	pps.SeqParameterSetID = uint32(r.ue())
+	pps.EntropyCodingMode = r.flag()
+	pps.BottomFieldPicOrderInFramePresent = r.flag()
+	pps.NumSliceGroupsMinus1 = uint32(r.ue())
+	if pps.NumSliceGroupsMinus1 > 7 {
+		return nil, fmt.Errorf("invalid num_slice_groups_minus1: %d", pps.NumSliceGroupsMinus1)
+	}
+	if pps.NumSliceGroupsMinus1 > 0 {
+		pps.SliceGroupMapType = uint32(r.ue())
+		switch pps.SliceGroupMapType {
+		case 0:
+			for i := uint32(0); i <= pps.NumSliceGroupsMinus1; i++ {
+				r.ue() // run_length_minus1
+			}
+		case 2:
+			for i := uint32(0); i < pps.NumSliceGroupsMinus1; i++ {
+				r.ue() // top_left
+				r.ue() // bottom_right
+			}
+		case 3, 4, 5:
+			r.flag() // slice_group_change_direction_flag
+			pps.SliceGroupChangeRateMinus1 = uint32(r.ue())
+		case 6:
+			n := r.ue() + 1 // pic_size_in_map_units_minus1
+			width := uint(bits.Len32(pps.NumSliceGroupsMinus1))
+			for i := uint64(0); i < n && r.err == nil; i++ {
+				r.u(width) // slice_group_id
+			}
+		}
+	}
+	pps.NumRefIdxL0DefaultActiveMinus1 = uint32(r.ue())
+	pps.NumRefIdxL1DefaultActiveMinus1 = uint32(r.ue())
+	pps.WeightedPred = r.flag()
+	pps.WeightedBipredIdc = uint8(r.u(2))
+	pps.PicInitQPMinus26 = int32(r.se())
+	pps.PicInitQSMinus26 = int32(r.se())
+	pps.ChromaQPIndexOffset = int32(r.se())
+	pps.DeblockingFilterControlPresent = r.flag()
+	pps.ConstrainedIntraPred = r.flag()
+	pps.RedundantPicCntPresent = r.flag()
+	pps.SecondChromaQPIndexOffset = pps.ChromaQPIndexOffset
+	if r.moreData() {
+		pps.Transform8x8Mode = r.flag()
+		pps.PicScalingMatrixPresent = r.flag()
+		if pps.PicScalingMatrixPresent {
+			n := 6
+			if pps.Transform8x8Mode {
+				if sps != nil && sps.ChromaFormatIdc == 3 {
+					n += 6
+				} else {
+					n += 2
+				}
+			}
+			r.readAVCScalingLists(n)
+		}
+		pps.SecondChromaQPIndexOffset = int32(r.se())
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return pps, nil
+}
+
+// String returns the main parameters of the PPS.
+func (pps *AVCPPS) String() string {
+	return fmt.Sprintf("PicParameterSetID=%d SeqParameterSetID=%d EntropyCodingMode=%t Transform8x8Mode=%t",
+		pps.PicParameterSetID, pps.SeqParameterSetID, pps.EntropyCodingMode, pps.Transform8x8Mode)
+}
+
+// HEVCPPS is the picture parameter set of ITU-T H.265.
+type HEVCPPS struct {
+	PicParameterSetID                  uint32
+	SeqParameterSetID                  uint32
+	DependentSliceSegmentsEnabled      bool
+	OutputFlagPresent                  bool
+	NumExtraSliceHeaderBits            uint8
+	SignDataHidingEnabled              bool
+	CabacInitPresent                   bool
+	NumRefIdxL0DefaultActiveMinus1     uint32
+	NumRefIdxL1DefaultActiveMinus1     uint32
+	InitQPMinus26                      int32
+	ConstrainedIntraPred               bool
+	TransformSkipEnabled               bool
+	CuQPDeltaEnabled                   bool
+	DiffCuQPDeltaDepth                 uint32
+	CbQPOffset                         int32
+	CrQPOffset                         int32
+	SliceChromaQPOffsetsPresent        bool
+	WeightedPred                       bool
+	WeightedBipred                     bool
+	TransquantBypassEnabled            bool
+	TilesEnabled                       bool
+	EntropyCodingSyncEnabled           bool
+	NumTileColumnsMinus1               uint32
+	NumTileRowsMinus1                  uint32
+	UniformSpacing                     bool
+	LoopFilterAcrossTilesEnabled       bool
+	LoopFilterAcrossSlicesEnabled      bool
+	DeblockingFilterControlPresent     bool
+	DeblockingFilterOverrideEnabled    bool
+	DeblockingFilterDisabled           bool
+	BetaOffsetDiv2                     int32
+	TcOffsetDiv2                       int32
+	ScalingListDataPresent             bool
+	ListsModificationPresent           bool
+	Log2ParallelMergeLevelMinus2       uint32
+	SliceSegmentHeaderExtensionPresent bool
+	ChromaQPOffsetListEnabled          bool
+}
+
+// ParseHEVCPPS parses the H.265 PPS NAL unit including its NAL unit header.
+// Only the leading fields of the range extension are parsed among the PPS extensions.
+func ParseHEVCPPS(nalu []byte) (*HEVCPPS, error) {
+	if len(nalu) < 3 || (nalu[0]>>1)&0x3f != 34 {
+		return nil, errors.New("invalid PPS NAL unit")
+	}
+	r := newRBSPReader(nalu[2:])
+
+	pps := &HEVCPPS{}
+	pps.PicParameterSetID = uint32(r.ue())
+	pps.SeqParameterSetID = uint32(r.ue())
+	pps.DependentSliceSegmentsEnabled = r.flag()
+	pps.OutputFlagPresent = r.flag()
+	pps.NumExtraSliceHeaderBits = uint8(r.u(3))
+	pps.SignDataHidingEnabled = r.flag()
+	pps.CabacInitPresent = r.flag()
+	pps.NumRefIdxL0DefaultActiveMinus1 = uint32(r.ue())
+	pps.NumRefIdxL1DefaultActiveMinus1 = uint32(r.ue())
+	pps.InitQPMinus26 = int32(r.se())
+	pps.ConstrainedIntraPred = r.flag()
+	pps.TransformSkipEnabled = r.flag()
+	pps.CuQPDeltaEnabled = r.flag()
+	if pps.CuQPDeltaEnabled {
+		pps.DiffCuQPDeltaDepth = uint32(r.ue())
+	}
+	pps.CbQPOffset = int32(r.se())
+	pps.CrQPOffset = int32(r.se())
+	pps.SliceChromaQPOffsetsPresent = r.flag()
+	pps.WeightedPred = r.flag()
+	pps.WeightedBipred = r.flag()
+	pps.TransquantBypassEnabled = r.flag()
+	pps.TilesEnabled = r.flag()
+	pps.EntropyCodingSyncEnabled = r.flag()
+	if pps.TilesEnabled {
+		pps.NumTileColumnsMinus1 = uint32(r.ue())
+		pps.NumTileRowsMinus1 = uint32(r.ue())
+		pps.UniformSpacing = r.flag()
+		if !pps.UniformSpacing {
+			if pps.NumTileColumnsMinus1 > 1024 || pps.NumTileRowsMinus1 > 1024 {
+				return nil, errors.New("invalid number of tiles")
+			}
+			for i := uint32(0); i < pps.NumTileColumnsMinus1; i++ {
+				r.ue() // column_width_minus1
+			}
+			for i := uint32(0); i < pps.NumTileRowsMinus1; i++ {
+				r.ue() // row_height_minus1
+			}
+		}
+		pps.LoopFilterAcrossTilesEnabled = r.flag()
+	}
+	pps.LoopFilterAcrossSlicesEnabled = r.flag()
+	pps.DeblockingFilterControlPresent = r.flag()
+	if pps.DeblockingFilterControlPresent {
+		pps.DeblockingFilterOverrideEnabled = r.flag()
+		pps.DeblockingFilterDisabled = r.flag()
+		if !pps.DeblockingFilterDisabled {
+			pps.BetaOffsetDiv2 = int32(r.se())
+			pps.TcOffsetDiv2 = int32(r.se())
+		}
+	}
+	pps.ScalingListDataPresent = r.flag()
+	if pps.ScalingListDataPresent {
+		r.readHEVCScalingListData()
+	}
+	pps.ListsModificationPresent = r.flag()
+	pps.Log2ParallelMergeLevelMinus2 = uint32(r.ue())
+	pps.SliceSegmentHeaderExtensionPresent = r.flag()
+	if r.moreData() && r.flag() { // pps_extension_present_flag
+		rangeExtension := r.flag()
+		r.u(7) // pps_multilayer_extension_flag, pps_3d_extension_flag, pps_scc_extension_flag, pps_extension_4bits
+		if rangeExtension {
+			if pps.TransformSkipEnabled {
+				r.ue() // log2_max_transform_skip_block_size_minus2
+			}
+			r.flag() // cross_component_prediction_enabled_flag
+			pps.ChromaQPOffsetListEnabled = r.flag()
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return pps, nil
+}
+
+// String returns the main parameters of the PPS.
+func (pps *HEVCPPS) String() string {
+	return fmt.Sprintf("PicParameterSetID=%d SeqParameterSetID=%d TilesEnabled=%t EntropyCodingSyncEnabled=%t",
+		pps.PicParameterSetID, pps.SeqParameterSetID, pps.TilesEnabled, pps.EntropyCodingSyncEnabled)
+}
@@ -0,0 +1,117 @@
+package mp4
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAVCPPS(t *testing.T) {
+	pps, err := ParseAVCPPS([]byte{0x68, 0xeb, 0xec, 0xb2, 0x2c}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, &AVCPPS{
+		EntropyCodingMode:              true,
+		NumRefIdxL0DefaultActiveMinus1: 2,
+		WeightedPred:                   true,
+		WeightedBipredIdc:              2,
+		ChromaQPIndexOffset:            -2,
+		DeblockingFilterControlPresent: true,
+		Transform8x8Mode:               true,
+		SecondChromaQPIndexOffset:      -2,
+	}, pps)
+	assert.Equal(t, "PicParameterSetID=0 SeqParameterSetID=0 EntropyCodingMode=true Transform8x8Mode=true", pps.String())
+
+	// without transform_8x8_mode_flag and the following fields
+	pps, err = ParseAVCPPS(append([]byte{0x68}, buildTestBits(
+		testUE(1),       // pic_parameter_set_id
+		testUE(0),       // seq_parameter_set_id
+		[2]uint64{0, 1}, // entropy_coding_mode_flag
+		[2]uint64{0, 1}, // bottom_field_pic_order_in_frame_present_flag
+		testUE(1),       // num_slice_groups_minus1
+		testUE(6),       // slice_group_map_type
+		testUE(3),       // pic_size_in_map_units_minus1
+		[2]uint64{0, 1}, // slice_group_id[0]
+		[2]uint64{1, 1}, // slice_group_id[1]
+		[2]uint64{0, 1}, // slice_group_id[2]
+		[2]uint64{1, 1}, // slice_group_id[3]
+		testUE(0),       // num_ref_idx_l0_default_active_minus1
+		testUE(0),       // num_ref_idx_l1_default_active_minus1
+		[2]uint64{0, 1}, // weighted_pred_flag
+		[2]uint64{0, 2}, // weighted_bipred_idc
+		testSE(0),       // pic_init_qp_minus26
+		testSE(0),       // pic_init_qs_minus26
+		testSE(3),       // chroma_qp_index_offset
+		[2]uint64{1, 1}, // deblocking_filter_control_present_flag
+		[2]uint64{0, 1}, // constrained_intra_pred_flag
+		[2]uint64{0, 1}, // redundant_pic_cnt_present_flag
+		[2]uint64{1, 1}, // rbsp_stop_one_bit
+	)...), nil)
+	require.NoError(t, err)
+	assert.Equal(t, &AVCPPS{
+		PicParameterSetID:              1,
+		NumSliceGroupsMinus1:           1,
+		SliceGroupMapType:              6,
+		ChromaQPIndexOffset:            3,
+		DeblockingFilterControlPresent: true,
+		SecondChromaQPIndexOffset:      3,
+	}, pps)
+
+	_, err = ParseAVCPPS([]byte{0x67, 0x64, 0x00, 0x0c}, nil)
+	assert.Error(t, err)
+}
+
+func TestParseHEVCPPS(t *testing.T) {
+	pps, err := ParseHEVCPPS([]byte{0x44, 0x01, 0xc1, 0x72, 0xb4, 0x62, 0x40})
+	require.NoError(t, err)
+	assert.Equal(t, &HEVCPPS{
+		SignDataHidingEnabled:         true,
+		CuQPDeltaEnabled:              true,
+		DiffCuQPDeltaDepth:            1,
+		WeightedPred:                  true,
+		EntropyCodingSyncEnabled:      true,
+		LoopFilterAcrossSlicesEnabled: true,
+	}, pps)
+	assert.Equal(t, "PicParameterSetID=0 SeqParameterSetID=0 TilesEnabled=false EntropyCodingSyncEnabled=true", pps.String())
+
+	_, err = ParseHEVCPPS([]byte{0x42, 0x01, 0x01})
+	assert.Error(t, err)
+}
+
+func TestParseHEVCPPSWithRangeExtension(t *testing.T) {
+	pps, err := ParseHEVCPPS(buildTestNALUnit([]byte{0x44, 0x01},
+		testUE(0),       // pps_pic_parameter_set_id
+		testUE(0),       // pps_seq_parameter_set_id
+		[2]uint64{0, 7}, // dependent_slice_segments_enabled_flag, output_flag_present_flag, num_extra_slice_header_bits, sign_data_hiding_enabled_flag, cabac_init_present_flag
+		testUE(0),       // num_ref_idx_l0_default_active_minus1
+		testUE(0),       // num_ref_idx_l1_default_active_minus1
+		testSE(0),       // init_qp_minus26
+		[2]uint64{0, 1}, // constrained_intra_pred_flag
+		[2]uint64{1, 1}, // transform_skip_enabled_flag
+		[2]uint64{0, 1}, // cu_qp_delta_enabled_flag
+		testSE(0),       // pps_cb_qp_offset
+		testSE(0),       // pps_cr_qp_offset
+		[2]uint64{0, 7}, // pps_slice_chroma_qp_offsets_present_flag ... pps_loop_filter_across_slices_enabled_flag
+		[2]uint64{0, 1}, // deblocking_filter_control_present_flag
+		[2]uint64{0, 1}, // pps_scaling_list_data_present_flag
+		[2]uint64{0, 1}, // lists_modification_present_flag
+		testUE(0),       // log2_parallel_merge_level_minus2
+		[2]uint64{0, 1}, // slice_segment_header_extension_present_flag
+		[2]uint64{1, 1}, // pps_extension_present_flag
+		[2]uint64{1, 1}, // pps_range_extension_flag
+		[2]uint64{0, 7}, // pps_multilayer_extension_flag, pps_3d_extension_flag, pps_scc_extension_flag, pps_extension_4bits
+		testUE(1),       // log2_max_transform_skip_block_size_minus2
+		[2]uint64{0, 1}, // cross_component_prediction_enabled_flag
+		[2]uint64{1, 1}, // chroma_qp_offset_list_enabled_flag
+		testUE(0),       // diff_cu_chroma_qp_offset_depth
+		testUE(0),       // chroma_qp_offset_list_len_minus1
+		testSE(0),       // cb_qp_offset_list[0]
+		testSE(0),       // cr_qp_offset_list[0]
+		testUE(0),       // log2_sao_offset_scale_luma
+		testUE(0),       // log2_sao_offset_scale_chroma
+		[2]uint64{1, 1}, // rbsp_stop_one_bit
+	))
+	require.NoError(t, err)
+	assert.True(t, pps.TransformSkipEnabled)
+	assert.True(t, pps.ChromaQPOffsetListEnabled)
+}
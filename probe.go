@@ -32,12 +32,35 @@ type Track struct {
 	Timescale uint32
 	Duration  uint64
 	Codec     Codec
+	// Format is the type of the sample entry.
+	// The original format in the frma box is used for encrypted tracks.
+	Format    BoxType
 	Encrypted bool
 	EditList  EditList
 	Samples   Samples
 	Chunks    Chunks
 	AVC       *AVCDecConfigInfo
+	HEVC      *HEVCDecConfigInfo
+	AV1       *AV1DecConfigInfo
+	VP        *VPDecConfigInfo
 	MP4A      *MP4AInfo
+	Opus      *OpusInfo
+	AC3       *AC3Info
+	EAC3      *EAC3Info
+	ALAC      *ALACInfo
+	PCM       *PCMInfo
+	FLAC      *FLACInfo
+	DOVI      *DOVIDecConfigInfo
+	VVC       *VVCDecConfigInfo
+	AC4       *AC4Info
+	MPEGH     *MPEGHInfo
+	HDR       *HDRInfo
+	// References are the track references in the tref box.
+	References []*TrackReference
+	// Kinds are the roles of the track given by the kind boxes in the udta box.
+	Kinds []*TrackKind
+	// ExtendedLanguage is the BCP 47 language tag in the elng box.
+	ExtendedLanguage string
 }
 
 type Codec int
@@ -46,6 +69,22 @@ const (
 	CodecUnknown Codec = iota
 	CodecAVC1
 	CodecMP4A
+	CodecDolbyVision
+	CodecVVC
+	CodecAC4
+	CodecMPEGH
+	CodecHEVC
+	CodecAV1
+	CodecVP8
+	CodecVP9
+	CodecOpus
+	CodecAC3
+	CodecEAC3
+	CodecALAC
+	CodecPCM
+	CodecFLAC
+	CodecWebVTT
+	CodecTTML
 )
 
 type EditList []*EditListEntry
@@ -55,6 +94,16 @@ type EditListEntry struct {
 	SegmentDuration uint64
 }
 
+type TrackReference struct {
+	ReferenceType BoxType
+	TrackIDs      []uint32
+}
+
+type TrackKind struct {
+	SchemeURI string
+	Value     string
+}
+
 type Samples []*Sample
 
 type Sample struct {
@@ -76,8 +125,127 @@ type AVCDecConfigInfo struct {
 	ProfileCompatibility uint8
 	Level                uint8
 	LengthSize           uint16
+	// Width and Height are the cropped size of the SPS, or the size of the sample entry if the SPS is not available.
+	Width  uint16
+	Height uint16
+	// SPS is the first SPS of avcC, or nil if it is absent or cannot be parsed.
+	SPS *AVCSPS
+}
+
+type HEVCDecConfigInfo struct {
+	ProfileSpace uint8
+	Tier         bool
+	Profile      uint8
+	// ProfileCompatibility has general_profile_compatibility_flag[0] in the most significant bit.
+	ProfileCompatibility uint32
+	ConstraintIndicator  [6]uint8
+	Level                uint8
+	ChromaFormat         uint8
+	BitDepthLuma         uint8
+	BitDepthChroma       uint8
+	LengthSize           uint16
+	// Width and Height are the cropped size of the SPS, or the size of the sample entry if the SPS is not available.
+	Width  uint16
+	Height uint16
+	// SPS is the first SPS of hvcC, or nil if it is absent or cannot be parsed.
+	SPS *HEVCSPS
+}
+
+type AV1DecConfigInfo struct {
+	Profile              uint8
+	Level                uint8
+	Tier                 bool
+	BitDepth             uint8
+	Monochrome           bool
+	ChromaSubsamplingX   bool
+	ChromaSubsamplingY   bool
+	ChromaSamplePosition uint8
 	Width                uint16
 	Height               uint16
+	// SequenceHeader is the sequence header OBU of av1C, or nil if it is absent or cannot be parsed.
+	SequenceHeader *AV1SequenceHeader
+}
+
+type VPDecConfigInfo struct {
+	Profile                 uint8
+	Level                   uint8
+	BitDepth                uint8
+	ChromaSubsampling       uint8
+	FullRange               bool
+	ColourPrimaries         uint8
+	TransferCharacteristics uint8
+	MatrixCoefficients      uint8
+	Width                   uint16
+	Height                  uint16
+}
+
+type VVCDecConfigInfo struct {
+	// Profile, Tier and Level are zero if the vvcC box has no PTL record.
+	Profile    uint8
+	Tier       bool
+	Level      uint8
+	LengthSize uint16
+	Width      uint16
+	Height     uint16
+}
+
+type DOVIDecConfigInfo struct {
+	VersionMajor            uint8
+	VersionMinor            uint8
+	Profile                 uint8
+	Level                   uint8
+	RPUPresent              bool
+	ELPresent               bool
+	BLPresent               bool
+	BLSignalCompatibilityID uint8
+	Width                   uint16
+	Height                  uint16
+}
+
+// HDRInfo is colour and HDR static metadata of a video track.
+type HDRInfo struct {
+	// ColourPrimaries, TransferCharacteristics, MatrixCoefficients and FullRange are
+	// taken from the nclx (or QuickTime nclc) colr box.
+	ColourPrimaries         uint16
+	TransferCharacteristics uint16
+	MatrixCoefficients      uint16
+	FullRange               bool
+
+	HasContentLightLevel bool
+	MaxCLL               uint16
+	MaxFALL              uint16
+
+	HasMasteringDisplay bool
+	// MaxMasteringLuminance and MinMasteringLuminance are in units of 0.0001 cd/m2.
+	MaxMasteringLuminance uint32
+	MinMasteringLuminance uint32
+}
+
+// IsHDR10 reports whether the track is signaled as HDR10, which uses BT.2020 primaries,
+// PQ transfer characteristics and the mastering display metadata.
+func (info *HDRInfo) IsHDR10() bool {
+	return info.ColourPrimaries == 9 && info.TransferCharacteristics == 16 && info.HasMasteringDisplay
+}
+
+// IsHLG reports whether the track uses HLG transfer characteristics.
+func (info *HDRInfo) IsHLG() bool {
+	return info.TransferCharacteristics == 18
+}
+
+type AC4Info struct {
+	BitstreamVersion uint8
+	// ChannelCount is taken from the sample entry.
+	ChannelCount      uint16
+	PresentationCount int
+	// Presentations are the decoded presentations, which exclude ones of unsupported versions.
+	Presentations []*AC4PresentationInfo
+}
+
+type MPEGHInfo struct {
+	ProfileLevelIndication uint8
+	ReferenceChannelLayout uint8
+	// ChannelCount is taken from the reference channel layout if it is defined, or the sample entry.
+	ChannelCount uint16
 }
 
 type MP4AInfo struct {
@@ -86,6 +254,64 @@ type MP4AInfo struct {
 	ChannelCount uint16
 }
 
+type OpusInfo struct {
+	ChannelCount         uint16
+	PreSkip              uint16
+	InputSampleRate      uint32
+	OutputGain           int16
+	ChannelMappingFamily uint8
+}
+
+type AC3Info struct {
+	SampleRate      uint32
+	BitstreamID     uint8
+	BitstreamMode   uint8
+	AudioCodingMode uint8
+	LFE             bool
+	// BitRate is in kbit/s.
+	BitRate      uint32
+	ChannelCount uint16
+}
+
+type EAC3Info struct {
+	// DataRate is in kbit/s.
+	DataRate                  uint16
+	IndependentSubstreamCount int
+	// SampleRate and ChannelCount are of the first independent substream,
+	// and ChannelCount includes the channels of its dependent substreams.
+	SampleRate   uint32
+	ChannelCount uint16
+	// JOC represents whether the stream is Dolby Atmos with joint object coding.
+	JOC                bool
+	JOCComplexityIndex uint8
+}
+
+type ALACInfo struct {
+	FrameLength  uint32
+	BitDepth     uint8
+	ChannelCount uint16
+	SampleRate   uint32
+	AvgBitRate   uint32
+}
+
+type PCMInfo struct {
+	Float        bool
+	LittleEndian bool
+	SampleSize   uint8
+	ChannelCount uint16
+	// SampleRate is taken from the sample entry.
+	SampleRate uint32
+}
+
+type FLACInfo struct {
+	MinimumBlockSize uint16
+	MaximumBlockSize uint16
+	SampleRate       uint32
+	ChannelCount     uint16
+	BitsPerSample    uint8
+	TotalSamples     uint64
+}
+
 type Segments []*Segment
 
 // Deprecated: replace with Segment
@@ -191,15 +417,65 @@ func probeTrak(r io.ReadSeeker, bi *BoxInfo) (*Track, error) {
 		{BoxTypeTkhd()},
 		{BoxTypeEdts(), BoxTypeElst()},
 		{BoxTypeMdia(), BoxTypeMdhd()},
+		{BoxTypeMdia(), BoxTypeElng()},
+		{BoxTypeUdta(), BoxTypeKind()},
 		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeAvc1()},
 		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeAvc1(), BoxTypeAvcC()},
 		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeEncv()},
 		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeEncv(), BoxTypeAvcC()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeEncv(), BoxTypeSinf(), BoxTypeFrma()},
 		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeMp4a()},
 		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeMp4a(), BoxTypeEsds()},
 		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeMp4a(), BoxTypeWave(), BoxTypeEsds()},
 		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeEnca()},
 		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeEnca(), BoxTypeEsds()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeEnca(), BoxTypeSinf(), BoxTypeFrma()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeHev1()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeHvc1()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeAny(), BoxTypeHvcC()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeAv01()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeAny(), BoxTypeAv1C()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeVp08()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeVp09()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeAny(), BoxTypeVpcC()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeOpus()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeAny(), BoxTypeDOps()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeAC3()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeAny(), BoxTypeDAC3()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeEC3()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeAny(), BoxTypeDEC3()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeAny(), BoxTypeAlac()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeIpcm()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeFpcm()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeAny(), BoxTypePcmC()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeFLaC()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeAny(), BoxTypeDfLa()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeWvtt()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeStpp()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeDvh1()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeDvhe()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeDva1()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeDvav()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeAny(), BoxTypeDvcC()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeAny(), BoxTypeDvvC()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeAny(), BoxTypeDvwC()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeVvc1()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeVvc1(), BoxTypeVvcC()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeVvi1()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeVvi1(), BoxTypeVvcC()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeAC4()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeAC4(), BoxTypeDAC4()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeMha1()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeMha1(), BoxTypeMhaC()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeMha2()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeMha2(), BoxTypeMhaC()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeMhm1()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeMhm1(), BoxTypeMhaC()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeMhm2()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeMhm2(), BoxTypeMhaC()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeAny(), BoxTypeColr()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeAny(), BoxTypeClli()},
+		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeAny(), BoxTypeMdcv()},
 		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStco()},
 		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeCo64()},
 		{BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStts()},
@@ -213,8 +489,26 @@ func probeTrak(r io.ReadSeeker, bi *BoxInfo) (*Track, error) {
 	var tkhd *Tkhd
 	var elst *Elst
 	var mdhd *Mdhd
-	var avc1 *VisualSampleEntry
+	var format BoxType
+	var frma *Frma
+	var visualSampleEntry *VisualSampleEntry
 	var avcC *AVCDecoderConfiguration
+	var hvcC *HvcC
+	var av1C *Av1C
+	var vpcC *VpcC
+	var dovi *DOVIDecoderConfiguration
+	var dOps *DOps
+	var dac3 *Dac3
+	var dec3 *Dec3
+	var alac *Alac
+	var pcmC *PcmC
+	var dfLa *DfLa
+	var dac4 *Dac4
+	var mhaC *MhaC
+	var vvcC *VvcC
+	var colr *Colr
+	var clli *Clli
+	var mdcv *Mdcv
 	var audioSampleEntry *AudioSampleEntry
 	var esds *Esds
 	var stco *Stco
@@ -231,23 +525,67 @@ func probeTrak(r io.ReadSeeker, bi *BoxInfo) (*Track, error) {
 			elst = bip.Payload.(*Elst)
 		case BoxTypeMdhd():
 			mdhd = bip.Payload.(*Mdhd)
-		case BoxTypeAvc1():
-			track.Codec = CodecAVC1
-			avc1 = bip.Payload.(*VisualSampleEntry)
+		case BoxTypeElng():
+			track.ExtendedLanguage = bip.Payload.(*Elng).ExtendedLanguage
+		case BoxTypeKind():
+			kind := bip.Payload.(*Kind)
+			track.Kinds = append(track.Kinds, &TrackKind{SchemeURI: kind.SchemeURI, Value: kind.Value})
+		case BoxTypeAvc1(), BoxTypeEncv(), BoxTypeHev1(), BoxTypeHvc1(),
+			BoxTypeDvh1(), BoxTypeDvhe(), BoxTypeDva1(), BoxTypeDvav(),
+			BoxTypeVvc1(), BoxTypeVvi1(), BoxTypeAv01(), BoxTypeVp08(), BoxTypeVp09():
+			format = bip.Info.Type
+			visualSampleEntry = bip.Payload.(*VisualSampleEntry)
+		case BoxTypeMp4a(), BoxTypeEnca(), BoxTypeOpus(), BoxTypeAC3(), BoxTypeEC3(), BoxTypeAC4(),
+			BoxTypeIpcm(), BoxTypeFpcm(), BoxTypeFLaC(),
+			BoxTypeMha1(), BoxTypeMha2(), BoxTypeMhm1(), BoxTypeMhm2():
+			format = bip.Info.Type
+			audioSampleEntry = bip.Payload.(*AudioSampleEntry)
+		case BoxTypeWvtt(), BoxTypeStpp():
+			format = bip.Info.Type
+		case BoxTypeFrma():
+			frma = bip.Payload.(*Frma)
 		case BoxTypeAvcC():
 			avcC = bip.Payload.(*AVCDecoderConfiguration)
-		case BoxTypeEncv():
-			track.Codec = CodecAVC1
-			track.Encrypted = true
-		case BoxTypeMp4a():
-			track.Codec = CodecMP4A
-			audioSampleEntry = bip.Payload.(*AudioSampleEntry)
-		case BoxTypeEnca():
-			track.Codec = CodecMP4A
-			track.Encrypted = true
-			audioSampleEntry = bip.Payload.(*AudioSampleEntry)
 		case BoxTypeEsds():
 			esds = bip.Payload.(*Esds)
+		case BoxTypeHvcC():
+			hvcC = bip.Payload.(*HvcC)
+		case BoxTypeAv1C():
+			av1C = bip.Payload.(*Av1C)
+		case BoxTypeVpcC():
+			vpcC = bip.Payload.(*VpcC)
+		case BoxTypeDvcC(), BoxTypeDvvC(), BoxTypeDvwC():
+			dovi = bip.Payload.(*DOVIDecoderConfiguration)
+		case BoxTypeVvcC():
+			vvcC = bip.Payload.(*VvcC)
+		case BoxTypeDOps():
+			dOps = bip.Payload.(*DOps)
+		case BoxTypeDAC3():
+			dac3 = bip.Payload.(*Dac3)
+		case BoxTypeDEC3():
+			dec3 = bip.Payload.(*Dec3)
+		case BoxTypeAlac():
+			// the alac sample entry itself is not extracted, because it can be decoded only in the stsd context
+			alac = bip.Payload.(*Alac)
+			if format == (BoxType{}) {
+				format = BoxTypeAlac()
+			}
+		case BoxTypePcmC():
+			pcmC = bip.Payload.(*PcmC)
+		case BoxTypeDfLa():
+			dfLa = bip.Payload.(*DfLa)
+		case BoxTypeDAC4():
+			dac4 = bip.Payload.(*Dac4)
+		case BoxTypeMhaC():
+			mhaC = bip.Payload.(*MhaC)
+		case BoxTypeColr():
+			if c := bip.Payload.(*Colr); c.ColourType == [4]byte{'n', 'c', 'l', 'x'} || c.ColourType == [4]byte{'n', 'c', 'l', 'c'} {
+				colr = c
+			}
+		case BoxTypeClli():
+			clli = bip.Payload.(*Clli)
+		case BoxTypeMdcv():
+			mdcv = bip.Payload.(*Mdcv)
 		case BoxTypeStco():
 			stco = bip.Payload.(*Stco)
 		case BoxTypeStts():
@@ -279,21 +617,265 @@ func probeTrak(r io.ReadSeeker, bi *BoxInfo) (*Track, error) {
 		track.EditList = editList
 	}
 
+	if track.References, err = probeTrackReferences(r, bi); err != nil {
+		return nil, err
+	}
+
 	if mdhd == nil {
 		return nil, errors.New("mdhd box not found")
 	}
 	track.Timescale = mdhd.Timescale
 	track.Duration = mdhd.GetDuration()
 
-	if avc1 != nil && avcC != nil {
+	if format == BoxTypeEncv() || format == BoxTypeEnca() {
+		track.Encrypted = true
+		if frma != nil {
+			format = BoxType(frma.DataFormat)
+		}
+	}
+	track.Format = format
+	track.Codec = codecOf(format)
+
+	if visualSampleEntry != nil && avcC != nil {
 		track.AVC = &AVCDecConfigInfo{
 			ConfigurationVersion: avcC.ConfigurationVersion,
 			Profile:              avcC.Profile,
 			ProfileCompatibility: avcC.ProfileCompatibility,
 			Level:                avcC.Level,
 			LengthSize:           uint16(avcC.LengthSizeMinusOne) + 1,
-			Width:                avc1.Width,
-			Height:               avc1.Height,
+			Width:                visualSampleEntry.Width,
+			Height:               visualSampleEntry.Height,
+		}
+		if len(avcC.SequenceParameterSets) != 0 {
+			if sps, err := ParseAVCSPS(avcC.SequenceParameterSets[0].NALUnit); err == nil {
+				track.AVC.SPS = sps
+				track.AVC.Width, track.AVC.Height = spsSize(sps.Width, sps.Height, track.AVC.Width, track.AVC.Height)
+			}
+		}
+	}
+
+	if visualSampleEntry != nil && hvcC != nil {
+		track.HEVC = &HEVCDecConfigInfo{
+			ProfileSpace:        hvcC.GeneralProfileSpace,
+			Tier:                hvcC.GeneralTierFlag,
+			Profile:             hvcC.GeneralProfileIdc,
+			ConstraintIndicator: hvcC.GeneralConstraintIndicator,
+			Level:               hvcC.GeneralLevelIdc,
+			ChromaFormat:        hvcC.ChromaFormatIdc,
+			BitDepthLuma:        hvcC.BitDepthLumaMinus8 + 8,
+			BitDepthChroma:      hvcC.BitDepthChromaMinus8 + 8,
+			LengthSize:          uint16(hvcC.LengthSizeMinusOne) + 1,
+			Width:               visualSampleEntry.Width,
+			Height:              visualSampleEntry.Height,
+		}
+		for i, flag := range hvcC.GeneralProfileCompatibility {
+			if flag {
+				track.HEVC.ProfileCompatibility |= 1 << uint(31-i)
+			}
+		}
+		for _, array := range hvcC.NaluArrays {
+			if array.NaluType == 33 && len(array.Nalus) != 0 {
+				if sps, err := ParseHEVCSPS(array.Nalus[0].NALUnit); err == nil {
+					track.HEVC.SPS = sps
+					track.HEVC.Width, track.HEVC.Height = spsSize(sps.Width, sps.Height, track.HEVC.Width, track.HEVC.Height)
+				}
+				break
+			}
+		}
+	}
+
+	if visualSampleEntry != nil && av1C != nil {
+		track.AV1 = &AV1DecConfigInfo{
+			Profile:              av1C.SeqProfile,
+			Level:                av1C.SeqLevelIdx0,
+			Tier:                 av1C.SeqTier0 != 0,
+			BitDepth:             8,
+			Monochrome:           av1C.Monochrome != 0,
+			ChromaSubsamplingX:   av1C.ChromaSubsamplingX != 0,
+			ChromaSubsamplingY:   av1C.ChromaSubsamplingY != 0,
+			ChromaSamplePosition: av1C.ChromaSamplePosition,
+			Width:                visualSampleEntry.Width,
+			Height:               visualSampleEntry.Height,
+		}
+		if av1C.HighBitdepth != 0 {
+			track.AV1.BitDepth = 10
+			if av1C.SeqProfile == 2 && av1C.TwelveBit != 0 {
+				track.AV1.BitDepth = 12
+			}
+		}
+		if seq, err := av1C.DecodeSequenceHeader(); err == nil {
+			track.AV1.SequenceHeader = seq
+		}
+	}
+
+	if visualSampleEntry != nil && vpcC != nil {
+		track.VP = &VPDecConfigInfo{
+			Profile:                 vpcC.Profile,
+			Level:                   vpcC.Level,
+			BitDepth:                vpcC.BitDepth,
+			ChromaSubsampling:       vpcC.ChromaSubsampling,
+			FullRange:               vpcC.VideoFullRangeFlag != 0,
+			ColourPrimaries:         vpcC.ColourPrimaries,
+			TransferCharacteristics: vpcC.TransferCharacteristics,
+			MatrixCoefficients:      vpcC.MatrixCoefficients,
+			Width:                   visualSampleEntry.Width,
+			Height:                  visualSampleEntry.Height,
+		}
+	}
+
+	if visualSampleEntry != nil && vvcC != nil {
+		track.VVC = &VVCDecConfigInfo{
+			LengthSize: uint16(vvcC.LengthSizeMinusOne) + 1,
+			Width:      visualSampleEntry.Width,
+			Height:     visualSampleEntry.Height,
+		}
+		if vvcC.PtlPresentFlag {
+			track.VVC.Profile = vvcC.NativePTL.GeneralProfileIdc
+			track.VVC.Tier = vvcC.NativePTL.GeneralTierFlag
+			track.VVC.Level = vvcC.NativePTL.GeneralLevelIdc
+		}
+	}
+
+	if dovi != nil {
+		track.DOVI = &DOVIDecConfigInfo{
+			VersionMajor:            dovi.DVVersionMajor,
+			VersionMinor:            dovi.DVVersionMinor,
+			Profile:                 dovi.DVProfile,
+			Level:                   dovi.DVLevel,
+			RPUPresent:              dovi.RPUPresentFlag,
+			ELPresent:               dovi.ELPresentFlag,
+			BLPresent:               dovi.BLPresentFlag,
+			BLSignalCompatibilityID: dovi.DVBLSignalCompatibilityID,
+		}
+		// the base layer may be signaled by an AVC or HEVC sample entry for backward compatibility
+		if visualSampleEntry != nil {
+			track.DOVI.Width = visualSampleEntry.Width
+			track.DOVI.Height = visualSampleEntry.Height
+		}
+	}
+
+	if colr != nil || clli != nil || mdcv != nil {
+		track.HDR = &HDRInfo{}
+		if colr != nil {
+			track.HDR.ColourPrimaries = colr.ColourPrimaries
+			track.HDR.TransferCharacteristics = colr.TransferCharacteristics
+			track.HDR.MatrixCoefficients = colr.MatrixCoefficients
+			track.HDR.FullRange = colr.FullRangeFlag
+		}
+		if clli != nil {
+			track.HDR.HasContentLightLevel = true
+			track.HDR.MaxCLL = clli.MaxContentLightLevel
+			track.HDR.MaxFALL = clli.MaxPicAverageLightLevel
+		}
+		if mdcv != nil {
+			track.HDR.HasMasteringDisplay = true
+			track.HDR.MaxMasteringLuminance = mdcv.MaxDisplayMasteringLuminance
+			track.HDR.MinMasteringLuminance = mdcv.MinDisplayMasteringLuminance
+		}
+	}
+
+	if track.Codec == CodecAC4 && audioSampleEntry != nil {
+		track.AC4 = &AC4Info{ChannelCount: audioSampleEntry.ChannelCount}
+		if dac4 != nil {
+			track.AC4.BitstreamVersion = dac4.BitstreamVersion
+			track.AC4.PresentationCount = len(dac4.Presentations)
+			for i := range dac4.Presentations {
+				info, err := dac4.Presentations[i].Decode()
+				if err != nil {
+					continue
+				}
+				track.AC4.Presentations = append(track.AC4.Presentations, info)
+			}
+		}
+	}
+
+	if track.Codec == CodecMPEGH && audioSampleEntry != nil {
+		// mhm1 and mhm2 may have no mhaC box, because the configuration is carried in-band
+		track.MPEGH = &MPEGHInfo{ChannelCount: audioSampleEntry.ChannelCount}
+		if mhaC != nil {
+			track.MPEGH.ProfileLevelIndication = mhaC.MPEGH3DAProfileLevelIndication
+			track.MPEGH.ReferenceChannelLayout = mhaC.ReferenceChannelLayout
+			if n := mhaC.GetChannelCount(); n != 0 {
+				track.MPEGH.ChannelCount = n
+			}
+		}
+	}
+
+	if dOps != nil {
+		track.Opus = &OpusInfo{
+			ChannelCount:         uint16(dOps.OutputChannelCount),
+			PreSkip:              dOps.PreSkip,
+			InputSampleRate:      dOps.InputSampleRate,
+			OutputGain:           dOps.OutputGain,
+			ChannelMappingFamily: dOps.ChannelMappingFamily,
+		}
+	}
+
+	if dac3 != nil {
+		track.AC3 = &AC3Info{
+			SampleRate:      ac3SampleRate(dac3.Fscod),
+			BitstreamID:     dac3.Bsid,
+			BitstreamMode:   dac3.Bsmod,
+			AudioCodingMode: dac3.Acmod,
+			LFE:             dac3.LfeOn != 0,
+			ChannelCount:    ac3ChannelCount(dac3.Acmod, dac3.LfeOn, 0),
+		}
+		if int(dac3.BitRateCode) < len(ac3BitRates) {
+			track.AC3.BitRate = ac3BitRates[dac3.BitRateCode]
+		}
+	}
+
+	if dec3 != nil {
+		track.EAC3 = &EAC3Info{
+			DataRate:                  dec3.DataRate,
+			IndependentSubstreamCount: len(dec3.IndSub),
+			JOC:                       dec3.FlagEC3ExtensionTypeA != 0,
+			JOCComplexityIndex:        dec3.ComplexityIndexTypeA,
+		}
+		if len(dec3.IndSub) != 0 {
+			sub := &dec3.IndSub[0]
+			track.EAC3.SampleRate = ac3SampleRate(sub.Fscod)
+			var chanLoc uint16
+			if sub.NumDepSub != 0 {
+				chanLoc = sub.ChanLoc
+			}
+			track.EAC3.ChannelCount = ac3ChannelCount(sub.Acmod, sub.LfeOn, chanLoc)
+		}
+	}
+
+	if alac != nil {
+		track.ALAC = &ALACInfo{
+			FrameLength:  alac.FrameLength,
+			BitDepth:     alac.BitDepth,
+			ChannelCount: uint16(alac.NumChannels),
+			SampleRate:   alac.SampleRate,
+			AvgBitRate:   alac.AvgBitRate,
+		}
+	}
+
+	if track.Codec == CodecPCM && audioSampleEntry != nil {
+		track.PCM = &PCMInfo{
+			Float:        format == BoxTypeFpcm(),
+			SampleSize:   uint8(audioSampleEntry.SampleSize),
+			ChannelCount: audioSampleEntry.ChannelCount,
+			SampleRate:   uint32(audioSampleEntry.GetSampleRateInt()),
+		}
+		if pcmC != nil {
+			track.PCM.LittleEndian = pcmC.FormatFlags&0x01 != 0
+			track.PCM.SampleSize = pcmC.PCMSampleSize
+		}
+	}
+
+	if dfLa != nil {
+		if info := dfLa.GetStreamInfo(); info != nil {
+			track.FLAC = &FLACInfo{
+				MinimumBlockSize: info.MinimumBlockSize,
+				MaximumBlockSize: info.MaximumBlockSize,
+				SampleRate:       info.SampleRate,
+				ChannelCount:     uint16(info.ChannelsMinusOne) + 1,
+				BitsPerSample:    info.BitsPerSampleMinus1 + 1,
+				TotalSamples:     info.TotalSamples,
+			}
 		}
 	}
 
@@ -365,6 +947,12 @@ func probeTrak(r io.ReadSeeker, bi *BoxInfo) (*Track, error) {
 	}
 
 	if stsz != nil {
+		if stsz.SampleSize != 0 {
+			// all samples have the same size, and EntrySize is empty
+			for i := 0; i < int(stsz.SampleCount) && i < len(track.Samples); i++ {
+				track.Samples[i].Size = stsz.SampleSize
+			}
+		}
 		for i := 0; i < len(stsz.EntrySize) && i < len(track.Samples); i++ {
 			track.Samples[i].Size = stsz.EntrySize[i]
 		}
@@ -373,6 +961,84 @@ func probeTrak(r io.ReadSeeker, bi *BoxInfo) (*Track, error) {
 	return track, nil
 }
 
+// spsSize returns the cropped size of the SPS, or the fallback size if the SPS size does not fit in the sample entry fields.
+func spsSize(width, height uint32, fallbackWidth, fallbackHeight uint16) (uint16, uint16) {
+	if width == 0 || height == 0 || width > 0xffff || height > 0xffff {
+		return fallbackWidth, fallbackHeight
+	}
+	return uint16(width), uint16(height)
+}
+
+// codecOf returns the codec of the sample entry type.
+func codecOf(format BoxType) Codec {
+	switch format {
+	case BoxTypeAvc1(), BoxTypeEncv(): // encv is assumed to be AVC when the frma box is absent
+		return CodecAVC1
+	case BoxTypeHev1(), BoxTypeHvc1():
+		return CodecHEVC
+	case BoxTypeDvh1(), BoxTypeDvhe(), BoxTypeDva1(), BoxTypeDvav():
+		return CodecDolbyVision
+	case BoxTypeVvc1(), BoxTypeVvi1():
+		return CodecVVC
+	case BoxTypeAv01():
+		return CodecAV1
+	case BoxTypeVp08():
+		return CodecVP8
+	case BoxTypeVp09():
+		return CodecVP9
+	case BoxTypeMp4a(), BoxTypeEnca(): // enca is assumed to be AAC when the frma box is absent
+		return CodecMP4A
+	case BoxTypeOpus():
+		return CodecOpus
+	case BoxTypeAC3():
+		return CodecAC3
+	case BoxTypeEC3():
+		return CodecEAC3
+	case BoxTypeAC4():
+		return CodecAC4
+	case BoxTypeAlac():
+		return CodecALAC
+	case BoxTypeIpcm(), BoxTypeFpcm():
+		return CodecPCM
+	case BoxTypeFLaC():
+		return CodecFLAC
+	case BoxTypeMha1(), BoxTypeMha2(), BoxTypeMhm1(), BoxTypeMhm2():
+		return CodecMPEGH
+	case BoxTypeWvtt():
+		return CodecWebVTT
+	case BoxTypeStpp():
+		return CodecTTML
+	}
+	return CodecUnknown
+}
+
+var ac3BitRates = []uint32{32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384, 448, 512, 576, 640}
+
+func ac3SampleRate(fscod uint8) uint32 {
+	switch fscod {
+	case 0:
+		return 48000
+	case 1:
+		return 44100
+	case 2:
+		return 32000
+	}
+	return 0
+}
+
+// ac3ChannelCount returns the number of channels given by acmod, lfeon and chan_loc of dec3.
+// chan_loc is a bit field in which the channel locations are assigned
+// from the most significant bit: Lc/Rc, Lrs/Rrs, Cs, Ts, Lsd/Rsd, Lw/Rw, Lvh/Rvh, Cvh and LFE2.
+func ac3ChannelCount(acmod, lfeon uint8, chanLoc uint16) uint16 {
+	n := []uint16{2, 1, 2, 3, 3, 4, 4, 5}[acmod&0x7] + uint16(lfeon&0x1)
+	for i, channels := range []uint16{2, 2, 1, 1, 2, 2, 2, 1, 1} {
+		if chanLoc&(0x100>>uint(i)) != 0 {
+			n += channels
+		}
+	}
+	return n
+}
+
 func detectAACProfile(esds *Esds) (oti, audOTI uint8, err error) {
 	configDscr := findDescriptorByTag(esds.Descriptors, DecoderConfigDescrTag)
 	if configDscr == nil || configDscr.DecoderConfigDescriptor == nil {
@@ -492,6 +1158,37 @@ func getAudioObjectType(r bitio.Reader) (byte, int, error) {
 	return audioObjectType[0] + 32, 11, nil
 }
 
+// probeTrackReferences reads the track reference type boxes in the tref box.
+// The boxes are unmarshalled with their own contexts, and unknown reference types are ignored.
+func probeTrackReferences(r io.ReadSeeker, bi *BoxInfo) ([]*TrackReference, error) {
+	bis, err := ExtractBoxes(r, bi, []BoxPath{{BoxTypeTref(), BoxTypeAny()}})
+	if err != nil {
+		return nil, err
+	}
+	var refs []*TrackReference
+	for _, bi := range bis {
+		if !bi.IsSupportedType() {
+			continue
+		}
+		if _, err := bi.SeekToPayload(r); err != nil {
+			return nil, err
+		}
+		if err := checkPayloadSize(r, bi); err != nil {
+			return nil, err
+		}
+		box, _, err := UnmarshalAny(r, bi.Type, bi.Size-bi.HeaderSize, bi.Context)
+		if err != nil {
+			return nil, err
+		}
+		tref, ok := box.(*TrackReferenceType)
+		if !ok {
+			continue
+		}
+		refs = append(refs, &TrackReference{ReferenceType: bi.Type, TrackIDs: tref.TrackIDs})
+	}
+	return refs, nil
+}
+
 func probeMoof(r io.ReadSeeker, bi *BoxInfo) (*Segment, error) {
 	bips, err := ExtractBoxesWithPayload(r, bi, []BoxPath{
 		{BoxTypeTraf(), BoxTypeTfhd()},
@@ -572,43 +1269,31 @@ func probeMoof(r io.ReadSeeker, bi *BoxInfo) (*Segment, error) {
 	return segment, nil
 }
 
+// FindIDRFrames returns the indices of the samples which are random access points in the bitstream.
+// In spite of its name, it detects not only IDR pictures but also CRA and BLA pictures of HEVC,
+// shown key frames of AV1, and key frames of VP8 and VP9. It returns nil for the other codecs.
+// The samples in movie fragments are also inspected.
 func FindIDRFrames(r io.ReadSeeker, trackInfo *TrackInfo) ([]int, error) {
-	if trackInfo.AVC == nil {
+	detector := newRandomAccessDetector(trackInfo)
+	if detector == nil {
 		return nil, nil
 	}
-	lengthSize := uint32(trackInfo.AVC.LengthSize)
+	sr, err := NewSampleReader(r, trackInfo.TrackID)
+	if err != nil {
+		return nil, err
+	}
 
-	var si int
 	idxs := make([]int, 0, 8)
-	for _, chunk := range trackInfo.Chunks {
-		end := si + int(chunk.SamplesPerChunk)
-		dataOffset := chunk.DataOffset
-		for ; si < end && si < len(trackInfo.Samples); si++ {
-			sample := trackInfo.Samples[si]
-			if sample.Size == 0 {
-				continue
-			}
-			for nalOffset := uint32(0); nalOffset+lengthSize+1 <= sample.Size; {
-				if _, err := r.Seek(int64(dataOffset+uint64(nalOffset)), io.SeekStart); err != nil {
-					return nil, err
-				}
-				data := make([]byte, lengthSize+1)
-				if _, err := io.ReadFull(r, data); err != nil {
-					return nil, err
-				}
-				var length uint32
-				for i := 0; i < int(lengthSize); i++ {
-					length = (length << 8) + uint32(data[i])
-				}
-				nalHeader := data[lengthSize]
-				nalType := nalHeader & 0x1f
-				if nalType == 5 {
-					idxs = append(idxs, si)
-					break
-				}
-				nalOffset += lengthSize + length
-			}
-			dataOffset += uint64(sample.Size)
+	for _, sample := range sr.Samples() {
+		if sample.Size == 0 {
+			continue
+		}
+		data, err := sr.ReadSampleData(sample)
+		if err != nil {
+			return nil, err
+		}
+		if t, _ := detector.detect(data); t.IsRandomAccessPoint() {
+			idxs = append(idxs, sample.Index)
 		}
 	}
 	return idxs, nil
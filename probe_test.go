@@ -1,9 +1,12 @@
 package mp4
 
 import (
+	"bytes"
+	"io"
 	"os"
 	"testing"
 
+	"github.com/orcaman/writerseeker"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -40,6 +43,10 @@ func TestProbe(t *testing.T) {
 	assert.Equal(t, uint16(0x04), info.Tracks[0].AVC.LengthSize)
 	assert.Equal(t, uint16(320), info.Tracks[0].AVC.Width)
 	assert.Equal(t, uint16(180), info.Tracks[0].AVC.Height)
+	require.NotNil(t, info.Tracks[0].AVC.SPS)
+	assert.Equal(t, uint32(320), info.Tracks[0].AVC.SPS.Width)
+	assert.Equal(t, uint32(180), info.Tracks[0].AVC.SPS.Height)
+	assert.Equal(t, float64(10), info.Tracks[0].AVC.SPS.FrameRate())
 	assert.False(t, info.Tracks[0].Encrypted)
 	require.Len(t, info.Tracks[0].EditList, 1)
 	assert.Equal(t, int64(2048), info.Tracks[0].EditList[0].MediaTime)
@@ -74,6 +81,19 @@ func TestProbe(t *testing.T) {
 	assert.Equal(t, 0, idxs[0])
 }
 
+func TestProbeConstantSampleSize(t *testing.T) {
+	r := buildTestRandomAccessFile(t)
+	info, err := Probe(r)
+	require.NoError(t, err)
+	require.Len(t, info.Tracks, 1)
+	require.Len(t, info.Tracks[0].Samples, 5)
+	for _, sample := range info.Tracks[0].Samples {
+		assert.Equal(t, uint32(7), sample.Size)
+	}
+	// 5 samples of 7 bytes in 500 ticks of the timescale 1000
+	assert.Equal(t, uint64(8*35*1000/500), info.Tracks[0].Samples.GetBitrate(info.Tracks[0].Timescale))
+}
+
 func TestProbeEncryptedVideo(t *testing.T) {
 	f, err := os.Open("./testdata/sample_init.encv.mp4")
 	require.NoError(t, err)
@@ -110,6 +130,697 @@ func TestProbeEncryptedAudio(t *testing.T) {
 	assert.True(t, info.Tracks[1].Encrypted)
 }
 
+// buildTestProbeFile returns a movie file which has a track of the sample entry written by sampleEntry.
+func buildTestProbeFile(t *testing.T, sampleEntry func(w *Writer)) io.ReadSeeker {
+	buf := &writerseeker.WriterSeeker{}
+	w := NewWriter(buf)
+	ctx := Context{}
+	writeTestBox(t, w, &Ftyp{MajorBrand: [4]byte{'i', 's', 'o', 'm'}}, ctx, nil)
+	writeTestBox(t, w, &Moov{}, ctx, func() {
+		writeTestBox(t, w, &Mvhd{Timescale: 1000, NextTrackID: 2}, ctx, nil)
+		writeTestBox(t, w, &Trak{}, ctx, func() {
+			writeTestBox(t, w, &Tkhd{TrackID: 1}, ctx, nil)
+			writeTestBox(t, w, &Mdia{}, ctx, func() {
+				writeTestBox(t, w, &Mdhd{Timescale: 1000}, ctx, nil)
+				writeTestBox(t, w, &Minf{}, ctx, func() {
+					writeTestBox(t, w, &Stbl{}, ctx, func() {
+						writeTestBox(t, w, &Stsd{EntryCount: 1}, ctx, func() {
+							sampleEntry(w)
+						})
+						writeTestBox(t, w, &Stts{}, ctx, nil)
+						writeTestBox(t, w, &Stsc{}, ctx, nil)
+						writeTestBox(t, w, &Stsz{}, ctx, nil)
+						writeTestBox(t, w, &Stco{}, ctx, nil)
+					})
+				})
+			})
+		})
+	})
+	data, err := io.ReadAll(buf.BytesReader())
+	require.NoError(t, err)
+	return bytes.NewReader(data)
+}
+
+func TestProbeCroppedSize(t *testing.T) {
+	t.Run("avc1", func(t *testing.T) {
+		sps := []byte{
+			0x67, 0x64, 0x00, 0x0c, 0xac, 0xd9, 0x41, 0x41, 0x9f, 0x9f, 0x01, 0x6c, 0x80, 0x00, 0x00, 0x03,
+			0x00, 0x80, 0x00, 0x00, 0x0a, 0x07, 0x8a, 0x14, 0xcb,
+		}
+		r := buildTestProbeFile(t, func(w *Writer) {
+			writeTestBox(t, w, &VisualSampleEntry{
+				SampleEntry: SampleEntry{AnyTypeBox: AnyTypeBox{Type: BoxTypeAvc1()}, DataReferenceIndex: 1},
+				Width:       320,
+				Height:      192,
+			}, Context{}, func() {
+				writeTestBox(t, w, &AVCDecoderConfiguration{
+					AnyTypeBox:                 AnyTypeBox{Type: BoxTypeAvcC()},
+					ConfigurationVersion:       1,
+					Profile:                    66,
+					Level:                      12,
+					Reserved:                   63,
+					LengthSizeMinusOne:         3,
+					Reserved2:                  7,
+					NumOfSequenceParameterSets: 1,
+					SequenceParameterSets:      []AVCParameterSet{{Length: uint16(len(sps)), NALUnit: sps}},
+				}, Context{}, nil)
+			})
+		})
+		info, err := Probe(r)
+		require.NoError(t, err)
+		require.Len(t, info.Tracks, 1)
+		require.NotNil(t, info.Tracks[0].AVC)
+		require.NotNil(t, info.Tracks[0].AVC.SPS)
+		assert.Equal(t, uint16(320), info.Tracks[0].AVC.Width)
+		assert.Equal(t, uint16(180), info.Tracks[0].AVC.Height)
+	})
+
+	t.Run("hvc1", func(t *testing.T) {
+		sps := []byte{
+			0x42, 0x01, 0x01, 0x01, 0x60, 0x00, 0x00, 0x03, 0x00, 0x90, 0x00, 0x00, 0x03, 0x00, 0x00, 0x03,
+			0x00, 0x5d, 0xa0, 0x02, 0x80, 0x80, 0x2d, 0x16, 0x59, 0x59, 0xa4, 0x93, 0x2b, 0xc0, 0x5a, 0x70,
+			0x80, 0x00, 0x01, 0xf4, 0x80, 0x00, 0x3a, 0x98, 0x04,
+		}
+		testCases := []struct {
+			name   string
+			sps    []byte
+			height uint16
+		}{
+			{name: "sps", sps: sps, height: 720},
+			{name: "broken sps", sps: sps[:8], height: 736},
+		}
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				r := buildTestProbeFile(t, func(w *Writer) {
+					writeTestBox(t, w, &VisualSampleEntry{
+						SampleEntry: SampleEntry{AnyTypeBox: AnyTypeBox{Type: BoxTypeHvc1()}, DataReferenceIndex: 1},
+						Width:       1280,
+						Height:      736,
+					}, Context{}, func() {
+						writeTestBox(t, w, &HvcC{
+							ConfigurationVersion: 1,
+							GeneralProfileIdc:    1,
+							GeneralLevelIdc:      93,
+							Reserved1:            15,
+							Reserved2:            63,
+							Reserved3:            63,
+							ChromaFormatIdc:      1,
+							Reserved4:            31,
+							Reserved5:            31,
+							LengthSizeMinusOne:   3,
+							NumOfNaluArrays:      1,
+							NaluArrays: []HEVCNaluArray{{
+								NaluType: 33,
+								NumNalus: 1,
+								Nalus:    []HEVCNalu{{Length: uint16(len(tc.sps)), NALUnit: tc.sps}},
+							}},
+						}, Context{}, nil)
+					})
+				})
+				info, err := Probe(r)
+				require.NoError(t, err)
+				require.Len(t, info.Tracks, 1)
+				require.NotNil(t, info.Tracks[0].HEVC)
+				assert.Equal(t, uint16(1280), info.Tracks[0].HEVC.Width)
+				assert.Equal(t, tc.height, info.Tracks[0].HEVC.Height)
+			})
+		}
+	})
+}
+
+func TestProbeDolbyVision(t *testing.T) {
+	testCases := []struct {
+		name        string
+		sampleEntry BoxType
+		config      BoxType
+		codec       Codec
+		profile     uint8
+		level       uint8
+	}{
+		{name: "dvh1", sampleEntry: BoxTypeDvh1(), config: BoxTypeDvcC(), codec: CodecDolbyVision, profile: 5, level: 6},
+		{name: "dvav", sampleEntry: BoxTypeDvav(), config: BoxTypeDvcC(), codec: CodecDolbyVision, profile: 9, level: 4},
+		{name: "hvc1 with dvvC", sampleEntry: BoxTypeHvc1(), config: BoxTypeDvvC(), codec: CodecHEVC, profile: 8, level: 9},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := buildTestProbeFile(t, func(w *Writer) {
+				writeTestBox(t, w, &VisualSampleEntry{
+					SampleEntry: SampleEntry{AnyTypeBox: AnyTypeBox{Type: tc.sampleEntry}, DataReferenceIndex: 1},
+					Width:       1920,
+					Height:      1080,
+				}, Context{}, func() {
+					writeTestBox(t, w, &DOVIDecoderConfiguration{
+						AnyTypeBox:                AnyTypeBox{Type: tc.config},
+						DVVersionMajor:            1,
+						DVProfile:                 tc.profile,
+						DVLevel:                   tc.level,
+						RPUPresentFlag:            true,
+						BLPresentFlag:             true,
+						DVBLSignalCompatibilityID: 1,
+					}, Context{}, nil)
+				})
+			})
+			info, err := Probe(r)
+			require.NoError(t, err)
+			require.Len(t, info.Tracks, 1)
+			assert.Equal(t, tc.codec, info.Tracks[0].Codec)
+			require.NotNil(t, info.Tracks[0].DOVI)
+			assert.Equal(t, &DOVIDecConfigInfo{
+				VersionMajor:            1,
+				Profile:                 tc.profile,
+				Level:                   tc.level,
+				RPUPresent:              true,
+				BLPresent:               true,
+				BLSignalCompatibilityID: 1,
+				Width:                   1920,
+				Height:                  1080,
+			}, info.Tracks[0].DOVI)
+		})
+	}
+}
+
+func TestProbeVVC(t *testing.T) {
+	r := buildTestProbeFile(t, func(w *Writer) {
+		writeTestBox(t, w, &VisualSampleEntry{
+			SampleEntry: SampleEntry{AnyTypeBox: AnyTypeBox{Type: BoxTypeVvc1()}, DataReferenceIndex: 1},
+			Width:       1920,
+			Height:      1080,
+		}, Context{}, func() {
+			writeTestBox(t, w, &VvcC{
+				Reserved1:          31,
+				LengthSizeMinusOne: 3,
+				PtlPresentFlag:     true,
+				NumSublayers:       1,
+				ChromaFormatIdc:    1,
+				Reserved2:          31,
+				NativePTL: VVCPTLRecord{
+					NumBytesConstraintInfo: 1,
+					GeneralProfileIdc:      1,
+					GeneralTierFlag:        true,
+					GeneralLevelIdc:        83,
+					GeneralConstraintInfo:  []byte{0x00},
+				},
+				MaxPictureWidth:  1920,
+				MaxPictureHeight: 1080,
+			}, Context{}, nil)
+		})
+	})
+	info, err := Probe(r)
+	require.NoError(t, err)
+	require.Len(t, info.Tracks, 1)
+	assert.Equal(t, CodecVVC, info.Tracks[0].Codec)
+	assert.Equal(t, &VVCDecConfigInfo{
+		Profile:    1,
+		Tier:       true,
+		Level:      83,
+		LengthSize: 4,
+		Width:      1920,
+		Height:     1080,
+	}, info.Tracks[0].VVC)
+}
+
+func TestProbeAC4(t *testing.T) {
+	r := buildTestProbeFile(t, func(w *Writer) {
+		writeTestBox(t, w, &AudioSampleEntry{
+			SampleEntry:  SampleEntry{AnyTypeBox: AnyTypeBox{Type: BoxTypeAC4()}, DataReferenceIndex: 1},
+			ChannelCount: 2,
+			SampleSize:   16,
+			SampleRate:   48000 << 16,
+		}, Context{}, func() {
+			writeTestBox(t, w, &Dac4{
+				AC4DSIVersion:    1,
+				BitstreamVersion: 2,
+				NPresentations:   2,
+				Presentations: []AC4Presentation{
+					{PresentationVersion: 1, PresBytes: uint8(len(testAC4PresentationV1)), PresentationBytes: testAC4PresentationV1},
+					{PresentationVersion: 0, PresBytes: 1, PresentationBytes: []byte{0x00}},
+				},
+			}, Context{}, nil)
+		})
+	})
+	info, err := Probe(r)
+	require.NoError(t, err)
+	require.Len(t, info.Tracks, 1)
+	assert.Equal(t, CodecAC4, info.Tracks[0].Codec)
+	require.NotNil(t, info.Tracks[0].AC4)
+	assert.Equal(t, uint8(2), info.Tracks[0].AC4.BitstreamVersion)
+	assert.Equal(t, uint16(2), info.Tracks[0].AC4.ChannelCount)
+	assert.Equal(t, 2, info.Tracks[0].AC4.PresentationCount)
+	require.Len(t, info.Tracks[0].AC4.Presentations, 1)
+	assert.Equal(t, 6, info.Tracks[0].AC4.Presentations[0].ChannelCount())
+}
+
+func TestProbeMPEGH(t *testing.T) {
+	r := buildTestProbeFile(t, func(w *Writer) {
+		writeTestBox(t, w, &AudioSampleEntry{
+			SampleEntry:  SampleEntry{AnyTypeBox: AnyTypeBox{Type: BoxTypeMha1()}, DataReferenceIndex: 1},
+			ChannelCount: 0,
+			SampleSize:   16,
+			SampleRate:   48000 << 16,
+		}, Context{}, func() {
+			writeTestBox(t, w, &MhaC{
+				ConfigurationVersion:           1,
+				MPEGH3DAProfileLevelIndication: 0x0d,
+				ReferenceChannelLayout:         19,
+			}, Context{}, nil)
+		})
+	})
+	info, err := Probe(r)
+	require.NoError(t, err)
+	require.Len(t, info.Tracks, 1)
+	assert.Equal(t, CodecMPEGH, info.Tracks[0].Codec)
+	assert.Equal(t, &MPEGHInfo{
+		ProfileLevelIndication: 0x0d,
+		ReferenceChannelLayout: 19,
+		ChannelCount:           12,
+	}, info.Tracks[0].MPEGH)
+
+	r = buildTestProbeFile(t, func(w *Writer) {
+		writeTestBox(t, w, &AudioSampleEntry{
+			SampleEntry:  SampleEntry{AnyTypeBox: AnyTypeBox{Type: BoxTypeMhm1()}, DataReferenceIndex: 1},
+			ChannelCount: 6,
+		}, Context{}, nil)
+	})
+	info, err = Probe(r)
+	require.NoError(t, err)
+	assert.Equal(t, CodecMPEGH, info.Tracks[0].Codec)
+	assert.Equal(t, &MPEGHInfo{ChannelCount: 6}, info.Tracks[0].MPEGH)
+}
+
+func TestProbeCodecs(t *testing.T) {
+	hvcC := &HvcC{
+		ConfigurationVersion:        1,
+		GeneralProfileIdc:           2,
+		GeneralProfileCompatibility: [32]bool{false, false, true},
+		GeneralConstraintIndicator:  [6]uint8{0x90},
+		GeneralLevelIdc:             153,
+		Reserved1:                   15,
+		Reserved2:                   63,
+		Reserved3:                   63,
+		ChromaFormatIdc:             1,
+		Reserved4:                   31,
+		BitDepthLumaMinus8:          2,
+		Reserved5:                   31,
+		BitDepthChromaMinus8:        2,
+		LengthSizeMinusOne:          3,
+	}
+	hevcInfo := &HEVCDecConfigInfo{
+		Profile:              2,
+		ProfileCompatibility: 0x20000000,
+		ConstraintIndicator:  [6]uint8{0x90},
+		Level:                153,
+		ChromaFormat:         1,
+		BitDepthLuma:         10,
+		BitDepthChroma:       10,
+		LengthSize:           4,
+		Width:                3840,
+		Height:               2160,
+	}
+	dec3 := &Dec3{
+		DataRate: 640,
+		IndSub: []IndSub{
+			{Fscod: 0, Bsid: 16, Acmod: 7, LfeOn: 1, NumDepSub: 1, ChanLoc: 0x80},
+		},
+		FlagEC3ExtensionTypeA: 1,
+		ComplexityIndexTypeA:  16,
+	}
+	eac3Info := &EAC3Info{
+		DataRate:                  640,
+		IndependentSubstreamCount: 1,
+		SampleRate:                48000,
+		ChannelCount:              8,
+		JOC:                       true,
+		JOCComplexityIndex:        16,
+	}
+	visualSampleEntry := func(boxType BoxType) *VisualSampleEntry {
+		return &VisualSampleEntry{
+			SampleEntry: SampleEntry{AnyTypeBox: AnyTypeBox{Type: boxType}, DataReferenceIndex: 1},
+			Width:       3840,
+			Height:      2160,
+		}
+	}
+	audioSampleEntry := func(boxType BoxType) *AudioSampleEntry {
+		return &AudioSampleEntry{
+			SampleEntry:  SampleEntry{AnyTypeBox: AnyTypeBox{Type: boxType}, DataReferenceIndex: 1},
+			ChannelCount: 2,
+			SampleSize:   16,
+			SampleRate:   48000 << 16,
+		}
+	}
+
+	testCases := []struct {
+		name        string
+		sampleEntry func(t *testing.T, w *Writer)
+		codec       Codec
+		format      BoxType
+		encrypted   bool
+		check       func(t *testing.T, track *Track)
+	}{
+		{
+			name: "hvc1",
+			sampleEntry: func(t *testing.T, w *Writer) {
+				writeTestBox(t, w, visualSampleEntry(BoxTypeHvc1()), Context{}, func() {
+					writeTestBox(t, w, hvcC, Context{}, nil)
+				})
+			},
+			codec:  CodecHEVC,
+			format: BoxTypeHvc1(),
+			check: func(t *testing.T, track *Track) {
+				assert.Equal(t, hevcInfo, track.HEVC)
+			},
+		},
+		{
+			name: "av01",
+			sampleEntry: func(t *testing.T, w *Writer) {
+				writeTestBox(t, w, visualSampleEntry(BoxTypeAv01()), Context{}, func() {
+					writeTestBox(t, w, &Av1C{
+						Marker:             1,
+						Version:            1,
+						SeqLevelIdx0:       12,
+						HighBitdepth:       1,
+						ChromaSubsamplingX: 1,
+						ChromaSubsamplingY: 1,
+					}, Context{}, nil)
+				})
+			},
+			codec:  CodecAV1,
+			format: BoxTypeAv01(),
+			check: func(t *testing.T, track *Track) {
+				assert.Equal(t, &AV1DecConfigInfo{
+					Level:              12,
+					BitDepth:           10,
+					ChromaSubsamplingX: true,
+					ChromaSubsamplingY: true,
+					Width:              3840,
+					Height:             2160,
+				}, track.AV1)
+			},
+		},
+		{
+			name: "vp09",
+			sampleEntry: func(t *testing.T, w *Writer) {
+				writeTestBox(t, w, visualSampleEntry(BoxTypeVp09()), Context{}, func() {
+					writeTestBox(t, w, &VpcC{
+						FullBox:                 FullBox{Version: 1},
+						Profile:                 2,
+						Level:                   51,
+						BitDepth:                10,
+						ChromaSubsampling:       1,
+						ColourPrimaries:         9,
+						TransferCharacteristics: 16,
+						MatrixCoefficients:      9,
+					}, Context{}, nil)
+				})
+			},
+			codec:  CodecVP9,
+			format: BoxTypeVp09(),
+			check: func(t *testing.T, track *Track) {
+				assert.Equal(t, &VPDecConfigInfo{
+					Profile:                 2,
+					Level:                   51,
+					BitDepth:                10,
+					ChromaSubsampling:       1,
+					ColourPrimaries:         9,
+					TransferCharacteristics: 16,
+					MatrixCoefficients:      9,
+					Width:                   3840,
+					Height:                  2160,
+				}, track.VP)
+			},
+		},
+		{
+			name: "Opus",
+			sampleEntry: func(t *testing.T, w *Writer) {
+				writeTestBox(t, w, audioSampleEntry(BoxTypeOpus()), Context{}, func() {
+					writeTestBox(t, w, &DOps{
+						OutputChannelCount: 2,
+						PreSkip:            312,
+						InputSampleRate:    44100,
+						OutputGain:         -256,
+					}, Context{}, nil)
+				})
+			},
+			codec:  CodecOpus,
+			format: BoxTypeOpus(),
+			check: func(t *testing.T, track *Track) {
+				assert.Equal(t, &OpusInfo{
+					ChannelCount:    2,
+					PreSkip:         312,
+					InputSampleRate: 44100,
+					OutputGain:      -256,
+				}, track.Opus)
+			},
+		},
+		{
+			name: "ac-3",
+			sampleEntry: func(t *testing.T, w *Writer) {
+				writeTestBox(t, w, audioSampleEntry(BoxTypeAC3()), Context{}, func() {
+					writeTestBox(t, w, &Dac3{Fscod: 1, Bsid: 8, Acmod: 7, LfeOn: 1, BitRateCode: 15}, Context{}, nil)
+				})
+			},
+			codec:  CodecAC3,
+			format: BoxTypeAC3(),
+			check: func(t *testing.T, track *Track) {
+				assert.Equal(t, &AC3Info{
+					SampleRate:      44100,
+					BitstreamID:     8,
+					AudioCodingMode: 7,
+					LFE:             true,
+					BitRate:         448,
+					ChannelCount:    6,
+				}, track.AC3)
+			},
+		},
+		{
+			name: "ec-3",
+			sampleEntry: func(t *testing.T, w *Writer) {
+				writeTestBox(t, w, audioSampleEntry(BoxTypeEC3()), Context{}, func() {
+					writeTestBox(t, w, dec3, Context{}, nil)
+				})
+			},
+			codec:  CodecEAC3,
+			format: BoxTypeEC3(),
+			check: func(t *testing.T, track *Track) {
+				assert.Equal(t, eac3Info, track.EAC3)
+			},
+		},
+		{
+			name: "alac",
+			sampleEntry: func(t *testing.T, w *Writer) {
+				writeTestBox(t, w, audioSampleEntry(BoxTypeAlac()), Context{UnderStsd: true}, func() {
+					writeTestBox(t, w, &Alac{
+						FrameLength: 4096,
+						BitDepth:    24,
+						NumChannels: 2,
+						AvgBitRate:  2000000,
+						SampleRate:  96000,
+					}, Context{}, nil)
+				})
+			},
+			codec:  CodecALAC,
+			format: BoxTypeAlac(),
+			check: func(t *testing.T, track *Track) {
+				assert.Equal(t, &ALACInfo{
+					FrameLength:  4096,
+					BitDepth:     24,
+					ChannelCount: 2,
+					SampleRate:   96000,
+					AvgBitRate:   2000000,
+				}, track.ALAC)
+			},
+		},
+		{
+			name: "ipcm",
+			sampleEntry: func(t *testing.T, w *Writer) {
+				writeTestBox(t, w, audioSampleEntry(BoxTypeIpcm()), Context{}, func() {
+					writeTestBox(t, w, &PcmC{FormatFlags: 1, PCMSampleSize: 24}, Context{}, nil)
+				})
+			},
+			codec:  CodecPCM,
+			format: BoxTypeIpcm(),
+			check: func(t *testing.T, track *Track) {
+				assert.Equal(t, &PCMInfo{
+					LittleEndian: true,
+					SampleSize:   24,
+					ChannelCount: 2,
+					SampleRate:   48000,
+				}, track.PCM)
+			},
+		},
+		{
+			name: "fpcm",
+			sampleEntry: func(t *testing.T, w *Writer) {
+				writeTestBox(t, w, audioSampleEntry(BoxTypeFpcm()), Context{}, nil)
+			},
+			codec:  CodecPCM,
+			format: BoxTypeFpcm(),
+			check: func(t *testing.T, track *Track) {
+				assert.Equal(t, &PCMInfo{
+					Float:        true,
+					SampleSize:   16,
+					ChannelCount: 2,
+					SampleRate:   48000,
+				}, track.PCM)
+			},
+		},
+		{
+			name: "fLaC",
+			sampleEntry: func(t *testing.T, w *Writer) {
+				writeTestBox(t, w, audioSampleEntry(BoxTypeFLaC()), Context{}, func() {
+					writeTestBox(t, w, &DfLa{
+						MetadataBlocks: []FLACMetadataBlock{{
+							LastMetadataBlockFlag: true,
+							BlockType:             FLACMetadataBlockTypeStreamInfo,
+							Length:                34,
+							MinimumBlockSize:      4096,
+							MaximumBlockSize:      4096,
+							SampleRate:            44100,
+							ChannelsMinusOne:      1,
+							BitsPerSampleMinus1:   15,
+							TotalSamples:          441000,
+						}},
+					}, Context{}, nil)
+				})
+			},
+			codec:  CodecFLAC,
+			format: BoxTypeFLaC(),
+			check: func(t *testing.T, track *Track) {
+				assert.Equal(t, &FLACInfo{
+					MinimumBlockSize: 4096,
+					MaximumBlockSize: 4096,
+					SampleRate:       44100,
+					ChannelCount:     2,
+					BitsPerSample:    16,
+					TotalSamples:     441000,
+				}, track.FLAC)
+			},
+		},
+		{
+			name: "wvtt",
+			sampleEntry: func(t *testing.T, w *Writer) {
+				writeTestBox(t, w, &WVTTSampleEntry{
+					SampleEntry: SampleEntry{AnyTypeBox: AnyTypeBox{Type: BoxTypeWvtt()}, DataReferenceIndex: 1},
+				}, Context{}, nil)
+			},
+			codec:  CodecWebVTT,
+			format: BoxTypeWvtt(),
+		},
+		{
+			name: "stpp",
+			sampleEntry: func(t *testing.T, w *Writer) {
+				writeTestBox(t, w, &XMLSubtitleSampleEntry{
+					SampleEntry: SampleEntry{AnyTypeBox: AnyTypeBox{Type: BoxTypeStpp()}, DataReferenceIndex: 1},
+					Namespace:   "http://www.w3.org/ns/ttml",
+				}, Context{}, nil)
+			},
+			codec:  CodecTTML,
+			format: BoxTypeStpp(),
+		},
+		{
+			name: "encv with frma",
+			sampleEntry: func(t *testing.T, w *Writer) {
+				writeTestBox(t, w, visualSampleEntry(BoxTypeEncv()), Context{}, func() {
+					writeTestBox(t, w, hvcC, Context{}, nil)
+					writeTestBox(t, w, &Sinf{}, Context{}, func() {
+						writeTestBox(t, w, &Frma{DataFormat: [4]byte{'h', 'v', 'c', '1'}}, Context{}, nil)
+						writeTestBox(t, w, &Schm{SchemeType: [4]byte{'c', 'b', 'c', 's'}, SchemeVersion: 0x00010000}, Context{}, nil)
+					})
+				})
+			},
+			codec:     CodecHEVC,
+			format:    BoxTypeHvc1(),
+			encrypted: true,
+			check: func(t *testing.T, track *Track) {
+				assert.Equal(t, hevcInfo, track.HEVC)
+			},
+		},
+		{
+			name: "enca with frma",
+			sampleEntry: func(t *testing.T, w *Writer) {
+				writeTestBox(t, w, audioSampleEntry(BoxTypeEnca()), Context{}, func() {
+					writeTestBox(t, w, dec3, Context{}, nil)
+					writeTestBox(t, w, &Sinf{}, Context{}, func() {
+						writeTestBox(t, w, &Frma{DataFormat: [4]byte{'e', 'c', '-', '3'}}, Context{}, nil)
+					})
+				})
+			},
+			codec:     CodecEAC3,
+			format:    BoxTypeEC3(),
+			encrypted: true,
+			check: func(t *testing.T, track *Track) {
+				assert.Equal(t, eac3Info, track.EAC3)
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := buildTestProbeFile(t, func(w *Writer) {
+				tc.sampleEntry(t, w)
+			})
+			info, err := Probe(r)
+			require.NoError(t, err)
+			require.Len(t, info.Tracks, 1)
+			assert.Equal(t, tc.codec, info.Tracks[0].Codec)
+			assert.Equal(t, tc.format, info.Tracks[0].Format)
+			assert.Equal(t, tc.encrypted, info.Tracks[0].Encrypted)
+			if tc.check != nil {
+				tc.check(t, info.Tracks[0])
+			}
+		})
+	}
+}
+
+func TestProbeHDR(t *testing.T) {
+	r := buildTestProbeFile(t, func(w *Writer) {
+		writeTestBox(t, w, &VisualSampleEntry{
+			SampleEntry: SampleEntry{AnyTypeBox: AnyTypeBox{Type: BoxTypeHvc1()}, DataReferenceIndex: 1},
+			Width:       3840,
+			Height:      2160,
+		}, Context{}, func() {
+			writeTestBox(t, w, &Colr{ColourType: [4]byte{'n', 'c', 'l', 'x'}, ColourPrimaries: 9, TransferCharacteristics: 16, MatrixCoefficients: 9}, Context{}, nil)
+			writeTestBox(t, w, &Mdcv{MaxDisplayMasteringLuminance: 10000000, MinDisplayMasteringLuminance: 50}, Context{}, nil)
+			writeTestBox(t, w, &Clli{MaxContentLightLevel: 1000, MaxPicAverageLightLevel: 400}, Context{}, nil)
+		})
+	})
+	info, err := Probe(r)
+	require.NoError(t, err)
+	require.Len(t, info.Tracks, 1)
+	require.NotNil(t, info.Tracks[0].HDR)
+	assert.Equal(t, &HDRInfo{
+		ColourPrimaries:         9,
+		TransferCharacteristics: 16,
+		MatrixCoefficients:      9,
+		HasContentLightLevel:    true,
+		MaxCLL:                  1000,
+		MaxFALL:                 400,
+		HasMasteringDisplay:     true,
+		MaxMasteringLuminance:   10000000,
+		MinMasteringLuminance:   50,
+	}, info.Tracks[0].HDR)
+	assert.True(t, info.Tracks[0].HDR.IsHDR10())
+	assert.False(t, info.Tracks[0].HDR.IsHLG())
+
+	r = buildTestProbeFile(t, func(w *Writer) {
+		writeTestBox(t, w, &VisualSampleEntry{
+			SampleEntry: SampleEntry{AnyTypeBox: AnyTypeBox{Type: BoxTypeAvc1()}, DataReferenceIndex: 1},
+		}, Context{}, func() {
+			writeTestBox(t, w, &Colr{ColourType: [4]byte{'n', 'c', 'l', 'c'}, ColourPrimaries: 9, TransferCharacteristics: 18, MatrixCoefficients: 9}, Context{}, nil)
+		})
+	})
+	info, err = Probe(r)
+	require.NoError(t, err)
+	require.NotNil(t, info.Tracks[0].HDR)
+	assert.False(t, info.Tracks[0].HDR.IsHDR10())
+	assert.True(t, info.Tracks[0].HDR.IsHLG())
+
+	f, err := os.Open("./testdata/sample.mp4")
+	require.NoError(t, err)
+	defer f.Close()
+	info, err = Probe(f)
+	require.NoError(t, err)
+	assert.Nil(t, info.Tracks[0].HDR)
+}
+
 func TestProbeWithFMP4(t *testing.T) {
 	f, err := os.Open("./testdata/sample_fragmented.mp4")
 	require.NoError(t, err)
@@ -387,3 +1098,56 @@ func TestSegmentsGetMaxBitrate(t *testing.T) {
 			{TrackID: 2, Duration: 10, Size: 200},
 		}.GetMaxBitrate(2, 100))
 }
+
+func TestProbeTrackReferences(t *testing.T) {
+	buf := &writerseeker.WriterSeeker{}
+	w := NewWriter(buf)
+	ctx := Context{}
+	writeTestBox(t, w, &Ftyp{MajorBrand: [4]byte{'i', 's', 'o', 'm'}}, ctx, nil)
+	writeTestBox(t, w, &Moov{}, ctx, func() {
+		writeTestBox(t, w, &Mvhd{Timescale: 1000, NextTrackID: 3}, ctx, nil)
+		writeTestBox(t, w, &Trak{}, ctx, func() {
+			writeTestBox(t, w, &Tkhd{TrackID: 2}, ctx, nil)
+			writeTestBox(t, w, &Tref{}, ctx, func() {
+				writeTestBox(t, w, &TrackReferenceType{AnyTypeBox: AnyTypeBox{Type: BoxTypeCdsc()}, TrackIDs: []uint32{1}}, Context{UnderTref: true}, nil)
+				writeTestBox(t, w, &TrackReferenceType{AnyTypeBox: AnyTypeBox{Type: BoxTypeChap()}, TrackIDs: []uint32{1, 3}}, Context{UnderTref: true}, nil)
+				// unknown reference type
+				_, err := w.StartBox(&BoxInfo{Type: StrToBoxType("xxxx")})
+				require.NoError(t, err)
+				_, err = w.Write([]byte{0x00, 0x00, 0x00, 0x01})
+				require.NoError(t, err)
+				_, err = w.EndBox()
+				require.NoError(t, err)
+			})
+			writeTestBox(t, w, &Mdia{}, ctx, func() {
+				writeTestBox(t, w, &Mdhd{Timescale: 1000}, ctx, nil)
+				writeTestBox(t, w, &Elng{ExtendedLanguage: "en-US"}, ctx, nil)
+				writeTestBox(t, w, &Minf{}, ctx, func() {
+					writeTestBox(t, w, &Stbl{}, ctx, func() {
+						writeTestBox(t, w, &Stsd{}, ctx, nil)
+						writeTestBox(t, w, &Stts{}, ctx, nil)
+						writeTestBox(t, w, &Stsc{}, ctx, nil)
+						writeTestBox(t, w, &Stsz{}, ctx, nil)
+						writeTestBox(t, w, &Stco{}, ctx, nil)
+					})
+				})
+			})
+			writeTestBox(t, w, &Udta{}, ctx, func() {
+				writeTestBox(t, w, &Kind{SchemeURI: "urn:mpeg:dash:role:2011", Value: "commentary"}, ctx, nil)
+			})
+		})
+	})
+
+	info, err := Probe(buf.BytesReader())
+	require.NoError(t, err)
+	require.Len(t, info.Tracks, 1)
+	track := info.Tracks[0]
+	assert.Equal(t, []*TrackReference{
+		{ReferenceType: BoxTypeCdsc(), TrackIDs: []uint32{1}},
+		{ReferenceType: BoxTypeChap(), TrackIDs: []uint32{1, 3}},
+	}, track.References)
+	assert.Equal(t, []*TrackKind{
+		{SchemeURI: "urn:mpeg:dash:role:2011", Value: "commentary"},
+	}, track.Kinds)
+	assert.Equal(t, "en-US", track.ExtendedLanguage)
+}
@@ -0,0 +1,462 @@
+package mp4
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// RandomAccessType is the type of random access point detected from the sample data.
+type RandomAccessType int
+
+const (
+	// RandomAccessUnknown means that the sample data cannot be classified (e.g. unsupported codec or encrypted).
+	RandomAccessUnknown RandomAccessType = iota
+	// RandomAccessNone means that the sample is not a random access point.
+	RandomAccessNone
+	// RandomAccessIDR is an IDR picture of AVC or HEVC.
+	RandomAccessIDR
+	// RandomAccessCRA is a CRA picture of HEVC.
+	RandomAccessCRA
+	// RandomAccessBLA is a BLA picture of HEVC.
+	RandomAccessBLA
+	// RandomAccessKeyFrame is a shown key frame of AV1, or a key frame of VP8 and VP9.
+	RandomAccessKeyFrame
+)
+
+func (t RandomAccessType) String() string {
+	switch t {
+	case RandomAccessUnknown:
+		return "unknown"
+	case RandomAccessNone:
+		return "none"
+	case RandomAccessIDR:
+		return "IDR"
+	case RandomAccessCRA:
+		return "CRA"
+	case RandomAccessBLA:
+		return "BLA"
+	case RandomAccessKeyFrame:
+		return "key frame"
+	}
+	return fmt.Sprintf("RandomAccessType(%d)", int(t))
+}
+
+// IsRandomAccessPoint reports whether decoding can start from the sample.
+func (t RandomAccessType) IsRandomAccessPoint() bool {
+	return t >= RandomAccessIDR
+}
+
+// isClosed reports whether the random access point has no leading pictures which refer to the preceding samples.
+func (t RandomAccessType) isClosed() bool {
+	return t == RandomAccessIDR || t == RandomAccessKeyFrame
+}
+
+// RandomAccessSample is the random access information of a sample given by each source.
+type RandomAccessSample struct {
+	// Index is the 0-origin sample number in the track.
+	Index int
+	// Sync is whether the sample is a sync sample in the stss box, or in the sample flags of trun, tfhd or trex boxes.
+	// All samples described by the sample table are sync samples when the stss box is absent.
+	Sync bool
+	// DependsOn is sample_depends_on in the sdtp box or the sample flags.
+	// 0 means unknown, 1 means that the sample depends on others, and 2 means that it does not.
+	DependsOn uint8
+	// SyncGroup is whether the sample is mapped to the sync sample group.
+	SyncGroup bool
+	// SyncGroupNALUnitType is NAL_unit_type of the sync sample group entry.
+	SyncGroupNALUnitType uint8
+	// RAPGroup is whether the sample is mapped to the rap sample group.
+	RAPGroup bool
+	// Bitstream is the type of random access point detected from the sample data.
+	Bitstream RandomAccessType
+	// NALUnitType is nal_unit_type of the first VCL NAL unit, which is valid only for AVC and HEVC.
+	NALUnitType uint8
+}
+
+// RandomAccessMismatch is an inconsistency between the random access information of a sample.
+type RandomAccessMismatch struct {
+	SampleIndex int
+	Message     string
+}
+
+func (m *RandomAccessMismatch) String() string {
+	return fmt.Sprintf("sample %d: %s", m.SampleIndex, m.Message)
+}
+
+// RandomAccessReport is the result of AnalyzeRandomAccess.
+type RandomAccessReport struct {
+	TrackID    uint32
+	Samples    []*RandomAccessSample
+	Mismatches []*RandomAccessMismatch
+}
+
+// SyncSamples returns the indices of the sync samples.
+func (rep *RandomAccessReport) SyncSamples() []int {
+	idxs := make([]int, 0, 8)
+	for _, s := range rep.Samples {
+		if s.Sync {
+			idxs = append(idxs, s.Index)
+		}
+	}
+	return idxs
+}
+
+// RandomAccessPoints returns the indices of the samples which are random access points in the bitstream.
+func (rep *RandomAccessReport) RandomAccessPoints() []int {
+	idxs := make([]int, 0, 8)
+	for _, s := range rep.Samples {
+		if s.Bitstream.IsRandomAccessPoint() {
+			idxs = append(idxs, s.Index)
+		}
+	}
+	return idxs
+}
+
+// AnalyzeRandomAccess collects the random access information of all samples of the track
+// from stss, sdtp, sync and rap sample groups, sample flags of movie fragments and the sample data,
+// and reports the samples whose information is inconsistent.
+// The bitstream is inspected for AVC, HEVC, AV1, VP8 and VP9.
+func AnalyzeRandomAccess(r io.ReadSeeker, track *Track) (*RandomAccessReport, error) {
+	sr, err := NewSampleReader(r, track.TrackID)
+	if err != nil {
+		return nil, err
+	}
+	groups, stblOffset, err := readRandomAccessSampleGroups(r, track.TrackID)
+	if err != nil {
+		return nil, err
+	}
+	detector := newRandomAccessDetector(track)
+
+	maxCount := len(sr.Samples())
+	stblGroups := groups[stblOffset]
+	syncIndices := make(map[uint64][]uint32)
+	rapIndices := make(map[uint64][]uint32)
+	rep := &RandomAccessReport{TrackID: track.TrackID}
+	for _, sample := range sr.Samples() {
+		s := &RandomAccessSample{
+			Index:     sample.Index,
+			Sync:      sample.IsSync,
+			DependsOn: uint8(sample.Flags & SampleFlagDependsOnMask >> 24),
+		}
+
+		container := stblOffset
+		index := sample.Index
+		if sample.fragment != nil {
+			container = sample.fragment.traf.Offset
+			index = sample.fragmentSampleIndex
+		}
+		if sg := groups[container]; sg != nil {
+			if _, ok := syncIndices[container]; !ok {
+				syncIndices[container] = sg.groupDescriptionIndices(sampleGroupTypeSync, maxCount)
+				rapIndices[container] = sg.groupDescriptionIndices(sampleGroupTypeRAP, maxCount)
+			}
+			if gdi := sampleGroupIndexAt(syncIndices[container], index); gdi != 0 {
+				s.SyncGroup = true
+				if entry, ok := sg.entry(stblGroups, sampleGroupTypeSync, gdi, sample.fragment != nil).(*SyncSampleEntry); ok {
+					s.SyncGroupNALUnitType = entry.NALUnitType
+				}
+			}
+			s.RAPGroup = sampleGroupIndexAt(rapIndices[container], index) != 0
+		}
+
+		if detector != nil && sample.Size != 0 {
+			data, err := sr.ReadSampleData(sample)
+			if err != nil {
+				return nil, err
+			}
+			s.Bitstream, s.NALUnitType = detector.detect(data)
+		}
+
+		rep.Samples = append(rep.Samples, s)
+		for _, msg := range checkRandomAccess(s, detector != nil && detector.nal()) {
+			rep.Mismatches = append(rep.Mismatches, &RandomAccessMismatch{SampleIndex: s.Index, Message: msg})
+		}
+	}
+	return rep, nil
+}
+
+// checkRandomAccess returns the descriptions of the inconsistencies of the random access information.
+func checkRandomAccess(s *RandomAccessSample, nal bool) []string {
+	var msgs []string
+	if s.Sync && s.DependsOn == 1 {
+		msgs = append(msgs, "sync sample depends on other samples")
+	}
+	if s.Bitstream == RandomAccessUnknown {
+		return msgs
+	}
+	bitstream := s.Bitstream.String()
+	if nal {
+		bitstream = fmt.Sprintf("%s (nal_unit_type=%d)", bitstream, s.NALUnitType)
+	}
+	if s.Bitstream.IsRandomAccessPoint() {
+		if !s.Sync && s.Bitstream.isClosed() {
+			msgs = append(msgs, fmt.Sprintf("%s is not a sync sample", bitstream))
+		}
+		if s.DependsOn == 1 {
+			msgs = append(msgs, fmt.Sprintf("%s depends on other samples", bitstream))
+		}
+		if s.SyncGroup && nal && s.SyncGroupNALUnitType != s.NALUnitType {
+			msgs = append(msgs, fmt.Sprintf("%s is mapped to sync sample group with NAL_unit_type=%d", bitstream, s.SyncGroupNALUnitType))
+		}
+		return msgs
+	}
+	if s.Sync {
+		msgs = append(msgs, fmt.Sprintf("sync sample is not a random access point: %s", bitstream))
+	}
+	if s.SyncGroup {
+		msgs = append(msgs, fmt.Sprintf("sample in sync sample group is not a random access point: %s", bitstream))
+	}
+	if s.RAPGroup {
+		msgs = append(msgs, fmt.Sprintf("sample in rap sample group is not a random access point: %s", bitstream))
+	}
+	return msgs
+}
+
+var (
+	sampleGroupTypeSync = [4]byte{'s', 'y', 'n', 'c'}
+	sampleGroupTypeRAP  = [4]byte{'r', 'a', 'p', ' '}
+)
+
+// sampleGroups holds the sbgp and sgpd boxes in a stbl or traf box.
+type sampleGroups struct {
+	sbgps []*Sbgp
+	sgpds []*Sgpd
+}
+
+// groupDescriptionIndices returns group_description_index of each sample for the grouping type.
+// The number of samples is limited to maxCount.
+func (sg *sampleGroups) groupDescriptionIndices(groupingType [4]byte, maxCount int) []uint32 {
+	for _, sbgp := range sg.sbgps {
+		if sbgp.GroupingType != binary.BigEndian.Uint32(groupingType[:]) {
+			continue
+		}
+		indices := make([]uint32, 0)
+		for _, entry := range sbgp.Entries {
+			for i := uint32(0); i < entry.SampleCount && len(indices) < maxCount; i++ {
+				indices = append(indices, entry.GroupDescriptionIndex)
+			}
+		}
+		return indices
+	}
+	return nil
+}
+
+// entry returns the sample group entry of the group description index.
+// In track fragments, the indices above 0x10000 refer to the sgpd box in the traf box,
+// and the others refer to the sgpd box in the stbl box.
+func (sg *sampleGroups) entry(stbl *sampleGroups, groupingType [4]byte, index uint32, fragment bool) ISampleGroupEntry {
+	if fragment {
+		if index > 0x10000 {
+			index -= 0x10000
+		} else {
+			sg = stbl
+		}
+	}
+	if sg == nil {
+		return nil
+	}
+	for _, sgpd := range sg.sgpds {
+		if sgpd.GroupingType == groupingType && index >= 1 && int(index) <= len(sgpd.Entries) {
+			return sgpd.Entries[index-1]
+		}
+	}
+	return nil
+}
+
+func sampleGroupIndexAt(indices []uint32, index int) uint32 {
+	if index < len(indices) {
+		return indices[index]
+	}
+	return 0
+}
+
+// readRandomAccessSampleGroups reads the sbgp and sgpd boxes of the track,
+// and returns them by the offsets of the stbl and traf boxes with the offset of the stbl box.
+func readRandomAccessSampleGroups(r io.ReadSeeker, trackID uint32) (map[uint64]*sampleGroups, uint64, error) {
+	stbl := BoxPath{BoxTypeMoov(), BoxTypeTrak(), BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl()}
+	traf := BoxPath{BoxTypeMoof(), BoxTypeTraf()}
+	bips, err := ExtractBoxesWithPayload(r, nil, []BoxPath{
+		stbl,
+		append(stbl, BoxTypeSbgp()),
+		append(stbl, BoxTypeSgpd()),
+		traf,
+		append(traf, BoxTypeSbgp()),
+		append(traf, BoxTypeSgpd()),
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	groups := make(map[uint64]*sampleGroups)
+	var stblOffset uint64
+	var container uint64
+	for _, bip := range bips {
+		switch bip.Info.Type {
+		case BoxTypeStbl():
+			container = bip.Info.Offset
+			if bip.Info.Context.TrackID == trackID {
+				stblOffset = container
+			}
+			continue
+		case BoxTypeTraf():
+			container = bip.Info.Offset
+			continue
+		}
+		if bip.Info.Context.TrackID != trackID {
+			continue
+		}
+		sg := groups[container]
+		if sg == nil {
+			sg = &sampleGroups{}
+			groups[container] = sg
+		}
+		switch box := bip.Payload.(type) {
+		case *Sbgp:
+			sg.sbgps = append(sg.sbgps, box)
+		case *Sgpd:
+			sg.sgpds = append(sg.sgpds, box)
+		}
+	}
+	return groups, stblOffset, nil
+}
+
+// randomAccessDetector detects random access points from the sample data.
+type randomAccessDetector struct {
+	codec      Codec
+	lengthSize int
+	av1Seq     *AV1SequenceHeader
+}
+
+// newRandomAccessDetector returns the detector for the codec of the track, or nil if the codec is not supported.
+func newRandomAccessDetector(track *Track) *randomAccessDetector {
+	switch {
+	case track.AVC != nil:
+		return &randomAccessDetector{codec: CodecAVC1, lengthSize: int(track.AVC.LengthSize)}
+	case track.HEVC != nil:
+		return &randomAccessDetector{codec: CodecHEVC, lengthSize: int(track.HEVC.LengthSize)}
+	case track.AV1 != nil:
+		return &randomAccessDetector{codec: CodecAV1, av1Seq: track.AV1.SequenceHeader}
+	case track.Codec == CodecVP8, track.Codec == CodecVP9:
+		return &randomAccessDetector{codec: track.Codec}
+	}
+	return nil
+}
+
+// nal reports whether the codec consists of NAL units.
+func (d *randomAccessDetector) nal() bool {
+	return d.codec == CodecAVC1 || d.codec == CodecHEVC
+}
+
+// detect classifies the sample data.
+// The sample which cannot be parsed is classified as RandomAccessUnknown.
+func (d *randomAccessDetector) detect(data []byte) (RandomAccessType, uint8) {
+	switch d.codec {
+	case CodecAVC1, CodecHEVC:
+		nalType, err := d.firstVCLNALUnitType(data)
+		if err != nil {
+			return RandomAccessUnknown, 0
+		}
+		if d.codec == CodecAVC1 {
+			if nalType == 5 {
+				return RandomAccessIDR, nalType
+			}
+			return RandomAccessNone, nalType
+		}
+		switch {
+		case nalType <= 15:
+			return RandomAccessNone, nalType
+		case nalType <= 18:
+			return RandomAccessBLA, nalType
+		case nalType <= 20:
+			return RandomAccessIDR, nalType
+		case nalType == 21:
+			return RandomAccessCRA, nalType
+		}
+		// reserved IRAP and non-IRAP types
+		return RandomAccessUnknown, nalType
+	case CodecAV1:
+		tu, err := ParseAV1TemporalUnit(data, d.av1Seq)
+		if err != nil {
+			return RandomAccessUnknown, 0
+		}
+		if tu.SequenceHeader != nil {
+			d.av1Seq = tu.SequenceHeader
+		}
+		if len(tu.FrameHeaders) == 0 {
+			return RandomAccessUnknown, 0
+		}
+		if tu.IsKeyFrame() {
+			return RandomAccessKeyFrame, 0
+		}
+		return RandomAccessNone, 0
+	case CodecVP8:
+		if len(data) < 3 {
+			return RandomAccessUnknown, 0
+		}
+		// key_frame of the frame tag is 0 for key frames
+		if data[0]&0x01 == 0 {
+			return RandomAccessKeyFrame, 0
+		}
+		return RandomAccessNone, 0
+	case CodecVP9:
+		return detectVP9KeyFrame(data)
+	}
+	return RandomAccessUnknown, 0
+}
+
+// firstVCLNALUnitType returns nal_unit_type of the first VCL NAL unit in the sample.
+func (d *randomAccessDetector) firstVCLNALUnitType(data []byte) (uint8, error) {
+	if d.lengthSize < 1 || d.lengthSize > 4 {
+		return 0, fmt.Errorf("invalid length size: %d", d.lengthSize)
+	}
+	for len(data) != 0 {
+		if len(data) < d.lengthSize+1 {
+			return 0, io.ErrUnexpectedEOF
+		}
+		var length int
+		for i := 0; i < d.lengthSize; i++ {
+			length = length<<8 | int(data[i])
+		}
+		data = data[d.lengthSize:]
+		if length == 0 || length > len(data) {
+			return 0, errors.New("invalid NAL unit length")
+		}
+		if d.codec == CodecAVC1 {
+			if nalType := data[0] & 0x1f; nalType >= 1 && nalType <= 5 {
+				return nalType, nil
+			}
+		} else {
+			if nalType := (data[0] >> 1) & 0x3f; nalType <= 31 {
+				return nalType, nil
+			}
+		}
+		data = data[length:]
+	}
+	return 0, errors.New("VCL NAL unit not found")
+}
+
+// detectVP9KeyFrame reads the leading fields of uncompressed_header() of the first frame.
+// The first frame of a superframe is at the beginning of the sample.
+func detectVP9KeyFrame(data []byte) (RandomAccessType, uint8) {
+	if len(data) == 0 {
+		return RandomAccessUnknown, 0
+	}
+	b := data[0]
+	if b>>6 != 2 { // frame_marker
+		return RandomAccessUnknown, 0
+	}
+	profile := (b>>5)&0x01 | (b>>3)&0x02 // profile_low_bit and profile_high_bit
+	shift := uint(3)
+	if profile == 3 {
+		shift-- // reserved_zero
+	}
+	if (b>>shift)&0x01 != 0 { // show_existing_frame
+		return RandomAccessNone, 0
+	}
+	if (b>>(shift-1))&0x01 == 0 { // frame_type
+		return RandomAccessKeyFrame, 0
+	}
+	return RandomAccessNone, 0
+}
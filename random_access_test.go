@@ -0,0 +1,358 @@
+package mp4
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/orcaman/writerseeker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testHEVCSample returns the sample which has a single NAL unit of the type.
+func testHEVCSample(nalType uint8) []byte {
+	return []byte{0x00, 0x00, 0x00, 0x03, nalType << 1, 0x01, 0xaf}
+}
+
+// buildTestRandomAccessFile builds the HEVC track which has 5 samples in the moov box and 2 samples in a movie fragment.
+//
+//	sample | bitstream | stss | sdtp | sample group
+//	0      | IDR_W_RADL| yes  | 2    | sync (NAL_unit_type=19)
+//	1      | TRAIL_R   |      | 1    |
+//	2      | TRAIL_R   | yes  | 1    |
+//	3      | CRA       |      | 2    | rap
+//	4      | IDR_N_LP  |      | 2    | sync (NAL_unit_type=21)
+//	5      | IDR_N_LP  | yes  | 2    | sync in traf (NAL_unit_type=20)
+//	6      | TRAIL_R   | yes  | 0    |
+func buildTestRandomAccessFile(t *testing.T) io.ReadSeeker {
+	samples := [][]byte{
+		testHEVCSample(19),
+		testHEVCSample(1),
+		testHEVCSample(1),
+		testHEVCSample(21),
+		testHEVCSample(20),
+		testHEVCSample(20),
+		testHEVCSample(1),
+	}
+	const sampleSize = 7
+	const mdatOffset = 16 // after the ftyp box
+	dataOffset := uint32(mdatOffset + 8)
+
+	buf := &writerseeker.WriterSeeker{}
+	w := NewWriter(buf)
+	ctx := Context{}
+	writeTestBox(t, w, &Ftyp{MajorBrand: [4]byte{'i', 's', 'o', 'm'}}, ctx, nil)
+	writeTestBox(t, w, &Mdat{Data: bytes.Join(samples, nil)}, ctx, nil)
+	writeTestBox(t, w, &Moov{}, ctx, func() {
+		writeTestBox(t, w, &Mvhd{Timescale: 1000, NextTrackID: 2}, ctx, nil)
+		writeTestBox(t, w, &Trak{}, ctx, func() {
+			writeTestBox(t, w, &Tkhd{TrackID: 1}, ctx, nil)
+			writeTestBox(t, w, &Mdia{}, ctx, func() {
+				writeTestBox(t, w, &Mdhd{Timescale: 1000}, ctx, nil)
+				writeTestBox(t, w, &Minf{}, ctx, func() {
+					writeTestBox(t, w, &Stbl{}, ctx, func() {
+						writeTestBox(t, w, &Stsd{EntryCount: 1}, ctx, func() {
+							writeTestBox(t, w, &VisualSampleEntry{
+								SampleEntry: SampleEntry{AnyTypeBox: AnyTypeBox{Type: BoxTypeHvc1()}, DataReferenceIndex: 1},
+								Width:       320,
+								Height:      240,
+							}, ctx, func() {
+								writeTestBox(t, w, &HvcC{
+									ConfigurationVersion: 1,
+									Reserved1:            15,
+									Reserved2:            63,
+									Reserved3:            63,
+									Reserved4:            31,
+									Reserved5:            31,
+									LengthSizeMinusOne:   3,
+								}, ctx, nil)
+							})
+						})
+						writeTestBox(t, w, &Stts{EntryCount: 1, Entries: []SttsEntry{{SampleCount: 5, SampleDelta: 100}}}, ctx, nil)
+						writeTestBox(t, w, &Stss{EntryCount: 2, SampleNumber: []uint32{1, 3}}, ctx, nil)
+						writeTestBox(t, w, &Stsc{EntryCount: 1, Entries: []StscEntry{{FirstChunk: 1, SamplesPerChunk: 5, SampleDescriptionIndex: 1}}}, ctx, nil)
+						writeTestBox(t, w, &Stsz{SampleSize: sampleSize, SampleCount: 5}, ctx, nil)
+						writeTestBox(t, w, &Stco{EntryCount: 1, ChunkOffset: []uint32{dataOffset}}, ctx, nil)
+						writeTestBox(t, w, &Sdtp{Samples: []SdtpSampleElem{
+							{SampleDependsOn: 2},
+							{SampleDependsOn: 1},
+							{SampleDependsOn: 1},
+							{SampleDependsOn: 2},
+							{SampleDependsOn: 2},
+						}}, ctx, nil)
+						writeTestBox(t, w, &Sbgp{
+							GroupingType: binary.BigEndian.Uint32([]byte("sync")),
+							EntryCount:   3,
+							Entries: []SbgpEntry{
+								{SampleCount: 1, GroupDescriptionIndex: 1},
+								{SampleCount: 3, GroupDescriptionIndex: 0},
+								{SampleCount: 1, GroupDescriptionIndex: 2},
+							},
+						}, ctx, nil)
+						writeTestBox(t, w, &Sgpd{
+							FullBox:       FullBox{Version: 1},
+							GroupingType:  [4]byte{'s', 'y', 'n', 'c'},
+							DefaultLength: 1,
+							EntryCount:    2,
+							Entries: []ISampleGroupEntry{
+								&SyncSampleEntry{NALUnitType: 19},
+								&SyncSampleEntry{NALUnitType: 21},
+							},
+						}, ctx, nil)
+						writeTestBox(t, w, &Sbgp{
+							GroupingType: binary.BigEndian.Uint32([]byte("rap ")),
+							EntryCount:   2,
+							Entries: []SbgpEntry{
+								{SampleCount: 3, GroupDescriptionIndex: 0},
+								{SampleCount: 1, GroupDescriptionIndex: 1},
+							},
+						}, ctx, nil)
+						writeTestBox(t, w, &Sgpd{
+							FullBox:                   FullBox{Version: 1},
+							GroupingType:              [4]byte{'r', 'a', 'p', ' '},
+							DefaultLength:             1,
+							EntryCount:                1,
+							VisualRandomAccessEntries: []VisualRandomAccessEntry{{}},
+						}, ctx, nil)
+					})
+				})
+			})
+		})
+		writeTestBox(t, w, &Mvex{}, ctx, func() {
+			writeTestBox(t, w, &Trex{TrackID: 1, DefaultSampleDescriptionIndex: 1}, ctx, nil)
+		})
+	})
+	writeTestBox(t, w, &Moof{}, ctx, func() {
+		writeTestBox(t, w, &Mfhd{SequenceNumber: 1}, ctx, nil)
+		writeTestBox(t, w, &Traf{}, ctx, func() {
+			writeTestBox(t, w, &Tfhd{
+				FullBox:               FullBox{Flags: [3]byte{0x00, 0x00, TfhdBaseDataOffsetPresent | TfhdDefaultSampleDurationPresent | TfhdDefaultSampleSizePresent}},
+				TrackID:               1,
+				BaseDataOffset:        uint64(dataOffset + 5*sampleSize),
+				DefaultSampleDuration: 100,
+				DefaultSampleSize:     sampleSize,
+			}, ctx, nil)
+			writeTestBox(t, w, &Trun{
+				FullBox:     FullBox{Flags: [3]byte{0x00, 0x04, 0x00}},
+				SampleCount: 2,
+				Entries: []TrunEntry{
+					{SampleFlags: SampleFlagDependsOnNone},
+					{},
+				},
+			}, ctx, nil)
+			writeTestBox(t, w, &Sbgp{
+				GroupingType: binary.BigEndian.Uint32([]byte("sync")),
+				EntryCount:   1,
+				Entries:      []SbgpEntry{{SampleCount: 1, GroupDescriptionIndex: 0x10001}},
+			}, ctx, nil)
+			writeTestBox(t, w, &Sgpd{
+				FullBox:       FullBox{Version: 1},
+				GroupingType:  [4]byte{'s', 'y', 'n', 'c'},
+				DefaultLength: 1,
+				EntryCount:    1,
+				Entries:       []ISampleGroupEntry{&SyncSampleEntry{NALUnitType: 20}},
+			}, ctx, nil)
+		})
+	})
+	data, err := io.ReadAll(buf.BytesReader())
+	require.NoError(t, err)
+	return bytes.NewReader(data)
+}
+
+func TestAnalyzeRandomAccess(t *testing.T) {
+	r := buildTestRandomAccessFile(t)
+	info, err := Probe(r)
+	require.NoError(t, err)
+	require.Len(t, info.Tracks, 1)
+	require.NotNil(t, info.Tracks[0].HEVC)
+
+	rep, err := AnalyzeRandomAccess(r, info.Tracks[0])
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1), rep.TrackID)
+	assert.Equal(t, []*RandomAccessSample{
+		{Index: 0, Sync: true, DependsOn: 2, SyncGroup: true, SyncGroupNALUnitType: 19, Bitstream: RandomAccessIDR, NALUnitType: 19},
+		{Index: 1, DependsOn: 1, Bitstream: RandomAccessNone, NALUnitType: 1},
+		{Index: 2, Sync: true, DependsOn: 1, Bitstream: RandomAccessNone, NALUnitType: 1},
+		{Index: 3, DependsOn: 2, RAPGroup: true, Bitstream: RandomAccessCRA, NALUnitType: 21},
+		{Index: 4, DependsOn: 2, SyncGroup: true, SyncGroupNALUnitType: 21, Bitstream: RandomAccessIDR, NALUnitType: 20},
+		{Index: 5, Sync: true, DependsOn: 2, SyncGroup: true, SyncGroupNALUnitType: 20, Bitstream: RandomAccessIDR, NALUnitType: 20},
+		{Index: 6, Sync: true, Bitstream: RandomAccessNone, NALUnitType: 1},
+	}, rep.Samples)
+	assert.Equal(t, []*RandomAccessMismatch{
+		{SampleIndex: 2, Message: "sync sample depends on other samples"},
+		{SampleIndex: 2, Message: "sync sample is not a random access point: none (nal_unit_type=1)"},
+		{SampleIndex: 4, Message: "IDR (nal_unit_type=20) is not a sync sample"},
+		{SampleIndex: 4, Message: "IDR (nal_unit_type=20) is mapped to sync sample group with NAL_unit_type=21"},
+		{SampleIndex: 6, Message: "sync sample is not a random access point: none (nal_unit_type=1)"},
+	}, rep.Mismatches)
+	assert.Equal(t, "sample 2: sync sample depends on other samples", rep.Mismatches[0].String())
+	assert.Equal(t, []int{0, 2, 5, 6}, rep.SyncSamples())
+	assert.Equal(t, []int{0, 3, 4, 5}, rep.RandomAccessPoints())
+
+	// the samples in the movie fragment are also inspected
+	idxs, err := FindIDRFrames(r, info.Tracks[0])
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 3, 4, 5}, idxs)
+}
+
+func TestAnalyzeRandomAccessSampleFiles(t *testing.T) {
+	testCases := []struct {
+		name        string
+		file        string
+		syncSamples []int
+	}{
+		{name: "progressive", file: "./testdata/sample.mp4", syncSamples: []int{0}},
+		{name: "fragmented", file: "./testdata/sample_fragmented.mp4", syncSamples: []int{0, 3, 5, 8}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := os.Open(tc.file)
+			require.NoError(t, err)
+			defer f.Close()
+
+			info, err := Probe(f)
+			require.NoError(t, err)
+
+			rep, err := AnalyzeRandomAccess(f, info.Tracks[0])
+			require.NoError(t, err)
+			assert.Len(t, rep.Samples, 10)
+			assert.Equal(t, tc.syncSamples, rep.SyncSamples())
+			assert.Equal(t, tc.syncSamples, rep.RandomAccessPoints())
+			assert.Empty(t, rep.Mismatches)
+
+			// audio samples are sync samples without bitstream inspection
+			rep, err = AnalyzeRandomAccess(f, info.Tracks[1])
+			require.NoError(t, err)
+			assert.Len(t, rep.Samples, 44)
+			assert.Empty(t, rep.Mismatches)
+			assert.Empty(t, rep.RandomAccessPoints())
+		})
+	}
+}
+
+func TestRandomAccessDetector(t *testing.T) {
+	testCases := []struct {
+		name      string
+		track     *Track
+		data      []byte
+		bitstream RandomAccessType
+		nalType   uint8
+	}{
+		{
+			name:      "AVC IDR after SEI",
+			track:     &Track{AVC: &AVCDecConfigInfo{LengthSize: 4}},
+			data:      []byte{0, 0, 0, 2, 0x06, 0x05, 0, 0, 0, 2, 0x65, 0x88},
+			bitstream: RandomAccessIDR,
+			nalType:   5,
+		},
+		{
+			name:      "AVC non-IDR",
+			track:     &Track{AVC: &AVCDecConfigInfo{LengthSize: 2}},
+			data:      []byte{0, 2, 0x41, 0x9a},
+			bitstream: RandomAccessNone,
+			nalType:   1,
+		},
+		{
+			name:      "AVC without VCL",
+			track:     &Track{AVC: &AVCDecConfigInfo{LengthSize: 4}},
+			data:      []byte{0, 0, 0, 2, 0x09, 0x10},
+			bitstream: RandomAccessUnknown,
+		},
+		{
+			name:      "AVC broken length",
+			track:     &Track{AVC: &AVCDecConfigInfo{LengthSize: 4}},
+			data:      []byte{0, 0, 0, 9, 0x65, 0x88},
+			bitstream: RandomAccessUnknown,
+		},
+		{
+			name:      "HEVC BLA after AUD",
+			track:     &Track{HEVC: &HEVCDecConfigInfo{LengthSize: 4}},
+			data:      []byte{0, 0, 0, 3, 0x46, 0x01, 0x50, 0, 0, 0, 2, 0x20, 0x01},
+			bitstream: RandomAccessBLA,
+			nalType:   16,
+		},
+		{
+			name:      "HEVC reserved IRAP",
+			track:     &Track{HEVC: &HEVCDecConfigInfo{LengthSize: 4}},
+			data:      testHEVCSample(22),
+			bitstream: RandomAccessUnknown,
+			nalType:   22,
+		},
+		{
+			name:      "VP8 key frame",
+			track:     &Track{Codec: CodecVP8},
+			data:      []byte{0x50, 0x42, 0x00, 0x9d, 0x01, 0x2a},
+			bitstream: RandomAccessKeyFrame,
+		},
+		{
+			name:      "VP8 inter frame",
+			track:     &Track{Codec: CodecVP8},
+			data:      []byte{0x51, 0x42, 0x00},
+			bitstream: RandomAccessNone,
+		},
+		{
+			name:      "VP9 profile 0 key frame",
+			track:     &Track{Codec: CodecVP9},
+			data:      []byte{0x82, 0x49, 0x83, 0x42},
+			bitstream: RandomAccessKeyFrame,
+		},
+		{
+			name:      "VP9 profile 0 inter frame",
+			track:     &Track{Codec: CodecVP9},
+			data:      []byte{0x86},
+			bitstream: RandomAccessNone,
+		},
+		{
+			name:      "VP9 profile 0 show existing frame",
+			track:     &Track{Codec: CodecVP9},
+			data:      []byte{0x88},
+			bitstream: RandomAccessNone,
+		},
+		{
+			name:      "VP9 profile 3 key frame",
+			track:     &Track{Codec: CodecVP9},
+			data:      []byte{0xb0},
+			bitstream: RandomAccessKeyFrame,
+		},
+		{
+			name:      "VP9 profile 3 inter frame",
+			track:     &Track{Codec: CodecVP9},
+			data:      []byte{0xb2},
+			bitstream: RandomAccessNone,
+		},
+		{
+			name:      "VP9 invalid frame marker",
+			track:     &Track{Codec: CodecVP9},
+			data:      []byte{0x42},
+			bitstream: RandomAccessUnknown,
+		},
+		{
+			name:      "AV1 key frame",
+			track:     &Track{AV1: &AV1DecConfigInfo{}},
+			data:      append(testAV1OBU(AV1OBUSequenceHeader, testAV1SequenceHeader), testAV1OBU(AV1OBUFrame, []byte{0x10})...),
+			bitstream: RandomAccessKeyFrame,
+		},
+		{
+			name:      "AV1 without sequence header",
+			track:     &Track{AV1: &AV1DecConfigInfo{}},
+			data:      testAV1OBU(AV1OBUFrame, []byte{0x10}),
+			bitstream: RandomAccessUnknown,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			d := newRandomAccessDetector(tc.track)
+			require.NotNil(t, d)
+			bitstream, nalType := d.detect(tc.data)
+			assert.Equal(t, tc.bitstream, bitstream)
+			assert.Equal(t, tc.nalType, nalType)
+		})
+	}
+
+	assert.Nil(t, newRandomAccessDetector(&Track{Codec: CodecMP4A}))
+	assert.Equal(t, "key frame", RandomAccessKeyFrame.String())
+	assert.Equal(t, "RandomAccessType(9)", RandomAccessType(9).String())
+}
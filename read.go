@@ -123,6 +123,8 @@ func readBoxStructureFromInternal(r io.ReadSeeker, bi *BoxInfo, path BoxPath, ha
 		}
 	} else if bi.Type == BoxTypeUdta() {
 		ctx.UnderUdta = true
+	} else if bi.Type == BoxTypeTref() {
+		ctx.UnderTref = true
 	} else if bi.Type == BoxTypeStsd() {
 		ctx.UnderStsd = true
 	} else if ctx.UnderStsd {
@@ -0,0 +1,455 @@
+package mp4
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// TrackSample is a media sample which is located by a sample table (stbl) or a track fragment (traf).
+type TrackSample struct {
+	// Index is the 0-origin sample number in the track.
+	Index int
+
+	// Offset is the absolute position of the sample data in the file.
+	Offset uint64
+
+	// Size is the size of the sample data in bytes.
+	Size uint32
+
+	// DTS is the decoding time in the media timescale.
+	DTS uint64
+
+	// PTS is the composition time in the media timescale.
+	PTS int64
+
+	// Duration is the sample duration in the media timescale.
+	Duration uint32
+
+	// IsSync represents whether the sample is a sync sample.
+	IsSync bool
+
+	// Flags is the sample flags defined at ISO/IEC 14496-12 8.8.3.1.
+	// For samples described by the sample table, it is synthesized from stss and sdtp boxes.
+	Flags uint32
+
+	// SampleDescriptionIndex is the 1-origin index of the sample entry in the stsd box.
+	SampleDescriptionIndex uint32
+
+	// Data is the sample payload which is set only by SampleReader.Next.
+	Data []byte
+
+	fragment            *trackFragment
+	fragmentSampleIndex int
+}
+
+// trackFragment holds the boxes which describe samples of a traf box.
+type trackFragment struct {
+	moof  *BoxInfo
+	traf  *BoxInfo
+	tfhd  *Tfhd
+	tfdt  *Tfdt
+	truns []*Trun
+}
+
+// SampleReader reads samples of a specific track in order of decoding time.
+// It supports both sample tables in the moov box and movie fragments.
+type SampleReader struct {
+	TrackID   uint32
+	Timescale uint32
+
+	r       io.ReadSeeker
+	samples []*TrackSample
+	next    int
+}
+
+// NewSampleReader builds the sample index of the specified track and returns a new SampleReader.
+func NewSampleReader(r io.ReadSeeker, trackID uint32) (*SampleReader, error) {
+	timescale, samples, err := readTrackSamples(r, trackID)
+	if err != nil {
+		return nil, err
+	}
+	return &SampleReader{
+		TrackID:   trackID,
+		Timescale: timescale,
+		r:         r,
+		samples:   samples,
+	}, nil
+}
+
+// Samples returns all samples of the track without payloads.
+func (sr *SampleReader) Samples() []*TrackSample {
+	return sr.samples
+}
+
+// Next returns the next sample with its payload.
+// It returns io.EOF when all samples have been read.
+func (sr *SampleReader) Next() (*TrackSample, error) {
+	if sr.next >= len(sr.samples) {
+		return nil, io.EOF
+	}
+	sample := *sr.samples[sr.next]
+	data, err := sr.ReadSampleData(&sample)
+	if err != nil {
+		return nil, err
+	}
+	sample.Data = data
+	sr.next++
+	return &sample, nil
+}
+
+// SeekSample moves the position of the reader to the sample of the specified index.
+func (sr *SampleReader) SeekSample(index int) error {
+	if index < 0 || index > len(sr.samples) {
+		return fmt.Errorf("sample index out of range: index=%d, samples=%d", index, len(sr.samples))
+	}
+	sr.next = index
+	return nil
+}
+
+// ReadSampleData reads the payload of the sample.
+func (sr *SampleReader) ReadSampleData(sample *TrackSample) ([]byte, error) {
+	if _, err := sr.r.Seek(int64(sample.Offset), io.SeekStart); err != nil {
+		return nil, err
+	}
+	data := make([]byte, sample.Size)
+	if _, err := io.ReadFull(sr.r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func readTrackSamples(r io.ReadSeeker, trackID uint32) (uint32, []*TrackSample, error) {
+	stbl := BoxPath{BoxTypeMoov(), BoxTypeTrak(), BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl()}
+	bips, err := ExtractBoxesWithPayload(r, nil, []BoxPath{
+		{BoxTypeMoov(), BoxTypeTrak(), BoxTypeTkhd()},
+		{BoxTypeMoov(), BoxTypeTrak(), BoxTypeMdia(), BoxTypeMdhd()},
+		append(stbl, BoxTypeStts()),
+		append(stbl, BoxTypeCtts()),
+		append(stbl, BoxTypeStss()),
+		append(stbl, BoxTypeStsc()),
+		append(stbl, BoxTypeStsz()),
+		append(stbl, BoxTypeStz2()),
+		append(stbl, BoxTypeStco()),
+		append(stbl, BoxTypeCo64()),
+		append(stbl, BoxTypeSdtp()),
+		{BoxTypeMoov(), BoxTypeMvex(), BoxTypeTrex()},
+		{BoxTypeMoof()},
+		{BoxTypeMoof(), BoxTypeTraf()},
+		{BoxTypeMoof(), BoxTypeTraf(), BoxTypeTfhd()},
+		{BoxTypeMoof(), BoxTypeTraf(), BoxTypeTfdt()},
+		{BoxTypeMoof(), BoxTypeTraf(), BoxTypeTrun()},
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var found bool
+	var mdhd *Mdhd
+	var st sampleTable
+	trexes := make(map[uint32]*Trex)
+	moofs := make([][]*trackFragment, 0)
+	var moof *BoxInfo
+	for _, bip := range bips {
+		if bip.Info.Type == BoxTypeMoof() {
+			moof = &bip.Info
+			moofs = append(moofs, make([]*trackFragment, 0, 2))
+			continue
+		} else if bip.Info.Type == BoxTypeTraf() {
+			moofs[len(moofs)-1] = append(moofs[len(moofs)-1], &trackFragment{moof: moof, traf: &bip.Info})
+			continue
+		}
+
+		switch box := bip.Payload.(type) {
+		case *Trex:
+			trexes[box.TrackID] = box
+			continue
+		case *Tfhd, *Tfdt, *Trun:
+			moof := moofs[len(moofs)-1]
+			tf := moof[len(moof)-1]
+			switch box := box.(type) {
+			case *Tfhd:
+				tf.tfhd = box
+			case *Tfdt:
+				tf.tfdt = box
+			case *Trun:
+				tf.truns = append(tf.truns, box)
+			}
+			continue
+		}
+
+		if bip.Info.Context.TrackID != trackID {
+			continue
+		}
+		switch box := bip.Payload.(type) {
+		case *Tkhd:
+			found = true
+		case *Mdhd:
+			mdhd = box
+		case *Stts:
+			st.stts = box
+		case *Ctts:
+			st.ctts = box
+		case *Stss:
+			st.stss = box
+		case *Stsc:
+			st.stsc = box
+		case *Stsz:
+			st.stsz = box
+		case *Stz2:
+			st.stz2 = box
+		case *Stco:
+			st.stco = box
+		case *Co64:
+			st.co64 = box
+		case *Sdtp:
+			st.sdtp = box
+		}
+	}
+	if !found {
+		return 0, nil, fmt.Errorf("track not found: trackID=%d", trackID)
+	}
+	if mdhd == nil {
+		return 0, nil, errors.New("mdhd box not found")
+	}
+
+	samples, err := st.samples()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var dts uint64
+	if len(samples) != 0 {
+		last := samples[len(samples)-1]
+		dts = last.DTS + uint64(last.Duration)
+	}
+	for _, moof := range moofs {
+		var prevEnd uint64
+		for ti, tf := range moof {
+			if tf.tfhd == nil {
+				return 0, nil, errors.New("tfhd box not found")
+			}
+			if tf.tfdt != nil && tf.tfhd.TrackID == trackID {
+				dts = tf.tfdt.GetBaseMediaDecodeTime()
+			}
+			fragSamples, end := tf.samples(trexes[tf.tfhd.TrackID], ti == 0, prevEnd, dts)
+			prevEnd = end
+			if tf.tfhd.TrackID != trackID {
+				continue
+			}
+			for _, s := range fragSamples {
+				s.Index = len(samples)
+				samples = append(samples, s)
+				dts = s.DTS + uint64(s.Duration)
+			}
+		}
+	}
+
+	return mdhd.Timescale, samples, nil
+}
+
+type sampleTable struct {
+	stts *Stts
+	ctts *Ctts
+	stss *Stss
+	stsc *Stsc
+	stsz *Stsz
+	stz2 *Stz2
+	stco *Stco
+	co64 *Co64
+	sdtp *Sdtp
+}
+
+func (st *sampleTable) samples() ([]*TrackSample, error) {
+	var sizes []uint32
+	var count, defaultSize uint32
+	if st.stsz != nil {
+		count = st.stsz.SampleCount
+		defaultSize = st.stsz.SampleSize
+		if st.stsz.SampleSize == 0 {
+			sizes = st.stsz.EntrySize
+		}
+	} else if st.stz2 != nil {
+		count = st.stz2.SampleCount
+		sizes = st.stz2.EntrySize
+	}
+	if count == 0 {
+		return make([]*TrackSample, 0), nil
+	}
+	if sizes != nil && uint32(len(sizes)) < count {
+		return nil, errors.New("the number of sample sizes is less than sample count")
+	}
+
+	var offsets []uint64
+	if st.stco != nil {
+		offsets = make([]uint64, 0, len(st.stco.ChunkOffset))
+		for _, offset := range st.stco.ChunkOffset {
+			offsets = append(offsets, uint64(offset))
+		}
+	} else if st.co64 != nil {
+		offsets = st.co64.ChunkOffset
+	} else {
+		return nil, errors.New("stco/co64 box not found")
+	}
+	if st.stts == nil {
+		return nil, errors.New("stts box not found")
+	}
+	if st.stsc == nil {
+		return nil, errors.New("stsc box not found")
+	}
+
+	samples := make([]*TrackSample, 0, count)
+	for ei, entry := range st.stsc.Entries {
+		if entry.FirstChunk == 0 {
+			return nil, errors.New("invalid first chunk in stsc box")
+		}
+		end := uint32(len(offsets))
+		if ei != len(st.stsc.Entries)-1 && st.stsc.Entries[ei+1].FirstChunk-1 < end {
+			end = st.stsc.Entries[ei+1].FirstChunk - 1
+		}
+		for ci := entry.FirstChunk - 1; ci < end; ci++ {
+			offset := offsets[ci]
+			for i := uint32(0); i < entry.SamplesPerChunk && uint32(len(samples)) < count; i++ {
+				size := defaultSize
+				if sizes != nil {
+					size = sizes[len(samples)]
+				}
+				samples = append(samples, &TrackSample{
+					Index:                  len(samples),
+					Offset:                 offset,
+					Size:                   size,
+					IsSync:                 st.stss == nil,
+					SampleDescriptionIndex: entry.SampleDescriptionIndex,
+				})
+				offset += uint64(size)
+			}
+		}
+	}
+	if uint32(len(samples)) != count {
+		return nil, fmt.Errorf("inconsistent sample count: stsz=%d, stsc=%d", count, len(samples))
+	}
+
+	var si int
+	var dts uint64
+	for _, entry := range st.stts.Entries {
+		for i := uint32(0); i < entry.SampleCount && si < len(samples); i++ {
+			samples[si].DTS = dts
+			samples[si].PTS = int64(dts)
+			samples[si].Duration = entry.SampleDelta
+			dts += uint64(entry.SampleDelta)
+			si++
+		}
+	}
+
+	if st.ctts != nil {
+		si = 0
+		for ei, entry := range st.ctts.Entries {
+			offset := st.ctts.GetSampleOffset(ei)
+			for i := uint32(0); i < entry.SampleCount && si < len(samples); i++ {
+				samples[si].PTS = int64(samples[si].DTS) + offset
+				si++
+			}
+		}
+	}
+
+	if st.stss != nil {
+		for _, number := range st.stss.SampleNumber {
+			if number >= 1 && int(number) <= len(samples) {
+				samples[number-1].IsSync = true
+			}
+		}
+	}
+
+	for si, sample := range samples {
+		if !sample.IsSync {
+			sample.Flags |= SampleFlagIsNonSyncSample
+		}
+		if st.sdtp != nil && si < len(st.sdtp.Samples) {
+			elem := st.sdtp.Samples[si]
+			sample.Flags |= uint32(elem.IsLeading)<<26 |
+				uint32(elem.SampleDependsOn)<<24 |
+				uint32(elem.SampleIsDependedOn)<<22 |
+				uint32(elem.SampleHasRedundancy)<<20
+		}
+	}
+
+	return samples, nil
+}
+
+// samples returns samples described by the traf box and the end offset of their data.
+func (tf *trackFragment) samples(trex *Trex, first bool, prevEnd uint64, dts uint64) ([]*TrackSample, uint64) {
+	tfhd := tf.tfhd
+	if trex == nil {
+		trex = &Trex{}
+	}
+
+	var base uint64
+	if tfhd.CheckFlag(TfhdBaseDataOffsetPresent) {
+		base = tfhd.BaseDataOffset
+	} else if tfhd.CheckFlag(TfhdDefaultBaseIsMoof) || first {
+		base = tf.moof.Offset
+	} else {
+		base = prevEnd
+	}
+
+	descIndex := trex.DefaultSampleDescriptionIndex
+	if tfhd.CheckFlag(TfhdSampleDescriptionIndexPresent) {
+		descIndex = tfhd.SampleDescriptionIndex
+	}
+	defaultDuration := trex.DefaultSampleDuration
+	if tfhd.CheckFlag(TfhdDefaultSampleDurationPresent) {
+		defaultDuration = tfhd.DefaultSampleDuration
+	}
+	defaultSize := trex.DefaultSampleSize
+	if tfhd.CheckFlag(TfhdDefaultSampleSizePresent) {
+		defaultSize = tfhd.DefaultSampleSize
+	}
+	defaultFlags := trex.DefaultSampleFlags
+	if tfhd.CheckFlag(TfhdDefaultSampleFlagsPresent) {
+		defaultFlags = tfhd.DefaultSampleFlags
+	}
+
+	samples := make([]*TrackSample, 0)
+	offset := base
+	for _, trun := range tf.truns {
+		if trun.CheckFlag(TrunDataOffsetPresent) {
+			offset = uint64(int64(base) + int64(trun.DataOffset))
+		}
+		for i := 0; i < int(trun.SampleCount); i++ {
+			var entry TrunEntry
+			if i < len(trun.Entries) {
+				entry = trun.Entries[i]
+			}
+			sample := &TrackSample{
+				Offset:                 offset,
+				Size:                   defaultSize,
+				DTS:                    dts,
+				PTS:                    int64(dts),
+				Duration:               defaultDuration,
+				Flags:                  defaultFlags,
+				SampleDescriptionIndex: descIndex,
+				fragment:               tf,
+				fragmentSampleIndex:    len(samples),
+			}
+			if trun.CheckFlag(TrunSampleDurationPresent) {
+				sample.Duration = entry.SampleDuration
+			}
+			if trun.CheckFlag(TrunSampleSizePresent) {
+				sample.Size = entry.SampleSize
+			}
+			if trun.CheckFlag(TrunSampleFlagsPresent) {
+				sample.Flags = entry.SampleFlags
+			} else if i == 0 && trun.CheckFlag(TrunFirstSampleFlagsPresent) {
+				sample.Flags = trun.FirstSampleFlags
+			}
+			if trun.CheckFlag(TrunSampleCompositionTimeOffsetPresent) && i < len(trun.Entries) {
+				sample.PTS += trun.GetSampleCompositionTimeOffset(i)
+			}
+			sample.IsSync = sample.Flags&SampleFlagIsNonSyncSample == 0
+			samples = append(samples, sample)
+			offset += uint64(sample.Size)
+			dts += uint64(sample.Duration)
+		}
+	}
+	return samples, offset
+}
@@ -0,0 +1,88 @@
+package mp4
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSampleReader(t *testing.T) {
+	f, err := os.Open("./testdata/sample.mp4")
+	require.NoError(t, err)
+	defer f.Close()
+
+	sr, err := NewSampleReader(f, 1)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(10240), sr.Timescale)
+
+	samples := sr.Samples()
+	require.Len(t, samples, 10)
+	assert.Equal(t, uint64(48), samples[0].Offset)
+	assert.Equal(t, uint32(3679), samples[0].Size)
+	assert.Equal(t, uint64(0), samples[0].DTS)
+	assert.Equal(t, int64(2048), samples[0].PTS)
+	assert.Equal(t, uint32(1024), samples[0].Duration)
+	assert.True(t, samples[0].IsSync)
+	assert.Equal(t, uint32(1), samples[0].SampleDescriptionIndex)
+	assert.Equal(t, uint64(3836), samples[2].Offset)
+	assert.Equal(t, uint64(2048), samples[2].DTS)
+	assert.Equal(t, int64(7168), samples[2].PTS)
+	assert.False(t, samples[2].IsSync)
+	assert.Equal(t, uint32(SampleFlagIsNonSyncSample), samples[2].Flags)
+
+	sample, err := sr.Next()
+	require.NoError(t, err)
+	require.Len(t, sample.Data, 3679)
+	assert.Equal(t, []byte{0x00, 0x00, 0x02, 0xbc, 0x06, 0x05}, sample.Data[:6])
+
+	require.NoError(t, sr.SeekSample(9))
+	sample, err = sr.Next()
+	require.NoError(t, err)
+	assert.Equal(t, 9, sample.Index)
+	assert.Len(t, sample.Data, 15)
+	_, err = sr.Next()
+	assert.Equal(t, io.EOF, err)
+
+	_, err = NewSampleReader(f, 3)
+	assert.Error(t, err)
+}
+
+func TestSampleReaderFragmented(t *testing.T) {
+	f, err := os.Open("./testdata/sample_fragmented.mp4")
+	require.NoError(t, err)
+	defer f.Close()
+
+	sr, err := NewSampleReader(f, 1)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(90000), sr.Timescale)
+
+	samples := sr.Samples()
+	require.Len(t, samples, 10)
+	assert.Equal(t, uint64(1363), samples[0].Offset)
+	assert.Equal(t, uint32(974), samples[0].Size)
+	assert.Equal(t, int64(18000), samples[0].PTS)
+	assert.True(t, samples[0].IsSync)
+	assert.Equal(t, uint64(2337), samples[1].Offset)
+	assert.Equal(t, uint64(9000), samples[1].DTS)
+	assert.False(t, samples[1].IsSync)
+	assert.Equal(t, uint64(2870), samples[3].Offset)
+	assert.Equal(t, uint64(27000), samples[3].DTS)
+	assert.True(t, samples[3].IsSync)
+
+	sample, err := sr.Next()
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x00, 0x00, 0x00, 0x02, 0x09, 0xf0}, sample.Data[:6])
+
+	sr, err = NewSampleReader(f, 2)
+	require.NoError(t, err)
+	samples = sr.Samples()
+	require.Len(t, samples, 44)
+	assert.Equal(t, uint64(2565), samples[0].Offset)
+	assert.Equal(t, uint32(8830), samples[0].Duration)
+	assert.Equal(t, uint64(8830), samples[1].DTS)
+	assert.Equal(t, uint64(5644), samples[43].Offset)
+	assert.Equal(t, uint64(52319), samples[43].DTS)
+}
@@ -0,0 +1,456 @@
+package mp4
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+// ceilLog2 returns Ceil(Log2(n)) for n >= 1.
+func ceilLog2(n uint64) uint {
+	if n <= 1 {
+		return 0
+	}
+	return uint(bits.Len64(n - 1))
+}
+
+// nalUnitOffset returns the offset in the NAL unit payload
+// which corresponds to the offset in the RBSP converted by removeEmulationPrevention.
+func nalUnitOffset(payload []byte, rbspOffset int) int {
+	var n, zeros int
+	for i, b := range payload {
+		if n == rbspOffset {
+			return i
+		}
+		if zeros >= 2 && b == 0x03 {
+			zeros = 0
+			continue
+		}
+		if b == 0x00 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+		n++
+	}
+	return len(payload)
+}
+
+// avcSliceHeaderSize returns the size of the NAL unit header and the slice header of the H.264 VCL NAL unit,
+// that is, the offset of the first byte which contains slice_data().
+func avcSliceHeaderSize(nalu []byte, spss map[uint32]*AVCSPS, ppss map[uint32]*AVCPPS) (int, error) {
+	if len(nalu) < 2 {
+		return 0, errors.New("invalid slice NAL unit")
+	}
+	nalRefIdc := nalu[0] >> 5 & 0x3
+	idr := nalu[0]&0x1f == 5
+	r := newRBSPReader(nalu[1:])
+
+	r.ue() // first_mb_in_slice
+	sliceType := r.ue() % 5
+	ppsID := uint32(r.ue())
+	if r.err != nil {
+		return 0, r.err
+	}
+	pps := ppss[ppsID]
+	if pps == nil {
+		return 0, fmt.Errorf("PPS not found: id=%d", ppsID)
+	}
+	sps := spss[pps.SeqParameterSetID]
+	if sps == nil {
+		return 0, fmt.Errorf("SPS not found: id=%d", pps.SeqParameterSetID)
+	}
+	p, b := sliceType == 0 || sliceType == 3, sliceType == 1
+
+	if sps.SeparateColourPlane {
+		r.u(2) // colour_plane_id
+	}
+	r.u(uint(sps.Log2MaxFrameNumMinus4) + 4) // frame_num
+	var fieldPic bool
+	if !sps.FrameMbsOnly {
+		fieldPic = r.flag()
+		if fieldPic {
+			r.flag() // bottom_field_flag
+		}
+	}
+	if idr {
+		r.ue() // idr_pic_id
+	}
+	switch {
+	case sps.PicOrderCntType == 0:
+		r.u(uint(sps.Log2MaxPicOrderCntLsbMinus4) + 4) // pic_order_cnt_lsb
+		if pps.BottomFieldPicOrderInFramePresent && !fieldPic {
+			r.se() // delta_pic_order_cnt_bottom
+		}
+	case sps.PicOrderCntType == 1 && !sps.DeltaPicOrderAlwaysZero:
+		r.se() // delta_pic_order_cnt[0]
+		if pps.BottomFieldPicOrderInFramePresent && !fieldPic {
+			r.se() // delta_pic_order_cnt[1]
+		}
+	}
+	if pps.RedundantPicCntPresent {
+		r.ue() // redundant_pic_cnt
+	}
+	if b {
+		r.flag() // direct_spatial_mv_pred_flag
+	}
+	numRefIdxL0, numRefIdxL1 := uint64(pps.NumRefIdxL0DefaultActiveMinus1), uint64(pps.NumRefIdxL1DefaultActiveMinus1)
+	if p || b {
+		if r.flag() { // num_ref_idx_active_override_flag
+			numRefIdxL0 = r.ue()
+			if b {
+				numRefIdxL1 = r.ue()
+			}
+		}
+	}
+	if numRefIdxL0 > 31 || numRefIdxL1 > 31 {
+		return 0, errors.New("invalid number of reference indices")
+	}
+
+	// ref_pic_list_modification()
+	for list := 0; list < 2 && (p || b); list++ {
+		if list == 1 && !b {
+			break
+		}
+		if !r.flag() { // ref_pic_list_modification_flag_l0/l1
+			continue
+		}
+		for r.err == nil {
+			idc := r.ue() // modification_of_pic_nums_idc
+			if idc == 3 {
+				break
+			} else if idc > 5 {
+				return 0, fmt.Errorf("invalid modification_of_pic_nums_idc: %d", idc)
+			}
+			r.ue() // abs_diff_pic_num_minus1, long_term_pic_num or abs_diff_view_idx_minus1
+		}
+	}
+
+	if pps.WeightedPred && p || pps.WeightedBipredIdc == 1 && b {
+		chroma := !sps.SeparateColourPlane && sps.ChromaFormatIdc != 0
+		r.ue() // luma_log2_weight_denom
+		if chroma {
+			r.ue() // chroma_log2_weight_denom
+		}
+		for list, n := range []uint64{numRefIdxL0, numRefIdxL1} {
+			if list == 1 && !b {
+				break
+			}
+			for i := uint64(0); i <= n && r.err == nil; i++ {
+				if r.flag() { // luma_weight_l0_flag/l1
+					r.se() // luma_weight
+					r.se() // luma_offset
+				}
+				if chroma && r.flag() { // chroma_weight_l0_flag/l1
+					for j := 0; j < 4; j++ {
+						r.se() // chroma_weight, chroma_offset
+					}
+				}
+			}
+		}
+	}
+
+	if nalRefIdc != 0 {
+		// dec_ref_pic_marking()
+		if idr {
+			r.flag() // no_output_of_prior_pics_flag
+			r.flag() // long_term_reference_flag
+		} else if r.flag() { // adaptive_ref_pic_marking_mode_flag
+			for r.err == nil {
+				mmco := r.ue() // memory_management_control_operation
+				if mmco == 0 {
+					break
+				} else if mmco > 6 {
+					return 0, fmt.Errorf("invalid memory_management_control_operation: %d", mmco)
+				}
+				if mmco == 1 || mmco == 3 {
+					r.ue() // difference_of_pic_nums_minus1
+				}
+				if mmco == 2 {
+					r.ue() // long_term_pic_num
+				}
+				if mmco == 3 || mmco == 6 {
+					r.ue() // long_term_frame_idx
+				}
+				if mmco == 4 {
+					r.ue() // max_long_term_frame_idx_plus1
+				}
+			}
+		}
+	}
+	if pps.EntropyCodingMode && sliceType != 2 && sliceType != 4 {
+		r.ue() // cabac_init_idc
+	}
+	r.se() // slice_qp_delta
+	if sliceType == 3 || sliceType == 4 {
+		if sliceType == 3 {
+			r.flag() // sp_for_switch_flag
+		}
+		r.se() // slice_qs_delta
+	}
+	if pps.DeblockingFilterControlPresent {
+		if r.ue() != 1 { // disable_deblocking_filter_idc
+			r.se() // slice_alpha_c0_offset_div2
+			r.se() // slice_beta_offset_div2
+		}
+	}
+	if pps.NumSliceGroupsMinus1 > 0 && pps.SliceGroupMapType >= 3 && pps.SliceGroupMapType <= 5 {
+		picSize := uint64(sps.PicWidthInMbsMinus1+1) * uint64(sps.PicHeightInMapUnitsMinus1+1)
+		rate := uint64(pps.SliceGroupChangeRateMinus1) + 1
+		// Ceil(Log2(PicSizeInMapUnits ÷ SliceGroupChangeRate + 1)) without truncation of the division
+		var width uint
+		for rate<<width < picSize+rate {
+			width++
+		}
+		r.u(width) // slice_group_change_cycle
+	}
+	if r.err != nil {
+		return 0, fmt.Errorf("failed to parse slice header: %w", r.err)
+	}
+	return 1 + nalUnitOffset(nalu[1:], int((r.pos+7)/8)), nil
+}
+
+// hevcSliceHeaderSize returns the size of the NAL unit header and the slice segment header of the H.265 VCL NAL unit,
+// that is, the offset of the first byte of slice_segment_data().
+// The syntax elements added by the screen content coding extensions are not supported.
+func hevcSliceHeaderSize(nalu []byte, spss map[uint32]*HEVCSPS, ppss map[uint32]*HEVCPPS) (int, error) {
+	if len(nalu) < 3 {
+		return 0, errors.New("invalid slice NAL unit")
+	}
+	nalType := nalu[0] >> 1 & 0x3f
+	r := newRBSPReader(nalu[2:])
+
+	firstSliceSegment := r.flag()
+	if nalType >= 16 && nalType <= 23 {
+		r.flag() // no_output_of_prior_pics_flag
+	}
+	ppsID := uint32(r.ue())
+	if r.err != nil {
+		return 0, r.err
+	}
+	pps := ppss[ppsID]
+	if pps == nil {
+		return 0, fmt.Errorf("PPS not found: id=%d", ppsID)
+	}
+	sps := spss[pps.SeqParameterSetID]
+	if sps == nil {
+		return 0, fmt.Errorf("SPS not found: id=%d", pps.SeqParameterSetID)
+	}
+
+	var dependent bool
+	if !firstSliceSegment {
+		if pps.DependentSliceSegmentsEnabled {
+			dependent = r.flag()
+		}
+		ctbLog2Size := uint(sps.Log2MinLumaCodingBlockSizeMinus3 + 3 + sps.Log2DiffMaxMinLumaCodingBlockSize)
+		if ctbLog2Size > 6 {
+			return 0, fmt.Errorf("invalid CTB size: %d", 1<<ctbLog2Size)
+		}
+		ctbSize := uint64(1) << ctbLog2Size
+		widthInCtbs := (uint64(sps.PicWidthInLumaSamples) + ctbSize - 1) / ctbSize
+		heightInCtbs := (uint64(sps.PicHeightInLumaSamples) + ctbSize - 1) / ctbSize
+		r.u(ceilLog2(widthInCtbs * heightInCtbs)) // slice_segment_address
+	}
+
+	if !dependent {
+		r.u(uint(pps.NumExtraSliceHeaderBits)) // slice_reserved_flag
+		sliceType := r.ue()
+		if sliceType > 2 {
+			return 0, fmt.Errorf("invalid slice_type: %d", sliceType)
+		}
+		p, b := sliceType == 1, sliceType == 0
+		if pps.OutputFlagPresent {
+			r.flag() // pic_output_flag
+		}
+		if sps.SeparateColourPlane {
+			r.u(2) // colour_plane_id
+		}
+		var numPicTotalCurr int
+		var temporalMVP bool
+		if nalType != 19 && nalType != 20 { // IDR_W_RADL, IDR_N_LP
+			pocLsbSize := uint(sps.Log2MaxPicOrderCntLsbMinus4) + 4
+			r.u(pocLsbSize) // slice_pic_order_cnt_lsb
+			var rps HEVCShortTermRefPicSet
+			numSets := len(sps.ShortTermRefPicSets)
+			if !r.flag() { // short_term_ref_pic_set_sps_flag
+				rps = r.readHEVCShortTermRefPicSet(numSets, numSets, sps.ShortTermRefPicSets)
+			} else if numSets != 0 {
+				idx := r.u(ceilLog2(uint64(numSets))) // short_term_ref_pic_set_idx
+				if idx >= uint64(numSets) {
+					return 0, fmt.Errorf("invalid short_term_ref_pic_set_idx: %d", idx)
+				}
+				rps = sps.ShortTermRefPicSets[idx]
+			}
+			numPicTotalCurr = rps.NumUsedByCurrPic()
+			if sps.LongTermRefPicsPresent {
+				var numLongTermSPS uint64
+				if sps.NumLongTermRefPicsSPS != 0 {
+					numLongTermSPS = r.ue()
+				}
+				numLongTermPics := r.ue()
+				if numLongTermSPS > uint64(sps.NumLongTermRefPicsSPS) || numLongTermPics > 32 {
+					return 0, errors.New("invalid number of long-term reference pictures")
+				}
+				for i := uint64(0); i < numLongTermSPS+numLongTermPics && r.err == nil; i++ {
+					if i < numLongTermSPS {
+						var idx uint64
+						if sps.NumLongTermRefPicsSPS > 1 {
+							idx = r.u(ceilLog2(uint64(sps.NumLongTermRefPicsSPS))) // lt_idx_sps
+						}
+						if idx >= uint64(len(sps.UsedByCurrPicLtSPS)) {
+							return 0, fmt.Errorf("invalid lt_idx_sps: %d", idx)
+						}
+						if sps.UsedByCurrPicLtSPS[idx] {
+							numPicTotalCurr++
+						}
+					} else {
+						r.u(pocLsbSize) // poc_lsb_lt
+						if r.flag() {   // used_by_curr_pic_lt_flag
+							numPicTotalCurr++
+						}
+					}
+					if r.flag() { // delta_poc_msb_present_flag
+						r.ue() // delta_poc_msb_cycle_lt
+					}
+				}
+			}
+			if sps.TemporalMVPEnabled {
+				temporalMVP = r.flag() // slice_temporal_mvp_enabled_flag
+			}
+		}
+
+		chroma := !sps.SeparateColourPlane && sps.ChromaFormatIdc != 0
+		var saoLuma, saoChroma bool
+		if sps.SampleAdaptiveOffsetEnabled {
+			saoLuma = r.flag()
+			if chroma {
+				saoChroma = r.flag()
+			}
+		}
+
+		if p || b {
+			numRefIdx := []uint64{uint64(pps.NumRefIdxL0DefaultActiveMinus1), uint64(pps.NumRefIdxL1DefaultActiveMinus1)}
+			if !b {
+				numRefIdx = numRefIdx[:1]
+			}
+			if r.flag() { // num_ref_idx_active_override_flag
+				for i := range numRefIdx {
+					numRefIdx[i] = r.ue()
+				}
+			}
+			for _, n := range numRefIdx {
+				if n > 14 {
+					return 0, fmt.Errorf("invalid number of reference indices: %d", n+1)
+				}
+			}
+			if pps.ListsModificationPresent && numPicTotalCurr > 1 {
+				// ref_pic_lists_modification()
+				for _, n := range numRefIdx {
+					if r.flag() { // ref_pic_list_modification_flag_l0/l1
+						for i := uint64(0); i <= n; i++ {
+							r.u(ceilLog2(uint64(numPicTotalCurr))) // list_entry_l0/l1
+						}
+					}
+				}
+			}
+			if b {
+				r.flag() // mvd_l1_zero_flag
+			}
+			if pps.CabacInitPresent {
+				r.flag() // cabac_init_flag
+			}
+			if temporalMVP {
+				collocatedFromL0 := true
+				if b {
+					collocatedFromL0 = r.flag()
+				}
+				if collocatedFromL0 && numRefIdx[0] > 0 || !collocatedFromL0 && numRefIdx[1] > 0 {
+					r.ue() // collocated_ref_idx
+				}
+			}
+			if pps.WeightedPred && p || pps.WeightedBipred && b {
+				// pred_weight_table()
+				r.ue() // luma_log2_weight_denom
+				if chroma {
+					r.se() // delta_chroma_log2_weight_denom
+				}
+				for _, n := range numRefIdx {
+					lumaWeights := make([]bool, n+1)
+					chromaWeights := make([]bool, n+1)
+					for i := range lumaWeights {
+						lumaWeights[i] = r.flag() // luma_weight_l0_flag/l1
+					}
+					if chroma {
+						for i := range chromaWeights {
+							chromaWeights[i] = r.flag() // chroma_weight_l0_flag/l1
+						}
+					}
+					for i := range lumaWeights {
+						if lumaWeights[i] {
+							r.se() // delta_luma_weight
+							r.se() // luma_offset
+						}
+						if chromaWeights[i] {
+							for j := 0; j < 4; j++ {
+								r.se() // delta_chroma_weight, delta_chroma_offset
+							}
+						}
+					}
+				}
+			}
+			r.ue() // five_minus_max_num_merge_cand
+		}
+
+		r.se() // slice_qp_delta
+		if pps.SliceChromaQPOffsetsPresent {
+			r.se() // slice_cb_qp_offset
+			r.se() // slice_cr_qp_offset
+		}
+		if pps.ChromaQPOffsetListEnabled {
+			r.flag() // cu_chroma_qp_offset_enabled_flag
+		}
+		var overridden bool
+		if pps.DeblockingFilterOverrideEnabled {
+			overridden = r.flag() // deblocking_filter_override_flag
+		}
+		deblockingDisabled := pps.DeblockingFilterDisabled
+		if overridden {
+			deblockingDisabled = r.flag() // slice_deblocking_filter_disabled_flag
+			if !deblockingDisabled {
+				r.se() // slice_beta_offset_div2
+				r.se() // slice_tc_offset_div2
+			}
+		}
+		if pps.LoopFilterAcrossSlicesEnabled && (saoLuma || saoChroma || !deblockingDisabled) {
+			r.flag() // slice_loop_filter_across_slices_enabled_flag
+		}
+	}
+
+	if pps.TilesEnabled || pps.EntropyCodingSyncEnabled {
+		n := r.ue() // num_entry_point_offsets
+		if n > 0 {
+			offsetLen := r.ue() + 1 // offset_len_minus1
+			if offsetLen > 32 {
+				return 0, fmt.Errorf("invalid offset_len_minus1: %d", offsetLen-1)
+			}
+			for i := uint64(0); i < n && r.err == nil; i++ {
+				r.u(uint(offsetLen)) // entry_point_offset_minus1
+			}
+		}
+	}
+	if pps.SliceSegmentHeaderExtensionPresent {
+		n := r.ue() // slice_segment_header_extension_length
+		for i := uint64(0); i < n && r.err == nil; i++ {
+			r.u(8) // slice_segment_header_extension_data_byte
+		}
+	}
+	// byte_alignment()
+	if !r.flag() && r.err == nil {
+		return 0, errors.New("invalid alignment_bit_equal_to_one")
+	}
+	r.u(uint(-r.pos & 7)) // alignment_bit_equal_to_zero
+	if r.err != nil {
+		return 0, fmt.Errorf("failed to parse slice segment header: %w", r.err)
+	}
+	return 2 + nalUnitOffset(nalu[2:], int(r.pos/8)), nil
+}
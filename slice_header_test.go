@@ -0,0 +1,376 @@
+package mp4
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// buildTestNALUnit builds a NAL unit from the NAL unit header and the fields of buildTestBits
+// with emulation_prevention_three_byte inserted.
+func buildTestNALUnit(header []byte, fields ...[2]uint64) []byte {
+	nalu := append([]byte(nil), header...)
+	var zeros int
+	for _, b := range buildTestBits(fields...) {
+		if zeros >= 2 && b <= 0x03 {
+			nalu = append(nalu, 0x03)
+			zeros = 0
+		}
+		if b == 0x00 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+		nalu = append(nalu, b)
+	}
+	return nalu
+}
+
+func TestNALUnitOffset(t *testing.T) {
+	payload := []byte{0x00, 0x00, 0x03, 0x01, 0x00, 0x00, 0x03, 0x03, 0xff}
+	assert.Equal(t, 0, nalUnitOffset(payload, 0))
+	assert.Equal(t, 2, nalUnitOffset(payload, 2))
+	assert.Equal(t, 4, nalUnitOffset(payload, 3))
+	assert.Equal(t, 8, nalUnitOffset(payload, 6))
+	assert.Equal(t, 9, nalUnitOffset(payload, 7))
+}
+
+func TestAVCSliceHeaderSize(t *testing.T) {
+	spss := map[uint32]*AVCSPS{
+		0: {
+			ChromaFormatIdc:             1,
+			Log2MaxFrameNumMinus4:       12,
+			Log2MaxPicOrderCntLsbMinus4: 4,
+			FrameMbsOnly:                true,
+		},
+		1: {
+			SeqParameterSetID: 1,
+			ChromaFormatIdc:   1,
+			PicOrderCntType:   1,
+		},
+		2: {
+			SeqParameterSetID:         2,
+			ChromaFormatIdc:           1,
+			PicOrderCntType:           2,
+			PicWidthInMbsMinus1:       21,
+			PicHeightInMapUnitsMinus1: 17,
+			FrameMbsOnly:              true,
+		},
+	}
+	ppss := map[uint32]*AVCPPS{
+		0: {
+			EntropyCodingMode:              true,
+			WeightedPred:                   true,
+			DeblockingFilterControlPresent: true,
+		},
+		1: {
+			PicParameterSetID:                 1,
+			SeqParameterSetID:                 1,
+			BottomFieldPicOrderInFramePresent: true,
+			NumRefIdxL0DefaultActiveMinus1:    1,
+			WeightedBipredIdc:                 1,
+			RedundantPicCntPresent:            true,
+		},
+		2: {
+			PicParameterSetID:          2,
+			SeqParameterSetID:          2,
+			NumSliceGroupsMinus1:       1,
+			SliceGroupMapType:          4,
+			SliceGroupChangeRateMinus1: 2,
+		},
+	}
+
+	weights := make([][2]uint64, 0, 16*8)
+	for i := 0; i < 16; i++ {
+		weights = append(weights,
+			[2]uint64{1, 1}, // luma_weight_l0_flag
+			testSE(-20),     // luma_weight_l0
+			testSE(-20),     // luma_offset_l0
+			[2]uint64{1, 1}, // chroma_weight_l0_flag
+			testSE(-20),     // chroma_weight_l0[0]
+			testSE(-20),     // chroma_offset_l0[0]
+			testSE(-20),     // chroma_weight_l0[1]
+			testSE(-20),     // chroma_offset_l0[1]
+		)
+	}
+
+	testCases := []struct {
+		name string
+		nalu []byte
+		size int
+	}{
+		{
+			// 1146 bits of the slice header, 6 bits of cabac_alignment_one_bit
+			name: "P slice with pred_weight_table",
+			nalu: buildTestNALUnit([]byte{0x41}, append(append([][2]uint64{
+				testUE(0),        // first_mb_in_slice
+				testUE(5),        // slice_type
+				testUE(0),        // pic_parameter_set_id
+				[2]uint64{1, 16}, // frame_num
+				[2]uint64{2, 8},  // pic_order_cnt_lsb
+				[2]uint64{1, 1},  // num_ref_idx_active_override_flag
+				testUE(15),       // num_ref_idx_l0_active_minus1
+				[2]uint64{0, 1},  // ref_pic_list_modification_flag_l0
+				testUE(6),        // luma_log2_weight_denom
+				testUE(6),        // chroma_log2_weight_denom
+			}, weights...),
+				[2]uint64{0, 1},           // adaptive_ref_pic_marking_mode_flag
+				testUE(0),                 // cabac_init_idc
+				testSE(0),                 // slice_qp_delta
+				testUE(1),                 // disable_deblocking_filter_idc
+				[2]uint64{0x3f, 6},        // cabac_alignment_one_bit
+				[2]uint64{0xdeadbeef, 32}, // slice_data
+			)...),
+			size: 1 + 144,
+		},
+		{
+			// 88 bits of the slice header, which are preceded by an emulation_prevention_three_byte in the third byte
+			name: "IDR slice with emulation prevention",
+			nalu: buildTestNALUnit([]byte{0x65},
+				testUE(1<<23-2),           // first_mb_in_slice
+				testUE(7),                 // slice_type
+				testUE(0),                 // pic_parameter_set_id
+				[2]uint64{0, 16},          // frame_num
+				testUE(0),                 // idr_pic_id
+				[2]uint64{0, 8},           // pic_order_cnt_lsb
+				[2]uint64{0, 1},           // no_output_of_prior_pics_flag
+				[2]uint64{0, 1},           // long_term_reference_flag
+				testSE(-3),                // slice_qp_delta
+				testUE(0),                 // disable_deblocking_filter_idc
+				testSE(0),                 // slice_alpha_c0_offset_div2
+				testSE(0),                 // slice_beta_offset_div2
+				[2]uint64{0xdeadbeef, 32}, // slice_data
+			),
+			size: 1 + 1 + 11,
+		},
+		{
+			// 72 bits of the slice header
+			name: "B slice with ref_pic_list_modification and dec_ref_pic_marking",
+			nalu: buildTestNALUnit([]byte{0x21},
+				testUE(0),                 // first_mb_in_slice
+				testUE(6),                 // slice_type
+				testUE(1),                 // pic_parameter_set_id
+				[2]uint64{3, 4},           // frame_num
+				[2]uint64{0, 1},           // field_pic_flag
+				testSE(2),                 // delta_pic_order_cnt[0]
+				testSE(-1),                // delta_pic_order_cnt[1]
+				testUE(0),                 // redundant_pic_cnt
+				[2]uint64{1, 1},           // direct_spatial_mv_pred_flag
+				[2]uint64{0, 1},           // num_ref_idx_active_override_flag
+				[2]uint64{1, 1},           // ref_pic_list_modification_flag_l0
+				testUE(0),                 // modification_of_pic_nums_idc
+				testUE(2),                 // abs_diff_pic_num_minus1
+				testUE(2),                 // modification_of_pic_nums_idc
+				testUE(0),                 // long_term_pic_num
+				testUE(3),                 // modification_of_pic_nums_idc
+				[2]uint64{0, 1},           // ref_pic_list_modification_flag_l1
+				testUE(0),                 // luma_log2_weight_denom
+				testUE(0),                 // chroma_log2_weight_denom
+				[2]uint64{1, 1},           // luma_weight_l0_flag[0]
+				testSE(1),                 // luma_weight_l0[0]
+				testSE(0),                 // luma_offset_l0[0]
+				[2]uint64{0, 1},           // chroma_weight_l0_flag[0]
+				[2]uint64{0, 1},           // luma_weight_l0_flag[1]
+				[2]uint64{1, 1},           // chroma_weight_l0_flag[1]
+				testSE(0),                 // chroma_weight_l0[1][0]
+				testSE(0),                 // chroma_offset_l0[1][0]
+				testSE(0),                 // chroma_weight_l0[1][1]
+				testSE(0),                 // chroma_offset_l0[1][1]
+				[2]uint64{0, 1},           // luma_weight_l1_flag[0]
+				[2]uint64{0, 1},           // chroma_weight_l1_flag[0]
+				[2]uint64{1, 1},           // adaptive_ref_pic_marking_mode_flag
+				testUE(1),                 // memory_management_control_operation
+				testUE(0),                 // difference_of_pic_nums_minus1
+				testUE(3),                 // memory_management_control_operation
+				testUE(0),                 // difference_of_pic_nums_minus1
+				testUE(0),                 // long_term_frame_idx
+				testUE(0),                 // memory_management_control_operation
+				testSE(1),                 // slice_qp_delta
+				[2]uint64{0xdeadbeef, 32}, // slice_data
+			),
+			size: 1 + 9,
+		},
+		{
+			// 24 bits of the slice header
+			// Ceil(Log2(22 * 18 ÷ 3 + 1)) = 8 bits of slice_group_change_cycle
+			name: "slice with slice_group_change_cycle",
+			nalu: buildTestNALUnit([]byte{0x01},
+				testUE(0),                 // first_mb_in_slice
+				testUE(7),                 // slice_type
+				testUE(2),                 // pic_parameter_set_id
+				[2]uint64{1, 4},           // frame_num
+				testSE(0),                 // slice_qp_delta
+				[2]uint64{0xff, 8},        // slice_group_change_cycle
+				[2]uint64{0xdeadbeef, 32}, // slice_data
+			),
+			size: 1 + 3,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			size, err := avcSliceHeaderSize(tc.nalu, spss, ppss)
+			require.NoError(t, err)
+			assert.Equal(t, tc.size, size)
+		})
+	}
+
+	t.Run("unknown PPS", func(t *testing.T) {
+		_, err := avcSliceHeaderSize(buildTestNALUnit([]byte{0x41}, testUE(0), testUE(5), testUE(3), [2]uint64{0xff, 8}), spss, ppss)
+		assert.Error(t, err)
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		_, err := avcSliceHeaderSize(testCases[0].nalu[:40], spss, ppss)
+		assert.Error(t, err)
+	})
+}
+
+func TestHEVCSliceHeaderSize(t *testing.T) {
+	spss := map[uint32]*HEVCSPS{
+		0: {
+			ChromaFormatIdc:                   1,
+			PicWidthInLumaSamples:             1920,
+			PicHeightInLumaSamples:            1080,
+			Log2MaxPicOrderCntLsbMinus4:       4,
+			Log2DiffMaxMinLumaCodingBlockSize: 3,
+			SampleAdaptiveOffsetEnabled:       true,
+			NumShortTermRefPicSets:            2,
+			ShortTermRefPicSets: []HEVCShortTermRefPicSet{
+				{DeltaPocS0: []int32{-1}, UsedByCurrPicS0: []bool{true}},
+				{DeltaPocS0: []int32{-1, -2}, UsedByCurrPicS0: []bool{true, true}},
+			},
+			TemporalMVPEnabled: true,
+		},
+	}
+	ppss := map[uint32]*HEVCPPS{
+		0: {
+			DependentSliceSegmentsEnabled:  true,
+			CabacInitPresent:               true,
+			NumRefIdxL0DefaultActiveMinus1: 1,
+			WeightedPred:                   true,
+			EntropyCodingSyncEnabled:       true,
+			LoopFilterAcrossSlicesEnabled:  true,
+			ListsModificationPresent:       true,
+		},
+	}
+
+	entryPoints := make([][2]uint64, 0, 16)
+	for i := 0; i < 16; i++ {
+		entryPoints = append(entryPoints, [2]uint64{0x1234, 16}) // entry_point_offset_minus1
+	}
+
+	testCases := []struct {
+		name string
+		nalu []byte
+		size int
+	}{
+		{
+			// 386 bits of the slice segment header and 6 bits of byte_alignment()
+			// st_ref_pic_set(2) is predicted from the set 1 with deltaRps=-1,
+			// so that NumPicTotalCurr is 3 and list_entry_l0 has 2 bits
+			name: "P slice with st_ref_pic_set, pred_weight_table and entry points",
+			nalu: buildTestNALUnit([]byte{0x02, 0x01}, append(append([][2]uint64{
+				[2]uint64{0, 1},   // first_slice_segment_in_pic_flag
+				testUE(0),         // slice_pic_parameter_set_id
+				[2]uint64{0, 1},   // dependent_slice_segment_flag
+				[2]uint64{255, 9}, // slice_segment_address
+				testUE(1),         // slice_type
+				[2]uint64{5, 8},   // slice_pic_order_cnt_lsb
+				[2]uint64{0, 1},   // short_term_ref_pic_set_sps_flag
+				[2]uint64{1, 1},   // inter_ref_pic_set_prediction_flag
+				testUE(0),         // delta_idx_minus1
+				[2]uint64{1, 1},   // delta_rps_sign
+				testUE(0),         // abs_delta_rps_minus1
+				[2]uint64{7, 3},   // used_by_curr_pic_flag[0..2]
+				[2]uint64{1, 1},   // slice_temporal_mvp_enabled_flag
+				[2]uint64{1, 1},   // slice_sao_luma_flag
+				[2]uint64{0, 1},   // slice_sao_chroma_flag
+				[2]uint64{1, 1},   // num_ref_idx_active_override_flag
+				testUE(2),         // num_ref_idx_l0_active_minus1
+				[2]uint64{1, 1},   // ref_pic_list_modification_flag_l0
+				[2]uint64{2, 2},   // list_entry_l0[0]
+				[2]uint64{1, 2},   // list_entry_l0[1]
+				[2]uint64{0, 2},   // list_entry_l0[2]
+				[2]uint64{0, 1},   // cabac_init_flag
+				testUE(1),         // collocated_ref_idx
+				testUE(6),         // luma_log2_weight_denom
+				testSE(0),         // delta_chroma_log2_weight_denom
+				[2]uint64{7, 3},   // luma_weight_l0_flag[0..2]
+				[2]uint64{5, 3},   // chroma_weight_l0_flag[0..2]
+				testSE(-5),        // delta_luma_weight_l0[0]
+				testSE(3),         // luma_offset_l0[0]
+				testSE(0),         // delta_chroma_weight_l0[0][0]
+				testSE(0),         // delta_chroma_offset_l0[0][0]
+				testSE(0),         // delta_chroma_weight_l0[0][1]
+				testSE(0),         // delta_chroma_offset_l0[0][1]
+				testSE(-5),        // delta_luma_weight_l0[1]
+				testSE(3),         // luma_offset_l0[1]
+				testSE(-5),        // delta_luma_weight_l0[2]
+				testSE(3),         // luma_offset_l0[2]
+				testSE(0),         // delta_chroma_weight_l0[2][0]
+				testSE(0),         // delta_chroma_offset_l0[2][0]
+				testSE(0),         // delta_chroma_weight_l0[2][1]
+				testSE(0),         // delta_chroma_offset_l0[2][1]
+				testUE(0),         // five_minus_max_num_merge_cand
+				testSE(-2),        // slice_qp_delta
+				[2]uint64{1, 1},   // slice_loop_filter_across_slices_enabled_flag
+				testUE(16),        // num_entry_point_offsets
+				testUE(15),        // offset_len_minus1
+			}, entryPoints...),
+				[2]uint64{1, 1},           // alignment_bit_equal_to_one
+				[2]uint64{0, 5},           // alignment_bit_equal_to_zero
+				[2]uint64{0xdeadbeef, 32}, // slice_segment_data
+			)...),
+			size: 2 + 49,
+		},
+		{
+			// 11 bits of the slice segment header and 5 bits of byte_alignment()
+			name: "IDR slice",
+			nalu: buildTestNALUnit([]byte{0x26, 0x01},
+				[2]uint64{1, 1},           // first_slice_segment_in_pic_flag
+				[2]uint64{0, 1},           // no_output_of_prior_pics_flag
+				testUE(0),                 // slice_pic_parameter_set_id
+				testUE(2),                 // slice_type
+				[2]uint64{0, 1},           // slice_sao_luma_flag
+				[2]uint64{0, 1},           // slice_sao_chroma_flag
+				testSE(0),                 // slice_qp_delta
+				[2]uint64{0, 1},           // slice_loop_filter_across_slices_enabled_flag
+				testUE(0),                 // num_entry_point_offsets
+				[2]uint64{1, 1},           // alignment_bit_equal_to_one
+				[2]uint64{0, 4},           // alignment_bit_equal_to_zero
+				[2]uint64{0xdeadbeef, 32}, // slice_segment_data
+			),
+			size: 2 + 2,
+		},
+		{
+			// 13 bits of the slice segment header and 3 bits of byte_alignment()
+			name: "dependent slice segment",
+			nalu: buildTestNALUnit([]byte{0x02, 0x01},
+				[2]uint64{0, 1},           // first_slice_segment_in_pic_flag
+				testUE(0),                 // slice_pic_parameter_set_id
+				[2]uint64{1, 1},           // dependent_slice_segment_flag
+				[2]uint64{300, 9},         // slice_segment_address
+				testUE(0),                 // num_entry_point_offsets
+				[2]uint64{1, 1},           // alignment_bit_equal_to_one
+				[2]uint64{0, 2},           // alignment_bit_equal_to_zero
+				[2]uint64{0xdeadbeef, 32}, // slice_segment_data
+			),
+			size: 2 + 2,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			size, err := hevcSliceHeaderSize(tc.nalu, spss, ppss)
+			require.NoError(t, err)
+			assert.Equal(t, tc.size, size)
+		})
+	}
+
+	t.Run("invalid byte_alignment", func(t *testing.T) {
+		nalu := append([]byte(nil), testCases[1].nalu...)
+		nalu[3] &^= 0x10 // alignment_bit_equal_to_one
+		_, err := hevcSliceHeaderSize(nalu, spss, ppss)
+		assert.Error(t, err)
+	})
+}
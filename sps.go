@@ -0,0 +1,788 @@
+package mp4
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/Spidey120703/go-mp4/internal/bitio"
+)
+
+// removeEmulationPrevention converts the NAL unit payload into RBSP
+// by removing emulation_prevention_three_byte (0x03 following 0x0000).
+func removeEmulationPrevention(data []byte) []byte {
+	rbsp := make([]byte, 0, len(data))
+	var zeros int
+	for _, b := range data {
+		if zeros >= 2 && b == 0x03 {
+			zeros = 0
+			continue
+		}
+		if b == 0x00 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+		rbsp = append(rbsp, b)
+	}
+	return rbsp
+}
+
+// rbspReader reads the syntax elements of RBSP.
+// It keeps the first error and returns zero values after that,
+// so that parsers can read a whole syntax structure and check the error once.
+type rbspReader struct {
+	bitio.Reader
+	pos  uint64 // number of bits which have been read
+	stop uint64 // position of rbsp_stop_one_bit
+	err  error
+}
+
+func newRBSPReader(payload []byte) *rbspReader {
+	rbsp := removeEmulationPrevention(payload)
+	r := &rbspReader{Reader: bitio.NewReader(bytes.NewReader(rbsp))}
+	for i := len(rbsp) - 1; i >= 0; i-- {
+		if rbsp[i] == 0 {
+			continue
+		}
+		var tz uint64
+		for rbsp[i]>>tz&1 == 0 {
+			tz++
+		}
+		r.stop = uint64(i)*8 + 7 - tz
+		break
+	}
+	return r
+}
+
+// ReadBit reads a bit and counts the position.
+// bitio.ReadUint and bitio.ReadUE read the bitstream through this method.
+func (r *rbspReader) ReadBit() (bool, error) {
+	bit, err := r.Reader.ReadBit()
+	if err == nil {
+		r.pos++
+	}
+	return bit, err
+}
+
+func (r *rbspReader) u(width uint) uint64 {
+	if r.err != nil {
+		return 0
+	}
+	var v uint64
+	v, r.err = bitio.ReadUint(r, width)
+	return v
+}
+
+func (r *rbspReader) flag() bool {
+	return r.u(1) != 0
+}
+
+func (r *rbspReader) ue() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	var v uint64
+	v, r.err = bitio.ReadUE(r)
+	return v
+}
+
+func (r *rbspReader) se() int64 {
+	if r.err != nil {
+		return 0
+	}
+	var v int64
+	v, r.err = bitio.ReadSE(r)
+	return v
+}
+
+// moreData corresponds to more_rbsp_data() of ITU-T H.264 and H.265.
+func (r *rbspReader) moreData() bool {
+	return r.err == nil && r.pos < r.stop
+}
+
+// AVCSPS is the sequence parameter set of ITU-T H.264.
+type AVCSPS struct {
+	ProfileIdc                  uint8
+	ConstraintSetFlags          uint8
+	LevelIdc                    uint8
+	SeqParameterSetID           uint32
+	ChromaFormatIdc             uint8
+	SeparateColourPlane         bool
+	BitDepthLumaMinus8          uint8
+	BitDepthChromaMinus8        uint8
+	QPPrimeYZeroTransformBypass bool
+	SeqScalingMatrixPresent     bool
+	Log2MaxFrameNumMinus4       uint32
+	PicOrderCntType             uint32
+	Log2MaxPicOrderCntLsbMinus4 uint32
+	DeltaPicOrderAlwaysZero     bool
+	MaxNumRefFrames             uint32
+	GapsInFrameNumValueAllowed  bool
+	PicWidthInMbsMinus1         uint32
+	PicHeightInMapUnitsMinus1   uint32
+	FrameMbsOnly                bool
+	MBAdaptiveFrameField        bool
+	Direct8x8Inference          bool
+	FrameCropping               bool
+	FrameCropLeftOffset         uint32
+	FrameCropRightOffset        uint32
+	FrameCropTopOffset          uint32
+	FrameCropBottomOffset       uint32
+	// Width and Height are the dimensions of the picture after cropping.
+	Width  uint32
+	Height uint32
+	// VUI is nil if vui_parameters_present_flag is 0.
+	VUI *VUIParameters
+}
+
+// ParseAVCSPS parses the H.264 SPS NAL unit including its NAL unit header.
+func ParseAVCSPS(nalu []byte) (*AVCSPS, error) {
+	if len(nalu) < 4 || nalu[0]&0x1f != 7 {
+		return nil, errors.New("invalid SPS NAL unit")
+	}
+	sps := &AVCSPS{
+		ProfileIdc:         nalu[1],
+		ConstraintSetFlags: nalu[2],
+		LevelIdc:           nalu[3],
+		ChromaFormatIdc:    1,
+	}
+	r := newRBSPReader(nalu[4:])
+
+	sps.SeqParameterSetID = uint32(r.ue())
+	if avcHasChromaFormat(sps.ProfileIdc) {
+		sps.ChromaFormatIdc = uint8(r.ue())
+		if sps.ChromaFormatIdc == 3 {
+			sps.SeparateColourPlane = r.flag()
+		}
+		sps.BitDepthLumaMinus8 = uint8(r.ue())
+		sps.BitDepthChromaMinus8 = uint8(r.ue())
+		sps.QPPrimeYZeroTransformBypass = r.flag()
+		sps.SeqScalingMatrixPresent = r.flag()
+		if sps.SeqScalingMatrixPresent {
+			n := 8
+			if sps.ChromaFormatIdc == 3 {
+				n = 12
+			}
+			r.readAVCScalingLists(n)
+		}
+	}
+	sps.Log2MaxFrameNumMinus4 = uint32(r.ue())
+	sps.PicOrderCntType = uint32(r.ue())
+	switch sps.PicOrderCntType {
+	case 0:
+		sps.Log2MaxPicOrderCntLsbMinus4 = uint32(r.ue())
+	case 1:
+		sps.DeltaPicOrderAlwaysZero = r.flag()
+		r.se() // offset_for_non_ref_pic
+		r.se() // offset_for_top_to_bottom_field
+		n := r.ue()
+		for i := uint64(0); i < n && r.err == nil; i++ {
+			r.se() // offset_for_ref_frame
+		}
+	}
+	sps.MaxNumRefFrames = uint32(r.ue())
+	sps.GapsInFrameNumValueAllowed = r.flag()
+	sps.PicWidthInMbsMinus1 = uint32(r.ue())
+	sps.PicHeightInMapUnitsMinus1 = uint32(r.ue())
+	sps.FrameMbsOnly = r.flag()
+	if !sps.FrameMbsOnly {
+		sps.MBAdaptiveFrameField = r.flag()
+	}
+	sps.Direct8x8Inference = r.flag()
+	sps.FrameCropping = r.flag()
+	if sps.FrameCropping {
+		sps.FrameCropLeftOffset = uint32(r.ue())
+		sps.FrameCropRightOffset = uint32(r.ue())
+		sps.FrameCropTopOffset = uint32(r.ue())
+		sps.FrameCropBottomOffset = uint32(r.ue())
+	}
+	if r.flag() { // vui_parameters_present_flag
+		sps.VUI = r.readAVCVUI()
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	cropUnitX, cropUnitY := uint32(1), uint32(1)
+	if sps.ChromaFormatIdc != 0 && !sps.SeparateColourPlane {
+		if sps.ChromaFormatIdc == 1 || sps.ChromaFormatIdc == 2 {
+			cropUnitX = 2
+		}
+		if sps.ChromaFormatIdc == 1 {
+			cropUnitY = 2
+		}
+	}
+	frameHeightInMbs := sps.PicHeightInMapUnitsMinus1 + 1
+	if !sps.FrameMbsOnly {
+		frameHeightInMbs *= 2
+		cropUnitY *= 2
+	}
+	sps.Width = (sps.PicWidthInMbsMinus1+1)*16 - cropUnitX*(sps.FrameCropLeftOffset+sps.FrameCropRightOffset)
+	sps.Height = frameHeightInMbs*16 - cropUnitY*(sps.FrameCropTopOffset+sps.FrameCropBottomOffset)
+	return sps, nil
+}
+
+// avcHasChromaFormat reports whether the SPS of the profile has chroma_format_idc and the following fields.
+func avcHasChromaFormat(profileIdc uint8) bool {
+	switch profileIdc {
+	case 100, 110, 122, 244, 44, 83, 86, 118, 128, 138, 139, 134, 135:
+		return true
+	}
+	return false
+}
+
+// readAVCScalingLists skips n scaling_list() structures of ITU-T H.264.
+func (r *rbspReader) readAVCScalingLists(n int) {
+	for i := 0; i < n && r.err == nil; i++ {
+		if !r.flag() { // scaling_list_present_flag
+			continue
+		}
+		size := 16
+		if i >= 6 {
+			size = 64
+		}
+		last, next := int64(8), int64(8)
+		for j := 0; j < size && r.err == nil; j++ {
+			if next != 0 {
+				next = (last + r.se() + 256) % 256
+			}
+			if next != 0 {
+				last = next
+			}
+		}
+	}
+}
+
+// FrameRate returns the frame rate derived from the timing information of VUI.
+// It returns 0 if the timing information is not present.
+func (sps *AVCSPS) FrameRate() float64 {
+	if sps.VUI == nil || !sps.VUI.TimingInfoPresent || sps.VUI.NumUnitsInTick == 0 {
+		return 0
+	}
+	// a frame consists of two fields, and a tick corresponds to a field
+	return float64(sps.VUI.TimeScale) / (2 * float64(sps.VUI.NumUnitsInTick))
+}
+
+// String returns the main parameters of the SPS.
+func (sps *AVCSPS) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "ProfileIdc=%d LevelIdc=%d ChromaFormatIdc=%d BitDepthLuma=%d BitDepthChroma=%d Width=%d Height=%d",
+		sps.ProfileIdc, sps.LevelIdc, sps.ChromaFormatIdc,
+		sps.BitDepthLumaMinus8+8, sps.BitDepthChromaMinus8+8,
+		sps.Width, sps.Height)
+	writeVUIString(&b, sps.VUI, sps.FrameRate())
+	return b.String()
+}
+
+// HEVCProfileTierLevel is the general part of profile_tier_level() of ITU-T H.265.
+type HEVCProfileTierLevel struct {
+	GeneralProfileSpace         uint8
+	GeneralTierFlag             bool
+	GeneralProfileIdc           uint8
+	GeneralProfileCompatibility [32]bool
+	GeneralConstraintIndicator  [6]uint8
+	GeneralLevelIdc             uint8
+}
+
+// readHEVCProfileTierLevel reads profile_tier_level(1, maxSubLayersMinus1) and skips the sub-layer parts.
+func (r *rbspReader) readHEVCProfileTierLevel(maxSubLayersMinus1 uint8) HEVCProfileTierLevel {
+	var ptl HEVCProfileTierLevel
+	ptl.GeneralProfileSpace = uint8(r.u(2))
+	ptl.GeneralTierFlag = r.flag()
+	ptl.GeneralProfileIdc = uint8(r.u(5))
+	for i := range ptl.GeneralProfileCompatibility {
+		ptl.GeneralProfileCompatibility[i] = r.flag()
+	}
+	for i := range ptl.GeneralConstraintIndicator {
+		ptl.GeneralConstraintIndicator[i] = uint8(r.u(8))
+	}
+	ptl.GeneralLevelIdc = uint8(r.u(8))
+	subLayerProfilePresent := make([]bool, maxSubLayersMinus1)
+	subLayerLevelPresent := make([]bool, maxSubLayersMinus1)
+	for i := range subLayerProfilePresent {
+		subLayerProfilePresent[i] = r.flag()
+		subLayerLevelPresent[i] = r.flag()
+	}
+	if maxSubLayersMinus1 > 0 {
+		for i := maxSubLayersMinus1; i < 8; i++ {
+			r.u(2) // reserved_zero_2bits
+		}
+	}
+	for i := range subLayerProfilePresent {
+		if subLayerProfilePresent[i] {
+			r.u(8)  // sub_layer_profile_space, sub_layer_tier_flag and sub_layer_profile_idc
+			r.u(32) // sub_layer_profile_compatibility_flag
+			r.u(48) // sub_layer constraint flags
+		}
+		if subLayerLevelPresent[i] {
+			r.u(8) // sub_layer_level_idc
+		}
+	}
+	return ptl
+}
+
+// HEVCVPS is the video parameter set of ITU-T H.265.
+type HEVCVPS struct {
+	VideoParameterSetID      uint8
+	BaseLayerInternal        bool
+	BaseLayerAvailable       bool
+	MaxLayersMinus1          uint8
+	MaxSubLayersMinus1       uint8
+	TemporalIDNesting        bool
+	ProfileTierLevel         HEVCProfileTierLevel
+	SubLayerOrderingInfo     []HEVCSubLayerOrderingInfo
+	MaxLayerID               uint8
+	NumLayerSetsMinus1       uint32
+	TimingInfoPresent        bool
+	NumUnitsInTick           uint32
+	TimeScale                uint32
+	POCProportionalToTiming  bool
+	NumTicksPOCDiffOneMinus1 uint32
+	NumHRDParameters         uint32
+}
+
+// HEVCSubLayerOrderingInfo is the DPB parameters of a sub-layer.
+type HEVCSubLayerOrderingInfo struct {
+	MaxDecPicBufferingMinus1 uint32
+	MaxNumReorderPics        uint32
+	MaxLatencyIncreasePlus1  uint32
+}
+
+// readHEVCSubLayerOrderingInfo reads the sub-layer ordering information of VPS and SPS.
+// The values of the lower sub-layers are inferred from the highest one if they are not present.
+func (r *rbspReader) readHEVCSubLayerOrderingInfo(maxSubLayersMinus1 uint8) []HEVCSubLayerOrderingInfo {
+	infos := make([]HEVCSubLayerOrderingInfo, int(maxSubLayersMinus1)+1)
+	present := r.flag()
+	first := 0
+	if !present {
+		first = int(maxSubLayersMinus1)
+	}
+	for i := first; i < len(infos); i++ {
+		infos[i].MaxDecPicBufferingMinus1 = uint32(r.ue())
+		infos[i].MaxNumReorderPics = uint32(r.ue())
+		infos[i].MaxLatencyIncreasePlus1 = uint32(r.ue())
+	}
+	for i := 0; i < first; i++ {
+		infos[i] = infos[first]
+	}
+	return infos
+}
+
+// ParseHEVCVPS parses the H.265 VPS NAL unit including its NAL unit header.
+// The parameters following vps_num_hrd_parameters are not parsed.
+func ParseHEVCVPS(nalu []byte) (*HEVCVPS, error) {
+	if len(nalu) < 3 || (nalu[0]>>1)&0x3f != 32 {
+		return nil, errors.New("invalid VPS NAL unit")
+	}
+	r := newRBSPReader(nalu[2:])
+
+	vps := &HEVCVPS{}
+	vps.VideoParameterSetID = uint8(r.u(4))
+	vps.BaseLayerInternal = r.flag()
+	vps.BaseLayerAvailable = r.flag()
+	vps.MaxLayersMinus1 = uint8(r.u(6))
+	vps.MaxSubLayersMinus1 = uint8(r.u(3))
+	vps.TemporalIDNesting = r.flag()
+	r.u(16) // vps_reserved_0xffff_16bits
+	vps.ProfileTierLevel = r.readHEVCProfileTierLevel(vps.MaxSubLayersMinus1)
+	vps.SubLayerOrderingInfo = r.readHEVCSubLayerOrderingInfo(vps.MaxSubLayersMinus1)
+	vps.MaxLayerID = uint8(r.u(6))
+	vps.NumLayerSetsMinus1 = uint32(r.ue())
+	for i := uint32(1); i <= vps.NumLayerSetsMinus1 && r.err == nil; i++ {
+		r.u(uint(vps.MaxLayerID) + 1) // layer_id_included_flag
+	}
+	vps.TimingInfoPresent = r.flag()
+	if vps.TimingInfoPresent {
+		vps.NumUnitsInTick = uint32(r.u(32))
+		vps.TimeScale = uint32(r.u(32))
+		vps.POCProportionalToTiming = r.flag()
+		if vps.POCProportionalToTiming {
+			vps.NumTicksPOCDiffOneMinus1 = uint32(r.ue())
+		}
+		vps.NumHRDParameters = uint32(r.ue())
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return vps, nil
+}
+
+// String returns the main parameters of the VPS.
+func (vps *HEVCVPS) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "VideoParameterSetID=%d MaxLayersMinus1=%d MaxSubLayersMinus1=%d GeneralProfileIdc=%d GeneralLevelIdc=%d",
+		vps.VideoParameterSetID, vps.MaxLayersMinus1, vps.MaxSubLayersMinus1,
+		vps.ProfileTierLevel.GeneralProfileIdc, vps.ProfileTierLevel.GeneralLevelIdc)
+	if vps.TimingInfoPresent && vps.NumUnitsInTick != 0 {
+		fmt.Fprintf(&b, " FrameRate=%s", formatFrameRate(float64(vps.TimeScale)/float64(vps.NumUnitsInTick)))
+	}
+	return b.String()
+}
+
+// HEVCSPS is the sequence parameter set of ITU-T H.265.
+type HEVCSPS struct {
+	VideoParameterSetID                  uint8
+	MaxSubLayersMinus1                   uint8
+	TemporalIDNesting                    bool
+	ProfileTierLevel                     HEVCProfileTierLevel
+	SeqParameterSetID                    uint32
+	ChromaFormatIdc                      uint8
+	SeparateColourPlane                  bool
+	PicWidthInLumaSamples                uint32
+	PicHeightInLumaSamples               uint32
+	ConformanceWindow                    bool
+	ConfWinLeftOffset                    uint32
+	ConfWinRightOffset                   uint32
+	ConfWinTopOffset                     uint32
+	ConfWinBottomOffset                  uint32
+	BitDepthLumaMinus8                   uint8
+	BitDepthChromaMinus8                 uint8
+	Log2MaxPicOrderCntLsbMinus4          uint32
+	SubLayerOrderingInfo                 []HEVCSubLayerOrderingInfo
+	Log2MinLumaCodingBlockSizeMinus3     uint32
+	Log2DiffMaxMinLumaCodingBlockSize    uint32
+	Log2MinLumaTransformBlockSizeMinus2  uint32
+	Log2DiffMaxMinLumaTransformBlockSize uint32
+	MaxTransformHierarchyDepthInter      uint32
+	MaxTransformHierarchyDepthIntra      uint32
+	ScalingListEnabled                   bool
+	AMPEnabled                           bool
+	SampleAdaptiveOffsetEnabled          bool
+	PCMEnabled                           bool
+	NumShortTermRefPicSets               uint32
+	ShortTermRefPicSets                  []HEVCShortTermRefPicSet
+	LongTermRefPicsPresent               bool
+	NumLongTermRefPicsSPS                uint32
+	UsedByCurrPicLtSPS                   []bool
+	TemporalMVPEnabled                   bool
+	StrongIntraSmoothingEnabled          bool
+	// Width and Height are the dimensions of the picture after cropping by the conformance window.
+	Width  uint32
+	Height uint32
+	// VUI is nil if vui_parameters_present_flag is 0.
+	VUI *VUIParameters
+}
+
+// ParseHEVCSPS parses the H.265 SPS NAL unit including its NAL unit header.
+// The SPS extensions are not parsed.
+func ParseHEVCSPS(nalu []byte) (*HEVCSPS, error) {
+	if len(nalu) < 3 || (nalu[0]>>1)&0x3f != 33 {
+		return nil, errors.New("invalid SPS NAL unit")
+	}
+	r := newRBSPReader(nalu[2:])
+
+	sps := &HEVCSPS{}
+	sps.VideoParameterSetID = uint8(r.u(4))
+	sps.MaxSubLayersMinus1 = uint8(r.u(3))
+	sps.TemporalIDNesting = r.flag()
+	sps.ProfileTierLevel = r.readHEVCProfileTierLevel(sps.MaxSubLayersMinus1)
+	sps.SeqParameterSetID = uint32(r.ue())
+	sps.ChromaFormatIdc = uint8(r.ue())
+	if sps.ChromaFormatIdc == 3 {
+		sps.SeparateColourPlane = r.flag()
+	}
+	sps.PicWidthInLumaSamples = uint32(r.ue())
+	sps.PicHeightInLumaSamples = uint32(r.ue())
+	sps.ConformanceWindow = r.flag()
+	if sps.ConformanceWindow {
+		sps.ConfWinLeftOffset = uint32(r.ue())
+		sps.ConfWinRightOffset = uint32(r.ue())
+		sps.ConfWinTopOffset = uint32(r.ue())
+		sps.ConfWinBottomOffset = uint32(r.ue())
+	}
+	sps.BitDepthLumaMinus8 = uint8(r.ue())
+	sps.BitDepthChromaMinus8 = uint8(r.ue())
+	sps.Log2MaxPicOrderCntLsbMinus4 = uint32(r.ue())
+	sps.SubLayerOrderingInfo = r.readHEVCSubLayerOrderingInfo(sps.MaxSubLayersMinus1)
+	sps.Log2MinLumaCodingBlockSizeMinus3 = uint32(r.ue())
+	sps.Log2DiffMaxMinLumaCodingBlockSize = uint32(r.ue())
+	sps.Log2MinLumaTransformBlockSizeMinus2 = uint32(r.ue())
+	sps.Log2DiffMaxMinLumaTransformBlockSize = uint32(r.ue())
+	sps.MaxTransformHierarchyDepthInter = uint32(r.ue())
+	sps.MaxTransformHierarchyDepthIntra = uint32(r.ue())
+	sps.ScalingListEnabled = r.flag()
+	if sps.ScalingListEnabled {
+		if r.flag() { // sps_scaling_list_data_present_flag
+			r.readHEVCScalingListData()
+		}
+	}
+	sps.AMPEnabled = r.flag()
+	sps.SampleAdaptiveOffsetEnabled = r.flag()
+	sps.PCMEnabled = r.flag()
+	if sps.PCMEnabled {
+		r.u(4)   // pcm_sample_bit_depth_luma_minus1
+		r.u(4)   // pcm_sample_bit_depth_chroma_minus1
+		r.ue()   // log2_min_pcm_luma_coding_block_size_minus3
+		r.ue()   // log2_diff_max_min_pcm_luma_coding_block_size
+		r.flag() // pcm_loop_filter_disabled_flag
+	}
+	sps.NumShortTermRefPicSets = uint32(r.ue())
+	if sps.NumShortTermRefPicSets > 64 {
+		return nil, fmt.Errorf("invalid num_short_term_ref_pic_sets: %d", sps.NumShortTermRefPicSets)
+	}
+	sps.ShortTermRefPicSets = make([]HEVCShortTermRefPicSet, 0, sps.NumShortTermRefPicSets)
+	for i := 0; i < int(sps.NumShortTermRefPicSets) && r.err == nil; i++ {
+		sps.ShortTermRefPicSets = append(sps.ShortTermRefPicSets, r.readHEVCShortTermRefPicSet(i, int(sps.NumShortTermRefPicSets), sps.ShortTermRefPicSets))
+	}
+	sps.LongTermRefPicsPresent = r.flag()
+	if sps.LongTermRefPicsPresent {
+		sps.NumLongTermRefPicsSPS = uint32(r.ue())
+		if sps.NumLongTermRefPicsSPS > 32 {
+			return nil, fmt.Errorf("invalid num_long_term_ref_pics_sps: %d", sps.NumLongTermRefPicsSPS)
+		}
+		for i := uint32(0); i < sps.NumLongTermRefPicsSPS && r.err == nil; i++ {
+			r.u(uint(sps.Log2MaxPicOrderCntLsbMinus4) + 4) // lt_ref_pic_poc_lsb_sps
+			sps.UsedByCurrPicLtSPS = append(sps.UsedByCurrPicLtSPS, r.flag())
+		}
+	}
+	sps.TemporalMVPEnabled = r.flag()
+	sps.StrongIntraSmoothingEnabled = r.flag()
+	if r.flag() { // vui_parameters_present_flag
+		sps.VUI = r.readHEVCVUI(sps.MaxSubLayersMinus1)
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	subWidthC, subHeightC := uint32(1), uint32(1)
+	if !sps.SeparateColourPlane {
+		if sps.ChromaFormatIdc == 1 || sps.ChromaFormatIdc == 2 {
+			subWidthC = 2
+		}
+		if sps.ChromaFormatIdc == 1 {
+			subHeightC = 2
+		}
+	}
+	sps.Width = sps.PicWidthInLumaSamples - subWidthC*(sps.ConfWinLeftOffset+sps.ConfWinRightOffset)
+	sps.Height = sps.PicHeightInLumaSamples - subHeightC*(sps.ConfWinTopOffset+sps.ConfWinBottomOffset)
+	return sps, nil
+}
+
+// readHEVCScalingListData skips scaling_list_data() of ITU-T H.265.
+func (r *rbspReader) readHEVCScalingListData() {
+	for sizeID := 0; sizeID < 4; sizeID++ {
+		step := 1
+		if sizeID == 3 {
+			step = 3
+		}
+		for matrixID := 0; matrixID < 6 && r.err == nil; matrixID += step {
+			if !r.flag() { // scaling_list_pred_mode_flag
+				r.ue() // scaling_list_pred_matrix_id_delta
+				continue
+			}
+			coefNum := 64
+			if sizeID == 0 {
+				coefNum = 16
+			}
+			if sizeID > 1 {
+				r.se() // scaling_list_dc_coef_minus8
+			}
+			for i := 0; i < coefNum && r.err == nil; i++ {
+				r.se() // scaling_list_delta_coef
+			}
+		}
+	}
+}
+
+// HEVCShortTermRefPicSet is the short-term reference picture set of ITU-T H.265.
+// DeltaPocS0 and DeltaPocS1 are the derived POC differences of the negative and positive pictures.
+type HEVCShortTermRefPicSet struct {
+	DeltaPocS0      []int32
+	UsedByCurrPicS0 []bool
+	DeltaPocS1      []int32
+	UsedByCurrPicS1 []bool
+}
+
+// NumDeltaPocs returns the number of pictures of the set.
+func (rps *HEVCShortTermRefPicSet) NumDeltaPocs() int {
+	return len(rps.DeltaPocS0) + len(rps.DeltaPocS1)
+}
+
+// NumUsedByCurrPic returns the number of pictures which are used by the current picture.
+func (rps *HEVCShortTermRefPicSet) NumUsedByCurrPic() int {
+	var n int
+	for _, used := range rps.UsedByCurrPicS0 {
+		if used {
+			n++
+		}
+	}
+	for _, used := range rps.UsedByCurrPicS1 {
+		if used {
+			n++
+		}
+	}
+	return n
+}
+
+// readHEVCShortTermRefPicSet reads st_ref_pic_set(idx) of ITU-T H.265.
+// sets are the sets in the SPS, and numSets is num_short_term_ref_pic_sets,
+// which equals idx when the set is in the slice header.
+func (r *rbspReader) readHEVCShortTermRefPicSet(idx, numSets int, sets []HEVCShortTermRefPicSet) HEVCShortTermRefPicSet {
+	var rps HEVCShortTermRefPicSet
+	if idx != 0 && r.flag() { // inter_ref_pic_set_prediction_flag
+		var deltaIdxMinus1 int
+		if idx == numSets {
+			deltaIdxMinus1 = int(r.ue())
+		}
+		refRPSIdx := idx - (deltaIdxMinus1 + 1)
+		if refRPSIdx < 0 || refRPSIdx >= len(sets) {
+			if r.err == nil {
+				r.err = errors.New("invalid delta_idx_minus1")
+			}
+			return rps
+		}
+		ref := &sets[refRPSIdx]
+		negative := r.flag()          // delta_rps_sign
+		deltaRPS := int32(r.ue()) + 1 // abs_delta_rps_minus1
+		if negative {
+			deltaRPS = -deltaRPS
+		}
+		n := ref.NumDeltaPocs()
+		usedByCurrPic := make([]bool, n+1)
+		useDelta := make([]bool, n+1)
+		for j := 0; j <= n; j++ {
+			usedByCurrPic[j] = r.flag()
+			useDelta[j] = usedByCurrPic[j] || r.flag()
+		}
+		numNegative := len(ref.DeltaPocS0)
+
+		// (7-61)
+		for j := len(ref.DeltaPocS1) - 1; j >= 0; j-- {
+			if dPoc := ref.DeltaPocS1[j] + deltaRPS; dPoc < 0 && useDelta[numNegative+j] {
+				rps.DeltaPocS0 = append(rps.DeltaPocS0, dPoc)
+				rps.UsedByCurrPicS0 = append(rps.UsedByCurrPicS0, usedByCurrPic[numNegative+j])
+			}
+		}
+		if deltaRPS < 0 && useDelta[n] {
+			rps.DeltaPocS0 = append(rps.DeltaPocS0, deltaRPS)
+			rps.UsedByCurrPicS0 = append(rps.UsedByCurrPicS0, usedByCurrPic[n])
+		}
+		for j := 0; j < numNegative; j++ {
+			if dPoc := ref.DeltaPocS0[j] + deltaRPS; dPoc < 0 && useDelta[j] {
+				rps.DeltaPocS0 = append(rps.DeltaPocS0, dPoc)
+				rps.UsedByCurrPicS0 = append(rps.UsedByCurrPicS0, usedByCurrPic[j])
+			}
+		}
+
+		// (7-62)
+		for j := numNegative - 1; j >= 0; j-- {
+			if dPoc := ref.DeltaPocS0[j] + deltaRPS; dPoc > 0 && useDelta[j] {
+				rps.DeltaPocS1 = append(rps.DeltaPocS1, dPoc)
+				rps.UsedByCurrPicS1 = append(rps.UsedByCurrPicS1, usedByCurrPic[j])
+			}
+		}
+		if deltaRPS > 0 && useDelta[n] {
+			rps.DeltaPocS1 = append(rps.DeltaPocS1, deltaRPS)
+			rps.UsedByCurrPicS1 = append(rps.UsedByCurrPicS1, usedByCurrPic[n])
+		}
+		for j := 0; j < len(ref.DeltaPocS1); j++ {
+			if dPoc := ref.DeltaPocS1[j] + deltaRPS; dPoc > 0 && useDelta[numNegative+j] {
+				rps.DeltaPocS1 = append(rps.DeltaPocS1, dPoc)
+				rps.UsedByCurrPicS1 = append(rps.UsedByCurrPicS1, usedByCurrPic[numNegative+j])
+			}
+		}
+		return rps
+	}
+
+	numNegativePics := r.ue()
+	numPositivePics := r.ue()
+	if numNegativePics > 16 || numPositivePics > 16 {
+		if r.err == nil {
+			r.err = errors.New("invalid number of short-term reference pictures")
+		}
+		return rps
+	}
+	var poc int32
+	for i := uint64(0); i < numNegativePics; i++ {
+		poc -= int32(r.ue()) + 1 // delta_poc_s0_minus1
+		rps.DeltaPocS0 = append(rps.DeltaPocS0, poc)
+		rps.UsedByCurrPicS0 = append(rps.UsedByCurrPicS0, r.flag())
+	}
+	poc = 0
+	for i := uint64(0); i < numPositivePics; i++ {
+		poc += int32(r.ue()) + 1 // delta_poc_s1_minus1
+		rps.DeltaPocS1 = append(rps.DeltaPocS1, poc)
+		rps.UsedByCurrPicS1 = append(rps.UsedByCurrPicS1, r.flag())
+	}
+	return rps
+}
+
+// FrameRate returns the frame rate derived from the timing information of VUI.
+// It returns 0 if the timing information is not present.
+func (sps *HEVCSPS) FrameRate() float64 {
+	if sps.VUI == nil || !sps.VUI.TimingInfoPresent || sps.VUI.NumUnitsInTick == 0 {
+		return 0
+	}
+	return float64(sps.VUI.TimeScale) / float64(sps.VUI.NumUnitsInTick)
+}
+
+// String returns the main parameters of the SPS.
+func (sps *HEVCSPS) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "GeneralProfileIdc=%d GeneralTierFlag=%t GeneralLevelIdc=%d ChromaFormatIdc=%d BitDepthLuma=%d BitDepthChroma=%d Width=%d Height=%d",
+		sps.ProfileTierLevel.GeneralProfileIdc, sps.ProfileTierLevel.GeneralTierFlag, sps.ProfileTierLevel.GeneralLevelIdc,
+		sps.ChromaFormatIdc, sps.BitDepthLumaMinus8+8, sps.BitDepthChromaMinus8+8,
+		sps.Width, sps.Height)
+	writeVUIString(&b, sps.VUI, sps.FrameRate())
+	return b.String()
+}
+
+// formatFrameRate formats the frame rate rounded to 3 decimal places.
+func formatFrameRate(fps float64) string {
+	return strconv.FormatFloat(math.Round(fps*1000)/1000, 'f', -1, 64)
+}
+
+// stringifyParameterSet formats the parameter set NAL unit as a byte array
+// followed by the decoded parameters.
+// It returns false if the NAL unit is not a parameter set or cannot be parsed.
+func stringifyParameterSet(nalu []byte, hevc bool) (string, bool) {
+	if len(nalu) == 0 {
+		return "", false
+	}
+	var name string
+	var ps fmt.Stringer
+	var err error
+	if hevc {
+		switch (nalu[0] >> 1) & 0x3f {
+		case 32:
+			name = "VPS"
+			ps, err = ParseHEVCVPS(nalu)
+		case 33:
+			name = "SPS"
+			ps, err = ParseHEVCSPS(nalu)
+		case 34:
+			name = "PPS"
+			ps, err = ParseHEVCPPS(nalu)
+		default:
+			return "", false
+		}
+	} else {
+		switch nalu[0] & 0x1f {
+		case 7:
+			name = "SPS"
+			ps, err = ParseAVCSPS(nalu)
+		case 8:
+			name = "PPS"
+			ps, err = ParseAVCPPS(nalu, nil)
+		default:
+			return "", false
+		}
+	}
+	if err != nil {
+		return "", false
+	}
+	var b strings.Builder
+	b.WriteString("[")
+	for i, v := range nalu {
+		if i != 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "0x%x", v)
+	}
+	fmt.Fprintf(&b, "] %s={%s}", name, ps)
+	return b.String(), true
+}
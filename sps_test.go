@@ -0,0 +1,414 @@
+package mp4
+
+import (
+	"math/bits"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testUE returns the field of buildTestBits for ue(v).
+func testUE(v uint64) [2]uint64 {
+	return [2]uint64{v + 1, uint64(2*bits.Len64(v+1) - 1)}
+}
+
+// testSE returns the field of buildTestBits for se(v).
+func testSE(v int64) [2]uint64 {
+	if v > 0 {
+		return testUE(uint64(2*v - 1))
+	}
+	return testUE(uint64(-2 * v))
+}
+
+func TestRemoveEmulationPrevention(t *testing.T) {
+	assert.Equal(t,
+		[]byte{0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x03},
+		removeEmulationPrevention([]byte{0x00, 0x00, 0x03, 0x00, 0x00, 0x03, 0x01, 0x00, 0x00, 0x03, 0x03}))
+}
+
+func TestParseAVCSPS(t *testing.T) {
+	sps, err := ParseAVCSPS([]byte{
+		0x67, 0x64, 0x00, 0x0c, 0xac, 0xd9, 0x41, 0x41, 0x9f, 0x9f, 0x01, 0x6c, 0x80, 0x00, 0x00, 0x03,
+		0x00, 0x80, 0x00, 0x00, 0x0a, 0x07, 0x8a, 0x14, 0xcb,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &AVCSPS{
+		ProfileIdc:                  100,
+		LevelIdc:                    12,
+		ChromaFormatIdc:             1,
+		PicOrderCntType:             0,
+		Log2MaxPicOrderCntLsbMinus4: 2,
+		MaxNumRefFrames:             4,
+		PicWidthInMbsMinus1:         19,
+		PicHeightInMapUnitsMinus1:   11,
+		FrameMbsOnly:                true,
+		Direct8x8Inference:          true,
+		FrameCropping:               true,
+		FrameCropBottomOffset:       6,
+		Width:                       320,
+		Height:                      180,
+		VUI: &VUIParameters{
+			AspectRatioInfoPresent:         true,
+			AspectRatioIdc:                 1,
+			SARWidth:                       1,
+			SARHeight:                      1,
+			VideoSignalTypePresent:         true,
+			VideoFormat:                    5,
+			VideoFullRange:                 true,
+			TimingInfoPresent:              true,
+			NumUnitsInTick:                 1,
+			TimeScale:                      20,
+			BitstreamRestriction:           true,
+			MotionVectorsOverPicBoundaries: true,
+			Log2MaxMvLengthHorizontal:      9,
+			Log2MaxMvLengthVertical:        9,
+			MaxNumReorderFrames:            2,
+			MaxDecFrameBuffering:           4,
+		},
+	}, sps)
+	assert.Equal(t, float64(10), sps.FrameRate())
+	assert.Equal(t, "ProfileIdc=100 LevelIdc=12 ChromaFormatIdc=1 BitDepthLuma=8 BitDepthChroma=8 "+
+		"Width=320 Height=180 SAR=1:1 VideoFullRange=true FrameRate=10", sps.String())
+
+	_, err = ParseAVCSPS([]byte{0x68, 0xeb, 0xec, 0xb2, 0x2c})
+	assert.Error(t, err)
+
+	// truncated
+	_, err = ParseAVCSPS([]byte{0x67, 0x64, 0x00, 0x0c, 0xac, 0xd9, 0x41})
+	assert.Error(t, err)
+}
+
+func TestParseAVCSPSWithHRD(t *testing.T) {
+	sps, err := ParseAVCSPS(append([]byte{0x67, 66, 0xc0, 40}, buildTestBits(
+		testUE(0),            // seq_parameter_set_id
+		testUE(0),            // log2_max_frame_num_minus4
+		testUE(2),            // pic_order_cnt_type
+		testUE(1),            // max_num_ref_frames
+		[2]uint64{0, 1},      // gaps_in_frame_num_value_allowed_flag
+		testUE(119),          // pic_width_in_mbs_minus1
+		testUE(67),           // pic_height_in_map_units_minus1
+		[2]uint64{1, 1},      // frame_mbs_only_flag
+		[2]uint64{1, 1},      // direct_8x8_inference_flag
+		[2]uint64{1, 1},      // frame_cropping_flag
+		testUE(0),            // frame_crop_left_offset
+		testUE(0),            // frame_crop_right_offset
+		testUE(0),            // frame_crop_top_offset
+		testUE(4),            // frame_crop_bottom_offset
+		[2]uint64{1, 1},      // vui_parameters_present_flag
+		[2]uint64{1, 1},      // aspect_ratio_info_present_flag
+		[2]uint64{255, 8},    // aspect_ratio_idc
+		[2]uint64{4, 16},     // sar_width
+		[2]uint64{3, 16},     // sar_height
+		[2]uint64{0, 1},      // overscan_info_present_flag
+		[2]uint64{1, 1},      // video_signal_type_present_flag
+		[2]uint64{5, 3},      // video_format
+		[2]uint64{0, 1},      // video_full_range_flag
+		[2]uint64{1, 1},      // colour_description_present_flag
+		[2]uint64{1, 8},      // colour_primaries
+		[2]uint64{1, 8},      // transfer_characteristics
+		[2]uint64{1, 8},      // matrix_coefficients
+		[2]uint64{0, 1},      // chroma_loc_info_present_flag
+		[2]uint64{1, 1},      // timing_info_present_flag
+		[2]uint64{1001, 32},  // num_units_in_tick
+		[2]uint64{60000, 32}, // time_scale
+		[2]uint64{1, 1},      // fixed_frame_rate_flag
+		[2]uint64{1, 1},      // nal_hrd_parameters_present_flag
+		testUE(0),            // cpb_cnt_minus1
+		[2]uint64{0, 4},      // bit_rate_scale
+		[2]uint64{2, 4},      // cpb_size_scale
+		testUE(78124),        // bit_rate_value_minus1
+		testUE(156249),       // cpb_size_value_minus1
+		[2]uint64{1, 1},      // cbr_flag
+		[2]uint64{23, 5},     // initial_cpb_removal_delay_length_minus1
+		[2]uint64{23, 5},     // cpb_removal_delay_length_minus1
+		[2]uint64{23, 5},     // dpb_output_delay_length_minus1
+		[2]uint64{24, 5},     // time_offset_length
+		[2]uint64{0, 1},      // vcl_hrd_parameters_present_flag
+		[2]uint64{0, 1},      // low_delay_hrd_flag
+		[2]uint64{1, 1},      // pic_struct_present_flag
+		[2]uint64{0, 1},      // bitstream_restriction_flag
+		[2]uint64{1, 1},      // rbsp_stop_one_bit
+	)...))
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1920), sps.Width)
+	assert.Equal(t, uint32(1080), sps.Height)
+	require.NotNil(t, sps.VUI)
+	assert.Equal(t, uint16(4), sps.VUI.SARWidth)
+	assert.Equal(t, uint16(3), sps.VUI.SARHeight)
+	assert.Equal(t, uint8(1), sps.VUI.ColourPrimaries)
+	assert.True(t, sps.VUI.FixedFrameRate)
+	assert.True(t, sps.VUI.PicStructPresent)
+	assert.Nil(t, sps.VUI.VCLHRD)
+	assert.Equal(t, &HRDParameters{
+		CPBSizeScale:                       2,
+		InitialCPBRemovalDelayLengthMinus1: 23,
+		CPBRemovalDelayLengthMinus1:        23,
+		DPBOutputDelayLengthMinus1:         23,
+		TimeOffsetLength:                   24,
+		CPBs:                               []HRDCPBParameters{{BitRate: 5000000, CPBSize: 10000000, CBR: true}},
+	}, sps.VUI.NALHRD)
+	assert.InDelta(t, 29.97, sps.FrameRate(), 0.001)
+	assert.Equal(t, "ProfileIdc=66 LevelIdc=40 ChromaFormatIdc=1 BitDepthLuma=8 BitDepthChroma=8 "+
+		"Width=1920 Height=1080 SAR=4:3 VideoFullRange=false "+
+		"ColourPrimaries=1 TransferCharacteristics=1 MatrixCoefficients=1 "+
+		"FrameRate=29.97 BitRate=5000000 CPBSize=10000000 CBR=true", sps.String())
+}
+
+func TestParseHEVCSPS(t *testing.T) {
+	sps, err := ParseHEVCSPS([]byte{
+		0x42, 0x01, 0x01, 0x01, 0x60, 0x00, 0x00, 0x03, 0x00, 0x90, 0x00, 0x00, 0x03, 0x00, 0x00, 0x03,
+		0x00, 0x5d, 0xa0, 0x02, 0x80, 0x80, 0x2d, 0x16, 0x59, 0x59, 0xa4, 0x93, 0x2b, 0xc0, 0x5a, 0x70,
+		0x80, 0x00, 0x01, 0xf4, 0x80, 0x00, 0x3a, 0x98, 0x04,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, HEVCProfileTierLevel{
+		GeneralProfileIdc:           1,
+		GeneralProfileCompatibility: [32]bool{false, true, true},
+		GeneralConstraintIndicator:  [6]uint8{0x90},
+		GeneralLevelIdc:             93,
+	}, sps.ProfileTierLevel)
+	assert.True(t, sps.TemporalIDNesting)
+	assert.Equal(t, uint8(1), sps.ChromaFormatIdc)
+	assert.Equal(t, uint32(1280), sps.PicWidthInLumaSamples)
+	assert.Equal(t, uint32(720), sps.PicHeightInLumaSamples)
+	assert.Equal(t, uint32(1280), sps.Width)
+	assert.Equal(t, uint32(720), sps.Height)
+	require.NotNil(t, sps.VUI)
+	assert.Equal(t, uint16(1), sps.VUI.SARWidth)
+	assert.Equal(t, uint16(1), sps.VUI.SARHeight)
+	assert.Equal(t, uint8(5), sps.VUI.VideoFormat)
+	assert.True(t, sps.VUI.ChromaLocInfoPresent)
+	assert.Equal(t, uint32(1001), sps.VUI.NumUnitsInTick)
+	assert.Equal(t, uint32(30000), sps.VUI.TimeScale)
+	assert.InDelta(t, 29.97, sps.FrameRate(), 0.001)
+	assert.Equal(t, "GeneralProfileIdc=1 GeneralTierFlag=false GeneralLevelIdc=93 ChromaFormatIdc=1 "+
+		"BitDepthLuma=8 BitDepthChroma=8 Width=1280 Height=720 SAR=1:1 VideoFullRange=false FrameRate=29.97", sps.String())
+
+	_, err = ParseHEVCSPS([]byte{0x44, 0x01, 0xc1, 0x72, 0xb4, 0x62, 0x40})
+	assert.Error(t, err)
+}
+
+func TestParseHEVCSPSWithHRD(t *testing.T) {
+	sps, err := ParseHEVCSPS(append([]byte{0x42, 0x01}, buildTestBits(
+		[2]uint64{0, 4},               // sps_video_parameter_set_id
+		[2]uint64{0, 3},               // sps_max_sub_layers_minus1
+		[2]uint64{1, 1},               // sps_temporal_id_nesting_flag
+		[2]uint64{0, 2},               // general_profile_space
+		[2]uint64{0, 1},               // general_tier_flag
+		[2]uint64{2, 5},               // general_profile_idc
+		[2]uint64{0x20000000, 32},     // general_profile_compatibility_flag
+		[2]uint64{0x900000000000, 48}, // general constraint flags
+		[2]uint64{153, 8},             // general_level_idc
+		testUE(0),                     // sps_seq_parameter_set_id
+		testUE(1),                     // chroma_format_idc
+		testUE(3840),                  // pic_width_in_luma_samples
+		testUE(2160),                  // pic_height_in_luma_samples
+		[2]uint64{0, 1},               // conformance_window_flag
+		testUE(2),                     // bit_depth_luma_minus8
+		testUE(2),                     // bit_depth_chroma_minus8
+		testUE(4),                     // log2_max_pic_order_cnt_lsb_minus4
+		[2]uint64{1, 1},               // sps_sub_layer_ordering_info_present_flag
+		testUE(4),                     // sps_max_dec_pic_buffering_minus1
+		testUE(2),                     // sps_max_num_reorder_pics
+		testUE(0),                     // sps_max_latency_increase_plus1
+		testUE(0),                     // log2_min_luma_coding_block_size_minus3
+		testUE(3),                     // log2_diff_max_min_luma_coding_block_size
+		testUE(0),                     // log2_min_luma_transform_block_size_minus2
+		testUE(3),                     // log2_diff_max_min_luma_transform_block_size
+		testUE(0),                     // max_transform_hierarchy_depth_inter
+		testUE(0),                     // max_transform_hierarchy_depth_intra
+		[2]uint64{1, 1},               // scaling_list_enabled_flag
+		[2]uint64{0, 1},               // sps_scaling_list_data_present_flag
+		[2]uint64{1, 1},               // amp_enabled_flag
+		[2]uint64{1, 1},               // sample_adaptive_offset_enabled_flag
+		[2]uint64{0, 1},               // pcm_enabled_flag
+		testUE(2),                     // num_short_term_ref_pic_sets
+		testUE(1),                     // st_ref_pic_set(0): num_negative_pics
+		testUE(0),                     // st_ref_pic_set(0): num_positive_pics
+		testUE(0),                     // st_ref_pic_set(0): delta_poc_s0_minus1
+		[2]uint64{1, 1},               // st_ref_pic_set(0): used_by_curr_pic_s0_flag
+		[2]uint64{1, 1},               // st_ref_pic_set(1): inter_ref_pic_set_prediction_flag
+		[2]uint64{0, 1},               // st_ref_pic_set(1): delta_rps_sign
+		testUE(0),                     // st_ref_pic_set(1): abs_delta_rps_minus1
+		[2]uint64{1, 1},               // st_ref_pic_set(1): used_by_curr_pic_flag[0]
+		[2]uint64{0, 1},               // st_ref_pic_set(1): used_by_curr_pic_flag[1]
+		[2]uint64{0, 1},               // st_ref_pic_set(1): use_delta_flag[1]
+		[2]uint64{0, 1},               // long_term_ref_pics_present_flag
+		[2]uint64{1, 1},               // sps_temporal_mvp_enabled_flag
+		[2]uint64{1, 1},               // strong_intra_smoothing_enabled_flag
+		[2]uint64{1, 1},               // vui_parameters_present_flag
+		[2]uint64{1, 1},               // aspect_ratio_info_present_flag
+		[2]uint64{1, 8},               // aspect_ratio_idc
+		[2]uint64{0, 1},               // overscan_info_present_flag
+		[2]uint64{1, 1},               // video_signal_type_present_flag
+		[2]uint64{5, 3},               // video_format
+		[2]uint64{0, 1},               // video_full_range_flag
+		[2]uint64{1, 1},               // colour_description_present_flag
+		[2]uint64{9, 8},               // colour_primaries
+		[2]uint64{16, 8},              // transfer_characteristics
+		[2]uint64{9, 8},               // matrix_coeffs
+		[2]uint64{0, 1},               // chroma_loc_info_present_flag
+		[2]uint64{0, 3},               // neutral_chroma_indication_flag, field_seq_flag, frame_field_info_present_flag
+		[2]uint64{0, 1},               // default_display_window_flag
+		[2]uint64{1, 1},               // vui_timing_info_present_flag
+		[2]uint64{1001, 32},           // vui_num_units_in_tick
+		[2]uint64{60000, 32},          // vui_time_scale
+		[2]uint64{0, 1},               // vui_poc_proportional_to_timing_flag
+		[2]uint64{1, 1},               // vui_hrd_parameters_present_flag
+		[2]uint64{1, 1},               // nal_hrd_parameters_present_flag
+		[2]uint64{0, 1},               // vcl_hrd_parameters_present_flag
+		[2]uint64{0, 1},               // sub_pic_hrd_params_present_flag
+		[2]uint64{0, 4},               // bit_rate_scale
+		[2]uint64{2, 4},               // cpb_size_scale
+		[2]uint64{23, 5},              // initial_cpb_removal_delay_length_minus1
+		[2]uint64{23, 5},              // au_cpb_removal_delay_length_minus1
+		[2]uint64{23, 5},              // dpb_output_delay_length_minus1
+		[2]uint64{1, 1},               // fixed_pic_rate_general_flag
+		testUE(0),                     // elemental_duration_in_tc_minus1
+		testUE(0),                     // cpb_cnt_minus1
+		testUE(78124),                 // bit_rate_value_minus1
+		testUE(156249),                // cpb_size_value_minus1
+		[2]uint64{0, 1},               // cbr_flag
+		[2]uint64{0, 1},               // bitstream_restriction_flag
+		[2]uint64{0, 1},               // sps_extension_present_flag
+		[2]uint64{1, 1},               // rbsp_stop_one_bit
+	)...))
+	require.NoError(t, err)
+	assert.Equal(t, uint32(2), sps.NumShortTermRefPicSets)
+	assert.Equal(t, []HEVCShortTermRefPicSet{
+		{DeltaPocS0: []int32{-1}, UsedByCurrPicS0: []bool{true}},
+		{},
+	}, sps.ShortTermRefPicSets)
+	assert.Equal(t, []HEVCSubLayerOrderingInfo{{MaxDecPicBufferingMinus1: 4, MaxNumReorderPics: 2}}, sps.SubLayerOrderingInfo)
+	assert.Equal(t, uint32(3840), sps.Width)
+	assert.Equal(t, uint32(2160), sps.Height)
+	require.NotNil(t, sps.VUI)
+	assert.Equal(t, uint8(16), sps.VUI.TransferCharacteristics)
+	assert.Nil(t, sps.VUI.VCLHRD)
+	assert.Equal(t, &HRDParameters{
+		CPBSizeScale:                       2,
+		InitialCPBRemovalDelayLengthMinus1: 23,
+		CPBRemovalDelayLengthMinus1:        23,
+		DPBOutputDelayLengthMinus1:         23,
+		FixedPicRate:                       true,
+		CPBs:                               []HRDCPBParameters{{BitRate: 5000000, CPBSize: 10000000}},
+	}, sps.VUI.NALHRD)
+	assert.InDelta(t, 59.94, sps.FrameRate(), 0.001)
+}
+
+func TestParseHEVCSPSWithSubLayers(t *testing.T) {
+	sps, err := ParseHEVCSPS(append([]byte{0x42, 0x01}, buildTestBits(
+		[2]uint64{0, 4},               // sps_video_parameter_set_id
+		[2]uint64{2, 3},               // sps_max_sub_layers_minus1
+		[2]uint64{1, 1},               // sps_temporal_id_nesting_flag
+		[2]uint64{0, 2},               // general_profile_space
+		[2]uint64{0, 1},               // general_tier_flag
+		[2]uint64{1, 5},               // general_profile_idc
+		[2]uint64{0x60000000, 32},     // general_profile_compatibility_flag
+		[2]uint64{0x900000000000, 48}, // general constraint flags
+		[2]uint64{120, 8},             // general_level_idc
+		[2]uint64{1, 1},               // sub_layer_profile_present_flag[0]
+		[2]uint64{1, 1},               // sub_layer_level_present_flag[0]
+		[2]uint64{1, 1},               // sub_layer_profile_present_flag[1]
+		[2]uint64{0, 1},               // sub_layer_level_present_flag[1]
+		[2]uint64{0, 12},              // reserved_zero_2bits
+		[2]uint64{1, 8},               // sub_layer_profile_space, sub_layer_tier_flag, sub_layer_profile_idc[0]
+		[2]uint64{0x60000000, 32},     // sub_layer_profile_compatibility_flag[0]
+		[2]uint64{0x900000000000, 48}, // sub_layer constraint flags[0]
+		[2]uint64{90, 8},              // sub_layer_level_idc[0]
+		[2]uint64{1, 8},               // sub_layer_profile_space, sub_layer_tier_flag, sub_layer_profile_idc[1]
+		[2]uint64{0x60000000, 32},     // sub_layer_profile_compatibility_flag[1]
+		[2]uint64{0x900000000000, 48}, // sub_layer constraint flags[1]
+		testUE(0),                     // sps_seq_parameter_set_id
+		testUE(1),                     // chroma_format_idc
+		testUE(1920),                  // pic_width_in_luma_samples
+		testUE(1088),                  // pic_height_in_luma_samples
+		[2]uint64{1, 1},               // conformance_window_flag
+		testUE(0),                     // conf_win_left_offset
+		testUE(0),                     // conf_win_right_offset
+		testUE(0),                     // conf_win_top_offset
+		testUE(4),                     // conf_win_bottom_offset
+		testUE(0),                     // bit_depth_luma_minus8
+		testUE(0),                     // bit_depth_chroma_minus8
+		testUE(4),                     // log2_max_pic_order_cnt_lsb_minus4
+		[2]uint64{1, 1},               // sps_sub_layer_ordering_info_present_flag
+		testUE(1),                     // sps_max_dec_pic_buffering_minus1[0]
+		testUE(0),                     // sps_max_num_reorder_pics[0]
+		testUE(0),                     // sps_max_latency_increase_plus1[0]
+		testUE(2),                     // sps_max_dec_pic_buffering_minus1[1]
+		testUE(1),                     // sps_max_num_reorder_pics[1]
+		testUE(0),                     // sps_max_latency_increase_plus1[1]
+		testUE(4),                     // sps_max_dec_pic_buffering_minus1[2]
+		testUE(2),                     // sps_max_num_reorder_pics[2]
+		testUE(0),                     // sps_max_latency_increase_plus1[2]
+		testUE(0),                     // log2_min_luma_coding_block_size_minus3
+		testUE(3),                     // log2_diff_max_min_luma_coding_block_size
+		testUE(0),                     // log2_min_luma_transform_block_size_minus2
+		testUE(3),                     // log2_diff_max_min_luma_transform_block_size
+		testUE(0),                     // max_transform_hierarchy_depth_inter
+		testUE(0),                     // max_transform_hierarchy_depth_intra
+		[2]uint64{0, 1},               // scaling_list_enabled_flag
+		[2]uint64{1, 1},               // amp_enabled_flag
+		[2]uint64{1, 1},               // sample_adaptive_offset_enabled_flag
+		[2]uint64{0, 1},               // pcm_enabled_flag
+		testUE(0),                     // num_short_term_ref_pic_sets
+		[2]uint64{0, 1},               // long_term_ref_pics_present_flag
+		[2]uint64{1, 1},               // sps_temporal_mvp_enabled_flag
+		[2]uint64{1, 1},               // strong_intra_smoothing_enabled_flag
+		[2]uint64{1, 1},               // vui_parameters_present_flag
+		[2]uint64{0, 1},               // aspect_ratio_info_present_flag
+		[2]uint64{0, 1},               // overscan_info_present_flag
+		[2]uint64{0, 1},               // video_signal_type_present_flag
+		[2]uint64{0, 1},               // chroma_loc_info_present_flag
+		[2]uint64{0, 3},               // neutral_chroma_indication_flag, field_seq_flag, frame_field_info_present_flag
+		[2]uint64{0, 1},               // default_display_window_flag
+		[2]uint64{1, 1},               // vui_timing_info_present_flag
+		[2]uint64{1000, 32},           // vui_num_units_in_tick
+		[2]uint64{25000, 32},          // vui_time_scale
+		[2]uint64{0, 1},               // vui_poc_proportional_to_timing_flag
+		[2]uint64{0, 1},               // vui_hrd_parameters_present_flag
+		[2]uint64{0, 1},               // bitstream_restriction_flag
+		[2]uint64{0, 1},               // sps_extension_present_flag
+		[2]uint64{1, 1},               // rbsp_stop_one_bit
+	)...))
+	require.NoError(t, err)
+	assert.Equal(t, uint8(2), sps.MaxSubLayersMinus1)
+	assert.Equal(t, uint8(120), sps.ProfileTierLevel.GeneralLevelIdc)
+	assert.Equal(t, []HEVCSubLayerOrderingInfo{
+		{MaxDecPicBufferingMinus1: 1},
+		{MaxDecPicBufferingMinus1: 2, MaxNumReorderPics: 1},
+		{MaxDecPicBufferingMinus1: 4, MaxNumReorderPics: 2},
+	}, sps.SubLayerOrderingInfo)
+	assert.Equal(t, uint32(1920), sps.PicWidthInLumaSamples)
+	assert.Equal(t, uint32(1088), sps.PicHeightInLumaSamples)
+	assert.Equal(t, uint32(1920), sps.Width)
+	assert.Equal(t, uint32(1080), sps.Height)
+	require.NotNil(t, sps.VUI)
+	assert.InDelta(t, 25.0, sps.FrameRate(), 0.001)
+}
+
+func TestParseHEVCVPS(t *testing.T) {
+	vps, err := ParseHEVCVPS([]byte{
+		0x40, 0x01, 0x0c, 0x01, 0xff, 0xff, 0x01, 0x60, 0x00, 0x00, 0x03, 0x00, 0x90, 0x00, 0x00, 0x03,
+		0x00, 0x00, 0x03, 0x00, 0x78, 0x99, 0x98, 0x09,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &HEVCVPS{
+		BaseLayerInternal:  true,
+		BaseLayerAvailable: true,
+		TemporalIDNesting:  true,
+		ProfileTierLevel: HEVCProfileTierLevel{
+			GeneralProfileIdc:           1,
+			GeneralProfileCompatibility: [32]bool{false, true, true},
+			GeneralConstraintIndicator:  [6]uint8{0x90},
+			GeneralLevelIdc:             120,
+		},
+		SubLayerOrderingInfo: vps.SubLayerOrderingInfo,
+	}, vps)
+	assert.Len(t, vps.SubLayerOrderingInfo, 1)
+	assert.Equal(t, "VideoParameterSetID=0 MaxLayersMinus1=0 MaxSubLayersMinus1=0 GeneralProfileIdc=1 GeneralLevelIdc=120", vps.String())
+
+	_, err = ParseHEVCVPS([]byte{0x42, 0x01, 0x01})
+	assert.Error(t, err)
+}
@@ -0,0 +1,175 @@
+package mp4
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// TTMLNamespace is the XML namespace of TTML documents.
+const TTMLNamespace = "http://www.w3.org/ns/ttml"
+
+// TTMLDocument is a TTML document in a sample of an XML subtitle (stpp) track.
+type TTMLDocument struct {
+	Start    time.Duration
+	Duration time.Duration
+
+	// Document is the XML document, which is the first subsample of the sample.
+	Document []byte
+
+	// Images are the resources (e.g. PNG images of IMSC1 image profile) referenced by the document.
+	// They are stored as the following subsamples, and referenced in order by URNs like "urn:mpeg:14496-30:subs:1".
+	Images [][]byte
+}
+
+// TTMLReader reads TTML documents of an XML subtitle (stpp) track.
+type TTMLReader struct {
+	TrackID            uint32
+	Timescale          uint32
+	Namespace          string
+	SchemaLocation     string
+	AuxiliaryMIMETypes string
+
+	sr *SampleReader
+	// subsamples holds subsample sizes of samples in the sample table, keyed by the 1-origin sample number.
+	subsamples map[int][]uint32
+	// fragmentSubsamples holds subsample sizes of samples in track fragments, keyed by the offset of the traf box.
+	fragmentSubsamples map[uint64]map[int][]uint32
+}
+
+// NewTTMLReader returns a new TTMLReader.
+// The first XML subtitle track is used when trackID is zero.
+func NewTTMLReader(r io.ReadSeeker, trackID uint32) (*TTMLReader, error) {
+	bips, err := ExtractBoxesWithPayload(r, nil, []BoxPath{
+		{BoxTypeMoov(), BoxTypeTrak(), BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeStpp()},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var stpp *XMLSubtitleSampleEntry
+	for _, bip := range bips {
+		if trackID == 0 || bip.Info.TrackID == trackID {
+			trackID = bip.Info.TrackID
+			stpp = bip.Payload.(*XMLSubtitleSampleEntry)
+			break
+		}
+	}
+	if stpp == nil {
+		if trackID != 0 {
+			return nil, fmt.Errorf("XML subtitle track not found: trackID=%d", trackID)
+		}
+		return nil, errors.New("XML subtitle track not found")
+	}
+
+	sr, err := NewSampleReader(r, trackID)
+	if err != nil {
+		return nil, err
+	}
+	if sr.Timescale == 0 {
+		return nil, errors.New("invalid timescale")
+	}
+	tr := &TTMLReader{
+		TrackID:            trackID,
+		Timescale:          sr.Timescale,
+		Namespace:          stpp.Namespace,
+		SchemaLocation:     stpp.SchemaLocation,
+		AuxiliaryMIMETypes: stpp.AuxiliaryMIMETypes,
+		sr:                 sr,
+		fragmentSubsamples: make(map[uint64]map[int][]uint32),
+	}
+	if err := tr.readSubsamples(r); err != nil {
+		return nil, err
+	}
+	return tr, nil
+}
+
+func (tr *TTMLReader) readSubsamples(r io.ReadSeeker) error {
+	bips, err := ExtractBoxesWithPayload(r, nil, []BoxPath{
+		{BoxTypeMoov(), BoxTypeTrak(), BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeSubs()},
+		{BoxTypeMoof(), BoxTypeTraf()},
+		{BoxTypeMoof(), BoxTypeTraf(), BoxTypeTfhd()},
+		{BoxTypeMoof(), BoxTypeTraf(), BoxTypeSubs()},
+	})
+	if err != nil {
+		return err
+	}
+	var traf *BoxInfo
+	var trafTrackID uint32
+	for _, bip := range bips {
+		switch box := bip.Payload.(type) {
+		case *Traf:
+			traf = &bip.Info
+			trafTrackID = 0
+		case *Tfhd:
+			trafTrackID = box.TrackID
+		case *Subs:
+			if traf == nil {
+				if bip.Info.TrackID == tr.TrackID {
+					tr.subsamples = subsampleSizes(box)
+				}
+			} else if trafTrackID == tr.TrackID {
+				tr.fragmentSubsamples[traf.Offset] = subsampleSizes(box)
+			}
+		}
+	}
+	return nil
+}
+
+// subsampleSizes returns subsample sizes keyed by the 1-origin sample number.
+func subsampleSizes(subs *Subs) map[int][]uint32 {
+	sizes := make(map[int][]uint32, len(subs.Entries))
+	var number int
+	for _, entry := range subs.Entries {
+		number += int(entry.SampleDelta)
+		for _, info := range entry.SubsampleInformation {
+			size := uint32(info.SubsampleSizeV0)
+			if subs.GetVersion() == 1 {
+				size = info.SubsampleSizeV1
+			}
+			sizes[number] = append(sizes[number], size)
+		}
+	}
+	return sizes
+}
+
+// Next returns the next TTML document.
+// It returns io.EOF when all documents have been read.
+func (tr *TTMLReader) Next() (*TTMLDocument, error) {
+	s, err := tr.sr.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	var sizes []uint32
+	if s.fragment != nil {
+		sizes = tr.fragmentSubsamples[s.fragment.traf.Offset][s.fragmentSampleIndex+1]
+	} else {
+		sizes = tr.subsamples[s.Index+1]
+	}
+
+	timescale := int64(tr.Timescale)
+	doc := &TTMLDocument{
+		Start:    time.Duration(s.PTS/timescale)*time.Second + time.Duration(s.PTS%timescale)*time.Second/time.Duration(timescale),
+		Duration: time.Duration(s.Duration) * time.Second / time.Duration(timescale),
+		Document: s.Data,
+	}
+	if len(sizes) == 0 {
+		return doc, nil
+	}
+	var total uint64
+	for _, size := range sizes {
+		total += uint64(size)
+	}
+	if total != uint64(len(s.Data)) {
+		return nil, fmt.Errorf("subsample sizes mismatch sample size: sample=%d", s.Index+1)
+	}
+	data := s.Data
+	doc.Document = data[:sizes[0]]
+	data = data[sizes[0]:]
+	for _, size := range sizes[1:] {
+		doc.Images = append(doc.Images, data[:size])
+		data = data[size:]
+	}
+	return doc, nil
+}
@@ -0,0 +1,78 @@
+package mp4
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/orcaman/writerseeker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTTMLMuxAndRead(t *testing.T) {
+	docs := []*TTMLDocument{
+		{
+			Start:    0,
+			Duration: 2 * time.Second,
+			Document: []byte(`<tt xmlns="http://www.w3.org/ns/ttml"><body><div><p begin="0s" end="2s">Hello</p></div></body></tt>`),
+		},
+		{
+			Start:    2 * time.Second,
+			Duration: 2 * time.Second,
+			Document: []byte(`<tt xmlns="http://www.w3.org/ns/ttml"><body><div smpte:backgroundImage="urn:mpeg:14496-30:subs:1"/></body></tt>`),
+			Images:   [][]byte{[]byte("\x89PNG first"), bytes.Repeat([]byte{0x01}, 0x10000)},
+		},
+		{
+			Start:    5 * time.Second,
+			Duration: 500 * time.Millisecond,
+			Document: []byte(`<tt xmlns="http://www.w3.org/ns/ttml"><body><div><p begin="5s" end="5.5s">World</p></div></body></tt>`),
+		},
+	}
+
+	for _, fragmented := range []bool{false, true} {
+		output := &writerseeker.WriterSeeker{}
+		m := NewMuxer(output, &MuxerConfig{Fragmented: fragmented, FragmentDuration: time.Second})
+		track, err := m.AddTTMLTrack(1000, "", "", "image/png")
+		require.NoError(t, err)
+		for _, doc := range docs {
+			require.NoError(t, track.WriteTTML(doc))
+		}
+		assert.Error(t, track.WriteTTML(docs[0]))
+		require.NoError(t, m.Close())
+		r := output.BytesReader()
+
+		tr, err := NewTTMLReader(r, 0)
+		require.NoError(t, err)
+		assert.Equal(t, uint32(1), tr.TrackID)
+		assert.Equal(t, TTMLNamespace, tr.Namespace)
+		assert.Equal(t, "image/png", tr.AuxiliaryMIMETypes)
+		for i, expected := range docs {
+			doc, err := tr.Next()
+			require.NoError(t, err)
+			assert.Equal(t, expected.Start, doc.Start)
+			if i == 1 {
+				// the gap until the next document is covered by this sample
+				assert.Equal(t, 3*time.Second, doc.Duration)
+			} else {
+				assert.Equal(t, expected.Duration, doc.Duration)
+			}
+			assert.Equal(t, expected.Document, doc.Document)
+			assert.Equal(t, expected.Images, doc.Images)
+		}
+		_, err = tr.Next()
+		assert.Equal(t, io.EOF, err)
+
+		bips, err := ExtractBoxesWithPayload(r, nil, []BoxPath{
+			{BoxTypeMoov(), BoxTypeTrak(), BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeSubs()},
+			{BoxTypeMoof(), BoxTypeTraf(), BoxTypeSubs()},
+		})
+		require.NoError(t, err)
+		require.Len(t, bips, 1)
+		assert.Equal(t, uint8(1), bips[0].Payload.(*Subs).GetVersion())
+	}
+
+	_, err := NewTTMLReader(bytes.NewReader(nil), 0)
+	assert.Error(t, err)
+}
@@ -0,0 +1,340 @@
+package mp4
+
+import (
+	"fmt"
+	"strings"
+)
+
+// VUIParameters is the video usability information of ITU-T H.264 and H.265.
+// Some fields are defined only in either of the specifications.
+type VUIParameters struct {
+	AspectRatioInfoPresent bool
+	AspectRatioIdc         uint8
+	// SARWidth and SARHeight are the sample aspect ratio.
+	// They are derived from aspect_ratio_idc unless it is Extended_SAR (255).
+	SARWidth                       uint16
+	SARHeight                      uint16
+	OverscanInfoPresent            bool
+	OverscanAppropriate            bool
+	VideoSignalTypePresent         bool
+	VideoFormat                    uint8
+	VideoFullRange                 bool
+	ColourDescriptionPresent       bool
+	ColourPrimaries                uint8
+	TransferCharacteristics        uint8
+	MatrixCoefficients             uint8
+	ChromaLocInfoPresent           bool
+	ChromaSampleLocTypeTopField    uint32
+	ChromaSampleLocTypeBottomField uint32
+
+	// H.265 only
+	NeutralChromaIndication   bool
+	FieldSeq                  bool
+	FrameFieldInfoPresent     bool
+	DefaultDisplayWindow      bool
+	DefDispWinLeftOffset      uint32
+	DefDispWinRightOffset     uint32
+	DefDispWinTopOffset       uint32
+	DefDispWinBottomOffset    uint32
+	POCProportionalToTiming   bool
+	NumTicksPOCDiffOneMinus1  uint32
+	TilesFixedStructure       bool
+	RestrictedRefPicLists     bool
+	MinSpatialSegmentationIdc uint32
+
+	TimingInfoPresent bool
+	NumUnitsInTick    uint32
+	TimeScale         uint32
+	FixedFrameRate    bool // H.264 only
+
+	// NALHRD and VCLHRD are nil if the corresponding HRD parameters are not present.
+	NALHRD *HRDParameters
+	VCLHRD *HRDParameters
+
+	PicStructPresent bool // H.264 only
+
+	BitstreamRestriction           bool
+	MotionVectorsOverPicBoundaries bool
+	MaxBytesPerPicDenom            uint32
+	// MaxBitsPerMbDenom is max_bits_per_mb_denom of H.264 or max_bits_per_min_cu_denom of H.265.
+	MaxBitsPerMbDenom         uint32
+	Log2MaxMvLengthHorizontal uint32
+	Log2MaxMvLengthVertical   uint32
+	MaxNumReorderFrames       uint32 // H.264 only
+	MaxDecFrameBuffering      uint32 // H.264 only
+}
+
+// HRDParameters is the hypothetical reference decoder parameters of ITU-T H.264 and H.265.
+// For H.265, the sub-layer parameters hold the values of the highest sub-layer.
+type HRDParameters struct {
+	SubPicHRDParamsPresent             bool // H.265 only
+	BitRateScale                       uint8
+	CPBSizeScale                       uint8
+	InitialCPBRemovalDelayLengthMinus1 uint8
+	// CPBRemovalDelayLengthMinus1 is cpb_removal_delay_length_minus1 of H.264
+	// or au_cpb_removal_delay_length_minus1 of H.265.
+	CPBRemovalDelayLengthMinus1 uint8
+	DPBOutputDelayLengthMinus1  uint8
+	TimeOffsetLength            uint8 // H.264 only
+	FixedPicRate                bool  // H.265 only
+	ElementalDurationInTcMinus1 uint32
+	LowDelay                    bool
+	CPBs                        []HRDCPBParameters
+}
+
+// HRDCPBParameters is the parameters of a coded picture buffer specification.
+type HRDCPBParameters struct {
+	// BitRate is the maximum input bit rate in bits per second.
+	BitRate uint64
+	// CPBSize is the size of the coded picture buffer in bits.
+	CPBSize uint64
+	CBR     bool
+}
+
+// sarTable is the sample aspect ratios indicated by aspect_ratio_idc (Table E-1).
+var sarTable = [...][2]uint16{
+	{0, 0}, {1, 1}, {12, 11}, {10, 11}, {16, 11}, {40, 33}, {24, 11}, {20, 11}, {32, 11},
+	{80, 33}, {18, 11}, {15, 11}, {64, 33}, {160, 99}, {4, 3}, {3, 2}, {2, 1},
+}
+
+const extendedSAR = 255
+
+// readVUICommon reads the fields from aspect_ratio_info_present_flag to chroma_loc_info,
+// which are common to H.264 and H.265.
+func (r *rbspReader) readVUICommon() *VUIParameters {
+	vui := &VUIParameters{}
+	vui.AspectRatioInfoPresent = r.flag()
+	if vui.AspectRatioInfoPresent {
+		vui.AspectRatioIdc = uint8(r.u(8))
+		if vui.AspectRatioIdc == extendedSAR {
+			vui.SARWidth = uint16(r.u(16))
+			vui.SARHeight = uint16(r.u(16))
+		} else if int(vui.AspectRatioIdc) < len(sarTable) {
+			vui.SARWidth = sarTable[vui.AspectRatioIdc][0]
+			vui.SARHeight = sarTable[vui.AspectRatioIdc][1]
+		}
+	}
+	vui.OverscanInfoPresent = r.flag()
+	if vui.OverscanInfoPresent {
+		vui.OverscanAppropriate = r.flag()
+	}
+	vui.VideoSignalTypePresent = r.flag()
+	if vui.VideoSignalTypePresent {
+		vui.VideoFormat = uint8(r.u(3))
+		vui.VideoFullRange = r.flag()
+		vui.ColourDescriptionPresent = r.flag()
+		if vui.ColourDescriptionPresent {
+			vui.ColourPrimaries = uint8(r.u(8))
+			vui.TransferCharacteristics = uint8(r.u(8))
+			vui.MatrixCoefficients = uint8(r.u(8))
+		}
+	}
+	vui.ChromaLocInfoPresent = r.flag()
+	if vui.ChromaLocInfoPresent {
+		vui.ChromaSampleLocTypeTopField = uint32(r.ue())
+		vui.ChromaSampleLocTypeBottomField = uint32(r.ue())
+	}
+	return vui
+}
+
+// readAVCVUI reads vui_parameters() of ITU-T H.264.
+func (r *rbspReader) readAVCVUI() *VUIParameters {
+	vui := r.readVUICommon()
+	vui.TimingInfoPresent = r.flag()
+	if vui.TimingInfoPresent {
+		vui.NumUnitsInTick = uint32(r.u(32))
+		vui.TimeScale = uint32(r.u(32))
+		vui.FixedFrameRate = r.flag()
+	}
+	if r.flag() { // nal_hrd_parameters_present_flag
+		vui.NALHRD = r.readAVCHRD()
+	}
+	if r.flag() { // vcl_hrd_parameters_present_flag
+		vui.VCLHRD = r.readAVCHRD()
+	}
+	if vui.NALHRD != nil || vui.VCLHRD != nil {
+		lowDelay := r.flag()
+		if vui.NALHRD != nil {
+			vui.NALHRD.LowDelay = lowDelay
+		}
+		if vui.VCLHRD != nil {
+			vui.VCLHRD.LowDelay = lowDelay
+		}
+	}
+	vui.PicStructPresent = r.flag()
+	vui.BitstreamRestriction = r.flag()
+	if vui.BitstreamRestriction {
+		vui.MotionVectorsOverPicBoundaries = r.flag()
+		vui.MaxBytesPerPicDenom = uint32(r.ue())
+		vui.MaxBitsPerMbDenom = uint32(r.ue())
+		vui.Log2MaxMvLengthHorizontal = uint32(r.ue())
+		vui.Log2MaxMvLengthVertical = uint32(r.ue())
+		vui.MaxNumReorderFrames = uint32(r.ue())
+		vui.MaxDecFrameBuffering = uint32(r.ue())
+	}
+	return vui
+}
+
+// readAVCHRD reads hrd_parameters() of ITU-T H.264.
+func (r *rbspReader) readAVCHRD() *HRDParameters {
+	hrd := &HRDParameters{}
+	cpbCnt := r.ue() + 1
+	if cpbCnt > 32 {
+		if r.err == nil {
+			r.err = fmt.Errorf("invalid cpb_cnt_minus1: %d", cpbCnt-1)
+		}
+		return hrd
+	}
+	hrd.BitRateScale = uint8(r.u(4))
+	hrd.CPBSizeScale = uint8(r.u(4))
+	hrd.CPBs = make([]HRDCPBParameters, cpbCnt)
+	for i := range hrd.CPBs {
+		hrd.CPBs[i].BitRate = (r.ue() + 1) << (6 + hrd.BitRateScale)
+		hrd.CPBs[i].CPBSize = (r.ue() + 1) << (4 + hrd.CPBSizeScale)
+		hrd.CPBs[i].CBR = r.flag()
+	}
+	hrd.InitialCPBRemovalDelayLengthMinus1 = uint8(r.u(5))
+	hrd.CPBRemovalDelayLengthMinus1 = uint8(r.u(5))
+	hrd.DPBOutputDelayLengthMinus1 = uint8(r.u(5))
+	hrd.TimeOffsetLength = uint8(r.u(5))
+	return hrd
+}
+
+// readHEVCVUI reads vui_parameters() of ITU-T H.265.
+func (r *rbspReader) readHEVCVUI(maxSubLayersMinus1 uint8) *VUIParameters {
+	vui := r.readVUICommon()
+	vui.NeutralChromaIndication = r.flag()
+	vui.FieldSeq = r.flag()
+	vui.FrameFieldInfoPresent = r.flag()
+	vui.DefaultDisplayWindow = r.flag()
+	if vui.DefaultDisplayWindow {
+		vui.DefDispWinLeftOffset = uint32(r.ue())
+		vui.DefDispWinRightOffset = uint32(r.ue())
+		vui.DefDispWinTopOffset = uint32(r.ue())
+		vui.DefDispWinBottomOffset = uint32(r.ue())
+	}
+	vui.TimingInfoPresent = r.flag()
+	if vui.TimingInfoPresent {
+		vui.NumUnitsInTick = uint32(r.u(32))
+		vui.TimeScale = uint32(r.u(32))
+		vui.POCProportionalToTiming = r.flag()
+		if vui.POCProportionalToTiming {
+			vui.NumTicksPOCDiffOneMinus1 = uint32(r.ue())
+		}
+		if r.flag() { // vui_hrd_parameters_present_flag
+			vui.NALHRD, vui.VCLHRD = r.readHEVCHRD(maxSubLayersMinus1)
+		}
+	}
+	vui.BitstreamRestriction = r.flag()
+	if vui.BitstreamRestriction {
+		vui.TilesFixedStructure = r.flag()
+		vui.MotionVectorsOverPicBoundaries = r.flag()
+		vui.RestrictedRefPicLists = r.flag()
+		vui.MinSpatialSegmentationIdc = uint32(r.ue())
+		vui.MaxBytesPerPicDenom = uint32(r.ue())
+		vui.MaxBitsPerMbDenom = uint32(r.ue())
+		vui.Log2MaxMvLengthHorizontal = uint32(r.ue())
+		vui.Log2MaxMvLengthVertical = uint32(r.ue())
+	}
+	return vui
+}
+
+// readHEVCHRD reads hrd_parameters(1, maxSubLayersMinus1) of ITU-T H.265.
+func (r *rbspReader) readHEVCHRD(maxSubLayersMinus1 uint8) (nal, vcl *HRDParameters) {
+	nalPresent := r.flag()
+	vclPresent := r.flag()
+	common := HRDParameters{}
+	if nalPresent || vclPresent {
+		common.SubPicHRDParamsPresent = r.flag()
+		if common.SubPicHRDParamsPresent {
+			r.u(8) // tick_divisor_minus2
+			r.u(5) // du_cpb_removal_delay_increment_length_minus1
+			r.u(1) // sub_pic_cpb_params_in_pic_timing_sei_flag
+			r.u(5) // dpb_output_delay_du_length_minus1
+		}
+		common.BitRateScale = uint8(r.u(4))
+		common.CPBSizeScale = uint8(r.u(4))
+		if common.SubPicHRDParamsPresent {
+			r.u(4) // cpb_size_du_scale
+		}
+		common.InitialCPBRemovalDelayLengthMinus1 = uint8(r.u(5))
+		common.CPBRemovalDelayLengthMinus1 = uint8(r.u(5))
+		common.DPBOutputDelayLengthMinus1 = uint8(r.u(5))
+	}
+	for i := 0; i <= int(maxSubLayersMinus1) && r.err == nil; i++ {
+		sub := common
+		sub.FixedPicRate = r.flag() // fixed_pic_rate_general_flag
+		if !sub.FixedPicRate {
+			sub.FixedPicRate = r.flag() // fixed_pic_rate_within_cvs_flag
+		}
+		if sub.FixedPicRate {
+			sub.ElementalDurationInTcMinus1 = uint32(r.ue())
+		} else {
+			sub.LowDelay = r.flag()
+		}
+		var cpbCnt uint64 = 1
+		if !sub.LowDelay {
+			cpbCnt = r.ue() + 1
+		}
+		if cpbCnt > 32 {
+			if r.err == nil {
+				r.err = fmt.Errorf("invalid cpb_cnt_minus1: %d", cpbCnt-1)
+			}
+			return nil, nil
+		}
+		nal, vcl = nil, nil
+		if nalPresent {
+			h := sub
+			h.CPBs = r.readHEVCSubLayerHRD(cpbCnt, &h)
+			nal = &h
+		}
+		if vclPresent {
+			h := sub
+			h.CPBs = r.readHEVCSubLayerHRD(cpbCnt, &h)
+			vcl = &h
+		}
+	}
+	return nal, vcl
+}
+
+// readHEVCSubLayerHRD reads sub_layer_hrd_parameters() of ITU-T H.265.
+func (r *rbspReader) readHEVCSubLayerHRD(cpbCnt uint64, hrd *HRDParameters) []HRDCPBParameters {
+	cpbs := make([]HRDCPBParameters, cpbCnt)
+	for i := range cpbs {
+		cpbs[i].BitRate = (r.ue() + 1) << (6 + hrd.BitRateScale)
+		cpbs[i].CPBSize = (r.ue() + 1) << (4 + hrd.CPBSizeScale)
+		if hrd.SubPicHRDParamsPresent {
+			r.ue() // cpb_size_du_value_minus1
+			r.ue() // bit_rate_du_value_minus1
+		}
+		cpbs[i].CBR = r.flag()
+	}
+	return cpbs
+}
+
+// writeVUIString writes the main parameters of VUI in the format of Stringify.
+func writeVUIString(b *strings.Builder, vui *VUIParameters, frameRate float64) {
+	if vui == nil {
+		return
+	}
+	if vui.SARWidth != 0 && vui.SARHeight != 0 {
+		fmt.Fprintf(b, " SAR=%d:%d", vui.SARWidth, vui.SARHeight)
+	}
+	if vui.VideoSignalTypePresent {
+		fmt.Fprintf(b, " VideoFullRange=%t", vui.VideoFullRange)
+	}
+	if vui.ColourDescriptionPresent {
+		fmt.Fprintf(b, " ColourPrimaries=%d TransferCharacteristics=%d MatrixCoefficients=%d",
+			vui.ColourPrimaries, vui.TransferCharacteristics, vui.MatrixCoefficients)
+	}
+	if frameRate != 0 {
+		fmt.Fprintf(b, " FrameRate=%s", formatFrameRate(frameRate))
+	}
+	hrd := vui.NALHRD
+	if hrd == nil {
+		hrd = vui.VCLHRD
+	}
+	if hrd != nil && len(hrd.CPBs) != 0 {
+		fmt.Fprintf(b, " BitRate=%d CPBSize=%d CBR=%t", hrd.CPBs[0].BitRate, hrd.CPBs[0].CPBSize, hrd.CPBs[0].CBR)
+	}
+}
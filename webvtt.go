@@ -0,0 +1,390 @@
+package mp4
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/orcaman/writerseeker"
+)
+
+const webVTTSignature = "WEBVTT"
+
+// WebVTT is a WebVTT file.
+type WebVTT struct {
+	// Header is the text before the first cue, which starts with "WEBVTT"
+	// and may include STYLE and REGION blocks.
+	Header string
+	Cues   []*WebVTTCue
+}
+
+// WebVTTCue is a cue of WebVTT.
+type WebVTTCue struct {
+	ID       string
+	Start    time.Duration
+	End      time.Duration
+	Settings string
+	Payload  string
+}
+
+// ParseWebVTT parses the WebVTT file.
+// NOTE blocks after the header are discarded.
+func ParseWebVTT(r io.Reader) (*WebVTT, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	text := strings.TrimPrefix(string(data), "\ufeff")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	var blocks [][]string
+	var block []string
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner.Buffer(make([]byte, 64*1024), len(text)+1)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if len(block) != 0 {
+				blocks = append(blocks, block)
+				block = nil
+			}
+			continue
+		}
+		block = append(block, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(block) != 0 {
+		blocks = append(blocks, block)
+	}
+	if len(blocks) == 0 || !isWebVTTSignature(blocks[0][0]) {
+		return nil, errors.New("WEBVTT signature not found")
+	}
+
+	vtt := &WebVTT{}
+	var headers []string
+	for _, block := range blocks {
+		timing := 0
+		if !strings.Contains(block[0], "-->") {
+			timing = 1
+		}
+		if timing >= len(block) || !strings.Contains(block[timing], "-->") {
+			if len(vtt.Cues) == 0 {
+				headers = append(headers, strings.Join(block, "\n"))
+			}
+			continue
+		}
+		cue, err := parseWebVTTTiming(block[timing])
+		if err != nil {
+			return nil, err
+		}
+		if timing == 1 {
+			cue.ID = block[0]
+		}
+		cue.Payload = strings.Join(block[timing+1:], "\n")
+		vtt.Cues = append(vtt.Cues, cue)
+	}
+	vtt.Header = strings.Join(headers, "\n\n")
+	return vtt, nil
+}
+
+func isWebVTTSignature(line string) bool {
+	return line == webVTTSignature || strings.HasPrefix(line, webVTTSignature+" ") || strings.HasPrefix(line, webVTTSignature+"\t")
+}
+
+// parseWebVTTTiming parses the line of cue timings and settings.
+func parseWebVTTTiming(line string) (*WebVTTCue, error) {
+	arrow := strings.Index(line, "-->")
+	fields := strings.Fields(line[arrow+3:])
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("invalid cue timings: %q", line)
+	}
+	start, err := parseWebVTTTimestamp(strings.TrimSpace(line[:arrow]))
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseWebVTTTimestamp(fields[0])
+	if err != nil {
+		return nil, err
+	}
+	if end < start {
+		return nil, fmt.Errorf("cue end time is earlier than start time: %q", line)
+	}
+	return &WebVTTCue{
+		Start:    start,
+		End:      end,
+		Settings: strings.Join(fields[1:], " "),
+	}, nil
+}
+
+// parseWebVTTTimestamp parses the timestamp in the form of [hh:]mm:ss.ttt.
+func parseWebVTTTimestamp(s string) (time.Duration, error) {
+	invalid := fmt.Errorf("invalid timestamp: %q", s)
+	dot := strings.IndexByte(s, '.')
+	if dot < 0 || len(s)-dot != 4 {
+		return 0, invalid
+	}
+	millis, err := strconv.ParseUint(s[dot+1:], 10, 16)
+	if err != nil {
+		return 0, invalid
+	}
+	parts := strings.Split(s[:dot], ":")
+	if len(parts) != 2 && len(parts) != 3 {
+		return 0, invalid
+	}
+	var seconds uint64
+	for i, part := range parts {
+		v, err := strconv.ParseUint(part, 10, 32)
+		if err != nil || (i != 0 && (len(part) != 2 || v >= 60)) {
+			return 0, invalid
+		}
+		seconds = seconds*60 + v
+	}
+	return time.Duration(seconds)*time.Second + time.Duration(millis)*time.Millisecond, nil
+}
+
+func formatWebVTTTimestamp(d time.Duration) string {
+	millis := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", millis/3600000, millis/60000%60, millis/1000%60, millis%1000)
+}
+
+// WriteTo writes the WebVTT file to w.
+func (vtt *WebVTT) WriteTo(w io.Writer) (int64, error) {
+	buf := &bytes.Buffer{}
+	header := strings.TrimRight(vtt.Header, "\n")
+	if header == "" {
+		header = webVTTSignature
+	}
+	buf.WriteString(header)
+	buf.WriteString("\n\n")
+	for _, cue := range vtt.Cues {
+		if cue.ID != "" {
+			buf.WriteString(cue.ID)
+			buf.WriteString("\n")
+		}
+		buf.WriteString(formatWebVTTTimestamp(cue.Start))
+		buf.WriteString(" --> ")
+		buf.WriteString(formatWebVTTTimestamp(cue.End))
+		if cue.Settings != "" {
+			buf.WriteString(" ")
+			buf.WriteString(cue.Settings)
+		}
+		buf.WriteString("\n")
+		if cue.Payload != "" {
+			buf.WriteString(cue.Payload)
+			buf.WriteString("\n")
+		}
+		buf.WriteString("\n")
+	}
+	return buf.WriteTo(w)
+}
+
+// ExtractWebVTT reads samples of the WebVTT track and writes them to w as a WebVTT file.
+// The header is taken from the vttC box, and a cue which spans several samples is merged into one.
+// The first WebVTT track is used when trackID is zero.
+func ExtractWebVTT(r io.ReadSeeker, w io.Writer, trackID uint32) error {
+	vtt, err := ReadWebVTT(r, trackID)
+	if err != nil {
+		return err
+	}
+	_, err = vtt.WriteTo(w)
+	return err
+}
+
+// ReadWebVTT reads samples of the WebVTT track and returns them as a WebVTT file.
+// The first WebVTT track is used when trackID is zero.
+func ReadWebVTT(r io.ReadSeeker, trackID uint32) (*WebVTT, error) {
+	bips, err := ExtractBoxesWithPayload(r, nil, []BoxPath{
+		{BoxTypeMoov(), BoxTypeTrak(), BoxTypeMdia(), BoxTypeMinf(), BoxTypeStbl(), BoxTypeStsd(), BoxTypeWvtt(), BoxTypeVttC()},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var vttC *WebVTTConfigurationBox
+	for _, bip := range bips {
+		if trackID == 0 || bip.Info.TrackID == trackID {
+			trackID = bip.Info.TrackID
+			vttC = bip.Payload.(*WebVTTConfigurationBox)
+			break
+		}
+	}
+	if vttC == nil {
+		if trackID != 0 {
+			return nil, fmt.Errorf("WebVTT track not found: trackID=%d", trackID)
+		}
+		return nil, errors.New("WebVTT track not found")
+	}
+
+	sr, err := NewSampleReader(r, trackID)
+	if err != nil {
+		return nil, err
+	}
+	if sr.Timescale == 0 {
+		return nil, errors.New("invalid timescale")
+	}
+	toDuration := func(t int64) time.Duration {
+		timescale := int64(sr.Timescale)
+		return time.Duration(t/timescale)*time.Second + time.Duration(t%timescale)*time.Second/time.Duration(timescale)
+	}
+
+	vtt := &WebVTT{Header: vttC.Config}
+	var active []*WebVTTCue
+	for {
+		s, err := sr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		start := toDuration(s.PTS)
+		end := toDuration(s.PTS + int64(s.Duration))
+		cues, err := parseWebVTTSample(s.Data)
+		if err != nil {
+			return nil, err
+		}
+
+		// the cue which continues from the previous sample is extended
+		var next []*WebVTTCue
+		for _, cue := range cues {
+			var extended bool
+			for i, a := range active {
+				if a != nil && a.End == start && a.ID == cue.ID && a.Settings == cue.Settings && a.Payload == cue.Payload {
+					a.End = end
+					next = append(next, a)
+					active[i] = nil
+					extended = true
+					break
+				}
+			}
+			if !extended {
+				cue.Start = start
+				cue.End = end
+				vtt.Cues = append(vtt.Cues, cue)
+				next = append(next, cue)
+			}
+		}
+		active = next
+	}
+	sort.SliceStable(vtt.Cues, func(i, j int) bool {
+		return vtt.Cues[i].Start < vtt.Cues[j].Start
+	})
+	return vtt, nil
+}
+
+// parseWebVTTSample parses the vttc boxes in the sample. vtte and vtta boxes are ignored.
+func parseWebVTTSample(data []byte) ([]*WebVTTCue, error) {
+	bips, err := ExtractBoxesWithPayload(bytes.NewReader(data), nil, []BoxPath{
+		{BoxTypeVttc()},
+		{BoxTypeVttc(), BoxTypeIden()},
+		{BoxTypeVttc(), BoxTypeSttg()},
+		{BoxTypeVttc(), BoxTypePayl()},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var cues []*WebVTTCue
+	for _, bip := range bips {
+		if bip.Info.Type == BoxTypeVttc() {
+			cues = append(cues, &WebVTTCue{})
+			continue
+		}
+		cue := cues[len(cues)-1]
+		switch box := bip.Payload.(type) {
+		case *CueIDBox:
+			cue.ID = box.CueId
+		case *CueSettingsBox:
+			cue.Settings = box.Settings
+		case *CuePayloadBox:
+			cue.Payload = box.CueText
+		}
+	}
+	return cues, nil
+}
+
+// buildWebVTTSamples converts the cues into samples of wvtt.
+// Overlapping cues are split at every start and end time, and gaps are filled with vtte boxes.
+// The first sample starts at the time of from.
+func buildWebVTTSamples(cues []*WebVTTCue, from time.Duration, timescale uint32) ([]*TrackSample, error) {
+	toTime := func(d time.Duration) uint64 {
+		return uint64(d/time.Second)*uint64(timescale) + uint64(d%time.Second)*uint64(timescale)/uint64(time.Second)
+	}
+
+	var boundaries []time.Duration
+	for _, cue := range cues {
+		if cue.Start < from {
+			return nil, fmt.Errorf("cue starts before the end of the previous cues: %s", formatWebVTTTimestamp(cue.Start))
+		}
+		if cue.End > cue.Start {
+			boundaries = append(boundaries, cue.Start, cue.End)
+		}
+	}
+	boundaries = append(boundaries, from)
+	sort.Slice(boundaries, func(i, j int) bool { return boundaries[i] < boundaries[j] })
+
+	var samples []*TrackSample
+	for i := 0; i+1 < len(boundaries); i++ {
+		start, end := boundaries[i], boundaries[i+1]
+		dts, endDTS := toTime(start), toTime(end)
+		if dts == endDTS {
+			continue
+		}
+		if endDTS-dts > math.MaxUint32 {
+			return nil, fmt.Errorf("too long sample duration: %s --> %s", formatWebVTTTimestamp(start), formatWebVTTTimestamp(end))
+		}
+
+		buf := &writerseeker.WriterSeeker{}
+		w := NewWriter(buf)
+		empty := true
+		for _, cue := range cues {
+			if cue.Start > start || cue.End < end || cue.End == cue.Start {
+				continue
+			}
+			empty = false
+			if err := writeParentBox(w, &VTTCueBox{}, Context{}, func() error {
+				if cue.ID != "" {
+					if err := writeBox(w, &CueIDBox{CueId: cue.ID}, Context{}); err != nil {
+						return err
+					}
+				}
+				if cue.Settings != "" {
+					if err := writeBox(w, &CueSettingsBox{Settings: cue.Settings}, Context{}); err != nil {
+						return err
+					}
+				}
+				return writeBox(w, &CuePayloadBox{CueText: cue.Payload}, Context{})
+			}); err != nil {
+				return nil, err
+			}
+		}
+		if empty {
+			if err := writeBox(w, &VTTEmptyCueBox{}, Context{}); err != nil {
+				return nil, err
+			}
+		}
+		data, err := io.ReadAll(buf.BytesReader())
+		if err != nil {
+			return nil, err
+		}
+
+		samples = append(samples, &TrackSample{
+			DTS:                    dts,
+			PTS:                    int64(dts),
+			Duration:               uint32(endDTS - dts),
+			Size:                   uint32(len(data)),
+			IsSync:                 true,
+			Flags:                  SampleFlagDependsOnNone,
+			SampleDescriptionIndex: 1,
+			Data:                   data,
+		})
+	}
+	return samples, nil
+}
@@ -0,0 +1,140 @@
+package mp4
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/orcaman/writerseeker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testWebVTT = "\ufeffWEBVTT - sample\r\n" +
+	"\r\n" +
+	"STYLE\r\n" +
+	"::cue { color: yellow }\r\n" +
+	"\r\n" +
+	"intro\r\n" +
+	"00:01.000 --> 00:04.000 align:start line:0\r\n" +
+	"Hello\r\n" +
+	"World\r\n" +
+	"\r\n" +
+	"NOTE this is a comment\r\n" +
+	"\r\n" +
+	"00:00:02.500 --> 00:00:03.000\r\n" +
+	"<v Bob>Overlap\r\n" +
+	"\r\n" +
+	"01:00:00.000 --> 01:00:01.250\r\n" +
+	"Later\r\n"
+
+func TestParseWebVTT(t *testing.T) {
+	vtt, err := ParseWebVTT(strings.NewReader(testWebVTT))
+	require.NoError(t, err)
+	assert.Equal(t, "WEBVTT - sample\n\nSTYLE\n::cue { color: yellow }", vtt.Header)
+	assert.Equal(t, []*WebVTTCue{
+		{ID: "intro", Start: time.Second, End: 4 * time.Second, Settings: "align:start line:0", Payload: "Hello\nWorld"},
+		{Start: 2500 * time.Millisecond, End: 3 * time.Second, Payload: "<v Bob>Overlap"},
+		{Start: time.Hour, End: time.Hour + 1250*time.Millisecond, Payload: "Later"},
+	}, vtt.Cues)
+
+	buf := &bytes.Buffer{}
+	_, err = vtt.WriteTo(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "WEBVTT - sample\n"+
+		"\n"+
+		"STYLE\n"+
+		"::cue { color: yellow }\n"+
+		"\n"+
+		"intro\n"+
+		"00:00:01.000 --> 00:00:04.000 align:start line:0\n"+
+		"Hello\n"+
+		"World\n"+
+		"\n"+
+		"00:00:02.500 --> 00:00:03.000\n"+
+		"<v Bob>Overlap\n"+
+		"\n"+
+		"01:00:00.000 --> 01:00:01.250\n"+
+		"Later\n"+
+		"\n", buf.String())
+
+	_, err = ParseWebVTT(strings.NewReader("00:01.000 --> 00:02.000\nHello\n"))
+	assert.Error(t, err)
+	_, err = ParseWebVTT(strings.NewReader("WEBVTT\n\n00:01.000 --> 00:0x.000\nHello\n"))
+	assert.Error(t, err)
+	_, err = ParseWebVTT(strings.NewReader("WEBVTT\n\n00:02.000 --> 00:01.000\nHello\n"))
+	assert.Error(t, err)
+}
+
+func TestWebVTTMuxAndExtract(t *testing.T) {
+	vtt, err := ParseWebVTT(strings.NewReader(testWebVTT))
+	require.NoError(t, err)
+
+	for _, fragmented := range []bool{false, true} {
+		output := &writerseeker.WriterSeeker{}
+		m := NewMuxer(output, &MuxerConfig{Fragmented: fragmented})
+		track, err := m.AddWebVTTTrack(1000, vtt.Header)
+		require.NoError(t, err)
+		require.NoError(t, track.WriteWebVTT(vtt.Cues[:2]))
+		// cues must not go back in time
+		assert.Error(t, track.WriteWebVTT(vtt.Cues[1:2]))
+		require.NoError(t, track.WriteWebVTT(vtt.Cues[2:]))
+		require.NoError(t, m.Close())
+		r := output.BytesReader()
+
+		sr, err := NewSampleReader(r, 1)
+		require.NoError(t, err)
+		// [0,1) vtte, [1,2.5) intro, [2.5,3) intro+overlap, [3,4) intro, [4,3600) vtte, [3600,3601.25) later
+		require.Len(t, sr.Samples(), 6)
+		s, err := sr.Next()
+		require.NoError(t, err)
+		assert.Equal(t, []byte{0, 0, 0, 8, 'v', 't', 't', 'e'}, s.Data)
+
+		extracted, err := ReadWebVTT(r, 0)
+		require.NoError(t, err)
+		assert.Equal(t, vtt, extracted)
+	}
+
+	_, err = ReadWebVTT(bytes.NewReader(nil), 0)
+	assert.Error(t, err)
+}
+
+func TestWebVTTMuxLongDuration(t *testing.T) {
+	// cue times multiplied by the timescale in nanoseconds exceed the range of uint64 after about 30 minutes
+	const timescale = 10000000
+	vtt := &WebVTT{Header: webVTTSignature}
+	for i := 0; i < 8; i++ {
+		vtt.Cues = append(vtt.Cues, &WebVTTCue{
+			Start:   time.Duration(i) * 5 * time.Minute,
+			End:     time.Duration(i+1) * 5 * time.Minute,
+			Payload: strconv.Itoa(i),
+		})
+	}
+	output := &writerseeker.WriterSeeker{}
+	m := NewMuxer(output, nil)
+	track, err := m.AddWebVTTTrack(timescale, vtt.Header)
+	require.NoError(t, err)
+	require.NoError(t, track.WriteWebVTT(vtt.Cues))
+	require.NoError(t, m.Close())
+	r := output.BytesReader()
+
+	sr, err := NewSampleReader(r, 1)
+	require.NoError(t, err)
+	require.Len(t, sr.Samples(), 8)
+	for i, s := range sr.Samples() {
+		assert.Equal(t, uint64(i)*300*timescale, s.DTS)
+		assert.Equal(t, uint32(300*timescale), s.Duration)
+	}
+
+	extracted, err := ReadWebVTT(r, 0)
+	require.NoError(t, err)
+	assert.Equal(t, vtt, extracted)
+
+	// the duration of the sample must fit in 32 bits
+	m = NewMuxer(&writerseeker.WriterSeeker{}, nil)
+	track, err = m.AddWebVTTTrack(timescale, vtt.Header)
+	require.NoError(t, err)
+	assert.Error(t, track.WriteWebVTT([]*WebVTTCue{{Start: 0, End: time.Hour, Payload: "long"}}))
+}
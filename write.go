@@ -66,3 +66,64 @@ func (w *Writer) CopyBox(r io.ReadSeeker, bi *BoxInfo) error {
 	}
 	return nil
 }
+
+// writeBox writes the box which has no children.
+func writeBox(w *Writer, box IBox, ctx Context) error {
+	if _, err := w.StartBox(&BoxInfo{Type: box.GetType()}); err != nil {
+		return err
+	}
+	if _, err := Marshal(w, box, ctx); err != nil {
+		return err
+	}
+	_, err := w.EndBox()
+	return err
+}
+
+// writeParentBox writes the box and its children which are written by writeChildren.
+func writeParentBox(w *Writer, box IBox, ctx Context, writeChildren func() error) error {
+	if _, err := w.StartBox(&BoxInfo{Type: box.GetType()}); err != nil {
+		return err
+	}
+	if _, err := Marshal(w, box, ctx); err != nil {
+		return err
+	}
+	if err := writeChildren(); err != nil {
+		return err
+	}
+	_, err := w.EndBox()
+	return err
+}
+
+// copyBoxFields copies the payload of the box except for its children.
+func copyBoxFields(r io.ReadSeeker, w io.Writer, h *ReadHandle) error {
+	_, n, err := h.ReadPayload()
+	if err != nil {
+		return err
+	}
+	if _, err := h.BoxInfo.SeekToPayload(r); err != nil {
+		return err
+	}
+	_, err = io.CopyN(w, r, int64(n))
+	return err
+}
+
+// writeContainerBox copies the box with its own fields as is, and expands its children by the handler.
+// appendChildren is called after all of children are written.
+func writeContainerBox(r io.ReadSeeker, w *Writer, h *ReadHandle, appendChildren func() error) error {
+	if _, err := w.StartBox(&BoxInfo{Type: h.BoxInfo.Type}); err != nil {
+		return err
+	}
+	if err := copyBoxFields(r, w, h); err != nil {
+		return err
+	}
+	if _, err := h.Expand(); err != nil {
+		return err
+	}
+	if appendChildren != nil {
+		if err := appendChildren(); err != nil {
+			return err
+		}
+	}
+	_, err := w.EndBox()
+	return err
+}